@@ -2,6 +2,7 @@ package adapters
 
 import (
 	"context"
+	"time"
 
 	"github.com/kubescape/go-logger"
 	"github.com/kubescape/kubevuln/core/domain"
@@ -21,6 +22,12 @@ func NewMockCVEAdapter() *MockCVEAdapter {
 	return &MockCVEAdapter{}
 }
 
+// DBBuilt returns a static build date
+func (m MockCVEAdapter) DBBuilt(context.Context) time.Time {
+	logger.L().Info("MockCVEAdapter.DBBuilt")
+	return time.Date(2023, 3, 24, 6, 54, 57, 0, time.UTC)
+}
+
 // DBVersion returns a static version
 func (m MockCVEAdapter) DBVersion(context.Context) string {
 	logger.L().Info("MockCVEAdapter.DBVersion")
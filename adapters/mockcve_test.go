@@ -8,6 +8,11 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func TestMockCVEAdapter_DBBuilt(t *testing.T) {
+	m := NewMockCVEAdapter()
+	assert.False(t, m.DBBuilt(context.TODO()).IsZero())
+}
+
 func TestMockCVEAdapter_DBVersion(t *testing.T) {
 	m := NewMockCVEAdapter()
 	assert.Equal(t, "v1.0.0", m.DBVersion(context.TODO()))
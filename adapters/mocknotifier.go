@@ -0,0 +1,40 @@
+package adapters
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// MockNotifier implements a mocked Notifier to be used for tests, it keeps the received reports
+type MockNotifier struct {
+	mu      sync.Mutex
+	reports []domain.ScanReport
+}
+
+var _ ports.Notifier = (*MockNotifier)(nil)
+
+// NewMockNotifier initializes the MockNotifier struct
+func NewMockNotifier() *MockNotifier {
+	return &MockNotifier{}
+}
+
+// Notify records the given report
+func (m *MockNotifier) Notify(ctx context.Context, report domain.ScanReport) error {
+	_, span := otel.Tracer("").Start(ctx, "MockNotifier.Notify")
+	defer span.End()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reports = append(m.reports, report)
+	return nil
+}
+
+// Reports returns the recorded reports
+func (m *MockNotifier) Reports() []domain.ScanReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]domain.ScanReport{}, m.reports...)
+}
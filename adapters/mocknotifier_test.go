@@ -0,0 +1,16 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockNotifier_Notify(t *testing.T) {
+	m := NewMockNotifier()
+	err := m.Notify(context.TODO(), domain.ScanReport{ScanID: "scanID", Verdict: domain.VerdictSuccess})
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.ScanReport{{ScanID: "scanID", Verdict: domain.VerdictSuccess}}, m.Reports())
+}
@@ -41,3 +41,10 @@ func (m MockPlatform) SubmitCVE(ctx context.Context, _ domain.CVEManifest, _ dom
 	defer span.End()
 	return nil
 }
+
+// SubmitTombstone logs the given deleted workload
+func (m MockPlatform) SubmitTombstone(ctx context.Context, _ string) error {
+	_, span := otel.Tracer("").Start(ctx, "MockPlatform.SubmitTombstone")
+	defer span.End()
+	return nil
+}
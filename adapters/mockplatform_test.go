@@ -28,3 +28,9 @@ func TestMockPlatform_SubmitCVE(t *testing.T) {
 	err := m.SubmitCVE(ctx, domain.CVEManifest{}, domain.CVEManifest{})
 	assert.NoError(t, err)
 }
+
+func TestMockPlatform_SubmitTombstone(t *testing.T) {
+	m := NewMockPlatform()
+	err := m.SubmitTombstone(context.TODO(), "wlid://cluster-minikube/namespace-default/deployment-nginx")
+	assert.NoError(t, err)
+}
@@ -23,6 +23,10 @@ type MockSBOMAdapter struct {
 }
 
 var _ ports.SBOMCreator = (*MockSBOMAdapter)(nil)
+var _ ports.ImageResolver = (*MockSBOMAdapter)(nil)
+var _ ports.RepositorySBOMCreator = (*MockSBOMAdapter)(nil)
+
+const MockDigest = "sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137"
 
 // NewMockSBOMAdapter initializes the MockSBOMAdapter struct
 func NewMockSBOMAdapter(error, timeout, toomanyrequests bool) *MockSBOMAdapter {
@@ -62,10 +66,60 @@ func (m MockSBOMAdapter) CreateSBOM(_ context.Context, name, imageID string, _ d
 	}
 	if m.timeout {
 		sbom.Status = instanceidhandler.Incomplete
+		sbom.Annotations[domain.AnnotationIncompleteReason] = domain.FailureTimeout
 	}
 	return sbom, nil
 }
 
+// CreateRepositorySBOM returns a dummy SBOM for the given repository
+func (m MockSBOMAdapter) CreateRepositorySBOM(_ context.Context, name string, repository domain.Repository) (domain.SBOM, error) {
+	logger.L().Info("CreateRepositorySBOM")
+	if m.error {
+		return domain.SBOM{}, domain.ErrMockError
+	}
+	sbom := domain.SBOM{
+		Name:               name,
+		SBOMCreatorVersion: m.Version(),
+		Annotations: map[string]string{
+			domain.AnnotationArtifactType:     domain.ArtifactTypeRepository,
+			domain.AnnotationRepositoryCommit: "0123456789abcdef0123456789abcdef01234567",
+			domain.AnnotationRepositoryURL:    repository.URL,
+		},
+		Content: &v1beta1.Document{
+			CreationInfo: &v1beta1.CreationInfo{
+				Created: time.Now().Format(time.RFC3339),
+			},
+		},
+	}
+	if m.timeout {
+		sbom.Status = instanceidhandler.Incomplete
+	}
+	return sbom, nil
+}
+
+// CreateLayerDiffSBOMs returns dummy SBOMs for the given images, which share no layer
+func (m MockSBOMAdapter) CreateLayerDiffSBOMs(ctx context.Context, baseID, imageID string, options domain.RegistryOptions) (domain.LayerDiffSBOMs, error) {
+	logger.L().Info("CreateLayerDiffSBOMs")
+	base, err := m.CreateSBOM(ctx, "base", baseID, options)
+	if err != nil {
+		return domain.LayerDiffSBOMs{}, err
+	}
+	image, err := m.CreateSBOM(ctx, "image", imageID, options)
+	if err != nil {
+		return domain.LayerDiffSBOMs{}, err
+	}
+	return domain.LayerDiffSBOMs{Base: base, Image: image}, nil
+}
+
+// ResolveDigest returns the given tag pinned to a static digest
+func (m MockSBOMAdapter) ResolveDigest(_ context.Context, imageTag string, _ domain.RegistryOptions) (string, error) {
+	logger.L().Info("ResolveDigest")
+	if m.error {
+		return "", domain.ErrMockError
+	}
+	return imageTag + "@" + MockDigest, nil
+}
+
 // Version returns a static version
 func (m MockSBOMAdapter) Version() string {
 	logger.L().Info("MockSBOMAdapter.Version")
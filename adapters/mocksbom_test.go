@@ -27,7 +27,24 @@ func TestMockSBOMAdapter_CreateSBOM_Timeout(t *testing.T) {
 	assert.Equal(t, instanceidhandler.Incomplete, sbom.Status)
 }
 
+func TestMockSBOMAdapter_CreateLayerDiffSBOMs(t *testing.T) {
+	m := NewMockSBOMAdapter(false, false, false)
+	sboms, err := m.CreateLayerDiffSBOMs(context.TODO(), "base", "image", domain.RegistryOptions{})
+	assert.NoError(t, err)
+	assert.NotNil(t, sboms.Base.Content)
+	assert.NotNil(t, sboms.Image.Content)
+	_, err = NewMockSBOMAdapter(true, false, false).CreateLayerDiffSBOMs(context.TODO(), "base", "image", domain.RegistryOptions{})
+	assert.Error(t, err)
+}
+
 func TestMockSBOMAdapter_Version(t *testing.T) {
 	m := NewMockSBOMAdapter(false, false, false)
 	assert.Equal(t, "Mock SBOM 1.0", m.Version())
 }
+
+func TestMockSBOMAdapter_ResolveDigest(t *testing.T) {
+	m := NewMockSBOMAdapter(false, false, false)
+	digest, err := m.ResolveDigest(context.TODO(), "nginx:latest", domain.RegistryOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx:latest@"+MockDigest, digest)
+}
@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"context"
+	"strings"
+
+	wlidpkg "github.com/armosec/utils-k8s-go/wlid"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ ports.WorkloadAnnotator = (*WorkloadAdapter)(nil)
+
+// WorkloadAnnotations returns the annotations of a Deployment, StatefulSet, DaemonSet, ReplicaSet, Job, CronJob or Pod
+// merged with the ones of its pod template, which take precedence; deleted workloads and the other kinds have none
+func (w *WorkloadAdapter) WorkloadAnnotations(ctx context.Context, wlid string) (map[string]string, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "WorkloadAdapter.WorkloadAnnotations")
+	defer span.End()
+	namespace, name := wlidpkg.GetNamespaceFromWlid(wlid), wlidpkg.GetNameFromWlid(wlid)
+	// the annotations of the workload, then the ones of its pod template
+	var layers [2]map[string]string
+	var err error
+	switch strings.ToLower(wlidpkg.GetKindFromWlid(wlid)) {
+	case "deployment":
+		deployment, getErr := w.client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			layers = [2]map[string]string{deployment.Annotations, deployment.Spec.Template.Annotations}
+		}
+	case "statefulset":
+		statefulSet, getErr := w.client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			layers = [2]map[string]string{statefulSet.Annotations, statefulSet.Spec.Template.Annotations}
+		}
+	case "daemonset":
+		daemonSet, getErr := w.client.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			layers = [2]map[string]string{daemonSet.Annotations, daemonSet.Spec.Template.Annotations}
+		}
+	case "replicaset":
+		replicaSet, getErr := w.client.AppsV1().ReplicaSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			layers = [2]map[string]string{replicaSet.Annotations, replicaSet.Spec.Template.Annotations}
+		}
+	case "job":
+		job, getErr := w.client.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			layers = [2]map[string]string{job.Annotations, job.Spec.Template.Annotations}
+		}
+	case "cronjob":
+		cronJob, getErr := w.client.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			layers = [2]map[string]string{cronJob.Annotations, cronJob.Spec.JobTemplate.Spec.Template.Annotations}
+		}
+	case "pod":
+		pod, getErr := w.client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err = getErr; err == nil {
+			layers[0] = pod.Annotations
+		}
+	default:
+		return nil, nil
+	}
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	annotations := map[string]string{}
+	for _, layer := range layers {
+		for key, value := range layer {
+			annotations[key] = value
+		}
+	}
+	return annotations, nil
+}
@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWorkloadAdapter_WorkloadAnnotations(t *testing.T) {
+	deployment := newDeployment("nginx", false)
+	deployment.Annotations = map[string]string{"kubescape.io/scan": "false", "owner": "team-a"}
+	deployment.Spec.Template.Annotations = map[string]string{"kubescape.io/scan": "true"}
+	client := fake.NewSimpleClientset(
+		deployment,
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default", Annotations: map[string]string{"kubescape.io/scan": "false"}}},
+	)
+	w := NewWorkloadAdapter(client)
+	ctx := context.TODO()
+
+	// the annotations of the pod template take precedence
+	annotations, err := w.WorkloadAnnotations(ctx, "wlid://cluster-minikube/namespace-default/deployment-nginx")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"kubescape.io/scan": "true", "owner": "team-a"}, annotations)
+
+	annotations, err = w.WorkloadAnnotations(ctx, "wlid://cluster-minikube/namespace-default/pod-debug")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"kubescape.io/scan": "false"}, annotations)
+
+	// deleted workloads and unsupported kinds have no annotations
+	annotations, err = w.WorkloadAnnotations(ctx, "wlid://cluster-minikube/namespace-default/statefulset-deleted")
+	require.NoError(t, err)
+	assert.Empty(t, annotations)
+	annotations, err = w.WorkloadAnnotations(ctx, "wlid://cluster-minikube/namespace-default/rollout-nginx")
+	require.NoError(t, err)
+	assert.Empty(t, annotations)
+}
@@ -2,11 +2,15 @@ package v1
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	wssc "github.com/armosec/armoapi-go/apis"
 	"github.com/armosec/armoapi-go/armotypes"
@@ -17,50 +21,122 @@ import (
 	pkgcautils "github.com/armosec/utils-k8s-go/armometadata"
 	wlidpkg "github.com/armosec/utils-k8s-go/wlid"
 	"github.com/hashicorp/go-multierror"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
 	"github.com/kubescape/kubevuln/core/domain"
 	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/internal/tools"
 	"go.opentelemetry.io/otel"
 )
 
 type ArmoAdapter struct {
-	clusterConfig        pkgcautils.ClusterConfig
-	getCVEExceptionsFunc func(string, string, *armotypes.PortalDesignator) ([]armotypes.VulnerabilityExceptionPolicy, error)
-	httpPostFunc         func(httputils.IHttpClient, string, map[string]string, []byte) (*http.Response, error)
-	sendStatusFunc       func(*sysreport.BaseReport, string, bool, chan<- error)
+	anonymizer               *anonymizer
+	breaker                  submissionBreaker
+	clusterConfig            pkgcautils.ClusterConfig
+	contextAttributes        map[string]string
+	endpoints                receiverEndpoints
+	filterTimeout            time.Duration
+	legacySBOMMaxSize        int64
+	namespaceLabelAttributes map[string]string
+	compression              reportCompression
+	negotiatedAt             time.Time
+	negotiatedVersion        string
+	quota                    submissionQuota
+	replaying                atomic.Bool
+	reportVersion            string
+	spoolDir                 string
+	spoolMaxReports          int
+	spoolMaxSize             int64
+	spoolMu                  sync.Mutex
+	spooled                  atomic.Int64
+	submitOrdering           string
+	uniqueImages             *uniqueImages
+	versionMu                sync.Mutex
+	getCVEExceptionsFunc     func(string, string, *armotypes.PortalDesignator) ([]armotypes.VulnerabilityExceptionPolicy, error)
+	getNamespaceLabelsFunc   func(context.Context, string) (map[string]string, error)
+	httpGetFunc              func(httputils.IHttpClient, string, map[string]string) (*http.Response, error)
+	httpPostFunc             func(httputils.IHttpClient, string, map[string]string, []byte) (*http.Response, error)
+	sendStatusFunc           func(*sysreport.BaseReport, string, bool, chan<- error)
 }
 
 var _ ports.Platform = (*ArmoAdapter)(nil)
 
-func NewArmoAdapter(accountID, gatewayRestURL, eventReceiverRestURL string) *ArmoAdapter {
-	return &ArmoAdapter{
+// WithFilterTimeout sets the timeout budget of the exceptions retrieval used for filtering, zero disables it
+func WithFilterTimeout(filterTimeout time.Duration) ArmoAdapterOption {
+	return func(a *ArmoAdapter) {
+		a.filterTimeout = filterTimeout
+	}
+}
+
+func NewArmoAdapter(accountID, gatewayRestURL, eventReceiverRestURL string, opts ...ArmoAdapterOption) *ArmoAdapter {
+	a := &ArmoAdapter{
 		clusterConfig: pkgcautils.ClusterConfig{
 			AccountID:            accountID,
 			EventReceiverRestURL: eventReceiverRestURL,
 			GatewayRestURL:       gatewayRestURL,
 		},
 		getCVEExceptionsFunc: wssc.BackendGetCVEExceptionByDEsignator,
+		httpGetFunc:          httputils.HttpGet,
 		httpPostFunc:         httputils.HttpPost,
 		sendStatusFunc: func(report *sysreport.BaseReport, status string, sendReport bool, errChan chan<- error) {
 			report.SendStatus(status, sendReport, errChan)
 		},
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
 const ActionName = "vuln scan"
 const ReporterName = "ca-vuln-scan"
 const maxBodySize int = 30000
 
+const (
+	attributeArtifactType       = "artifactType"
+	attributeCVEDBBuilt         = "cveDBBuilt"
+	attributeCVEDBVersion       = "cveDBVersion"
+	attributeCVEScannerVersion  = "cveScannerVersion"
+	attributeEmbeddedImages     = "embeddedImages"
+	attributeExploitAvailable   = "exploitAvailable"
+	attributeExploitSources     = "exploitSources"
+	attributeFailedCatalogers   = "failedCatalogers"
+	attributeGoldenBase         = "goldenBase"
+	attributeGoldenBaseVersion  = "goldenBaseVersion"
+	attributeImageCreated       = "imageCreated"
+	attributeImageRiskScore     = "imageRiskScore"
+	attributeImageTooOld        = "imageTooOld"
+	attributeMatchExplanation   = "matchExplanation"
+	attributePartial            = "partial"
+	attributePreviousDigest     = "previousImageDigest"
+	attributeRelayedFrom        = "relayedFrom"
+	attributeRepositoryCommit   = "repositoryCommit"
+	attributeRepositoryRef      = "repositoryRef"
+	attributeRepositoryURL      = "repositoryURL"
+	attributeSBOMCreatorVersion = "sbomCreatorVersion"
+	attributeScanProvenance     = "scanProvenance"
+	attributeSnoozedUntil       = "snoozedUntil"
+	attributeTagMutated         = "tagMutated"
+	attributeTriage             = "triage"
+)
+
 var details = []string{
 	sysreport.JobStarted,
 	sysreport.JobStarted,
 	sysreport.JobSuccess,
 	sysreport.JobDone,
+	"skipped-by-annotation",
+	"skipped-trusted-digest",
+	sysreport.JobFailed,
 }
 var statuses = []string{
 	"Inqueueing",
 	"Dequeueing",
 	"Dequeueing",
 	"Dequeueing",
+	"Dequeueing",
+	"Dequeueing",
+	"Dequeueing",
 }
 
 func (a *ArmoAdapter) GetCVEExceptions(ctx context.Context) (domain.CVEExceptions, error) {
@@ -72,22 +148,45 @@ func (a *ArmoAdapter) GetCVEExceptions(ctx context.Context) (domain.CVEException
 	if !ok {
 		return nil, domain.ErrCastingWorkload
 	}
+	// the exceptions are scoped to the workloads as the platform knows them
+	wlid, err := a.anonymizeWlid(ctx, workload.Wlid)
+	if err != nil {
+		return nil, err
+	}
 
 	designator := armotypes.PortalDesignator{
 		DesignatorType: armotypes.DesignatorAttribute,
 		Attributes: map[string]string{
 			"customerGUID":        a.clusterConfig.AccountID,
-			"scope.cluster":       wlidpkg.GetClusterFromWlid(workload.Wlid),
-			"scope.namespace":     wlidpkg.GetNamespaceFromWlid(workload.Wlid),
-			"scope.kind":          strings.ToLower(wlidpkg.GetKindFromWlid(workload.Wlid)),
-			"scope.name":          wlidpkg.GetNameFromWlid(workload.Wlid),
+			"scope.cluster":       wlidpkg.GetClusterFromWlid(wlid),
+			"scope.namespace":     wlidpkg.GetNamespaceFromWlid(wlid),
+			"scope.kind":          strings.ToLower(wlidpkg.GetKindFromWlid(wlid)),
+			"scope.name":          wlidpkg.GetNameFromWlid(wlid),
 			"scope.containerName": workload.ContainerName,
 		},
 	}
 
-	vulnExceptionList, err := a.getCVEExceptionsFunc(a.clusterConfig.GatewayRestURL, a.clusterConfig.AccountID, &designator)
-	if err != nil {
-		return nil, err
+	// the backend client takes no context, the request is abandoned once ctx is done
+	type exceptions struct {
+		list []armotypes.VulnerabilityExceptionPolicy
+		err  error
+	}
+	done := make(chan exceptions, 1)
+	go func() {
+		var e exceptions
+		defer func() { done <- e }()
+		defer tools.RecoverPanic(ctx, &e.err)
+		e.list, e.err = a.getCVEExceptionsFunc(a.clusterConfig.GatewayRestURL, a.clusterConfig.AccountID, &designator)
+	}()
+	var vulnExceptionList []armotypes.VulnerabilityExceptionPolicy
+	select {
+	case e := <-done:
+		if e.err != nil {
+			return nil, e.err
+		}
+		vulnExceptionList = e.list
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 	return vulnExceptionList, nil
 }
@@ -101,34 +200,179 @@ func (a *ArmoAdapter) SendStatus(ctx context.Context, step int) error {
 	if !ok {
 		return domain.ErrCastingWorkload
 	}
+	wlid, err := a.anonymizeWlid(ctx, workload.Wlid)
+	if err != nil {
+		return err
+	}
 
 	lastAction := workload.LastAction + 1
 	report := sysreport.NewBaseReport(
 		a.clusterConfig.AccountID,
 		ReporterName,
-		a.clusterConfig.EventReceiverRestURL,
-		&http.Client{},
+		a.eventReceiverURL(),
+		backendClient,
 	)
 	report.Status = statuses[step]
 	report.Target = fmt.Sprintf("vuln scan:: scanning wlid: %v , container: %v imageTag: %v imageHash: %s",
-		workload.Wlid, workload.ContainerName, workload.ImageTagNormalized, workload.ImageHash)
+		wlid, workload.ContainerName, workload.ImageTagNormalized, workload.ImageHash)
 	report.ActionID = strconv.Itoa(lastAction)
 	report.ActionIDN = lastAction
 	report.ActionName = ActionName
 	report.JobID = workload.JobID
 	report.ParentAction = workload.ParentJobID
 	report.Details = details[step]
+	if version, ok := ctx.Value(domain.TrustedDigestsVersionKey{}).(string); ok && step == domain.Trusted {
+		report.Details += " (allowlist " + version + ")"
+	}
+	if code, ok := ctx.Value(domain.ErrorCodeKey{}).(string); ok && step == domain.Failed {
+		report.Details += " (" + code + ")"
+	}
 
-	ReportErrorsChan := make(chan error)
+	// the report is sent in the background, it is abandoned once ctx is done
+	ReportErrorsChan := make(chan error, 1)
 	a.sendStatusFunc(report, sysreport.JobSuccess, true, ReportErrorsChan)
-	err := <-ReportErrorsChan
-	return err
+	select {
+	case err := <-ReportErrorsChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// injectArtifactAttributes adds the type of the scanned OCI artifact to the designators, missing for container images
+func injectArtifactAttributes(annotations, attributes map[string]string) {
+	if artifactType, ok := annotations[domain.AnnotationArtifactType]; ok {
+		attributes[attributeArtifactType] = artifactType
+	}
+}
+
+// injectEmbeddedAttributes adds the images embedded in the image to the designators, separated by commas
+func injectEmbeddedAttributes(annotations, attributes map[string]string) {
+	var embedded []domain.EmbeddedImage
+	if err := json.Unmarshal([]byte(annotations[domain.AnnotationEmbeddedImages]), &embedded); err != nil {
+		return
+	}
+	images := make([]string, 0, len(embedded))
+	for _, image := range embedded {
+		images = append(images, image.Image)
+	}
+	attributes[attributeEmbeddedImages] = strings.Join(images, ",")
+}
+
+// injectGoldenBaseAttributes adds the status of the base image against the golden base catalog, and the version of
+// the catalog, to the designators
+func injectGoldenBaseAttributes(annotations, attributes map[string]string) {
+	if status, ok := annotations[domain.AnnotationGoldenBase]; ok {
+		attributes[attributeGoldenBase] = status
+		attributes[attributeGoldenBaseVersion] = annotations[domain.AnnotationGoldenBaseVersion]
+	}
+}
+
+// injectPartialAttributes flags the reports missing packages of the image, with the catalogers which failed separated
+// by commas
+func injectPartialAttributes(annotations, attributes map[string]string) {
+	if annotations[domain.AnnotationPartial] != "true" {
+		return
+	}
+	attributes[attributePartial] = "true"
+	var statuses []domain.CatalogerStatus
+	if err := json.Unmarshal([]byte(annotations[domain.AnnotationCatalogerStatus]), &statuses); err != nil {
+		return
+	}
+	var failed []string
+	for _, status := range statuses {
+		if status.Error != "" {
+			failed = append(failed, status.Name)
+		}
+	}
+	if len(failed) > 0 {
+		attributes[attributeFailedCatalogers] = strings.Join(failed, ",")
+	}
+}
+
+// injectProvenanceAttributes adds the image creation timestamp, age flag and OCI labels to the designators
+func injectProvenanceAttributes(annotations, attributes map[string]string) {
+	if created, ok := annotations[domain.AnnotationImageCreated]; ok {
+		attributes[attributeImageCreated] = created
+	}
+	if annotations[domain.AnnotationImageTooOld] == "true" {
+		attributes[attributeImageTooOld] = "true"
+	}
+	for key, value := range annotations {
+		if strings.HasPrefix(key, domain.OCILabelPrefix) {
+			attributes[key] = value
+		}
+	}
+}
+
+// injectRelayAttributes adds the edge cluster a relayed SBOM was created in to the designators
+func injectRelayAttributes(annotations, attributes map[string]string) {
+	if cluster, ok := annotations[domain.AnnotationRelayedFrom]; ok {
+		attributes[attributeRelayedFrom] = cluster
+	}
 }
 
-// SubmitCVE submits the given CVE to the platform
+// injectRepositoryAttributes adds the URL, ref and commit of the scanned git repository to the designators
+func injectRepositoryAttributes(annotations, attributes map[string]string) {
+	for key, annotation := range map[string]string{
+		attributeRepositoryCommit: domain.AnnotationRepositoryCommit,
+		attributeRepositoryRef:    domain.AnnotationRepositoryRef,
+		attributeRepositoryURL:    domain.AnnotationRepositoryURL,
+	} {
+		if value, ok := annotations[annotation]; ok {
+			attributes[key] = value
+		}
+	}
+}
+
+// injectRiskAttributes adds the risk score of the image to the designators, ranking the images of the summaries
+func injectRiskAttributes(annotations, attributes map[string]string) {
+	if riskScore, ok := annotations[domain.AnnotationRiskScore]; ok {
+		attributes[attributeImageRiskScore] = riskScore
+	}
+}
+
+// injectScanProvenanceAttributes adds the scan provenance, as recorded in the annotations, to the designators
+func injectScanProvenanceAttributes(annotations, attributes map[string]string) {
+	if provenance, ok := annotations[domain.AnnotationScanProvenance]; ok {
+		attributes[attributeScanProvenance] = provenance
+	}
+}
+
+// injectVersionAttributes adds the versions of the scanners and of the vulnerabilities database to the designators
+func injectVersionAttributes(cve domain.CVEManifest, attributes map[string]string) {
+	for key, value := range map[string]string{
+		attributeSBOMCreatorVersion: cve.SBOMCreatorVersion,
+		attributeCVEScannerVersion:  cve.CVEScannerVersion,
+		attributeCVEDBVersion:       cve.CVEDBVersion,
+		attributeCVEDBBuilt:         cve.Annotations[domain.AnnotationCVEDBBuilt],
+	} {
+		if value != "" {
+			attributes[key] = value
+		}
+	}
+}
+
+// SubmitCVE submits the given CVE to the platform, or spools it while the circuit breaker of the event receiver is open
 func (a *ArmoAdapter) SubmitCVE(ctx context.Context, cve domain.CVEManifest, cvep domain.CVEManifest) error {
 	ctx, span := otel.Tracer("").Start(ctx, "ArmoAdapter.SubmitCVE")
 	defer span.End()
+
+	allowed, probe := a.breaker.allow()
+	if probe {
+		defer a.breaker.done()
+	}
+	if allowed {
+		err := a.submitCVE(ctx, cve, cvep)
+		if !errors.Is(err, domain.ErrCircuitOpen) {
+			return domain.Categorize(domain.ErrSubmit, err)
+		}
+	}
+	return domain.Categorize(domain.ErrSubmit, a.spoolSubmission(ctx, cve, cvep))
+}
+
+// submitCVE converts the given CVE to the reports of the platform and posts them
+func (a *ArmoAdapter) submitCVE(ctx context.Context, cve domain.CVEManifest, cvep domain.CVEManifest) error {
 	// retrieve timestamp from context
 	timestamp, ok := ctx.Value(domain.TimestampKey{}).(int64)
 	if !ok {
@@ -150,13 +394,31 @@ func (a *ArmoAdapter) SubmitCVE(ctx context.Context, cve domain.CVEManifest, cve
 		return domain.ErrInvalidScanID
 	}
 
-	// get exceptions
-	exceptions, err := a.GetCVEExceptions(ctx)
+	// the workload identifiers leave the cluster pseudonymized in privacy mode
+	submitted := workload
+	wlid, err := a.anonymizeWlid(ctx, workload.Wlid)
+	if err != nil {
+		return err
+	}
+	submitted.Wlid = wlid
+	submittedCtx := context.WithValue(ctx, domain.WorkloadKey{}, submitted)
+
+	// the reports of the scans nobody waits for, without job, give way to the others when the quota is low
+	if err := a.quota.wait(ctx, workload.JobID == ""); err != nil {
+		return err
+	}
+
+	// get exceptions within the filtering timeout budget
+	exceptions, err := tools.RunWithTimeout(ctx, domain.StageFilter, a.filterTimeout, a.GetCVEExceptions)
 	if err != nil {
 		return err
 	}
 	// convert to vulnerabilities
-	vulnerabilities, err := domainToArmo(ctx, *cve.Content, exceptions)
+	vulnerabilities, err := domainToArmo(submittedCtx, *cve.Content, exceptions)
+	if err != nil {
+		return err
+	}
+	vulnerabilities, err = withFalsePositives(submittedCtx, vulnerabilities, cve)
 	if err != nil {
 		return err
 	}
@@ -165,7 +427,11 @@ func (a *ArmoAdapter) SubmitCVE(ctx context.Context, cve domain.CVEManifest, cve
 	if cvep.Content != nil {
 		hasRelevancy = true
 		// convert to relevantVulnerabilities
-		relevantVulnerabilities, err := domainToArmo(ctx, *cvep.Content, exceptions)
+		relevantVulnerabilities, err := domainToArmo(submittedCtx, *cvep.Content, exceptions)
+		if err != nil {
+			return err
+		}
+		relevantVulnerabilities, err = withFalsePositives(submittedCtx, relevantVulnerabilities, cvep)
 		if err != nil {
 			return err
 		}
@@ -181,8 +447,11 @@ func (a *ArmoAdapter) SubmitCVE(ctx context.Context, cve domain.CVEManifest, cve
 		}
 	}
 
+	// drop the entries the platform cannot process
+	vulnerabilities = sanitizeVulnerabilities(ctx, vulnerabilities)
+
 	finalReport := v1.ScanResultReport{
-		Designators:     *armotypes.AttributesDesignatorsFromWLID(workload.Wlid),
+		Designators:     *armotypes.AttributesDesignatorsFromWLID(submitted.Wlid),
 		Summary:         nil,
 		ContainerScanID: scanID,
 		Timestamp:       timestamp,
@@ -204,20 +473,56 @@ func (a *ArmoAdapter) SubmitCVE(ctx context.Context, cve domain.CVEManifest, cve
 	if val, ok := workload.Args[armotypes.AttributeSensor]; ok {
 		finalReport.Designators.Attributes[armotypes.AttributeSensor] = val.(string)
 	}
+	a.injectContextAttributes(ctx, workload.Wlid, finalReport.Designators.Attributes)
+	if previousDigest, ok := cve.Annotations[domain.AnnotationPreviousDigest]; ok {
+		finalReport.Designators.Attributes[attributeTagMutated] = "true"
+		finalReport.Designators.Attributes[attributePreviousDigest] = previousDigest
+	}
+	injectArtifactAttributes(cve.Annotations, finalReport.Designators.Attributes)
+	injectPartialAttributes(cve.Annotations, finalReport.Designators.Attributes)
+	injectEmbeddedAttributes(cve.Annotations, finalReport.Designators.Attributes)
+	injectGoldenBaseAttributes(cve.Annotations, finalReport.Designators.Attributes)
+	injectProvenanceAttributes(cve.Annotations, finalReport.Designators.Attributes)
+	injectRelayAttributes(cve.Annotations, finalReport.Designators.Attributes)
+	injectRepositoryAttributes(cve.Annotations, finalReport.Designators.Attributes)
+	injectRiskAttributes(cve.Annotations, finalReport.Designators.Attributes)
+	injectScanProvenanceAttributes(cve.Annotations, finalReport.Designators.Attributes)
+	injectVersionAttributes(cve, finalReport.Designators.Attributes)
 
 	// fill context and designators into vulnerabilities
 	armoContext := armotypes.DesignatorToArmoContext(&finalReport.Designators, "designators")
 	for i := range vulnerabilities {
 		vulnerabilities[i].Context = armoContext
-		vulnerabilities[i].Designators = finalReport.Designators
+		attributes := withMatchExplanation(vulnerabilityAttributes(cve, vulnerabilities[i].Name), cve, vulnerabilities[i].Vulnerability)
+		attributes = withTriageAttributes(attributes, cve, vulnerabilities[i].Vulnerability)
+		vulnerabilities[i].Designators = withVulnerabilityAttributes(finalReport.Designators, attributes)
 	}
 
 	// add summary
-	finalReport.Summary, vulnerabilities = summarize(finalReport, vulnerabilities, workload, hasRelevancy)
+	finalReport.Summary, vulnerabilities = summarize(finalReport, vulnerabilities, submitted, hasRelevancy)
 	finalReport.Summary.Context = armoContext
+	if err := verifySummary(finalReport.Summary, vulnerabilities); err != nil {
+		logger.L().Ctx(ctx).Error("report summary does not match its vulnerabilities", helpers.Error(err),
+			helpers.String("wlid", workload.Wlid))
+	}
+
+	// the legacy event receiver takes the whole report at once
+	if a.getReportVersion(ctx) == ReportVersionV1 {
+		return a.postLegacyReport(ctx, finalReport, vulnerabilities)
+	}
+
+	// the vulnerabilities of an image already submitted for another workload are referenced rather than sent again
+	var fingerprint string
+	if a.uniqueImages != nil {
+		fingerprint = vulnerabilitiesFingerprint(finalReport, vulnerabilities)
+		if imageScanID, ok := a.uniqueImages.reported(workload.ImageHash, fingerprint, time.Now()); ok {
+			return a.fallbackToLegacyReport(ctx, a.postImageAssociation(ctx, finalReport, imageScanID), finalReport, vulnerabilities)
+		}
+	}
 
 	// split vulnerabilities to chunks
-	chunksChan, totalVulnerabilities := httputils.SplitSlice2Chunks(vulnerabilities, maxBodySize, 10)
+	chunksChan, totalVulnerabilities := splitVulnerabilities(finalReport, vulnerabilities, maxBodySize)
+	ctx = withSubmitProgress(ctx, totalVulnerabilities)
 
 	// send report(s)
 	sendWG := &sync.WaitGroup{}
@@ -225,8 +530,14 @@ func (a *ArmoAdapter) SubmitCVE(ctx context.Context, cve domain.CVEManifest, cve
 	// get the first chunk
 	firstVulnerabilitiesChunk := <-chunksChan
 	firstChunkVulnerabilitiesCount := len(firstVulnerabilitiesChunk)
-	// send the summary and the first chunk in one or two reports according to the size
-	nextPartNum := a.sendSummaryAndVulnerabilities(ctx, &finalReport, a.clusterConfig.EventReceiverRestURL, totalVulnerabilities, scanID, firstVulnerabilitiesChunk, errChan, sendWG)
+	// send the whole report at once, or the summary followed by the first chunk
+	nextPartNum, err := a.sendSummaryAndVulnerabilities(ctx, &finalReport, a.clusterConfig.EventReceiverRestURL, totalVulnerabilities, scanID, firstVulnerabilitiesChunk, errChan, sendWG)
+	if err != nil {
+		// the vulnerabilities are not sent without their summary, release the splitting goroutine
+		for range chunksChan {
+		}
+		return a.fallbackToLegacyReport(ctx, err, finalReport, vulnerabilities)
+	}
 	// if not all vulnerabilities got into the first chunk
 	if totalVulnerabilities != firstChunkVulnerabilitiesCount {
 		//send the rest of the vulnerabilities - error channel will be closed when all vulnerabilities are sent
@@ -245,5 +556,24 @@ func (a *ArmoAdapter) SubmitCVE(ctx context.Context, cve domain.CVEManifest, cve
 	for e := range errChan {
 		err = multierror.Append(err, e)
 	}
+	// only the first post tells whether the event receiver serves v2 reports, a later chunk rejected after the
+	// summary was accepted must not send the report again
+	if nextPartNum == 1 {
+		err = a.fallbackToLegacyReport(ctx, err, finalReport, vulnerabilities)
+	}
+	if err == nil && fingerprint != "" {
+		a.uniqueImages.record(workload.ImageHash, fingerprint, scanID, time.Now())
+	}
+	return err
+}
+
+// fallbackToLegacyReport downgrades to the legacy report if the event receiver does not know the v2 endpoint, err must
+// be the outcome of the first post of the report
+func (a *ArmoAdapter) fallbackToLegacyReport(ctx context.Context, err error, finalReport v1.ScanResultReport, vulnerabilities []cs.CommonContainerVulnerabilityResult) error {
+	if errors.Is(err, errReportVersionNotFound) && a.reportVersion == "" {
+		logger.L().Ctx(ctx).Warning("event receiver does not support v2 reports, falling back to v1")
+		a.setReportVersion(ReportVersionV1)
+		return a.postLegacyReport(ctx, finalReport, vulnerabilities)
+	}
 	return err
 }
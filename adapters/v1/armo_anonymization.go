@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	wlidpkg "github.com/armosec/utils-k8s-go/wlid"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+)
+
+const (
+	// pseudonymPrefix tells the pseudonymized namespaces and workload names from the original ones
+	pseudonymPrefix = "anon-"
+	// pseudonymLength is the number of hexadecimal digits of the HMAC kept in a pseudonym
+	pseudonymLength = 16
+)
+
+// anonymizer pseudonymizes the workload identifiers with a keyed hash, the original values are stored in-cluster
+type anonymizer struct {
+	salt       []byte
+	repository ports.PseudonymRepository
+	// stored are the pseudonyms already stored, they are not stored again
+	stored sync.Map
+}
+
+// WithAnonymization enables the privacy mode: the namespaces and the names of the workloads are submitted as
+// pseudonyms, a keyed hash with the salt of the tenant, whose original values are stored in repository
+func WithAnonymization(salt []byte, repository ports.PseudonymRepository) ArmoAdapterOption {
+	return func(a *ArmoAdapter) {
+		a.anonymizer = &anonymizer{salt: salt, repository: repository}
+	}
+}
+
+// pseudonym returns the pseudonym of value, the same for a given salt
+func (an *anonymizer) pseudonym(value string) string {
+	mac := hmac.New(sha256.New, an.salt)
+	mac.Write([]byte(value))
+	return pseudonymPrefix + hex.EncodeToString(mac.Sum(nil))[:pseudonymLength]
+}
+
+// anonymizeWlid returns the workload ID submitted for wlid: the same without privacy mode, otherwise with the namespace
+// and the name replaced by their pseudonyms. Nothing is submitted unless the pseudonyms can be resolved in-cluster,
+// but for a full pseudonyms store: the scans are then submitted with pseudonyms which cannot be resolved.
+func (a *ArmoAdapter) anonymizeWlid(ctx context.Context, wlid string) (string, error) {
+	if a.anonymizer == nil || wlid == "" {
+		return wlid, nil
+	}
+	// the pseudonyms of the namespace and the name
+	var pseudonyms [2]string
+	for i, value := range []string{wlidpkg.GetNamespaceFromWlid(wlid), wlidpkg.GetNameFromWlid(wlid)} {
+		if value == "" {
+			continue
+		}
+		pseudonyms[i] = a.anonymizer.pseudonym(value)
+		if _, ok := a.anonymizer.stored.Load(pseudonyms[i]); ok {
+			continue
+		}
+		err := a.anonymizer.repository.StorePseudonym(ctx, pseudonyms[i], value)
+		switch {
+		case errors.Is(err, domain.ErrPseudonymStoreFull):
+			logger.L().Ctx(ctx).Warning("pseudonym not stored, it cannot be resolved in-cluster", helpers.Error(err),
+				helpers.String("pseudonym", pseudonyms[i]))
+		case err != nil:
+			return "", fmt.Errorf("failed to store pseudonym: %w", err)
+		}
+		a.anonymizer.stored.Store(pseudonyms[i], struct{}{})
+	}
+	return wlidpkg.GetK8sWLID(wlidpkg.GetClusterFromWlid(wlid), pseudonyms[0], wlidpkg.GetKindFromWlid(wlid), pseudonyms[1]), nil
+}
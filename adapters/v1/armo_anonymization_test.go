@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/armosec/utils-go/httputils"
+	"github.com/google/uuid"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pseudonymRepository records the stored pseudonyms
+type pseudonymRepository struct {
+	mu         sync.Mutex
+	err        error
+	calls      int
+	pseudonyms map[string]string
+}
+
+func (r *pseudonymRepository) StorePseudonym(_ context.Context, pseudonym, original string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.err != nil {
+		return r.err
+	}
+	if r.pseudonyms == nil {
+		r.pseudonyms = map[string]string{}
+	}
+	r.pseudonyms[pseudonym] = original
+	return nil
+}
+
+func Test_anonymizer_pseudonym(t *testing.T) {
+	an := &anonymizer{salt: []byte("tenant-a")}
+	pseudonym := an.pseudonym("default")
+	assert.True(t, strings.HasPrefix(pseudonym, pseudonymPrefix))
+	assert.Len(t, pseudonym, len(pseudonymPrefix)+pseudonymLength)
+	assert.Equal(t, pseudonym, an.pseudonym("default"))
+	assert.NotEqual(t, pseudonym, an.pseudonym("kube-system"))
+	// the pseudonyms differ from one tenant to another
+	assert.NotEqual(t, pseudonym, (&anonymizer{salt: []byte("tenant-b")}).pseudonym("default"))
+}
+
+func TestArmoAdapter_anonymizeWlid(t *testing.T) {
+	ctx := context.TODO()
+	const wlid = "wlid://cluster-minikube/namespace-default/deployment-nginx"
+
+	a := &ArmoAdapter{}
+	got, err := a.anonymizeWlid(ctx, wlid)
+	require.NoError(t, err)
+	assert.Equal(t, wlid, got)
+
+	repository := &pseudonymRepository{}
+	a = NewArmoAdapter("", "", "", WithAnonymization([]byte("tenant-a"), repository))
+	got, err = a.anonymizeWlid(ctx, wlid)
+	require.NoError(t, err)
+	namespace, name := a.anonymizer.pseudonym("default"), a.anonymizer.pseudonym("nginx")
+	assert.Equal(t, "wlid://cluster-minikube/namespace-"+namespace+"/deployment-"+name, got)
+	assert.Equal(t, map[string]string{namespace: "default", name: "nginx"}, repository.pseudonyms)
+	// the designators of the platform are read from the pseudonymized workload ID
+	assert.Equal(t, map[string]string{
+		armotypes.AttributeCluster:   "minikube",
+		armotypes.AttributeNamespace: namespace,
+		armotypes.AttributeKind:      "deployment",
+		armotypes.AttributeName:      name,
+	}, armotypes.AttributesDesignatorsFromWLID(got).Attributes)
+	// the pseudonyms are stored once
+	_, err = a.anonymizeWlid(ctx, wlid)
+	require.NoError(t, err)
+	assert.Equal(t, 2, repository.calls)
+
+	// nothing is submitted unless the pseudonyms can be resolved
+	a = NewArmoAdapter("", "", "", WithAnonymization([]byte("tenant-a"), &pseudonymRepository{err: domain.ErrMockError}))
+	_, err = a.anonymizeWlid(ctx, wlid)
+	assert.ErrorIs(t, err, domain.ErrMockError)
+
+	// but for a full store, the scans are still submitted with their pseudonyms
+	full := &pseudonymRepository{err: domain.ErrPseudonymStoreFull}
+	a = NewArmoAdapter("", "", "", WithAnonymization([]byte("tenant-a"), full))
+	got, err = a.anonymizeWlid(ctx, wlid)
+	require.NoError(t, err)
+	assert.Equal(t, "wlid://cluster-minikube/namespace-"+namespace+"/deployment-"+name, got)
+	_, err = a.anonymizeWlid(ctx, wlid)
+	require.NoError(t, err)
+	assert.Equal(t, 2, full.calls)
+}
+
+func TestArmoAdapter_SubmitCVE_anonymized(t *testing.T) {
+	mu := &sync.Mutex{}
+	var bodies []string
+	repository := &pseudonymRepository{}
+	a := NewArmoAdapter("", "", "", WithAnonymization([]byte("tenant-a"), repository), WithReportVersion(ReportVersionV2))
+	a.getCVEExceptionsFunc = func(_ string, _ string, designator *armotypes.PortalDesignator) ([]armotypes.VulnerabilityExceptionPolicy, error) {
+		assert.Equal(t, a.anonymizer.pseudonym("default"), designator.Attributes["scope.namespace"])
+		return nil, nil
+	}
+	a.httpPostFunc = func(_ httputils.IHttpClient, _ string, _ map[string]string, body []byte) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, string(body))
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(bytes.NewBuffer([]byte{}))}, nil
+	}
+	ctx := context.TODO()
+	ctx = context.WithValue(ctx, domain.TimestampKey{}, time.Now().Unix())
+	ctx = context.WithValue(ctx, domain.ScanIDKey{}, uuid.New().String())
+	ctx = context.WithValue(ctx, domain.WorkloadKey{}, domain.ScanCommand{
+		JobID: "jobID",
+		Wlid:  "wlid://cluster-minikube/namespace-default/deployment-nginx",
+	})
+	require.NoError(t, a.SubmitCVE(ctx, fileToCVEManifest("testdata/nginx-cve-small.json"), domain.CVEManifest{}))
+	require.NotEmpty(t, bodies)
+	for _, body := range bodies {
+		assert.NotContains(t, body, "namespace-default")
+		assert.NotContains(t, body, `"namespace":"default"`)
+		assert.NotContains(t, body, `"name":"nginx"`)
+		assert.Contains(t, body, a.anonymizer.pseudonym("default"))
+	}
+}
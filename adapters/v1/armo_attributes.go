@@ -0,0 +1,161 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akyoto/cache"
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+	wlidpkg "github.com/armosec/utils-k8s-go/wlid"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const namespaceLabelsTTL = 10 * time.Minute
+
+// ArmoAdapterOption configures optional behaviors of the ArmoAdapter
+type ArmoAdapterOption func(*ArmoAdapter)
+
+// WithContextAttributes injects extra attributes into every report's designators and context:
+// attributes are added as is, while namespaceLabelAttributes maps an attribute name to the label
+// of the workload namespace holding its value
+func WithContextAttributes(attributes, namespaceLabelAttributes map[string]string, getNamespaceLabelsFunc func(context.Context, string) (map[string]string, error)) ArmoAdapterOption {
+	return func(a *ArmoAdapter) {
+		a.contextAttributes = attributes
+		a.namespaceLabelAttributes = namespaceLabelAttributes
+		a.getNamespaceLabelsFunc = getNamespaceLabelsFunc
+	}
+}
+
+// NamespaceLabelsGetter returns a function retrieving (and caching) namespace labels from the cluster
+func NamespaceLabelsGetter(client kubernetes.Interface) func(context.Context, string) (map[string]string, error) {
+	labelsCache := cache.New(namespaceLabelsTTL)
+	return func(ctx context.Context, namespace string) (map[string]string, error) {
+		if labels, ok := labelsCache.Get(namespace); ok {
+			return labels.(map[string]string), nil
+		}
+		ns, err := client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		labelsCache.Set(namespace, ns.Labels, namespaceLabelsTTL)
+		return ns.Labels, nil
+	}
+}
+
+// injectContextAttributes adds the configured attributes to the given designators attributes,
+// existing attributes are never overwritten
+func (a *ArmoAdapter) injectContextAttributes(ctx context.Context, wlid string, attributes map[string]string) {
+	for key, value := range a.contextAttributes {
+		if _, ok := attributes[key]; !ok {
+			attributes[key] = value
+		}
+	}
+	if len(a.namespaceLabelAttributes) == 0 || a.getNamespaceLabelsFunc == nil {
+		return
+	}
+	namespace := wlidpkg.GetNamespaceFromWlid(wlid)
+	if namespace == "" {
+		return
+	}
+	labels, err := a.getNamespaceLabelsFunc(ctx, namespace)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to get namespace labels", helpers.Error(err),
+			helpers.String(armotypes.AttributeNamespace, namespace))
+		return
+	}
+	for key, label := range a.namespaceLabelAttributes {
+		if value, ok := labels[label]; ok {
+			if _, exists := attributes[key]; !exists {
+				attributes[key] = value
+			}
+		}
+	}
+}
+
+// vulnerabilityAttributes returns the attributes of the organization attached to a vulnerability, along with the
+// availability and the sources of its public exploits once they were looked up
+func vulnerabilityAttributes(cve domain.CVEManifest, id string) map[string]string {
+	if cve.Exploits == nil {
+		return cve.Enrichment[id]
+	}
+	attributes := make(map[string]string, len(cve.Enrichment[id])+2)
+	for key, value := range cve.Enrichment[id] {
+		attributes[key] = value
+	}
+	attributes[attributeExploitAvailable] = strconv.FormatBool(len(cve.Exploits[id]) > 0)
+	if sources := cve.Exploits[id]; len(sources) > 0 {
+		attributes[attributeExploitSources] = strings.Join(sources, ",")
+	}
+	return attributes
+}
+
+// withMatchExplanation returns the attributes of a vulnerability with the explanations of the match of its package
+// encoded in JSON, when recorded
+func withMatchExplanation(attributes map[string]string, cve domain.CVEManifest, vulnerability containerscan.Vulnerability) map[string]string {
+	var explanations []domain.MatchExplanation
+	for _, explanation := range cve.Explanations[vulnerability.Name] {
+		if explanation.Package == vulnerability.RelatedPackageName && explanation.Version == vulnerability.PackageVersion {
+			explanations = append(explanations, explanation)
+		}
+	}
+	if len(explanations) == 0 {
+		return attributes
+	}
+	value, err := json.Marshal(explanations)
+	if err != nil {
+		return attributes
+	}
+	// the attributes of the organization are shared by the other reports
+	explained := make(map[string]string, len(attributes)+1)
+	for key, val := range attributes {
+		explained[key] = val
+	}
+	explained[attributeMatchExplanation] = string(value)
+	return explained
+}
+
+// withTriageAttributes returns the attributes of a vulnerability with the state of its triage, and the date of its
+// snooze, when triaged
+func withTriageAttributes(attributes map[string]string, cve domain.CVEManifest, vulnerability containerscan.Vulnerability) map[string]string {
+	for _, triage := range cve.Triage {
+		if triage.Vulnerability != vulnerability.Name || (triage.Package != "" && triage.Package != vulnerability.RelatedPackageName) {
+			continue
+		}
+		// the attributes of the organization are shared by the other reports
+		triaged := make(map[string]string, len(attributes)+2)
+		for key, val := range attributes {
+			triaged[key] = val
+		}
+		triaged[attributeTriage] = triage.State
+		if triage.Until != nil {
+			triaged[attributeSnoozedUntil] = triage.Until.UTC().Format(time.RFC3339)
+		}
+		return triaged
+	}
+	return attributes
+}
+
+// withVulnerabilityAttributes returns the designators of a vulnerability, with the attributes of the organization
+// attached to it, which never overwrite the attributes of the report
+func withVulnerabilityAttributes(designators armotypes.PortalDesignator, enrichment map[string]string) armotypes.PortalDesignator {
+	if len(enrichment) == 0 {
+		return designators
+	}
+	attributes := make(map[string]string, len(designators.Attributes)+len(enrichment))
+	for key, value := range enrichment {
+		attributes[key] = value
+	}
+	for key, value := range designators.Attributes {
+		attributes[key] = value
+	}
+	designators.Attributes = attributes
+	return designators
+}
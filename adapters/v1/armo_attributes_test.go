@@ -0,0 +1,143 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestArmoAdapter_injectContextAttributes(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "default",
+			Labels: map[string]string{"cost-center": "1234", "env": "prod"},
+		},
+	})
+	tests := []struct {
+		name                     string
+		attributes               map[string]string
+		contextAttributes        map[string]string
+		namespaceLabelAttributes map[string]string
+		getNamespaceLabelsFunc   func(context.Context, string) (map[string]string, error)
+		want                     map[string]string
+	}{
+		{
+			name:       "nothing configured",
+			attributes: map[string]string{"cluster": "minikube"},
+			want:       map[string]string{"cluster": "minikube"},
+		},
+		{
+			name:              "static attributes do not overwrite designators",
+			attributes:        map[string]string{"cluster": "minikube"},
+			contextAttributes: map[string]string{"cluster": "alias", "environment": "staging"},
+			want:              map[string]string{"cluster": "minikube", "environment": "staging"},
+		},
+		{
+			name:                     "namespace label attributes",
+			attributes:               map[string]string{},
+			namespaceLabelAttributes: map[string]string{"costCenter": "cost-center", "owner": "missing"},
+			getNamespaceLabelsFunc:   NamespaceLabelsGetter(client),
+			want:                     map[string]string{"costCenter": "1234"},
+		},
+		{
+			name:                     "namespace labels error",
+			attributes:               map[string]string{},
+			contextAttributes:        map[string]string{"environment": "prod"},
+			namespaceLabelAttributes: map[string]string{"costCenter": "cost-center"},
+			getNamespaceLabelsFunc: func(context.Context, string) (map[string]string, error) {
+				return nil, fmt.Errorf("error")
+			},
+			want: map[string]string{"environment": "prod"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewArmoAdapter("", "", "",
+				WithContextAttributes(tt.contextAttributes, tt.namespaceLabelAttributes, tt.getNamespaceLabelsFunc))
+			a.injectContextAttributes(context.TODO(), "wlid://cluster-minikube/namespace-default/deployment-nginx", tt.attributes)
+			assert.Equal(t, tt.want, tt.attributes)
+		})
+	}
+}
+
+func Test_withVulnerabilityAttributes(t *testing.T) {
+	designators := armotypes.PortalDesignator{Attributes: map[string]string{"cluster": "minikube"}}
+	assert.Equal(t, designators, withVulnerabilityAttributes(designators, nil))
+	got := withVulnerabilityAttributes(designators, map[string]string{"cluster": "alias", "owner": "team-a", "riskScore": "8.1"})
+	assert.Equal(t, map[string]string{"cluster": "minikube", "owner": "team-a", "riskScore": "8.1"}, got.Attributes)
+	// the designators of the report are shared by the other vulnerabilities
+	assert.Equal(t, map[string]string{"cluster": "minikube"}, designators.Attributes)
+}
+
+func Test_vulnerabilityAttributes(t *testing.T) {
+	cve := domain.CVEManifest{Enrichment: map[string]map[string]string{"CVE-2021-44228": {"owner": "team-a"}}}
+	assert.Equal(t, map[string]string{"owner": "team-a"}, vulnerabilityAttributes(cve, "CVE-2021-44228"))
+	assert.Nil(t, vulnerabilityAttributes(cve, "CVE-2022-32149"))
+	// once looked up, every vulnerability is flagged
+	cve.Exploits = map[string][]string{"CVE-2021-44228": {domain.ExploitSourceExploitDB, domain.ExploitSourceMetasploit}}
+	assert.Equal(t, map[string]string{"owner": "team-a", "exploitAvailable": "true", "exploitSources": "exploitdb,metasploit"},
+		vulnerabilityAttributes(cve, "CVE-2021-44228"))
+	assert.Equal(t, map[string]string{"exploitAvailable": "false"}, vulnerabilityAttributes(cve, "CVE-2022-32149"))
+	// the attributes of the organization are shared by the other reports
+	assert.Equal(t, map[string]string{"owner": "team-a"}, cve.Enrichment["CVE-2021-44228"])
+}
+
+func Test_withMatchExplanation(t *testing.T) {
+	explanation := domain.MatchExplanation{
+		Package:    "log4j-core",
+		Version:    "2.14.1",
+		Matcher:    "java-matcher",
+		MatchType:  domain.MatchTypeCPE,
+		Namespace:  "nvd:cpe",
+		SearchedBy: domain.SearchedByCPE,
+	}
+	cve := domain.CVEManifest{
+		Enrichment:   map[string]map[string]string{"CVE-2021-44228": {"owner": "team-a"}},
+		Explanations: map[string][]domain.MatchExplanation{"CVE-2021-44228": {explanation}},
+	}
+	vulnerability := containerscan.Vulnerability{Name: "CVE-2021-44228", RelatedPackageName: "log4j-core", PackageVersion: "2.14.1"}
+	assert.Equal(t, map[string]string{
+		"owner":            "team-a",
+		"matchExplanation": `[{"package":"log4j-core","version":"2.14.1","matcher":"java-matcher","matchType":"cpe-match","namespace":"nvd:cpe","searchedBy":"cpe"}]`,
+	}, withMatchExplanation(vulnerabilityAttributes(cve, "CVE-2021-44228"), cve, vulnerability))
+	// the attributes of the organization are shared by the other reports
+	assert.Equal(t, map[string]string{"owner": "team-a"}, cve.Enrichment["CVE-2021-44228"])
+	// the explanations of the other packages are left out
+	vulnerability.RelatedPackageName = "log4j-api"
+	assert.Equal(t, map[string]string{"owner": "team-a"}, withMatchExplanation(vulnerabilityAttributes(cve, "CVE-2021-44228"), cve, vulnerability))
+	assert.Nil(t, withMatchExplanation(nil, domain.CVEManifest{}, vulnerability))
+}
+
+func Test_withTriageAttributes(t *testing.T) {
+	until := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	cve := domain.CVEManifest{
+		Enrichment: map[string]map[string]string{"CVE-2021-44228": {"owner": "team-a"}},
+		Triage: []domain.Triage{
+			{Vulnerability: "CVE-2021-44228", Package: "log4j-core", State: domain.TriageSnoozed, Until: &until},
+			{Vulnerability: "CVE-2023-0286", State: domain.TriageAcknowledged},
+		},
+	}
+	vulnerability := containerscan.Vulnerability{Name: "CVE-2021-44228", RelatedPackageName: "log4j-core"}
+	assert.Equal(t, map[string]string{
+		"owner":        "team-a",
+		"triage":       "snoozed",
+		"snoozedUntil": "2023-07-01T00:00:00Z",
+	}, withTriageAttributes(vulnerabilityAttributes(cve, "CVE-2021-44228"), cve, vulnerability))
+	// the attributes of the organization are shared by the other reports
+	assert.Equal(t, map[string]string{"owner": "team-a"}, cve.Enrichment["CVE-2021-44228"])
+	// the triage of the other packages is left out
+	vulnerability.RelatedPackageName = "log4j-api"
+	assert.Equal(t, map[string]string{"owner": "team-a"}, withTriageAttributes(vulnerabilityAttributes(cve, "CVE-2021-44228"), cve, vulnerability))
+	// the triage without package applies to any package
+	vulnerability = containerscan.Vulnerability{Name: "CVE-2023-0286", RelatedPackageName: "openssl"}
+	assert.Equal(t, map[string]string{"triage": "acknowledged"}, withTriageAttributes(nil, cve, vulnerability))
+}
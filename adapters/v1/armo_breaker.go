@@ -0,0 +1,369 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// states of the circuit breaker of the event receiver, as reported by the kubevuln_submission_breaker_state metric
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+const (
+	// maxSpoolAttempts caps the replays of a spooled report, which is dropped after as many failures
+	maxSpoolAttempts = 5
+	// spoolReplayTimeout bounds the submission of a spooled report
+	spoolReplayTimeout = 5 * time.Minute
+)
+
+var breakerTrips, _ = otel.Meter("").Int64Counter("kubevuln_submission_breaker_trips",
+	metric.WithDescription("Number of times the circuit breaker of the event receiver opened"))
+
+var spoolDrops, _ = otel.Meter("").Int64Counter("kubevuln_submission_spool_dropped",
+	metric.WithDescription("Number of spooled reports dropped, by reason: spoolFull for the oldest reports evicted from a full spool, attempts after repeated failures"))
+
+// submissionBreaker stops the posts to the event receiver once threshold posts in a row failed, until coolDown is
+// over, then lets a single submission probe the event receiver, closing it again if the probe succeeds
+type submissionBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	coolDown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *submissionBreaker) stateAt(now time.Time) int {
+	switch {
+	case b.openUntil.IsZero():
+		return breakerClosed
+	case now.Before(b.openUntil):
+		return breakerOpen
+	}
+	return breakerHalfOpen
+}
+
+// state returns the current state of the breaker
+func (b *submissionBreaker) state() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateAt(time.Now())
+}
+
+// allow tells whether a submission can be sent, once the cool-down is over only the probe can, which probe reports
+// and which is released with done
+func (b *submissionBreaker) allow() (allowed, probe bool) {
+	if b.threshold <= 0 {
+		return true, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.stateAt(time.Now()) {
+	case breakerClosed:
+		return true, false
+	case breakerHalfOpen:
+		if !b.probing {
+			b.probing = true
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// done releases the probe
+func (b *submissionBreaker) done() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// blocked tells whether the posts are stopped, the posts of the probe go through once the cool-down is over
+func (b *submissionBreaker) blocked() bool {
+	return b.threshold > 0 && b.state() == breakerOpen
+}
+
+// record counts the posts failed in a row and reports whether the breaker opened, which it does once they reach the
+// threshold or the probe fails, a successful post closes it
+func (b *submissionBreaker) record(failed bool) (opened bool) {
+	if b.threshold <= 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return false
+	}
+	b.failures++
+	now := time.Now()
+	switch b.stateAt(now) {
+	case breakerOpen:
+		// the concurrent posts failing with the one that opened it
+		return false
+	case breakerClosed:
+		if b.failures < b.threshold {
+			return false
+		}
+	}
+	b.openUntil = now.Add(b.coolDown)
+	return true
+}
+
+// WithCircuitBreaker stops posting to the event receiver for coolDown once threshold posts in a row failed, with
+// a network error or a 5xx response, so that the scans do not wait for a dead event receiver. The submissions are
+// spooled meanwhile if WithSubmissionSpool is set, otherwise they fail right away. Zero disables it.
+func WithCircuitBreaker(threshold int, coolDown time.Duration) ArmoAdapterOption {
+	return func(a *ArmoAdapter) {
+		a.breaker.threshold = threshold
+		a.breaker.coolDown = coolDown
+		meter := otel.Meter("")
+		stateGauge, _ := meter.Int64ObservableGauge("kubevuln_submission_breaker_state",
+			metric.WithDescription("State of the circuit breaker of the event receiver: 0 closed, 1 open, 2 half-open"))
+		spooledGauge, _ := meter.Int64ObservableGauge("kubevuln_submission_spooled",
+			metric.WithDescription("Number of reports spooled while the event receiver is unavailable"))
+		if stateGauge != nil && spooledGauge != nil {
+			_, _ = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+				o.ObserveInt64(stateGauge, int64(a.breaker.state()))
+				o.ObserveInt64(spooledGauge, a.spooled.Load())
+				return nil
+			}, stateGauge, spooledGauge)
+		}
+	}
+}
+
+// WithSubmissionSpool keeps the reports in dir while the circuit breaker is open, they are submitted in order once
+// the event receiver is back, including those left by a previous run. The oldest reports are dropped once the spool
+// holds more than maxReports reports or maxSize bytes.
+func WithSubmissionSpool(dir string, maxReports int, maxSize int64) ArmoAdapterOption {
+	return func(a *ArmoAdapter) {
+		a.spoolDir = dir
+		a.spoolMaxReports = maxReports
+		a.spoolMaxSize = maxSize
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			logger.L().Warning("failed to create the submission spool", helpers.Error(err),
+				helpers.String("dir", dir))
+		}
+		a.spooled.Store(int64(len(a.spooledFiles())))
+	}
+}
+
+// recordPost updates the circuit breaker with the outcome of a post to the event receiver
+func (a *ArmoAdapter) recordPost(ctx context.Context, failed bool) {
+	if !a.breaker.record(failed) {
+		if !failed && a.spooled.Load() > 0 {
+			go a.replaySpool()
+		}
+		return
+	}
+	logger.L().Ctx(ctx).Warning("event receiver keeps failing, pausing the submissions",
+		helpers.String("coolDown", a.breaker.coolDown.String()))
+	if breakerTrips != nil {
+		breakerTrips.Add(ctx, 1)
+	}
+	if a.spoolDir != "" {
+		// the replay probes the event receiver once the cool-down is over
+		time.AfterFunc(a.breaker.coolDown, a.replaySpool)
+	}
+}
+
+// spooledSubmission is a report kept on disk while the event receiver is unavailable
+type spooledSubmission struct {
+	Timestamp int64
+	ScanID    string
+	Workload  domain.ScanCommand
+	CVE       domain.CVEManifest
+	CVEP      domain.CVEManifest
+	Attempts  int
+}
+
+// spoolSubmission keeps a report in the spool, it fails with ErrCircuitOpen without spool
+func (a *ArmoAdapter) spoolSubmission(ctx context.Context, cve domain.CVEManifest, cvep domain.CVEManifest) error {
+	if a.spoolDir == "" {
+		return domain.ErrCircuitOpen
+	}
+	timestamp, ok := ctx.Value(domain.TimestampKey{}).(int64)
+	if !ok {
+		return domain.ErrMissingTimestamp
+	}
+	scanID, ok := ctx.Value(domain.ScanIDKey{}).(string)
+	if !ok {
+		return domain.ErrMissingScanID
+	}
+	workload, ok := ctx.Value(domain.WorkloadKey{}).(domain.ScanCommand)
+	if !ok {
+		return domain.ErrCastingWorkload
+	}
+	// the registry credentials are not needed to submit the report, they are not written to disk
+	workload.Credentialslist = nil
+	submission := spooledSubmission{Timestamp: timestamp, ScanID: scanID, Workload: workload, CVE: cve, CVEP: cvep}
+	if err := a.writeSpooled(filepath.Join(a.spoolDir, fmt.Sprintf("%019d-%s.json", time.Now().UnixNano(), scanID)), submission); err != nil {
+		return err
+	}
+	a.spooled.Add(1)
+	logger.L().Ctx(ctx).Info("event receiver unavailable, report spooled",
+		helpers.String("scanID", scanID),
+		helpers.String("wlid", workload.Wlid))
+	a.evictSpooled(ctx)
+	return nil
+}
+
+// evictSpooled drops the oldest spooled reports until the spool holds at most spoolMaxReports reports and spoolMaxSize
+// bytes, so that a long outage of the event receiver does not fill the volume shared with the scans
+func (a *ArmoAdapter) evictSpooled(ctx context.Context) {
+	a.spoolMu.Lock()
+	defer a.spoolMu.Unlock()
+	files := a.spooledFiles()
+	sizes := make([]int64, len(files))
+	var size int64
+	for i, path := range files {
+		if info, err := os.Stat(path); err == nil {
+			sizes[i] = info.Size()
+			size += sizes[i]
+		}
+	}
+	remaining := len(files)
+	for i, path := range files {
+		if remaining <= a.spoolMaxReports && size <= a.spoolMaxSize {
+			return
+		}
+		if err := os.Remove(path); err != nil {
+			continue
+		}
+		remaining--
+		size -= sizes[i]
+		a.spooled.Add(-1)
+		if spoolDrops != nil {
+			spoolDrops.Add(ctx, 1, metric.WithAttributes(attribute.String("reason", "spoolFull")))
+		}
+		logger.L().Ctx(ctx).Warning("submission spool full, dropping the oldest report",
+			helpers.String("path", path))
+	}
+}
+
+// writeSpooled writes a spooled report atomically, so that a partial file is never replayed
+func (a *ArmoAdapter) writeSpooled(path string, submission spooledSubmission) error {
+	data, err := json.Marshal(submission)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(a.spoolDir, ".spool-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// spooledFiles lists the spooled reports, the oldest first
+func (a *ArmoAdapter) spooledFiles() []string {
+	entries, err := os.ReadDir(a.spoolDir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			files = append(files, filepath.Join(a.spoolDir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// replaySpool submits the spooled reports in order, it stops when the circuit breaker does not allow it, to be
+// resumed once the event receiver answers again
+func (a *ArmoAdapter) replaySpool() {
+	if a.spoolDir == "" || !a.replaying.CompareAndSwap(false, true) {
+		return
+	}
+	defer a.replaying.Store(false)
+	for _, path := range a.spooledFiles() {
+		if !a.replaySpooled(path) {
+			return
+		}
+	}
+}
+
+// replaySpooled submits a spooled report, it reports whether the replay can go on with the next one
+func (a *ArmoAdapter) replaySpooled(path string) bool {
+	allowed, probe := a.breaker.allow()
+	if !allowed {
+		return false
+	}
+	if probe {
+		defer a.breaker.done()
+	}
+	forget := func() {
+		if err := os.Remove(path); err == nil {
+			a.spooled.Add(-1)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.L().Warning("failed to read spooled report", helpers.Error(err),
+			helpers.String("path", path))
+		forget()
+		return true
+	}
+	var submission spooledSubmission
+	if err := json.Unmarshal(data, &submission); err != nil {
+		logger.L().Warning("dropping unreadable spooled report", helpers.Error(err),
+			helpers.String("path", path))
+		forget()
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), spoolReplayTimeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, domain.TimestampKey{}, submission.Timestamp)
+	ctx = context.WithValue(ctx, domain.ScanIDKey{}, submission.ScanID)
+	ctx = context.WithValue(ctx, domain.WorkloadKey{}, submission.Workload)
+	err = a.submitCVE(ctx, submission.CVE, submission.CVEP)
+	if err == nil {
+		logger.L().Info("spooled report submitted",
+			helpers.String("scanID", submission.ScanID),
+			helpers.String("wlid", submission.Workload.Wlid))
+		forget()
+		return true
+	}
+	submission.Attempts++
+	if submission.Attempts >= maxSpoolAttempts {
+		logger.L().Error("dropping spooled report after repeated failures", helpers.Error(err),
+			helpers.String("scanID", submission.ScanID),
+			helpers.String("wlid", submission.Workload.Wlid))
+		if spoolDrops != nil {
+			spoolDrops.Add(ctx, 1, metric.WithAttributes(attribute.String("reason", "attempts")))
+		}
+		forget()
+		return true
+	}
+	if err := a.writeSpooled(path, submission); err != nil {
+		logger.L().Warning("failed to update spooled report", helpers.Error(err),
+			helpers.String("path", path))
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/armosec/utils-go/httputils"
+	"github.com/google/uuid"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_submissionBreaker(t *testing.T) {
+	b := submissionBreaker{threshold: 2, coolDown: time.Hour}
+	allowed, probe := b.allow()
+	assert.True(t, allowed)
+	assert.False(t, probe)
+
+	// a success resets the failures in a row
+	assert.False(t, b.record(true))
+	assert.False(t, b.record(false))
+	assert.False(t, b.record(true))
+	assert.Equal(t, breakerClosed, b.state())
+	assert.True(t, b.record(true))
+	assert.Equal(t, breakerOpen, b.state())
+	assert.True(t, b.blocked())
+	allowed, _ = b.allow()
+	assert.False(t, allowed)
+	// the posts failing concurrently do not open it again
+	assert.False(t, b.record(true))
+
+	// a single probe is allowed once the cool-down is over
+	b.openUntil = time.Now()
+	assert.Equal(t, breakerHalfOpen, b.state())
+	assert.False(t, b.blocked())
+	allowed, probe = b.allow()
+	assert.True(t, allowed)
+	assert.True(t, probe)
+	allowed, _ = b.allow()
+	assert.False(t, allowed)
+	// a failed probe opens it again
+	assert.True(t, b.record(true))
+	assert.Equal(t, breakerOpen, b.state())
+	b.done()
+
+	// a successful probe closes it
+	b.openUntil = time.Now()
+	_, probe = b.allow()
+	assert.True(t, probe)
+	assert.False(t, b.record(false))
+	b.done()
+	assert.Equal(t, breakerClosed, b.state())
+
+	// a zero threshold disables it
+	var disabled submissionBreaker
+	for i := 0; i < 10; i++ {
+		assert.False(t, disabled.record(true))
+	}
+	allowed, _ = disabled.allow()
+	assert.True(t, allowed)
+}
+
+func TestArmoAdapter_SubmitCVE_circuitBreaker(t *testing.T) {
+	mu := &sync.Mutex{}
+	var posts int
+	status := http.StatusInternalServerError
+	newAdapter := func(opts ...ArmoAdapterOption) *ArmoAdapter {
+		a := &ArmoAdapter{
+			getCVEExceptionsFunc: func(string, string, *armotypes.PortalDesignator) ([]armotypes.VulnerabilityExceptionPolicy, error) {
+				return nil, nil
+			},
+			httpPostFunc: func(_ httputils.IHttpClient, _ string, _ map[string]string, _ []byte) (*http.Response, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				posts++
+				return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(bytes.NewBuffer([]byte{}))}, nil
+			},
+			reportVersion: ReportVersionV2,
+		}
+		for _, opt := range opts {
+			opt(a)
+		}
+		return a
+	}
+	ctx := context.TODO()
+	ctx = context.WithValue(ctx, domain.TimestampKey{}, time.Now().Unix())
+	ctx = context.WithValue(ctx, domain.ScanIDKey{}, uuid.New().String())
+	ctx = context.WithValue(ctx, domain.WorkloadKey{}, domain.ScanCommand{JobID: "jobID", Wlid: "wlid://cluster-minikube/namespace-default/deployment-nginx"})
+	cve := fileToCVEManifest("testdata/nginx-cve-small.json")
+
+	t.Run("without spool the submissions fail right away", func(t *testing.T) {
+		posts = 0
+		a := newAdapter(WithCircuitBreaker(2, time.Hour))
+		assert.Error(t, a.SubmitCVE(ctx, cve, domain.CVEManifest{}))
+		assert.Error(t, a.SubmitCVE(ctx, cve, domain.CVEManifest{}))
+		assert.ErrorIs(t, a.SubmitCVE(ctx, cve, domain.CVEManifest{}), domain.ErrCircuitOpen)
+		assert.Equal(t, 2, posts)
+	})
+
+	t.Run("the spooled reports are submitted once the event receiver is back", func(t *testing.T) {
+		posts = 0
+		status = http.StatusInternalServerError
+		dir := t.TempDir()
+		a := newAdapter(WithCircuitBreaker(1, time.Hour), WithSubmissionSpool(dir, 10, 1<<30))
+		assert.Error(t, a.SubmitCVE(ctx, cve, domain.CVEManifest{}))
+		require.NoError(t, a.SubmitCVE(ctx, cve, domain.CVEManifest{}))
+		require.NoError(t, a.SubmitCVE(ctx, cve, domain.CVEManifest{}))
+		assert.Equal(t, 1, posts)
+		assert.Equal(t, int64(2), a.spooled.Load())
+
+		// a restart finds the spooled reports
+		assert.Equal(t, int64(2), newAdapter(WithSubmissionSpool(dir, 10, 1<<30)).spooled.Load())
+
+		// the replay probes the event receiver once the cool-down is over
+		status = http.StatusOK
+		a.breaker.openUntil = time.Now()
+		a.replaySpool()
+		assert.Equal(t, 3, posts)
+		assert.Equal(t, breakerClosed, a.breaker.state())
+		assert.Equal(t, int64(0), a.spooled.Load())
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("a failed replay keeps the spooled report", func(t *testing.T) {
+		posts = 0
+		status = http.StatusInternalServerError
+		dir := t.TempDir()
+		a := newAdapter(WithCircuitBreaker(1, time.Hour), WithSubmissionSpool(dir, 10, 1<<30))
+		assert.Error(t, a.SubmitCVE(ctx, cve, domain.CVEManifest{}))
+		require.NoError(t, a.SubmitCVE(ctx, cve, domain.CVEManifest{}))
+		a.breaker.openUntil = time.Now()
+		a.replaySpool()
+		assert.Equal(t, 2, posts)
+		assert.Equal(t, breakerOpen, a.breaker.state())
+		files := a.spooledFiles()
+		require.Len(t, files, 1)
+		var submission spooledSubmission
+		data, err := os.ReadFile(files[0])
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &submission))
+		assert.Equal(t, 1, submission.Attempts)
+		assert.Equal(t, "jobID", submission.Workload.JobID)
+	})
+
+	t.Run("the oldest spooled reports are dropped once the spool is full", func(t *testing.T) {
+		posts = 0
+		status = http.StatusInternalServerError
+		dir := t.TempDir()
+		a := newAdapter(WithCircuitBreaker(1, time.Hour), WithSubmissionSpool(dir, 2, 1<<30))
+		assert.Error(t, a.SubmitCVE(ctx, cve, domain.CVEManifest{}))
+		for i := 0; i < 3; i++ {
+			require.NoError(t, a.SubmitCVE(ctx, cve, domain.CVEManifest{}))
+		}
+		files := a.spooledFiles()
+		assert.Len(t, files, 2)
+		assert.Equal(t, int64(2), a.spooled.Load())
+		// the size of the spool is capped too
+		info, err := os.Stat(files[1])
+		require.NoError(t, err)
+		a.spoolMaxSize = info.Size()
+		require.NoError(t, a.SubmitCVE(ctx, cve, domain.CVEManifest{}))
+		remaining := a.spooledFiles()
+		require.Len(t, remaining, 1)
+		assert.NotContains(t, files, remaining[0])
+		assert.Equal(t, int64(1), a.spooled.Load())
+	})
+}
@@ -0,0 +1,95 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/armosec/armoapi-go/apis"
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+	v1 "github.com/armosec/cluster-container-scanner-api/containerscan/v1"
+	"github.com/armosec/utils-go/httputils"
+)
+
+// orderings of the submission of the chunks of a report split in parts
+const (
+	// SubmitOrderingConcurrent posts the chunks concurrently, the last one may be received before the others
+	SubmitOrderingConcurrent = "concurrent"
+	// SubmitOrderingLastChunkBarrier posts the chunks concurrently but the last one once all the others are answered
+	SubmitOrderingLastChunkBarrier = "lastChunkBarrier"
+	// SubmitOrderingSequential posts the chunks one after the other, each once the previous one is answered
+	SubmitOrderingSequential = "sequential"
+)
+
+// WithSubmitOrdering sets the ordering of the submission of the chunks of the reports, concurrent by default
+func WithSubmitOrdering(ordering string) ArmoAdapterOption {
+	return func(a *ArmoAdapter) {
+		a.submitOrdering = ordering
+	}
+}
+
+// postChunk posts a chunk of a report split in parts according to the submission ordering, waiting for the posts
+// of sendWG to be answered first when it must arrive after them
+func (a *ArmoAdapter) postChunk(ctx context.Context, report *v1.ScanResultReport, eventReceiverURL, imagetag, wlid string, errorChan chan<- error, sendWG *sync.WaitGroup) {
+	switch {
+	case a.submitOrdering == SubmitOrderingSequential:
+		a.postResults(ctx, report, eventReceiverURL, imagetag, wlid, errorChan)
+	case a.submitOrdering == SubmitOrderingLastChunkBarrier && report.PaginationInfo.IsLastReport:
+		sendWG.Wait()
+		a.postResults(ctx, report, eventReceiverURL, imagetag, wlid, errorChan)
+	default:
+		a.postResultsAsGoroutine(ctx, report, eventReceiverURL, imagetag, wlid, errorChan, sendWG)
+	}
+}
+
+// chunkEnvelopeSize is the size of the JSON of a chunk of the report without its vulnerabilities: designators,
+// scan ID, timestamp and the widest pagination marks
+func chunkEnvelopeSize(report v1.ScanResultReport, totalVulnerabilities int) int {
+	return httputils.JSONSize(v1.ScanResultReport{
+		PaginationInfo:  apis.PaginationMarks{ReportNumber: totalVulnerabilities + 1},
+		Vulnerabilities: []containerscan.CommonContainerVulnerabilityResult{},
+		ContainerScanID: report.ContainerScanID,
+		Timestamp:       report.Timestamp,
+		Designators:     report.Designators,
+	})
+}
+
+// splitVulnerabilities packs the vulnerabilities in chunks whose JSON body, envelope included, fits in maxSize.
+// The size of each vulnerability is measured once, so that every chunk is filled up to the limit instead of halving
+// the slice until it fits, a vulnerability larger than the limit on its own is sent alone.
+func splitVulnerabilities(report v1.ScanResultReport, vulnerabilities []containerscan.CommonContainerVulnerabilityResult, maxSize int) (<-chan []containerscan.CommonContainerVulnerabilityResult, int) {
+	var chunks [][]containerscan.CommonContainerVulnerabilityResult
+	envelope := chunkEnvelopeSize(report, len(vulnerabilities))
+	start, size := 0, envelope
+	for i := range vulnerabilities {
+		entry := vulnerabilitySize(vulnerabilities[i])
+		if i > start {
+			// the comma separating the entries
+			entry++
+		}
+		if i > start && size+entry > maxSize {
+			chunks = append(chunks, vulnerabilities[start:i])
+			start, size = i, envelope
+			entry--
+		}
+		size += entry
+	}
+	if start < len(vulnerabilities) {
+		chunks = append(chunks, vulnerabilities[start:])
+	}
+	chunksChan := make(chan []containerscan.CommonContainerVulnerabilityResult, len(chunks))
+	for _, chunk := range chunks {
+		chunksChan <- chunk
+	}
+	close(chunksChan)
+	return chunksChan, len(vulnerabilities)
+}
+
+// vulnerabilitySize is the size of the JSON of a vulnerability within a report
+func vulnerabilitySize(vulnerability containerscan.CommonContainerVulnerabilityResult) int {
+	b, err := json.Marshal(vulnerability)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
@@ -0,0 +1,146 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/armosec/armoapi-go/apis"
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+	v1 "github.com/armosec/cluster-container-scanner-api/containerscan/v1"
+	"github.com/armosec/utils-go/httputils"
+	"github.com/google/uuid"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_splitVulnerabilities(t *testing.T) {
+	report := v1.ScanResultReport{
+		ContainerScanID: "scanID",
+		Timestamp:       1680000000,
+		Designators: armotypes.PortalDesignator{
+			Attributes: map[string]string{"cluster": "minikube", "namespace": "default"},
+		},
+	}
+	var vulnerabilities []containerscan.CommonContainerVulnerabilityResult
+	for i := 0; i < 200; i++ {
+		vulnerabilities = append(vulnerabilities, containerscan.CommonContainerVulnerabilityResult{
+			Vulnerability: containerscan.Vulnerability{
+				Name:        fmt.Sprintf("CVE-2023-%04d", i),
+				Description: strings.Repeat("x", (i*37)%400),
+			},
+		})
+	}
+	// one vulnerability does not fit in a chunk on its own
+	vulnerabilities[100].Description = strings.Repeat("x", 6000)
+	const maxSize = 5000
+
+	chunksChan, total := splitVulnerabilities(report, vulnerabilities, maxSize)
+	assert.Equal(t, len(vulnerabilities), total)
+	var chunks [][]containerscan.CommonContainerVulnerabilityResult
+	for chunk := range chunksChan {
+		chunks = append(chunks, chunk)
+	}
+	var sent []containerscan.CommonContainerVulnerabilityResult
+	for i, chunk := range chunks {
+		require.NotEmpty(t, chunk)
+		sent = append(sent, chunk...)
+		body := chunkBody(t, report, chunk, i+1)
+		if len(chunk) == 1 && chunk[0].Name == "CVE-2023-0100" {
+			assert.Greater(t, body, maxSize)
+			continue
+		}
+		assert.LessOrEqual(t, body, maxSize)
+		// the chunks are filled up to the limit
+		if i+1 < len(chunks) && chunks[i+1][0].Name != "CVE-2023-0100" {
+			assert.Greater(t, chunkBody(t, report, append(chunk[:len(chunk):len(chunk)], chunks[i+1][0]), i+1), maxSize)
+		}
+	}
+	assert.Equal(t, vulnerabilities, sent)
+
+	chunksChan, total = splitVulnerabilities(report, nil, maxSize)
+	assert.Zero(t, total)
+	_, ok := <-chunksChan
+	assert.False(t, ok)
+}
+
+// chunkBody returns the size of the body posted for a chunk
+func chunkBody(t *testing.T, report v1.ScanResultReport, chunk []containerscan.CommonContainerVulnerabilityResult, partNum int) int {
+	body, err := json.Marshal(v1.ScanResultReport{
+		PaginationInfo:  apis.PaginationMarks{ReportNumber: partNum, IsLastReport: true},
+		Vulnerabilities: chunk,
+		ContainerScanID: report.ContainerScanID,
+		Timestamp:       report.Timestamp,
+		Designators:     report.Designators,
+	})
+	require.NoError(t, err)
+	return len(body)
+}
+
+func TestArmoAdapter_SubmitCVE_ordering(t *testing.T) {
+	tests := []struct {
+		ordering string
+		// the most chunks in flight at once
+		wantMaxInFlight int
+	}{
+		{ordering: SubmitOrderingSequential, wantMaxInFlight: 1},
+		{ordering: SubmitOrderingLastChunkBarrier},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ordering, func(t *testing.T) {
+			mu := &sync.Mutex{}
+			var received []v1.ScanResultReport
+			var inFlight, maxInFlight, inFlightAtLast int
+			a := NewArmoAdapter("", "", "", WithReportVersion(ReportVersionV2), WithSubmitOrdering(tt.ordering))
+			a.getCVEExceptionsFunc = func(string, string, *armotypes.PortalDesignator) ([]armotypes.VulnerabilityExceptionPolicy, error) {
+				return nil, nil
+			}
+			a.httpPostFunc = func(_ httputils.IHttpClient, _ string, _ map[string]string, body []byte) (*http.Response, error) {
+				var report v1.ScanResultReport
+				assert.NoError(t, json.Unmarshal(body, &report))
+				mu.Lock()
+				inFlight++
+				if inFlight > maxInFlight {
+					maxInFlight = inFlight
+				}
+				if report.PaginationInfo.IsLastReport {
+					inFlightAtLast = inFlight
+				}
+				mu.Unlock()
+				// the first chunks are the slowest to be answered
+				if !report.PaginationInfo.IsLastReport {
+					time.Sleep(10 * time.Millisecond)
+				}
+				mu.Lock()
+				inFlight--
+				received = append(received, report)
+				mu.Unlock()
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBuffer([]byte{}))}, nil
+			}
+			ctx := context.TODO()
+			ctx = context.WithValue(ctx, domain.TimestampKey{}, time.Now().Unix())
+			ctx = context.WithValue(ctx, domain.ScanIDKey{}, uuid.New().String())
+			ctx = context.WithValue(ctx, domain.WorkloadKey{}, domain.ScanCommand{})
+			require.NoError(t, a.SubmitCVE(ctx, fileToCVEManifest("testdata/nginx-cve.json"), domain.CVEManifest{}))
+			require.Greater(t, len(received), 3)
+			// the last chunk is posted alone and received after all the others
+			assert.Equal(t, 1, inFlightAtLast)
+			assert.True(t, received[len(received)-1].PaginationInfo.IsLastReport)
+			if tt.wantMaxInFlight > 0 {
+				assert.Equal(t, tt.wantMaxInFlight, maxInFlight)
+				for i := 1; i < len(received); i++ {
+					assert.Equal(t, i, received[i].PaginationInfo.ReportNumber)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package v1
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// compression modes of the reports sent to the event receiver
+const (
+	// CompressionAuto gzip compresses the reports once the event receiver advertises it accepts them (default)
+	CompressionAuto = "auto"
+	// CompressionNone never compresses the reports
+	CompressionNone = "none"
+)
+
+// minCompressedSize is the size under which report parts are sent as is, compressing them saving too little
+const minCompressedSize = 1024
+
+var reportCompressionSaved, _ = otel.Meter("").Int64Counter("kubevuln_report_compression_saved_bytes",
+	metric.WithDescription("Number of bytes saved by compressing the report parts sent to the event receiver"))
+
+// WithCompression sets the compression mode of the reports, CompressionAuto or CompressionNone
+func WithCompression(mode string) ArmoAdapterOption {
+	return func(a *ArmoAdapter) {
+		a.compression.disabled = mode == CompressionNone
+	}
+}
+
+// reportCompression tracks whether the event receiver accepts gzip compressed reports, as it advertises in the
+// Accept-Encoding header of its responses (RFC 7694)
+type reportCompression struct {
+	accepted atomic.Bool
+	disabled bool
+}
+
+// observe records the content codings advertised by a response of the event receiver, the responses without
+// Accept-Encoding header leave them unchanged
+func (c *reportCompression) observe(resp *http.Response) {
+	values := resp.Header.Values("Accept-Encoding")
+	if len(values) == 0 {
+		return
+	}
+	c.accepted.Store(acceptsGzip(values))
+}
+
+// acceptsGzip tells whether the Accept-Encoding header values accept gzip
+func acceptsGzip(values []string) bool {
+	for _, value := range values {
+		for _, coding := range strings.Split(value, ",") {
+			coding, params, _ := strings.Cut(strings.TrimSpace(coding), ";")
+			if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+				continue
+			}
+			// gzip;q=0 refuses the coding
+			q := strings.ReplaceAll(params, " ", "")
+			return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+		}
+	}
+	return false
+}
+
+// encode returns the payload gzip compressed with its content coding if the event receiver accepts it, the payload as
+// is otherwise
+func (c *reportCompression) encode(payload []byte) ([]byte, string) {
+	if c.disabled || !c.accepted.Load() || len(payload) < minCompressedSize {
+		return payload, ""
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(payload); err != nil {
+		return payload, ""
+	}
+	if err := w.Close(); err != nil {
+		return payload, ""
+	}
+	return buf.Bytes(), "gzip"
+}
+
+// postEncoded posts a report part compressed if the event receiver accepts it; a compressed part rejected as
+// unsupported is posted again as is, and the parts are no longer compressed until the event receiver advertises it
+// again
+func (a *ArmoAdapter) postEncoded(ctx context.Context, fullURL string, payload []byte) (*http.Response, error) {
+	body, encoding := a.compression.encode(payload)
+	resp, err := a.postFailover(ctx, fullURL, body, encoding)
+	if err != nil {
+		return nil, err
+	}
+	if encoding == "" || resp.StatusCode != http.StatusUnsupportedMediaType {
+		a.compression.observe(resp)
+		if encoding != "" && reportCompressionSaved != nil {
+			reportCompressionSaved.Add(ctx, int64(len(payload)-len(body)))
+		}
+		return resp, nil
+	}
+	_ = resp.Body.Close()
+	a.compression.accepted.Store(false)
+	logger.L().Ctx(ctx).Warning("event receiver rejected the compressed report, sending it uncompressed",
+		helpers.String("encoding", encoding))
+	return a.postFailover(ctx, fullURL, payload, "")
+}
@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/armosec/utils-go/httputils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_acceptsGzip(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   bool
+	}{
+		{name: "gzip", values: []string{"gzip"}, want: true},
+		{name: "listed", values: []string{"br, GZIP;q=0.8"}, want: true},
+		{name: "several headers", values: []string{"br", "gzip"}, want: true},
+		{name: "refused", values: []string{"gzip;q=0"}, want: false},
+		{name: "other codings", values: []string{"br, deflate"}, want: false},
+		{name: "identity only", values: []string{""}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, acceptsGzip(tt.values))
+		})
+	}
+}
+
+func TestArmoAdapter_postEncoded(t *testing.T) {
+	payload := bytes.Repeat([]byte(`{"name":"CVE-2023-0001"},`), 100)
+	var encodings []string
+	var bodies [][]byte
+	status := http.StatusOK
+	acceptEncoding := "gzip"
+	a := &ArmoAdapter{
+		httpPostFunc: func(_ httputils.IHttpClient, _ string, headers map[string]string, body []byte) (*http.Response, error) {
+			encodings = append(encodings, headers["Content-Encoding"])
+			bodies = append(bodies, body)
+			header := http.Header{}
+			if acceptEncoding != "" {
+				header.Set("Accept-Encoding", acceptEncoding)
+			}
+			code := http.StatusOK
+			if headers["Content-Encoding"] != "" {
+				code = status
+			}
+			return &http.Response{StatusCode: code, Header: header, Body: http.NoBody}, nil
+		},
+	}
+	fullURL := "https://report.armo.cloud/k8s/v2/containerScan?customerGUID=account"
+
+	// the parts are sent as is until the event receiver advertises gzip
+	_, err := a.postEncoded(context.TODO(), fullURL, payload)
+	require.NoError(t, err)
+	assert.Equal(t, []string{""}, encodings)
+	assert.True(t, a.compression.accepted.Load())
+
+	// then compressed, except the small ones
+	encodings, bodies = nil, nil
+	_, err = a.postEncoded(context.TODO(), fullURL, payload)
+	require.NoError(t, err)
+	_, err = a.postEncoded(context.TODO(), fullURL, []byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gzip", ""}, encodings)
+	assert.Less(t, len(bodies[0]), len(payload)/2)
+	r, err := gzip.NewReader(bytes.NewReader(bodies[0]))
+	require.NoError(t, err)
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, payload, got)
+
+	// a rejected compressed part is sent again as is, and the next ones are not compressed
+	status, acceptEncoding = http.StatusUnsupportedMediaType, ""
+	encodings, bodies = nil, nil
+	resp, err := a.postEncoded(context.TODO(), fullURL, payload)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_, err = a.postEncoded(context.TODO(), fullURL, payload)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gzip", "", ""}, encodings)
+	assert.Equal(t, payload, bodies[1])
+	assert.False(t, a.compression.accepted.Load())
+}
+
+func TestWithCompression(t *testing.T) {
+	a := &ArmoAdapter{}
+	WithCompression(CompressionNone)(a)
+	a.compression.accepted.Store(true)
+	payload := bytes.Repeat([]byte("a"), 2*minCompressedSize)
+	body, encoding := a.compression.encode(payload)
+	assert.Empty(t, encoding)
+	assert.Equal(t, payload, body)
+
+	WithCompression(CompressionAuto)(a)
+	_, encoding = a.compression.encode(payload)
+	assert.Equal(t, "gzip", encoding)
+}
@@ -0,0 +1,190 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// endpointRetryInterval is how long a failed event receiver endpoint is passed over before it is tried again
+const endpointRetryInterval = 30 * time.Second
+
+var receiverFailovers, _ = otel.Meter("").Int64Counter("kubevuln_event_receiver_failovers",
+	metric.WithDescription("Number of posts sent to another event receiver endpoint after a failure"))
+
+// receiverEndpoints tracks the health of the event receiver endpoints, the primary one first, the posts go to the
+// first healthy endpoint in order, so that they fail back to the primary one once it answers again
+type receiverEndpoints struct {
+	mu        sync.Mutex
+	urls      []*url.URL
+	downUntil []time.Time
+}
+
+// order returns the indices of the endpoints to post to: the healthy ones in order, then the others, the first to be
+// tried again first
+func (e *receiverEndpoints) order() []int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := time.Now()
+	indices := make([]int, len(e.urls))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		a, b := e.downUntil[indices[i]], e.downUntil[indices[j]]
+		if !a.After(now) || !b.After(now) {
+			return !a.After(now) && b.After(now)
+		}
+		return a.Before(b)
+	})
+	return indices
+}
+
+// preferred returns the base URL of the endpoint the posts go to first, or fallback without endpoints
+func (e *receiverEndpoints) preferred(fallback string) string {
+	order := e.order()
+	if len(order) == 0 {
+		return fallback
+	}
+	return e.urls[order[0]].String()
+}
+
+// record marks an endpoint as down for endpointRetryInterval after a failed post, or as up, and reports whether it
+// changed
+func (e *receiverEndpoints) record(i int, failed bool) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := time.Now()
+	wasUp := !e.downUntil[i].After(now)
+	if failed {
+		e.downUntil[i] = now.Add(endpointRetryInterval)
+	} else {
+		e.downUntil[i] = time.Time{}
+	}
+	return wasUp == failed
+}
+
+// up tells whether an endpoint is healthy
+func (e *receiverEndpoints) up(i int) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return !e.downUntil[i].After(time.Now())
+}
+
+// rewrite points a report URL, built with the base URL of any endpoint, to the base URL of the endpoint i, its path
+// prefix included
+func (e *receiverEndpoints) rewrite(fullURL string, i int) (string, error) {
+	target, err := url.Parse(fullURL)
+	if err != nil {
+		return "", err
+	}
+	// the path relative to the base URL the report URL was built with, the longest matching one
+	relative, prefixLength := target.Path, -1
+	for _, u := range e.urls {
+		prefix := strings.TrimSuffix(u.Path, "/")
+		if u.Host == target.Host && len(prefix) > prefixLength && strings.HasPrefix(target.Path, prefix+"/") {
+			relative, prefixLength = strings.TrimPrefix(target.Path, prefix), len(prefix)
+		}
+	}
+	target.Scheme, target.Host = e.urls[i].Scheme, e.urls[i].Host
+	target.Path = strings.TrimSuffix(e.urls[i].Path, "/") + relative
+	return target.String(), nil
+}
+
+// WithFailoverEndpoints adds event receiver endpoints the posts fail over to, in order, when the primary one fails
+// with a network error or a 5xx response; a failed endpoint is passed over for endpointRetryInterval
+func WithFailoverEndpoints(urls []string) ArmoAdapterOption {
+	return func(a *ArmoAdapter) {
+		for _, raw := range append([]string{a.clusterConfig.EventReceiverRestURL}, urls...) {
+			u, err := url.Parse(raw)
+			if err != nil || u.Host == "" {
+				logger.L().Warning("ignoring invalid event receiver endpoint", helpers.String("url", raw))
+				continue
+			}
+			a.endpoints.urls = append(a.endpoints.urls, u)
+			a.endpoints.downUntil = append(a.endpoints.downUntil, time.Time{})
+		}
+		meter := otel.Meter("")
+		upGauge, _ := meter.Int64ObservableGauge("kubevuln_event_receiver_endpoint_up",
+			metric.WithDescription("Whether the event receiver endpoint is healthy: 1 up, 0 passed over after a failure"))
+		if upGauge != nil {
+			_, _ = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+				for i, u := range a.endpoints.urls {
+					var up int64
+					if a.endpoints.up(i) {
+						up = 1
+					}
+					o.ObserveInt64(upGauge, up, metric.WithAttributes(attribute.String("endpoint", u.Host)))
+				}
+				return nil
+			}, upGauge)
+		}
+	}
+}
+
+// eventReceiverURL returns the base URL of the event receiver endpoint to use
+func (a *ArmoAdapter) eventReceiverURL() string {
+	return a.endpoints.preferred(a.clusterConfig.EventReceiverRestURL)
+}
+
+// postFailover posts a report part, in the given content coding if any, to the event receiver endpoints in order
+// until one answers without a server error, it returns the last answer otherwise
+func (a *ArmoAdapter) postFailover(ctx context.Context, fullURL string, payload []byte, encoding string) (*http.Response, error) {
+	headers := map[string]string{"Content-Type": "application/json"}
+	if encoding != "" {
+		headers["Content-Encoding"] = encoding
+	}
+	order := a.endpoints.order()
+	if len(order) == 0 {
+		return a.httpPostFunc(clientWithContext(ctx), fullURL, headers, payload)
+	}
+	var resp *http.Response
+	var err error
+	for n, i := range order {
+		target, rewriteErr := a.endpoints.rewrite(fullURL, i)
+		if rewriteErr != nil {
+			return nil, rewriteErr
+		}
+		if n > 0 {
+			_ = resp.Body.Close()
+			logger.L().Ctx(ctx).Warning("failing over to another event receiver endpoint",
+				helpers.String("endpoint", a.endpoints.urls[i].Host))
+			if receiverFailovers != nil {
+				receiverFailovers.Add(ctx, 1)
+			}
+		}
+		resp, err = a.httpPostFunc(clientWithContext(ctx), target, headers, payload)
+		if err != nil {
+			// the posts abandoned by the scans are not failures of the endpoint
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			// an empty answer to close before the next endpoint
+			resp = &http.Response{StatusCode: http.StatusBadGateway, Body: http.NoBody}
+		}
+		failed := resp.StatusCode >= http.StatusInternalServerError
+		if a.endpoints.record(i, failed) {
+			logger.L().Ctx(ctx).Info("event receiver endpoint health changed",
+				helpers.String("endpoint", a.endpoints.urls[i].Host),
+				helpers.String("up", fmt.Sprint(!failed)))
+		}
+		if !failed {
+			return resp, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
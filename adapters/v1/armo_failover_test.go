@@ -0,0 +1,110 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/armosec/utils-go/httputils"
+	"github.com/armosec/utils-k8s-go/armometadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArmoAdapter_postReport_failover(t *testing.T) {
+	var hosts []string
+	status := map[string]int{"report.armo.cloud": http.StatusBadGateway, "report-eu.armo.cloud": http.StatusOK}
+	a := &ArmoAdapter{
+		clusterConfig: armometadata.ClusterConfig{EventReceiverRestURL: "https://report.armo.cloud"},
+		httpPostFunc: func(_ httputils.IHttpClient, fullURL string, _ map[string]string, _ []byte) (*http.Response, error) {
+			u, err := url.Parse(fullURL)
+			require.NoError(t, err)
+			assert.Equal(t, "/k8s/v2/containerScan", u.Path)
+			hosts = append(hosts, u.Host)
+			if u.Host == "report-us.armo.cloud" {
+				return nil, errors.New("connection refused")
+			}
+			return &http.Response{StatusCode: status[u.Host], Header: http.Header{}, Body: http.NoBody}, nil
+		},
+	}
+	WithCircuitBreaker(1, time.Hour)(a)
+	WithFailoverEndpoints([]string{"https://report-us.armo.cloud", "https://report-eu.armo.cloud"})(a)
+	fullURL := "https://report.armo.cloud/k8s/v2/containerScan?customerGUID=account"
+
+	// the report part goes to the next endpoint right away, without tripping the breaker
+	resp, err := a.postReport(context.TODO(), fullURL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"report.armo.cloud", "report-us.armo.cloud", "report-eu.armo.cloud"}, hosts)
+	assert.Equal(t, breakerClosed, a.breaker.state())
+	assert.Equal(t, "https://report-eu.armo.cloud", a.eventReceiverURL())
+
+	// the failed endpoints are passed over
+	hosts = nil
+	_, err = a.postReport(context.TODO(), fullURL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"report-eu.armo.cloud"}, hosts)
+
+	// the posts fail back to the primary endpoint once it is tried again
+	status["report.armo.cloud"] = http.StatusOK
+	a.endpoints.downUntil[0] = time.Now()
+	hosts = nil
+	_, err = a.postReport(context.TODO(), fullURL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"report.armo.cloud"}, hosts)
+	assert.Equal(t, "https://report.armo.cloud", a.eventReceiverURL())
+
+	// the breaker counts a failure once all the endpoints failed
+	status["report.armo.cloud"] = http.StatusServiceUnavailable
+	status["report-eu.armo.cloud"] = http.StatusServiceUnavailable
+	hosts = nil
+	_, err = a.postReport(context.TODO(), fullURL, nil)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"report.armo.cloud", "report-eu.armo.cloud", "report-us.armo.cloud"}, hosts)
+	assert.Equal(t, breakerOpen, a.breaker.state())
+}
+
+func TestArmoAdapter_postReport_failoverPath(t *testing.T) {
+	var targets []string
+	a := &ArmoAdapter{
+		clusterConfig: armometadata.ClusterConfig{EventReceiverRestURL: "https://gateway.example.com/receiver/"},
+		httpPostFunc: func(_ httputils.IHttpClient, fullURL string, _ map[string]string, _ []byte) (*http.Response, error) {
+			targets = append(targets, fullURL)
+			if len(targets) < 3 {
+				return &http.Response{StatusCode: http.StatusBadGateway, Header: http.Header{}, Body: http.NoBody}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody}, nil
+		},
+	}
+	WithFailoverEndpoints([]string{"https://backup.example.com/events", "https://report.armo.cloud"})(a)
+	fullURL, err := reportURL(a.clusterConfig.EventReceiverRestURL, containerScanPathV2, "account")
+	require.NoError(t, err)
+
+	// each endpoint is posted to with its own path prefix
+	_, err = a.postReport(context.TODO(), fullURL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"https://gateway.example.com/receiver/k8s/v2/containerScan?customerGUID=account",
+		"https://backup.example.com/events/k8s/v2/containerScan?customerGUID=account",
+		"https://report.armo.cloud/k8s/v2/containerScan?customerGUID=account",
+	}, targets)
+
+	// the report URLs built with a failover endpoint are rewritten too
+	target, err := a.endpoints.rewrite("https://backup.example.com/events/k8s/capabilities", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "https://gateway.example.com/receiver/k8s/capabilities", target)
+}
+
+func TestReceiverEndpoints_order(t *testing.T) {
+	now := time.Now()
+	e := receiverEndpoints{
+		urls:      make([]*url.URL, 4),
+		downUntil: []time.Time{now.Add(time.Minute), {}, now.Add(time.Second), {}},
+	}
+	// the healthy endpoints in order, then the first to be tried again
+	assert.Equal(t, []int{1, 3, 2, 0}, e.order())
+	assert.Equal(t, "fallback", (&receiverEndpoints{}).preferred("fallback"))
+}
@@ -0,0 +1,67 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+)
+
+// falsePositiveException names the exceptions of the false positives, telling them apart from the exception policies
+// of the platform
+const falsePositiveException = "kubevuln-false-positive"
+
+// withFalsePositives appends to the vulnerabilities the ignored matches of the manifest marked as false positives,
+// reported as excepted by their feedback
+func withFalsePositives(ctx context.Context, vulnerabilities []containerscan.CommonContainerVulnerabilityResult, cve domain.CVEManifest) ([]containerscan.CommonContainerVulnerabilityResult, error) {
+	if len(cve.FalsePositives) == 0 || cve.Content == nil {
+		return vulnerabilities, nil
+	}
+	document := *cve.Content
+	document.Matches = nil
+	document.IgnoredMatches = nil
+	var falsePositives []domain.FalsePositive
+	for _, ignored := range cve.Content.IgnoredMatches {
+		if falsePositive, ok := falsePositiveOf(cve.FalsePositives, ignored.Match); ok {
+			document.Matches = append(document.Matches, ignored.Match)
+			falsePositives = append(falsePositives, falsePositive)
+		}
+	}
+	excepted, err := domainToArmo(ctx, document, nil)
+	if err != nil {
+		return vulnerabilities, err
+	}
+	// domainToArmo converts each match to one vulnerability, in order
+	for i := range excepted {
+		excepted[i].ExceptionApplied = []armotypes.VulnerabilityExceptionPolicy{falsePositiveExceptionPolicy(falsePositives[i])}
+	}
+	return append(vulnerabilities, excepted...), nil
+}
+
+// falsePositiveOf returns the first feedback marking the match as a false positive
+func falsePositiveOf(falsePositives []domain.FalsePositive, match v1beta1.Match) (domain.FalsePositive, bool) {
+	for _, falsePositive := range falsePositives {
+		if falsePositive.Matches(match) {
+			return falsePositive, true
+		}
+	}
+	return domain.FalsePositive{}, false
+}
+
+// falsePositiveExceptionPolicy returns the exception ignoring the vulnerability of a false positive
+func falsePositiveExceptionPolicy(falsePositive domain.FalsePositive) armotypes.VulnerabilityExceptionPolicy {
+	return armotypes.VulnerabilityExceptionPolicy{
+		PortalBase: armotypes.PortalBase{
+			GUID: falsePositive.ID,
+			Name: falsePositiveException,
+		},
+		PolicyType:            string(armotypes.VulnerabilityExceptionPolicyType),
+		CreationTime:          falsePositive.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000"),
+		Actions:               []armotypes.VulnerabilityExceptionPolicyActions{armotypes.Ignore},
+		VulnerabilityPolicies: []armotypes.VulnerabilityPolicy{{Name: falsePositive.Vulnerability}},
+		Reason:                falsePositive.Reason,
+		CreatedBy:             "kubevuln",
+	}
+}
@@ -0,0 +1,68 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_withFalsePositives(t *testing.T) {
+	ctx := context.WithValue(context.TODO(), domain.TimestampKey{}, time.Now().Unix())
+	ctx = context.WithValue(ctx, domain.ScanIDKey{}, "scanID")
+	ctx = context.WithValue(ctx, domain.WorkloadKey{}, domain.ScanCommand{})
+	match := func(id, name string) v1beta1.Match {
+		return v1beta1.Match{
+			Vulnerability: v1beta1.Vulnerability{VulnerabilityMetadata: v1beta1.VulnerabilityMetadata{ID: id, Severity: "High"}},
+			Artifact:      v1beta1.GrypePackage{Name: name, Version: "1.0.0"},
+		}
+	}
+	falsePositive := domain.FalsePositive{
+		ID:            "id",
+		Vulnerability: "CVE-2023-0286",
+		Package:       "openssl",
+		Reason:        "not reachable",
+		CreatedAt:     time.Date(2023, 2, 8, 10, 0, 0, 0, time.UTC),
+	}
+	cve := domain.CVEManifest{
+		Content: &v1beta1.GrypeDocument{
+			Source:  &v1beta1.Source{Target: json.RawMessage(`{"layers":[]}`)},
+			Matches: []v1beta1.Match{match("CVE-2021-44228", "log4j-core")},
+			IgnoredMatches: []v1beta1.IgnoredMatch{
+				{Match: match("CVE-2023-0286", "openssl")},
+				// ignored by a rule of the configuration, not by the feedback
+				{Match: match("CVE-2022-1292", "openssl")},
+			},
+		},
+	}
+	vulnerabilities := []containerscan.CommonContainerVulnerabilityResult{{Vulnerability: containerscan.Vulnerability{Name: "CVE-2021-44228"}}}
+
+	got, err := withFalsePositives(ctx, vulnerabilities, cve)
+	require.NoError(t, err)
+	assert.Equal(t, vulnerabilities, got)
+
+	cve.FalsePositives = []domain.FalsePositive{falsePositive}
+	got, err = withFalsePositives(ctx, vulnerabilities, cve)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, vulnerabilities[0], got[0])
+	assert.Equal(t, "CVE-2023-0286", got[1].Name)
+	assert.Equal(t, "openssl", got[1].RelatedPackageName)
+	assert.True(t, isExcepted(got[1]))
+	assert.Equal(t, []armotypes.VulnerabilityExceptionPolicy{{
+		PortalBase:            armotypes.PortalBase{GUID: "id", Name: falsePositiveException},
+		PolicyType:            "vulnerabilityExceptionPolicy",
+		CreationTime:          "2023-02-08T10:00:00.000000",
+		Actions:               []armotypes.VulnerabilityExceptionPolicyActions{armotypes.Ignore},
+		VulnerabilityPolicies: []armotypes.VulnerabilityPolicy{{Name: "CVE-2023-0286"}},
+		Reason:                "not reachable",
+		CreatedBy:             "kubevuln",
+	}}, got[1].ExceptionApplied)
+}
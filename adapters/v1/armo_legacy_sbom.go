@@ -0,0 +1,70 @@
+package v1
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// legacySBOMEncoding is the encoding of the SBOM attached to the v1 reports: the SPDX JSON document, gzipped then
+// base64 encoded
+const legacySBOMEncoding = "spdx-json+gzip+base64"
+
+// legacySBOMReport is a v1 report with the SBOM the vulnerabilities were matched from
+type legacySBOMReport struct {
+	containerscan.ScanResultReport
+	SBOM         string `json:"sbom,omitempty"`
+	SBOMEncoding string `json:"sbomEncoding,omitempty"`
+}
+
+// WithLegacySBOM attaches the SBOM of the scans to the v1 reports, for the event receivers predating the v2 reports,
+// once compressed and encoded if it is at most maxSize bytes, zero disables it
+func WithLegacySBOM(maxSize int64) ArmoAdapterOption {
+	return func(a *ArmoAdapter) {
+		a.legacySBOMMaxSize = maxSize
+	}
+}
+
+// withLegacySBOM adds the SBOM of the scan in the context to a v1 report, the SBOMs over the size cap are left out
+func (a *ArmoAdapter) withLegacySBOM(ctx context.Context, legacy containerscan.ScanResultReport) interface{} {
+	if a.legacySBOMMaxSize <= 0 {
+		return legacy
+	}
+	sbom, ok := ctx.Value(domain.SBOMKey{}).(domain.SBOM)
+	if !ok || sbom.Content == nil {
+		return legacy
+	}
+	encoded, err := encodeSBOM(sbom)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to encode the SBOM of the v1 report, leaving it out", helpers.Error(err),
+			helpers.String("imageSlug", sbom.Name))
+		return legacy
+	}
+	if int64(len(encoded)) > a.legacySBOMMaxSize {
+		logger.L().Ctx(ctx).Warning("SBOM of the v1 report over the size cap, leaving it out",
+			helpers.String("imageSlug", sbom.Name),
+			helpers.Int("size", len(encoded)))
+		return legacy
+	}
+	return legacySBOMReport{ScanResultReport: legacy, SBOM: encoded, SBOMEncoding: legacySBOMEncoding}
+}
+
+// encodeSBOM gzips the SPDX JSON document of an SBOM and encodes it in base64
+func encodeSBOM(sbom domain.SBOM) (string, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(sbom.Content); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
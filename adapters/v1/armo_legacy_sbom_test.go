@@ -0,0 +1,44 @@
+package v1
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArmoAdapter_withLegacySBOM(t *testing.T) {
+	legacy := containerscan.ScanResultReport{CustomerGUID: "account", ImgTag: "alpine:3.18"}
+	sbom := domain.SBOM{Name: "alpine-slug", Content: fileToSBOM("testdata/alpine-sbom.json")}
+	ctx := context.WithValue(context.TODO(), domain.SBOMKey{}, sbom)
+
+	// disabled, or without SBOM in the context
+	assert.Equal(t, legacy, (&ArmoAdapter{}).withLegacySBOM(ctx, legacy))
+	assert.Equal(t, legacy, (&ArmoAdapter{legacySBOMMaxSize: 1 << 20}).withLegacySBOM(context.TODO(), legacy))
+
+	// the SBOM is attached compressed and encoded, next to the report fields
+	payload, err := json.Marshal((&ArmoAdapter{legacySBOMMaxSize: 1 << 20}).withLegacySBOM(ctx, legacy))
+	require.NoError(t, err)
+	var report legacySBOMReport
+	require.NoError(t, json.Unmarshal(payload, &report))
+	assert.Equal(t, "alpine:3.18", report.ImgTag)
+	assert.Equal(t, legacySBOMEncoding, report.SBOMEncoding)
+	compressed, err := base64.StdEncoding.DecodeString(report.SBOM)
+	require.NoError(t, err)
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	var document v1beta1.Document
+	require.NoError(t, json.NewDecoder(zr).Decode(&document))
+	assert.Equal(t, sbom.Content.Packages, document.Packages)
+
+	// the SBOMs over the size cap are left out
+	assert.Equal(t, legacy, (&ArmoAdapter{legacySBOMMaxSize: int64(len(report.SBOM) - 1)}).withLegacySBOM(ctx, legacy))
+}
@@ -0,0 +1,165 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// rate limit headers of the event receiver
+const (
+	headerRateLimitLimit     = "X-RateLimit-Limit"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+	headerRetryAfter         = "Retry-After"
+)
+
+const (
+	// defaultRetryAfter is the pause after a 429 response, or an exhausted quota, telling no reset time
+	defaultRetryAfter = time.Minute
+	// lowQuotaRatio is the share of the quota left under which the low priority reports are deferred
+	lowQuotaRatio = 0.1
+	// maxRateLimitedRetries caps the posts of a report part rejected with 429
+	maxRateLimitedRetries = 3
+	// epochThreshold tells the reset times given as Unix timestamps from the ones given in seconds
+	epochThreshold = 1_000_000_000
+)
+
+// reasons for which a report is deferred
+const (
+	deferredLowQuota    = "low_quota"
+	deferredRateLimited = "rate_limited"
+)
+
+var deferredCounter, _ = otel.Meter("").Int64Counter("kubevuln_report_deferred",
+	metric.WithDescription("Number of reports deferred to honor the quota of the event receiver, by reason"))
+
+var deferredSeconds, _ = otel.Meter("").Float64Histogram("kubevuln_report_deferred_seconds",
+	metric.WithDescription("Time reports waited for the quota of the event receiver, by reason"))
+
+// submissionQuota tracks the quota of the event receiver, read from the headers of its responses
+type submissionQuota struct {
+	mu sync.Mutex
+	// blockedUntil is when the reports can be sent again
+	blockedUntil time.Time
+	// lowUntil is when the low priority reports can be sent again
+	lowUntil time.Time
+}
+
+// observe updates the quota from a response of the event receiver
+func (q *submissionQuota) observe(resp *http.Response) {
+	now := time.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		q.blockedUntil = later(q.blockedUntil, now.Add(retryAfter(resp.Header.Get(headerRetryAfter), now)))
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get(headerRateLimitRemaining))
+	if err != nil {
+		return
+	}
+	reset := now.Add(defaultRetryAfter)
+	if value, err := strconv.ParseInt(resp.Header.Get(headerRateLimitReset), 10, 64); err == nil {
+		if value > epochThreshold {
+			reset = time.Unix(value, 0)
+		} else {
+			reset = now.Add(time.Duration(value) * time.Second)
+		}
+	}
+	limit, _ := strconv.Atoi(resp.Header.Get(headerRateLimitLimit))
+	switch {
+	case remaining <= 0:
+		q.blockedUntil = later(q.blockedUntil, reset)
+	case limit > 0 && float64(remaining) <= float64(limit)*lowQuotaRatio:
+		q.lowUntil = later(q.lowUntil, reset)
+	}
+}
+
+// wait blocks until the quota allows sending a report, the low priority ones also wait while the quota is low.
+// The time waited is recorded in the metrics, an error is returned if ctx is done first.
+func (q *submissionQuota) wait(ctx context.Context, lowPriority bool) error {
+	q.mu.Lock()
+	until, reason := q.blockedUntil, deferredRateLimited
+	if lowPriority && q.lowUntil.After(until) {
+		until, reason = q.lowUntil, deferredLowQuota
+	}
+	q.mu.Unlock()
+	delay := time.Until(until)
+	if delay <= 0 {
+		return nil
+	}
+	logger.L().Ctx(ctx).Info("deferring report to honor the event receiver quota",
+		helpers.String("reason", reason),
+		helpers.String("delay", delay.String()))
+	attributes := metric.WithAttributes(attribute.String("reason", reason))
+	if deferredCounter != nil {
+		deferredCounter.Add(ctx, 1, attributes)
+	}
+	start := time.Now()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if deferredSeconds != nil {
+		deferredSeconds.Record(ctx, time.Since(start).Seconds(), attributes)
+	}
+	return nil
+}
+
+// retryAfter parses a Retry-After header, given in seconds or as an HTTP date
+func retryAfter(value string, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date.Sub(now)
+	}
+	return defaultRetryAfter
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// postReport posts a report part to the event receiver and records its quota and the outcome for the circuit
+// breaker, a part rejected with 429 is posted again once the quota allows it
+func (a *ArmoAdapter) postReport(ctx context.Context, fullURL string, payload []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if a.breaker.blocked() {
+			return nil, domain.ErrCircuitOpen
+		}
+		resp, err := a.postEncoded(ctx, fullURL, payload)
+		if err != nil {
+			// the posts abandoned by the scans are not failures of the event receiver
+			if ctx.Err() == nil {
+				a.recordPost(ctx, true)
+			}
+			return nil, err
+		}
+		a.recordPost(ctx, resp.StatusCode >= http.StatusInternalServerError)
+		a.quota.observe(resp)
+		if resp.StatusCode != http.StatusTooManyRequests || attempt == maxRateLimitedRetries {
+			return resp, nil
+		}
+		_ = resp.Body.Close()
+		// the parts of a report being sent are not deferred any longer than needed
+		if err := a.quota.wait(ctx, false); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/armosec/utils-go/httputils"
+	"github.com/google/uuid"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_submissionQuota_observe(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		status      int
+		headers     map[string]string
+		wantBlocked time.Duration
+		wantLow     time.Duration
+	}{
+		{
+			name:   "plenty of quota",
+			status: http.StatusOK,
+			headers: map[string]string{
+				headerRateLimitLimit:     "100",
+				headerRateLimitRemaining: "50",
+				headerRateLimitReset:     "30",
+			},
+		},
+		{
+			name:   "low quota",
+			status: http.StatusOK,
+			headers: map[string]string{
+				headerRateLimitLimit:     "100",
+				headerRateLimitRemaining: "5",
+				headerRateLimitReset:     "30",
+			},
+			wantLow: 30 * time.Second,
+		},
+		{
+			name:   "exhausted quota reset at a timestamp",
+			status: http.StatusOK,
+			headers: map[string]string{
+				headerRateLimitRemaining: "0",
+				headerRateLimitReset:     strconv.FormatInt(now.Add(45*time.Second).Unix(), 10),
+			},
+			wantBlocked: 45 * time.Second,
+		},
+		{
+			name:        "too many requests",
+			status:      http.StatusTooManyRequests,
+			headers:     map[string]string{headerRetryAfter: "20"},
+			wantBlocked: 20 * time.Second,
+		},
+		{
+			name:        "too many requests until a date",
+			status:      http.StatusTooManyRequests,
+			headers:     map[string]string{headerRetryAfter: now.Add(90 * time.Second).UTC().Format(http.TimeFormat)},
+			wantBlocked: 90 * time.Second,
+		},
+		{
+			name:        "too many requests without retry",
+			status:      http.StatusTooManyRequests,
+			wantBlocked: defaultRetryAfter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for key, value := range tt.headers {
+				resp.Header.Set(key, value)
+			}
+			var q submissionQuota
+			q.observe(resp)
+			assertUntil(t, tt.wantBlocked, q.blockedUntil)
+			assertUntil(t, tt.wantLow, q.lowUntil)
+		})
+	}
+}
+
+// assertUntil checks that until is about want from now, or unset when want is zero
+func assertUntil(t *testing.T, want time.Duration, until time.Time) {
+	if want == 0 {
+		assert.True(t, until.IsZero(), until)
+		return
+	}
+	assert.WithinDuration(t, time.Now().Add(want), until, 2*time.Second)
+}
+
+func Test_submissionQuota_wait(t *testing.T) {
+	var q submissionQuota
+	assert.NoError(t, q.wait(context.TODO(), true))
+
+	// only the low priority reports wait while the quota is low
+	q.lowUntil = time.Now().Add(time.Hour)
+	assert.NoError(t, q.wait(context.TODO(), false))
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q.wait(ctx, true), context.DeadlineExceeded)
+
+	q.blockedUntil = time.Now().Add(50 * time.Millisecond)
+	start := time.Now()
+	assert.NoError(t, q.wait(context.TODO(), false))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestArmoAdapter_SubmitCVE_rateLimited(t *testing.T) {
+	mu := &sync.Mutex{}
+	var posts int
+	a := &ArmoAdapter{
+		getCVEExceptionsFunc: func(string, string, *armotypes.PortalDesignator) ([]armotypes.VulnerabilityExceptionPolicy, error) {
+			return nil, nil
+		},
+		httpPostFunc: func(_ httputils.IHttpClient, _ string, _ map[string]string, _ []byte) (*http.Response, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			posts++
+			resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(bytes.NewBuffer([]byte{}))}
+			// the first post is rejected, to be sent again right away
+			if posts == 1 {
+				resp.StatusCode = http.StatusTooManyRequests
+				resp.Header.Set(headerRetryAfter, "0")
+			}
+			return resp, nil
+		},
+		reportVersion: ReportVersionV2,
+	}
+	ctx := context.TODO()
+	ctx = context.WithValue(ctx, domain.TimestampKey{}, time.Now().Unix())
+	ctx = context.WithValue(ctx, domain.ScanIDKey{}, uuid.New().String())
+	ctx = context.WithValue(ctx, domain.WorkloadKey{}, domain.ScanCommand{JobID: "jobID"})
+	assert.NoError(t, a.SubmitCVE(ctx, fileToCVEManifest("testdata/nginx-cve-small.json"), domain.CVEManifest{}))
+	assert.Equal(t, 2, posts)
+
+	// an exhausted quota defers the next reports
+	a.quota.blockedUntil = time.Now().Add(time.Hour)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, a.SubmitCVE(ctx, fileToCVEManifest("testdata/nginx-cve-small.json"), domain.CVEManifest{}), context.DeadlineExceeded)
+	assert.Equal(t, 2, posts)
+}
@@ -0,0 +1,56 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// reasons for which report entries are sanitized
+const (
+	sanitizedDuplicate       = "duplicate"
+	sanitizedMissingName     = "missing_name"
+	sanitizedMissingSeverity = "missing_severity"
+)
+
+var sanitizedCounter, _ = otel.Meter("").Int64Counter("kubevuln_report_sanitized_entries",
+	metric.WithDescription("Number of vulnerabilities dropped from reports before submission, by reason"))
+
+// sanitizeVulnerabilities is the quality gate of the reports: it drops the duplicate vulnerabilities
+// (same CVE, package and version) and the ones without name or severity, keeping the order of the others
+func sanitizeVulnerabilities(ctx context.Context, vulnerabilities []containerscan.CommonContainerVulnerabilityResult) []containerscan.CommonContainerVulnerabilityResult {
+	sanitized := map[string]int64{}
+	seen := map[string]struct{}{}
+	result := make([]containerscan.CommonContainerVulnerabilityResult, 0, len(vulnerabilities))
+	for _, v := range vulnerabilities {
+		switch {
+		case v.Name == "":
+			sanitized[sanitizedMissingName]++
+			continue
+		case v.Severity == "":
+			sanitized[sanitizedMissingSeverity]++
+			continue
+		}
+		id := v.Name + "+" + v.RelatedPackageName + "@" + v.PackageVersion
+		if _, ok := seen[id]; ok {
+			sanitized[sanitizedDuplicate]++
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, v)
+	}
+	for reason, count := range sanitized {
+		if sanitizedCounter != nil {
+			sanitizedCounter.Add(ctx, count, metric.WithAttributes(attribute.String("reason", reason)))
+		}
+		logger.L().Ctx(ctx).Warning("sanitized report entries",
+			helpers.String("reason", reason),
+			helpers.Int("count", int(count)))
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+	"github.com/stretchr/testify/assert"
+)
+
+func vulnerability(name, severity, pkg, version string) containerscan.CommonContainerVulnerabilityResult {
+	return containerscan.CommonContainerVulnerabilityResult{
+		Vulnerability: containerscan.Vulnerability{
+			Name:               name,
+			Severity:           severity,
+			RelatedPackageName: pkg,
+			PackageVersion:     version,
+		},
+	}
+}
+
+func Test_sanitizeVulnerabilities(t *testing.T) {
+	tests := []struct {
+		name            string
+		vulnerabilities []containerscan.CommonContainerVulnerabilityResult
+		want            []containerscan.CommonContainerVulnerabilityResult
+	}{
+		{
+			name:            "empty",
+			vulnerabilities: nil,
+			want:            []containerscan.CommonContainerVulnerabilityResult{},
+		},
+		{
+			name: "valid entries are kept in order",
+			vulnerabilities: []containerscan.CommonContainerVulnerabilityResult{
+				vulnerability("CVE-2023-0286", "High", "openssl", "3.0.7"),
+				vulnerability("CVE-2023-0286", "High", "openssl", "1.1.1"),
+				vulnerability("CVE-2022-37434", "Critical", "zlib", "1.2.12"),
+			},
+			want: []containerscan.CommonContainerVulnerabilityResult{
+				vulnerability("CVE-2023-0286", "High", "openssl", "3.0.7"),
+				vulnerability("CVE-2023-0286", "High", "openssl", "1.1.1"),
+				vulnerability("CVE-2022-37434", "Critical", "zlib", "1.2.12"),
+			},
+		},
+		{
+			name: "duplicates and incomplete entries are dropped",
+			vulnerabilities: []containerscan.CommonContainerVulnerabilityResult{
+				vulnerability("CVE-2023-0286", "High", "openssl", "3.0.7"),
+				vulnerability("", "High", "openssl", "3.0.7"),
+				vulnerability("CVE-2022-37434", "", "zlib", "1.2.12"),
+				vulnerability("CVE-2023-0286", "High", "openssl", "3.0.7"),
+			},
+			want: []containerscan.CommonContainerVulnerabilityResult{
+				vulnerability("CVE-2023-0286", "High", "openssl", "3.0.7"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sanitizeVulnerabilities(context.TODO(), tt.vulnerabilities))
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package v1
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+)
+
+// severityOrder sorts the severities stats from the most to the least severe
+var severityOrder = map[string]int{
+	containerscan.CriticalSeverity:   0,
+	containerscan.HighSeverity:       1,
+	containerscan.MediumSeverity:     2,
+	containerscan.LowSeverity:        3,
+	containerscan.NegligibleSeverity: 4,
+	containerscan.UnknownSeverity:    5,
+}
+
+// severitySummarizer computes the severities stats of a report: the global stats and the per severity stats
+// count the vulnerabilities which are not excepted, excepted vulnerabilities are counted apart
+type severitySummarizer struct {
+	global   containerscan.SeverityStats
+	actual   map[string]containerscan.SeverityStats
+	excepted map[string]containerscan.SeverityStats
+}
+
+func newSeveritySummarizer() *severitySummarizer {
+	return &severitySummarizer{
+		actual:   map[string]containerscan.SeverityStats{},
+		excepted: map[string]containerscan.SeverityStats{},
+	}
+}
+
+// isExcepted tells whether an ignore exception applies to the vulnerability
+func isExcepted(v containerscan.CommonContainerVulnerabilityResult) bool {
+	return len(v.ExceptionApplied) > 0 &&
+		len(v.ExceptionApplied[0].Actions) > 0 &&
+		v.ExceptionApplied[0].Actions[0] == armotypes.Ignore
+}
+
+// add counts the vulnerability, whose severity must be known
+func (s *severitySummarizer) add(v containerscan.CommonContainerVulnerabilityResult) {
+	excepted := isExcepted(v)
+	stats := s.actual
+	if excepted {
+		stats = s.excepted
+	}
+	severityStats, ok := stats[v.Severity]
+	if !ok {
+		severityStats = containerscan.SeverityStats{Severity: v.Severity}
+	}
+	severityStats.TotalCount++
+	incrementCounter(&s.global.TotalCount, true, excepted)
+	isFixed := containerscan.CalculateFixed(v.Fixes) > 0
+	if isFixed {
+		severityStats.FixAvailableOfTotalCount++
+		incrementCounter(&s.global.FixAvailableOfTotalCount, true, excepted)
+	}
+	if v.IsRCE() {
+		severityStats.RCECount++
+		incrementCounter(&s.global.RCECount, true, excepted)
+		if isFixed {
+			severityStats.RCEFixCount++
+			incrementCounter(&s.global.RCEFixCount, true, excepted)
+		}
+	}
+	if isRelevant := v.GetIsRelevant(); isRelevant != nil && *isRelevant {
+		severityStats.RelevantCount++
+		incrementCounter(&s.global.RelevantCount, true, excepted)
+		if isFixed {
+			severityStats.RelevantFixCount++
+			incrementCounter(&s.global.RelevantFixCount, true, excepted)
+		}
+	}
+	stats[v.Severity] = severityStats
+}
+
+// stats returns the global stats, and the per severity stats of the vulnerabilities not excepted and excepted,
+// sorted from the most to the least severe; with relevancy data, stats not excepted are marked as relevancy scans
+func (s *severitySummarizer) stats(hasRelevancy bool) (containerscan.SeverityStats, []containerscan.SeverityStats, []containerscan.SeverityStats) {
+	global := s.global
+	if hasRelevancy {
+		global.RelevancyScanCount = 1
+	}
+	sorted := func(stats map[string]containerscan.SeverityStats, relevancyScan bool) []containerscan.SeverityStats {
+		var result []containerscan.SeverityStats
+		for _, severityStats := range stats {
+			if relevancyScan {
+				severityStats.RelevancyScanCount = 1
+			}
+			result = append(result, severityStats)
+		}
+		sort.Slice(result, func(i, j int) bool {
+			return severityOrder[result[i].Severity] < severityOrder[result[j].Severity]
+		})
+		return result
+	}
+	return global, sorted(s.actual, hasRelevancy), sorted(s.excepted, false)
+}
+
+// verifySummary checks that the summary counts match the submitted vulnerabilities
+func verifySummary(summary *containerscan.CommonContainerScanSummaryResult, vulnerabilities []containerscan.CommonContainerVulnerabilityResult) error {
+	var actual, excepted, relevant int64
+	for _, stats := range summary.SeveritiesStats {
+		actual += stats.TotalCount
+		relevant += stats.RelevantCount
+	}
+	for _, stats := range summary.ExcludedSeveritiesStats {
+		excepted += stats.TotalCount
+	}
+	switch {
+	case actual != summary.TotalCount:
+		return fmt.Errorf("severities stats count %d vulnerabilities, summary %d", actual, summary.TotalCount)
+	case relevant != summary.RelevantCount:
+		return fmt.Errorf("severities stats count %d relevant vulnerabilities, summary %d", relevant, summary.RelevantCount)
+	case int64(len(summary.Vulnerabilities)) != summary.TotalCount:
+		return fmt.Errorf("summary lists %d vulnerabilities, counts %d", len(summary.Vulnerabilities), summary.TotalCount)
+	case actual+excepted != int64(len(vulnerabilities)):
+		return fmt.Errorf("summary counts %d vulnerabilities and %d excepted, report has %d", actual, excepted, len(vulnerabilities))
+	}
+	return nil
+}
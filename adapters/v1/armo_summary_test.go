@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+	v1 "github.com/armosec/cluster-container-scanner-api/containerscan/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/pointer"
+)
+
+func summaryVulnerability(name, severity string, fixed, relevant, excepted bool) containerscan.CommonContainerVulnerabilityResult {
+	v := containerscan.CommonContainerVulnerabilityResult{
+		Vulnerability: containerscan.Vulnerability{Name: name, Severity: severity, IsRelevant: pointer.Bool(relevant)},
+	}
+	if fixed {
+		v.Vulnerability.Fixes = containerscan.VulFixes{{Version: "1.0.1"}}
+	}
+	if excepted {
+		v.ExceptionApplied = []armotypes.VulnerabilityExceptionPolicy{{
+			PolicyType: "vulnerabilityExceptionPolicy",
+			Actions:    []armotypes.VulnerabilityExceptionPolicyActions{armotypes.Ignore},
+		}}
+	}
+	return v
+}
+
+func Test_severitySummarizer(t *testing.T) {
+	tests := []struct {
+		name            string
+		vulnerabilities []containerscan.CommonContainerVulnerabilityResult
+		hasRelevancy    bool
+		wantGlobal      containerscan.SeverityStats
+		wantActual      []containerscan.SeverityStats
+		wantExcepted    []containerscan.SeverityStats
+	}{
+		{
+			name: "no vulnerabilities",
+		},
+		{
+			name: "sorted by severity",
+			vulnerabilities: []containerscan.CommonContainerVulnerabilityResult{
+				summaryVulnerability("CVE-1", containerscan.LowSeverity, false, false, false),
+				summaryVulnerability("CVE-2", containerscan.CriticalSeverity, true, false, false),
+				summaryVulnerability("CVE-3", containerscan.UnknownSeverity, false, false, false),
+				summaryVulnerability("CVE-4", containerscan.CriticalSeverity, false, false, false),
+			},
+			wantGlobal: containerscan.SeverityStats{TotalCount: 4, FixAvailableOfTotalCount: 1},
+			wantActual: []containerscan.SeverityStats{
+				{Severity: containerscan.CriticalSeverity, TotalCount: 2, FixAvailableOfTotalCount: 1},
+				{Severity: containerscan.LowSeverity, TotalCount: 1},
+				{Severity: containerscan.UnknownSeverity, TotalCount: 1},
+			},
+		},
+		{
+			name: "excepted counted apart",
+			vulnerabilities: []containerscan.CommonContainerVulnerabilityResult{
+				summaryVulnerability("CVE-1", containerscan.HighSeverity, true, true, false),
+				summaryVulnerability("CVE-2", containerscan.HighSeverity, true, true, true),
+				summaryVulnerability("CVE-3", containerscan.MediumSeverity, false, false, true),
+			},
+			hasRelevancy: true,
+			wantGlobal:   containerscan.SeverityStats{TotalCount: 1, FixAvailableOfTotalCount: 1, RelevantCount: 1, RelevantFixCount: 1, RelevancyScanCount: 1},
+			wantActual: []containerscan.SeverityStats{
+				{Severity: containerscan.HighSeverity, TotalCount: 1, FixAvailableOfTotalCount: 1, RelevantCount: 1, RelevantFixCount: 1, RelevancyScanCount: 1},
+			},
+			wantExcepted: []containerscan.SeverityStats{
+				{Severity: containerscan.HighSeverity, TotalCount: 1, FixAvailableOfTotalCount: 1, RelevantCount: 1, RelevantFixCount: 1},
+				{Severity: containerscan.MediumSeverity, TotalCount: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSeveritySummarizer()
+			for _, v := range tt.vulnerabilities {
+				s.add(v)
+			}
+			global, actual, excepted := s.stats(tt.hasRelevancy)
+			assert.Equal(t, tt.wantGlobal, global)
+			assert.Equal(t, tt.wantActual, actual)
+			assert.Equal(t, tt.wantExcepted, excepted)
+		})
+	}
+}
+
+func Test_verifySummary(t *testing.T) {
+	vulnerabilities := []containerscan.CommonContainerVulnerabilityResult{
+		summaryVulnerability("CVE-1", containerscan.HighSeverity, true, true, false),
+		summaryVulnerability("CVE-2", containerscan.HighSeverity, false, false, true),
+		summaryVulnerability("CVE-3", "bogus", false, false, false),
+	}
+	summary, vulnerabilities := summarize(v1.ScanResultReport{}, vulnerabilities, domain.ScanCommand{}, true)
+	assert.NoError(t, verifySummary(summary, vulnerabilities))
+	assert.Equal(t, int64(2), summary.TotalCount)
+	assert.Len(t, summary.ExcludedSeveritiesStats, 1)
+
+	tests := []struct {
+		name   string
+		modify func(*containerscan.CommonContainerScanSummaryResult)
+	}{
+		{
+			name:   "total count",
+			modify: func(s *containerscan.CommonContainerScanSummaryResult) { s.TotalCount++ },
+		},
+		{
+			name:   "relevant count",
+			modify: func(s *containerscan.CommonContainerScanSummaryResult) { s.RelevantCount = 0 },
+		},
+		{
+			name:   "vulnerabilities list",
+			modify: func(s *containerscan.CommonContainerScanSummaryResult) { s.Vulnerabilities = s.Vulnerabilities[1:] },
+		},
+		{
+			name:   "excepted count",
+			modify: func(s *containerscan.CommonContainerScanSummaryResult) { s.ExcludedSeveritiesStats = nil },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified := *summary
+			tt.modify(&modified)
+			assert.Error(t, verifySummary(&modified, vulnerabilities))
+		})
+	}
+}
@@ -196,6 +196,87 @@ func TestArmoAdapter_SubmitCVE(t *testing.T) {
 	}
 }
 
+func TestArmoAdapter_SubmitCVE_summaryFirst(t *testing.T) {
+	tests := []struct {
+		name            string
+		cve             domain.CVEManifest
+		summaryFails    bool
+		wantProvisional bool
+		wantErr         bool
+	}{
+		{
+			name: "single report is final",
+			cve:  fileToCVEManifest("testdata/nginx-cve-small.json"),
+		},
+		{
+			name:            "summary sent before the chunks",
+			cve:             fileToCVEManifest("testdata/nginx-cve.json"),
+			wantProvisional: true,
+		},
+		{
+			name:            "no chunk sent without summary",
+			cve:             fileToCVEManifest("testdata/nginx-cve.json"),
+			summaryFails:    true,
+			wantProvisional: true,
+			wantErr:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu := &sync.Mutex{}
+			var reports []v1.ScanResultReport
+			a := &ArmoAdapter{
+				getCVEExceptionsFunc: func(string, string, *armotypes.PortalDesignator) ([]armotypes.VulnerabilityExceptionPolicy, error) {
+					return nil, nil
+				},
+				httpPostFunc: func(_ httputils.IHttpClient, _ string, _ map[string]string, body []byte) (*http.Response, error) {
+					var report v1.ScanResultReport
+					assert.NoError(t, json.Unmarshal(body, &report))
+					mu.Lock()
+					reports = append(reports, report)
+					mu.Unlock()
+					if tt.summaryFails {
+						return nil, domain.ErrMockError
+					}
+					return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBuffer([]byte{}))}, nil
+				},
+				reportVersion: ReportVersionV2,
+			}
+			ctx := context.TODO()
+			ctx = context.WithValue(ctx, domain.TimestampKey{}, time.Now().Unix())
+			ctx = context.WithValue(ctx, domain.ScanIDKey{}, uuid.New().String())
+			ctx = context.WithValue(ctx, domain.WorkloadKey{}, domain.ScanCommand{})
+			err := a.SubmitCVE(ctx, tt.cve, domain.CVEManifest{})
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			if !assert.NotEmpty(t, reports) {
+				return
+			}
+			summary := reports[0]
+			assert.NotNil(t, summary.Summary)
+			if !tt.wantProvisional {
+				assert.Len(t, reports, 1)
+				assert.True(t, summary.PaginationInfo.IsLastReport)
+				assert.NotContains(t, summary.Designators.Attributes, attributeProvisionalSummary)
+				return
+			}
+			assert.Equal(t, "true", summary.Designators.Attributes[attributeProvisionalSummary])
+			assert.Equal(t, "true", summary.Summary.Designators.Attributes[attributeProvisionalSummary])
+			assert.Empty(t, summary.Vulnerabilities)
+			assert.False(t, summary.PaginationInfo.IsLastReport)
+			if tt.summaryFails {
+				assert.Len(t, reports, 1)
+				return
+			}
+			assert.Greater(t, len(reports), 2)
+			for _, chunk := range reports[1:] {
+				assert.Nil(t, chunk.Summary)
+				assert.NotEmpty(t, chunk.Vulnerabilities)
+				assert.NotContains(t, chunk.Designators.Attributes, attributeProvisionalSummary)
+			}
+		})
+	}
+}
+
 func TestNewArmoAdapter(t *testing.T) {
 	type args struct {
 		accountID            string
@@ -267,3 +348,128 @@ func TestArmoAdapter_SendStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestArmoAdapter_SendStatus_cancellation(t *testing.T) {
+	// a backend never acknowledging the status
+	a := &ArmoAdapter{
+		sendStatusFunc: func(*sysreport.BaseReport, string, bool, chan<- error) {},
+	}
+	ctx, cancel := context.WithCancel(context.WithValue(context.TODO(), domain.WorkloadKey{}, domain.ScanCommand{Wlid: "wlid"}))
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	assert.ErrorIs(t, a.SendStatus(ctx, domain.Started), context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second, "status not abandoned within 5s of the cancellation")
+}
+
+func TestArmoAdapter_SendStatus_failed(t *testing.T) {
+	var details, status string
+	a := &ArmoAdapter{
+		sendStatusFunc: func(report *sysreport.BaseReport, _ string, _ bool, c chan<- error) {
+			details, status = report.Details, report.Status
+			close(c)
+		},
+	}
+	ctx := context.WithValue(context.TODO(), domain.WorkloadKey{}, domain.ScanCommand{Wlid: "wlid"})
+	ctx = context.WithValue(ctx, domain.ErrorCodeKey{}, domain.ErrorCodeImagePull)
+	assert.NoError(t, a.SendStatus(ctx, domain.Failed))
+	assert.Equal(t, "Dequeueing", status)
+	assert.Equal(t, sysreport.JobFailed+" (imagePull)", details)
+}
+
+func Test_injectArtifactAttributes(t *testing.T) {
+	attributes := map[string]string{"namespace": "default"}
+	injectArtifactAttributes(map[string]string{}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default"}, attributes)
+	injectArtifactAttributes(map[string]string{domain.AnnotationArtifactType: domain.ArtifactTypeHelmChart}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default", attributeArtifactType: domain.ArtifactTypeHelmChart}, attributes)
+}
+
+func Test_injectEmbeddedAttributes(t *testing.T) {
+	attributes := map[string]string{"namespace": "default"}
+	injectEmbeddedAttributes(map[string]string{}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default"}, attributes)
+	injectEmbeddedAttributes(map[string]string{
+		domain.AnnotationEmbeddedImages: `[{"path":"/kind/images/kindnetd.tar","image":"kindest/kindnetd:v20230511"},{"path":"/kind/images/pause.tar","image":"registry.k8s.io/pause:3.7"}]`,
+	}, attributes)
+	assert.Equal(t, map[string]string{
+		"namespace":             "default",
+		attributeEmbeddedImages: "kindest/kindnetd:v20230511,registry.k8s.io/pause:3.7",
+	}, attributes)
+}
+
+func Test_injectGoldenBaseAttributes(t *testing.T) {
+	attributes := map[string]string{"namespace": "default"}
+	injectGoldenBaseAttributes(map[string]string{}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default"}, attributes)
+	injectGoldenBaseAttributes(map[string]string{domain.AnnotationGoldenBase: domain.GoldenBaseStale, domain.AnnotationGoldenBaseVersion: "2023-06"}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default", attributeGoldenBase: "stale", attributeGoldenBaseVersion: "2023-06"}, attributes)
+}
+
+func Test_injectPartialAttributes(t *testing.T) {
+	attributes := map[string]string{"namespace": "default"}
+	injectPartialAttributes(map[string]string{}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default"}, attributes)
+	injectPartialAttributes(map[string]string{domain.AnnotationPartial: "true"}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default", attributePartial: "true"}, attributes)
+	injectPartialAttributes(map[string]string{
+		domain.AnnotationPartial:         "true",
+		domain.AnnotationCatalogerStatus: `[{"name":"dpkgdb-cataloger","attempts":1},{"name":"rpm-db-cataloger","attempts":2,"error":"corrupt database"}]`,
+	}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default", attributePartial: "true", attributeFailedCatalogers: "rpm-db-cataloger"}, attributes)
+}
+
+func Test_injectRelayAttributes(t *testing.T) {
+	attributes := map[string]string{"namespace": "default"}
+	injectRelayAttributes(map[string]string{}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default"}, attributes)
+	injectRelayAttributes(map[string]string{domain.AnnotationRelayedFrom: "edge-1"}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default", attributeRelayedFrom: "edge-1"}, attributes)
+}
+
+func Test_injectRepositoryAttributes(t *testing.T) {
+	attributes := map[string]string{"namespace": "default"}
+	injectRepositoryAttributes(map[string]string{}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default"}, attributes)
+	injectRepositoryAttributes(map[string]string{
+		domain.AnnotationRepositoryCommit: "0123456789abcdef0123456789abcdef01234567",
+		domain.AnnotationRepositoryURL:    "https://github.com/kubescape/kubevuln",
+	}, attributes)
+	assert.Equal(t, map[string]string{
+		"namespace":               "default",
+		attributeRepositoryCommit: "0123456789abcdef0123456789abcdef01234567",
+		attributeRepositoryURL:    "https://github.com/kubescape/kubevuln",
+	}, attributes)
+}
+
+func Test_injectRiskAttributes(t *testing.T) {
+	attributes := map[string]string{"namespace": "default"}
+	injectRiskAttributes(map[string]string{}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default"}, attributes)
+	injectRiskAttributes(map[string]string{domain.AnnotationRiskScore: "28.5"}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default", attributeImageRiskScore: "28.5"}, attributes)
+}
+
+func Test_injectScanProvenanceAttributes(t *testing.T) {
+	attributes := map[string]string{"namespace": "default"}
+	injectScanProvenanceAttributes(map[string]string{}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default"}, attributes)
+	provenance := `{"sbomCreatorVersion":"v0.76.0","cveScannerVersion":"v0.61.0","cveDBVersion":"sha256:0123","nodeName":"node-1","scannedAt":"2023-01-01T00:00:00Z"}`
+	injectScanProvenanceAttributes(map[string]string{domain.AnnotationScanProvenance: provenance}, attributes)
+	assert.Equal(t, map[string]string{"namespace": "default", attributeScanProvenance: provenance}, attributes)
+}
+
+func Test_injectProvenanceAttributes(t *testing.T) {
+	attributes := map[string]string{"namespace": "default"}
+	injectProvenanceAttributes(map[string]string{
+		domain.AnnotationImageCreated:       "2023-01-01T00:00:00Z",
+		domain.AnnotationImageTooOld:        "true",
+		"org.opencontainers.image.revision": "abc123",
+		"unrelated":                         "value",
+	}, attributes)
+	assert.Equal(t, map[string]string{
+		"namespace":                         "default",
+		attributeImageCreated:               "2023-01-01T00:00:00Z",
+		attributeImageTooOld:                "true",
+		"org.opencontainers.image.revision": "abc123",
+	}, attributes)
+}
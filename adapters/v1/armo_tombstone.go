@@ -0,0 +1,45 @@
+package v1
+
+import (
+	"context"
+	"time"
+
+	"github.com/armosec/armoapi-go/apis"
+	"github.com/armosec/armoapi-go/armotypes"
+	cs "github.com/armosec/cluster-container-scanner-api/containerscan"
+	v1 "github.com/armosec/cluster-container-scanner-api/containerscan/v1"
+	"go.opentelemetry.io/otel"
+)
+
+// attributeWorkloadDeleted flags the tombstone reports of the deleted workloads
+const attributeWorkloadDeleted = "workloadDeleted"
+
+// SubmitTombstone reports a deleted workload with an empty report, so that the platform replaces its last findings
+func (a *ArmoAdapter) SubmitTombstone(ctx context.Context, wlid string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ArmoAdapter.SubmitTombstone")
+	defer span.End()
+
+	// the tombstone replaces the findings submitted under the pseudonymized workload ID
+	wlid, err := a.anonymizeWlid(ctx, wlid)
+	if err != nil {
+		return err
+	}
+	report := v1.ScanResultReport{
+		Designators:     *armotypes.AttributesDesignatorsFromWLID(wlid),
+		PaginationInfo:  apis.PaginationMarks{IsLastReport: true},
+		Timestamp:       time.Now().Unix(),
+		Vulnerabilities: []cs.CommonContainerVulnerabilityResult{},
+	}
+	report.Designators.Attributes[armotypes.AttributeCustomerGUID] = a.clusterConfig.AccountID
+	report.Designators.Attributes[attributeWorkloadDeleted] = "true"
+	report.Summary = &cs.CommonContainerScanSummaryResult{
+		CustomerGUID: a.clusterConfig.AccountID,
+		Designators:  report.Designators,
+		WLID:         wlid,
+		Context:      armotypes.DesignatorToArmoContext(&report.Designators, "designators"),
+	}
+	errChan := make(chan error, 1)
+	a.postResults(ctx, &report, a.clusterConfig.EventReceiverRestURL, "", wlid, errChan)
+	close(errChan)
+	return <-errChan
+}
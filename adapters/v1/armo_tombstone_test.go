@@ -0,0 +1,101 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	v1 "github.com/armosec/cluster-container-scanner-api/containerscan/v1"
+	"github.com/armosec/utils-go/httputils"
+	"github.com/armosec/utils-k8s-go/armometadata"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArmoAdapter_SubmitTombstone(t *testing.T) {
+	wlid := "wlid://cluster-minikube/namespace-default/deployment-nginx"
+	tests := []struct {
+		name    string
+		status  int
+		postErr error
+		wantErr bool
+	}{
+		{
+			name:   "submitted",
+			status: http.StatusOK,
+		},
+		{
+			name:    "post error",
+			postErr: errors.New("connection refused"),
+			wantErr: true,
+		},
+		{
+			name:    "not found",
+			status:  http.StatusNotFound,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var report v1.ScanResultReport
+			a := &ArmoAdapter{
+				clusterConfig: armometadata.ClusterConfig{AccountID: "account", EventReceiverRestURL: "https://report.armo.cloud"},
+				httpPostFunc: func(_ httputils.IHttpClient, fullURL string, _ map[string]string, body []byte) (*http.Response, error) {
+					assert.Equal(t, "https://report.armo.cloud/"+containerScanPathV2+"?customerGUID=account", fullURL)
+					assert.NoError(t, json.Unmarshal(body, &report))
+					if tt.postErr != nil {
+						return nil, tt.postErr
+					}
+					return &http.Response{StatusCode: tt.status, Body: io.NopCloser(bytes.NewBuffer([]byte{}))}, nil
+				},
+			}
+			err := a.SubmitTombstone(context.TODO(), wlid)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "true", report.Designators.Attributes[attributeWorkloadDeleted])
+			assert.Equal(t, "nginx", report.Designators.Attributes[armotypes.AttributeName])
+			assert.Equal(t, wlid, report.Summary.WLID)
+			assert.Empty(t, report.Vulnerabilities)
+			assert.True(t, report.PaginationInfo.IsLastReport)
+		})
+	}
+}
+
+func TestArmoAdapter_SubmitTombstone_anonymized(t *testing.T) {
+	wlid := "wlid://cluster-minikube/namespace-default/deployment-nginx"
+	var report v1.ScanResultReport
+	var posted string
+	repository := &pseudonymRepository{}
+	a := NewArmoAdapter("account", "", "https://report.armo.cloud", WithAnonymization([]byte("tenant-a"), repository))
+	a.httpPostFunc = func(_ httputils.IHttpClient, _ string, _ map[string]string, body []byte) (*http.Response, error) {
+		posted = string(body)
+		assert.NoError(t, json.Unmarshal(body, &report))
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBuffer([]byte{}))}, nil
+	}
+	require.NoError(t, a.SubmitTombstone(context.TODO(), wlid))
+	namespace, name := a.anonymizer.pseudonym("default"), a.anonymizer.pseudonym("nginx")
+	anonymized := "wlid://cluster-minikube/namespace-" + namespace + "/deployment-" + name
+	assert.Equal(t, anonymized, report.Summary.WLID)
+	assert.Equal(t, namespace, report.Designators.Attributes[armotypes.AttributeNamespace])
+	assert.Equal(t, name, report.Designators.Attributes[armotypes.AttributeName])
+	// neither the namespace nor the name are submitted
+	assert.NotContains(t, posted, "nginx")
+	assert.NotContains(t, posted, "namespace-default")
+
+	// nothing is submitted unless the pseudonyms can be resolved
+	a = NewArmoAdapter("account", "", "https://report.armo.cloud", WithAnonymization([]byte("tenant-a"), &pseudonymRepository{err: domain.ErrMockError}))
+	a.httpPostFunc = func(httputils.IHttpClient, string, map[string]string, []byte) (*http.Response, error) {
+		t.Error("tombstone submitted without pseudonyms")
+		return nil, nil
+	}
+	assert.ErrorIs(t, a.SubmitTombstone(context.TODO(), wlid), domain.ErrMockError)
+}
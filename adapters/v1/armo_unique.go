@@ -0,0 +1,129 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+	v1 "github.com/armosec/cluster-container-scanner-api/containerscan/v1"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+// attributeImageReportScanID references, in the summary of a workload submitted without its vulnerabilities, the
+// scan whose report holds the vulnerabilities of the image
+const attributeImageReportScanID = "imageReportScanID"
+
+// WithUniqueImageReports submits the vulnerabilities of an image digest once per interval, the other workloads
+// running the image with the same vulnerabilities are submitted as their summary referencing that report
+func WithUniqueImageReports(interval time.Duration) ArmoAdapterOption {
+	return func(a *ArmoAdapter) {
+		a.uniqueImages = &uniqueImages{
+			interval: interval,
+			reports:  map[string]uniqueImageReport{},
+		}
+	}
+}
+
+// uniqueImages remembers the last report of each image digest submitted with its vulnerabilities
+type uniqueImages struct {
+	interval time.Duration
+	mu       sync.Mutex
+	reports  map[string]uniqueImageReport
+}
+
+type uniqueImageReport struct {
+	fingerprint string
+	scanID      string
+	submittedAt time.Time
+}
+
+// reported returns the scan whose report holds the same vulnerabilities of the image, submitted within the interval
+func (u *uniqueImages) reported(imageHash, fingerprint string, now time.Time) (string, bool) {
+	if u == nil || imageHash == "" {
+		return "", false
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	report, ok := u.reports[imageHash]
+	if !ok || report.fingerprint != fingerprint || now.Sub(report.submittedAt) >= u.interval {
+		return "", false
+	}
+	return report.scanID, true
+}
+
+// record remembers the report of an image submitted with its vulnerabilities, and forgets the expired ones
+func (u *uniqueImages) record(imageHash, fingerprint, scanID string, now time.Time) {
+	if u == nil || imageHash == "" {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for hash, report := range u.reports {
+		if now.Sub(report.submittedAt) >= u.interval {
+			delete(u.reports, hash)
+		}
+	}
+	u.reports[imageHash] = uniqueImageReport{fingerprint: fingerprint, scanID: scanID, submittedAt: now}
+}
+
+// vulnerabilitiesFingerprint hashes the vulnerabilities as the platform gets them, without the designators of the
+// workload but with the attributes specific to each vulnerability, so that workloads with different exceptions or
+// triage of the same image are submitted separately
+func vulnerabilitiesFingerprint(report v1.ScanResultReport, vulnerabilities []containerscan.CommonContainerVulnerabilityResult) string {
+	type entry struct {
+		Vulnerability     containerscan.Vulnerability `json:"vulnerability"`
+		IntroducedInLayer string                      `json:"layerHash"`
+		RelevantLabel     containerscan.RelevantLabel `json:"relevantLabel"`
+		Attributes        map[string]string           `json:"attributes,omitempty"`
+	}
+	entries := make([]string, 0, len(vulnerabilities))
+	for _, v := range vulnerabilities {
+		e := entry{Vulnerability: v.Vulnerability, IntroducedInLayer: v.IntroducedInLayer, RelevantLabel: v.RelevantLabel}
+		for key, value := range v.Designators.Attributes {
+			if _, ok := report.Designators.Attributes[key]; !ok {
+				if e.Attributes == nil {
+					e.Attributes = map[string]string{}
+				}
+				e.Attributes[key] = value
+			}
+		}
+		b, _ := json.Marshal(e)
+		entries = append(entries, string(b))
+	}
+	sort.Strings(entries)
+	h := sha256.New()
+	for _, e := range entries {
+		h.Write([]byte(e))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// postImageAssociation posts the summary of a workload without its vulnerabilities, referencing the report of the
+// scan holding them
+func (a *ArmoAdapter) postImageAssociation(ctx context.Context, report v1.ScanResultReport, imageScanID string) error {
+	attributes := make(map[string]string, len(report.Designators.Attributes)+1)
+	for k, v := range report.Designators.Attributes {
+		attributes[k] = v
+	}
+	attributes[attributeImageReportScanID] = imageScanID
+	report.Designators.Attributes = attributes
+	summary := *report.Summary
+	summary.Designators.Attributes = attributes
+	report.Summary = &summary
+	report.Vulnerabilities = nil
+	report.PaginationInfo.IsLastReport = true
+	logger.L().Debug("submitting the workload summary, the vulnerabilities of its image were submitted already",
+		helpers.String("wlid", summary.WLID),
+		helpers.String("imageReportScanID", imageScanID))
+	errChan := make(chan error, 1)
+	a.postResults(ctx, &report, a.clusterConfig.EventReceiverRestURL, summary.ImageTag, summary.WLID, errChan)
+	close(errChan)
+	return <-errChan
+}
@@ -0,0 +1,80 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	v1 "github.com/armosec/cluster-container-scanner-api/containerscan/v1"
+	"github.com/armosec/utils-go/httputils"
+	"github.com/google/uuid"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArmoAdapter_SubmitCVE_uniqueImages(t *testing.T) {
+	mu := &sync.Mutex{}
+	var received []v1.ScanResultReport
+	var exceptions []armotypes.VulnerabilityExceptionPolicy
+	a := NewArmoAdapter("", "", "", WithReportVersion(ReportVersionV2), WithUniqueImageReports(time.Hour))
+	a.getCVEExceptionsFunc = func(string, string, *armotypes.PortalDesignator) ([]armotypes.VulnerabilityExceptionPolicy, error) {
+		return exceptions, nil
+	}
+	a.httpPostFunc = func(_ httputils.IHttpClient, _ string, _ map[string]string, body []byte) (*http.Response, error) {
+		var report v1.ScanResultReport
+		assert.NoError(t, json.Unmarshal(body, &report))
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, report)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(bytes.NewBuffer([]byte{}))}, nil
+	}
+	submit := func(wlid, imageHash string) (string, v1.ScanResultReport) {
+		scanID := uuid.New().String()
+		ctx := context.TODO()
+		ctx = context.WithValue(ctx, domain.TimestampKey{}, time.Now().Unix())
+		ctx = context.WithValue(ctx, domain.ScanIDKey{}, scanID)
+		ctx = context.WithValue(ctx, domain.WorkloadKey{}, domain.ScanCommand{Wlid: wlid, ImageHash: imageHash})
+		received = nil
+		require.NoError(t, a.SubmitCVE(ctx, fileToCVEManifest("testdata/nginx-cve-small.json"), domain.CVEManifest{}))
+		require.Len(t, received, 1)
+		return scanID, received[0]
+	}
+	imageHash := "sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"
+
+	// the first workload of the image is submitted with the vulnerabilities
+	imageScanID, report := submit("wlid://cluster-minikube/namespace-default/deployment-nginx", imageHash)
+	assert.NotEmpty(t, report.Vulnerabilities)
+	assert.NotContains(t, report.Summary.Designators.Attributes, attributeImageReportScanID)
+
+	// the other workloads of the image are submitted as their summary
+	_, report = submit("wlid://cluster-minikube/namespace-staging/deployment-nginx", imageHash)
+	assert.Empty(t, report.Vulnerabilities)
+	assert.True(t, report.PaginationInfo.IsLastReport)
+	assert.Equal(t, "staging", report.Summary.Designators.Attributes[armotypes.AttributeNamespace])
+	assert.Equal(t, imageScanID, report.Summary.Designators.Attributes[attributeImageReportScanID])
+	assert.NotZero(t, report.Summary.TotalCount)
+
+	// other images, and the workloads whose vulnerabilities differ, are submitted with their vulnerabilities
+	_, report = submit("wlid://cluster-minikube/namespace-default/deployment-httpd", "sha256:0000000000000000000000000000000000000000000000000000000000000000")
+	assert.NotEmpty(t, report.Vulnerabilities)
+	exceptions = []armotypes.VulnerabilityExceptionPolicy{{
+		PolicyType:            "vulnerabilityExceptionPolicy",
+		Actions:               []armotypes.VulnerabilityExceptionPolicyActions{armotypes.Ignore},
+		VulnerabilityPolicies: []armotypes.VulnerabilityPolicy{{Name: report.Vulnerabilities[0].Name}},
+	}}
+	_, report = submit("wlid://cluster-minikube/namespace-prod/deployment-nginx", imageHash)
+	assert.NotEmpty(t, report.Vulnerabilities)
+
+	// the vulnerabilities are submitted again once the interval is over
+	exceptions = nil
+	a.uniqueImages.interval = 0
+	_, report = submit("wlid://cluster-minikube/namespace-dev/deployment-nginx", imageHash)
+	assert.NotEmpty(t, report.Vulnerabilities)
+}
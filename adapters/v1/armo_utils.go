@@ -5,9 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/url"
 	"strconv"
 	"sync"
+	"sync/atomic"
 
 	"github.com/armosec/armoapi-go/apis"
 	"github.com/armosec/armoapi-go/armotypes"
@@ -18,39 +18,58 @@ import (
 	"github.com/kubescape/go-logger"
 	"github.com/kubescape/go-logger/helpers"
 	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
 )
 
-func (a *ArmoAdapter) sendSummaryAndVulnerabilities(ctx context.Context, report *v1.ScanResultReport, eventReceiverURL string, totalVulnerabilities int, scanID string, firstVulnerabilitiesChunk []containerscan.CommonContainerVulnerabilityResult, errChan chan<- error, sendWG *sync.WaitGroup) (nextPartNum int) {
+// attributeProvisionalSummary flags the summary posted ahead of the vulnerabilities of a report split in parts,
+// it is provisional until the last part is received
+const attributeProvisionalSummary = "provisionalSummary"
+
+func (a *ArmoAdapter) sendSummaryAndVulnerabilities(ctx context.Context, report *v1.ScanResultReport, eventReceiverURL string, totalVulnerabilities int, scanID string, firstVulnerabilitiesChunk []containerscan.CommonContainerVulnerabilityResult, errChan chan<- error, sendWG *sync.WaitGroup) (nextPartNum int, err error) {
 	//get the first chunk
 	firstChunkVulnerabilitiesCount := len(firstVulnerabilitiesChunk)
-	//if size of summary + first chunk does not exceed max size
-	if httputils.JSONSize(report)+httputils.JSONSize(firstVulnerabilitiesChunk) <= maxBodySize {
-		//then post the summary report with the first vulnerabilities chunk
+	//if the whole report fits in a single part, post the summary with all the vulnerabilities
+	if totalVulnerabilities == firstChunkVulnerabilitiesCount && httputils.JSONSize(report)+httputils.JSONSize(firstVulnerabilitiesChunk) <= maxBodySize {
 		report.Vulnerabilities = firstVulnerabilitiesChunk
-		//if all vulnerabilities got into the first chunk set this as the last report
-		report.PaginationInfo.IsLastReport = totalVulnerabilities == firstChunkVulnerabilitiesCount
-		//first chunk sent (or is nil) so set to nil
-		firstVulnerabilitiesChunk = nil
-	} else {
-		//first chunk is not included in the summary, so if there are vulnerabilities to send set the last part to false
-		report.PaginationInfo.IsLastReport = firstChunkVulnerabilitiesCount == 0
+		report.PaginationInfo.IsLastReport = true
+		a.postResultsAsGoroutine(ctx, report, eventReceiverURL, report.Summary.ImageTag, report.Summary.WLID, errChan, sendWG)
+		return 1, nil
+	}
+	//otherwise post the summary alone before any vulnerability, so that the dashboards show the severity counts right away
+	if err := a.postProvisionalSummary(ctx, *report, eventReceiverURL); err != nil {
+		return 0, err
 	}
-	//send the summary report
-	a.postResultsAsGoroutine(ctx, report, eventReceiverURL, report.Summary.ImageTag, report.Summary.WLID, errChan, sendWG)
 	nextPartNum++
-	//send the first chunk if it was not sent yet (because of summary size)
-	if firstVulnerabilitiesChunk != nil {
-		a.postResultsAsGoroutine(ctx,
-			&v1.ScanResultReport{
-				PaginationInfo:  apis.PaginationMarks{ReportNumber: nextPartNum, IsLastReport: totalVulnerabilities == firstChunkVulnerabilitiesCount},
-				Vulnerabilities: firstVulnerabilitiesChunk,
-				ContainerScanID: scanID,
-				Timestamp:       report.Timestamp,
-				Designators:     report.Designators,
-			}, eventReceiverURL, report.Summary.ImageTag, report.Summary.WLID, errChan, sendWG)
-		nextPartNum++
+	//then the first chunk
+	a.postChunk(ctx,
+		&v1.ScanResultReport{
+			PaginationInfo:  apis.PaginationMarks{ReportNumber: nextPartNum, IsLastReport: totalVulnerabilities == firstChunkVulnerabilitiesCount},
+			Vulnerabilities: firstVulnerabilitiesChunk,
+			ContainerScanID: scanID,
+			Timestamp:       report.Timestamp,
+			Designators:     report.Designators,
+		}, eventReceiverURL, report.Summary.ImageTag, report.Summary.WLID, errChan, sendWG)
+	nextPartNum++
+	return nextPartNum, nil
+}
+
+// postProvisionalSummary posts the summary of a report split in parts, flagged as provisional, and waits for its response
+func (a *ArmoAdapter) postProvisionalSummary(ctx context.Context, report v1.ScanResultReport, eventReceiverURL string) error {
+	attributes := make(map[string]string, len(report.Designators.Attributes)+1)
+	for k, v := range report.Designators.Attributes {
+		attributes[k] = v
 	}
-	return nextPartNum
+	attributes[attributeProvisionalSummary] = "true"
+	report.Designators.Attributes = attributes
+	summary := *report.Summary
+	summary.Designators.Attributes = attributes
+	report.Summary = &summary
+	report.Vulnerabilities = nil
+	report.PaginationInfo = apis.PaginationMarks{}
+	errChan := make(chan error, 1)
+	a.postResults(ctx, &report, eventReceiverURL, summary.ImageTag, summary.WLID, errChan)
+	close(errChan)
+	return <-errChan
 }
 
 func (a *ArmoAdapter) postResultsAsGoroutine(ctx context.Context, report *v1.ScanResultReport, eventReceiverURL, imagetag string, wlid string, errorChan chan<- error, wg *sync.WaitGroup) {
@@ -70,30 +89,35 @@ func (a *ArmoAdapter) postResults(ctx context.Context, report *v1.ScanResultRepo
 		return
 	}
 
-	urlBase, err := url.Parse(eventReceiverURL)
+	fullURL, err := reportURL(eventReceiverURL, containerScanPathV2, report.Designators.Attributes[armotypes.AttributeCustomerGUID])
 	if err != nil {
 		logger.L().Ctx(ctx).Error("failed parsing eventReceiverURL", helpers.Error(err),
 			helpers.String("url", eventReceiverURL),
 			helpers.String("wlid", wlid))
-		err = fmt.Errorf("fail parsing URL, %s, err: %s", eventReceiverURL, err.Error())
 		errorChan <- err
 		return
 	}
 
-	urlBase.Path = "k8s/v2/containerScan"
-	q := urlBase.Query()
-	q.Add(armotypes.CustomerGuidQuery, report.Designators.Attributes[armotypes.AttributeCustomerGUID])
-	urlBase.RawQuery = q.Encode()
-
-	resp, err := a.httpPostFunc(http.DefaultClient, urlBase.String(), map[string]string{"Content-Type": "application/json"}, payload)
+	chunk := map[string]string{
+		"chunk":           strconv.Itoa(report.PaginationInfo.ReportNumber),
+		"last":            strconv.FormatBool(report.PaginationInfo.IsLastReport),
+		"vulnerabilities": strconv.Itoa(len(report.Vulnerabilities)),
+	}
+	resp, err := a.postReport(ctx, fullURL, payload)
 	if err != nil {
 		logger.L().Ctx(ctx).Error("failed posting to event", helpers.Error(err),
 			helpers.String("image", imagetag),
 			helpers.String("wlid", wlid))
+		chunk["error"] = err.Error()
+		tools.RecordScanEvent(ctx, "report chunk post failed", chunk)
 		errorChan <- err
 		return
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		errorChan <- errReportVersionNotFound
+		return
+	}
 	body, err := httputils.HttpRespToString(resp)
 	if err != nil {
 		logger.L().Ctx(ctx).Error("Vulnerabilities post to event receiver failed", helpers.Error(err),
@@ -102,6 +126,31 @@ func (a *ArmoAdapter) postResults(ctx context.Context, report *v1.ScanResultRepo
 		return
 	}
 	logger.L().Debug(fmt.Sprintf("posting to event receiver image %s wlid %s finished successfully response body: %s", imagetag, wlid, body)) // systest dependent
+	tools.RecordScanEvent(ctx, "report chunk posted", chunk)
+	reportSubmitted(ctx, len(report.Vulnerabilities))
+}
+
+// submitProgressKey carries the progress of the submission of a report split in chunks
+type submitProgressKey struct{}
+
+type submitProgress struct {
+	sent  atomic.Int64
+	total int64
+}
+
+// withSubmitProgress starts tracking the submission of totalVulnerabilities
+func withSubmitProgress(ctx context.Context, totalVulnerabilities int) context.Context {
+	return context.WithValue(ctx, submitProgressKey{}, &submitProgress{total: int64(totalVulnerabilities)})
+}
+
+// reportSubmitted adds the vulnerabilities of a posted chunk to the submission progress and reports it
+func reportSubmitted(ctx context.Context, count int) {
+	progress, ok := ctx.Value(submitProgressKey{}).(*submitProgress)
+	if !ok {
+		return
+	}
+	sent := progress.sent.Add(int64(count))
+	tools.ReportProgress(ctx, domain.Progress{Stage: domain.StageSubmit, Unit: domain.ProgressVulnerabilities, Current: sent, Total: progress.total})
 }
 
 func (a *ArmoAdapter) sendVulnerabilitiesRoutine(ctx context.Context, chunksChan <-chan []containerscan.CommonContainerVulnerabilityResult, eventReceiverURL string, scanID string, finalReport v1.ScanResultReport, errChan chan error, sendWG *sync.WaitGroup, totalVulnerabilities int, firstChunkVulnerabilitiesCount int, nextPartNum int) {
@@ -119,7 +168,7 @@ func (a *ArmoAdapter) sendVulnerabilities(ctx context.Context, chunksChan <-chan
 	chunksVulnerabilitiesCount := 0
 	for vulnerabilities := range chunksChan {
 		chunksVulnerabilitiesCount += len(vulnerabilities)
-		a.postResultsAsGoroutine(ctx,
+		a.postChunk(ctx,
 			&v1.ScanResultReport{
 				PaginationInfo:  apis.PaginationMarks{ReportNumber: partNum, IsLastReport: chunksVulnerabilitiesCount == expectedVulnerabilitiesSum},
 				Vulnerabilities: vulnerabilities,
@@ -169,95 +218,44 @@ func summarize(report v1.ScanResultReport, vulnerabilities []containerscan.Commo
 
 	summary.PackagesName = make([]string, 0)
 
-	actualSeveritiesStats := map[string]containerscan.SeverityStats{}
-	exculdedSeveritiesStats := map[string]containerscan.SeverityStats{}
-
+	summarizer := newSeveritySummarizer()
 	vulnsList := make([]containerscan.ShortVulnerabilityResult, 0)
 
 	for i := range vulnerabilities {
-		isIgnored := len(vulnerabilities[i].ExceptionApplied) > 0 &&
-			len(vulnerabilities[i].ExceptionApplied[0].Actions) > 0 &&
-			vulnerabilities[i].ExceptionApplied[0].Actions[0] == armotypes.Ignore
-
-		severitiesStats := exculdedSeveritiesStats
-		if !isIgnored {
-			summary.TotalCount++
-			vulnsList = append(vulnsList, *(vulnerabilities[i].ToShortVulnerabilityResult()))
-			severitiesStats = actualSeveritiesStats
-		}
-
 		// TODO: maybe add all severities just to have a placeholders
 		if !containerscan.KnownSeverities[vulnerabilities[i].Severity] {
 			vulnerabilities[i].Severity = containerscan.UnknownSeverity
 		}
-
-		vulnSeverityStats, ok := severitiesStats[vulnerabilities[i].Severity]
-		if !ok {
-			vulnSeverityStats = containerscan.SeverityStats{Severity: vulnerabilities[i].Severity}
-		}
-
-		vulnSeverityStats.TotalCount++
-		isFixed := containerscan.CalculateFixed(vulnerabilities[i].Fixes) > 0
-		if isFixed {
-			vulnSeverityStats.FixAvailableOfTotalCount++
-			incrementCounter(&summary.FixAvailableOfTotalCount, true, isIgnored)
-		}
-		isRCE := vulnerabilities[i].IsRCE()
-		if isRCE {
-			vulnSeverityStats.RCECount++
-			incrementCounter(&summary.RCECount, true, isIgnored)
-			if isFixed {
-				vulnSeverityStats.RCEFixCount++
-				incrementCounter(&summary.RCEFixCount, true, isIgnored)
-			}
+		if !isExcepted(vulnerabilities[i]) {
+			vulnsList = append(vulnsList, *(vulnerabilities[i].ToShortVulnerabilityResult()))
 		}
+		summarizer.add(vulnerabilities[i])
 
 		isRelevant := vulnerabilities[i].GetIsRelevant()
 		if isRelevant != nil { // if IsRelevant is not nil, we have relevancy data
 			if *isRelevant {
 				// vulnerability is relevant
 				vulnerabilities[i].SetRelevantLabel(containerscan.RelevantLabelYes)
-				vulnSeverityStats.RelevantCount++
-				incrementCounter(&summary.RelevantCount, true, isIgnored)
-				if isFixed {
-					vulnSeverityStats.RelevantFixCount++
-					incrementCounter(&summary.RelevantFixCount, true, isIgnored)
-				}
 			} else {
 				// vulnerability is not relevant
 				vulnerabilities[i].SetRelevantLabel(containerscan.RelevantLabelNo)
 			}
 		}
-		severitiesStats[vulnerabilities[i].Severity] = vulnSeverityStats
 	}
 
 	summary.Status = "Success"
 	summary.Vulnerabilities = vulnsList
+	summary.SeverityStats, summary.SeveritiesStats, summary.ExcludedSeveritiesStats = summarizer.stats(hasRelevancy)
 
 	// if there is no CVEp, label is empty
 	if !hasRelevancy {
 		summary.SetRelevantLabel(containerscan.RelevantLabelNotExists)
+	} else if summary.SeverityStats.RelevantCount == 0 {
+		// if there is CVEp but no relevant vulnerabilities, label is "no"
+		summary.SetRelevantLabel(containerscan.RelevantLabelNo)
 	} else {
-		// mark relevancy scan in severities stats
-		for severity, severityStats := range actualSeveritiesStats {
-			severityStats.RelevancyScanCount = 1
-			actualSeveritiesStats[severity] = severityStats
-		}
-		summary.SeverityStats.RelevancyScanCount = 1
-		if summary.SeverityStats.RelevantCount == 0 {
-			// if there is CVEp but no relevant vulnerabilities, label is "no"
-			summary.SetRelevantLabel(containerscan.RelevantLabelNo)
-		} else {
-			// if there is CVEp and there are relevant vulnerabilities, label is "yes"
-			summary.SetRelevantLabel(containerscan.RelevantLabelYes)
-		}
-	}
-
-	for sever := range actualSeveritiesStats {
-		summary.SeveritiesStats = append(summary.SeveritiesStats, actualSeveritiesStats[sever])
-	}
-	for sever := range exculdedSeveritiesStats {
-		summary.ExcludedSeveritiesStats = append(summary.ExcludedSeveritiesStats, exculdedSeveritiesStats[sever])
+		// if there is CVEp and there are relevant vulnerabilities, label is "yes"
+		summary.SetRelevantLabel(containerscan.RelevantLabelYes)
 	}
 
 	return &summary, vulnerabilities
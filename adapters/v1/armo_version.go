@@ -0,0 +1,208 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+	v1 "github.com/armosec/cluster-container-scanner-api/containerscan/v1"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+)
+
+// report versions of the event receiver containerScan endpoint
+const (
+	ReportVersionV1 = "v1"
+	ReportVersionV2 = "v2"
+)
+
+const (
+	capabilitiesPath     = "k8s/capabilities"
+	containerScanPathV1  = "k8s/containerScan"
+	containerScanPathV2  = "k8s/v2/containerScan"
+	reportVersionsHeader = "X-Containerscan-Versions"
+	// reportVersionTimeout bounds the negotiation of the report version, which delays the report
+	reportVersionTimeout = 10 * time.Second
+	// reportVersionInterval is how long a negotiated report version is used before it is checked again, so that
+	// upgraded event receivers get the latest version
+	reportVersionInterval = time.Hour
+)
+
+// errReportVersionNotFound is reported when the event receiver does not serve the containerScan version used
+var errReportVersionNotFound = errors.New("containerScan report version not supported by the event receiver")
+
+// WithReportVersion pins the containerScan report version instead of negotiating it with the event receiver
+func WithReportVersion(version string) ArmoAdapterOption {
+	return func(a *ArmoAdapter) {
+		a.reportVersion = version
+	}
+}
+
+// getReportVersion returns the containerScan report version to use, negotiating it with the event receiver unless it
+// is pinned; the negotiated version is checked again after reportVersionInterval, failed negotiations keep the previous
+// version, or the latest one, and are retried
+func (a *ArmoAdapter) getReportVersion(ctx context.Context) string {
+	if a.reportVersion != "" {
+		return a.reportVersion
+	}
+	a.versionMu.Lock()
+	previous := a.negotiatedVersion
+	if previous != "" {
+		if time.Since(a.negotiatedAt) < reportVersionInterval {
+			a.versionMu.Unlock()
+			return previous
+		}
+		// the other reports keep the previous version while this one checks it again
+		a.negotiatedAt = time.Now()
+	}
+	a.versionMu.Unlock()
+	// negotiated without holding the lock, so that the reports never wait on a slow event receiver
+	negotiateCtx, cancel := context.WithTimeout(ctx, reportVersionTimeout)
+	defer cancel()
+	version, err := a.negotiateReportVersion(negotiateCtx)
+	if err != nil {
+		if previous != "" {
+			return previous
+		}
+		logger.L().Ctx(ctx).Warning("failed to negotiate the report version, using the latest", helpers.Error(err),
+			helpers.String("version", ReportVersionV2))
+		return ReportVersionV2
+	}
+	logger.L().Debug("negotiated the report version with the event receiver", helpers.String("version", version))
+	a.setReportVersion(version)
+	return version
+}
+
+// setReportVersion overrides the negotiated report version, used when the event receiver rejects the current one
+func (a *ArmoAdapter) setReportVersion(version string) {
+	a.versionMu.Lock()
+	defer a.versionMu.Unlock()
+	a.negotiatedVersion = version
+	a.negotiatedAt = time.Now()
+}
+
+// negotiateReportVersion picks the latest report version supported by the event receiver, read from its
+// capabilities response header or body; event receivers predating the capabilities endpoint are assumed
+// to support the latest version, and are downgraded if they reject it
+func (a *ArmoAdapter) negotiateReportVersion(ctx context.Context) (string, error) {
+	if a.httpGetFunc == nil {
+		return ReportVersionV2, nil
+	}
+	capabilitiesURL, err := reportURL(a.eventReceiverURL(), capabilitiesPath, a.clusterConfig.AccountID)
+	if err != nil {
+		return "", err
+	}
+	resp, err := a.httpGetFunc(clientWithContext(ctx), capabilitiesURL, map[string]string{"Accept": "application/json"})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	a.compression.observe(resp)
+	if resp.StatusCode == http.StatusNotFound {
+		return ReportVersionV2, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("capabilities request failed: %s", resp.Status)
+	}
+	var supported []string
+	if header := resp.Header.Get(reportVersionsHeader); header != "" {
+		supported = strings.Split(header, ",")
+	} else {
+		var capabilities struct {
+			ContainerScan []string `json:"containerScan"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&capabilities); err != nil {
+			return "", fmt.Errorf("failed to decode capabilities: %w", err)
+		}
+		supported = capabilities.ContainerScan
+	}
+	for _, version := range []string{ReportVersionV2, ReportVersionV1} {
+		for _, s := range supported {
+			if strings.TrimSpace(s) == version {
+				return version, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no supported report version in %v", supported)
+}
+
+// reportURL builds the URL of the given event receiver path for the given account
+func reportURL(base, path, customerGUID string) (string, error) {
+	urlBase, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("fail parsing URL, %s, err: %s", base, err.Error())
+	}
+	// the path prefix of the event receiver, such as the one of a gateway, is kept
+	urlBase.Path = strings.TrimSuffix(urlBase.Path, "/") + "/" + path
+	q := urlBase.Query()
+	q.Add(armotypes.CustomerGuidQuery, customerGUID)
+	urlBase.RawQuery = q.Encode()
+	return urlBase.String(), nil
+}
+
+// legacyReport converts a v2 report and its vulnerabilities into a v1 report, grouping vulnerabilities by layer
+func legacyReport(report v1.ScanResultReport, vulnerabilities []containerscan.CommonContainerVulnerabilityResult) containerscan.ScanResultReport {
+	legacy := containerscan.ScanResultReport{
+		Designators:              report.Designators,
+		CustomerGUID:             report.Designators.Attributes[armotypes.AttributeCustomerGUID],
+		Timestamp:                report.Timestamp,
+		Layers:                   containerscan.LayersList{},
+		ListOfDangerousArtifcats: []string{},
+	}
+	if report.Summary != nil {
+		legacy.ImgTag = report.Summary.ImageTag
+		legacy.ImgHash = report.Summary.ImageID
+		legacy.WLID = report.Summary.WLID
+		legacy.ContainerName = report.Summary.ContainerName
+	}
+	layerIndices := map[string]int{}
+	for _, v := range vulnerabilities {
+		i, ok := layerIndices[v.IntroducedInLayer]
+		if !ok {
+			layer := containerscan.ScanResultLayer{LayerHash: v.IntroducedInLayer, Vulnerabilities: containerscan.VulnerabilitiesList{}, Packages: containerscan.LinuxPkgs{}}
+			if len(v.Layers) > 0 {
+				layer.ParentLayerHash = v.Layers[0].ParentLayerHash
+			}
+			i = len(legacy.Layers)
+			layerIndices[v.IntroducedInLayer] = i
+			legacy.Layers = append(legacy.Layers, layer)
+		}
+		legacy.Layers[i].Vulnerabilities = append(legacy.Layers[i].Vulnerabilities, v.Vulnerability)
+	}
+	return legacy
+}
+
+// postLegacyReport sends the report to the v1 containerScan endpoint, in a single request
+func (a *ArmoAdapter) postLegacyReport(ctx context.Context, report v1.ScanResultReport, vulnerabilities []containerscan.CommonContainerVulnerabilityResult) error {
+	legacy := legacyReport(report, vulnerabilities)
+	payload, err := json.Marshal(a.withLegacySBOM(ctx, legacy))
+	if err != nil {
+		return err
+	}
+	fullURL, err := reportURL(a.clusterConfig.EventReceiverRestURL, containerScanPathV1, legacy.CustomerGUID)
+	if err != nil {
+		return err
+	}
+	resp, err := a.postReport(ctx, fullURL, payload)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("failed posting to event", helpers.Error(err),
+			helpers.String("image", legacy.ImgTag),
+			helpers.String("wlid", legacy.WLID))
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("posting v1 report failed: %s", resp.Status)
+	}
+	tools.ReportProgress(ctx, domain.Progress{Stage: domain.StageSubmit, Unit: domain.ProgressVulnerabilities, Current: int64(len(vulnerabilities)), Total: int64(len(vulnerabilities))})
+	return nil
+}
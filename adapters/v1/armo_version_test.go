@@ -0,0 +1,256 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/armosec/cluster-container-scanner-api/containerscan"
+	v1 "github.com/armosec/cluster-container-scanner-api/containerscan/v1"
+	"github.com/armosec/utils-go/httputils"
+	"github.com/armosec/utils-k8s-go/armometadata"
+	"github.com/google/uuid"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArmoAdapter_negotiateReportVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		header  string
+		body    string
+		err     error
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "both versions",
+			status: http.StatusOK,
+			body:   `{"containerScan":["v1","v2"]}`,
+			want:   ReportVersionV2,
+		},
+		{
+			name:   "legacy only",
+			status: http.StatusOK,
+			body:   `{"containerScan":["v1"]}`,
+			want:   ReportVersionV1,
+		},
+		{
+			name:   "versions in header",
+			status: http.StatusOK,
+			header: "v1, v2",
+			want:   ReportVersionV2,
+		},
+		{
+			name:   "no capabilities endpoint",
+			status: http.StatusNotFound,
+			want:   ReportVersionV2,
+		},
+		{
+			name:    "unknown versions",
+			status:  http.StatusOK,
+			body:    `{"containerScan":["v3"]}`,
+			wantErr: true,
+		},
+		{
+			name:    "server error",
+			status:  http.StatusInternalServerError,
+			wantErr: true,
+		},
+		{
+			name:    "unreachable",
+			err:     errors.New("connection refused"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &ArmoAdapter{
+				clusterConfig: armometadata.ClusterConfig{AccountID: "accountID", EventReceiverRestURL: "https://report.armo.cloud"},
+				httpGetFunc: func(_ httputils.IHttpClient, fullURL string, _ map[string]string) (*http.Response, error) {
+					assert.Equal(t, "https://report.armo.cloud/k8s/capabilities?customerGUID=accountID", fullURL)
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					resp := &http.Response{StatusCode: tt.status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(tt.body))}
+					if tt.header != "" {
+						resp.Header.Set(reportVersionsHeader, tt.header)
+					}
+					return resp, nil
+				},
+			}
+			got, err := a.negotiateReportVersion(context.TODO())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("negotiateReportVersion() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+			// failed negotiations are not cached
+			version := a.getReportVersion(context.TODO())
+			if tt.wantErr {
+				assert.Equal(t, ReportVersionV2, version)
+				assert.Empty(t, a.negotiatedVersion)
+			} else {
+				assert.Equal(t, tt.want, a.negotiatedVersion)
+			}
+		})
+	}
+}
+
+func TestArmoAdapter_getReportVersion_recheck(t *testing.T) {
+	capabilities := `{"containerScan":["v1","v2"]}`
+	var fail bool
+	a := &ArmoAdapter{
+		clusterConfig: armometadata.ClusterConfig{AccountID: "accountID", EventReceiverRestURL: "https://report.armo.cloud"},
+		httpGetFunc: func(httputils.IHttpClient, string, map[string]string) (*http.Response, error) {
+			if fail {
+				return nil, errors.New("connection refused")
+			}
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(capabilities))}, nil
+		},
+	}
+	// the fallback to v1 is checked again once the event receiver is upgraded
+	a.setReportVersion(ReportVersionV1)
+	assert.Equal(t, ReportVersionV1, a.getReportVersion(context.TODO()))
+	a.negotiatedAt = time.Now().Add(-reportVersionInterval)
+	assert.Equal(t, ReportVersionV2, a.getReportVersion(context.TODO()))
+	// a failed check keeps the negotiated version
+	capabilities = `{"containerScan":["v1"]}`
+	fail = true
+	a.negotiatedAt = time.Now().Add(-reportVersionInterval)
+	assert.Equal(t, ReportVersionV2, a.getReportVersion(context.TODO()))
+	assert.Equal(t, ReportVersionV2, a.negotiatedVersion)
+}
+
+func Test_legacyReport(t *testing.T) {
+	report := v1.ScanResultReport{
+		Designators: armotypes.PortalDesignator{Attributes: map[string]string{armotypes.AttributeCustomerGUID: "accountID"}},
+		Summary:     &containerscan.CommonContainerScanSummaryResult{ImageTag: "nginx:1.23", ImageID: "sha256:abc", WLID: "wlid://cluster-minikube/namespace-default/deployment-nginx", ContainerName: "nginx"},
+		Timestamp:   42,
+	}
+	vulnerabilities := []containerscan.CommonContainerVulnerabilityResult{
+		{IntroducedInLayer: "layer1", Vulnerability: containerscan.Vulnerability{Name: "CVE-1"}},
+		{IntroducedInLayer: "layer2", Layers: []containerscan.ESLayer{{LayerHash: "layer2", ParentLayerHash: "layer1"}}, Vulnerability: containerscan.Vulnerability{Name: "CVE-2"}},
+		{IntroducedInLayer: "layer1", Vulnerability: containerscan.Vulnerability{Name: "CVE-3"}},
+	}
+	got := legacyReport(report, vulnerabilities)
+	assert.Equal(t, "accountID", got.CustomerGUID)
+	assert.Equal(t, "nginx:1.23", got.ImgTag)
+	assert.Equal(t, "sha256:abc", got.ImgHash)
+	assert.Equal(t, "nginx", got.ContainerName)
+	assert.Equal(t, int64(42), got.Timestamp)
+	assert.Len(t, got.Layers, 2)
+	assert.Equal(t, "layer1", got.Layers[0].LayerHash)
+	assert.Len(t, got.Layers[0].Vulnerabilities, 2)
+	assert.Equal(t, "layer1", got.Layers[1].ParentLayerHash)
+	assert.Equal(t, "CVE-2", got.Layers[1].Vulnerabilities[0].Name)
+}
+
+func TestArmoAdapter_SubmitCVE_reportVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		reportVersion string
+		capabilities  string
+		manifest      string
+		v2NotFound    bool
+		chunkNotFound bool
+		wantPaths     []string
+		wantVersion   string
+		wantErr       bool
+	}{
+		{
+			name:         "negotiated v1",
+			capabilities: `{"containerScan":["v1"]}`,
+			wantPaths:    []string{containerScanPathV1},
+			wantVersion:  ReportVersionV1,
+		},
+		{
+			name:          "pinned v1",
+			reportVersion: ReportVersionV1,
+			capabilities:  `{"containerScan":["v1","v2"]}`,
+			wantPaths:     []string{containerScanPathV1},
+		},
+		{
+			name:         "negotiated v2",
+			capabilities: `{"containerScan":["v1","v2"]}`,
+			wantPaths:    []string{containerScanPathV2},
+			wantVersion:  ReportVersionV2,
+		},
+		{
+			name:        "fallback to v1",
+			v2NotFound:  true,
+			wantPaths:   []string{containerScanPathV2, containerScanPathV1},
+			wantVersion: ReportVersionV1,
+		},
+		{
+			name:          "chunk rejected after the summary",
+			capabilities:  `{"containerScan":["v1","v2"]}`,
+			manifest:      "testdata/nginx-cve.json",
+			chunkNotFound: true,
+			wantPaths:     []string{containerScanPathV2},
+			wantVersion:   ReportVersionV2,
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu := &sync.Mutex{}
+			var paths []string
+			a := &ArmoAdapter{
+				clusterConfig: armometadata.ClusterConfig{EventReceiverRestURL: "https://report.armo.cloud"},
+				getCVEExceptionsFunc: func(string, string, *armotypes.PortalDesignator) ([]armotypes.VulnerabilityExceptionPolicy, error) {
+					return nil, nil
+				},
+				httpGetFunc: func(httputils.IHttpClient, string, map[string]string) (*http.Response, error) {
+					status := http.StatusOK
+					if tt.capabilities == "" {
+						status = http.StatusNotFound
+					}
+					return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(tt.capabilities))}, nil
+				},
+				httpPostFunc: func(_ httputils.IHttpClient, fullURL string, _ map[string]string, body []byte) (*http.Response, error) {
+					path := strings.TrimPrefix(strings.Split(fullURL, "?")[0], "https://report.armo.cloud/")
+					mu.Lock()
+					if len(paths) == 0 || paths[len(paths)-1] != path {
+						paths = append(paths, path)
+					}
+					mu.Unlock()
+					status := http.StatusOK
+					switch path {
+					case containerScanPathV1:
+						var report containerscan.ScanResultReport
+						assert.NoError(t, json.Unmarshal(body, &report))
+						assert.NotEmpty(t, report.Layers)
+					case containerScanPathV2:
+						if tt.v2NotFound || (tt.chunkNotFound && !bytes.Contains(body, []byte(attributeProvisionalSummary))) {
+							status = http.StatusNotFound
+						}
+					}
+					return &http.Response{StatusCode: status, Body: io.NopCloser(bytes.NewBuffer([]byte{}))}, nil
+				},
+				reportVersion: tt.reportVersion,
+			}
+			ctx := context.TODO()
+			ctx = context.WithValue(ctx, domain.TimestampKey{}, time.Now().Unix())
+			ctx = context.WithValue(ctx, domain.ScanIDKey{}, uuid.New().String())
+			ctx = context.WithValue(ctx, domain.WorkloadKey{}, domain.ScanCommand{})
+			manifest := tt.manifest
+			if manifest == "" {
+				manifest = "testdata/nginx-cve-small.json"
+			}
+			err := a.SubmitCVE(ctx, fileToCVEManifest(manifest), domain.CVEManifest{})
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantPaths, paths)
+			assert.Equal(t, tt.wantVersion, a.negotiatedVersion)
+		})
+	}
+}
@@ -0,0 +1,487 @@
+package v1
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+	"github.com/google/go-containerregistry/pkg/name"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	helmConfigMediaType     = "application/vnd.cncf.helm.config.v1+json"
+	helmChartLayerMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	wasmConfigMediaType     = "application/vnd.wasm.config.v0+json"
+	// wasmDependencySection is the custom section where cargo auditable embeds the dependencies of a Rust module
+	wasmDependencySection = ".dep-v0"
+	// maxChartDepth bounds the charts pulled as the OCI dependencies of a chart, the top chart being the first
+	maxChartDepth = 3
+	// maxChartImages bounds the images scanned for a chart, its subcharts and dependencies included
+	maxChartImages = 50
+)
+
+// wasmLayerMediaTypes are the media types of the WASM module layers, of the CNCF and of the older wasm-to-oci artifacts
+var wasmLayerMediaTypes = []string{
+	"application/vnd.wasm.content.layer.v1+wasm",
+	"application/vnd.module.wasm.content.layer.v1+wasm",
+}
+
+// templateImage matches the literal images of the chart templates, the templated ones come from the values
+var templateImage = regexp.MustCompile(`(?m)^\s*(?:-\s+)?image:\s*["']?([^\s"'{}]+)["']?\s*$`)
+
+// chartDepthKey is the context key of the depth of the chart being scanned
+type chartDepthKey struct{}
+
+// ociArtifactType tells the type of an OCI artifact from the media types of its manifest
+func ociArtifactType(img containerregistryV1.Image) string {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return domain.ArtifactTypeImage
+	}
+	switch string(manifest.Config.MediaType) {
+	case helmConfigMediaType:
+		return domain.ArtifactTypeHelmChart
+	case wasmConfigMediaType:
+		return domain.ArtifactTypeWasm
+	}
+	for _, layer := range manifest.Layers {
+		if containsString(wasmLayerMediaTypes, string(layer.MediaType)) {
+			return domain.ArtifactTypeWasm
+		}
+	}
+	return domain.ArtifactTypeImage
+}
+
+// readArtifact returns the content layer of a Helm chart or of a WASM module, the first layer of a WASM artifact
+// without a known layer media type
+func readArtifact(img containerregistryV1.Image, artifactType string, maxSize int64) ([]byte, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, err
+	}
+	var content containerregistryV1.Layer
+	for _, layer := range layers {
+		mediaType, err := layer.MediaType()
+		if err != nil {
+			return nil, err
+		}
+		if (artifactType == domain.ArtifactTypeHelmChart && mediaType == helmChartLayerMediaType) ||
+			(artifactType == domain.ArtifactTypeWasm && containsString(wasmLayerMediaTypes, string(mediaType))) {
+			content = layer
+			break
+		}
+	}
+	if content == nil && artifactType == domain.ArtifactTypeWasm && len(layers) > 0 {
+		content = layers[0]
+	}
+	if content == nil {
+		return nil, fmt.Errorf("%s artifact without a content layer", artifactType)
+	}
+	rc, err := content.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return readLimited(rc, maxSize)
+}
+
+// readLimited reads at most maxSize bytes, or everything when maxSize is zero
+func readLimited(r io.Reader, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		return io.ReadAll(r)
+	}
+	content, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > maxSize {
+		return nil, ErrImageTooLarge
+	}
+	return content, nil
+}
+
+// createArtifactSBOM fills the SBOM of a Helm chart with the packages of the images it references, or the SBOM of a
+// WASM module with the Rust crates it embeds
+func (s *SyftAdapter) createArtifactSBOM(ctx context.Context, domainSBOM domain.SBOM, imageID string, options domain.RegistryOptions, artifactType string, content []byte) (domain.SBOM, error) {
+	domainSBOM.Annotations[domain.AnnotationArtifactType] = artifactType
+	logger.L().Debug("cataloging OCI artifact",
+		helpers.String("imageID", imageID),
+		helpers.String("artifactType", artifactType))
+	var err error
+	switch artifactType {
+	case domain.ArtifactTypeHelmChart:
+		err = s.createChartSBOM(ctx, &domainSBOM, imageID, options, content)
+	case domain.ArtifactTypeWasm:
+		var packages []pkg.Package
+		packages, err = wasmPackages(content, s.maxImageSize)
+		if err == nil {
+			domainSBOM.Content, err = s.syftToDomain(artifactSBOM(imageID, packages...))
+		}
+	}
+	if err != nil {
+		domainSBOM.Status = instanceidhandler.Incomplete
+	}
+	return domainSBOM, err
+}
+
+// createChartSBOM merges the SBOMs of the images of a chart, of its subcharts and of its OCI dependencies, the SBOM
+// being incomplete if one of them is
+func (s *SyftAdapter) createChartSBOM(ctx context.Context, domainSBOM *domain.SBOM, imageID string, options domain.RegistryOptions, content []byte) error {
+	depth, _ := ctx.Value(chartDepthKey{}).(int)
+	if depth >= maxChartDepth {
+		return fmt.Errorf("chart %s exceeds the maximum depth of %d charts", imageID, maxChartDepth)
+	}
+	images, err := chartImages(content, s.maxImageSize)
+	if err != nil {
+		return fmt.Errorf("failed to read chart %s: %w", imageID, err)
+	}
+	if len(images) > maxChartImages {
+		logger.L().Ctx(ctx).Warning("chart references too many images, scanning the first ones",
+			helpers.String("imageID", imageID),
+			helpers.Int("images", len(images)),
+			helpers.Int("maxChartImages", maxChartImages))
+		images = images[:maxChartImages]
+	}
+	domainSBOM.Annotations[domain.AnnotationChartImages] = strings.Join(images, ",")
+	domainSBOM.Content, err = s.syftToDomain(artifactSBOM(imageID))
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, chartDepthKey{}, depth+1)
+	for _, image := range images {
+		imageSBOM, err := s.CreateSBOM(ctx, domainSBOM.Name, image, options)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to create the SBOM of a chart image", helpers.Error(err),
+				helpers.String("imageID", imageID),
+				helpers.String("image", image))
+			domainSBOM.Status = instanceidhandler.Incomplete
+			continue
+		}
+		if imageSBOM.Status == instanceidhandler.Incomplete {
+			domainSBOM.Status = instanceidhandler.Incomplete
+		}
+		mergeDocuments(domainSBOM.Content, imageSBOM.Content)
+	}
+	return nil
+}
+
+// artifactSBOM wraps the packages of an artifact into a Syft SBOM
+func artifactSBOM(imageID string, packages ...pkg.Package) sbom.SBOM {
+	return sbom.SBOM{
+		Source: source.Metadata{
+			Scheme:        source.ImageScheme,
+			ImageMetadata: source.ImageMetadata{UserInput: imageID},
+		},
+		Artifacts: sbom.Artifacts{PackageCatalog: pkg.NewCatalog(packages...)},
+	}
+}
+
+// mergeDocuments adds the packages, files, licenses and relationships of a document to another one, skipping the
+// elements it already holds
+func mergeDocuments(doc, other *v1beta1.Document) {
+	if other == nil {
+		return
+	}
+	packageIDs := map[v1beta1.ElementID]struct{}{}
+	for _, p := range doc.Packages {
+		packageIDs[p.PackageSPDXIdentifier] = struct{}{}
+	}
+	for _, p := range other.Packages {
+		if _, ok := packageIDs[p.PackageSPDXIdentifier]; !ok {
+			packageIDs[p.PackageSPDXIdentifier] = struct{}{}
+			doc.Packages = append(doc.Packages, p)
+		}
+	}
+	fileIDs := map[v1beta1.ElementID]struct{}{}
+	for _, f := range doc.Files {
+		fileIDs[f.FileSPDXIdentifier] = struct{}{}
+	}
+	for _, f := range other.Files {
+		if _, ok := fileIDs[f.FileSPDXIdentifier]; !ok {
+			fileIDs[f.FileSPDXIdentifier] = struct{}{}
+			doc.Files = append(doc.Files, f)
+		}
+	}
+	licenseIDs := map[string]struct{}{}
+	for _, l := range doc.OtherLicenses {
+		licenseIDs[l.LicenseIdentifier] = struct{}{}
+	}
+	for _, l := range other.OtherLicenses {
+		if _, ok := licenseIDs[l.LicenseIdentifier]; !ok {
+			licenseIDs[l.LicenseIdentifier] = struct{}{}
+			doc.OtherLicenses = append(doc.OtherLicenses, l)
+		}
+	}
+	relationships := map[v1beta1.Relationship]struct{}{}
+	for _, r := range doc.Relationships {
+		relationships[*r] = struct{}{}
+	}
+	for _, r := range other.Relationships {
+		if _, ok := relationships[*r]; !ok {
+			relationships[*r] = struct{}{}
+			doc.Relationships = append(doc.Relationships, r)
+		}
+	}
+}
+
+// chartImages returns the sorted images referenced by the values and the templates of a packaged chart and of its
+// subcharts, and the references of its dependencies hosted in OCI registries
+func chartImages(content []byte, maxSize int64) ([]string, error) {
+	files, err := untarChart(content, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	root := ""
+	for file := range files {
+		if i := strings.Index(file, "/"); i > 0 {
+			root = file[:i+1]
+			break
+		}
+	}
+	found := map[string]struct{}{}
+	if err := collectChartImages(files, root, maxSize, 0, found); err != nil {
+		return nil, err
+	}
+	images := make([]string, 0, len(found))
+	for image := range found {
+		if _, err := name.ParseReference(image); err == nil {
+			images = append(images, image)
+		}
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// untarChart returns the regular files of a packaged chart keyed by path, their total size being bounded by maxSize
+func untarChart(content []byte, maxSize int64) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	files := map[string][]byte{}
+	var size int64
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		file, err := readLimited(tr, maxSize)
+		if err != nil {
+			return nil, err
+		}
+		size += int64(len(file))
+		if maxSize > 0 && size > maxSize {
+			return nil, ErrImageTooLarge
+		}
+		files[path.Clean(strings.TrimPrefix(header.Name, "./"))] = file
+	}
+}
+
+// collectChartImages adds the images of the chart rooted at root, then goes through its vendored subcharts, either
+// directories or archives under charts/
+func collectChartImages(files map[string][]byte, root string, maxSize int64, depth int, images map[string]struct{}) error {
+	if depth >= maxChartDepth {
+		return nil
+	}
+	var chart struct {
+		AppVersion   string `yaml:"appVersion"`
+		Dependencies []struct {
+			Name       string `yaml:"name"`
+			Version    string `yaml:"version"`
+			Repository string `yaml:"repository"`
+		} `yaml:"dependencies"`
+	}
+	if err := yaml.Unmarshal(files[root+"Chart.yaml"], &chart); err != nil {
+		return fmt.Errorf("invalid %sChart.yaml: %w", root, err)
+	}
+	var values interface{}
+	if err := yaml.Unmarshal(files[root+"values.yaml"], &values); err != nil {
+		return fmt.Errorf("invalid %svalues.yaml: %w", root, err)
+	}
+	valuesImages(values, "", chart.AppVersion, images)
+	subcharts := map[string]struct{}{}
+	for file, content := range files {
+		switch {
+		case strings.HasPrefix(file, root+"templates/"):
+			for _, match := range templateImage.FindAllSubmatch(content, -1) {
+				images[string(match[1])] = struct{}{}
+			}
+		case strings.HasPrefix(file, root+"charts/"):
+			rest := strings.TrimPrefix(file, root+"charts/")
+			if i := strings.Index(rest, "/"); i > 0 {
+				subcharts[root+"charts/"+rest[:i+1]] = struct{}{}
+			} else if strings.HasSuffix(rest, ".tgz") {
+				subchart, err := chartImages(content, maxSize)
+				if err != nil {
+					return fmt.Errorf("invalid subchart %s: %w", file, err)
+				}
+				for _, image := range subchart {
+					images[image] = struct{}{}
+				}
+			}
+		}
+	}
+	for subchart := range subcharts {
+		if err := collectChartImages(files, subchart, maxSize, depth+1, images); err != nil {
+			return err
+		}
+	}
+	// the dependencies vendored under charts/ were read above, the ones of OCI registries are pulled as charts
+	for _, dependency := range chart.Dependencies {
+		if strings.HasPrefix(dependency.Repository, "oci://") && isExactVersion(dependency.Version) {
+			images[strings.TrimSuffix(strings.TrimPrefix(dependency.Repository, "oci://"), "/")+"/"+dependency.Name+":"+dependency.Version] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// valuesImages adds the images of the chart values: the image strings, and the maps with a repository, prefixed by
+// their registry and suffixed by their digest or their tag, the chart appVersion by default
+func valuesImages(value interface{}, key, appVersion string, images map[string]struct{}) {
+	switch v := value.(type) {
+	case string:
+		if key == "image" && v != "" {
+			images[v] = struct{}{}
+		}
+	case []interface{}:
+		for _, item := range v {
+			valuesImages(item, "", appVersion, images)
+		}
+	case map[string]interface{}:
+		if repository, ok := v["repository"].(string); ok && repository != "" {
+			image := repository
+			if registry, ok := v["registry"].(string); ok && registry != "" {
+				image = strings.TrimSuffix(registry, "/") + "/" + image
+			}
+			tag := appVersion
+			if t, ok := v["tag"]; ok && t != nil && fmt.Sprint(t) != "" {
+				tag = fmt.Sprint(t)
+			}
+			switch digest, _ := v["digest"].(string); {
+			case digest != "":
+				image += "@" + digest
+			case tag != "":
+				image += ":" + tag
+			}
+			images[image] = struct{}{}
+		}
+		for k, item := range v {
+			valuesImages(item, k, appVersion, images)
+		}
+	}
+}
+
+// isExactVersion tells whether a dependency version is a single version usable as a tag, rather than a range
+func isExactVersion(version string) bool {
+	return version != "" && !strings.ContainsAny(version, "^~<>=*|, xX")
+}
+
+// wasmPackages returns the Rust crates embedded by cargo auditable into a WASM module, the local and build-time crates
+// left out; a module built without cargo auditable has no packages
+func wasmPackages(content []byte, maxSize int64) ([]pkg.Package, error) {
+	section, err := wasmCustomSection(content, wasmDependencySection)
+	if err != nil || section == nil {
+		return nil, err
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(section))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s section: %w", wasmDependencySection, err)
+	}
+	defer zr.Close()
+	dependencies, err := readLimited(zr, maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s section: %w", wasmDependencySection, err)
+	}
+	var audit struct {
+		Packages []struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+			Source  string `json:"source"`
+			Kind    string `json:"kind"`
+		} `json:"packages"`
+	}
+	if err := json.Unmarshal(dependencies, &audit); err != nil {
+		return nil, fmt.Errorf("invalid %s section: %w", wasmDependencySection, err)
+	}
+	var packages []pkg.Package
+	for _, crate := range audit.Packages {
+		if crate.Kind == "build" || crate.Source == "local" {
+			continue
+		}
+		p := pkg.Package{
+			Name:         crate.Name,
+			Version:      crate.Version,
+			FoundBy:      "wasm-cargo-auditable-cataloger",
+			Language:     pkg.Rust,
+			Type:         pkg.RustPkg,
+			PURL:         packageurl.NewPackageURL(packageurl.TypeCargo, "", crate.Name, crate.Version, nil, "").ToString(),
+			MetadataType: pkg.RustCargoPackageMetadataType,
+			Metadata: pkg.CargoPackageMetadata{
+				Name:    crate.Name,
+				Version: crate.Version,
+				Source:  crate.Source,
+			},
+		}
+		p.SetID()
+		packages = append(packages, p)
+	}
+	return packages, nil
+}
+
+// wasmCustomSection returns the content of the named custom section of a WASM module, nil when missing
+func wasmCustomSection(content []byte, sectionName string) ([]byte, error) {
+	if len(content) < 8 || !bytes.Equal(content[:4], []byte("\x00asm")) {
+		return nil, errors.New("not a WASM module")
+	}
+	for r := content[8:]; len(r) > 0; {
+		id := r[0]
+		size, n := binary.Uvarint(r[1:])
+		if n <= 0 || size > uint64(len(r)-1-n) {
+			return nil, errors.New("truncated WASM section")
+		}
+		section := r[1+n : 1+n+int(size)]
+		r = r[1+n+int(size):]
+		// custom sections have the zero ID and start with their name
+		if id != 0 {
+			continue
+		}
+		nameSize, m := binary.Uvarint(section)
+		if m <= 0 || nameSize > uint64(len(section)-m) {
+			return nil, errors.New("truncated WASM custom section name")
+		}
+		if string(section[m:m+int(nameSize)]) == sectionName {
+			return section[m+int(nameSize):], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,278 @@
+package v1
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chartArchive packages the files of a chart into a gzipped tarball
+func chartArchive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for file, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: file, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+// wasmModule builds an empty WASM module with the custom sections given by name
+func wasmModule(sections map[string][]byte) []byte {
+	module := []byte("\x00asm\x01\x00\x00\x00")
+	// a type section without types, skipped by the parser
+	module = append(module, 1, 1, 0)
+	for sectionName, content := range sections {
+		payload := binary.AppendUvarint(nil, uint64(len(sectionName)))
+		payload = append(append(payload, sectionName...), content...)
+		module = append(module, 0)
+		module = binary.AppendUvarint(module, uint64(len(payload)))
+		module = append(module, payload...)
+	}
+	return module
+}
+
+// auditableSection compresses the cargo auditable dependencies of a module
+func auditableSection(t *testing.T, dependencies string) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	_, err := zw.Write([]byte(dependencies))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// artifactImage builds a single layer OCI artifact
+func artifactImage(t *testing.T, configMediaType, layerMediaType string, content []byte) containerregistryV1.Image {
+	img, err := mutate.AppendLayers(empty.Image, static.NewLayer(content, types.MediaType(layerMediaType)))
+	require.NoError(t, err)
+	return mutate.ConfigMediaType(mutate.MediaType(img, types.OCIManifestSchema1), types.MediaType(configMediaType))
+}
+
+func Test_ociArtifactType(t *testing.T) {
+	tests := []struct {
+		name            string
+		configMediaType string
+		layerMediaType  string
+		want            string
+	}{
+		{
+			name:            "image",
+			configMediaType: string(types.OCIConfigJSON),
+			layerMediaType:  string(types.OCILayer),
+			want:            domain.ArtifactTypeImage,
+		},
+		{
+			name:            "helm chart",
+			configMediaType: helmConfigMediaType,
+			layerMediaType:  helmChartLayerMediaType,
+			want:            domain.ArtifactTypeHelmChart,
+		},
+		{
+			name:            "wasm config",
+			configMediaType: wasmConfigMediaType,
+			layerMediaType:  "application/octet-stream",
+			want:            domain.ArtifactTypeWasm,
+		},
+		{
+			name:            "wasm-to-oci layer",
+			configMediaType: string(types.OCIConfigJSON),
+			layerMediaType:  "application/vnd.module.wasm.content.layer.v1+wasm",
+			want:            domain.ArtifactTypeWasm,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := artifactImage(t, tt.configMediaType, tt.layerMediaType, []byte("content"))
+			assert.Equal(t, tt.want, ociArtifactType(img))
+			if tt.want != domain.ArtifactTypeImage {
+				content, err := readArtifact(img, tt.want, 1<<10)
+				require.NoError(t, err)
+				assert.Equal(t, []byte("content"), content)
+				_, err = readArtifact(img, tt.want, 3)
+				assert.ErrorIs(t, err, ErrImageTooLarge)
+			}
+		})
+	}
+}
+
+func Test_chartImages(t *testing.T) {
+	subchart := chartArchive(t, map[string]string{
+		"redis/Chart.yaml":  "name: redis\nappVersion: 7.0.11\n",
+		"redis/values.yaml": "image:\n  registry: docker.io\n  repository: bitnami/redis\n",
+	})
+	chart := chartArchive(t, map[string]string{
+		"app/Chart.yaml": `name: app
+appVersion: 1.2.3
+dependencies:
+  - name: postgresql
+    version: 12.5.6
+    repository: oci://registry-1.docker.io/bitnamicharts
+  - name: ranged
+    version: ^1.0.0
+    repository: oci://registry-1.docker.io/bitnamicharts
+  - name: redis
+    version: 17.11.3
+    repository: https://charts.bitnami.com/bitnami
+`,
+		"app/values.yaml": `image:
+  repository: quay.io/example/app
+  tag: ""
+sidecars:
+  - name: proxy
+    image: envoyproxy/envoy:v1.26.1
+  - name: exporter
+    image:
+      repository: prom/exporter
+      digest: sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+      tag: 1.0.0
+`,
+		"app/templates/job.yaml": `spec:
+  containers:
+    - name: migrate
+      image: "busybox:1.36"
+    - name: templated
+      image: "{{ .Values.image.repository }}"
+`,
+		"app/charts/redis-17.11.3.tgz":     string(subchart),
+		"app/charts/common/Chart.yaml":     "name: common\n",
+		"app/charts/common/values.yaml":    "image: alpine:3.18\n",
+		"app/charts/common/templates/x.md": "image: NOT AN IMAGE\n",
+	})
+	images, err := chartImages(chart, 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"alpine:3.18",
+		"busybox:1.36",
+		"docker.io/bitnami/redis:7.0.11",
+		"envoyproxy/envoy:v1.26.1",
+		"prom/exporter@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		"quay.io/example/app:1.2.3",
+		"registry-1.docker.io/bitnamicharts/postgresql:12.5.6",
+	}, images)
+
+	_, err = chartImages(chart, 16)
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+	_, err = chartImages([]byte("not a chart"), 1<<20)
+	assert.Error(t, err)
+}
+
+func Test_wasmPackages(t *testing.T) {
+	module := wasmModule(map[string][]byte{
+		"name": []byte("ignored"),
+		wasmDependencySection: auditableSection(t, `{"packages":[
+			{"name":"module","version":"0.1.0","source":"local","root":true},
+			{"name":"regex","version":"1.5.4","source":"crates.io"},
+			{"name":"cc","version":"1.0.79","source":"crates.io","kind":"build"}
+		]}`),
+	})
+	packages, err := wasmPackages(module, 1<<20)
+	require.NoError(t, err)
+	require.Len(t, packages, 1)
+	assert.Equal(t, "regex", packages[0].Name)
+	assert.Equal(t, "1.5.4", packages[0].Version)
+	assert.Equal(t, "pkg:cargo/regex@1.5.4", packages[0].PURL)
+	assert.NotEmpty(t, packages[0].ID())
+
+	// a module built without cargo auditable has no packages
+	packages, err = wasmPackages(wasmModule(nil), 1<<20)
+	require.NoError(t, err)
+	assert.Empty(t, packages)
+
+	_, err = wasmPackages([]byte("\x7fELF"), 1<<20)
+	assert.ErrorContains(t, err, "not a WASM module")
+	_, err = wasmPackages(append(wasmModule(nil), 0, 10), 1<<20)
+	assert.ErrorContains(t, err, "truncated WASM section")
+}
+
+func Test_mergeDocuments(t *testing.T) {
+	relationship := v1beta1.Relationship{
+		RefA:         v1beta1.DocElementID{ElementRefID: "Package-a"},
+		RefB:         v1beta1.DocElementID{ElementRefID: "File-a"},
+		Relationship: "CONTAINS",
+	}
+	doc := &v1beta1.Document{
+		Packages:      []*v1beta1.Package{{PackageSPDXIdentifier: "Package-a"}},
+		Files:         []*v1beta1.File{{FileSPDXIdentifier: "File-a"}},
+		Relationships: []*v1beta1.Relationship{&relationship},
+	}
+	other := relationship
+	mergeDocuments(doc, &v1beta1.Document{
+		Packages:      []*v1beta1.Package{{PackageSPDXIdentifier: "Package-a"}, {PackageSPDXIdentifier: "Package-b"}},
+		Files:         []*v1beta1.File{{FileSPDXIdentifier: "File-a"}},
+		OtherLicenses: []*v1beta1.OtherLicense{{LicenseIdentifier: "LicenseRef-a"}},
+		Relationships: []*v1beta1.Relationship{&other},
+	})
+	mergeDocuments(doc, nil)
+	assert.Len(t, doc.Packages, 2)
+	assert.Len(t, doc.Files, 1)
+	assert.Len(t, doc.OtherLicenses, 1)
+	assert.Len(t, doc.Relationships, 1)
+}
+
+func TestSyftAdapter_CreateSBOM_artifacts(t *testing.T) {
+	ctx := context.TODO()
+	canary, options, err := NewCanaryAdapter("").CanaryImage(ctx)
+	require.NoError(t, err)
+	registry := strings.SplitN(canary, "/", 2)[0]
+	push := func(repository string, img containerregistryV1.Image) string {
+		ref, err := name.ParseReference(registry+"/"+repository, name.Insecure)
+		require.NoError(t, err)
+		require.NoError(t, remote.Write(ref, img))
+		return ref.String()
+	}
+	packageNames := func(sbom domain.SBOM) []string {
+		require.NotNil(t, sbom.Content)
+		var packages []string
+		for _, p := range sbom.Content.Packages {
+			packages = append(packages, p.PackageName+"@"+p.PackageVersion)
+		}
+		sort.Strings(packages)
+		return packages
+	}
+	s := NewSyftAdapter(time.Minute, 1<<20)
+
+	module := wasmModule(map[string][]byte{
+		wasmDependencySection: auditableSection(t, `{"packages":[{"name":"regex","version":"1.5.4","source":"crates.io"}]}`),
+	})
+	wasm := push("wasm/module:v1", artifactImage(t, wasmConfigMediaType, wasmLayerMediaTypes[0], module))
+	sbom, err := s.CreateSBOM(ctx, "wasm", wasm, options)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ArtifactTypeWasm, sbom.Annotations[domain.AnnotationArtifactType])
+	assert.Equal(t, []string{"regex@1.5.4"}, packageNames(sbom))
+
+	chart := chartArchive(t, map[string]string{
+		"app/Chart.yaml":  "name: app\n",
+		"app/values.yaml": "image: " + canary + "\n",
+	})
+	helm := push("charts/app:0.1.0", artifactImage(t, helmConfigMediaType, helmChartLayerMediaType, chart))
+	sbom, err = s.CreateSBOM(ctx, "chart", helm, options)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ArtifactTypeHelmChart, sbom.Annotations[domain.AnnotationArtifactType])
+	assert.Equal(t, canary, sbom.Annotations[domain.AnnotationChartImages])
+	assert.Empty(t, sbom.Status)
+	assert.Equal(t, []string{"busybox@1.35.0-r29", "musl@1.2.3-r4"}, packageNames(sbom))
+}
@@ -0,0 +1,295 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"go.opentelemetry.io/otel"
+)
+
+// the formats of the attestations cosign verifies with "cosign verify-attestation --type spdxjson"
+const (
+	cosignSignatureAnnotation                 = "dev.cosignproject.cosign/signature"
+	dsseMediaType             types.MediaType = "application/vnd.dsse.envelope.v1+json"
+	inTotoPayloadType                         = "application/vnd.in-toto+json"
+	inTotoStatementType                       = "https://in-toto.io/Statement/v0.1"
+	spdxPredicateType                         = "https://spdx.dev/Document"
+)
+
+var (
+	ErrNoSBOMContent         = errors.New("the SBOM has no content to attest")
+	ErrSBOMNotSigned         = errors.New("the SBOM is not signed")
+	ErrSBOMSignatureInvalid  = errors.New("invalid SBOM signature")
+	ErrUnsupportedSigningKey = errors.New("unsupported signing key, expected an ECDSA, Ed25519 or RSA key")
+)
+
+// inTotoStatement attests the SPDX SBOM of the image of its subject
+type inTotoStatement struct {
+	Type          string            `json:"_type"`
+	PredicateType string            `json:"predicateType"`
+	Subject       []inTotoSubject   `json:"subject"`
+	Predicate     *v1beta1.Document `json:"predicate"`
+}
+
+type inTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// dsseEnvelope carries a payload and its signatures, see https://github.com/secure-systems-lab/dsse
+type dsseEnvelope struct {
+	PayloadType string          `json:"payloadType"`
+	Payload     string          `json:"payload"`
+	Signatures  []dsseSignature `json:"signatures"`
+}
+
+type dsseSignature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// SBOMSigner implements SBOMSigner from ports with a private key, the attestations are verified with the public key
+// by cosign
+type SBOMSigner struct {
+	key   crypto.Signer
+	keyID string
+}
+
+var _ ports.SBOMSigner = (*SBOMSigner)(nil)
+
+// NewSBOMSigner initializes the SBOMSigner struct with the unencrypted PEM private key of the given file, a PKCS #8
+// ECDSA, Ed25519 or RSA key, or a SEC 1 ECDSA key
+func NewSBOMSigner(keyFile string) (*SBOMSigner, error) {
+	content, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM private key in %s", keyFile)
+	}
+	var key interface{}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the private key of %s: %w", keyFile, err)
+	}
+	return newSBOMSigner(key)
+}
+
+func newSBOMSigner(key interface{}) (*SBOMSigner, error) {
+	var signer crypto.Signer
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		signer = k
+	case ed25519.PrivateKey:
+		signer = k
+	case *rsa.PrivateKey:
+		signer = k
+	default:
+		return nil, ErrUnsupportedSigningKey
+	}
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	return &SBOMSigner{key: signer, keyID: hex.EncodeToString(sum[:])}, nil
+}
+
+// SignSBOM signs the in-toto statement attesting the SBOM of the image, imageID is a digest reference
+func (s *SBOMSigner) SignSBOM(ctx context.Context, sbom domain.SBOM, imageID string) (domain.SBOMAttestation, error) {
+	_, span := otel.Tracer("").Start(ctx, "SBOMSigner.SignSBOM")
+	defer span.End()
+
+	statement, err := sbomStatement(sbom, imageID)
+	if err != nil {
+		return domain.SBOMAttestation{}, err
+	}
+	signature, err := s.sign(pae(inTotoPayloadType, statement))
+	if err != nil {
+		return domain.SBOMAttestation{}, err
+	}
+	encodedSignature := base64.StdEncoding.EncodeToString(signature)
+	envelope, err := json.Marshal(dsseEnvelope{
+		PayloadType: inTotoPayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(statement),
+		Signatures:  []dsseSignature{{KeyID: s.keyID, Sig: encodedSignature}},
+	})
+	if err != nil {
+		return domain.SBOMAttestation{}, err
+	}
+	sum := sha256.Sum256(statement)
+	return domain.SBOMAttestation{
+		Envelope:        envelope,
+		Signature:       encodedSignature,
+		KeyID:           s.keyID,
+		StatementDigest: "sha256:" + hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// sign signs the message with the key, hashed with SHA-256 but for Ed25519 keys
+func (s *SBOMSigner) sign(message []byte) ([]byte, error) {
+	if _, ok := s.key.(ed25519.PrivateKey); ok {
+		return s.key.Sign(rand.Reader, message, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(message)
+	return s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// PushAttestation appends the attestation to the ones of the image in its repository, under the
+// "sha256-<digest>.att" tag cosign reads them from
+func (s *SBOMSigner) PushAttestation(ctx context.Context, attestation domain.SBOMAttestation, imageID string, options domain.RegistryOptions) error {
+	ctx, span := otel.Tracer("").Start(ctx, "SBOMSigner.PushAttestation")
+	defer span.End()
+
+	registryOptions := domainToRegistryOptions(options)
+	ref, err := name.NewDigest(imageID, prepareReferenceOptions(registryOptions)...)
+	if err != nil {
+		return err
+	}
+	tag := ref.Context().Tag(strings.Replace(ref.DigestStr(), ":", "-", 1) + ".att")
+	remoteOptions := prepareRemoteOptions(ctx, tag, registryOptions, nil)
+	attestations, err := remote.Image(tag, remoteOptions...)
+	var transportError *transport.Error
+	switch {
+	case errors.As(err, &transportError) && transportError.StatusCode == http.StatusNotFound:
+		attestations = mutate.MediaType(mutate.ConfigMediaType(empty.Image, types.OCIConfigJSON), types.OCIManifestSchema1)
+	case err != nil:
+		return err
+	}
+	attestations, err = mutate.Append(attestations, mutate.Addendum{
+		Layer: blobLayer{content: attestation.Envelope, mediaType: dsseMediaType},
+		Annotations: map[string]string{
+			cosignSignatureAnnotation: "",
+			"predicateType":           spdxPredicateType,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return remote.Write(tag, attestations, remoteOptions...)
+}
+
+// VerifySBOM checks the signature annotated on the SBOM was made with the private key of publicKey for the image
+func VerifySBOM(publicKey crypto.PublicKey, sbom domain.SBOM, imageID string) error {
+	encodedSignature, ok := sbom.Annotations[domain.AnnotationSBOMSignature]
+	if !ok {
+		return ErrSBOMNotSigned
+	}
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSBOMSignatureInvalid, err)
+	}
+	statement, err := sbomStatement(sbom, imageID)
+	if err != nil {
+		return err
+	}
+	valid, err := verifySignature(publicKey, pae(inTotoPayloadType, statement), signature)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrSBOMSignatureInvalid
+	}
+	return nil
+}
+
+// verifySignature checks the signature of the message, made as sign does: of its SHA-256 digest except with Ed25519
+func verifySignature(publicKey crypto.PublicKey, message, signature []byte) (bool, error) {
+	digest := sha256.Sum256(message)
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest[:], signature), nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, message, signature), nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil, nil
+	}
+	return false, ErrUnsupportedSigningKey
+}
+
+// sbomStatement returns the JSON in-toto statement attesting the SBOM of the image, imageID is a digest reference
+func sbomStatement(sbom domain.SBOM, imageID string) ([]byte, error) {
+	if sbom.Content == nil {
+		return nil, ErrNoSBOMContent
+	}
+	ref, err := name.NewDigest(imageID)
+	if err != nil {
+		return nil, err
+	}
+	algorithm, digest, _ := strings.Cut(ref.DigestStr(), ":")
+	return json.Marshal(inTotoStatement{
+		Type:          inTotoStatementType,
+		PredicateType: spdxPredicateType,
+		Subject:       []inTotoSubject{{Name: ref.Context().Name(), Digest: map[string]string{algorithm: digest}}},
+		Predicate:     sbom.Content,
+	})
+}
+
+// pae is the pre-authentication encoding of a DSSE payload, which is signed
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// blobLayer is a layer holding a single uncompressed blob, such as a DSSE envelope
+type blobLayer struct {
+	content   []byte
+	mediaType types.MediaType
+}
+
+func (l blobLayer) Digest() (containerregistryV1.Hash, error) {
+	hash, _, err := containerregistryV1.SHA256(bytes.NewReader(l.content))
+	return hash, err
+}
+
+func (l blobLayer) DiffID() (containerregistryV1.Hash, error) {
+	return l.Digest()
+}
+
+func (l blobLayer) Compressed() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(l.content)), nil
+}
+
+func (l blobLayer) Uncompressed() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(l.content)), nil
+}
+
+func (l blobLayer) Size() (int64, error) {
+	return int64(len(l.content)), nil
+}
+
+func (l blobLayer) MediaType() (types.MediaType, error) {
+	return l.mediaType, nil
+}
@@ -0,0 +1,178 @@
+package v1
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const attestedImageID = "k8s.gcr.io/kube-proxy@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137"
+
+// keyFile writes the PEM private key into a file of the test directory
+func keyFile(t *testing.T, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600))
+	return path
+}
+
+func pkcs8KeyFile(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return keyFile(t, "PRIVATE KEY", der)
+}
+
+func signedSBOM(t *testing.T, signer *SBOMSigner) (domain.SBOM, domain.SBOMAttestation) {
+	sbom := domain.SBOM{
+		Name:    "k8s.gcr.io-kube-proxy-sha256-c1b13",
+		Content: &v1beta1.Document{SPDXIdentifier: "DOCUMENT", DocumentName: "kube-proxy"},
+	}
+	attestation, err := signer.SignSBOM(context.TODO(), sbom, attestedImageID)
+	require.NoError(t, err)
+	sbom.Annotations = map[string]string{domain.AnnotationSBOMSignature: attestation.Signature}
+	return sbom, attestation
+}
+
+func TestSBOMSigner_SignSBOM(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	sec1, err := x509.MarshalECPrivateKey(ecdsaKey)
+	require.NoError(t, err)
+	tests := []struct {
+		name      string
+		keyFile   string
+		publicKey interface{}
+	}{
+		{name: "PKCS #8 ECDSA key", keyFile: pkcs8KeyFile(t, ecdsaKey), publicKey: ecdsaKey.Public()},
+		{name: "SEC 1 ECDSA key", keyFile: keyFile(t, "EC PRIVATE KEY", sec1), publicKey: ecdsaKey.Public()},
+		{name: "Ed25519 key", keyFile: pkcs8KeyFile(t, ed25519Key), publicKey: ed25519Key.Public()},
+		{name: "RSA key", keyFile: pkcs8KeyFile(t, rsaKey), publicKey: rsaKey.Public()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewSBOMSigner(tt.keyFile)
+			require.NoError(t, err)
+			sbom, attestation := signedSBOM(t, signer)
+			assert.Len(t, attestation.KeyID, 64)
+			assert.True(t, strings.HasPrefix(attestation.StatementDigest, "sha256:"))
+			assert.NoError(t, VerifySBOM(tt.publicKey, sbom, attestedImageID))
+
+			// the envelope carries the statement of the SBOM of the image
+			var envelope dsseEnvelope
+			require.NoError(t, json.Unmarshal(attestation.Envelope, &envelope))
+			assert.Equal(t, inTotoPayloadType, envelope.PayloadType)
+			assert.Equal(t, []dsseSignature{{KeyID: attestation.KeyID, Sig: attestation.Signature}}, envelope.Signatures)
+			payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+			require.NoError(t, err)
+			var statement inTotoStatement
+			require.NoError(t, json.Unmarshal(payload, &statement))
+			assert.Equal(t, spdxPredicateType, statement.PredicateType)
+			assert.Equal(t, []inTotoSubject{{
+				Name:   "k8s.gcr.io/kube-proxy",
+				Digest: map[string]string{"sha256": "c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137"},
+			}}, statement.Subject)
+			assert.Equal(t, "kube-proxy", statement.Predicate.DocumentName)
+
+			// the signature does not hold for another SBOM or another image
+			tampered := sbom
+			tampered.Content = &v1beta1.Document{SPDXIdentifier: "DOCUMENT", DocumentName: "kube-apiserver"}
+			assert.ErrorIs(t, VerifySBOM(tt.publicKey, tampered, attestedImageID), ErrSBOMSignatureInvalid)
+			assert.ErrorIs(t, VerifySBOM(tt.publicKey, sbom, "k8s.gcr.io/kube-proxy@sha256:0000000000000000000000000000000000000000000000000000000000000000"), ErrSBOMSignatureInvalid)
+		})
+	}
+}
+
+func TestNewSBOMSigner(t *testing.T) {
+	_, err := NewSBOMSigner(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.Error(t, err)
+	_, err = NewSBOMSigner(keyFile(t, "PRIVATE KEY", []byte("garbage")))
+	assert.Error(t, err)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, []byte("not a PEM file"), 0600))
+	_, err = NewSBOMSigner(path)
+	assert.Error(t, err)
+	_, err = newSBOMSigner("key")
+	assert.ErrorIs(t, err, ErrUnsupportedSigningKey)
+}
+
+func TestVerifySBOM(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := newSBOMSigner(key)
+	require.NoError(t, err)
+	sbom, _ := signedSBOM(t, signer)
+
+	assert.ErrorIs(t, VerifySBOM(key.Public(), domain.SBOM{Content: sbom.Content}, attestedImageID), ErrSBOMNotSigned)
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	assert.ErrorIs(t, VerifySBOM(other.Public(), sbom, attestedImageID), ErrSBOMSignatureInvalid)
+	sbom.Annotations[domain.AnnotationSBOMSignature] = "not base64"
+	assert.ErrorIs(t, VerifySBOM(key.Public(), sbom, attestedImageID), ErrSBOMSignatureInvalid)
+}
+
+func TestSBOMSigner_PushAttestation(t *testing.T) {
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer srv.Close()
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	ref, err := name.ParseReference(strings.TrimPrefix(srv.URL, "http://") + "/kube-proxy:v1.24.3")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	imageID := ref.Context().Digest(digest.String()).String()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := newSBOMSigner(key)
+	require.NoError(t, err)
+	sbom := domain.SBOM{Content: &v1beta1.Document{DocumentName: "kube-proxy"}}
+	attestation, err := signer.SignSBOM(context.TODO(), sbom, imageID)
+	require.NoError(t, err)
+	options := domain.RegistryOptions{InsecureUseHTTP: true}
+	require.NoError(t, signer.PushAttestation(context.TODO(), attestation, imageID, options))
+	// the attestations of a rescan are appended
+	require.NoError(t, signer.PushAttestation(context.TODO(), attestation, imageID, options))
+
+	tag := ref.Context().Tag("sha256-" + digest.Hex + ".att")
+	attestations, err := remote.Image(tag)
+	require.NoError(t, err)
+	manifest, err := attestations.Manifest()
+	require.NoError(t, err)
+	require.Len(t, manifest.Layers, 2)
+	assert.Equal(t, dsseMediaType, manifest.Layers[0].MediaType)
+	assert.Equal(t, spdxPredicateType, manifest.Layers[0].Annotations["predicateType"])
+	layers, err := attestations.Layers()
+	require.NoError(t, err)
+	content, err := layers[0].Compressed()
+	require.NoError(t, err)
+	envelope, err := io.ReadAll(content)
+	require.NoError(t, err)
+	assert.Equal(t, attestation.Envelope, envelope)
+}
@@ -0,0 +1,199 @@
+package v1
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/anchore/syft/syft"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+	"github.com/google/go-containerregistry/pkg/name"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// benchImageID is the reference under which the synthetic images are exported
+const benchImageID = "library/alpine@sha256:c0669ef34cdc14332c0f1ab0c2c01acb91d96014b172f1a76f3a39e63d1f0bda"
+
+// benchCorpus is the reference corpus of synthetic Alpine images, from a minimal image to a large application image
+var benchCorpus = []struct {
+	name     string
+	packages int
+	bulk     int64
+}{
+	{name: "small", packages: 15, bulk: 1 << 20},
+	{name: "medium", packages: 150, bulk: 16 << 20},
+	{name: "large", packages: 1000, bulk: 64 << 20},
+}
+
+// benchPackages are real Alpine packages, so that matching finds vulnerabilities
+var benchPackages = [][2]string{
+	{"busybox", "1.35.0-r17"},
+	{"libcrypto3", "3.0.7-r0"},
+	{"libssl3", "3.0.7-r0"},
+	{"musl", "1.2.3-r4"},
+	{"zlib", "1.2.12-r3"},
+}
+
+// syntheticImage builds an Alpine-like image with the given number of installed packages and random bulk data
+func syntheticImage(b *testing.B, packages int, bulk int64) containerregistryV1.Image {
+	var installed bytes.Buffer
+	for i := 0; i < packages; i++ {
+		name, version := fmt.Sprintf("synthetic-%d", i), "1.0.0-r0"
+		if i < len(benchPackages) {
+			name, version = benchPackages[i][0], benchPackages[i][1]
+		}
+		_, _ = fmt.Fprintf(&installed, "P:%s\nV:%s\nA:x86_64\nL:MIT\no:%s\n\n", name, version, name)
+	}
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for path, content := range map[string][]byte{
+		"etc/os-release":       []byte("NAME=\"Alpine Linux\"\nID=alpine\nVERSION_ID=3.17.0\n"),
+		"lib/apk/db/installed": installed.Bytes(),
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: path, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		b.Fatal(err)
+	}
+	content := buf.Bytes()
+	packagesLayer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	bulkLayer, err := random.Layer(bulk, types.DockerLayer)
+	if err != nil {
+		b.Fatal(err)
+	}
+	img, err := mutate.AppendLayers(empty.Image, packagesLayer, bulkLayer)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return img
+}
+
+// benchExportFunc mimics a container runtime export of the given image
+func benchExportFunc(img containerregistryV1.Image) func(context.Context, string, io.Writer) error {
+	return func(_ context.Context, imageID string, w io.Writer) error {
+		ref, err := name.ParseReference(imageID)
+		if err != nil {
+			return err
+		}
+		return tarball.Write(ref, img, w)
+	}
+}
+
+// benchPull reads the image into a new workspace, the caller must clean it up
+func benchPull(b *testing.B, img containerregistryV1.Image) (*workspace, source.Source) {
+	t, err := newWorkspace("")
+	if err != nil {
+		b.Fatal(err)
+	}
+	sourceInput, err := source.ParseInput(benchImageID, "amd64")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var src source.Source
+	err = fetchFromCRI(context.TODO(), t, sourceInput, benchExportFunc(img), func(img containerregistryV1.Image, metadata []image.AdditionalMetadata) (err error) {
+		src, err = newFromImage(t, sourceInput, img, metadata, 1<<30)
+		return err
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return t, src
+}
+
+// benchSBOM extracts the packages of the given source and converts them into an SBOM
+func benchSBOM(b *testing.B, s *SyftAdapter, src source.Source) domain.SBOM {
+	pkgCatalog, relationships, actualDistro, err := syft.CatalogPackages(&src, catalogConfig())
+	if err != nil {
+		b.Fatal(err)
+	}
+	content, err := s.syftToDomain(sbom.SBOM{
+		Source:        src.Metadata,
+		Relationships: relationships,
+		Artifacts: sbom.Artifacts{
+			PackageCatalog:    pkgCatalog,
+			LinuxDistribution: actualDistro,
+		},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return domain.SBOM{Name: benchImageID, Content: content}
+}
+
+func BenchmarkPull(b *testing.B) {
+	for _, image := range benchCorpus {
+		img := syntheticImage(b, image.packages, image.bulk)
+		b.Run(image.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(image.bulk)
+			for i := 0; i < b.N; i++ {
+				t, _ := benchPull(b, img)
+				b.StopTimer()
+				_ = t.Cleanup()
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+func BenchmarkSBOM(b *testing.B) {
+	s := NewSyftAdapter(5*time.Minute, 1<<30)
+	for _, image := range benchCorpus {
+		t, src := benchPull(b, syntheticImage(b, image.packages, image.bulk))
+		b.Run(image.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchSBOM(b, s, src)
+			}
+		})
+		_ = t.Cleanup()
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	ctx := context.TODO()
+	go func() {
+		_ = http.ListenAndServe(":8000", http.FileServer(http.Dir("testdata")))
+	}()
+	g := NewGrypeAdapterFixedDB()
+	if !g.Ready(ctx) {
+		b.Skip("vulnerability DB is not available")
+	}
+	s := NewSyftAdapter(5*time.Minute, 1<<30)
+	for _, image := range benchCorpus {
+		t, src := benchPull(b, syntheticImage(b, image.packages, image.bulk))
+		sbom := benchSBOM(b, s, src)
+		_ = t.Cleanup()
+		b.Run(image.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := g.ScanSBOM(ctx, sbom); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/armosec/utils-go/httputils"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"go.opentelemetry.io/otel"
+)
+
+// callbackTimeout bounds the post of a report, which delays the completion of the scan notifying it
+const callbackTimeout = 10 * time.Second
+
+// CallbackAdapter implements Notifier from ports by posting the scan report to the callback URL of the scan command
+type CallbackAdapter struct {
+	contentType  string
+	httpPostFunc func(httputils.IHttpClient, string, map[string]string, []byte) (*http.Response, error)
+	template     *template.Template
+	timeout      time.Duration
+}
+
+var _ ports.Notifier = (*CallbackAdapter)(nil)
+
+// CallbackAdapterOption configures optional settings of the CallbackAdapter
+type CallbackAdapterOption func(*CallbackAdapter)
+
+// WithPayloadTemplate renders the posted payloads with the template, executed on the domain.ScanReport, instead of
+// posting the report as JSON; payloads of a JSON content type must be valid JSON
+func WithPayloadTemplate(tmpl *template.Template, contentType string) CallbackAdapterOption {
+	return func(c *CallbackAdapter) {
+		c.template = tmpl
+		if contentType != "" {
+			c.contentType = contentType
+		}
+	}
+}
+
+// NewCallbackAdapter initializes the CallbackAdapter struct
+func NewCallbackAdapter(opts ...CallbackAdapterOption) *CallbackAdapter {
+	c := &CallbackAdapter{
+		contentType:  "application/json",
+		httpPostFunc: httputils.HttpPost,
+		timeout:      callbackTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// templateFuncs are the functions available to the payload templates besides the text/template builtins
+var templateFuncs = template.FuncMap{
+	// json encodes a value, such as a string to embed in a JSON payload with its quotes
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// ParsePayloadTemplate parses the payload template of the given file, see WithPayloadTemplate, the missing
+// severities of the summary are zero
+func ParsePayloadTemplate(path string) (*template.Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New("payload").Funcs(templateFuncs).Option("missingkey=zero").Parse(string(text))
+}
+
+// Notify posts the given report to the callback URL of the workload, if any, within the callback timeout whatever
+// the deadline of the scan, so that the reports of the scans that timed out are posted too
+func (c *CallbackAdapter) Notify(ctx context.Context, report domain.ScanReport) error {
+	ctx, span := otel.Tracer("").Start(ctx, "CallbackAdapter.Notify")
+	defer span.End()
+	// retrieve workload from context
+	workload, ok := ctx.Value(domain.WorkloadKey{}).(domain.ScanCommand)
+	if !ok {
+		return domain.ErrCastingWorkload
+	}
+	if workload.CallbackURL == "" {
+		return nil
+	}
+
+	payload, err := c.payload(report)
+	if err != nil {
+		return err
+	}
+	postCtx, cancel := context.WithTimeout(tools.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+	resp, err := c.httpPostFunc(clientWithContext(postCtx), workload.CallbackURL, map[string]string{"Content-Type": c.contentType}, payload)
+	if err != nil {
+		return err
+	}
+	body, err := httputils.HttpRespToString(resp)
+	if err != nil {
+		return fmt.Errorf("callback to %s failed: %w, body: %s", workload.CallbackURL, err, body)
+	}
+	logger.L().Debug("posted scan report to callback",
+		helpers.String("scanID", report.ScanID),
+		helpers.String("callbackURL", workload.CallbackURL))
+	return nil
+}
+
+// payload renders the report with the template if any, as JSON otherwise
+func (c *CallbackAdapter) payload(report domain.ScanReport) ([]byte, error) {
+	if c.template == nil {
+		return json.Marshal(report)
+	}
+	var buf bytes.Buffer
+	if err := c.template.Execute(&buf, report); err != nil {
+		return nil, fmt.Errorf("failed to render the callback payload: %w", err)
+	}
+	if strings.Contains(c.contentType, "json") && !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("the callback payload template rendered invalid JSON for scan %s", report.ScanID)
+	}
+	return buf.Bytes(), nil
+}
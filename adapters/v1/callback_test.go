@@ -0,0 +1,166 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallbackAdapter_Notify(t *testing.T) {
+	var got domain.ScanReport
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &got)
+		if got.Verdict == domain.VerdictError {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+	tests := []struct {
+		name        string
+		workload    bool
+		callbackURL string
+		report      domain.ScanReport
+		want        domain.ScanReport
+		wantErr     bool
+	}{
+		{
+			name:    "no workload",
+			wantErr: true,
+		},
+		{
+			name:     "no callback URL",
+			workload: true,
+			report:   domain.ScanReport{ScanID: "scanID", Verdict: domain.VerdictSuccess},
+		},
+		{
+			name:        "callback posted",
+			workload:    true,
+			callbackURL: ts.URL,
+			report:      domain.ScanReport{ScanID: "scanID", Verdict: domain.VerdictSuccess, Summary: map[string]int{"High": 2}},
+			want:        domain.ScanReport{ScanID: "scanID", Verdict: domain.VerdictSuccess, Summary: map[string]int{"High": 2}},
+		},
+		{
+			name:        "callback rejected",
+			workload:    true,
+			callbackURL: ts.URL,
+			report:      domain.ScanReport{ScanID: "scanID", Verdict: domain.VerdictError},
+			want:        domain.ScanReport{ScanID: "scanID", Verdict: domain.VerdictError},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = domain.ScanReport{}
+			c := NewCallbackAdapter()
+			ctx := context.TODO()
+			if tt.workload {
+				ctx = context.WithValue(ctx, domain.WorkloadKey{}, domain.ScanCommand{CallbackURL: tt.callbackURL})
+			}
+			err := c.Notify(ctx, tt.report)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCallbackAdapter_Notify_timeout(t *testing.T) {
+	posted := make(chan struct{}, 1)
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted <- struct{}{}
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+	c := NewCallbackAdapter()
+	c.timeout = 100 * time.Millisecond
+	// the scan timed out, its report is posted anyway
+	ctx, cancel := context.WithCancel(context.WithValue(context.TODO(), domain.WorkloadKey{}, domain.ScanCommand{CallbackURL: ts.URL}))
+	cancel()
+	start := time.Now()
+	err := c.Notify(ctx, domain.ScanReport{ScanID: "scanID", Verdict: domain.VerdictError})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Len(t, posted, 1)
+}
+
+func TestCallbackAdapter_Notify_template(t *testing.T) {
+	var gotBody, gotContentType string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody, gotContentType = string(b), r.Header.Get("Content-Type")
+	}))
+	defer ts.Close()
+	report := domain.ScanReport{
+		ScanID:     "scanID",
+		ImageTag:   "nginx:1.25",
+		Verdict:    domain.VerdictSuccess,
+		Violations: []string{"2 critical vulnerabilities", `"latest" tag`},
+		Summary:    map[string]int{"Critical": 2, "High": 5},
+	}
+	tests := []struct {
+		name            string
+		template        string
+		contentType     string
+		wantBody        string
+		wantContentType string
+		wantErr         bool
+	}{
+		{
+			name:            "slack blocks",
+			template:        `{"text": {{ printf "%s: %s" .ImageTag (upper .Verdict) | json }}, "blocks": [{"type": "section", "text": {"type": "mrkdwn", "text": {{ join .Violations "\n" | json }}}}]}`,
+			wantBody:        `{"text": "nginx:1.25: SUCCESS", "blocks": [{"type": "section", "text": {"type": "mrkdwn", "text": "2 critical vulnerabilities\n\"latest\" tag"}}]}`,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "missing severities are zero",
+			template:        `{{ .ImageTag }} critical={{ .Summary.Critical }} medium={{ .Summary.Medium }}`,
+			contentType:     "text/plain",
+			wantBody:        `nginx:1.25 critical=2 medium=0`,
+			wantContentType: "text/plain",
+		},
+		{
+			name:     "invalid JSON",
+			template: `{"text": "{{ .Violations }}"}`,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown field",
+			template: `{"text": {{ json .Severity }}}`,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBody, gotContentType = "", ""
+			path := filepath.Join(t.TempDir(), "payload.tmpl")
+			require.NoError(t, os.WriteFile(path, []byte(tt.template), 0600))
+			tmpl, err := ParsePayloadTemplate(path)
+			require.NoError(t, err)
+			c := NewCallbackAdapter(WithPayloadTemplate(tmpl, tt.contentType))
+			ctx := context.WithValue(context.TODO(), domain.WorkloadKey{}, domain.ScanCommand{CallbackURL: ts.URL})
+			err = c.Notify(ctx, report)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, gotBody)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, gotBody)
+			assert.Equal(t, tt.wantContentType, gotContentType)
+		})
+	}
+}
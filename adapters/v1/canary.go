@@ -0,0 +1,156 @@
+package v1
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// canaryRepository is the repository of the embedded canary image in the loopback registry
+const canaryRepository = "kubevuln/canary:latest"
+
+// canaryFS is the root filesystem of the embedded canary image, an Alpine release with a few packages
+//
+//go:embed all:canary
+var canaryFS embed.FS
+
+// CanaryAdapter provides the reference image of the self-test: a configured image, or the canary image embedded in
+// kubevuln, served by a registry listening on the loopback interface from the first self-test on
+type CanaryAdapter struct {
+	imageTag string
+	mu       sync.Mutex
+	served   string
+}
+
+var _ ports.CanaryProvider = (*CanaryAdapter)(nil)
+
+// NewCanaryAdapter initializes the CanaryAdapter, imageTag is the reference image, the embedded one when empty
+func NewCanaryAdapter(imageTag string) *CanaryAdapter {
+	return &CanaryAdapter{imageTag: imageTag}
+}
+
+// CanaryImage returns the reference image with its registry options, starting the loopback registry of the embedded
+// image if needed
+func (c *CanaryAdapter) CanaryImage(ctx context.Context) (string, domain.RegistryOptions, error) {
+	_, span := otel.Tracer("").Start(ctx, "CanaryAdapter.CanaryImage")
+	defer span.End()
+	if c.imageTag != "" {
+		return c.imageTag, domain.RegistryOptions{}, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.served == "" {
+		served, err := serveCanary()
+		if err != nil {
+			return "", domain.RegistryOptions{}, fmt.Errorf("failed to serve the canary image: %w", err)
+		}
+		logger.L().Info("serving the canary image", helpers.String("imageTag", served))
+		c.served = served
+	}
+	return c.served, domain.RegistryOptions{InsecureUseHTTP: true}, nil
+}
+
+// serveCanary starts a registry on the loopback interface and pushes the embedded canary image into it, the registry
+// keeps running until kubevuln exits
+func serveCanary() (string, error) {
+	img, err := canaryImage()
+	if err != nil {
+		return "", err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	srv := &http.Server{
+		Handler:           registry.New(registry.Logger(log.New(io.Discard, "", 0))),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	imageTag := fmt.Sprintf("%s/%s", listener.Addr().String(), canaryRepository)
+	ref, err := name.ParseReference(imageTag, name.Insecure)
+	if err != nil {
+		_ = srv.Close()
+		return "", err
+	}
+	if err := remote.Write(ref, img); err != nil {
+		_ = srv.Close()
+		return "", err
+	}
+	return imageTag, nil
+}
+
+// canaryImage builds the single layer image of the embedded root filesystem, for the platform of kubevuln
+func canaryImage() (containerregistryV1.Image, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err := fs.WalkDir(canaryFS, "canary", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == "canary" {
+			return err
+		}
+		header := &tar.Header{Name: path[len("canary/"):], Mode: 0755, Typeflag: tar.TypeDir}
+		var content []byte
+		if !d.IsDir() {
+			content, err = canaryFS.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			header.Mode = 0644
+			header.Typeflag = tar.TypeReg
+			header.Size = int64(len(content))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err = tw.Write(content)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	if err != nil {
+		return nil, err
+	}
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	config = config.DeepCopy()
+	config.OS = "linux"
+	config.Architecture = runtime.GOARCH
+	return mutate.ConfigFile(img, config)
+}
@@ -0,0 +1,40 @@
+package v1
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanaryAdapter_CanaryImage(t *testing.T) {
+	ctx := context.TODO()
+	imageTag, options, err := NewCanaryAdapter("quay.io/kubescape/canary:v1").CanaryImage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "quay.io/kubescape/canary:v1", imageTag)
+	assert.False(t, options.InsecureUseHTTP)
+
+	c := NewCanaryAdapter("")
+	imageTag, options, err = c.CanaryImage(ctx)
+	require.NoError(t, err)
+	assert.Regexp(t, `^127\.0\.0\.1:\d+/kubevuln/canary:latest$`, imageTag)
+	assert.True(t, options.InsecureUseHTTP)
+	// the registry is started once
+	again, _, err := c.CanaryImage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, imageTag, again)
+
+	// the embedded image goes through the whole SBOM creation
+	sbom, err := NewSyftAdapter(time.Minute, 1<<20).CreateSBOM(ctx, "canary", imageTag, options)
+	require.NoError(t, err)
+	require.NotNil(t, sbom.Content)
+	var packages []string
+	for _, p := range sbom.Content.Packages {
+		packages = append(packages, p.PackageName+"@"+p.PackageVersion)
+	}
+	sort.Strings(packages)
+	assert.Equal(t, []string{"busybox@1.35.0-r29", "musl@1.2.3-r4"}, packages)
+}
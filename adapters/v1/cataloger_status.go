@@ -0,0 +1,141 @@
+package v1
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/anchore/syft/syft/artifact"
+	"github.com/anchore/syft/syft/linux"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/pkg/cataloger"
+	"github.com/anchore/syft/syft/source"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+)
+
+// WithCatalogerRetries retries the failed catalogers up to retries times once the other catalogers are done, the
+// SBOM keeping the packages of the catalogers which succeeded when they still fail
+func WithCatalogerRetries(retries int) SyftAdapterOption {
+	return func(s *SyftAdapter) {
+		s.catalogerRetries = retries
+	}
+}
+
+// catalogWithRetries runs the catalogers, then retries the failed ones up to retries times; the failures are only
+// returned when every cataloger failed, the packages of the others being kept otherwise
+func catalogWithRetries(ctx context.Context, resolver source.FileResolver, release *linux.Release, parallelism, retries int, catalogers []pkg.Cataloger) (*pkg.Catalog, []artifact.Relationship, []domain.CatalogerStatus, error) {
+	statuses := newCatalogerStatuses(ctx)
+	catalog, relationships, err := cataloger.Catalog(resolver, release, parallelism, statuses.wrap(catalogers)...)
+	for attempt := 1; err != nil && attempt <= retries; attempt++ {
+		logger.L().Ctx(ctx).Warning("retrying the failed catalogers", helpers.Error(err),
+			helpers.Int("attempt", attempt))
+		var retried *pkg.Catalog
+		var retriedRelationships []artifact.Relationship
+		retried, retriedRelationships, err = cataloger.Catalog(resolver, release, parallelism, statuses.wrap(statuses.failed(catalogers))...)
+		for p := range retried.Enumerate() {
+			catalog.Add(p)
+		}
+		relationships = append(relationships, retriedRelationships...)
+	}
+	if err != nil {
+		if len(statuses.failed(catalogers)) == len(catalogers) {
+			return nil, nil, nil, err
+		}
+		logger.L().Ctx(ctx).Warning("some catalogers failed, keeping the packages of the others", helpers.Error(err))
+	}
+	return catalog, relationships, statuses.list(), nil
+}
+
+// catalogerStatuses records the outcome of the catalogers of a source by name
+type catalogerStatuses struct {
+	ctx      context.Context
+	mu       sync.Mutex
+	statuses map[string]*domain.CatalogerStatus
+}
+
+func newCatalogerStatuses(ctx context.Context) *catalogerStatuses {
+	return &catalogerStatuses{ctx: ctx, statuses: map[string]*domain.CatalogerStatus{}}
+}
+
+// wrap wraps the catalogers to record their outcome, a panic only failing its cataloger
+func (c *catalogerStatuses) wrap(catalogers []pkg.Cataloger) []pkg.Cataloger {
+	wrapped := make([]pkg.Cataloger, 0, len(catalogers))
+	for _, cataloger := range catalogers {
+		wrapped = append(wrapped, statusCataloger{Cataloger: cataloger, statuses: c})
+	}
+	return wrapped
+}
+
+// record records an attempt of the named cataloger
+func (c *catalogerStatuses) record(name string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status, ok := c.statuses[name]
+	if !ok {
+		status = &domain.CatalogerStatus{Name: name}
+		c.statuses[name] = status
+	}
+	status.Attempts++
+	status.Error = ""
+	if err != nil {
+		status.Error = err.Error()
+	}
+}
+
+// failed returns the catalogers whose last attempt failed
+func (c *catalogerStatuses) failed(catalogers []pkg.Cataloger) []pkg.Cataloger {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var failed []pkg.Cataloger
+	for _, cataloger := range catalogers {
+		if status, ok := c.statuses[cataloger.Name()]; ok && status.Error != "" {
+			failed = append(failed, cataloger)
+		}
+	}
+	return failed
+}
+
+// list returns the statuses sorted by cataloger name
+func (c *catalogerStatuses) list() []domain.CatalogerStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	statuses := make([]domain.CatalogerStatus, 0, len(c.statuses))
+	for _, status := range c.statuses {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// catalogersFailed tells whether one of the catalogers failed
+func catalogersFailed(statuses []domain.CatalogerStatus) bool {
+	for _, status := range statuses {
+		if status.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// statusCataloger records the outcome of a cataloger
+type statusCataloger struct {
+	pkg.Cataloger
+	statuses *catalogerStatuses
+}
+
+func (c statusCataloger) Catalog(resolver source.FileResolver) (packages []pkg.Package, relationships []artifact.Relationship, err error) {
+	defer func() {
+		c.statuses.record(c.Name(), err)
+		attributes := map[string]string{"cataloger": c.Name(), "packages": strconv.Itoa(len(packages))}
+		if err != nil {
+			attributes["error"] = err.Error()
+		}
+		tools.RecordScanEvent(c.statuses.ctx, "cataloger finished", attributes)
+	}()
+	defer tools.RecoverPanic(c.statuses.ctx, &err)
+	return c.Cataloger.Catalog(resolver)
+}
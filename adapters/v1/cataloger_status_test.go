@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/anchore/syft/syft/artifact"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/source"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyCataloger finds a single package once it has failed failures times, panicking instead of failing if panics
+type flakyCataloger struct {
+	name     string
+	failures *int
+	panics   bool
+}
+
+func (c flakyCataloger) Name() string {
+	return c.name
+}
+
+func (c flakyCataloger) Catalog(source.FileResolver) ([]pkg.Package, []artifact.Relationship, error) {
+	if *c.failures > 0 {
+		*c.failures--
+		if c.panics {
+			panic("malformed archive")
+		}
+		return nil, nil, errors.New("corrupt database")
+	}
+	p := pkg.Package{Name: c.name, Version: "1.0.0"}
+	p.SetID()
+	return []pkg.Package{p}, nil, nil
+}
+
+func newFlakyCataloger(name string, failures int, panics bool) flakyCataloger {
+	return flakyCataloger{name: name, failures: &failures, panics: panics}
+}
+
+func packageNames(catalog *pkg.Catalog) []string {
+	var names []string
+	for _, p := range catalog.Sorted() {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func Test_catalogWithRetries(t *testing.T) {
+	src, err := source.NewFromDirectory("testdata/monolith")
+	require.NoError(t, err)
+	resolver, err := src.FileResolver(source.SquashedScope)
+	require.NoError(t, err)
+	ctx := context.TODO()
+
+	// the packages of the catalogers which succeeded are kept
+	catalog, _, statuses, err := catalogWithRetries(ctx, resolver, nil, 1, 0, []pkg.Cataloger{
+		newFlakyCataloger("apkdb-cataloger", 0, false),
+		newFlakyCataloger("rpm-db-cataloger", 1, false),
+		newFlakyCataloger("java-cataloger", 1, true),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"apkdb-cataloger"}, packageNames(catalog))
+	assert.True(t, catalogersFailed(statuses))
+	require.Len(t, statuses, 3)
+	assert.Equal(t, domain.CatalogerStatus{Name: "apkdb-cataloger", Attempts: 1}, statuses[0])
+	assert.Contains(t, statuses[1].Error, "malformed archive")
+	assert.Equal(t, domain.CatalogerStatus{Name: "rpm-db-cataloger", Attempts: 1, Error: "corrupt database"}, statuses[2])
+
+	// only the failed catalogers are retried
+	catalog, _, statuses, err = catalogWithRetries(ctx, resolver, nil, 1, 2, []pkg.Cataloger{
+		newFlakyCataloger("apkdb-cataloger", 0, false),
+		newFlakyCataloger("rpm-db-cataloger", 2, false),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"apkdb-cataloger", "rpm-db-cataloger"}, packageNames(catalog))
+	assert.False(t, catalogersFailed(statuses))
+	assert.Equal(t, []domain.CatalogerStatus{{Name: "apkdb-cataloger", Attempts: 1}, {Name: "rpm-db-cataloger", Attempts: 3}}, statuses)
+
+	// the cataloging fails when no cataloger succeeded
+	_, _, _, err = catalogWithRetries(ctx, resolver, nil, 1, 1, []pkg.Cataloger{
+		newFlakyCataloger("rpm-db-cataloger", 2, false),
+	})
+	assert.ErrorContains(t, err, "corrupt database")
+}
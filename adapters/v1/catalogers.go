@@ -0,0 +1,184 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anchore/syft/syft/artifact"
+	"github.com/anchore/syft/syft/linux"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/pkg/cataloger"
+	"github.com/anchore/syft/syft/pkg/cataloger/dart"
+	"github.com/anchore/syft/syft/pkg/cataloger/php"
+	"github.com/anchore/syft/syft/pkg/cataloger/ruby"
+	"github.com/anchore/syft/syft/pkg/cataloger/rust"
+	"github.com/anchore/syft/syft/source"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// cataloging modes of the language ecosystems
+const (
+	// CatalogerModeInstalled analyzes the installed packages, including vendored sources (default)
+	CatalogerModeInstalled = "installed"
+	// CatalogerModeLockfile trusts the lockfiles and skips the analysis of the installed packages
+	CatalogerModeLockfile = "lockfile"
+	// CatalogerModeDisabled skips the ecosystem
+	CatalogerModeDisabled = "disabled"
+)
+
+// ecosystemCatalogers are the catalogers of the installed packages and of the lockfiles of the language ecosystems
+// whose cataloging mode is configurable
+var ecosystemCatalogers = map[string]struct {
+	installed string
+	lockfile  func() pkg.Cataloger
+}{
+	"php": {
+		installed: "php-composer-installed-cataloger",
+		lockfile:  func() pkg.Cataloger { return php.NewComposerLockCataloger() },
+	},
+	"ruby": {
+		installed: "ruby-gemspec-cataloger",
+		lockfile:  func() pkg.Cataloger { return ruby.NewGemFileLockCataloger() },
+	},
+}
+
+// optional language ecosystems, not cataloged in images by default
+const (
+	EcosystemDart  = "dart"
+	EcosystemRust  = "rust"
+	EcosystemSwift = "swift"
+)
+
+// optionalEcosystemCatalogers are the catalogers of the lockfiles of the optional ecosystems, and of the Rust binaries
+// built with cargo auditable
+var optionalEcosystemCatalogers = map[string]func() []pkg.Cataloger{
+	EcosystemDart: func() []pkg.Cataloger {
+		return []pkg.Cataloger{dart.NewPubspecLockCataloger()}
+	},
+	EcosystemRust: func() []pkg.Cataloger {
+		return []pkg.Cataloger{rust.NewCargoLockCataloger(), rust.NewAuditBinaryCataloger()}
+	},
+	EcosystemSwift: func() []pkg.Cataloger {
+		return []pkg.Cataloger{newPackageResolvedCataloger()}
+	},
+}
+
+// WithEcosystems catalogs the optional ecosystems, among EcosystemDart, EcosystemRust and EcosystemSwift
+func WithEcosystems(ecosystems []string) SyftAdapterOption {
+	return func(s *SyftAdapter) {
+		s.ecosystems = ecosystems
+	}
+}
+
+// WithCatalogerModes sets the cataloging mode of the php and ruby ecosystems, ecosystems not listed
+// are cataloged from their installed packages
+func WithCatalogerModes(modes map[string]string) SyftAdapterOption {
+	return func(s *SyftAdapter) {
+		s.catalogerModes = modes
+	}
+}
+
+// imageCatalogers returns Syft's image catalogers with the language ecosystems switched to their cataloging mode,
+// and the NuGet packages.config cataloger; the fast profile only runs the OS package catalogers
+func (s *SyftAdapter) imageCatalogers(cfg cataloger.Config) ([]pkg.Cataloger, error) {
+	if s.scanProfile == ScanProfileFast {
+		var catalogers []pkg.Cataloger
+		for _, c := range cataloger.ImageCatalogers(cfg) {
+			if osPackageCatalogers[c.Name()] {
+				catalogers = append(catalogers, c)
+			}
+		}
+		return catalogers, nil
+	}
+	skipped := map[string]bool{}
+	var catalogers []pkg.Cataloger
+	for ecosystem, mode := range s.catalogerModes {
+		ecosystemCataloger, ok := ecosystemCatalogers[ecosystem]
+		if !ok {
+			return nil, fmt.Errorf("unknown ecosystem %q", ecosystem)
+		}
+		switch mode {
+		case CatalogerModeInstalled:
+		case CatalogerModeLockfile:
+			skipped[ecosystemCataloger.installed] = true
+			catalogers = append(catalogers, ecosystemCataloger.lockfile())
+		case CatalogerModeDisabled:
+			skipped[ecosystemCataloger.installed] = true
+		default:
+			return nil, fmt.Errorf("unknown cataloger mode %q for ecosystem %q", mode, ecosystem)
+		}
+	}
+	for _, c := range cataloger.ImageCatalogers(cfg) {
+		if !skipped[c.Name()] {
+			catalogers = append(catalogers, c)
+		}
+	}
+	optional, err := s.optionalCatalogers()
+	if err != nil {
+		return nil, err
+	}
+	catalogers = append(catalogers, optional...)
+	return append(catalogers, newPackagesConfigCataloger()), nil
+}
+
+// directoryCatalogers returns Syft's directory catalogers, which read the lockfiles of the optional ecosystems Syft
+// supports, and the catalogers of the other enabled optional ecosystems
+func (s *SyftAdapter) directoryCatalogers(cfg cataloger.Config) ([]pkg.Cataloger, error) {
+	catalogers := cataloger.DirectoryCatalogers(cfg)
+	names := map[string]bool{}
+	for _, c := range catalogers {
+		names[c.Name()] = true
+	}
+	optional, err := s.optionalCatalogers()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range optional {
+		if !names[c.Name()] {
+			catalogers = append(catalogers, c)
+		}
+	}
+	return catalogers, nil
+}
+
+// optionalCatalogers returns the catalogers of the enabled optional ecosystems
+func (s *SyftAdapter) optionalCatalogers() ([]pkg.Cataloger, error) {
+	var catalogers []pkg.Cataloger
+	for _, ecosystem := range s.ecosystems {
+		ecosystemCatalogers, ok := optionalEcosystemCatalogers[ecosystem]
+		if !ok {
+			return nil, fmt.Errorf("unknown ecosystem %q", ecosystem)
+		}
+		catalogers = append(catalogers, ecosystemCatalogers()...)
+	}
+	return catalogers, nil
+}
+
+// catalogPackages extracts the packages of the source like syft.CatalogPackages does for images,
+// using the catalogers returned by imageCatalogers as catalogWithRetries does; the file resolver is returned for
+// further analysis, with the status of each cataloger
+func (s *SyftAdapter) catalogPackages(ctx context.Context, src *source.Source) (*pkg.Catalog, []artifact.Relationship, *linux.Release, source.FileResolver, []domain.CatalogerStatus, error) {
+	cfg := catalogConfig()
+	catalogers, err := s.imageCatalogers(cfg)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	resolver, err := src.FileResolver(cfg.Search.Scope)
+	if err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("unable to determine resolver while cataloging packages: %w", err)
+	}
+	release := linux.IdentifyRelease(resolver)
+	catalog, relationships, statuses, err := catalogWithRetries(ctx, resolver, release, cfg.Parallelism, s.catalogerRetries, withCatalogProgress(ctx, catalogers))
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	addGoStdlibPackages(catalog)
+	for p := range catalog.Enumerate() {
+		relationships = append(relationships, artifact.Relationship{
+			From: src,
+			To:   p,
+			Type: artifact.ContainsRelationship,
+		})
+	}
+	return catalog, relationships, release, resolver, statuses, nil
+}
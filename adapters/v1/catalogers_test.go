@@ -0,0 +1,125 @@
+package v1
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/anchore/syft/syft/source"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyftAdapter_catalogPackages(t *testing.T) {
+	tests := []struct {
+		name    string
+		modes   map[string]string
+		profile string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "installed packages by default",
+			want: []string{"nokogiri@1.14.2", "symfony/http-foundation@v5.4.21"},
+		},
+		{
+			name:  "lockfile only",
+			modes: map[string]string{"php": CatalogerModeLockfile, "ruby": CatalogerModeLockfile},
+			want:  []string{"monolog/monolog@2.9.1", "rack@2.2.6.4"},
+		},
+		{
+			name:  "mixed modes",
+			modes: map[string]string{"php": CatalogerModeDisabled, "ruby": CatalogerModeInstalled},
+			want:  []string{"nokogiri@1.14.2"},
+		},
+		{
+			name:    "fast profile skips the language ecosystems",
+			modes:   map[string]string{"php": CatalogerModeLockfile},
+			profile: ScanProfileFast,
+		},
+		{
+			name:    "unknown ecosystem",
+			modes:   map[string]string{"python": CatalogerModeLockfile},
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			modes:   map[string]string{"php": "vendored"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := source.NewFromDirectory("testdata/monolith")
+			assert.NoError(t, err)
+			s := NewSyftAdapter(0, 0, WithCatalogerModes(tt.modes), WithScanProfile(tt.profile))
+			catalog, _, _, _, _, err := s.catalogPackages(context.TODO(), &src)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("catalogPackages() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			var got []string
+			for _, p := range catalog.Sorted() {
+				got = append(got, p.Name+"@"+p.Version)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSyftAdapter_catalogPackages_ecosystems(t *testing.T) {
+	tests := []struct {
+		name       string
+		ecosystems []string
+		want       []string
+		wantErr    bool
+	}{
+		{
+			name: "not cataloged by default",
+		},
+		{
+			name:       "rust",
+			ecosystems: []string{EcosystemRust},
+			want:       []string{"pkg:cargo/app@0.1.0", "pkg:cargo/smallvec@1.6.0"},
+		},
+		{
+			name:       "all",
+			ecosystems: []string{EcosystemDart, EcosystemRust, EcosystemSwift},
+			want: []string{
+				"pkg:cargo/app@0.1.0",
+				"pkg:cargo/smallvec@1.6.0",
+				"pkg:pub/http@0.13.5",
+				"pkg:swift/github.com/Alamofire/Alamofire@5.4.3",
+				"pkg:swift/github.com/apple/swift-nio@2.40.0",
+			},
+		},
+		{
+			name:       "unknown ecosystem",
+			ecosystems: []string{"kotlin"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := source.NewFromDirectory("testdata/ecosystems")
+			assert.NoError(t, err)
+			s := NewSyftAdapter(0, 0, WithEcosystems(tt.ecosystems))
+			catalog, _, _, _, _, err := s.catalogPackages(context.TODO(), &src)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("catalogPackages() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			var got []string
+			for _, p := range catalog.Sorted() {
+				got = append(got, p.PURL)
+			}
+			sort.Strings(got)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/anchore/syft/syft/source"
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/images/archive"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/platforms"
+	"github.com/docker/docker/client"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// criNamespace is the containerd namespace used by the CRI plugin for Kubernetes images
+const criNamespace = "k8s.io"
+
+// the container runtimes whose sockets are detected, CRIRuntimeAuto detects the first one listening on the node
+const (
+	CRIRuntimeAuto       = "auto"
+	CRIRuntimeContainerd = "containerd"
+	CRIRuntimeCRIO       = "crio"
+	CRIRuntimeDocker     = "docker"
+)
+
+// imageSourceRegistry is the image source of the images downloaded from the registry
+const imageSourceRegistry = "registry"
+
+var (
+	errCRIDisabled    = errors.New("container runtime image access is disabled")
+	errCRIOExport     = errors.New("CRI-O cannot export images, they are pulled from the registry")
+	errNoCRISocket    = errors.New("no container runtime socket found")
+	errUnknownRuntime = errors.New("unknown container runtime")
+)
+
+// criSockets are the default socket addresses of the container runtimes, in detection order
+var criSockets = []struct {
+	runtime string
+	paths   []string
+}{
+	{CRIRuntimeContainerd, []string{"/run/containerd/containerd.sock", "/var/run/containerd/containerd.sock", "/run/k3s/containerd/containerd.sock"}},
+	{CRIRuntimeCRIO, []string{"/run/crio/crio.sock", "/var/run/crio/crio.sock"}},
+	{CRIRuntimeDocker, []string{"/run/docker.sock", "/var/run/docker.sock"}},
+}
+
+// CRIRuntime is a container runtime listening on a socket of the node
+type CRIRuntime struct {
+	Name   string
+	Socket string
+}
+
+// DetectCRIRuntime returns the container runtime to read the images from: runtime is auto to detect it from its
+// socket, or the name of the runtime; socket overrides the default sockets, without runtime it is a containerd one
+func DetectCRIRuntime(runtime, socket string) (CRIRuntime, error) {
+	if runtime == "" {
+		runtime = CRIRuntimeContainerd
+	}
+	if socket != "" {
+		if runtime == CRIRuntimeAuto {
+			runtime = runtimeOfSocket(socket)
+		}
+		if !isSocket(socket) {
+			return CRIRuntime{}, fmt.Errorf("%w: %s", errNoCRISocket, socket)
+		}
+		return CRIRuntime{Name: runtime, Socket: socket}, nil
+	}
+	known := false
+	for _, candidate := range criSockets {
+		if runtime != CRIRuntimeAuto && runtime != candidate.runtime {
+			continue
+		}
+		known = true
+		for _, path := range candidate.paths {
+			if isSocket(path) {
+				return CRIRuntime{Name: candidate.runtime, Socket: path}, nil
+			}
+		}
+	}
+	if !known {
+		return CRIRuntime{}, fmt.Errorf("%w: %s", errUnknownRuntime, runtime)
+	}
+	return CRIRuntime{}, fmt.Errorf("%w for %s", errNoCRISocket, runtime)
+}
+
+// runtimeOfSocket guesses the container runtime from the path of its socket, containerd by default
+func runtimeOfSocket(socket string) string {
+	switch base := filepath.Base(socket); {
+	case strings.Contains(base, "crio"):
+		return CRIRuntimeCRIO
+	case strings.Contains(base, "docker"):
+		return CRIRuntimeDocker
+	default:
+		return CRIRuntimeContainerd
+	}
+}
+
+// isSocket tells whether a unix socket exists at path
+func isSocket(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&os.ModeSocket != 0
+}
+
+// ExportFunc returns the export function of the images of the container runtime, CRI-O images cannot be exported
+func (r CRIRuntime) ExportFunc() (func(context.Context, string, io.Writer) error, error) {
+	switch r.Name {
+	case CRIRuntimeContainerd:
+		return ContainerdExportFunc(r.Socket), nil
+	case CRIRuntimeDocker:
+		return DockerExportFunc(r.Socket), nil
+	case CRIRuntimeCRIO:
+		return nil, errCRIOExport
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownRuntime, r.Name)
+	}
+}
+
+// WithCRIExport reads images already pulled on the node through the given export function
+// instead of downloading them from the registry, images missing from the node are still pulled
+func WithCRIExport(exportFunc func(context.Context, string, io.Writer) error) SyftAdapterOption {
+	return func(s *SyftAdapter) {
+		s.criExportFunc = exportFunc
+		s.criRuntime = "cri"
+	}
+}
+
+// WithCRIRuntime reads images already pulled on the node from the container runtime, images missing from the node
+// are still pulled; the SBOMs record whether the image was read from the runtime or the registry
+func WithCRIRuntime(runtime CRIRuntime, exportFunc func(context.Context, string, io.Writer) error) SyftAdapterOption {
+	return func(s *SyftAdapter) {
+		s.criExportFunc = exportFunc
+		s.criRuntime = runtime.Name
+	}
+}
+
+// ContainerdExportFunc returns an export function writing a docker-archive of an image stored
+// by the containerd instance listening on the given socket address (mounted from the node)
+func ContainerdExportFunc(address string) func(context.Context, string, io.Writer) error {
+	return func(ctx context.Context, imageID string, w io.Writer) error {
+		client, err := containerd.New(address, containerd.WithDefaultNamespace(criNamespace))
+		if err != nil {
+			return fmt.Errorf("failed to connect to containerd: %w", err)
+		}
+		defer client.Close()
+		ctx = namespaces.WithNamespace(ctx, criNamespace)
+		return client.Export(ctx, w,
+			archive.WithImage(client.ImageService(), imageID),
+			archive.WithPlatform(platforms.DefaultStrict()))
+	}
+}
+
+// DockerExportFunc returns an export function writing a docker-archive of an image stored by the Docker daemon
+// listening on the given socket address (mounted from the node)
+func DockerExportFunc(address string) func(context.Context, string, io.Writer) error {
+	return func(ctx context.Context, imageID string, w io.Writer) error {
+		cli, err := client.NewClientWithOpts(client.WithHost("unix://"+address), client.WithAPIVersionNegotiation())
+		if err != nil {
+			return fmt.Errorf("failed to connect to docker: %w", err)
+		}
+		defer cli.Close()
+		archive, err := cli.ImageSave(ctx, []string{imageID})
+		if err != nil {
+			return err
+		}
+		defer archive.Close()
+		_, err = io.Copy(w, archive)
+		return err
+	}
+}
+
+// fetchFromCRI exports the image from the container runtime into a temporary archive and passes it to the loader
+func fetchFromCRI(ctx context.Context, t *workspace, sourceInput *source.Input, exportFunc func(context.Context, string, io.Writer) error, load imageLoader) error {
+	archiveDir, err := t.NewDirectory("cri-image")
+	if err != nil {
+		return err
+	}
+	archivePath := filepath.Join(archiveDir, "image.tar")
+	f, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	err = exportFunc(ctx, sourceInput.UserInput, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to export image from container runtime: %w", err)
+	}
+
+	imgArchive, err := tarball.ImageFromPath(archivePath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to load exported image: %w", err)
+	}
+
+	var metadata []image.AdditionalMetadata
+	if ref, err := name.NewDigest(sourceInput.UserInput); err == nil {
+		metadata = append(metadata, image.WithRepoDigests(ref.String()))
+	}
+	if manifestBytes, err := imgArchive.RawManifest(); err == nil {
+		metadata = append(metadata, image.WithManifest(manifestBytes))
+	}
+
+	return load(imgArchive, metadata)
+}
@@ -0,0 +1,159 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyftAdapter_CreateSBOMFromCRI(t *testing.T) {
+	imageID := "library/alpine@sha256:c0669ef34cdc14332c0f1ab0c2c01acb91d96014b172f1a76f3a39e63d1f0bda"
+	img, err := random.Image(1024, 2)
+	assert.NoError(t, err)
+	tests := []struct {
+		name         string
+		exportFunc   func(context.Context, string, io.Writer) error
+		maxImageSize int64
+		wantStatus   string
+		wantErr      bool
+	}{
+		{
+			name: "image read from container runtime",
+			exportFunc: func(_ context.Context, imageID string, w io.Writer) error {
+				ref, _ := name.ParseReference(imageID)
+				return tarball.Write(ref, img, w)
+			},
+			maxImageSize: 512 * 1024 * 1024,
+		},
+		{
+			name: "image too large",
+			exportFunc: func(_ context.Context, imageID string, w io.Writer) error {
+				ref, _ := name.ParseReference(imageID)
+				return tarball.Write(ref, img, w)
+			},
+			maxImageSize: 1,
+			wantStatus:   instanceidhandler.Incomplete,
+		},
+		{
+			name: "export failure falls back to registry",
+			exportFunc: func(context.Context, string, io.Writer) error {
+				return fmt.Errorf("image not found")
+			},
+			maxImageSize: 512 * 1024 * 1024,
+			wantErr:      true, // invalid registry in tests
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSyftAdapter(5*time.Minute, tt.maxImageSize, WithCRIExport(tt.exportFunc))
+			options := domain.RegistryOptions{InsecureUseHTTP: true}
+			got, err := s.CreateSBOM(context.TODO(), "name", "localhost:1/"+imageID, options)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateSBOM() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.wantStatus, got.Status)
+			if !tt.wantErr && tt.wantStatus == "" {
+				assert.NotNil(t, got.Content)
+				assert.Equal(t, "cri", got.Annotations[domain.AnnotationImageSource])
+			}
+		})
+	}
+}
+
+func TestDetectCRIRuntime(t *testing.T) {
+	dir := t.TempDir()
+	listen := func(name string) string {
+		path := filepath.Join(dir, name)
+		l, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = l.Close() })
+		return path
+	}
+	containerd := filepath.Join(dir, "containerd.sock")
+	crio := listen("crio.sock")
+	docker := listen("docker.sock")
+	sockets := criSockets
+	criSockets = []struct {
+		runtime string
+		paths   []string
+	}{
+		{CRIRuntimeContainerd, []string{containerd}},
+		{CRIRuntimeCRIO, []string{crio}},
+		{CRIRuntimeDocker, []string{docker}},
+	}
+	t.Cleanup(func() { criSockets = sockets })
+	tests := []struct {
+		name    string
+		runtime string
+		socket  string
+		want    CRIRuntime
+		wantErr error
+	}{
+		{
+			name:    "first socket listening",
+			runtime: CRIRuntimeAuto,
+			want:    CRIRuntime{Name: CRIRuntimeCRIO, Socket: crio},
+		},
+		{
+			name:    "runtime given",
+			runtime: CRIRuntimeDocker,
+			want:    CRIRuntime{Name: CRIRuntimeDocker, Socket: docker},
+		},
+		{
+			name:    "runtime not listening",
+			runtime: CRIRuntimeContainerd,
+			wantErr: errNoCRISocket,
+		},
+		{
+			name:    "unknown runtime",
+			runtime: "podman",
+			wantErr: errUnknownRuntime,
+		},
+		{
+			name:    "socket given",
+			runtime: CRIRuntimeAuto,
+			socket:  docker,
+			want:    CRIRuntime{Name: CRIRuntimeDocker, Socket: docker},
+		},
+		{
+			name:   "socket given without runtime is containerd",
+			socket: docker,
+			want:   CRIRuntime{Name: CRIRuntimeContainerd, Socket: docker},
+		},
+		{
+			name:    "socket missing",
+			socket:  containerd,
+			wantErr: errNoCRISocket,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectCRIRuntime(tt.runtime, tt.socket)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCRIRuntime_ExportFunc(t *testing.T) {
+	for _, runtime := range []string{CRIRuntimeContainerd, CRIRuntimeDocker} {
+		exportFunc, err := CRIRuntime{Name: runtime, Socket: "/run/test.sock"}.ExportFunc()
+		assert.NoError(t, err)
+		assert.NotNil(t, exportFunc)
+	}
+	_, err := CRIRuntime{Name: CRIRuntimeCRIO, Socket: "/run/crio/crio.sock"}.ExportFunc()
+	assert.ErrorIs(t, err, errCRIOExport)
+}
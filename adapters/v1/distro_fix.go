@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/anchore/grype/grype/search"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+)
+
+// distroFixState is the fix state reported for vulnerabilities fixed by a distro patched build
+const distroFixState = "fixed-in-distro"
+
+// distroFormats are the version formats of the distro package managers, whose feeds track
+// the patches backported by the distro maintainers onto the same upstream version
+var distroFormats = map[string]bool{
+	"apk":     true,
+	"deb":     true,
+	"portage": true,
+	"rpm":     true,
+}
+
+// distroFixVersion returns the distro version fixing the match, read from a distro feed constraint
+// such as "< 1.1.1n-0+deb11u4 (deb)" when the vulnerability does not list its fix versions
+func distroFixVersion(match v1beta1.Match) (string, bool) {
+	for _, detail := range match.MatchDetails {
+		var found search.CPEResult
+		if err := json.Unmarshal(detail.Found, &found); err != nil {
+			continue
+		}
+		constraint, format := splitConstraintFormat(found.VersionConstraint)
+		if !distroFormats[format] {
+			continue
+		}
+		for _, unit := range strings.FieldsFunc(constraint, func(r rune) bool { return r == ',' || r == '|' }) {
+			unit = strings.TrimSpace(unit)
+			if strings.HasPrefix(unit, "<") && !strings.HasPrefix(unit, "<=") {
+				if version := strings.TrimSpace(strings.TrimPrefix(unit, "<")); version != "" {
+					return version, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// splitConstraintFormat splits a grype version constraint from its trailing version format
+func splitConstraintFormat(constraint string) (string, string) {
+	i := strings.LastIndex(constraint, " (")
+	if i < 0 || !strings.HasSuffix(constraint, ")") {
+		return constraint, ""
+	}
+	return constraint[:i], constraint[i+2 : len(constraint)-1]
+}
+
+// isBackport tells whether the fix version is a distro patched build of the installed upstream version
+func isBackport(pkgType v1beta1.SyftType, installed, fixed string) bool {
+	if !distroFormats[string(pkgType)] || installed == fixed {
+		return false
+	}
+	upstream := upstreamVersion(string(pkgType), installed)
+	return upstream != "" && upstream == upstreamVersion(string(pkgType), fixed)
+}
+
+// upstreamVersion strips the epoch and the distro revision of a package version
+func upstreamVersion(format, version string) string {
+	if i := strings.Index(version, ":"); i >= 0 {
+		version = version[i+1:]
+	}
+	separator := "-"
+	if format == "apk" || format == "portage" {
+		separator = "-r"
+	}
+	if i := strings.LastIndex(version, separator); i >= 0 {
+		version = version[:i]
+	}
+	return version
+}
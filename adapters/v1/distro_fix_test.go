@@ -0,0 +1,110 @@
+package v1
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_distroFixVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraints []string
+		want        string
+		wantOk      bool
+	}{
+		{
+			name:        "deb constraint",
+			constraints: []string{"< 1.1.1n-0+deb11u4 (deb)"},
+			want:        "1.1.1n-0+deb11u4",
+			wantOk:      true,
+		},
+		{
+			name:        "rpm range",
+			constraints: []string{">= 0:1.1.1k-1.el8, < 1:1.1.1k-7.el8_6 (rpm)"},
+			want:        "1:1.1.1k-7.el8_6",
+			wantOk:      true,
+		},
+		{
+			name:        "upstream constraint then distro constraint",
+			constraints: []string{"< 3.0.7 (unknown)", "< 3.0.7-r0 (apk)"},
+			want:        "3.0.7-r0",
+			wantOk:      true,
+		},
+		{
+			name:        "not fixed in distro",
+			constraints: []string{"none (deb)"},
+		},
+		{
+			name:        "inclusive bound",
+			constraints: []string{"<= 1.1.1n-0+deb11u4 (deb)"},
+		},
+		{
+			name:        "language constraint",
+			constraints: []string{"< 2.15.0 (semver)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var match v1beta1.Match
+			for _, constraint := range tt.constraints {
+				found, _ := json.Marshal(map[string]string{"versionConstraint": constraint})
+				match.MatchDetails = append(match.MatchDetails, v1beta1.MatchDetails{Found: found})
+			}
+			got, ok := distroFixVersion(match)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_isBackport(t *testing.T) {
+	tests := []struct {
+		name      string
+		pkgType   v1beta1.SyftType
+		installed string
+		fixed     string
+		want      bool
+	}{
+		{
+			name:      "debian security update",
+			pkgType:   "deb",
+			installed: "1.1.1n-0+deb11u3",
+			fixed:     "1.1.1n-0+deb11u4",
+			want:      true,
+		},
+		{
+			name:      "rhel patched build with epoch",
+			pkgType:   "rpm",
+			installed: "1:1.1.1k-6.el8_5",
+			fixed:     "1:1.1.1k-7.el8_6",
+			want:      true,
+		},
+		{
+			name:      "alpine release",
+			pkgType:   "apk",
+			installed: "3.0.7-r0",
+			fixed:     "3.0.7-r2",
+			want:      true,
+		},
+		{
+			name:      "new upstream version",
+			pkgType:   "deb",
+			installed: "1.1.1n-0+deb11u3",
+			fixed:     "1.1.1w-0+deb11u1",
+		},
+		{
+			name:      "language package",
+			pkgType:   "java-archive",
+			installed: "2.14.1-1",
+			fixed:     "2.14.1-2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isBackport(tt.pkgType, tt.installed, tt.fixed))
+		})
+	}
+}
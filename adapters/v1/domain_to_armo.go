@@ -53,11 +53,20 @@ func domainToArmo(ctx context.Context, grypeDocument v1beta1.GrypeDocument, vuln
 		for _, match := range grypeDocument.Matches {
 			var isFixed int
 			var version string
+			fixState := match.Vulnerability.Fix.State
 			description := match.Vulnerability.Description
 			link := "https://nvd.nist.gov/vuln/detail/" + match.Vulnerability.ID
 			if len(match.Vulnerability.Fix.Versions) != 0 {
 				isFixed = 1
 				version = match.Vulnerability.Fix.Versions[0]
+				if isBackport(match.Artifact.Type, match.Artifact.Version, version) {
+					fixState = distroFixState
+				}
+			} else if distroVersion, ok := distroFixVersion(match); ok {
+				// the distro feed bounds the vulnerable versions with a patched build
+				isFixed = 1
+				version = distroVersion
+				fixState = distroFixState
 			} else {
 				// also check CPE matches
 				for _, detail := range match.MatchDetails {
@@ -100,7 +109,7 @@ func domainToArmo(ctx context.Context, grypeDocument v1beta1.GrypeDocument, vuln
 					SeverityScore:      containerscan.SeverityStr2Score[match.Vulnerability.Severity],
 					Fixes: []containerscan.FixedIn{
 						{
-							Name:    match.Vulnerability.Fix.State,
+							Name:    fixState,
 							ImgTag:  workload.ImageTagNormalized,
 							Version: version,
 						},
@@ -126,6 +126,42 @@ func Test_domainToArmo(t *testing.T) {
 				IsFixed:       1,
 			}},
 		},
+		{
+			name: "Detect distro backport with distro constraint",
+			grypeDocument: v1beta1.GrypeDocument{
+				Source: &v1beta1.Source{
+					Target: json.RawMessage(`{"userInput":"","imageID":"","manifestDigest":"","mediaType":"","tags":null,"imageSize":0,"layers":[{"mediaType":"","digest":"dummyLayer","size":0}],"manifest":null,"config":null,"repoDigests":null,"architecture":"","os":""}`),
+				},
+				Matches: []v1beta1.Match{{
+					Vulnerability: v1beta1.Vulnerability{
+						VulnerabilityMetadata: v1beta1.VulnerabilityMetadata{
+							ID:          "CVE-2022-1292",
+							Description: "c_rehash script vulnerability",
+						},
+					},
+					MatchDetails: []v1beta1.MatchDetails{{
+						Type:  "exact-direct-match",
+						Found: json.RawMessage(`{"vulnerabilityID":"CVE-2022-1292","versionConstraint":"< 1.1.1n-0+deb11u2 (deb)"}`),
+					}},
+					Artifact: v1beta1.GrypePackage{Name: "openssl", Version: "1.1.1n-0+deb11u1", Type: "deb"},
+				}},
+			},
+			want: []containerscan.CommonContainerVulnerabilityResult{{
+				IntroducedInLayer: dummyLayer,
+				Vulnerability: containerscan.Vulnerability{
+					Description:        "c_rehash script vulnerability",
+					Name:               "CVE-2022-1292",
+					Link:               "https://nvd.nist.gov/vuln/detail/CVE-2022-1292",
+					RelatedPackageName: "openssl",
+					PackageVersion:     "1.1.1n-0+deb11u1",
+					Fixes:              containerscan.VulFixes{{Name: distroFixState, Version: "1.1.1n-0+deb11u2"}},
+				},
+				Layers:        []containerscan.ESLayer{{LayerHash: dummyLayer}},
+				RelevantLinks: []string{"https://nvd.nist.gov/vuln/detail/CVE-2022-1292", ""},
+				IsLastScan:    1,
+				IsFixed:       1,
+			}},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,105 @@
+package v1
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/anchore/syft/syft/artifact"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/pkg/cataloger/generic"
+	"github.com/anchore/syft/syft/source"
+)
+
+const (
+	dotnetDepsGlob          = "**/*.deps.json"
+	packagesConfigCataloger = "dotnet-packages-config-cataloger"
+	packagesConfigGlob      = "**/packages.config"
+)
+
+// packagesConfig is the NuGet packages.config of .NET Framework projects
+type packagesConfig struct {
+	Packages []struct {
+		ID              string `xml:"id,attr"`
+		Version         string `xml:"version,attr"`
+		TargetFramework string `xml:"targetFramework,attr"`
+	} `xml:"package"`
+}
+
+// newPackagesConfigCataloger returns a cataloger of the NuGet packages listed in packages.config files,
+// which Syft does not catalog while .NET Framework applications ship them instead of deps.json files
+func newPackagesConfigCataloger() *generic.Cataloger {
+	return generic.NewCataloger(packagesConfigCataloger).
+		WithParserByGlobs(parsePackagesConfig, packagesConfigGlob)
+}
+
+func parsePackagesConfig(_ source.FileResolver, _ *generic.Environment, reader source.LocationReadCloser) ([]pkg.Package, []artifact.Relationship, error) {
+	var config packagesConfig
+	if err := xml.NewDecoder(reader).Decode(&config); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse packages.config file: %w", err)
+	}
+	var pkgs []pkg.Package
+	for _, entry := range config.Packages {
+		if entry.ID == "" || entry.Version == "" {
+			continue
+		}
+		p := pkg.Package{
+			Name:         entry.ID,
+			Version:      entry.Version,
+			Locations:    source.NewLocationSet(reader.Location),
+			PURL:         packageurl.NewPackageURL(packageurl.TypeNuget, "", entry.ID, entry.Version, nil, "").ToString(),
+			Language:     pkg.Dotnet,
+			Type:         pkg.DotnetPkg,
+			MetadataType: pkg.DotnetDepsMetadataType,
+			Metadata:     pkg.DotnetDepsMetadata{Name: entry.ID, Version: entry.Version},
+		}
+		p.SetID()
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil, nil
+}
+
+// dotnetTargetFrameworks returns the sorted frameworks targeted by the .NET applications of the image,
+// read from the runtime target of their deps.json files and the target framework of their packages.config files
+func dotnetTargetFrameworks(resolver source.FileResolver) []string {
+	frameworks := map[string]struct{}{}
+	locations, err := resolver.FilesByGlob(dotnetDepsGlob, packagesConfigGlob)
+	if err != nil {
+		return nil
+	}
+	for _, location := range locations {
+		reader, err := resolver.FileContentsByLocation(location)
+		if err != nil {
+			continue
+		}
+		if strings.HasSuffix(location.RealPath, ".deps.json") {
+			var deps struct {
+				RuntimeTarget struct {
+					Name string `json:"name"`
+				} `json:"runtimeTarget"`
+			}
+			if json.NewDecoder(reader).Decode(&deps) == nil && deps.RuntimeTarget.Name != "" {
+				frameworks[deps.RuntimeTarget.Name] = struct{}{}
+			}
+		} else {
+			var config packagesConfig
+			if xml.NewDecoder(reader).Decode(&config) == nil {
+				for _, entry := range config.Packages {
+					if entry.TargetFramework != "" {
+						frameworks[entry.TargetFramework] = struct{}{}
+					}
+				}
+			}
+		}
+		_ = reader.Close()
+	}
+	result := make([]string, 0, len(frameworks))
+	for framework := range frameworks {
+		result = append(result, framework)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,38 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/source"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parsePackagesConfig(t *testing.T) {
+	src, err := source.NewFromDirectory("testdata/dotnet")
+	assert.NoError(t, err)
+	catalog, relationships, _, _, _, err := (&SyftAdapter{}).catalogPackages(context.TODO(), &src)
+	assert.NoError(t, err)
+	var got []string
+	for _, p := range catalog.Sorted() {
+		assert.Equal(t, pkg.DotnetPkg, p.Type)
+		assert.Equal(t, pkg.Dotnet, p.Language)
+		got = append(got, p.PURL)
+	}
+	assert.Equal(t, []string{
+		"pkg:nuget/Newtonsoft.Json@13.0.1",
+		"pkg:nuget/Newtonsoft.Json@9.0.1",
+		"pkg:nuget/System.Text.Encodings.Web@4.5.0",
+	}, got)
+	// each package is contained by the source
+	assert.Len(t, relationships, 3)
+}
+
+func Test_dotnetTargetFrameworks(t *testing.T) {
+	src, err := source.NewFromDirectory("testdata/dotnet")
+	assert.NoError(t, err)
+	resolver, err := src.FileResolver(source.SquashedScope)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".NETCoreApp,Version=v6.0", "net472"}, dotnetTargetFrameworks(resolver))
+}
@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/armosec/utils-go/httputils"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// defaultElasticsearchIndex is the index of the findings indexed into Elasticsearch when none is configured
+const defaultElasticsearchIndex = "kubevuln-vulnerabilities"
+
+// ElasticsearchAdapter implements ResultSink from ports by indexing the routed findings into Elasticsearch or
+// OpenSearch, one document per finding, with the bulk API
+type ElasticsearchAdapter struct {
+	batchSize    int
+	httpPostFunc func(httputils.IHttpClient, string, map[string]string, []byte) (*http.Response, error)
+	index        string
+	now          func() time.Time
+	token        string
+	url          string
+}
+
+var _ ports.ResultSink = (*ElasticsearchAdapter)(nil)
+
+// bulkResponse is the part of the bulk API responses telling which documents failed
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// NewElasticsearchAdapter initializes the ElasticsearchAdapter posting to the cluster url, such as
+// "https://elasticsearch.example.com:9200", with the API key token if any; index is the index of the documents, see
+// indexName, "kubevuln-vulnerabilities" when empty; batchSize is the number of documents per request, 500 when zero
+func NewElasticsearchAdapter(url, token, index string, batchSize int) *ElasticsearchAdapter {
+	if index == "" {
+		index = defaultElasticsearchIndex
+	}
+	if batchSize <= 0 {
+		batchSize = defaultSinkBatchSize
+	}
+	return &ElasticsearchAdapter{
+		batchSize:    batchSize,
+		httpPostFunc: httputils.HttpPost,
+		index:        index,
+		now:          time.Now,
+		token:        token,
+		url:          strings.TrimSuffix(url, "/") + "/_bulk",
+	}
+}
+
+// SendResults indexes the findings of a scan, in batches; the batches with rejected documents fail
+func (e *ElasticsearchAdapter) SendResults(ctx context.Context, report domain.ScanReport, findings []domain.Finding) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ElasticsearchAdapter.SendResults")
+	defer span.End()
+	now := e.now()
+	action, err := json.Marshal(map[string]map[string]string{"index": {"_index": indexName(e.index, now)}})
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{"Content-Type": "application/x-ndjson"}
+	if e.token != "" {
+		headers["Authorization"] = "ApiKey " + e.token
+	}
+	for _, batch := range batches(findingEvents(report, findings, now), e.batchSize) {
+		var payload bytes.Buffer
+		for _, event := range batch {
+			document, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			payload.Write(action)
+			payload.WriteByte('\n')
+			payload.Write(document)
+			payload.WriteByte('\n')
+		}
+		resp, err := e.httpPostFunc(clientWithContext(ctx), e.url, headers, payload.Bytes())
+		if err != nil {
+			return err
+		}
+		body, err := httputils.HttpRespToString(resp)
+		if err != nil {
+			return fmt.Errorf("indexing the findings into Elasticsearch failed: %w, body: %s", err, body)
+		}
+		if err := bulkError(body); err != nil {
+			return err
+		}
+	}
+	logger.L().Debug("indexed scan results into Elasticsearch",
+		helpers.String("scanID", report.ScanID),
+		helpers.Int("findings", len(findings)))
+	return nil
+}
+
+// bulkError returns the first rejected document of a bulk API response, if any
+func bulkError(body string) error {
+	var resp bulkResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		return fmt.Errorf("failed to decode the Elasticsearch bulk response: %w", err)
+	}
+	if !resp.Errors {
+		return nil
+	}
+	failed := 0
+	var first string
+	for _, item := range resp.Items {
+		for _, result := range item {
+			if result.Status < 300 {
+				continue
+			}
+			if failed == 0 {
+				first = result.Error.Type + ": " + result.Error.Reason
+			}
+			failed++
+		}
+	}
+	return fmt.Errorf("the Elasticsearch bulk request rejected %d findings, first: %s", failed, first)
+}
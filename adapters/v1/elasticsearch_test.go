@@ -0,0 +1,53 @@
+package v1
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElasticsearchAdapter_SendResults(t *testing.T) {
+	var bodies []string
+	var gotPath, gotAuthorization, gotContentType string
+	response := `{"errors":false,"items":[{"index":{"status":201}}]}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuthorization = r.Header.Get("Authorization")
+		gotContentType = r.Header.Get("Content-Type")
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		_, _ = w.Write([]byte(response))
+	}))
+	defer ts.Close()
+	e := NewElasticsearchAdapter(ts.URL+"/", "api-key", "", 0)
+	e.now = func() time.Time { return time.Date(2023, 5, 31, 12, 0, 0, 0, time.UTC) }
+	report := domain.ScanReport{ScanID: "scanID", Verdict: domain.VerdictFail}
+	findings := []domain.Finding{
+		{ID: "CVE-2022-37434", Package: "zlib", Version: "1.2.12", Severity: "Critical"},
+		{ID: "CVE-2023-0286", Package: "openssl", Version: "3.0.7", Severity: "High"},
+	}
+
+	require.NoError(t, e.SendResults(context.TODO(), report, findings))
+	assert.Equal(t, "/_bulk", gotPath)
+	assert.Equal(t, "ApiKey api-key", gotAuthorization)
+	assert.Equal(t, "application/x-ndjson", gotContentType)
+	require.Len(t, bodies, 1)
+	lines := strings.Split(strings.TrimSuffix(bodies[0], "\n"), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, `{"index":{"_index":"kubevuln-vulnerabilities"}}`, lines[0])
+	assert.Contains(t, lines[1], `"vulnerability":"CVE-2022-37434"`)
+	assert.Contains(t, lines[3], `"vulnerability":"CVE-2023-0286"`)
+
+	response = `{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [version]"}}}]}`
+	err := e.SendResults(context.TODO(), report, findings)
+	assert.ErrorContains(t, err, "rejected 1 findings")
+	assert.ErrorContains(t, err, "mapper_parsing_exception")
+}
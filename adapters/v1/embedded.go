@@ -0,0 +1,294 @@
+package v1
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/source"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+const (
+	// maxEmbeddedArchives bounds the archives read for embedded images in a single image
+	maxEmbeddedArchives = 20
+	// maxArchiveIndexSize bounds the size of the manifest.json and index.json files of an archive
+	maxArchiveIndexSize = 1 << 20
+)
+
+// WithEmbeddedImages scans the images embedded in the scanned images as archives, and the images embedded in those,
+// up to depth levels; the embedded images are only listed when zero
+func WithEmbeddedImages(depth int) SyftAdapterOption {
+	return func(s *SyftAdapter) {
+		s.embeddedImagesDepth = depth
+	}
+}
+
+// embeddedArchive is an image archive found in a filesystem, with the images it holds
+type embeddedArchive struct {
+	location source.Location
+	images   []domain.EmbeddedImage
+	// repoTags are the tags of each image of a docker archive, used to select the images of multi-image archives
+	repoTags [][]string
+}
+
+// embeddedImages lists the images embedded as docker or OCI archives in the filesystem of the resolver, and returns
+// the packages of the docker archives read up to depth levels, located under the archive paths
+func (s *SyftAdapter) embeddedImages(ctx context.Context, resolver source.FileResolver, depth int) ([]domain.EmbeddedImage, []pkg.Package) {
+	locations, err := resolver.FilesByGlob("**/*.tar")
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to search for embedded images", helpers.Error(err))
+		return nil, nil
+	}
+	var archives []embeddedArchive
+	for _, location := range locations {
+		if len(archives) == maxEmbeddedArchives {
+			logger.L().Ctx(ctx).Warning("too many embedded images, skipping the next archives",
+				helpers.Int("maxEmbeddedArchives", maxEmbeddedArchives))
+			break
+		}
+		rc, err := resolver.FileContentsByLocation(location)
+		if err != nil {
+			continue
+		}
+		archive, ok := readImageArchive(rc, location)
+		_ = rc.Close()
+		if ok {
+			archives = append(archives, archive)
+		}
+	}
+	var images []domain.EmbeddedImage
+	var packages []pkg.Package
+	for _, archive := range archives {
+		if depth <= 0 || archive.images[0].Format != domain.EmbeddedFormatDocker {
+			images = append(images, archive.images...)
+			continue
+		}
+		nested, archivePackages := s.scanArchive(ctx, resolver, archive, depth)
+		images = append(images, archive.images...)
+		images = append(images, nested...)
+		packages = append(packages, archivePackages...)
+	}
+	return images, packages
+}
+
+// readImageArchive tells whether a tar file is a docker archive, saved by docker save or ctr export, or an OCI
+// archive, and lists its images
+func readImageArchive(r io.Reader, location source.Location) (embeddedArchive, bool) {
+	archive := embeddedArchive{location: location}
+	var manifest, index []byte
+	var ociLayout bool
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			// a truncated or non tar file holds no image
+			if !errors.Is(err, io.EOF) {
+				return archive, false
+			}
+			break
+		}
+		switch path.Clean(strings.TrimPrefix(header.Name, "./")) {
+		case "manifest.json":
+			manifest, err = io.ReadAll(io.LimitReader(tr, maxArchiveIndexSize))
+		case "index.json":
+			index, err = io.ReadAll(io.LimitReader(tr, maxArchiveIndexSize))
+		case "oci-layout":
+			ociLayout = true
+		}
+		if err != nil {
+			return archive, false
+		}
+	}
+	if manifest != nil {
+		var entries []struct {
+			Config   string   `json:"Config"`
+			RepoTags []string `json:"RepoTags"`
+		}
+		if err := json.Unmarshal(manifest, &entries); err != nil || len(entries) == 0 {
+			return archive, false
+		}
+		for _, entry := range entries {
+			image := configDigest(entry.Config)
+			if len(entry.RepoTags) > 0 {
+				image = entry.RepoTags[0]
+			}
+			archive.images = append(archive.images, domain.EmbeddedImage{
+				Path:   location.RealPath,
+				Image:  image,
+				Format: domain.EmbeddedFormatDocker,
+			})
+			archive.repoTags = append(archive.repoTags, entry.RepoTags)
+		}
+		return archive, true
+	}
+	if index != nil && ociLayout {
+		var entries struct {
+			Manifests []struct {
+				Digest      string            `json:"digest"`
+				Annotations map[string]string `json:"annotations"`
+			} `json:"manifests"`
+		}
+		if err := json.Unmarshal(index, &entries); err != nil || len(entries.Manifests) == 0 {
+			return archive, false
+		}
+		for _, entry := range entries.Manifests {
+			image := entry.Digest
+			for _, key := range []string{"io.containerd.image.name", "org.opencontainers.image.ref.name"} {
+				if entry.Annotations[key] != "" {
+					image = entry.Annotations[key]
+					break
+				}
+			}
+			archive.images = append(archive.images, domain.EmbeddedImage{
+				Path:   location.RealPath,
+				Image:  image,
+				Format: domain.EmbeddedFormatOCI,
+			})
+		}
+		return archive, true
+	}
+	return archive, false
+}
+
+// configDigest returns the digest of the image of a docker archive from the path of its config file, either
+// <hex>.json or blobs/sha256/<hex>
+func configDigest(config string) string {
+	if strings.HasPrefix(config, "blobs/") {
+		return strings.Replace(strings.TrimPrefix(config, "blobs/"), "/", ":", 1)
+	}
+	return "sha256:" + strings.TrimSuffix(config, ".json")
+}
+
+// scanArchive catalogs the images of a docker archive, and the images they embed, marking the scanned images; the
+// multi-image archives can only be read by tag, their untagged images are skipped
+func (s *SyftAdapter) scanArchive(ctx context.Context, resolver source.FileResolver, archive embeddedArchive, depth int) ([]domain.EmbeddedImage, []pkg.Package) {
+	archivePath := archive.location.RealPath
+	t, err := newWorkspace(s.workDir)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to create the workspace of an embedded image", helpers.Error(err))
+		return nil, nil
+	}
+	defer func() { _ = t.Cleanup() }()
+	file, err := copyArchive(t, resolver, archive.location, s.maxImageSize)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to copy an embedded image archive", helpers.Error(err),
+			helpers.String("path", archivePath))
+		return nil, nil
+	}
+	var nested []domain.EmbeddedImage
+	var packages []pkg.Package
+	for i := range archive.images {
+		var tag *name.Tag
+		if len(archive.images) > 1 {
+			if len(archive.repoTags[i]) == 0 {
+				continue
+			}
+			parsed, err := name.NewTag(archive.repoTags[i][0])
+			if err != nil {
+				continue
+			}
+			tag = &parsed
+		}
+		img, err := tarball.ImageFromPath(file, tag)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to read an embedded image", helpers.Error(err),
+				helpers.String("path", archivePath))
+			continue
+		}
+		imageName := archive.images[i].Image
+		src, err := newFromImage(t, &source.Input{UserInput: imageName, Scheme: source.ImageScheme, Location: imageName, Name: imageName}, img, nil, s.maxImageSize)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to read an embedded image", helpers.Error(err),
+				helpers.String("path", archivePath))
+			continue
+		}
+		catalog, _, _, childResolver, _, err := s.catalogPackages(ctx, &src)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to catalog an embedded image", helpers.Error(err),
+				helpers.String("path", archivePath))
+			continue
+		}
+		imagePackages := catalog.Sorted()
+		childImages, childPackages := s.embeddedImages(ctx, childResolver, depth-1)
+		imagePackages = append(imagePackages, childPackages...)
+		for _, child := range childImages {
+			if child.Parent == "" {
+				child.Parent = archivePath
+			} else {
+				child.Parent = archivePath + ":" + child.Parent
+			}
+			child.Path = archivePath + ":" + child.Path
+			nested = append(nested, child)
+		}
+		for _, p := range imagePackages {
+			packages = append(packages, embeddedPackage(p, archive.location))
+		}
+		archive.images[i].Scanned = true
+		archive.images[i].Packages = len(imagePackages)
+	}
+	return nested, packages
+}
+
+// copyArchive copies an archive of the filesystem into the workspace, within the size limit of the images
+func copyArchive(t *workspace, resolver source.FileResolver, location source.Location, maxSize int64) (string, error) {
+	dir, err := t.NewDirectory("embedded-image")
+	if err != nil {
+		return "", err
+	}
+	rc, err := resolver.FileContentsByLocation(location)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	file := filepath.Join(dir, "image.tar")
+	f, err := os.Create(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var r io.Reader = rc
+	if maxSize > 0 {
+		r = io.LimitReader(rc, maxSize+1)
+	}
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return "", err
+	}
+	if maxSize > 0 && n > maxSize {
+		return "", ErrImageTooLarge
+	}
+	return file, nil
+}
+
+// embeddedPackage moves the locations of a package of an embedded image under the path of its archive, in the layer
+// holding the archive, as Syft does for nested Java archives
+func embeddedPackage(p pkg.Package, archive source.Location) pkg.Package {
+	var locations []source.Location
+	for _, location := range p.Locations.ToSlice() {
+		embedded := source.Location{
+			Coordinates: source.Coordinates{
+				RealPath:     archive.RealPath + ":" + location.RealPath,
+				FileSystemID: archive.FileSystemID,
+			},
+		}
+		if location.VirtualPath != "" {
+			embedded.VirtualPath = archive.RealPath + ":" + location.VirtualPath
+		}
+		locations = append(locations, embedded)
+	}
+	p.Locations = source.NewLocationSet(locations...)
+	p.SetID()
+	return p
+}
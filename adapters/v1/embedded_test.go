@@ -0,0 +1,158 @@
+package v1
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anchore/syft/syft/source"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readImageArchive(t *testing.T) {
+	archive := func(files map[string]string) io.Reader {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for file, content := range files {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: file, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+			_, err := tw.Write([]byte(content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+		return &buf
+	}
+	location := source.NewLocation("/kind/images/images.tar")
+	tests := []struct {
+		name       string
+		r          io.Reader
+		wantImages []domain.EmbeddedImage
+		wantOK     bool
+	}{
+		{
+			name: "docker archive",
+			r: archive(map[string]string{
+				"manifest.json": `[{"Config":"abc.json","RepoTags":["kindest/kindnetd:v20230511"]},{"Config":"blobs/sha256/def"}]`,
+			}),
+			wantImages: []domain.EmbeddedImage{
+				{Path: "/kind/images/images.tar", Image: "kindest/kindnetd:v20230511", Format: domain.EmbeddedFormatDocker},
+				{Path: "/kind/images/images.tar", Image: "sha256:def", Format: domain.EmbeddedFormatDocker},
+			},
+			wantOK: true,
+		},
+		{
+			name: "oci archive",
+			r: archive(map[string]string{
+				"oci-layout": `{"imageLayoutVersion":"1.0.0"}`,
+				"index.json": `{"manifests":[{"digest":"sha256:abc","annotations":{"io.containerd.image.name":"registry.k8s.io/pause:3.7"}},{"digest":"sha256:def"}]}`,
+			}),
+			wantImages: []domain.EmbeddedImage{
+				{Path: "/kind/images/images.tar", Image: "registry.k8s.io/pause:3.7", Format: domain.EmbeddedFormatOCI},
+				{Path: "/kind/images/images.tar", Image: "sha256:def", Format: domain.EmbeddedFormatOCI},
+			},
+			wantOK: true,
+		},
+		{
+			name: "other archive",
+			r:    archive(map[string]string{"index.json": `{"manifests":[{"digest":"sha256:abc"}]}`}),
+		},
+		{
+			name: "not an archive",
+			r:    strings.NewReader(strings.Repeat("not a tar file", 100)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := readImageArchive(tt.r, location)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantImages, got.images)
+		})
+	}
+}
+
+func TestSyftAdapter_CreateSBOM_embeddedImages(t *testing.T) {
+	ctx := context.TODO()
+	canary, options, err := NewCanaryAdapter("").CanaryImage(ctx)
+	require.NoError(t, err)
+	child, err := canaryImage()
+	require.NoError(t, err)
+	tag, err := name.NewTag("kindest/canary:v1")
+	require.NoError(t, err)
+	var archive bytes.Buffer
+	require.NoError(t, tarball.Write(tag, child, &archive))
+	var layer bytes.Buffer
+	tw := tar.NewWriter(&layer)
+	for _, header := range []*tar.Header{
+		{Name: "kind/", Mode: 0755, Typeflag: tar.TypeDir},
+		{Name: "kind/images/", Mode: 0755, Typeflag: tar.TypeDir},
+		{Name: "kind/images/canary.tar", Mode: 0644, Typeflag: tar.TypeReg, Size: int64(archive.Len())},
+	} {
+		require.NoError(t, tw.WriteHeader(header))
+	}
+	_, err = tw.Write(archive.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	archiveLayer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(layer.Bytes())), nil
+	})
+	require.NoError(t, err)
+	parent, err := mutate.AppendLayers(child, archiveLayer)
+	require.NoError(t, err)
+	ref, err := name.ParseReference(strings.SplitN(canary, "/", 2)[0]+"/kind/node:v1", name.Insecure)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, parent))
+
+	tests := []struct {
+		name         string
+		depth        int
+		wantPackages []string
+	}{
+		{
+			name:         "listed",
+			wantPackages: []string{"busybox@1.35.0-r29", "musl@1.2.3-r4"},
+		},
+		{
+			name:         "scanned",
+			depth:        1,
+			wantPackages: []string{"busybox@1.35.0-r29", "busybox@1.35.0-r29", "musl@1.2.3-r4", "musl@1.2.3-r4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sbom, err := NewSyftAdapter(time.Minute, 1<<22, WithEmbeddedImages(tt.depth)).CreateSBOM(ctx, "kind", ref.String(), options)
+			require.NoError(t, err)
+			var embedded []domain.EmbeddedImage
+			require.NoError(t, json.Unmarshal([]byte(sbom.Annotations[domain.AnnotationEmbeddedImages]), &embedded))
+			want := domain.EmbeddedImage{Path: "/kind/images/canary.tar", Image: "kindest/canary:v1", Format: domain.EmbeddedFormatDocker}
+			if tt.depth > 0 {
+				want.Scanned = true
+				want.Packages = 2
+			}
+			assert.Equal(t, []domain.EmbeddedImage{want}, embedded)
+			require.NotNil(t, sbom.Content)
+			var packages []string
+			var embeddedPackages int
+			for _, p := range sbom.Content.Packages {
+				packages = append(packages, p.PackageName+"@"+p.PackageVersion)
+				// the packages of the embedded image are located under its archive
+				if strings.Contains(p.PackageSourceInfo, "/kind/images/canary.tar:/lib/apk/db/installed") {
+					embeddedPackages++
+				}
+			}
+			assert.Equal(t, want.Packages, embeddedPackages)
+			sort.Strings(packages)
+			assert.Equal(t, tt.wantPackages, packages)
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// defaultEnrichmentRefresh is the interval between two downloads of an enrichment URL
+const defaultEnrichmentRefresh = time.Hour
+
+// enrichmentIDColumns are the names of the column of the vulnerability IDs of the CSV enrichment sources
+var enrichmentIDColumns = []string{"id", "cve", "vulnerability"}
+
+// EnrichmentAdapter implements VulnerabilityEnricher from ports by reading the attributes of the vulnerabilities from
+// a CSV or JSON file, read again when modified, or from an HTTP endpoint serving either, downloaded again periodically
+type EnrichmentAdapter struct {
+	source          string
+	refreshInterval time.Duration
+	httpClient      *http.Client
+	now             func() time.Time
+	mu              sync.Mutex
+	attributes      map[string]map[string]string
+	version         time.Time
+}
+
+var _ ports.VulnerabilityEnricher = (*EnrichmentAdapter)(nil)
+
+// NewEnrichmentAdapter initializes the EnrichmentAdapter with a file path or an http(s) URL, refreshInterval is the
+// interval between two downloads of a URL, one hour when zero
+func NewEnrichmentAdapter(source string, refreshInterval time.Duration) *EnrichmentAdapter {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultEnrichmentRefresh
+	}
+	return &EnrichmentAdapter{
+		source:          source,
+		refreshInterval: refreshInterval,
+		httpClient:      &http.Client{Timeout: 30 * time.Second},
+		now:             time.Now,
+	}
+}
+
+// Enrich returns the attributes of the given vulnerability IDs, the IDs without attributes are left out; the last
+// attributes read are used when the source cannot be read again
+func (e *EnrichmentAdapter) Enrich(ctx context.Context, ids []string) (map[string]map[string]string, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "EnrichmentAdapter.Enrich")
+	defer span.End()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.refresh(ctx); err != nil {
+		if e.attributes == nil {
+			return nil, err
+		}
+		logger.L().Ctx(ctx).Warning("failed to refresh the enrichment source, using the last attributes read", helpers.Error(err),
+			helpers.String("source", e.source))
+	}
+	enrichment := map[string]map[string]string{}
+	for _, id := range ids {
+		if attributes, ok := e.attributes[strings.ToUpper(id)]; ok {
+			enrichment[id] = attributes
+		}
+	}
+	return enrichment, nil
+}
+
+// refresh reads the source again if it was modified, or if its refresh interval is over for a URL
+func (e *EnrichmentAdapter) refresh(ctx context.Context) error {
+	if isURL(e.source) {
+		now := e.now()
+		if e.attributes != nil && now.Sub(e.version) < e.refreshInterval {
+			return nil
+		}
+		attributes, err := e.download(ctx)
+		if err != nil {
+			return err
+		}
+		e.attributes, e.version = attributes, now
+		return nil
+	}
+	info, err := os.Stat(e.source)
+	if err != nil {
+		return err
+	}
+	if e.attributes != nil && info.ModTime().Equal(e.version) {
+		return nil
+	}
+	content, err := os.ReadFile(e.source)
+	if err != nil {
+		return err
+	}
+	attributes, err := parseEnrichment(content, filepath.Ext(e.source) == ".csv")
+	if err != nil {
+		return fmt.Errorf("failed to parse enrichment file %s: %w", e.source, err)
+	}
+	e.attributes, e.version = attributes, info.ModTime()
+	return nil
+}
+
+// download reads the attributes served by the enrichment URL, as CSV when its content type or path says so
+func (e *EnrichmentAdapter) download(ctx context.Context) (map[string]map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json, text/csv")
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download enrichment source %s: %s", e.source, resp.Status)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	attributes, err := parseEnrichment(content, mediaType == "text/csv" || strings.HasSuffix(req.URL.Path, ".csv"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse enrichment source %s: %w", e.source, err)
+	}
+	return attributes, nil
+}
+
+// parseEnrichment reads the attributes keyed by upper-cased vulnerability ID, from a CSV with a header row naming
+// the ID column and the attributes, or from a JSON object of attribute objects keyed by ID
+func parseEnrichment(content []byte, isCSV bool) (map[string]map[string]string, error) {
+	attributes := map[string]map[string]string{}
+	if !isCSV {
+		var entries map[string]map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if err := decoder.Decode(&entries); err != nil {
+			return nil, err
+		}
+		for id, entry := range entries {
+			values := make(map[string]string, len(entry))
+			for key, value := range entry {
+				switch value.(type) {
+				case string, json.Number, bool:
+					values[key] = fmt.Sprint(value)
+				default:
+					return nil, fmt.Errorf("attribute %q of %s must be a string, a number or a boolean", key, id)
+				}
+			}
+			attributes[strings.ToUpper(id)] = values
+		}
+		return attributes, nil
+	}
+	records, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return attributes, nil
+	}
+	header := records[0]
+	idColumn := -1
+	for i, name := range header {
+		for _, idName := range enrichmentIDColumns {
+			if strings.EqualFold(strings.TrimSpace(name), idName) {
+				idColumn = i
+			}
+		}
+	}
+	if idColumn < 0 {
+		return nil, errors.New("missing the vulnerability ID column, named " + strings.Join(enrichmentIDColumns, ", "))
+	}
+	for _, record := range records[1:] {
+		id := strings.TrimSpace(record[idColumn])
+		if id == "" {
+			continue
+		}
+		values := map[string]string{}
+		for i, value := range record {
+			if i != idColumn && value != "" {
+				values[strings.TrimSpace(header[i])] = value
+			}
+		}
+		attributes[strings.ToUpper(id)] = values
+	}
+	return attributes, nil
+}
+
+// isURL tells whether the enrichment source is an http(s) URL rather than a file
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
@@ -0,0 +1,111 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseEnrichment(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		isCSV   bool
+		want    map[string]map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "json",
+			content: `{"CVE-2023-0286": {"riskScore": 8.1, "owner": "platform", "exploited": true}, "ghsa-vvpx-j8f3-3w6h": {"owner": "team-a"}}`,
+			want: map[string]map[string]string{
+				"CVE-2023-0286":       {"riskScore": "8.1", "owner": "platform", "exploited": "true"},
+				"GHSA-VVPX-J8F3-3W6H": {"owner": "team-a"},
+			},
+		},
+		{
+			name:    "json nested attribute",
+			content: `{"CVE-2023-0286": {"owners": ["platform"]}}`,
+			wantErr: true,
+		},
+		{
+			name:    "csv",
+			content: "riskScore,CVE,owner\n8.1,CVE-2023-0286,platform\n,cve-2022-32149,team-a\n3,,team-b\n",
+			isCSV:   true,
+			want: map[string]map[string]string{
+				"CVE-2023-0286":  {"riskScore": "8.1", "owner": "platform"},
+				"CVE-2022-32149": {"owner": "team-a"},
+			},
+		},
+		{
+			name:    "csv without ID column",
+			content: "riskScore,owner\n8.1,platform\n",
+			isCSV:   true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEnrichment([]byte(tt.content), tt.isCSV)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEnrichmentAdapter_Enrich_file(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "risk.csv")
+	require.NoError(t, os.WriteFile(path, []byte("cve,riskScore\nCVE-2023-0286,8.1\n"), 0600))
+	e := NewEnrichmentAdapter(path, 0)
+	got, err := e.Enrich(context.TODO(), []string{"CVE-2023-0286", "CVE-2022-32149"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"CVE-2023-0286": {"riskScore": "8.1"}}, got)
+	// the file is read again once modified
+	require.NoError(t, os.WriteFile(path, []byte("cve,riskScore\nCVE-2023-0286,9.8\n"), 0600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	got, err = e.Enrich(context.TODO(), []string{"CVE-2023-0286"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"CVE-2023-0286": {"riskScore": "9.8"}}, got)
+	// the last attributes read are kept when the file disappears
+	require.NoError(t, os.Remove(path))
+	got, err = e.Enrich(context.TODO(), []string{"CVE-2023-0286"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"CVE-2023-0286": {"riskScore": "9.8"}}, got)
+
+	_, err = NewEnrichmentAdapter(path, 0).Enrich(context.TODO(), []string{"CVE-2023-0286"})
+	assert.Error(t, err)
+}
+
+func TestEnrichmentAdapter_Enrich_url(t *testing.T) {
+	downloads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		downloads++
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		_, _ = w.Write([]byte("id,owner\nCVE-2023-0286,platform\n"))
+	}))
+	defer srv.Close()
+	now := time.Now()
+	e := NewEnrichmentAdapter(srv.URL+"/risk", time.Hour)
+	e.now = func() time.Time { return now }
+	for i := 0; i < 2; i++ {
+		got, err := e.Enrich(context.TODO(), []string{"CVE-2023-0286"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]map[string]string{"CVE-2023-0286": {"owner": "platform"}}, got)
+	}
+	assert.Equal(t, 1, downloads)
+	// downloaded again once the refresh interval is over
+	now = now.Add(time.Hour)
+	_, err := e.Enrich(context.TODO(), []string{"CVE-2023-0286"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, downloads)
+}
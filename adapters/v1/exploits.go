@@ -0,0 +1,245 @@
+package v1
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	// ExploitDBFile is the index of the exploits of ExploitDB, in its repository and in the offline bundles
+	ExploitDBFile = "files_exploits.csv"
+	// MetasploitFile is the metadata of the modules of Metasploit, in its repository and in the offline bundles
+	MetasploitFile = "modules_metadata_base.json"
+	// defaultExploitRefresh is the interval between two downloads of the exploit sources
+	defaultExploitRefresh = 24 * time.Hour
+)
+
+// ExploitAdapter implements ExploitProvider from ports by mapping the CVEs to the exploits of ExploitDB and the modules
+// of Metasploit, downloaded periodically, or read from an offline bundle directory, read again when modified, for
+// air-gapped sites
+type ExploitAdapter struct {
+	exploitDBURL    string
+	metasploitURL   string
+	bundle          string
+	refreshInterval time.Duration
+	httpClient      *http.Client
+	now             func() time.Time
+	mu              sync.Mutex
+	exploits        map[string][]string
+	version         time.Time
+}
+
+var _ ports.ExploitProvider = (*ExploitAdapter)(nil)
+
+// NewExploitAdapter initializes the ExploitAdapter with the URLs of the ExploitDB index and of the Metasploit modules
+// metadata, an empty URL skipping its source, or with the bundle directory holding ExploitDBFile and MetasploitFile,
+// used instead of the URLs when set; refreshInterval is the interval between two downloads, one day when zero
+func NewExploitAdapter(exploitDBURL, metasploitURL, bundle string, refreshInterval time.Duration) *ExploitAdapter {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultExploitRefresh
+	}
+	return &ExploitAdapter{
+		exploitDBURL:    exploitDBURL,
+		metasploitURL:   metasploitURL,
+		bundle:          bundle,
+		refreshInterval: refreshInterval,
+		httpClient:      &http.Client{Timeout: 5 * time.Minute},
+		now:             time.Now,
+	}
+}
+
+// Exploits returns the sources of the public exploits of the given vulnerability IDs, the IDs without exploits are
+// left out; the last mapping read is used when the sources cannot be read again
+func (e *ExploitAdapter) Exploits(ctx context.Context, ids []string) (map[string][]string, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ExploitAdapter.Exploits")
+	defer span.End()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.refresh(ctx); err != nil {
+		if e.exploits == nil {
+			return nil, err
+		}
+		logger.L().Ctx(ctx).Warning("failed to refresh the exploit sources, using the last mapping read", helpers.Error(err))
+	}
+	exploits := map[string][]string{}
+	for _, id := range ids {
+		if sources, ok := e.exploits[strings.ToUpper(id)]; ok {
+			exploits[id] = sources
+		}
+	}
+	return exploits, nil
+}
+
+// refresh reads the bundle again if one of its files was modified, or downloads the sources again once the refresh
+// interval is over
+func (e *ExploitAdapter) refresh(ctx context.Context) error {
+	if e.bundle == "" {
+		now := e.now()
+		if e.exploits != nil && now.Sub(e.version) < e.refreshInterval {
+			return nil
+		}
+		exploits := map[string][]string{}
+		for _, source := range []struct{ name, url string }{
+			{domain.ExploitSourceExploitDB, e.exploitDBURL},
+			{domain.ExploitSourceMetasploit, e.metasploitURL},
+		} {
+			if source.url == "" {
+				continue
+			}
+			if err := e.download(ctx, source.name, source.url, exploits); err != nil {
+				return err
+			}
+		}
+		e.exploits, e.version = exploits, now
+		logger.L().Info("exploit sources downloaded", helpers.Int("vulnerabilities", len(exploits)))
+		return nil
+	}
+	var version time.Time
+	var files []string
+	for _, file := range []string{ExploitDBFile, MetasploitFile} {
+		info, err := os.Stat(filepath.Join(e.bundle, file))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		if info.ModTime().After(version) {
+			version = info.ModTime()
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("exploit bundle %s holds neither %s nor %s", e.bundle, ExploitDBFile, MetasploitFile)
+	}
+	if e.exploits != nil && version.Equal(e.version) {
+		return nil
+	}
+	exploits := map[string][]string{}
+	for _, file := range files {
+		f, err := os.Open(filepath.Join(e.bundle, file))
+		if err != nil {
+			return err
+		}
+		err = parseExploits(f, file, exploits)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse exploit bundle file %s: %w", file, err)
+		}
+	}
+	e.exploits, e.version = exploits, version
+	return nil
+}
+
+// download adds the exploits of a source served at url to the mapping
+func (e *ExploitAdapter) download(ctx context.Context, source, url string, exploits map[string][]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download exploit source %s: %s", url, resp.Status)
+	}
+	file := ExploitDBFile
+	if source == domain.ExploitSourceMetasploit {
+		file = MetasploitFile
+	}
+	if err := parseExploits(resp.Body, file, exploits); err != nil {
+		return fmt.Errorf("failed to parse exploit source %s: %w", url, err)
+	}
+	return nil
+}
+
+// parseExploits adds the CVEs of the exploits of a source file to the mapping, the sources of each CVE are kept sorted:
+// the ExploitDB index lists the CVEs of each exploit in its semicolon separated codes column, and the Metasploit
+// metadata lists them in the references of each module
+func parseExploits(r io.Reader, file string, exploits map[string][]string) error {
+	var source string
+	var ids []string
+	switch file {
+	case ExploitDBFile:
+		source = domain.ExploitSourceExploitDB
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		header, err := reader.Read()
+		if err != nil {
+			return err
+		}
+		codesColumn := -1
+		for i, name := range header {
+			if strings.TrimSpace(name) == "codes" {
+				codesColumn = i
+			}
+		}
+		if codesColumn < 0 {
+			return errors.New("missing the codes column")
+		}
+		for {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if codesColumn < len(record) {
+				ids = append(ids, strings.Split(record[codesColumn], ";")...)
+			}
+		}
+	case MetasploitFile:
+		source = domain.ExploitSourceMetasploit
+		var modules map[string]struct {
+			References []string `json:"references"`
+		}
+		if err := json.NewDecoder(r).Decode(&modules); err != nil {
+			return err
+		}
+		for _, module := range modules {
+			ids = append(ids, module.References...)
+		}
+	default:
+		return fmt.Errorf("unknown exploit source file %s", file)
+	}
+	for _, id := range ids {
+		id = strings.ToUpper(strings.TrimSpace(id))
+		if !strings.HasPrefix(id, "CVE-") || containsString(exploits[id], source) {
+			continue
+		}
+		exploits[id] = append(exploits[id], source)
+		sort.Strings(exploits[id])
+	}
+	return nil
+}
+
+// containsString tells whether a slice holds a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testExploitDB = `id,file,description,date_published,author,type,platform,port,date_added,date_updated,verified,codes,tags,aliases,screenshot_url,application_url,source_url
+50592,exploits/java/remote/50592.py,"Apache Log4j2 2.14.1 - Information Disclosure",2021-12-14,leonjza,remote,java,,2021-12-14,2021-12-14,0,CVE-2021-44228,,,,,
+32745,exploits/multiple/remote/32745.py,"OpenSSL TLS Heartbeat Extension - 'Heartbleed' Memory Disclosure",2014-04-08,"Jared Stafford",remote,multiple,443,2014-04-08,2014-04-08,1,CVE-2014-0160;OSVDB-105465,,,,,
+`
+	testMetasploit = `{
+  "exploit_multi/http/log4shell_header_injection": {"name": "Log4Shell HTTP Header Injection", "references": ["CVE-2021-44228", "URL-https://logging.apache.org/log4j/2.x/security.html"]},
+  "auxiliary_scanner/http/log4shell_scanner": {"name": "Log4Shell HTTP Scanner", "references": ["cve-2021-44228"]}
+}`
+)
+
+func Test_parseExploits(t *testing.T) {
+	exploits := map[string][]string{}
+	require.NoError(t, parseExploits(strings.NewReader(testMetasploit), MetasploitFile, exploits))
+	require.NoError(t, parseExploits(strings.NewReader(testExploitDB), ExploitDBFile, exploits))
+	assert.Equal(t, map[string][]string{
+		"CVE-2021-44228": {domain.ExploitSourceExploitDB, domain.ExploitSourceMetasploit},
+		"CVE-2014-0160":  {domain.ExploitSourceExploitDB},
+	}, exploits)
+
+	assert.Error(t, parseExploits(strings.NewReader("id,file\n1,exploits/1.py\n"), ExploitDBFile, exploits))
+	assert.Error(t, parseExploits(strings.NewReader(`["CVE-2021-44228"]`), MetasploitFile, exploits))
+}
+
+func TestExploitAdapter_Exploits_bundle(t *testing.T) {
+	bundle := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bundle, ExploitDBFile), []byte(testExploitDB), 0600))
+	e := NewExploitAdapter("", "", bundle, 0)
+	got, err := e.Exploits(context.TODO(), []string{"CVE-2021-44228", "CVE-2022-32149"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"CVE-2021-44228": {domain.ExploitSourceExploitDB}}, got)
+	// the bundle is read again once a file is added
+	path := filepath.Join(bundle, MetasploitFile)
+	require.NoError(t, os.WriteFile(path, []byte(testMetasploit), 0600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	got, err = e.Exploits(context.TODO(), []string{"CVE-2021-44228"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"CVE-2021-44228": {domain.ExploitSourceExploitDB, domain.ExploitSourceMetasploit}}, got)
+
+	_, err = NewExploitAdapter("", "", t.TempDir(), 0).Exploits(context.TODO(), []string{"CVE-2021-44228"})
+	assert.ErrorContains(t, err, "holds neither")
+}
+
+func TestExploitAdapter_Exploits_url(t *testing.T) {
+	downloads := 0
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		if fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, ".csv") {
+			_, _ = w.Write([]byte(testExploitDB))
+			return
+		}
+		_, _ = w.Write([]byte(testMetasploit))
+	}))
+	defer srv.Close()
+	now := time.Now()
+	e := NewExploitAdapter(srv.URL+"/"+ExploitDBFile, srv.URL+"/"+MetasploitFile, "", time.Hour)
+	e.now = func() time.Time { return now }
+	want := map[string][]string{
+		"CVE-2021-44228": {domain.ExploitSourceExploitDB, domain.ExploitSourceMetasploit},
+		"CVE-2014-0160":  {domain.ExploitSourceExploitDB},
+	}
+	for i := 0; i < 2; i++ {
+		got, err := e.Exploits(context.TODO(), []string{"CVE-2021-44228", "CVE-2014-0160"})
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	assert.Equal(t, 2, downloads)
+	// the last mapping is kept when the sources cannot be downloaded again
+	now = now.Add(time.Hour)
+	fail = true
+	got, err := e.Exploits(context.TODO(), []string{"CVE-2021-44228", "CVE-2014-0160"})
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, 3, downloads)
+}
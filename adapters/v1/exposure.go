@@ -0,0 +1,90 @@
+package v1
+
+import (
+	"context"
+	"strings"
+
+	wlidpkg "github.com/armosec/utils-k8s-go/wlid"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var _ ports.ExposureChecker = (*WorkloadAdapter)(nil)
+
+// IsExposed tells whether the pods of a Deployment, StatefulSet, DaemonSet or Pod are reachable from outside the
+// cluster: through the host network, or selected by a LoadBalancer or NodePort Service, a Service with external IPs
+// or a Service backing an Ingress of the namespace; the other kinds are considered unexposed
+func (w *WorkloadAdapter) IsExposed(ctx context.Context, wlid string) (bool, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "WorkloadAdapter.IsExposed")
+	defer span.End()
+	namespace, name := wlidpkg.GetNamespaceFromWlid(wlid), wlidpkg.GetNameFromWlid(wlid)
+	var template corev1.PodTemplateSpec
+	switch strings.ToLower(wlidpkg.GetKindFromWlid(wlid)) {
+	case "deployment":
+		deployment, err := w.client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		template = deployment.Spec.Template
+	case "statefulset":
+		statefulSet, err := w.client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		template = statefulSet.Spec.Template
+	case "daemonset":
+		daemonSet, err := w.client.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		template = daemonSet.Spec.Template
+	case "pod":
+		pod, err := w.client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		template = corev1.PodTemplateSpec{ObjectMeta: pod.ObjectMeta, Spec: pod.Spec}
+	default:
+		return false, nil
+	}
+	if template.Spec.HostNetwork {
+		return true, nil
+	}
+	services, err := w.client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	ingresses, err := w.client.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	ingressBackends := map[string]bool{}
+	for _, ingress := range ingresses.Items {
+		if backend := ingress.Spec.DefaultBackend; backend != nil && backend.Service != nil {
+			ingressBackends[backend.Service.Name] = true
+		}
+		for _, rule := range ingress.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				if path.Backend.Service != nil {
+					ingressBackends[path.Backend.Service.Name] = true
+				}
+			}
+		}
+	}
+	for _, service := range services.Items {
+		if len(service.Spec.Selector) == 0 || !labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(template.Labels)) {
+			continue
+		}
+		if service.Spec.Type == corev1.ServiceTypeLoadBalancer || service.Spec.Type == corev1.ServiceTypeNodePort ||
+			len(service.Spec.ExternalIPs) > 0 || ingressBackends[service.Name] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newDeployment(name string, hostNetwork bool) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+			Spec:       corev1.PodSpec{HostNetwork: hostNetwork},
+		}},
+	}
+}
+
+func newService(name, app string, serviceType corev1.ServiceType) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": app}, Type: serviceType},
+	}
+}
+
+func TestWorkloadAdapter_IsExposed(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newDeployment("internal", false),
+		newDeployment("node-exporter", true),
+		newDeployment("frontend", false),
+		newDeployment("web", false),
+		newService("internal", "internal", corev1.ServiceTypeClusterIP),
+		newService("frontend", "frontend", corev1.ServiceTypeLoadBalancer),
+		newService("web", "web", corev1.ServiceTypeClusterIP),
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "web"}}}},
+				}},
+			}}},
+		},
+	)
+	w := NewWorkloadAdapter(client)
+	tests := []struct {
+		wlid    string
+		want    bool
+		wantErr bool
+	}{
+		{wlid: "wlid://cluster-minikube/namespace-default/deployment-internal"},
+		{wlid: "wlid://cluster-minikube/namespace-default/deployment-node-exporter", want: true},
+		{wlid: "wlid://cluster-minikube/namespace-default/deployment-frontend", want: true},
+		{wlid: "wlid://cluster-minikube/namespace-default/deployment-web", want: true},
+		{wlid: "wlid://cluster-minikube/namespace-default/cronjob-backup"},
+		{wlid: "wlid://cluster-minikube/namespace-default/deployment-missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wlid, func(t *testing.T) {
+			got, err := w.IsExposed(context.TODO(), tt.wlid)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package v1
+
+import (
+	"strings"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/anchore/syft/syft/cpe"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/source"
+)
+
+// goStdlibPackage is the name of the synthetic package of the Go standard library compiled into the Go binaries
+const goStdlibPackage = "stdlib"
+
+// addGoStdlibPackages adds a stdlib package to the catalog for each Go binary, versioned after the Go release it was
+// compiled with; Syft does not catalog the standard library, whose vulnerabilities such as the net/http ones are
+// matched by the golang:go CPE
+func addGoStdlibPackages(catalog *pkg.Catalog) {
+	stdlibs := map[string]pkg.Package{}
+	for p := range catalog.Enumerate(pkg.GoModulePkg) {
+		metadata, ok := p.Metadata.(pkg.GolangBinMetadata)
+		if !ok {
+			continue
+		}
+		version := goStdlibVersion(metadata.GoCompiledVersion)
+		if version == "" {
+			continue
+		}
+		// the modules of a binary share its location, each binary gets one stdlib package
+		for _, location := range p.Locations.ToSlice() {
+			key := location.RealPath + "@" + version
+			if _, ok := stdlibs[key]; !ok {
+				stdlibs[key] = newGoStdlibPackage(version, metadata, location)
+			}
+		}
+	}
+	for _, stdlib := range stdlibs {
+		catalog.Add(stdlib)
+	}
+}
+
+// goStdlibVersion returns the version of the Go release of a binary, such as 1.20.3 for go1.20.3 or
+// go1.20.3 X:boringcrypto, empty for the development builds
+func goStdlibVersion(goCompiledVersion string) string {
+	fields := strings.Fields(goCompiledVersion)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "go1") {
+		return ""
+	}
+	return strings.TrimPrefix(fields[0], "go")
+}
+
+func newGoStdlibPackage(version string, binary pkg.GolangBinMetadata, location source.Location) pkg.Package {
+	p := pkg.Package{
+		Name:         goStdlibPackage,
+		Version:      version,
+		Locations:    source.NewLocationSet(location),
+		PURL:         packageurl.NewPackageURL(packageurl.TypeGolang, "", goStdlibPackage, version, nil, "").ToString(),
+		CPEs:         []cpe.CPE{cpe.Must("cpe:2.3:a:golang:go:" + version + ":*:*:*:*:*:*:*")},
+		Language:     pkg.Go,
+		Type:         pkg.GoModulePkg,
+		MetadataType: pkg.GolangBinMetadataType,
+		Metadata: pkg.GolangBinMetadata{
+			GoCompiledVersion: binary.GoCompiledVersion,
+			Architecture:      binary.Architecture,
+		},
+	}
+	p.SetID()
+	return p
+}
@@ -0,0 +1,87 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/anchore/syft/syft/cpe"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_goStdlibVersion(t *testing.T) {
+	tests := []struct {
+		goCompiledVersion string
+		want              string
+	}{
+		{"go1.20.3", "1.20.3"},
+		{"go1.20.3 X:boringcrypto", "1.20.3"},
+		{"go1.21rc2", "1.21rc2"},
+		{"devel go1.22-8bba868de9 Tue Aug 8 20:00:00 2023 +0000", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, goStdlibVersion(tt.goCompiledVersion), tt.goCompiledVersion)
+	}
+}
+
+func Test_addGoStdlibPackages(t *testing.T) {
+	goModule := func(name, binary, goCompiledVersion string) pkg.Package {
+		p := pkg.Package{
+			Name:         name,
+			Version:      "v1.0.0",
+			Locations:    source.NewLocationSet(source.NewLocation(binary)),
+			Language:     pkg.Go,
+			Type:         pkg.GoModulePkg,
+			MetadataType: pkg.GolangBinMetadataType,
+			Metadata:     pkg.GolangBinMetadata{GoCompiledVersion: goCompiledVersion, Architecture: "amd64"},
+		}
+		p.SetID()
+		return p
+	}
+	catalog := pkg.NewCatalog(
+		goModule("github.com/spf13/cobra", "/usr/bin/app", "go1.20.3"),
+		goModule("golang.org/x/net", "/usr/bin/app", "go1.20.3"),
+		goModule("golang.org/x/net", "/usr/bin/tool", "go1.19.8 X:boringcrypto"),
+		goModule("golang.org/x/net", "/usr/bin/devel", "devel go1.22-8bba868de9"),
+	)
+	addGoStdlibPackages(catalog)
+	var stdlibs []pkg.Package
+	for _, p := range catalog.Sorted() {
+		if p.Name == goStdlibPackage {
+			stdlibs = append(stdlibs, p)
+		}
+	}
+	// one per binary compiled with a Go release
+	require.Len(t, stdlibs, 2)
+	assert.Equal(t, "1.19.8", stdlibs[0].Version)
+	assert.Equal(t, "/usr/bin/tool", stdlibs[0].Locations.ToSlice()[0].RealPath)
+	assert.Equal(t, "1.20.3", stdlibs[1].Version)
+	assert.Equal(t, "/usr/bin/app", stdlibs[1].Locations.ToSlice()[0].RealPath)
+	assert.Equal(t, "pkg:golang/stdlib@1.20.3", stdlibs[1].PURL)
+	assert.Equal(t, []cpe.CPE{cpe.Must("cpe:2.3:a:golang:go:1.20.3:*:*:*:*:*:*:*")}, stdlibs[1].CPEs)
+
+	// the stdlib packages are matched from the stored SBOMs, which keep their type and CPE
+	doc, err := (&SyftAdapter{}).syftToDomain(sbom.SBOM{Artifacts: sbom.Artifacts{PackageCatalog: catalog}})
+	require.NoError(t, err)
+	s, err := domainToSyft(*doc)
+	require.NoError(t, err)
+	var found bool
+	for p := range s.Artifacts.PackageCatalog.Enumerate(pkg.GoModulePkg) {
+		if p.Name == goStdlibPackage && p.Version == "1.20.3" {
+			found = true
+			assert.Equal(t, []string{"cpe:2.3:a:golang:go:1.20.3:*:*:*:*:*:*:*"}, cpeStrings(p.CPEs))
+		}
+	}
+	assert.True(t, found)
+}
+
+func cpeStrings(cpes []cpe.CPE) []string {
+	var strs []string
+	for _, c := range cpes {
+		strs = append(strs, cpe.String(c))
+	}
+	return strs
+}
@@ -0,0 +1,38 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// ParseGoldenBases reads the JSON catalog of the golden base images, each approved at a single digest
+func ParseGoldenBases(path string) (domain.GoldenBases, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return domain.GoldenBases{}, err
+	}
+	var catalog domain.GoldenBases
+	if err := json.Unmarshal(content, &catalog); err != nil {
+		return domain.GoldenBases{}, fmt.Errorf("failed to parse golden bases file %s: %w", path, err)
+	}
+	if catalog.Version == "" {
+		return domain.GoldenBases{}, fmt.Errorf("golden bases file %s has no version", path)
+	}
+	for i, base := range catalog.Bases {
+		if strings.Contains(base.Name, "@") {
+			return domain.GoldenBases{}, fmt.Errorf("invalid golden base %d of %s: expected a repository and tag, got %q", i, path, base.Name)
+		}
+		if _, err := name.NewTag(base.Name); err != nil {
+			return domain.GoldenBases{}, fmt.Errorf("invalid golden base %d of %s: %w", i, path, err)
+		}
+		if !digestPattern.MatchString(base.Digest) {
+			return domain.GoldenBases{}, fmt.Errorf("invalid digest of golden base %d of %s: expected sha256:<hex>, got %q", i, path, base.Digest)
+		}
+	}
+	return catalog, nil
+}
@@ -0,0 +1,66 @@
+package v1
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/anchore/syft/syft/source"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGoldenBases(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    domain.GoldenBases
+		wantErr string
+	}{
+		{
+			name:    "valid catalog",
+			content: `{"version":"2023-06","bases":[{"name":"registry.corp/golden/debian:12","digest":"` + trustedDigest + `"}]}`,
+			want:    domain.GoldenBases{Version: "2023-06", Bases: []domain.GoldenBase{{Name: "registry.corp/golden/debian:12", Digest: trustedDigest}}},
+		},
+		{
+			name:    "missing version",
+			content: `{"bases":[]}`,
+			wantErr: "has no version",
+		},
+		{
+			name:    "digest in the name",
+			content: `{"version":"2023-06","bases":[{"name":"debian@` + trustedDigest + `","digest":"` + trustedDigest + `"}]}`,
+			wantErr: "expected a repository and tag",
+		},
+		{
+			name:    "invalid digest",
+			content: `{"version":"2023-06","bases":[{"name":"debian:12","digest":"latest"}]}`,
+			wantErr: "expected sha256:<hex>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "golden-bases.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0600))
+			got, err := ParseGoldenBases(path)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_imageProvenance_baseImage(t *testing.T) {
+	src := source.Source{Image: &image.Image{Metadata: image.Metadata{
+		RawManifest: []byte(`{"schemaVersion":2,"annotations":{"org.opencontainers.image.base.name":"docker.io/library/debian:12","org.opencontainers.image.base.digest":"` + trustedDigest + `","com.example.team":"a"}}`),
+	}}}
+	assert.Equal(t, map[string]string{
+		domain.OCIBaseName:   "docker.io/library/debian:12",
+		domain.OCIBaseDigest: trustedDigest,
+	}, imageProvenance(src))
+}
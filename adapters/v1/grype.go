@@ -31,25 +31,56 @@ import (
 
 // GrypeAdapter implements CVEScanner from ports using Grype's API
 type GrypeAdapter struct {
-	mu           sync.RWMutex
-	dbCloser     *db.Closer
-	dbStatus     *db.Status
-	store        *store.Store
-	dbConfig     db.Config
-	lastDbUpdate time.Time
+	mu            sync.RWMutex
+	dbCloser      *db.Closer
+	dbStatus      *db.Status
+	store         *store.Store
+	dbConfig      db.Config
+	dbURLRewrites map[string]string
+	lastDbUpdate  time.Time
+	loadMu        sync.Mutex
+	loading       *dbLoad
+	osv           *OSVAdapter
+	overrides     []PackageOverride
+}
+
+// dbLoad is an update of the vulnerabilities DB running in the background, err is set once done is closed
+type dbLoad struct {
+	done chan struct{}
+	err  error
 }
 
 var _ ports.CVEScanner = (*GrypeAdapter)(nil)
 
+// GrypeAdapterOption configures optional behaviors of the GrypeAdapter
+type GrypeAdapterOption func(*GrypeAdapter)
+
+// WithDBRootDir sets the directory holding the vulnerabilities DB, instead of the user cache directory
+func WithDBRootDir(dbRootDir string) GrypeAdapterOption {
+	return func(g *GrypeAdapter) {
+		g.dbConfig.DBRootDir = dbRootDir
+	}
+}
+
+// WithOSV adds the matches of the packages of the optional ecosystems to the OSV advisories to the scans
+func WithOSV(osv *OSVAdapter) GrypeAdapterOption {
+	return func(g *GrypeAdapter) {
+		g.osv = osv
+	}
+}
+
 // NewGrypeAdapter initializes the GrypeAdapter structure
 // DB loading is done via readiness probes
-func NewGrypeAdapter(listingURL string) *GrypeAdapter {
+func NewGrypeAdapter(listingURL string, opts ...GrypeAdapterOption) *GrypeAdapter {
 	g := &GrypeAdapter{
 		dbConfig: db.Config{
 			DBRootDir:  path.Join(xdg.CacheHome, "grype", "db"),
 			ListingURL: listingURL,
 		},
 	}
+	for _, opt := range opts {
+		opt(g)
+	}
 	return g
 }
 
@@ -63,6 +94,17 @@ func NewGrypeAdapterFixedDB() *GrypeAdapter {
 	return g
 }
 
+// DBBuilt returns the build date of the vulnerabilities DB, zero until it is loaded
+func (g *GrypeAdapter) DBBuilt(context.Context) time.Time {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.dbStatus == nil {
+		return time.Time{}
+	}
+	return g.dbStatus.Built
+}
+
 // DBVersion returns the vulnerabilities DB checksum which is used to tag CVE manifests
 func (g *GrypeAdapter) DBVersion(context.Context) string {
 	g.mu.RLock()
@@ -71,47 +113,120 @@ func (g *GrypeAdapter) DBVersion(context.Context) string {
 	return g.dbStatus.Checksum
 }
 
-// Ready returns the status of the vulnerabilities DB
+// Ready returns the status of the vulnerabilities DB, kubevuln restarts to release the previous DB when it cannot
+// be updated; a probe cancelled during an update leaves it running in the background
 func (g *GrypeAdapter) Ready(ctx context.Context) bool {
 	// DB update is in progress
 	if !g.mu.TryRLock() {
 		return false
 	}
 	g.mu.RUnlock() // because TryRLock doesn't unlock
-	// DB is not initialized or needs to be updated
-	now := time.Now()
-	if g.dbStatus == nil || now.Sub(g.lastDbUpdate) > 24*time.Hour {
-		g.mu.Lock()
-		defer g.mu.Unlock()
-		ctx, span := otel.Tracer("").Start(ctx, "GrypeAdapter.UpdateDB")
-		defer span.End()
-		logger.L().Info("updating grype DB",
-			helpers.String("listingURL", g.dbConfig.ListingURL))
-		var err error
-		g.store, g.dbStatus, g.dbCloser, err = grype.LoadVulnerabilityDB(g.dbConfig, true)
-		if err != nil {
-			logger.L().Ctx(ctx).Error("failed to update grype DB", helpers.Error(err))
-			err := tools.DeleteContents(g.dbConfig.DBRootDir)
-			logger.L().Debug("cleaned up cache", helpers.Error(err),
-				helpers.String("DBRootDir", g.dbConfig.DBRootDir))
-			logger.L().Info("restarting to release previous grype DB")
-			os.Exit(0)
+	if err := g.LoadDB(ctx); err != nil {
+		if ctx.Err() != nil {
+			return false
 		}
-		g.lastDbUpdate = now
-		logger.L().Info("grype DB updated")
-		return true
+		err := tools.DeleteContents(g.dbConfig.DBRootDir)
+		logger.L().Debug("cleaned up cache", helpers.Error(err),
+			helpers.String("DBRootDir", g.dbConfig.DBRootDir))
+		logger.L().Info("restarting to release previous grype DB")
+		os.Exit(0)
 	}
-
+	g.mu.RLock()
+	defer g.mu.RUnlock()
 	return g.dbStatus.Err == nil
 }
 
+// LoadDB loads the vulnerabilities DB when it is not initialized or needs to be updated, the update is shared by the
+// concurrent callers and runs in the background so that the cancellation of ctx returns right away
+func (g *GrypeAdapter) LoadDB(ctx context.Context) error {
+	if !g.needsUpdate() {
+		return nil
+	}
+	g.loadMu.Lock()
+	load := g.loading
+	if load == nil {
+		load = &dbLoad{done: make(chan struct{})}
+		g.loading = load
+		go func() {
+			load.err = g.updateDB(tools.WithoutCancel(ctx))
+			g.loadMu.Lock()
+			g.loading = nil
+			g.loadMu.Unlock()
+			close(load.done)
+		}()
+	}
+	g.loadMu.Unlock()
+	select {
+	case <-load.done:
+		return load.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitDB waits for the update of the vulnerabilities DB in progress, if any, until ctx is done
+func (g *GrypeAdapter) waitDB(ctx context.Context) error {
+	g.loadMu.Lock()
+	load := g.loading
+	g.loadMu.Unlock()
+	if load == nil {
+		return nil
+	}
+	select {
+	case <-load.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// updateDB downloads the vulnerabilities DB if needed and loads it, scans wait for the update
+func (g *GrypeAdapter) updateDB(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	// the DB may have been updated while waiting for the lock
+	if g.dbStatus != nil && now.Sub(g.lastDbUpdate) <= 24*time.Hour {
+		return nil
+	}
+	ctx, span := otel.Tracer("").Start(ctx, "GrypeAdapter.UpdateDB")
+	defer span.End()
+	logger.L().Info("updating grype DB",
+		helpers.String("listingURL", g.dbConfig.ListingURL))
+	store, dbStatus, dbCloser, err := g.loadVulnerabilityDB()
+	if err != nil {
+		logger.L().Ctx(ctx).Error("failed to update grype DB", helpers.Error(err))
+		return err
+	}
+	g.store, g.dbStatus, g.dbCloser = store, dbStatus, dbCloser
+	g.lastDbUpdate = now
+	logger.L().Info("grype DB updated")
+	return nil
+}
+
+// needsUpdate tells whether the vulnerabilities DB is not initialized or older than a day
+func (g *GrypeAdapter) needsUpdate() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.dbStatus == nil || time.Since(g.lastDbUpdate) > 24*time.Hour
+}
+
 const dummyLayer = "generatedlayer"
 
 // ScanSBOM generates a CVE manifest by scanning an SBOM
 func (g *GrypeAdapter) ScanSBOM(ctx context.Context, sbom domain.SBOM) (domain.CVEManifest, error) {
 	ctx, span := otel.Tracer("").Start(ctx, "GrypeAdapter.ScanSBOM")
 	defer span.End()
+	cve, err := g.scanSBOM(ctx, sbom)
+	return cve, domain.Categorize(domain.ErrMatching, err)
+}
 
+// scanSBOM matches the packages of the SBOM to the vulnerabilities of the DB
+func (g *GrypeAdapter) scanSBOM(ctx context.Context, sbom domain.SBOM) (domain.CVEManifest, error) {
+	// the DB is locked during its update, which cannot be interrupted
+	if err := g.waitDB(ctx); err != nil {
+		return domain.CVEManifest{}, err
+	}
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
@@ -132,6 +247,7 @@ func (g *GrypeAdapter) ScanSBOM(ctx context.Context, sbom domain.SBOM) (domain.C
 	if err != nil {
 		return domain.CVEManifest{}, err
 	}
+	g.overridePackages(ctx, packages)
 	pkgContext := pkg.Context{
 		Source: &s.Source,
 		Distro: s.Artifacts.LinuxDistribution,
@@ -143,10 +259,12 @@ func (g *GrypeAdapter) ScanSBOM(ctx context.Context, sbom domain.SBOM) (domain.C
 
 	logger.L().Debug("finding vulnerabilities",
 		helpers.String("name", sbom.Name))
+	tools.ReportProgress(ctx, domain.Progress{Stage: domain.StageMatch, Unit: domain.ProgressPackages, Total: int64(len(packages))})
 	remainingMatches, ignoredMatches, err := vulnMatcher.FindMatches(packages, pkgContext)
 	if err != nil {
 		return domain.CVEManifest{}, err
 	}
+	tools.ReportProgress(ctx, domain.Progress{Stage: domain.StageMatch, Unit: domain.ProgressPackages, Current: int64(len(packages)), Total: int64(len(packages))})
 
 	logger.L().Debug("compiling results",
 		helpers.String("name", sbom.Name))
@@ -154,6 +272,15 @@ func (g *GrypeAdapter) ScanSBOM(ctx context.Context, sbom domain.SBOM) (domain.C
 	if err != nil {
 		return domain.CVEManifest{}, err
 	}
+	if g.osv != nil {
+		// the scan goes on with the matches of the database when OSV cannot be reached
+		osvMatches, err := g.osv.Matches(ctx, packages, doc.Matches)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to match the packages to the OSV advisories", helpers.Error(err),
+				helpers.String("name", sbom.Name))
+		}
+		doc.Matches = append(doc.Matches, osvMatches...)
+	}
 
 	logger.L().Debug("converting results to common format",
 		helpers.String("name", sbom.Name))
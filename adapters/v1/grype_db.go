@@ -0,0 +1,116 @@
+package v1
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anchore/grype/grype/db"
+	"github.com/anchore/grype/grype/store"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/wagoodman/go-progress"
+)
+
+// checksum policies of the vulnerabilities DB
+const (
+	// DBChecksumDownload verifies the checksum of the listing when the DB is downloaded
+	DBChecksumDownload = "download"
+	// DBChecksumAlways also verifies the checksum of the DB each time it is loaded
+	DBChecksumAlways = "always"
+)
+
+// WithDBCACert trusts the CA certificates of the PEM file caCertFile to download the listing and the DB, such as
+// the CA of an internal artifact server
+func WithDBCACert(caCertFile string) GrypeAdapterOption {
+	return func(g *GrypeAdapter) {
+		g.dbConfig.CACert = caCertFile
+	}
+}
+
+// WithDBChecksumPolicy sets when the checksum of the DB is verified, DBChecksumDownload or DBChecksumAlways
+func WithDBChecksumPolicy(policy string) GrypeAdapterOption {
+	return func(g *GrypeAdapter) {
+		g.dbConfig.ValidateByHashOnGet = policy == DBChecksumAlways
+	}
+}
+
+// WithDBMaxAge refuses the DBs built more than maxAge ago, 0 accepts any age
+func WithDBMaxAge(maxAge time.Duration) GrypeAdapterOption {
+	return func(g *GrypeAdapter) {
+		g.dbConfig.ValidateAge = maxAge > 0
+		g.dbConfig.MaxAllowedBuiltAge = maxAge
+	}
+}
+
+// WithDBURLRewrites rewrites the download URLs of the listing starting with a key of rewrites by replacing that
+// prefix with its value, so that a listing mirrored as is serves the DBs from a proxy cache
+func WithDBURLRewrites(rewrites map[string]string) GrypeAdapterOption {
+	return func(g *GrypeAdapter) {
+		g.dbURLRewrites = rewrites
+	}
+}
+
+// rewriteDBURL returns the download URL rewritten by the longest matching prefix
+func rewriteDBURL(u *url.URL, rewrites map[string]string) (*url.URL, error) {
+	prefixes := make([]string, 0, len(rewrites))
+	for prefix := range rewrites {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if rest, ok := strings.CutPrefix(u.String(), prefix); ok {
+			return url.Parse(rewrites[prefix] + rest)
+		}
+	}
+	return u, nil
+}
+
+// loadVulnerabilityDB downloads the DB listed by the listing if it supersedes the current one, with its download
+// URL rewritten, then loads it; as with Grype, the current DB is kept when the listing cannot be read
+func (g *GrypeAdapter) loadVulnerabilityDB() (*store.Store, *db.Status, *db.Closer, error) {
+	curator, err := db.NewCurator(g.dbConfig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	updateAvailable, _, entry, err := curator.IsUpdateAvailable()
+	if err != nil {
+		logger.L().Warning("unable to check for vulnerability DB update", helpers.Error(err),
+			helpers.String("listingURL", g.dbConfig.ListingURL))
+	}
+	if updateAvailable {
+		downloadURL, err := rewriteDBURL(entry.URL, g.dbURLRewrites)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid rewritten DB URL: %w", err)
+		}
+		entry.URL = downloadURL
+		logger.L().Info("downloading grype DB",
+			helpers.String("url", downloadURL.Redacted()),
+			helpers.String("built", entry.Built.String()))
+		if err := curator.UpdateTo(entry, progress.NewManual(1), progress.NewManual(1), &progress.Stage{}); err != nil {
+			return nil, nil, nil, fmt.Errorf("unable to update vulnerability database: %w", err)
+		}
+	}
+	// Grype only checks the age of the DB on validation
+	if g.dbConfig.ValidateAge {
+		if err := curator.Validate(); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	storeReader, dbCloser, err := curator.GetStore()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	status := curator.Status()
+	provider, err := db.NewVulnerabilityProvider(storeReader)
+	if err != nil {
+		return nil, &status, nil, err
+	}
+	return &store.Store{
+		Provider:          provider,
+		MetadataProvider:  db.NewVulnerabilityMetadataProvider(storeReader),
+		ExclusionProvider: db.NewMatchExclusionProvider(storeReader),
+	}, &status, &db.Closer{DBCloser: dbCloser}, nil
+}
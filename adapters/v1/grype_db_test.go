@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_rewriteDBURL(t *testing.T) {
+	rewrites := map[string]string{
+		"https://toolbox-data.anchore.io/":                    "https://artifactory.example.com/anchore/",
+		"https://toolbox-data.anchore.io/grype/databases/":    "https://artifactory.example.com/grype/",
+		"https://toolbox-data.anchore.io/grype/databases/v6/": "https://artifactory.example.com/grype-v6/",
+	}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{
+			url:  "https://toolbox-data.anchore.io/grype/databases/vulnerability-db_v5.tar.gz",
+			want: "https://artifactory.example.com/grype/vulnerability-db_v5.tar.gz",
+		},
+		{
+			url:  "https://toolbox-data.anchore.io/other/file.tar.gz",
+			want: "https://artifactory.example.com/anchore/other/file.tar.gz",
+		},
+		{
+			url:  "https://mirror.example.com/vulnerability-db_v5.tar.gz",
+			want: "https://mirror.example.com/vulnerability-db_v5.tar.gz",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			require.NoError(t, err)
+			got, err := rewriteDBURL(u, rewrites)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestNewGrypeAdapter_dbOptions(t *testing.T) {
+	g := NewGrypeAdapter("https://artifactory.example.com/grype/listing.json",
+		WithDBCACert("/etc/ssl/artifactory/ca.crt"),
+		WithDBChecksumPolicy(DBChecksumAlways),
+		WithDBMaxAge(120*time.Hour))
+	assert.Equal(t, "/etc/ssl/artifactory/ca.crt", g.dbConfig.CACert)
+	assert.True(t, g.dbConfig.ValidateByHashOnGet)
+	assert.True(t, g.dbConfig.ValidateAge)
+	assert.Equal(t, 120*time.Hour, g.dbConfig.MaxAllowedBuiltAge)
+
+	g = NewGrypeAdapter("https://artifactory.example.com/grype/listing.json", WithDBChecksumPolicy(""), WithDBMaxAge(0))
+	assert.False(t, g.dbConfig.ValidateByHashOnGet)
+	assert.False(t, g.dbConfig.ValidateAge)
+}
+
+func TestGrypeAdapter_LoadDB_rewrites(t *testing.T) {
+	// the listing mirrored as is still points to the upstream server
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+	g := NewGrypeAdapter(srv.URL+"/listing.json",
+		WithDBRootDir(t.TempDir()),
+		WithDBChecksumPolicy(DBChecksumAlways),
+		WithDBURLRewrites(map[string]string{"http://localhost:8000/": srv.URL + "/"}))
+	ctx := context.TODO()
+	require.NoError(t, g.LoadDB(ctx))
+	assert.Equal(t, "sha256:9be2df3d7d657bfb40ddcc68c9d00520ee7f5a34c7a26333f90cf89cefd5668a", g.DBVersion(ctx))
+
+	// the DB of the listing is too old
+	g = NewGrypeAdapter(srv.URL+"/listing.json",
+		WithDBRootDir(t.TempDir()),
+		WithDBMaxAge(time.Hour),
+		WithDBURLRewrites(map[string]string{"http://localhost:8000/": srv.URL + "/"}))
+	assert.ErrorContains(t, g.LoadDB(ctx), "the vulnerability database was built")
+}
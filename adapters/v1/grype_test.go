@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -21,9 +22,11 @@ func Test_grypeAdapter_DBVersion(t *testing.T) {
 		_ = http.ListenAndServe(":8000", http.FileServer(http.Dir("testdata")))
 	}()
 	g := NewGrypeAdapterFixedDB()
+	assert.True(t, g.DBBuilt(ctx).IsZero())
 	g.Ready(ctx) // need to call ready to load the DB
 	version := g.DBVersion(ctx)
 	assert.Equal(t, "sha256:9be2df3d7d657bfb40ddcc68c9d00520ee7f5a34c7a26333f90cf89cefd5668a", version)
+	assert.False(t, g.DBBuilt(ctx).IsZero())
 }
 
 func fileToSBOM(path string) *v1beta1.Document {
@@ -88,3 +91,35 @@ func Test_grypeAdapter_Version(t *testing.T) {
 	version := g.Version(ctx)
 	assert.NotEqual(t, version, "")
 }
+
+func TestNewGrypeAdapter_WithDBRootDir(t *testing.T) {
+	g := NewGrypeAdapter("https://toolbox-data.anchore.io/grype/databases/listing.json", WithDBRootDir("/scratch/grype/db"))
+	assert.Equal(t, "/scratch/grype/db", g.dbConfig.DBRootDir)
+}
+
+func TestGrypeAdapter_LoadDB_cancellation(t *testing.T) {
+	// a listing server never answering until released
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	g := NewGrypeAdapter(srv.URL+"/listing.json", WithDBRootDir(t.TempDir()))
+	ctx, cancel := context.WithCancel(context.TODO())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	assert.ErrorIs(t, g.LoadDB(ctx), context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second, "DB load not aborted within 5s of the cancellation")
+	// scans waiting for the update are aborted too
+	_, err := g.ScanSBOM(ctx, domain.SBOM{})
+	assert.ErrorIs(t, err, context.Canceled)
+	// the probe reports the update in progress instead of restarting
+	assert.False(t, g.Ready(ctx))
+	// the update keeps running in the background and its failure is shared
+	close(release)
+	assert.Error(t, g.LoadDB(context.TODO()))
+}
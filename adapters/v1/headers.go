@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// ScanIDHeader carries the scanID of the registry and backend requests sent for a scan, so that the server-side logs
+// can be correlated with the logs of kubevuln
+const ScanIDHeader = "X-Kubevuln-Scan-Id"
+
+// RequestHeaders identify kubevuln to the registries and the backend
+type RequestHeaders struct {
+	// UserAgent replaces the User-Agent of the requests, unless empty
+	UserAgent string
+	// Headers are added to the requests, such as a token allowlisting kubevuln in a WAF
+	Headers map[string]string
+}
+
+// requestHeaders are shared by all the adapters, like the default transports they apply to
+var requestHeaders atomic.Pointer[RequestHeaders]
+
+// SetRequestHeaders sets the headers of the registry and backend requests, before the first scan
+func SetRequestHeaders(headers RequestHeaders) {
+	requestHeaders.Store(&headers)
+}
+
+// UserAgent identifies the component, its version and the cluster it runs in
+func UserAgent(component, version, cluster string) string {
+	if version == "" {
+		version = "unknown"
+	}
+	if cluster == "" {
+		return fmt.Sprintf("%s/%s", component, version)
+	}
+	return fmt.Sprintf("%s/%s (cluster %s)", component, version, cluster)
+}
+
+// headerTransport sends the request headers and the scanID of the context of each request
+type headerTransport struct {
+	base http.RoundTripper
+}
+
+// withRequestHeaders wraps base to send the request headers
+func withRequestHeaders(base http.RoundTripper) http.RoundTripper {
+	return headerTransport{base: base}
+}
+
+// RoundTrip sends a copy of the request with the headers, the requests must not be modified by a RoundTripper
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	headers := requestHeaders.Load()
+	scanID, _ := req.Context().Value(domain.ScanIDKey{}).(string)
+	if headers == nil && scanID == "" {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	if headers != nil {
+		for key, value := range headers.Headers {
+			req.Header.Set(key, value)
+		}
+		if headers.UserAgent != "" {
+			req.Header.Set("User-Agent", headers.UserAgent)
+		}
+	}
+	if scanID != "" {
+		req.Header.Set(ScanIDHeader, scanID)
+	}
+	return t.base.RoundTrip(req)
+}
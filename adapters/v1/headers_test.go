@@ -0,0 +1,60 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserAgent(t *testing.T) {
+	assert.Equal(t, "kubevuln/v0.3.0 (cluster minikube)", UserAgent("kubevuln", "v0.3.0", "minikube"))
+	assert.Equal(t, "kubevuln/unknown", UserAgent("kubevuln", "", ""))
+}
+
+func TestHeaderTransport(t *testing.T) {
+	defer requestHeaders.Store(nil)
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	ctx := context.WithValue(context.TODO(), domain.ScanIDKey{}, "scanID")
+
+	// without headers set, only the scanID is sent
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := clientWithContext(ctx).Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, "scanID", got.Get(ScanIDHeader))
+	assert.Equal(t, "Go-http-client/1.1", got.Get("User-Agent"))
+
+	SetRequestHeaders(RequestHeaders{
+		UserAgent: "kubevuln/v0.3.0 (cluster minikube)",
+		Headers:   map[string]string{"X-Waf-Token": "s3cr3t"},
+	})
+	req, _ = http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err = clientWithContext(ctx).Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, "kubevuln/v0.3.0 (cluster minikube)", got.Get("User-Agent"))
+	assert.Equal(t, "s3cr3t", got.Get("X-Waf-Token"))
+	assert.Equal(t, "scanID", got.Get(ScanIDHeader))
+	assert.Empty(t, req.Header, "the request must not be modified")
+
+	// the registry requests override the User-Agent of go-containerregistry
+	ref, err := name.ParseReference(srv.Listener.Addr().String()+"/library/nginx:1.25", name.Insecure)
+	require.NoError(t, err)
+	_, err = remote.Head(ref, prepareRemoteOptions(ctx, ref, domainToRegistryOptions(domain.RegistryOptions{InsecureUseHTTP: true}), nil)...)
+	assert.Error(t, err)
+	assert.Equal(t, "kubevuln/v0.3.0 (cluster minikube)", got.Get("User-Agent"))
+	assert.Equal(t, "s3cr3t", got.Get("X-Waf-Token"))
+	assert.Equal(t, "scanID", got.Get(ScanIDHeader))
+}
@@ -0,0 +1,29 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/armosec/utils-go/httputils"
+)
+
+// contextClient cancels the requests of the httputils helpers, which take no context, with ctx
+type contextClient struct {
+	ctx    context.Context
+	client *http.Client
+}
+
+var _ httputils.IHttpClient = contextClient{}
+
+// backendClient sends the request headers with the default transport
+var backendClient = &http.Client{Transport: withRequestHeaders(http.DefaultTransport)}
+
+// clientWithContext returns the backend HTTP client bound to ctx
+func clientWithContext(ctx context.Context) httputils.IHttpClient {
+	return contextClient{ctx: ctx, client: backendClient}
+}
+
+// Do sends the request, aborted once ctx is done
+func (c contextClient) Do(req *http.Request) (*http.Response, error) {
+	return c.client.Do(req.WithContext(c.ctx))
+}
@@ -0,0 +1,31 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/armosec/utils-go/httputils"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_clientWithContext(t *testing.T) {
+	// a server never answering until released
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	ctx, cancel := context.WithCancel(context.TODO())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := httputils.HttpPost(clientWithContext(ctx), srv.URL, nil, []byte("{}"))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second, "request not aborted within 5s of the cancellation")
+}
@@ -0,0 +1,393 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anchore/grype/grype/pkg"
+	"github.com/anchore/grype/grype/presenter/models"
+	"github.com/anchore/packageurl-go"
+	"github.com/anchore/syft/syft/source"
+	"github.com/facebookincubator/nvdtools/cvss3"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	// DefaultOSVURL is the API of OSV.dev
+	DefaultOSVURL = "https://api.osv.dev"
+	// osvBatchSize is the maximum number of queries of a batch query
+	osvBatchSize = 1000
+	// osvMatcher names the matcher of the OSV matches in their details
+	osvMatcher = "osv-matcher"
+)
+
+// osvEcosystems maps the package URL types of the optional ecosystems to their OSV ecosystems
+var osvEcosystems = map[string]struct{ osv, ecosystem string }{
+	packageurl.TypeCargo: {osv: "crates.io", ecosystem: EcosystemRust},
+	packageurl.TypePub:   {osv: "Pub", ecosystem: EcosystemDart},
+	packageurl.TypeSwift: {osv: "SwiftURL", ecosystem: EcosystemSwift},
+}
+
+// OSVAdapter matches the packages of the optional ecosystems to the advisories of OSV.dev, such as the RustSec and the
+// GitHub advisories of the Rust, Swift and Dart packages, which the Grype database partially covers; the advisories
+// are cached until OSV modifies them
+type OSVAdapter struct {
+	url        string
+	ecosystems map[string]bool
+	httpClient *http.Client
+	mu         sync.Mutex
+	vulns      map[string]osvVulnerability
+}
+
+// osvQuery is the query of the advisories of a package version
+type osvQuery struct {
+	Version string `json:"version"`
+	Package struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	} `json:"package"`
+}
+
+// osvVulnerability is an OSV advisory, see https://ossf.github.io/osv-schema
+type osvVulnerability struct {
+	ID        string   `json:"id"`
+	Modified  string   `json:"modified"`
+	Withdrawn string   `json:"withdrawn"`
+	Aliases   []string `json:"aliases"`
+	Summary   string   `json:"summary"`
+	Details   string   `json:"details"`
+	Severity  []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	Affected []struct {
+		Package struct {
+			Name      string `json:"name"`
+			Ecosystem string `json:"ecosystem"`
+		} `json:"package"`
+		Ranges []struct {
+			Events []struct {
+				Fixed string `json:"fixed"`
+			} `json:"events"`
+		} `json:"ranges"`
+	} `json:"affected"`
+	References []struct {
+		URL string `json:"url"`
+	} `json:"references"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// NewOSVAdapter initializes the OSVAdapter with the URL of the OSV API, DefaultOSVURL when empty, and the optional
+// ecosystems matched
+func NewOSVAdapter(osvURL string, ecosystems []string) *OSVAdapter {
+	if osvURL == "" {
+		osvURL = DefaultOSVURL
+	}
+	enabled := map[string]bool{}
+	for _, ecosystem := range ecosystems {
+		enabled[ecosystem] = true
+	}
+	return &OSVAdapter{
+		url:        strings.TrimSuffix(osvURL, "/"),
+		ecosystems: enabled,
+		httpClient: &http.Client{Timeout: time.Minute},
+		vulns:      map[string]osvVulnerability{},
+	}
+}
+
+// Matches returns the matches of the packages of the enabled ecosystems to the OSV advisories, leaving out the
+// advisories already matched to a package under their ID or one of their aliases
+func (o *OSVAdapter) Matches(ctx context.Context, packages []pkg.Package, matches []models.Match) ([]models.Match, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "OSVAdapter.Matches")
+	defer span.End()
+	var queried []pkg.Package
+	var queries []osvQuery
+	for _, p := range packages {
+		query, ok := o.query(p)
+		if ok {
+			queried = append(queried, p)
+			queries = append(queries, query)
+		}
+	}
+	if len(queries) == 0 {
+		return nil, nil
+	}
+	matched := map[string]map[string]bool{}
+	for _, m := range matches {
+		key := m.Artifact.Name + "@" + m.Artifact.Version
+		if matched[key] == nil {
+			matched[key] = map[string]bool{}
+		}
+		matched[key][m.Vulnerability.ID] = true
+		for _, related := range m.RelatedVulnerabilities {
+			matched[key][related.ID] = true
+		}
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var result []models.Match
+	for start := 0; start < len(queries); start += osvBatchSize {
+		end := start + osvBatchSize
+		if end > len(queries) {
+			end = len(queries)
+		}
+		results, err := o.queryBatch(ctx, queries[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for i, vulns := range results {
+			p, query := queried[start+i], queries[start+i]
+			for _, ref := range vulns {
+				vuln, err := o.vulnerability(ctx, ref.ID, ref.Modified)
+				if err != nil {
+					return nil, err
+				}
+				if vuln.Withdrawn != "" || alreadyMatched(matched[p.Name+"@"+p.Version], vuln) {
+					continue
+				}
+				result = append(result, osvMatch(p, query, vuln))
+			}
+		}
+	}
+	logger.L().Debug("matched packages to OSV advisories",
+		helpers.Int("packages", len(queries)),
+		helpers.Int("matches", len(result)))
+	return result, nil
+}
+
+// query returns the OSV query of a package of an enabled ecosystem, the Swift packages are named after their URL
+func (o *OSVAdapter) query(p pkg.Package) (osvQuery, bool) {
+	var query osvQuery
+	purl, err := packageurl.FromString(p.PURL)
+	if err != nil || p.Version == "" {
+		return query, false
+	}
+	ecosystem, ok := osvEcosystems[purl.Type]
+	if !ok || !o.ecosystems[ecosystem.ecosystem] {
+		return query, false
+	}
+	query.Version = p.Version
+	query.Package.Ecosystem = ecosystem.osv
+	query.Package.Name = purl.Name
+	if purl.Type == packageurl.TypeSwift && purl.Namespace != "" {
+		query.Package.Name = purl.Namespace + "/" + purl.Name
+	}
+	return query, true
+}
+
+// queryBatch returns the IDs and modification dates of the advisories of each query
+func (o *OSVAdapter) queryBatch(ctx context.Context, queries []osvQuery) ([][]osvVulnerability, error) {
+	body, err := json.Marshal(map[string][]osvQuery{"queries": queries})
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Results []struct {
+			Vulns []osvVulnerability `json:"vulns"`
+		} `json:"results"`
+	}
+	if err := o.do(ctx, http.MethodPost, o.url+"/v1/querybatch", body, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Results) != len(queries) {
+		return nil, fmt.Errorf("OSV answered %d results to %d queries", len(response.Results), len(queries))
+	}
+	results := make([][]osvVulnerability, 0, len(queries))
+	for _, r := range response.Results {
+		results = append(results, r.Vulns)
+	}
+	return results, nil
+}
+
+// vulnerability returns an advisory, downloaded again if modified since it was cached
+func (o *OSVAdapter) vulnerability(ctx context.Context, id, modified string) (osvVulnerability, error) {
+	if vuln, ok := o.vulns[id]; ok && vuln.Modified == modified {
+		return vuln, nil
+	}
+	var vuln osvVulnerability
+	if err := o.do(ctx, http.MethodGet, o.url+"/v1/vulns/"+url.PathEscape(id), nil, &vuln); err != nil {
+		return vuln, err
+	}
+	o.vulns[id] = vuln
+	return vuln, nil
+}
+
+// do sends a request to the OSV API and decodes its JSON response into out
+func (o *OSVAdapter) do(ctx context.Context, method, endpoint string, body []byte, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query OSV: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("failed to query OSV: %s %s: %s", method, endpoint, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// alreadyMatched tells whether the advisory, or one of its aliases, is among the vulnerabilities matched to a package
+func alreadyMatched(matched map[string]bool, vuln osvVulnerability) bool {
+	if matched[vuln.ID] {
+		return true
+	}
+	for _, alias := range vuln.Aliases {
+		if matched[alias] {
+			return true
+		}
+	}
+	return false
+}
+
+// osvMatch converts the advisory of a package to a Grype match, its aliases being the related vulnerabilities
+func osvMatch(p pkg.Package, query osvQuery, vuln osvVulnerability) models.Match {
+	urls := []string{"https://osv.dev/vulnerability/" + vuln.ID}
+	for _, reference := range vuln.References {
+		urls = append(urls, reference.URL)
+	}
+	description := vuln.Summary
+	if description == "" {
+		description = vuln.Details
+	}
+	cvss := osvCVSS(vuln)
+	var fixes []string
+	for _, affected := range vuln.Affected {
+		if affected.Package.Ecosystem != query.Package.Ecosystem || affected.Package.Name != query.Package.Name {
+			continue
+		}
+		for _, r := range affected.Ranges {
+			for _, event := range r.Events {
+				if event.Fixed != "" {
+					fixes = append(fixes, event.Fixed)
+				}
+			}
+		}
+	}
+	sort.Strings(fixes)
+	fix := models.Fix{Versions: fixes, State: "fixed"}
+	if len(fixes) == 0 {
+		fix = models.Fix{Versions: []string{}, State: "not-fixed"}
+	}
+	related := make([]models.VulnerabilityMetadata, 0, len(vuln.Aliases))
+	for _, alias := range vuln.Aliases {
+		related = append(related, models.VulnerabilityMetadata{ID: alias, URLs: []string{}, Cvss: []models.Cvss{}})
+	}
+	locations := make([]source.Coordinates, 0)
+	for _, l := range p.Locations.ToSlice() {
+		locations = append(locations, l.Coordinates)
+	}
+	return models.Match{
+		Vulnerability: models.Vulnerability{
+			VulnerabilityMetadata: models.VulnerabilityMetadata{
+				ID:          vuln.ID,
+				DataSource:  "https://osv.dev/vulnerability/" + vuln.ID,
+				Namespace:   "osv:" + query.Package.Ecosystem,
+				Severity:    osvSeverity(vuln, cvss),
+				URLs:        urls,
+				Description: description,
+				Cvss:        cvss,
+			},
+			Fix:        fix,
+			Advisories: []models.Advisory{},
+		},
+		RelatedVulnerabilities: related,
+		MatchDetails: []models.MatchDetails{{
+			Type:    "exact-direct-match",
+			Matcher: osvMatcher,
+			SearchedBy: map[string]string{
+				"ecosystem": query.Package.Ecosystem,
+				"package":   query.Package.Name,
+				"version":   query.Version,
+			},
+			Found: map[string]string{"vulnerabilityID": vuln.ID},
+		}},
+		Artifact: models.Package{
+			Name:      p.Name,
+			Version:   p.Version,
+			Type:      p.Type,
+			Locations: locations,
+			Language:  p.Language,
+			Licenses:  []string{},
+			CPEs:      []string{},
+			PURL:      p.PURL,
+			Upstreams: []models.UpstreamPackage{},
+		},
+	}
+}
+
+// osvCVSS returns the CVSS v3 scores of an advisory
+func osvCVSS(vuln osvVulnerability) []models.Cvss {
+	cvss := []models.Cvss{}
+	for _, severity := range vuln.Severity {
+		if severity.Type != "CVSS_V3" {
+			continue
+		}
+		vector, err := cvss3.VectorFromString(severity.Score)
+		if err != nil {
+			continue
+		}
+		version := strings.TrimPrefix(strings.SplitN(severity.Score, "/", 2)[0], "CVSS:")
+		cvss = append(cvss, models.Cvss{
+			Version:        version,
+			Vector:         severity.Score,
+			Metrics:        models.CvssMetrics{BaseScore: vector.BaseScore()},
+			VendorMetadata: map[string]interface{}{},
+		})
+	}
+	return cvss
+}
+
+// osvSeverity returns the severity of an advisory, given by its database or rated from its highest CVSS v3 score
+func osvSeverity(vuln osvVulnerability, cvss []models.Cvss) string {
+	switch strings.ToUpper(vuln.DatabaseSpecific.Severity) {
+	case "CRITICAL":
+		return "Critical"
+	case "HIGH":
+		return "High"
+	case "MODERATE", "MEDIUM":
+		return "Medium"
+	case "LOW":
+		return "Low"
+	}
+	var score float64
+	for _, c := range cvss {
+		if c.Metrics.BaseScore > score {
+			score = c.Metrics.BaseScore
+		}
+	}
+	switch {
+	case score >= 9:
+		return "Critical"
+	case score >= 7:
+		return "High"
+	case score >= 4:
+		return "Medium"
+	case score > 0:
+		return "Low"
+	}
+	return "Unknown"
+}
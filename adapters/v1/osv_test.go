@@ -0,0 +1,148 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anchore/grype/grype/pkg"
+	"github.com/anchore/grype/grype/presenter/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// osvServer serves the advisories of the smallvec crate and of the http Dart package, and counts the requests of each
+// advisory
+func osvServer(t *testing.T) (*httptest.Server, map[string]int) {
+	vulns := map[string]string{
+		"RUSTSEC-2021-0003": `{"id":"RUSTSEC-2021-0003","modified":"2023-01-01T00:00:00Z","aliases":["CVE-2021-25900","GHSA-43w2-9j62-hq99"],
+			"summary":"Buffer overflow in SmallVec::insert_many",
+			"severity":[{"type":"CVSS_V3","score":"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}],
+			"affected":[{"package":{"ecosystem":"crates.io","name":"smallvec"},"ranges":[{"type":"SEMVER","events":[{"introduced":"0.6.3"},{"fixed":"0.6.14"}]},{"type":"SEMVER","events":[{"introduced":"1.0.0"},{"fixed":"1.6.1"}]}]}],
+			"references":[{"type":"ADVISORY","url":"https://rustsec.org/advisories/RUSTSEC-2021-0003.html"}]}`,
+		"RUSTSEC-2019-0009": `{"id":"RUSTSEC-2019-0009","modified":"2023-01-01T00:00:00Z","aliases":["CVE-2019-15551"],"summary":"Double-free in SmallVec::grow"}`,
+		"RUSTSEC-2020-0000": `{"id":"RUSTSEC-2020-0000","modified":"2023-01-01T00:00:00Z","withdrawn":"2023-01-01T00:00:00Z"}`,
+		"GHSA-4rc4-7gjq-5mv9": `{"id":"GHSA-4rc4-7gjq-5mv9","modified":"2023-01-01T00:00:00Z","summary":"http before 0.13.3 vulnerable to header injection",
+			"affected":[{"package":{"ecosystem":"Pub","name":"http"},"ranges":[{"type":"ECOSYSTEM","events":[{"introduced":"0"}]}]}],
+			"database_specific":{"severity":"MODERATE"}}`,
+	}
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/v1/querybatch" {
+			var body struct {
+				Queries []osvQuery `json:"queries"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			var results []string
+			for _, query := range body.Queries {
+				switch query.Package.Ecosystem + "/" + query.Package.Name + "@" + query.Version {
+				case "crates.io/smallvec@1.6.0":
+					results = append(results, `{"vulns":[{"id":"RUSTSEC-2021-0003","modified":"2023-01-01T00:00:00Z"},{"id":"RUSTSEC-2019-0009","modified":"2023-01-01T00:00:00Z"},{"id":"RUSTSEC-2020-0000","modified":"2023-01-01T00:00:00Z"}]}`)
+				case "Pub/http@0.13.5":
+					results = append(results, `{"vulns":[{"id":"GHSA-4rc4-7gjq-5mv9","modified":"2023-01-01T00:00:00Z"}]}`)
+				default:
+					results = append(results, `{}`)
+				}
+			}
+			_, _ = w.Write([]byte(`{"results":[` + strings.Join(results, ",") + `]}`))
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/v1/vulns/")
+		vuln, ok := vulns[id]
+		if r.Method != http.MethodGet || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		requests[id]++
+		_, _ = w.Write([]byte(vuln))
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func TestOSVAdapter_Matches(t *testing.T) {
+	ctx := context.TODO()
+	server, requests := osvServer(t)
+	packages := []pkg.Package{
+		{Name: "smallvec", Version: "1.6.0", PURL: "pkg:cargo/smallvec@1.6.0"},
+		{Name: "http", Version: "0.13.5", PURL: "pkg:pub/http@0.13.5"},
+		{Name: "swift-nio", Version: "2.40.0", PURL: "pkg:swift/github.com/apple/swift-nio@2.40.0"},
+		{Name: "lodash", Version: "4.17.20", PURL: "pkg:npm/lodash@4.17.20"},
+	}
+	// the database already matched the double-free under its CVE
+	existing := []models.Match{{
+		Vulnerability: models.Vulnerability{VulnerabilityMetadata: models.VulnerabilityMetadata{ID: "GHSA-jqxx-whatever"}},
+		RelatedVulnerabilities: []models.VulnerabilityMetadata{
+			{ID: "CVE-2019-15551"},
+		},
+		Artifact: models.Package{Name: "smallvec", Version: "1.6.0"},
+	}}
+
+	o := NewOSVAdapter(server.URL+"/", []string{EcosystemRust, EcosystemSwift})
+	matches, err := o.Matches(ctx, packages, existing)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	match := matches[0]
+	assert.Equal(t, "RUSTSEC-2021-0003", match.Vulnerability.ID)
+	assert.Equal(t, "osv:crates.io", match.Vulnerability.Namespace)
+	assert.Equal(t, "Critical", match.Vulnerability.Severity)
+	assert.Equal(t, "Buffer overflow in SmallVec::insert_many", match.Vulnerability.Description)
+	require.Len(t, match.Vulnerability.Cvss, 1)
+	assert.Equal(t, "3.1", match.Vulnerability.Cvss[0].Version)
+	assert.Equal(t, 9.8, match.Vulnerability.Cvss[0].Metrics.BaseScore)
+	assert.Equal(t, models.Fix{Versions: []string{"0.6.14", "1.6.1"}, State: "fixed"}, match.Vulnerability.Fix)
+	assert.Equal(t, []string{"https://osv.dev/vulnerability/RUSTSEC-2021-0003", "https://rustsec.org/advisories/RUSTSEC-2021-0003.html"}, match.Vulnerability.URLs)
+	assert.Equal(t, "CVE-2021-25900", match.RelatedVulnerabilities[0].ID)
+	assert.Equal(t, osvMatcher, match.MatchDetails[0].Matcher)
+	assert.Equal(t, "pkg:cargo/smallvec@1.6.0", match.Artifact.PURL)
+
+	// the advisories are cached until modified
+	_, err = o.Matches(ctx, packages, existing)
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests["RUSTSEC-2021-0003"])
+
+	o = NewOSVAdapter(server.URL, []string{EcosystemDart})
+	matches, err = o.Matches(ctx, packages, nil)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "GHSA-4rc4-7gjq-5mv9", matches[0].Vulnerability.ID)
+	assert.Equal(t, "Medium", matches[0].Vulnerability.Severity)
+	assert.Equal(t, "not-fixed", matches[0].Vulnerability.Fix.State)
+
+	// no package of the enabled ecosystems, no query
+	matches, err = NewOSVAdapter("http://127.0.0.1:0", []string{EcosystemDart}).Matches(ctx, packages[:1], nil)
+	assert.NoError(t, err)
+	assert.Empty(t, matches)
+
+	_, err = NewOSVAdapter("http://127.0.0.1:0", []string{EcosystemRust}).Matches(ctx, packages, nil)
+	assert.ErrorContains(t, err, "failed to query OSV")
+}
+
+func Test_osvSeverity(t *testing.T) {
+	tests := []struct {
+		name  string
+		vuln  string
+		score float64
+		want  string
+	}{
+		{name: "database severity", vuln: `{"database_specific":{"severity":"HIGH"}}`, score: 9.8, want: "High"},
+		{name: "critical score", vuln: `{}`, score: 9.1, want: "Critical"},
+		{name: "medium score", vuln: `{}`, score: 5.3, want: "Medium"},
+		{name: "low score", vuln: `{}`, score: 3.1, want: "Low"},
+		{name: "unrated", vuln: `{}`, want: "Unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var vuln osvVulnerability
+			require.NoError(t, json.Unmarshal([]byte(tt.vuln), &vuln))
+			var cvss []models.Cvss
+			if tt.score > 0 {
+				cvss = append(cvss, models.Cvss{Metrics: models.CvssMetrics{BaseScore: tt.score}})
+			}
+			assert.Equal(t, tt.want, osvSeverity(vuln, cvss))
+		})
+	}
+}
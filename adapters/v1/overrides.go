@@ -0,0 +1,136 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/anchore/grype/grype/pkg"
+	"github.com/anchore/syft/syft/cpe"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+// PackageOverride corrects the identity of the packages matching all its non-empty selectors before they are matched
+// against the vulnerabilities, for the internal, forked or renamed packages the SBOM misidentifies; the selectors are
+// glob patterns, "**" matching any number of path segments
+type PackageOverride struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	PURL    string `json:"purl,omitempty"`
+	// Set replaces the non-empty fields of the identity of the selected packages
+	Set PackageIdentity `json:"set"`
+}
+
+// PackageIdentity identifies a package for the matching, a non-nil empty CPEs disables the CPE matching
+type PackageIdentity struct {
+	Name    string    `json:"name,omitempty"`
+	Version string    `json:"version,omitempty"`
+	PURL    string    `json:"purl,omitempty"`
+	CPEs    *[]string `json:"cpes,omitempty"`
+}
+
+// ParsePackageOverrides reads a JSON list of package overrides, see WithPackageOverrides
+func ParsePackageOverrides(path string) ([]PackageOverride, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var overrides []PackageOverride
+	if err := json.Unmarshal(content, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse package overrides file %s: %w", path, err)
+	}
+	for i, override := range overrides {
+		if err := override.validate(); err != nil {
+			return nil, fmt.Errorf("invalid package override %d of %s: %w", i, path, err)
+		}
+	}
+	return overrides, nil
+}
+
+// validate checks the selectors are glob patterns, at least one of them, and the CPEs are well-formed
+func (o PackageOverride) validate() error {
+	if o.Name == "" && o.Version == "" && o.PURL == "" {
+		return errors.New("needs at least a name, version or purl selector")
+	}
+	for _, pattern := range []string{o.Name, o.Version, o.PURL} {
+		if !doublestar.ValidatePattern(pattern) {
+			return fmt.Errorf("selectors must be glob patterns, got %q", pattern)
+		}
+	}
+	if o.Set == (PackageIdentity{}) {
+		return errors.New("sets nothing")
+	}
+	if o.Set.CPEs != nil {
+		for _, c := range *o.Set.CPEs {
+			if _, err := cpe.New(c); err != nil {
+				return fmt.Errorf("invalid CPE %q: %w", c, err)
+			}
+		}
+	}
+	return nil
+}
+
+// selects tells whether the override applies to a package
+func (o PackageOverride) selects(p pkg.Package) bool {
+	return selectorMatch(o.Name, p.Name) && selectorMatch(o.Version, p.Version) && selectorMatch(o.PURL, p.PURL)
+}
+
+// selectorMatch matches a value against a glob pattern, an empty pattern matches anything
+func selectorMatch(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, err := doublestar.Match(pattern, value)
+	return err == nil && matched
+}
+
+// WithPackageOverrides corrects the identity of the packages of the SBOMs before matching them, the first override
+// selecting a package applies; the vulnerability manifests report the packages with their corrected identity
+func WithPackageOverrides(overrides []PackageOverride) GrypeAdapterOption {
+	return func(g *GrypeAdapter) {
+		g.overrides = overrides
+	}
+}
+
+// overridePackages applies the package overrides to the packages, in place
+func (g *GrypeAdapter) overridePackages(ctx context.Context, packages []pkg.Package) {
+	var overridden int
+	for i := range packages {
+		for _, override := range g.overrides {
+			if !override.selects(packages[i]) {
+				continue
+			}
+			override.Set.apply(&packages[i])
+			overridden++
+			break
+		}
+	}
+	if overridden > 0 {
+		logger.L().Ctx(ctx).Debug("overrode package identities", helpers.Int("packages", overridden))
+	}
+}
+
+// apply replaces the identity of the package by the non-empty fields of the identity, skipping the malformed CPEs
+func (i PackageIdentity) apply(p *pkg.Package) {
+	if i.Name != "" {
+		p.Name = i.Name
+	}
+	if i.Version != "" {
+		p.Version = i.Version
+	}
+	if i.PURL != "" {
+		p.PURL = i.PURL
+	}
+	if i.CPEs != nil {
+		p.CPEs = make([]cpe.CPE, 0, len(*i.CPEs))
+		for _, c := range *i.CPEs {
+			if parsed, err := cpe.New(c); err == nil {
+				p.CPEs = append(p.CPEs, parsed)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anchore/grype/grype/pkg"
+	"github.com/anchore/syft/syft/cpe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePackageOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []PackageOverride
+		wantErr string
+	}{
+		{
+			name:    "overrides",
+			content: `[{"name": "acme-log4j", "set": {"name": "log4j-core", "cpes": []}}, {"purl": "pkg:maven/com.acme/**", "set": {"version": "2.17.1"}}]`,
+			want: []PackageOverride{
+				{Name: "acme-log4j", Set: PackageIdentity{Name: "log4j-core", CPEs: &[]string{}}},
+				{PURL: "pkg:maven/com.acme/**", Set: PackageIdentity{Version: "2.17.1"}},
+			},
+		},
+		{
+			name:    "malformed",
+			content: `{"name": "acme-log4j"}`,
+			wantErr: "failed to parse package overrides file",
+		},
+		{
+			name:    "no selector",
+			content: `[{"set": {"name": "log4j-core"}}]`,
+			wantErr: "needs at least a name, version or purl selector",
+		},
+		{
+			name:    "invalid pattern",
+			content: `[{"name": "acme-[log4j", "set": {"name": "log4j-core"}}]`,
+			wantErr: `selectors must be glob patterns, got "acme-[log4j"`,
+		},
+		{
+			name:    "nothing set",
+			content: `[{"name": "acme-log4j", "set": {}}]`,
+			wantErr: "sets nothing",
+		},
+		{
+			name:    "invalid CPE",
+			content: `[{"name": "acme-log4j", "set": {"cpes": ["apache:log4j"]}}]`,
+			wantErr: `invalid CPE "apache:log4j"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "overrides.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+			got, err := ParsePackageOverrides(path)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+	_, err := ParsePackageOverrides(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestGrypeAdapter_overridePackages(t *testing.T) {
+	log4j := cpe.Must("cpe:2.3:a:apache:log4j:2.17.1:*:*:*:*:*:*:*")
+	g := NewGrypeAdapter("", WithPackageOverrides([]PackageOverride{
+		{Name: "acme-log4j", Version: "2.*", Set: PackageIdentity{Name: "log4j-core", PURL: "pkg:maven/org.apache.logging.log4j/log4j-core@2.17.1", CPEs: &[]string{log4j.BindToFmtString()}}},
+		{PURL: "pkg:npm/@acme/**", Set: PackageIdentity{CPEs: &[]string{}}},
+		// shadowed by the first override
+		{Name: "acme-*", Set: PackageIdentity{Name: "shadowed"}},
+	}))
+	acmeCPE := cpe.Must("cpe:2.3:a:acme:acme-log4j:2.14.1:*:*:*:*:*:*:*")
+	packages := []pkg.Package{
+		{Name: "acme-log4j", Version: "2.14.1", CPEs: []cpe.CPE{acmeCPE}},
+		{Name: "acme-log4j", Version: "1.2.17", CPEs: []cpe.CPE{acmeCPE}},
+		{Name: "left-pad", Version: "1.3.0", PURL: "pkg:npm/@acme/utils/left-pad@1.3.0", CPEs: []cpe.CPE{cpe.Must("cpe:2.3:a:left-pad:left-pad:1.3.0:*:*:*:*:*:*:*")}},
+		{Name: "openssl", Version: "3.0.2"},
+	}
+	g.overridePackages(context.TODO(), packages)
+	assert.Equal(t, []pkg.Package{
+		{Name: "log4j-core", Version: "2.14.1", PURL: "pkg:maven/org.apache.logging.log4j/log4j-core@2.17.1", CPEs: []cpe.CPE{log4j}},
+		{Name: "shadowed", Version: "1.2.17", CPEs: []cpe.CPE{acmeCPE}},
+		{Name: "left-pad", Version: "1.3.0", PURL: "pkg:npm/@acme/utils/left-pad@1.3.0", CPEs: []cpe.CPE{}},
+		{Name: "openssl", Version: "3.0.2"},
+	}, packages)
+}
@@ -0,0 +1,76 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	wlidpkg "github.com/armosec/utils-k8s-go/wlid"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// fieldManager owns the annotations patched by kubevuln
+const fieldManager = "kubevuln"
+
+// WorkloadPatchAdapter implements WorkloadPatcher from ports by merge patching the metadata of the workloads, at most
+// qps patches per second so that a scan burst does not flood the API server
+type WorkloadPatchAdapter struct {
+	client  kubernetes.Interface
+	limiter flowcontrol.RateLimiter
+}
+
+var _ ports.WorkloadPatcher = (*WorkloadPatchAdapter)(nil)
+
+// NewWorkloadPatchAdapter initializes the WorkloadPatchAdapter struct
+func NewWorkloadPatchAdapter(client kubernetes.Interface, qps float64) *WorkloadPatchAdapter {
+	burst := int(qps)
+	if burst < 1 {
+		burst = 1
+	}
+	return &WorkloadPatchAdapter{client: client, limiter: flowcontrol.NewTokenBucketRateLimiter(float32(qps), burst)}
+}
+
+// PatchWorkloadAnnotations adds annotations to the metadata of a Deployment, StatefulSet, DaemonSet, ReplicaSet, Job,
+// CronJob or Pod, leaving its pod template alone so that no rollout is triggered; deleted workloads and the other
+// kinds are skipped
+func (w *WorkloadPatchAdapter) PatchWorkloadAnnotations(ctx context.Context, wlid string, annotations map[string]string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "WorkloadPatchAdapter.PatchWorkloadAnnotations")
+	defer span.End()
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+	if err := w.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	namespace, name := wlidpkg.GetNamespaceFromWlid(wlid), wlidpkg.GetNameFromWlid(wlid)
+	options := metav1.PatchOptions{FieldManager: fieldManager}
+	switch strings.ToLower(wlidpkg.GetKindFromWlid(wlid)) {
+	case "deployment":
+		_, err = w.client.AppsV1().Deployments(namespace).Patch(ctx, name, types.MergePatchType, patch, options)
+	case "statefulset":
+		_, err = w.client.AppsV1().StatefulSets(namespace).Patch(ctx, name, types.MergePatchType, patch, options)
+	case "daemonset":
+		_, err = w.client.AppsV1().DaemonSets(namespace).Patch(ctx, name, types.MergePatchType, patch, options)
+	case "replicaset":
+		_, err = w.client.AppsV1().ReplicaSets(namespace).Patch(ctx, name, types.MergePatchType, patch, options)
+	case "job":
+		_, err = w.client.BatchV1().Jobs(namespace).Patch(ctx, name, types.MergePatchType, patch, options)
+	case "cronjob":
+		_, err = w.client.BatchV1().CronJobs(namespace).Patch(ctx, name, types.MergePatchType, patch, options)
+	case "pod":
+		_, err = w.client.CoreV1().Pods(namespace).Patch(ctx, name, types.MergePatchType, patch, options)
+	default:
+		return nil
+	}
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWorkloadPatchAdapter_PatchWorkloadAnnotations(t *testing.T) {
+	deployment := newDeployment("nginx", false)
+	deployment.Annotations = map[string]string{"owner": "team-a"}
+	client := fake.NewSimpleClientset(
+		deployment,
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "default"}},
+	)
+	w := NewWorkloadPatchAdapter(client, 100)
+	ctx := context.TODO()
+	annotations := map[string]string{"vulnerabilities.kubescape.io/nginx": `{"verdict":"success"}`}
+
+	// the annotations are merged into the metadata, the pod template is left alone
+	require.NoError(t, w.PatchWorkloadAnnotations(ctx, "wlid://cluster-minikube/namespace-default/deployment-nginx", annotations))
+	patched, err := client.AppsV1().Deployments("default").Get(ctx, "nginx", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"owner": "team-a", "vulnerabilities.kubescape.io/nginx": `{"verdict":"success"}`}, patched.Annotations)
+	assert.Empty(t, patched.Spec.Template.Annotations)
+
+	require.NoError(t, w.PatchWorkloadAnnotations(ctx, "wlid://cluster-minikube/namespace-default/pod-debug", annotations))
+	pod, err := client.CoreV1().Pods("default").Get(ctx, "debug", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, annotations, pod.Annotations)
+
+	// deleted workloads and unsupported kinds are skipped
+	assert.NoError(t, w.PatchWorkloadAnnotations(ctx, "wlid://cluster-minikube/namespace-default/statefulset-deleted", annotations))
+	assert.NoError(t, w.PatchWorkloadAnnotations(ctx, "wlid://cluster-minikube/namespace-default/rollout-nginx", annotations))
+}
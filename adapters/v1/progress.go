@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+)
+
+// progressBufferSize bounds the events buffered for a subscriber, further events are dropped until it catches up
+const progressBufferSize = 64
+
+// ProgressBroker fans out the progress of the scans to the subscribers
+type ProgressBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan domain.Progress]struct{}
+}
+
+var _ ports.ProgressBroker = (*ProgressBroker)(nil)
+
+// NewProgressBroker initializes the ProgressBroker struct
+func NewProgressBroker() *ProgressBroker {
+	return &ProgressBroker{
+		subscribers: map[chan domain.Progress]struct{}{},
+	}
+}
+
+// Publish sends the event to the subscribers without blocking, slow subscribers miss it
+func (b *ProgressBroker) Publish(progress domain.Progress) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- progress:
+		default:
+		}
+	}
+}
+
+// Subscribe returns the events published until ctx is done, the channel is then closed
+func (b *ProgressBroker) Subscribe(ctx context.Context) <-chan domain.Progress {
+	subscriber := make(chan domain.Progress, progressBufferSize)
+	b.mu.Lock()
+	b.subscribers[subscriber] = struct{}{}
+	b.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, subscriber)
+		close(subscriber)
+	}()
+	return subscriber
+}
@@ -0,0 +1,30 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressBroker(t *testing.T) {
+	b := NewProgressBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	events := b.Subscribe(ctx)
+	b.Publish(domain.Progress{ScanID: "scan", Stage: domain.StagePull})
+	assert.Equal(t, domain.Progress{ScanID: "scan", Stage: domain.StagePull}, <-events)
+	// slow subscribers miss the events overflowing their buffer
+	for i := 0; i < 2*progressBufferSize; i++ {
+		b.Publish(domain.Progress{Current: int64(i)})
+	}
+	assert.Len(t, events, progressBufferSize)
+	// the channel is closed once the subscriber is gone
+	cancel()
+	for range events {
+	}
+	b.Publish(domain.Progress{})
+	b.mu.Lock()
+	assert.Empty(t, b.subscribers)
+	b.mu.Unlock()
+}
@@ -0,0 +1,324 @@
+package v1
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// tokenRefreshMargin is how long before their expiry the Artifactory access tokens are refreshed
+const tokenRefreshMargin = time.Minute
+
+// RegistryAuth configures the credentials of the repositories of a registry, the secrets are read from files
+// at each pull so that their rotation is picked up without a restart
+type RegistryAuth struct {
+	// Provider is domain.AuthProviderHarbor for robot accounts or domain.AuthProviderArtifactory for access tokens
+	Provider string
+	// Registry is the registry host
+	Registry string
+	// Repositories restricts the credentials to these repositories and the ones below them, all when empty
+	Repositories []string
+	// Username is the name of the robot account, or of the owner of the access token which is otherwise sent as
+	// a bearer token
+	Username string
+	// PasswordFile holds the secret of the robot account
+	PasswordFile string
+	// TokenFile holds the access token
+	TokenFile string
+	// RefreshTokenFile holds the refresh token of the access token, which is then refreshed before it expires
+	RefreshTokenFile string
+	// URL is the base URL of Artifactory used to refresh the access token, https://<registry> when empty
+	URL string
+}
+
+// RegistryAuthBroker resolves the credentials of the Harbor robot accounts and Artifactory access tokens configured
+// per registry and repository, refreshing the access tokens before they expire
+type RegistryAuthBroker struct {
+	credentials []*brokerCredentials
+	httpClient  *http.Client
+	now         func() time.Time
+}
+
+// brokerCredentials are the credentials of a registry with the state of their access token
+type brokerCredentials struct {
+	RegistryAuth
+	// fileToken is the access token last read from TokenFile, token the one in use which may have been refreshed
+	fileToken    string
+	expiry       time.Time
+	mu           sync.Mutex
+	refreshToken string
+	registry     string
+	token        string
+}
+
+// NewRegistryAuthBroker initializes the RegistryAuthBroker struct, the secret files are only read when pulling
+func NewRegistryAuthBroker(auths []RegistryAuth) (*RegistryAuthBroker, error) {
+	b := &RegistryAuthBroker{httpClient: &http.Client{Transport: withRequestHeaders(http.DefaultTransport), Timeout: 30 * time.Second}, now: time.Now}
+	for _, auth := range auths {
+		registry, err := name.NewRegistry(auth.Registry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid registry %q: %w", auth.Registry, err)
+		}
+		switch auth.Provider {
+		case domain.AuthProviderHarbor:
+			if auth.Username == "" || auth.PasswordFile == "" {
+				return nil, fmt.Errorf("the robot account of %s needs a username and a password file", auth.Registry)
+			}
+		case domain.AuthProviderArtifactory:
+			if auth.TokenFile == "" {
+				return nil, fmt.Errorf("the access token of %s needs a token file", auth.Registry)
+			}
+			if auth.URL == "" {
+				auth.URL = "https://" + registry.RegistryStr()
+			}
+		default:
+			return nil, fmt.Errorf("unknown registry credentials provider %q of %s", auth.Provider, auth.Registry)
+		}
+		b.credentials = append(b.credentials, &brokerCredentials{RegistryAuth: auth, registry: registry.RegistryStr()})
+	}
+	return b, nil
+}
+
+// WithRegistryAuth resolves the registry credentials with the broker first, before the pull secrets of the workloads
+// and the default keychain
+func WithRegistryAuth(broker *RegistryAuthBroker) SyftAdapterOption {
+	return func(s *SyftAdapter) {
+		s.registryAuth = broker
+	}
+}
+
+// credentialsFor returns the first credentials covering the repository, and whether the broker has credentials
+// for its registry at all
+func (b *RegistryAuthBroker) credentialsFor(repository name.Repository) (*brokerCredentials, bool) {
+	configured := false
+	for _, c := range b.credentials {
+		if c.registry != repository.RegistryStr() {
+			continue
+		}
+		configured = true
+		if c.covers(repository.RepositoryStr()) {
+			return c, true
+		}
+	}
+	return nil, configured
+}
+
+// covers tells whether the credentials are scoped to the repository
+func (c *brokerCredentials) covers(repository string) bool {
+	if len(c.Repositories) == 0 {
+		return true
+	}
+	for _, scope := range c.Repositories {
+		scope = strings.Trim(scope, "/")
+		if repository == scope || strings.HasPrefix(repository, scope+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// withRegistryAuth prepends the broker credentials covering the repository of the image to the registry options
+func (s *SyftAdapter) withRegistryAuth(ctx context.Context, imageRef string, registryOptions image.RegistryOptions) image.RegistryOptions {
+	if s.registryAuth == nil {
+		return registryOptions
+	}
+	ref, err := name.ParseReference(imageRef, prepareReferenceOptions(registryOptions)...)
+	if err != nil {
+		return registryOptions
+	}
+	c, _ := s.registryAuth.credentialsFor(ref.Context())
+	if c == nil {
+		return registryOptions
+	}
+	credentials := image.RegistryCredentials{
+		Authority:     c.registry,
+		Authenticator: brokerAuthenticator{ctx: ctx, broker: s.registryAuth, credentials: c},
+	}
+	registryOptions.Credentials = append([]image.RegistryCredentials{credentials}, registryOptions.Credentials...)
+	return registryOptions
+}
+
+// authError qualifies the registry authentication failures with the credentials used to pull and the reason,
+// other errors are returned as is
+func (s *SyftAdapter) authError(err error, imageRef string, registryOptions image.RegistryOptions) error {
+	var authErr *domain.RegistryAuthError
+	var transportError *transport.Error
+	if err == nil || errors.As(err, &authErr) || !errors.As(err, &transportError) {
+		return err
+	}
+	reason := domain.AuthReasonUnauthorized
+	switch {
+	case transportError.StatusCode == http.StatusForbidden:
+		reason = domain.AuthReasonDenied
+	case transportError.StatusCode != http.StatusUnauthorized:
+		return err
+	}
+	for _, diagnostic := range transportError.Errors {
+		if diagnostic.Code == transport.DeniedErrorCode {
+			reason = domain.AuthReasonDenied
+		}
+	}
+	ref, parseErr := name.ParseReference(imageRef, prepareReferenceOptions(registryOptions)...)
+	if parseErr != nil {
+		return err
+	}
+	registry := ref.Context().RegistryStr()
+	provider := domain.AuthProviderKeychain
+	switch authenticator := registryOptions.Authenticator(registry).(type) {
+	case nil:
+	case brokerAuthenticator:
+		provider = authenticator.credentials.Provider
+	default:
+		provider = domain.AuthProviderPullSecret
+	}
+	// the registry has credentials, none of them for this repository
+	if s.registryAuth != nil {
+		if c, configured := s.registryAuth.credentialsFor(ref.Context()); c == nil && configured {
+			reason = domain.AuthReasonOutOfScope
+		}
+	}
+	return &domain.RegistryAuthError{Registry: registry, Provider: provider, Reason: reason, Err: err}
+}
+
+// brokerAuthenticator implements authn.Authenticator with the broker credentials, resolved at each pull
+type brokerAuthenticator struct {
+	broker      *RegistryAuthBroker
+	credentials *brokerCredentials
+	ctx         context.Context
+}
+
+func (a brokerAuthenticator) Authorization() (*authn.AuthConfig, error) {
+	c := a.credentials
+	fail := func(reason string, err error) error {
+		return &domain.RegistryAuthError{Registry: c.registry, Provider: c.Provider, Reason: reason, Err: err}
+	}
+	if c.Provider == domain.AuthProviderHarbor {
+		secret, err := readSecret(c.PasswordFile)
+		if err != nil {
+			return nil, fail(domain.AuthReasonUnauthorized, err)
+		}
+		return &authn.AuthConfig{Username: c.Username, Password: secret}, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// a new token in the file replaces the refreshed one
+	token, err := readSecret(c.TokenFile)
+	if err != nil {
+		return nil, fail(domain.AuthReasonUnauthorized, err)
+	}
+	if token != c.fileToken {
+		c.fileToken, c.token, c.expiry, c.refreshToken = token, token, tokenExpiry(token), ""
+	}
+	if c.refreshToken == "" && c.RefreshTokenFile != "" {
+		if c.refreshToken, err = readSecret(c.RefreshTokenFile); err != nil {
+			return nil, fail(domain.AuthReasonRefreshFailed, err)
+		}
+	}
+	now := a.broker.now()
+	if !c.expiry.IsZero() && now.Add(tokenRefreshMargin).After(c.expiry) {
+		switch {
+		case c.refreshToken != "":
+			if err := a.broker.refresh(a.ctx, c); err != nil {
+				if now.After(c.expiry) {
+					return nil, fail(domain.AuthReasonRefreshFailed, err)
+				}
+				logger.L().Ctx(a.ctx).Warning("failed to refresh the access token, using it until it expires", helpers.Error(err),
+					helpers.String("registry", c.registry))
+			}
+		case now.After(c.expiry):
+			return nil, fail(domain.AuthReasonExpired, fmt.Errorf("access token expired at %s", c.expiry.Format(time.RFC3339)))
+		}
+	}
+	if c.Username == "" {
+		return &authn.AuthConfig{RegistryToken: c.token}, nil
+	}
+	return &authn.AuthConfig{Username: c.Username, Password: c.token}, nil
+}
+
+// refresh exchanges the refresh token of the credentials for a new access token, with the Artifactory access API
+func (b *RegistryAuthBroker) refresh(ctx context.Context, c *brokerCredentials) error {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"access_token":  {c.token},
+		"refresh_token": {c.refreshToken},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.URL, "/")+"/access/api/v1/tokens", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("token refresh failed with status %d", resp.StatusCode)
+	}
+	var refreshed struct {
+		AccessToken  string `json:"access_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&refreshed); err != nil {
+		return fmt.Errorf("failed to decode the refreshed token: %w", err)
+	}
+	if refreshed.AccessToken == "" {
+		return fmt.Errorf("token refresh returned no access token")
+	}
+	c.token = refreshed.AccessToken
+	c.expiry = tokenExpiry(refreshed.AccessToken)
+	if refreshed.ExpiresIn > 0 {
+		c.expiry = b.now().Add(time.Duration(refreshed.ExpiresIn) * time.Second)
+	}
+	if refreshed.RefreshToken != "" {
+		c.refreshToken = refreshed.RefreshToken
+	}
+	logger.L().Debug("refreshed the access token", helpers.String("registry", c.registry),
+		helpers.String("expiry", c.expiry.Format(time.RFC3339)))
+	return nil
+}
+
+// tokenExpiry returns the expiry of a JWT access token, zero for the tokens which are not JWTs or never expire
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// readSecret reads a secret from a file, without the trailing newline
+func readSecret(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
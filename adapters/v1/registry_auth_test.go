@@ -0,0 +1,334 @@
+package v1
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jwt returns an unsigned JWT expiring at the given time
+func jwt(exp time.Time) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"none"}`)) + "." + encode([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix()))) + ".sig"
+}
+
+// secretFile writes a secret into a file of the test directory
+func secretFile(t *testing.T, name, secret string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(secret+"\n"), 0600))
+	return path
+}
+
+func TestNewRegistryAuthBroker(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    RegistryAuth
+		wantErr bool
+	}{
+		{
+			name: "harbor robot account",
+			auth: RegistryAuth{Provider: domain.AuthProviderHarbor, Registry: "harbor.example.com", Username: "robot$kubevuln", PasswordFile: "/secret"},
+		},
+		{
+			name: "artifactory access token",
+			auth: RegistryAuth{Provider: domain.AuthProviderArtifactory, Registry: "example.jfrog.io", TokenFile: "/token"},
+		},
+		{
+			name:    "harbor without secret",
+			auth:    RegistryAuth{Provider: domain.AuthProviderHarbor, Registry: "harbor.example.com", Username: "robot$kubevuln"},
+			wantErr: true,
+		},
+		{
+			name:    "artifactory without token",
+			auth:    RegistryAuth{Provider: domain.AuthProviderArtifactory, Registry: "example.jfrog.io"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown provider",
+			auth:    RegistryAuth{Provider: "quay", Registry: "quay.io"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRegistryAuthBroker([]RegistryAuth{tt.auth})
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestRegistryAuthBroker_credentialsFor(t *testing.T) {
+	broker, err := NewRegistryAuthBroker([]RegistryAuth{
+		{Provider: domain.AuthProviderHarbor, Registry: "harbor.example.com", Repositories: []string{"team-a", "/shared/base/"}, Username: "robot$team-a", PasswordFile: "/secret"},
+		{Provider: domain.AuthProviderArtifactory, Registry: "example.jfrog.io", TokenFile: "/token"},
+	})
+	require.NoError(t, err)
+	tests := []struct {
+		image          string
+		wantProvider   string
+		wantConfigured bool
+	}{
+		{image: "harbor.example.com/team-a/app:1.0", wantProvider: domain.AuthProviderHarbor, wantConfigured: true},
+		{image: "harbor.example.com/shared/base/alpine:3.17", wantProvider: domain.AuthProviderHarbor, wantConfigured: true},
+		{image: "harbor.example.com/team-ab/app:1.0", wantConfigured: true},
+		{image: "example.jfrog.io/docker-local/app:1.0", wantProvider: domain.AuthProviderArtifactory, wantConfigured: true},
+		{image: "alpine:3.17"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			ref, err := name.ParseReference(tt.image)
+			require.NoError(t, err)
+			c, configured := broker.credentialsFor(ref.Context())
+			assert.Equal(t, tt.wantConfigured, configured)
+			if tt.wantProvider == "" {
+				assert.Nil(t, c)
+				return
+			}
+			require.NotNil(t, c)
+			assert.Equal(t, tt.wantProvider, c.Provider)
+		})
+	}
+}
+
+func TestBrokerAuthenticator_harbor(t *testing.T) {
+	secret := secretFile(t, "secret", "s3cr3t")
+	broker, err := NewRegistryAuthBroker([]RegistryAuth{{Provider: domain.AuthProviderHarbor, Registry: "harbor.example.com", Username: "robot$kubevuln", PasswordFile: secret}})
+	require.NoError(t, err)
+	authenticator := brokerAuthenticator{ctx: context.TODO(), broker: broker, credentials: broker.credentials[0]}
+	got, err := authenticator.Authorization()
+	require.NoError(t, err)
+	assert.Equal(t, &authn.AuthConfig{Username: "robot$kubevuln", Password: "s3cr3t"}, got)
+	// the rotated secret is used by the next pull
+	require.NoError(t, os.WriteFile(secret, []byte("r0tated"), 0600))
+	got, err = authenticator.Authorization()
+	require.NoError(t, err)
+	assert.Equal(t, "r0tated", got.Password)
+	// a missing secret is an authentication failure
+	require.NoError(t, os.Remove(secret))
+	_, err = authenticator.Authorization()
+	var authErr *domain.RegistryAuthError
+	require.ErrorAs(t, err, &authErr)
+	assert.Equal(t, domain.AuthReasonUnauthorized, authErr.Reason)
+}
+
+func TestBrokerAuthenticator_artifactory(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	refreshed := jwt(now.Add(time.Hour))
+	tests := []struct {
+		name         string
+		token        string
+		refreshToken string
+		username     string
+		refreshFails bool
+		want         *authn.AuthConfig
+		wantReason   string
+	}{
+		{
+			name:  "valid token sent as bearer",
+			token: jwt(now.Add(time.Hour)),
+			want:  &authn.AuthConfig{RegistryToken: jwt(now.Add(time.Hour))},
+		},
+		{
+			name:     "valid token with its owner",
+			token:    "reference-token",
+			username: "ci",
+			want:     &authn.AuthConfig{Username: "ci", Password: "reference-token"},
+		},
+		{
+			name:         "expiring token is refreshed",
+			token:        jwt(now.Add(30 * time.Second)),
+			refreshToken: "refresh",
+			want:         &authn.AuthConfig{RegistryToken: refreshed},
+		},
+		{
+			name:         "expiring token is used when the refresh fails",
+			token:        jwt(now.Add(30 * time.Second)),
+			refreshToken: "refresh",
+			refreshFails: true,
+			want:         &authn.AuthConfig{RegistryToken: jwt(now.Add(30 * time.Second))},
+		},
+		{
+			name:         "expired token whose refresh fails",
+			token:        jwt(now.Add(-time.Minute)),
+			refreshToken: "refresh",
+			refreshFails: true,
+			wantReason:   domain.AuthReasonRefreshFailed,
+		},
+		{
+			name:       "expired token without refresh token",
+			token:      jwt(now.Add(-time.Minute)),
+			wantReason: domain.AuthReasonExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(t, r.ParseForm())
+				if tt.refreshFails || r.URL.Path != "/access/api/v1/tokens" || r.PostForm.Get("refresh_token") != tt.refreshToken || r.PostForm.Get("access_token") != tt.token {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				_, _ = fmt.Fprintf(w, `{"access_token":%q,"refresh_token":"refresh-2","expires_in":3600,"token_type":"Bearer"}`, refreshed)
+			}))
+			defer access.Close()
+			auth := RegistryAuth{Provider: domain.AuthProviderArtifactory, Registry: "example.jfrog.io", Username: tt.username, TokenFile: secretFile(t, "token", tt.token), URL: access.URL}
+			if tt.refreshToken != "" {
+				auth.RefreshTokenFile = secretFile(t, "refresh", tt.refreshToken)
+			}
+			broker, err := NewRegistryAuthBroker([]RegistryAuth{auth})
+			require.NoError(t, err)
+			broker.now = func() time.Time { return now }
+			got, err := brokerAuthenticator{ctx: context.TODO(), broker: broker, credentials: broker.credentials[0]}.Authorization()
+			if tt.wantReason != "" {
+				var authErr *domain.RegistryAuthError
+				require.ErrorAs(t, err, &authErr)
+				assert.Equal(t, tt.wantReason, authErr.Reason)
+				assert.Equal(t, domain.AuthProviderArtifactory, authErr.Provider)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSyftAdapter_authError(t *testing.T) {
+	broker, err := NewRegistryAuthBroker([]RegistryAuth{{Provider: domain.AuthProviderHarbor, Registry: "harbor.example.com", Repositories: []string{"team-a"}, Username: "robot$team-a", PasswordFile: "/secret"}})
+	require.NoError(t, err)
+	s := NewSyftAdapter(0, 0, WithRegistryAuth(broker))
+	unauthorized := &transport.Error{StatusCode: http.StatusUnauthorized}
+	tests := []struct {
+		name         string
+		image        string
+		err          error
+		credentials  []image.RegistryCredentials
+		wantProvider string
+		wantReason   string
+	}{
+		{
+			name:         "robot account rejected",
+			image:        "harbor.example.com/team-a/app:1.0",
+			err:          unauthorized,
+			wantProvider: domain.AuthProviderHarbor,
+			wantReason:   domain.AuthReasonUnauthorized,
+		},
+		{
+			name:         "robot account denied",
+			image:        "harbor.example.com/team-a/app:1.0",
+			err:          fmt.Errorf("failed to get image descriptor from registry: %w", &transport.Error{StatusCode: http.StatusForbidden}),
+			wantProvider: domain.AuthProviderHarbor,
+			wantReason:   domain.AuthReasonDenied,
+		},
+		{
+			name:         "repository outside of the robot account",
+			image:        "harbor.example.com/team-b/app:1.0",
+			err:          unauthorized,
+			credentials:  []image.RegistryCredentials{{Username: "user", Password: "password"}},
+			wantProvider: domain.AuthProviderPullSecret,
+			wantReason:   domain.AuthReasonOutOfScope,
+		},
+		{
+			name:         "default keychain",
+			image:        "private.example.com/app:1.0",
+			err:          &transport.Error{StatusCode: http.StatusUnauthorized, Errors: []transport.Diagnostic{{Code: transport.DeniedErrorCode}}},
+			wantProvider: domain.AuthProviderKeychain,
+			wantReason:   domain.AuthReasonDenied,
+		},
+		{
+			name:  "not an authentication failure",
+			image: "harbor.example.com/team-a/app:1.0",
+			err:   &transport.Error{StatusCode: http.StatusNotFound},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registryOptions := s.withRegistryAuth(context.TODO(), tt.image, image.RegistryOptions{Credentials: tt.credentials})
+			err := s.authError(tt.err, tt.image, registryOptions)
+			var authErr *domain.RegistryAuthError
+			if tt.wantReason == "" {
+				assert.False(t, errors.As(err, &authErr))
+				assert.Equal(t, tt.err, err)
+				return
+			}
+			require.ErrorAs(t, err, &authErr)
+			assert.Equal(t, tt.wantProvider, authErr.Provider)
+			assert.Equal(t, tt.wantReason, authErr.Reason)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestSyftAdapter_ResolveDigest_registryAuth(t *testing.T) {
+	// a registry only serving the robot account
+	handler := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "robot$team-a" || password != "s3cr3t" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="harbor"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	ref, err := name.ParseReference(host + "/team-a/app:1.0")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img, remote.WithAuth(&authn.Basic{Username: "robot$team-a", Password: "s3cr3t"})))
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	tests := []struct {
+		name       string
+		secret     string
+		wantReason string
+	}{
+		{
+			name:   "robot account",
+			secret: "s3cr3t",
+		},
+		{
+			name:       "wrong secret",
+			secret:     "wrong",
+			wantReason: domain.AuthReasonUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker, err := NewRegistryAuthBroker([]RegistryAuth{{Provider: domain.AuthProviderHarbor, Registry: host, Username: "robot$team-a", PasswordFile: secretFile(t, "secret", tt.secret)}})
+			require.NoError(t, err)
+			s := NewSyftAdapter(0, 0, WithRegistryAuth(broker))
+			got, err := s.ResolveDigest(context.TODO(), ref.String(), domain.RegistryOptions{InsecureUseHTTP: true})
+			if tt.wantReason != "" {
+				var authErr *domain.RegistryAuthError
+				require.ErrorAs(t, err, &authErr)
+				assert.Equal(t, tt.wantReason, authErr.Reason)
+				assert.Equal(t, domain.AuthProviderHarbor, authErr.Provider)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, host+"/team-a/app@"+digest.String(), got)
+		})
+	}
+}
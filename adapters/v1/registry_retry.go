@@ -0,0 +1,147 @@
+package v1
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/anchore/syft/syft/source"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// WithPullRetries retries the pulls failing with transient registry errors, timeouts, 5xx responses or dropped
+// connections, up to retries times per endpoint waiting backoff, doubled at each retry, in between
+func WithPullRetries(retries int, backoff time.Duration) SyftAdapterOption {
+	return func(s *SyftAdapter) {
+		s.pullRetries = retries
+		s.pullRetryBackoff = backoff
+	}
+}
+
+// WithRegistryMirrors pulls the images from the mirrors of their registry, tried in order, when the registry keeps
+// failing with transient errors or TLS errors. The mirrors are hosts optionally followed by a path prefix, such as
+// the proxy cache projects of Harbor, serving the repositories of the registry under the same names.
+func WithRegistryMirrors(mirrors map[string][]string) SyftAdapterOption {
+	return func(s *SyftAdapter) {
+		s.registryMirrors = map[string][]string{}
+		for registry, endpoints := range mirrors {
+			// docker.io is served by index.docker.io
+			if r, err := name.NewRegistry(registry); err == nil {
+				registry = r.RegistryStr()
+			}
+			s.registryMirrors[registry] = endpoints
+		}
+	}
+}
+
+// fetchWithRetries downloads the image from its registry, then from the mirrors of the registry while the errors are
+// transient, the other errors of the registry are returned as is and those of the mirrors make it try the next one
+func (s *SyftAdapter) fetchWithRetries(ctx context.Context, sourceInput *source.Input, registryOptions image.RegistryOptions, load imageLoader) error {
+	ref, err := name.ParseReference(sourceInput.UserInput, prepareReferenceOptions(registryOptions)...)
+	if err != nil {
+		return fetchFromRegistry(ctx, sourceInput, registryOptions, load)
+	}
+	registry := ref.Context().RegistryStr()
+	pullErr := &domain.RegistryPullError{}
+	for i, endpoint := range append([]string{registry}, s.registryMirrors[registry]...) {
+		input, options := sourceInput, registryOptions
+		if i > 0 {
+			mirrored := *sourceInput
+			mirrored.UserInput = mirrorReference(ref, endpoint)
+			input, options = &mirrored, s.withRegistryAuth(ctx, mirrored.UserInput, registryOptions)
+			logger.L().Debug("pulling image from mirror",
+				helpers.String("imageID", sourceInput.UserInput),
+				helpers.String("mirror", endpoint))
+		}
+		attempt := domain.PullAttempt{Endpoint: endpoint}
+		for {
+			attempt.Tries++
+			err = fetchFromRegistry(ctx, input, options, load)
+			attempt.Class = classifyPullError(err)
+			if err == nil || (i == 0 && attempt.Class == "") {
+				return err
+			}
+			// the TLS errors are not retried, the certificate of the endpoint will not change meanwhile
+			if attempt.Class == "" || attempt.Class == domain.PullErrorTLS || attempt.Tries > s.pullRetries {
+				break
+			}
+			backoff := s.pullRetryBackoff << (attempt.Tries - 1)
+			logger.L().Debug("transient registry error, retrying", helpers.Error(err),
+				helpers.String("imageID", input.UserInput),
+				helpers.String("class", attempt.Class),
+				helpers.String("backoff", backoff.String()))
+			if !sleepContext(ctx, backoff) {
+				break
+			}
+		}
+		attempt.Err = err
+		pullErr.Attempts = append(pullErr.Attempts, attempt)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return pullErr
+}
+
+// mirrorReference returns the reference of the image in the repository of the same name of the mirror
+func mirrorReference(ref name.Reference, mirror string) string {
+	separator := ":"
+	if _, ok := ref.(name.Digest); ok {
+		separator = "@"
+	}
+	return strings.TrimSuffix(mirror, "/") + "/" + ref.Context().RepositoryStr() + separator + ref.Identifier()
+}
+
+// classifyPullError returns the class of the transient registry errors, or an empty string for the others
+func classifyPullError(err error) string {
+	var transportError *transport.Error
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameError x509.HostnameError
+	var invalidCertificate x509.CertificateInvalidError
+	var verificationError *tls.CertificateVerificationError
+	var recordHeaderError tls.RecordHeaderError
+	var netError net.Error
+	var opError *net.OpError
+	switch {
+	case err == nil || errors.Is(err, context.Canceled):
+		return ""
+	case errors.As(err, &transportError):
+		if transportError.StatusCode >= http.StatusInternalServerError {
+			return domain.PullErrorServer
+		}
+		return ""
+	case errors.As(err, &unknownAuthority), errors.As(err, &hostnameError), errors.As(err, &invalidCertificate),
+		errors.As(err, &verificationError), errors.As(err, &recordHeaderError):
+		return domain.PullErrorTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netError) && netError.Timeout():
+		return domain.PullErrorTimeout
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET),
+		errors.As(err, &opError):
+		return domain.PullErrorNetwork
+	}
+	return ""
+}
+
+// sleepContext waits for the duration unless the context is done first, which it reports with false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
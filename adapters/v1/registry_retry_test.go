@@ -0,0 +1,169 @@
+package v1
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func Test_classifyPullError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "nil"},
+		{name: "not found", err: fmt.Errorf("failed to get image descriptor from registry: %w", &transport.Error{StatusCode: http.StatusNotFound})},
+		{name: "too many requests", err: &transport.Error{StatusCode: http.StatusTooManyRequests}},
+		{name: "canceled", err: fmt.Errorf("pull: %w", context.Canceled)},
+		{name: "service unavailable", err: fmt.Errorf("failed to get image descriptor from registry: %w", &transport.Error{StatusCode: http.StatusServiceUnavailable}), want: domain.PullErrorServer},
+		{name: "unknown authority", err: &url.Error{Op: "Get", URL: "https://registry.example.com/v2/", Err: x509.UnknownAuthorityError{}}, want: domain.PullErrorTLS},
+		{name: "deadline", err: context.DeadlineExceeded, want: domain.PullErrorTimeout},
+		{name: "net timeout", err: &url.Error{Op: "Get", URL: "https://registry.example.com/v2/", Err: timeoutError{}}, want: domain.PullErrorTimeout},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, want: domain.PullErrorNetwork},
+		{name: "unexpected EOF", err: fmt.Errorf("could not read image: %w", io.ErrUnexpectedEOF), want: domain.PullErrorNetwork},
+		{name: "other", err: errors.New("unable to parse registry reference")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, classifyPullError(tt.err))
+		})
+	}
+}
+
+func Test_mirrorReference(t *testing.T) {
+	tests := []struct {
+		image  string
+		mirror string
+		want   string
+	}{
+		{image: "nginx:1.25", mirror: "mirror.gcr.io", want: "mirror.gcr.io/library/nginx:1.25"},
+		{image: "quay.io/kubescape/kubevuln@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137", mirror: "harbor.example.com/quay-proxy/",
+			want: "harbor.example.com/quay-proxy/kubescape/kubevuln@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			ref, err := name.ParseReference(tt.image)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, mirrorReference(ref, tt.mirror))
+		})
+	}
+}
+
+func Test_WithRegistryMirrors(t *testing.T) {
+	s := NewSyftAdapter(time.Minute, 1<<30, WithRegistryMirrors(map[string][]string{"docker.io": {"mirror.gcr.io"}}))
+	assert.Equal(t, map[string][]string{"index.docker.io": {"mirror.gcr.io"}}, s.registryMirrors)
+}
+
+func TestSyftAdapter_CreateSBOM_pullRetries(t *testing.T) {
+	// the registry fails the manifest requests with 501, which the registry client does not retry by itself
+	var failures, manifests atomic.Int64
+	handler := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut && strings.Contains(r.URL.Path, "/manifests/") {
+			manifests.Add(1)
+			if failures.Add(-1) >= 0 {
+				w.WriteHeader(http.StatusNotImplemented)
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer origin.Close()
+	mirror := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer mirror.Close()
+	originHost := strings.TrimPrefix(origin.URL, "http://")
+	mirrorHost := strings.TrimPrefix(mirror.URL, "http://")
+	img := layersImage(t, layerTar(t, map[string]string{
+		"etc/os-release":       "ID=alpine\nVERSION_ID=3.17.3\n",
+		"lib/apk/db/installed": apkInstalled,
+	}))
+	for _, host := range []string{originHost, mirrorHost + "/proxy"} {
+		ref, err := name.ParseReference(host + "/app:v1")
+		require.NoError(t, err)
+		require.NoError(t, remote.Write(ref, img))
+	}
+	imageID := originHost + "/app:v1"
+	options := domain.RegistryOptions{InsecureUseHTTP: true}
+
+	tests := []struct {
+		name          string
+		failures      int64
+		retries       int
+		mirrors       []string
+		wantManifests int64
+		wantErr       string
+	}{
+		{
+			name:          "transient errors are retried",
+			failures:      2,
+			retries:       2,
+			wantManifests: 3,
+		},
+		{
+			name:          "the registry fails after the retries",
+			failures:      3,
+			retries:       1,
+			wantManifests: 2,
+			wantErr:       fmt.Sprintf("failed to pull image, attempted %s (server, 2 tries)", originHost),
+		},
+		{
+			name:          "the mirror serves the image",
+			failures:      100,
+			mirrors:       []string{mirrorHost + "/proxy"},
+			wantManifests: 1,
+		},
+		{
+			name:          "the endpoints attempted are reported",
+			failures:      100,
+			mirrors:       []string{mirrorHost + "/missing", mirrorHost + "/proxy-down"},
+			wantManifests: 1,
+			wantErr: fmt.Sprintf("failed to pull image, attempted %s (server), %s/missing (error), %s/proxy-down (error)",
+				originHost, mirrorHost, mirrorHost),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failures.Store(tt.failures)
+			manifests.Store(0)
+			s := NewSyftAdapter(time.Minute, 1<<30, WithPullRetries(tt.retries, time.Millisecond),
+				WithRegistryMirrors(map[string][]string{originHost: tt.mirrors}))
+			sbom, err := s.CreateSBOM(context.TODO(), "app", imageID, options)
+			assert.Equal(t, tt.wantManifests, manifests.Load())
+			if tt.wantErr != "" {
+				var pullErr *domain.RegistryPullError
+				require.ErrorAs(t, err, &pullErr)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []string{"musl@1.2.3-r4"}, sbomPackages(sbom))
+		})
+	}
+}
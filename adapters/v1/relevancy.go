@@ -0,0 +1,173 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"go.opentelemetry.io/otel"
+)
+
+// relevancy providers selectable in the configuration
+const (
+	RelevancyApplicationProfile = "applicationProfile"
+	RelevancyFile               = "file"
+	RelevancyNone               = "none"
+)
+
+// ApplicationProfileRelevancy implements RelevancyProvider from ports by reading the relevant SBOMs the node-agent
+// stores in the storage from the application profiles of the workload instances
+type ApplicationProfileRelevancy struct {
+	repository         ports.SBOMRepository
+	sbomCreatorVersion string
+}
+
+var _ ports.RelevancyProvider = (*ApplicationProfileRelevancy)(nil)
+
+// NewApplicationProfileRelevancy initializes the ApplicationProfileRelevancy struct
+func NewApplicationProfileRelevancy(repository ports.SBOMRepository, sbomCreatorVersion string) *ApplicationProfileRelevancy {
+	return &ApplicationProfileRelevancy{repository: repository, sbomCreatorVersion: sbomCreatorVersion}
+}
+
+// GetRelevantSBOM returns the relevant SBOM stored under the instanceID of the workload
+func (a *ApplicationProfileRelevancy) GetRelevantSBOM(ctx context.Context, workload domain.ScanCommand, _ domain.SBOM) (domain.SBOM, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ApplicationProfileRelevancy.GetRelevantSBOM")
+	defer span.End()
+	return a.repository.GetSBOMp(ctx, workload.InstanceID, a.sbomCreatorVersion)
+}
+
+// relevantFiles lists the files used at runtime by the containers matching its non-empty selectors
+type relevantFiles struct {
+	Wlid          string   `json:"wlid"`
+	ContainerName string   `json:"containerName"`
+	ImageSlug     string   `json:"imageSlug"`
+	Files         []string `json:"files"`
+}
+
+func (r relevantFiles) matches(workload domain.ScanCommand) bool {
+	return (r.Wlid == "" || r.Wlid == workload.Wlid) &&
+		(r.ContainerName == "" || r.ContainerName == workload.ContainerName) &&
+		(r.ImageSlug == "" || r.ImageSlug == workload.ImageSlug)
+}
+
+// FileRelevancy implements RelevancyProvider from ports by restricting the image SBOMs to the packages of the files
+// listed in a static file, for runtime observability sources exporting the files used by each container
+type FileRelevancy struct {
+	entries            []relevantFiles
+	repository         ports.SBOMRepository
+	sbomCreatorVersion string
+}
+
+var _ ports.RelevancyProvider = (*FileRelevancy)(nil)
+
+// NewFileRelevancy initializes the FileRelevancy struct from a JSON list of entries selecting containers by wlid,
+// containerName and imageSlug with their files, repository is used to read the image SBOMs not at hand and may be nil
+func NewFileRelevancy(path string, repository ports.SBOMRepository, sbomCreatorVersion string) (*FileRelevancy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []relevantFiles
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse relevancy file %s: %w", path, err)
+	}
+	return &FileRelevancy{entries: entries, repository: repository, sbomCreatorVersion: sbomCreatorVersion}, nil
+}
+
+// GetRelevantSBOM restricts the image SBOM to the packages of the files listed by the first matching entry
+func (f *FileRelevancy) GetRelevantSBOM(ctx context.Context, workload domain.ScanCommand, sbom domain.SBOM) (domain.SBOM, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "FileRelevancy.GetRelevantSBOM")
+	defer span.End()
+
+	var files []string
+	found := false
+	for _, entry := range f.entries {
+		if entry.matches(workload) {
+			files, found = entry.Files, true
+			break
+		}
+	}
+	if !found {
+		return domain.SBOM{}, nil
+	}
+	if sbom.Content == nil && f.repository != nil {
+		var err error
+		sbom, err = f.repository.GetSBOM(ctx, workload.ImageSlug, f.sbomCreatorVersion)
+		if err != nil {
+			return domain.SBOM{}, err
+		}
+	}
+	if sbom.Content == nil {
+		return domain.SBOM{}, nil
+	}
+	return domain.SBOM{
+		Name:               workload.InstanceID,
+		SBOMCreatorName:    sbom.SBOMCreatorName,
+		SBOMCreatorVersion: sbom.SBOMCreatorVersion,
+		Status:             sbom.Status,
+		Content:            filterDocument(sbom.Content, files),
+		Annotations:        sbom.Annotations,
+		Labels:             sbom.Labels,
+	}, nil
+}
+
+// filterDocument returns a copy of the SPDX document restricted to the given files and the packages containing them
+func filterDocument(document *v1beta1.Document, files []string) *v1beta1.Document {
+	relevant := map[string]bool{}
+	for _, file := range files {
+		relevant[file] = true
+	}
+	kept := map[v1beta1.ElementID]bool{}
+	removed := map[v1beta1.ElementID]bool{}
+	filtered := *document
+	filtered.Files = nil
+	for _, file := range document.Files {
+		if relevant[file.FileName] {
+			kept[file.FileSPDXIdentifier] = true
+			filtered.Files = append(filtered.Files, file)
+		} else {
+			removed[file.FileSPDXIdentifier] = true
+		}
+	}
+	// packages are kept when they contain a relevant file
+	containing := map[v1beta1.ElementID]bool{}
+	for _, relationship := range document.Relationships {
+		if relationship.Relationship == "CONTAINS" && kept[relationship.RefB.ElementRefID] {
+			containing[relationship.RefA.ElementRefID] = true
+		}
+	}
+	filtered.Packages = nil
+	for _, p := range document.Packages {
+		if containing[p.PackageSPDXIdentifier] {
+			filtered.Packages = append(filtered.Packages, p)
+		} else {
+			removed[p.PackageSPDXIdentifier] = true
+		}
+	}
+	filtered.Relationships = nil
+	for _, relationship := range document.Relationships {
+		if !removed[relationship.RefA.ElementRefID] && !removed[relationship.RefB.ElementRefID] {
+			filtered.Relationships = append(filtered.Relationships, relationship)
+		}
+	}
+	return &filtered
+}
+
+// NoRelevancy implements RelevancyProvider from ports without any relevancy data, only the whole images are scanned
+type NoRelevancy struct{}
+
+var _ ports.RelevancyProvider = (*NoRelevancy)(nil)
+
+// NewNoRelevancy initializes the NoRelevancy struct
+func NewNoRelevancy() *NoRelevancy {
+	return &NoRelevancy{}
+}
+
+// GetRelevantSBOM always returns an empty SBOM
+func (n *NoRelevancy) GetRelevantSBOM(context.Context, domain.ScanCommand, domain.SBOM) (domain.SBOM, error) {
+	return domain.SBOM{}, nil
+}
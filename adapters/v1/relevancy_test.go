@@ -0,0 +1,114 @@
+package v1
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplicationProfileRelevancy_GetRelevantSBOM(t *testing.T) {
+	storage := repositories.NewMemoryStorage(false, false)
+	sbomp := domain.SBOM{Name: "instanceID", SBOMCreatorVersion: "v0.76.0", Content: fileToSBOM("testdata/nginx-filtered-sbom.json")}
+	require.NoError(t, storage.StoreSBOM(context.TODO(), sbomp))
+	a := NewApplicationProfileRelevancy(storage, "v0.76.0")
+	got, err := a.GetRelevantSBOM(context.TODO(), domain.ScanCommand{InstanceID: "instanceID"}, domain.SBOM{})
+	require.NoError(t, err)
+	assert.Equal(t, sbomp.Content, got.Content)
+	got, err = a.GetRelevantSBOM(context.TODO(), domain.ScanCommand{InstanceID: "unknown"}, domain.SBOM{})
+	require.NoError(t, err)
+	assert.Nil(t, got.Content)
+}
+
+func TestFileRelevancy_GetRelevantSBOM(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relevancy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"wlid": "wlid://cluster-minikube/namespace-default/deployment-nginx", "containerName": "nginx", "files": ["/bin/bash", "/usr/sbin/nginx"]},
+		{"imageSlug": "redis-slug", "files": []}
+	]`), 0600))
+	sbom := domain.SBOM{Name: "nginx-slug", SBOMCreatorVersion: "v0.76.0", Content: fileToSBOM("testdata/nginx-sbom.json")}
+	storage := repositories.NewMemoryStorage(false, false)
+	require.NoError(t, storage.StoreSBOM(context.TODO(), sbom))
+	f, err := NewFileRelevancy(path, storage, "v0.76.0")
+	require.NoError(t, err)
+
+	nginx := domain.ScanCommand{
+		InstanceID:    "instanceID",
+		ImageSlug:     "nginx-slug",
+		Wlid:          "wlid://cluster-minikube/namespace-default/deployment-nginx",
+		ContainerName: "nginx",
+	}
+	tests := []struct {
+		name         string
+		workload     domain.ScanCommand
+		sbom         domain.SBOM
+		wantNone     bool
+		wantPackages []string
+		wantFiles    int
+	}{
+		{
+			name:         "image SBOM at hand",
+			workload:     nginx,
+			sbom:         sbom,
+			wantPackages: []string{"bash", "nginx"},
+			wantFiles:    2,
+		},
+		{
+			name:         "image SBOM from storage",
+			workload:     nginx,
+			wantPackages: []string{"bash", "nginx"},
+			wantFiles:    2,
+		},
+		{
+			name:     "other container",
+			workload: domain.ScanCommand{InstanceID: "instanceID", ImageSlug: "nginx-slug", Wlid: nginx.Wlid, ContainerName: "sidecar"},
+			sbom:     sbom,
+			wantNone: true,
+		},
+		{
+			name:     "no file used",
+			workload: domain.ScanCommand{InstanceID: "instanceID", ImageSlug: "redis-slug"},
+			sbom:     sbom,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.GetRelevantSBOM(context.TODO(), tt.workload, tt.sbom)
+			require.NoError(t, err)
+			if tt.wantNone {
+				assert.Nil(t, got.Content)
+				return
+			}
+			require.NotNil(t, got.Content)
+			assert.Equal(t, tt.workload.InstanceID, got.Name)
+			var packages []string
+			for _, p := range got.Content.Packages {
+				packages = append(packages, p.PackageName)
+			}
+			assert.ElementsMatch(t, tt.wantPackages, packages)
+			assert.Len(t, got.Content.Files, tt.wantFiles)
+			// the image SBOM is left untouched
+			assert.Len(t, sbom.Content.Packages, 109)
+		})
+	}
+}
+
+func TestNewFileRelevancy(t *testing.T) {
+	_, err := NewFileRelevancy(filepath.Join(t.TempDir(), "missing.json"), nil, "")
+	assert.Error(t, err)
+	path := filepath.Join(t.TempDir(), "relevancy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"files": []}`), 0600))
+	_, err = NewFileRelevancy(path, nil, "")
+	assert.ErrorContains(t, err, "failed to parse relevancy file")
+}
+
+func TestNoRelevancy_GetRelevantSBOM(t *testing.T) {
+	got, err := NewNoRelevancy().GetRelevantSBOM(context.TODO(), domain.ScanCommand{InstanceID: "instanceID"}, domain.SBOM{Content: fileToSBOM("testdata/nginx-sbom.json")})
+	require.NoError(t, err)
+	assert.Equal(t, domain.SBOM{}, got)
+}
@@ -0,0 +1,182 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/anchore/syft/syft/artifact"
+	"github.com/anchore/syft/syft/linux"
+	"github.com/anchore/syft/syft/pkg/cataloger"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+	"github.com/eapache/go-resiliency/deadline"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"go.opentelemetry.io/otel"
+)
+
+// gitProtocols are the transports git may use to clone the repositories, neither the local nor the ext ones
+const gitProtocols = "https"
+
+var _ ports.RepositorySBOMCreator = (*SyftAdapter)(nil)
+
+// CreateRepositorySBOM clones a git repository at a ref, shallowly and without its submodules, within the pull
+// timeout budget, and catalogs the packages of its manifests and lockfiles with Syft's directory catalogers
+func (s *SyftAdapter) CreateRepositorySBOM(ctx context.Context, name string, repository domain.Repository) (domain.SBOM, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "SyftAdapter.CreateRepositorySBOM")
+	defer span.End()
+	domainSBOM := domain.SBOM{
+		Name:               name,
+		SBOMCreatorVersion: s.Version(),
+		Annotations: map[string]string{
+			domain.AnnotationArtifactType:  domain.ArtifactTypeRepository,
+			domain.AnnotationRepositoryURL: repository.URL,
+		},
+	}
+	if repository.Ref != "" {
+		domainSBOM.Annotations[domain.AnnotationRepositoryRef] = repository.Ref
+	}
+	t, err := newWorkspace(s.workDir)
+	if err != nil {
+		return domainSBOM, fmt.Errorf("failed to create scan workspace: %w", err)
+	}
+	defer func(t *workspace) {
+		err := t.Cleanup()
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to cleanup scan workspace", helpers.Error(err),
+				helpers.String("repository", repository.URL))
+		}
+	}(t)
+	dir, err := t.NewDirectory("repository")
+	if err != nil {
+		return domainSBOM, err
+	}
+	commit, err := tools.RunWithTimeout(ctx, domain.StagePull, s.pullTimeout, func(ctx context.Context) (string, error) {
+		return s.cloneRepository(ctx, dir, repository)
+	})
+	if err != nil {
+		return domainSBOM, domain.Categorize(domain.ErrImagePull, err)
+	}
+	domainSBOM.Annotations[domain.AnnotationRepositoryCommit] = commit
+	// the history is not cataloged
+	if err := os.RemoveAll(filepath.Join(dir, ".git")); err != nil {
+		return domainSBOM, err
+	}
+	var syftSBOM sbom.SBOM
+	err = deadline.New(s.scanTimeout).Run(func(stopper <-chan struct{}) (err error) {
+		defer tools.RecoverPanic(ctx, &err)
+		syftSBOM, err = s.catalogDirectory(dir, repository.URL)
+		return err
+	})
+	switch err {
+	case deadline.ErrTimedOut:
+		logger.L().Ctx(ctx).Warning("Syft timed out",
+			helpers.String("repository", repository.URL))
+		domainSBOM.Status = instanceidhandler.Incomplete
+		return domainSBOM, nil
+	case nil:
+	default:
+		domainSBOM.Status = instanceidhandler.Incomplete
+		return domainSBOM, domain.Categorize(domain.ErrSBOM, err)
+	}
+	domainSBOM.Content, err = s.syftToDomain(syftSBOM)
+	return domainSBOM, domain.Categorize(domain.ErrSBOM, err)
+}
+
+// cloneRepository fetches the ref of the repository, its default branch when empty, into dir and returns the commit
+// checked out; the credentials are passed through the environment so that they appear neither in the process list
+// nor in the errors
+func (s *SyftAdapter) cloneRepository(ctx context.Context, dir string, repository domain.Repository) (string, error) {
+	protocols := s.gitProtocols
+	if protocols == "" {
+		protocols = gitProtocols
+	}
+	env := []string{
+		"HOME=" + dir,
+		"GIT_ALLOW_PROTOCOL=" + protocols,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_TERMINAL_PROMPT=0",
+	}
+	if repository.Username != "" || repository.Password != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(repository.Username + ":" + repository.Password))
+		env = append(env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+credentials)
+	}
+	git := func(args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = dir
+		cmd.Env = env
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+		}
+		return strings.TrimSpace(stdout.String()), nil
+	}
+	ref := repository.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	logger.L().Debug("cloning repository",
+		helpers.String("repository", repository.URL),
+		helpers.String("ref", ref))
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"fetch", "--quiet", "--depth", "1", "--no-tags", "--no-recurse-submodules", "--", repository.URL, ref},
+		{"checkout", "--quiet", "FETCH_HEAD"},
+	} {
+		if _, err := git(args...); err != nil {
+			return "", err
+		}
+	}
+	return git("rev-parse", "HEAD")
+}
+
+// catalogDirectory catalogs the packages of the manifests and lockfiles of a directory
+func (s *SyftAdapter) catalogDirectory(dir, name string) (sbom.SBOM, error) {
+	src, err := source.NewFromDirectoryWithName(dir, name)
+	if err != nil {
+		return sbom.SBOM{}, err
+	}
+	cfg := catalogConfig()
+	catalogers, err := s.directoryCatalogers(cfg)
+	if err != nil {
+		return sbom.SBOM{}, err
+	}
+	resolver, err := src.FileResolver(cfg.Search.Scope)
+	if err != nil {
+		return sbom.SBOM{}, fmt.Errorf("unable to determine resolver while cataloging packages: %w", err)
+	}
+	catalog, relationships, err := cataloger.Catalog(resolver, nil, cfg.Parallelism, catalogers...)
+	if err != nil {
+		return sbom.SBOM{}, err
+	}
+	for p := range catalog.Enumerate() {
+		relationships = append(relationships, artifact.Relationship{
+			From: &src,
+			To:   p,
+			Type: artifact.ContainsRelationship,
+		})
+	}
+	return sbom.SBOM{
+		Source:        src.Metadata,
+		Relationships: relationships,
+		Artifacts: sbom.Artifacts{
+			PackageCatalog:    catalog,
+			LinuxDistribution: (*linux.Release)(nil),
+		},
+	}, nil
+}
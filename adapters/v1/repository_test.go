@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gitRepository commits the files into a new repository and returns its path
+func gitRepository(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for file, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0644))
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch", "main"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "initial"},
+		{"branch", "release"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	return dir
+}
+
+func TestSyftAdapter_CreateRepositorySBOM(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := gitRepository(t, map[string]string{
+		"README.md": "# app\n",
+		"web/package-lock.json": `{
+  "name": "web",
+  "version": "1.0.0",
+  "lockfileVersion": 2,
+  "packages": {
+    "": {"name": "web", "version": "1.0.0"},
+    "node_modules/lodash": {"version": "4.17.20", "resolved": "https://registry.npmjs.org/lodash/-/lodash-4.17.20.tgz"}
+  }
+}
+`,
+	})
+	ctx := context.TODO()
+	s := NewSyftAdapter(time.Minute, 1<<20)
+	s.gitProtocols = "file"
+	url := "file://" + dir
+
+	tests := []struct {
+		name       string
+		repository domain.Repository
+		wantErr    bool
+	}{
+		{
+			name:       "default branch",
+			repository: domain.Repository{URL: url},
+		},
+		{
+			name:       "branch",
+			repository: domain.Repository{URL: url, Ref: "release"},
+		},
+		{
+			name:       "unknown ref",
+			repository: domain.Repository{URL: url, Ref: "unknown"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sbom, err := s.CreateRepositorySBOM(ctx, "repository", tt.repository)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "git fetch failed")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, domain.ArtifactTypeRepository, sbom.Annotations[domain.AnnotationArtifactType])
+			assert.Equal(t, url, sbom.Annotations[domain.AnnotationRepositoryURL])
+			assert.Equal(t, tt.repository.Ref, sbom.Annotations[domain.AnnotationRepositoryRef])
+			assert.Len(t, sbom.Annotations[domain.AnnotationRepositoryCommit], 40)
+			require.NotNil(t, sbom.Content)
+			var packages []string
+			for _, p := range sbom.Content.Packages {
+				packages = append(packages, p.PackageName+"@"+p.PackageVersion)
+			}
+			sort.Strings(packages)
+			assert.Equal(t, []string{"lodash@4.17.20", "web@1.0.0"}, packages)
+		})
+	}
+
+	// the local transports are refused by default
+	_, err := NewSyftAdapter(time.Minute, 1<<20).CreateRepositorySBOM(ctx, "repository", domain.Repository{URL: url})
+	assert.ErrorContains(t, err, "git fetch failed")
+}
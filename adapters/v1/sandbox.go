@@ -0,0 +1,239 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/anchore/syft/syft/formats/syftjson"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+)
+
+const (
+	// sandboxExitImageTooLarge is the exit code of the extractor for images exceeding the size limit
+	sandboxExitImageTooLarge = 3
+	// sandboxStderrLimit caps the extractor output kept to report its failures
+	sandboxStderrLimit = 4096
+)
+
+// extraction is the output of the extractor
+type extraction struct {
+	Annotations map[string]string `json:"annotations"`
+	SBOM        json.RawMessage   `json:"sbom"`
+}
+
+// WithExtractionSandbox extracts and catalogs the image layers in a child process running the given extractor binary,
+// without network access nor dangerous syscalls, so that archive parsing bugs cannot compromise the scanner;
+// the scanner only downloads the image blobs
+func WithExtractionSandbox(binary string) SyftAdapterOption {
+	return func(s *SyftAdapter) {
+		s.sandboxBinary = binary
+	}
+}
+
+// writeLayout saves the image blobs into an OCI layout inside the workspace, without reading the layers,
+// and returns it with the repo digest found in the metadata
+func writeLayout(t *workspace, img containerregistryV1.Image, metadata []image.AdditionalMetadata) (string, string, error) {
+	dir, err := t.NewDirectory("oci-layout")
+	if err != nil {
+		return "", "", err
+	}
+	p, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		return "", "", err
+	}
+	if err := p.AppendImage(img); err != nil {
+		return "", "", fmt.Errorf("failed to download image: %w", err)
+	}
+	var repoDigest string
+	probe := &image.Image{}
+	for _, m := range metadata {
+		_ = m(probe)
+	}
+	if len(probe.Metadata.RepoDigests) > 0 {
+		repoDigest = probe.Metadata.RepoDigests[0]
+	}
+	return dir, repoDigest, nil
+}
+
+// extractInSandbox runs the extractor on the OCI layout and decodes the SBOM it produces
+func (s *SyftAdapter) extractInSandbox(ctx context.Context, t *workspace, layoutDir, imageID, repoDigest string) (sbom.SBOM, map[string]string, error) {
+	args := []string{
+		"-layout", layoutDir,
+		"-image", imageID,
+		"-max-image-size", strconv.FormatInt(s.maxImageSize, 10),
+	}
+	if repoDigest != "" {
+		args = append(args, "-repo-digest", repoDigest)
+	}
+	if s.scanProfile != "" {
+		args = append(args, "-scan-profile", s.scanProfile)
+	}
+	if s.embeddedImagesDepth > 0 {
+		args = append(args, "-embedded-images-depth", strconv.Itoa(s.embeddedImagesDepth))
+	}
+	if s.catalogerRetries > 0 {
+		args = append(args, "-cataloger-retries", strconv.Itoa(s.catalogerRetries))
+	}
+	ecosystems := make([]string, 0, len(s.catalogerModes))
+	for ecosystem := range s.catalogerModes {
+		ecosystems = append(ecosystems, ecosystem)
+	}
+	sort.Strings(ecosystems)
+	for _, ecosystem := range ecosystems {
+		args = append(args, "-cataloger-mode", ecosystem+"="+s.catalogerModes[ecosystem])
+	}
+	for _, ecosystem := range s.ecosystems {
+		args = append(args, "-ecosystem", ecosystem)
+	}
+	cmd := exec.CommandContext(ctx, s.sandboxBinary, args...)
+	// the environment holds credentials, the extractor only gets a temporary directory inside the workspace
+	cmd.Env = []string{"TMPDIR=" + t.root}
+	cmd.Dir = t.root
+	cmd.SysProcAttr = sandboxProcAttr()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == sandboxExitImageTooLarge:
+		return sbom.SBOM{}, nil, ErrImageTooLarge
+	case err != nil:
+		output := strings.TrimSpace(stderr.String())
+		if len(output) > sandboxStderrLimit {
+			output = output[len(output)-sandboxStderrLimit:]
+		}
+		return sbom.SBOM{}, nil, fmt.Errorf("extraction sandbox failed: %w: %s", err, output)
+	}
+	var result extraction
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return sbom.SBOM{}, nil, fmt.Errorf("failed to decode extraction: %w", err)
+	}
+	syftSBOM, err := syftjson.Format().Decode(bytes.NewReader(result.SBOM))
+	if err != nil {
+		return sbom.SBOM{}, nil, fmt.Errorf("failed to decode extracted SBOM: %w", err)
+	}
+	return *syftSBOM, result.Annotations, nil
+}
+
+// RunExtractor is the entrypoint of the extractor binary: it restricts its own process, reads the image
+// of an OCI layout, catalogs its packages and writes the extraction to stdout, it returns the exit code
+func RunExtractor(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("extractor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	layoutDir := flags.String("layout", "", "OCI layout holding the image")
+	imageID := flags.String("image", "", "image reference, used to name the SBOM source")
+	repoDigest := flags.String("repo-digest", "", "repo digest of the image")
+	maxImageSize := flags.Int64("max-image-size", 0, "maximum uncompressed size of the image, in bytes")
+	scanProfile := flags.String("scan-profile", "", "scan profile, \"full\" or \"fast\"")
+	embeddedImagesDepth := flags.Int("embedded-images-depth", 0, "levels of embedded images scanned")
+	catalogerRetries := flags.Int("cataloger-retries", 0, "retries of the failed catalogers")
+	modes := map[string]string{}
+	flags.Func("cataloger-mode", "cataloging mode of an ecosystem, as ecosystem=mode", func(value string) error {
+		ecosystem, mode, ok := strings.Cut(value, "=")
+		if !ok {
+			return fmt.Errorf("expected ecosystem=mode, got %q", value)
+		}
+		modes[ecosystem] = mode
+		return nil
+	})
+	var ecosystems []string
+	flags.Func("ecosystem", "optional ecosystem cataloged, repeated for each ecosystem", func(value string) error {
+		ecosystems = append(ecosystems, value)
+		return nil
+	})
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	// the extractor only reads the layout and works in its own workspace, created before restricting the process
+	t, err := newWorkspace("")
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer func() { _ = t.Cleanup() }()
+	if err := RestrictProcess(*layoutDir, t.root); err != nil {
+		fmt.Fprintf(stderr, "failed to restrict the extractor process: %v\n", err)
+		return 1
+	}
+	result, err := extract(t, *layoutDir, *imageID, *repoDigest, *maxImageSize, *scanProfile, *embeddedImagesDepth, *catalogerRetries, modes, ecosystems)
+	if errors.Is(err, ErrImageTooLarge) {
+		return sandboxExitImageTooLarge
+	}
+	if err == nil {
+		err = json.NewEncoder(stdout).Encode(result)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// extract reads the image of the OCI layout into the workspace and catalogs its packages
+func extract(t *workspace, layoutDir, imageID, repoDigest string, maxImageSize int64, scanProfile string, embeddedImagesDepth, catalogerRetries int, modes map[string]string, ecosystems []string) (extraction, error) {
+	p, err := layout.FromPath(layoutDir)
+	if err != nil {
+		return extraction{}, err
+	}
+	index, err := p.ImageIndex()
+	if err != nil {
+		return extraction{}, err
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return extraction{}, err
+	}
+	if len(manifest.Manifests) != 1 {
+		return extraction{}, fmt.Errorf("expected a single image in the layout, got %d", len(manifest.Manifests))
+	}
+	img, err := p.Image(manifest.Manifests[0].Digest)
+	if err != nil {
+		return extraction{}, err
+	}
+	if scanProfile == ScanProfileFast {
+		img = withOSPackageFiles(context.Background(), img, maxImageSize)
+	}
+	var metadata []image.AdditionalMetadata
+	if repoDigest != "" {
+		metadata = append(metadata, image.WithRepoDigests(repoDigest))
+	}
+	if manifestBytes, err := img.RawManifest(); err == nil {
+		metadata = append(metadata, image.WithManifest(manifestBytes))
+	}
+	sourceInput, err := source.ParseInput(imageID, "")
+	if err != nil {
+		return extraction{}, err
+	}
+	src, err := newFromImage(t, sourceInput, img, metadata, maxImageSize)
+	if err != nil {
+		return extraction{}, err
+	}
+	s := NewSyftAdapter(0, maxImageSize, WithCatalogerModes(modes), WithScanProfile(scanProfile), WithEmbeddedImages(embeddedImagesDepth), WithCatalogerRetries(catalogerRetries), WithEcosystems(ecosystems))
+	syftSBOM, annotations, err := s.extractSBOM(context.Background(), src)
+	if err != nil {
+		return extraction{}, err
+	}
+	for key, value := range imageProvenance(src) {
+		annotations[key] = value
+	}
+	var buf bytes.Buffer
+	if err := syftjson.Format().Encode(&buf, syftSBOM); err != nil {
+		return extraction{}, err
+	}
+	return extraction{Annotations: annotations, SBOM: buf.Bytes()}, nil
+}
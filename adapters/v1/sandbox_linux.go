@@ -0,0 +1,212 @@
+//go:build linux && (amd64 || arm64)
+
+package v1
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+)
+
+// seccomp filter return values and flags, see linux/seccomp.h
+const (
+	seccompRetKillProcess = 0x80000000
+	seccompRetErrno       = 0x00050000
+	seccompRetAllow       = 0x7fff0000
+	seccompSetModeFilter  = 1
+	seccompFlagTsync      = 1
+	// offsets of the syscall number, architecture and first argument in struct seccomp_data
+	seccompDataNr   = 0
+	seccompDataArch = 4
+	seccompDataArg0 = 16
+	// x32SyscallBit flags the syscalls of the x32 ABI, which would bypass the amd64 numbers
+	x32SyscallBit = 0x40000000
+)
+
+// deniedSyscalls are the syscalls the extractor never needs: networking, new programs,
+// mounts and namespaces, kernel modules and keys, and access to other processes
+var deniedSyscalls = []uint32{
+	unix.SYS_SOCKET,
+	unix.SYS_SOCKETPAIR,
+	unix.SYS_CONNECT,
+	unix.SYS_BIND,
+	unix.SYS_LISTEN,
+	unix.SYS_ACCEPT,
+	unix.SYS_ACCEPT4,
+	unix.SYS_EXECVE,
+	unix.SYS_EXECVEAT,
+	unix.SYS_PTRACE,
+	unix.SYS_PROCESS_VM_READV,
+	unix.SYS_PROCESS_VM_WRITEV,
+	unix.SYS_MOUNT,
+	unix.SYS_UMOUNT2,
+	unix.SYS_PIVOT_ROOT,
+	unix.SYS_CHROOT,
+	unix.SYS_UNSHARE,
+	unix.SYS_SETNS,
+	unix.SYS_KEXEC_LOAD,
+	unix.SYS_INIT_MODULE,
+	unix.SYS_FINIT_MODULE,
+	unix.SYS_DELETE_MODULE,
+	unix.SYS_BPF,
+	unix.SYS_PERF_EVENT_OPEN,
+	unix.SYS_USERFAULTFD,
+	unix.SYS_KEYCTL,
+	unix.SYS_ADD_KEY,
+	unix.SYS_REQUEST_KEY,
+	unix.SYS_IO_URING_SETUP,
+	unix.SYS_IO_URING_ENTER,
+	unix.SYS_IO_URING_REGISTER,
+}
+
+// cloneNamespaceFlags are the clone flags creating namespaces, clone3 cannot be inspected and reports ENOSYS
+// so that callers fall back to clone
+const cloneNamespaceFlags = unix.CLONE_NEWNS | unix.CLONE_NEWCGROUP | unix.CLONE_NEWUTS | unix.CLONE_NEWIPC |
+	unix.CLONE_NEWUSER | unix.CLONE_NEWPID | unix.CLONE_NEWNET
+
+// landlock access rights of the layout, read only, and of the extractor workspace, see linux/landlock.h
+const (
+	landlockReadAccess  = unix.LANDLOCK_ACCESS_FS_READ_FILE | unix.LANDLOCK_ACCESS_FS_READ_DIR
+	landlockWriteAccess = landlockReadAccess | unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE | unix.LANDLOCK_ACCESS_FS_MAKE_DIR | unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM | unix.LANDLOCK_ACCESS_FS_REFER | unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	// landlockAccessV1 are all the rights of the first landlock ABI, the later ones are handled when supported
+	landlockAccessV1 = 1<<13 - 1
+)
+
+// auditArch is the seccomp architecture of the running binary
+var auditArch = map[string]uint32{
+	"amd64": unix.AUDIT_ARCH_X86_64,
+	"arm64": unix.AUDIT_ARCH_AARCH64,
+}[runtime.GOARCH]
+
+// RestrictProcess forbids the current process, and every program it could start, to gain privileges,
+// limits its filesystem access to reading layoutDir and working in workDir and installs a seccomp filter
+// denying the syscalls the extractor does not need
+func RestrictProcess(layoutDir, workDir string) error {
+	filter, err := seccompFilter()
+	if err != nil {
+		return err
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+	if err := restrictFilesystem(map[string]uint64{layoutDir: landlockReadAccess, workDir: landlockWriteAccess}); err != nil {
+		return err
+	}
+	prog := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+	// the filter is synchronized on every thread of the Go runtime
+	if _, _, errno := syscall.RawSyscall(unix.SYS_SECCOMP, seccompSetModeFilter, seccompFlagTsync, uintptr(unsafe.Pointer(&prog))); errno != 0 {
+		return fmt.Errorf("failed to install seccomp filter: %w", errno)
+	}
+	runtime.KeepAlive(filter)
+	return nil
+}
+
+// restrictFilesystem denies, with landlock, every filesystem access but the given rights beneath the given paths,
+// kernels without landlock (before 5.13) and binaries built with cgo are left unrestricted
+func restrictFilesystem(paths map[string]uint64) error {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno == unix.ENOSYS || errno == unix.EOPNOTSUPP {
+		return nil
+	}
+	if errno != 0 {
+		return fmt.Errorf("failed to get landlock version: %w", errno)
+	}
+	handled := uint64(landlockAccessV1)
+	if abi >= 2 {
+		handled |= unix.LANDLOCK_ACCESS_FS_REFER
+	}
+	if abi >= 3 {
+		handled |= unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	}
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	ruleset, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to create landlock ruleset: %w", errno)
+	}
+	defer unix.Close(int(ruleset))
+	for path, access := range paths {
+		// the rights of a rule must be handled by the ruleset
+		if err := addLandlockRule(int(ruleset), path, access&handled); err != nil {
+			return err
+		}
+	}
+	// landlock restricts the calling thread only, every thread of the Go runtime is restricted, which cgo prevents
+	_, _, errno = syscall.AllThreadsSyscall(unix.SYS_LANDLOCK_RESTRICT_SELF, ruleset, 0, 0)
+	if errno == unix.ENOTSUP {
+		return nil
+	}
+	if errno != 0 {
+		return fmt.Errorf("failed to enforce landlock ruleset: %w", errno)
+	}
+	return nil
+}
+
+// addLandlockRule grants the access rights beneath path
+func addLandlockRule(ruleset int, path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer unix.Close(fd)
+	rule := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(fd)}
+	if _, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(ruleset), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("failed to add landlock rule for %s: %w", path, errno)
+	}
+	return nil
+}
+
+// seccompFilter assembles the seccomp program: foreign architectures kill the process, denied syscalls and clones
+// into new namespaces fail with EPERM, clone3 fails with ENOSYS and everything else is allowed
+func seccompFilter() ([]unix.SockFilter, error) {
+	instructions := []bpf.Instruction{
+		bpf.LoadAbsolute{Off: seccompDataArch, Size: 4},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: auditArch, SkipTrue: 1},
+		bpf.RetConstant{Val: seccompRetKillProcess},
+		bpf.LoadAbsolute{Off: seccompDataNr, Size: 4},
+	}
+	if runtime.GOARCH == "amd64" {
+		instructions = append(instructions,
+			bpf.JumpIf{Cond: bpf.JumpGreaterOrEqual, Val: x32SyscallBit, SkipFalse: 1},
+			bpf.RetConstant{Val: seccompRetErrno | uint32(unix.EPERM)},
+		)
+	}
+	for _, nr := range deniedSyscalls {
+		instructions = append(instructions,
+			bpf.JumpIf{Cond: bpf.JumpEqual, Val: nr, SkipFalse: 1},
+			bpf.RetConstant{Val: seccompRetErrno | uint32(unix.EPERM)},
+		)
+	}
+	instructions = append(instructions,
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: unix.SYS_CLONE3, SkipFalse: 1},
+		bpf.RetConstant{Val: seccompRetErrno | uint32(unix.ENOSYS)},
+		// the clone flags are its first argument on amd64 and arm64, only their lower half holds namespaces
+		bpf.JumpIf{Cond: bpf.JumpNotEqual, Val: unix.SYS_CLONE, SkipTrue: 3},
+		bpf.LoadAbsolute{Off: seccompDataArg0, Size: 4},
+		bpf.JumpIf{Cond: bpf.JumpBitsSet, Val: cloneNamespaceFlags, SkipFalse: 1},
+		bpf.RetConstant{Val: seccompRetErrno | uint32(unix.EPERM)},
+		bpf.RetConstant{Val: seccompRetAllow},
+	)
+	raw, err := bpf.Assemble(instructions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assemble seccomp filter: %w", err)
+	}
+	filter := make([]unix.SockFilter, len(raw))
+	for i, r := range raw {
+		filter[i] = unix.SockFilter{Code: r.Op, Jt: r.Jt, Jf: r.Jf, K: r.K}
+	}
+	return filter, nil
+}
+
+// sandboxProcAttr kills the extractor when the scanner dies
+func sandboxProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+}
@@ -0,0 +1,99 @@
+//go:build linux && (amd64 || arm64)
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+)
+
+func Test_seccompFilter(t *testing.T) {
+	filter, err := seccompFilter()
+	assert.NoError(t, err)
+	raw := make([]bpf.RawInstruction, len(filter))
+	for i, f := range filter {
+		raw[i] = bpf.RawInstruction{Op: f.Code, Jt: f.Jt, Jf: f.Jf, K: f.K}
+	}
+	instructions, ok := bpf.Disassemble(raw)
+	assert.True(t, ok)
+	vm, err := bpf.NewVM(instructions)
+	assert.NoError(t, err)
+	tests := []struct {
+		name string
+		arch uint32
+		nr   uint32
+		arg0 uint32
+		want int
+	}{
+		{
+			name: "read allowed",
+			arch: auditArch,
+			nr:   unix.SYS_READ,
+			want: seccompRetAllow,
+		},
+		{
+			name: "socket denied",
+			arch: auditArch,
+			nr:   unix.SYS_SOCKET,
+			want: seccompRetErrno | int(unix.EPERM),
+		},
+		{
+			name: "execve denied",
+			arch: auditArch,
+			nr:   unix.SYS_EXECVE,
+			want: seccompRetErrno | int(unix.EPERM),
+		},
+		{
+			name: "io_uring denied",
+			arch: auditArch,
+			nr:   unix.SYS_IO_URING_SETUP,
+			want: seccompRetErrno | int(unix.EPERM),
+		},
+		{
+			name: "thread clone allowed",
+			arch: auditArch,
+			nr:   unix.SYS_CLONE,
+			arg0: unix.CLONE_VM | unix.CLONE_FS | unix.CLONE_FILES | unix.CLONE_SIGHAND | unix.CLONE_THREAD,
+			want: seccompRetAllow,
+		},
+		{
+			name: "namespace clone denied",
+			arch: auditArch,
+			nr:   unix.SYS_CLONE,
+			arg0: unix.CLONE_NEWUSER | unix.CLONE_NEWNS,
+			want: seccompRetErrno | int(unix.EPERM),
+		},
+		{
+			name: "clone3 unavailable",
+			arch: auditArch,
+			nr:   unix.SYS_CLONE3,
+			want: seccompRetErrno | int(unix.ENOSYS),
+		},
+		{
+			name: "foreign architecture killed",
+			arch: unix.AUDIT_ARCH_I386,
+			nr:   unix.SYS_READ,
+			want: seccompRetKillProcess,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// struct seccomp_data in native (little endian) order, the VM loads big endian words
+			data := make([]byte, 64)
+			putWord(data[seccompDataNr:], tt.nr)
+			putWord(data[seccompDataArch:], tt.arch)
+			putWord(data[seccompDataArg0:], tt.arg0)
+			got, err := vm.Run(data)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// putWord writes the word so that the big endian BPF VM reads it as the kernel reads the native word
+func putWord(b []byte, v uint32) {
+	b[0], b[1], b[2], b[3] = byte(v>>24), byte(v>>16), byte(v>>8), byte(v)
+}
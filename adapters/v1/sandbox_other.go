@@ -0,0 +1,18 @@
+//go:build !(linux && (amd64 || arm64))
+
+package v1
+
+import (
+	"errors"
+	"syscall"
+)
+
+// RestrictProcess is only supported on linux amd64 and arm64
+func RestrictProcess(string, string) error {
+	return errors.New("process restriction is not supported on this platform")
+}
+
+// sandboxProcAttr has no extra attributes on this platform
+func sandboxProcAttr() *syscall.SysProcAttr {
+	return nil
+}
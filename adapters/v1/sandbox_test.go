@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/anchore/stereoscope/pkg/image"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain lets the test binary act as the extractor when started by the extraction sandbox
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == "-layout" {
+		os.Exit(RunExtractor(os.Args[1:], os.Stdout, os.Stderr))
+	}
+	os.Exit(m.Run())
+}
+
+// sandboxImage returns an image with a single layer holding the composer.lock of the monolith testdata
+func sandboxImage(t *testing.T) containerregistryV1.Image {
+	content, err := os.ReadFile("testdata/monolith/app/composer.lock")
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	require.NoError(t, w.WriteHeader(&tar.Header{Name: "app/composer.lock", Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err = w.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	})
+	require.NoError(t, err)
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	require.NoError(t, err)
+	return img
+}
+
+func TestSyftAdapter_extractInSandbox(t *testing.T) {
+	if runtime.GOOS != "linux" || (runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64") {
+		t.Skip("the extraction sandbox needs linux amd64 or arm64")
+	}
+	const repoDigest = "library/app@sha256:c0669ef34cdc14332c0f1ab0c2c01acb91d96014b172f1a76f3a39e63d1f0bda"
+	tests := []struct {
+		name         string
+		maxImageSize int64
+		want         []string
+		wantErr      error
+	}{
+		{
+			name:         "packages cataloged by the extractor",
+			maxImageSize: 1 << 30,
+			want:         []string{"monolog/monolog@2.9.1"},
+		},
+		{
+			name:         "image too large",
+			maxImageSize: 1,
+			wantErr:      ErrImageTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := newWorkspace("")
+			require.NoError(t, err)
+			defer func() { _ = w.Cleanup() }()
+			layoutDir, gotDigest, err := writeLayout(w, sandboxImage(t), []image.AdditionalMetadata{image.WithRepoDigests(repoDigest)})
+			require.NoError(t, err)
+			assert.Equal(t, repoDigest, gotDigest)
+			s := NewSyftAdapter(0, tt.maxImageSize,
+				WithCatalogerModes(map[string]string{"php": CatalogerModeLockfile}),
+				WithExtractionSandbox(os.Args[0]))
+			syftSBOM, _, err := s.extractInSandbox(context.TODO(), w, layoutDir, "library/app:latest", gotDigest)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			var got []string
+			for _, p := range syftSBOM.Artifacts.PackageCatalog.Sorted() {
+				got = append(got, p.Name+"@"+p.Version)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
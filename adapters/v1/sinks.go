@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"strings"
+	"time"
+
+	wlidpkg "github.com/armosec/utils-k8s-go/wlid"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// defaultSinkBatchSize is the number of findings indexed per request by the Splunk and Elasticsearch sinks
+const defaultSinkBatchSize = 500
+
+// findingEvent is a finding indexed by the Splunk and Elasticsearch sinks, flattened with the scan it belongs to
+type findingEvent struct {
+	Timestamp     time.Time `json:"@timestamp"`
+	ScanID        string    `json:"scanID"`
+	Wlid          string    `json:"wlid,omitempty"`
+	Namespace     string    `json:"namespace,omitempty"`
+	ImageSlug     string    `json:"imageSlug,omitempty"`
+	ImageTag      string    `json:"imageTag,omitempty"`
+	ImageHash     string    `json:"imageHash,omitempty"`
+	Verdict       string    `json:"verdict"`
+	Policy        string    `json:"policy,omitempty"`
+	Vulnerability string    `json:"vulnerability"`
+	Severity      string    `json:"severity"`
+	Package       string    `json:"package"`
+	Version       string    `json:"version"`
+}
+
+// findingEvents flattens the findings of a scan at the given time
+func findingEvents(report domain.ScanReport, findings []domain.Finding, now time.Time) []findingEvent {
+	var namespace string
+	if report.Wlid != "" {
+		namespace = wlidpkg.GetNamespaceFromWlid(report.Wlid)
+	}
+	events := make([]findingEvent, 0, len(findings))
+	for _, finding := range findings {
+		events = append(events, findingEvent{
+			Timestamp:     now,
+			ScanID:        report.ScanID,
+			Wlid:          report.Wlid,
+			Namespace:     namespace,
+			ImageSlug:     report.ImageSlug,
+			ImageTag:      report.ImageTag,
+			ImageHash:     report.ImageHash,
+			Verdict:       report.Verdict,
+			Policy:        report.Policy,
+			Vulnerability: finding.ID,
+			Severity:      finding.Severity,
+			Package:       finding.Package,
+			Version:       finding.Version,
+		})
+	}
+	return events
+}
+
+// batches splits the events in batches of at most size events
+func batches(events []findingEvent, size int) [][]findingEvent {
+	var batches [][]findingEvent
+	for len(events) > size {
+		batches = append(batches, events[:size])
+		events = events[size:]
+	}
+	if len(events) > 0 {
+		batches = append(batches, events)
+	}
+	return batches
+}
+
+// indexName expands the {date} placeholder of an index name with the UTC date, such as "kubevuln-2023.05.31" for
+// "kubevuln-{date}", to roll the indices daily
+func indexName(index string, now time.Time) string {
+	return strings.ReplaceAll(index, "{date}", now.UTC().Format("2006.01.02"))
+}
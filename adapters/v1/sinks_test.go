@@ -0,0 +1,42 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_findingEvents(t *testing.T) {
+	now := time.Date(2023, 5, 31, 12, 0, 0, 0, time.UTC)
+	report := domain.ScanReport{ScanID: "scanID", Wlid: "wlid://cluster-minikube/namespace-prod/deployment-nginx", ImageTag: "nginx:1.25", Verdict: domain.VerdictFail, Policy: "production"}
+	findings := []domain.Finding{{ID: "CVE-2022-37434", Package: "zlib", Version: "1.2.12", Severity: "Critical"}}
+	assert.Equal(t, []findingEvent{{
+		Timestamp:     now,
+		ScanID:        "scanID",
+		Wlid:          "wlid://cluster-minikube/namespace-prod/deployment-nginx",
+		Namespace:     "prod",
+		ImageTag:      "nginx:1.25",
+		Verdict:       domain.VerdictFail,
+		Policy:        "production",
+		Vulnerability: "CVE-2022-37434",
+		Severity:      "Critical",
+		Package:       "zlib",
+		Version:       "1.2.12",
+	}}, findingEvents(report, findings, now))
+}
+
+func Test_batches(t *testing.T) {
+	events := make([]findingEvent, 5)
+	assert.Len(t, batches(events, 2), 3)
+	assert.Len(t, batches(events, 5), 1)
+	assert.Len(t, batches(events[:4], 2)[1], 2)
+	assert.Empty(t, batches(nil, 2))
+}
+
+func Test_indexName(t *testing.T) {
+	now := time.Date(2023, 5, 31, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*60*60))
+	assert.Equal(t, "kubevuln-2023.06.01", indexName("kubevuln-{date}", now))
+	assert.Equal(t, "kubevuln", indexName("kubevuln", now))
+}
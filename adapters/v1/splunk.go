@@ -0,0 +1,94 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/armosec/utils-go/httputils"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// splunkSourceType is the sourcetype of the findings indexed into Splunk
+const splunkSourceType = "kubevuln:vulnerability"
+
+// SplunkAdapter implements ResultSink from ports by indexing the routed findings into Splunk, one event per finding,
+// with its HTTP Event Collector (HEC)
+type SplunkAdapter struct {
+	batchSize    int
+	httpPostFunc func(httputils.IHttpClient, string, map[string]string, []byte) (*http.Response, error)
+	index        string
+	now          func() time.Time
+	token        string
+	url          string
+}
+
+var _ ports.ResultSink = (*SplunkAdapter)(nil)
+
+// splunkEvent is an event of the HEC event endpoint
+type splunkEvent struct {
+	Time       int64        `json:"time"`
+	Source     string       `json:"source"`
+	SourceType string       `json:"sourcetype"`
+	Index      string       `json:"index,omitempty"`
+	Event      findingEvent `json:"event"`
+}
+
+// NewSplunkAdapter initializes the SplunkAdapter posting to the HEC event endpoint url, such as
+// "https://splunk.example.com:8088/services/collector/event", with the HEC token; index is the Splunk index of the
+// events, see indexName, the default index of the token when empty; batchSize is the number of events per request,
+// 500 when zero
+func NewSplunkAdapter(url, token, index string, batchSize int) *SplunkAdapter {
+	if batchSize <= 0 {
+		batchSize = defaultSinkBatchSize
+	}
+	return &SplunkAdapter{
+		batchSize:    batchSize,
+		httpPostFunc: httputils.HttpPost,
+		index:        index,
+		now:          time.Now,
+		token:        token,
+		url:          url,
+	}
+}
+
+// SendResults indexes the findings of a scan, in batches
+func (s *SplunkAdapter) SendResults(ctx context.Context, report domain.ScanReport, findings []domain.Finding) error {
+	ctx, span := otel.Tracer("").Start(ctx, "SplunkAdapter.SendResults")
+	defer span.End()
+	now := s.now()
+	index := indexName(s.index, now)
+	headers := map[string]string{"Content-Type": "application/json"}
+	if s.token != "" {
+		headers["Authorization"] = "Splunk " + s.token
+	}
+	for _, batch := range batches(findingEvents(report, findings, now), s.batchSize) {
+		// HEC takes the events of a batch concatenated
+		var payload bytes.Buffer
+		encoder := json.NewEncoder(&payload)
+		for _, event := range batch {
+			if err := encoder.Encode(splunkEvent{Time: now.Unix(), Source: "kubevuln", SourceType: splunkSourceType, Index: index, Event: event}); err != nil {
+				return err
+			}
+		}
+		resp, err := s.httpPostFunc(clientWithContext(ctx), s.url, headers, payload.Bytes())
+		if err != nil {
+			return err
+		}
+		body, err := httputils.HttpRespToString(resp)
+		if err != nil {
+			return fmt.Errorf("indexing the findings into Splunk failed: %w, body: %s", err, body)
+		}
+	}
+	logger.L().Debug("indexed scan results into Splunk",
+		helpers.String("scanID", report.ScanID),
+		helpers.Int("findings", len(findings)))
+	return nil
+}
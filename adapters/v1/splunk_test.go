@@ -0,0 +1,62 @@
+package v1
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplunkAdapter_SendResults(t *testing.T) {
+	var requests [][]splunkEvent
+	var gotAuthorization string
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuthorization = r.Header.Get("Authorization")
+		b, _ := io.ReadAll(r.Body)
+		var events []splunkEvent
+		scanner := bufio.NewScanner(bytes.NewReader(b))
+		for scanner.Scan() {
+			var event splunkEvent
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+			events = append(events, event)
+		}
+		requests = append(requests, events)
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+	now := time.Date(2023, 5, 31, 12, 0, 0, 0, time.UTC)
+	s := NewSplunkAdapter(ts.URL+"/services/collector/event", "hec-token", "vulns-{date}", 2)
+	s.now = func() time.Time { return now }
+	report := domain.ScanReport{ScanID: "scanID", Verdict: domain.VerdictSuccess}
+	findings := []domain.Finding{
+		{ID: "CVE-2022-37434", Package: "zlib", Version: "1.2.12", Severity: "Critical"},
+		{ID: "CVE-2023-0286", Package: "openssl", Version: "3.0.7", Severity: "High"},
+		{ID: "CVE-2023-23914", Package: "curl", Version: "7.87.0", Severity: "Medium"},
+	}
+
+	require.NoError(t, s.SendResults(context.TODO(), report, findings))
+	assert.Equal(t, "Splunk hec-token", gotAuthorization)
+	require.Len(t, requests, 2)
+	assert.Len(t, requests[0], 2)
+	assert.Len(t, requests[1], 1)
+	event := requests[1][0]
+	assert.Equal(t, now.Unix(), event.Time)
+	assert.Equal(t, "kubevuln", event.Source)
+	assert.Equal(t, splunkSourceType, event.SourceType)
+	assert.Equal(t, "vulns-2023.05.31", event.Index)
+	assert.Equal(t, "CVE-2023-23914", event.Event.Vulnerability)
+	assert.Equal(t, "scanID", event.Event.ScanID)
+
+	status = http.StatusForbidden
+	assert.Error(t, s.SendResults(context.TODO(), report, findings))
+}
@@ -0,0 +1,98 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/anchore/syft/syft/artifact"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/pkg/cataloger/generic"
+	"github.com/anchore/syft/syft/source"
+)
+
+const (
+	packageResolvedCataloger = "swift-package-manager-cataloger"
+	packageResolvedGlob      = "**/Package.resolved"
+	// swiftPkg is the type of the Swift Package Manager packages, named like in the later Syft versions
+	swiftPkg pkg.Type = "swift"
+)
+
+// packageResolvedPin is a dependency pinned by a Package.resolved file, in its version 1 (repositoryURL) or later
+// (location) format
+type packageResolvedPin struct {
+	Location      string `json:"location"`
+	RepositoryURL string `json:"repositoryURL"`
+	State         struct {
+		Version string `json:"version"`
+	} `json:"state"`
+}
+
+// newPackageResolvedCataloger returns a cataloger of the Swift packages pinned in Package.resolved files, which Syft
+// does not catalog
+func newPackageResolvedCataloger() *generic.Cataloger {
+	return generic.NewCataloger(packageResolvedCataloger).
+		WithParserByGlobs(parsePackageResolved, packageResolvedGlob)
+}
+
+func parsePackageResolved(_ source.FileResolver, _ *generic.Environment, reader source.LocationReadCloser) ([]pkg.Package, []artifact.Relationship, error) {
+	var resolved struct {
+		Pins   []packageResolvedPin `json:"pins"`
+		Object struct {
+			Pins []packageResolvedPin `json:"pins"`
+		} `json:"object"`
+	}
+	if err := json.NewDecoder(reader).Decode(&resolved); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse Package.resolved file: %w", err)
+	}
+	var pkgs []pkg.Package
+	for _, pin := range append(resolved.Pins, resolved.Object.Pins...) {
+		location := pin.Location
+		if location == "" {
+			location = pin.RepositoryURL
+		}
+		// the packages pinned to a branch or a revision have no version to match
+		namespace, name := swiftPackageName(location)
+		if name == "" || pin.State.Version == "" {
+			continue
+		}
+		p := pkg.Package{
+			Name:      name,
+			Version:   pin.State.Version,
+			Locations: source.NewLocationSet(reader.Location),
+			PURL:      packageurl.NewPackageURL(packageurl.TypeSwift, namespace, name, pin.State.Version, nil, "").ToString(),
+			Language:  pkg.Swift,
+			Type:      swiftPkg,
+		}
+		p.SetID()
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil, nil
+}
+
+// swiftPackageName splits the source URL of a Swift package into the namespace and name of its package URL, such as
+// github.com/apple and swift-nio for https://github.com/apple/swift-nio.git
+func swiftPackageName(location string) (string, string) {
+	u, err := url.Parse(location)
+	if err != nil || u.Host == "" {
+		// scp-like git URLs such as git@github.com:apple/swift-nio.git
+		_, rest, ok := strings.Cut(location, "@")
+		host, repository, found := strings.Cut(rest, ":")
+		if !ok || !found {
+			return "", ""
+		}
+		u = &url.URL{Host: host, Path: "/" + repository}
+	}
+	repository := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if repository == "" {
+		return "", ""
+	}
+	namespace := path.Dir(repository)
+	if namespace == "." {
+		return u.Host, path.Base(repository)
+	}
+	return u.Host + "/" + namespace, path.Base(repository)
+}
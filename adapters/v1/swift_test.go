@@ -0,0 +1,29 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_swiftPackageName(t *testing.T) {
+	tests := []struct {
+		location      string
+		wantNamespace string
+		wantName      string
+	}{
+		{location: "https://github.com/apple/swift-nio.git", wantNamespace: "github.com/apple", wantName: "swift-nio"},
+		{location: "https://github.com/apple/swift-log", wantNamespace: "github.com/apple", wantName: "swift-log"},
+		{location: "git@github.com:Alamofire/Alamofire.git", wantNamespace: "github.com/Alamofire", wantName: "Alamofire"},
+		{location: "https://example.com/package", wantNamespace: "example.com", wantName: "package"},
+		{location: "/Users/dev/LocalPackage"},
+		{location: "https://example.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			namespace, name := swiftPackageName(tt.location)
+			assert.Equal(t, tt.wantNamespace, namespace)
+			assert.Equal(t, tt.wantName, name)
+		})
+	}
+}
@@ -3,19 +3,19 @@ package v1
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/anchore/stereoscope/pkg/file"
 	"github.com/anchore/stereoscope/pkg/filetree"
 	"github.com/anchore/stereoscope/pkg/image"
-	"github.com/anchore/syft/syft"
 	"github.com/anchore/syft/syft/artifact"
-	"github.com/anchore/syft/syft/linux"
-	"github.com/anchore/syft/syft/pkg"
 	"github.com/anchore/syft/syft/pkg/cataloger"
 	"github.com/anchore/syft/syft/sbom"
 	"github.com/anchore/syft/syft/source"
@@ -36,19 +36,65 @@ import (
 
 // SyftAdapter implements SBOMCreator from ports using Syft's API
 type SyftAdapter struct {
-	maxImageSize int64
-	scanTimeout  time.Duration
+	catalogerModes      map[string]string
+	catalogerRetries    int
+	criExportFunc       func(context.Context, string, io.Writer) error
+	criRuntime          string
+	ecosystems          []string
+	embeddedImagesDepth int
+	excludeFiles        bool
+	gitProtocols        string
+	maxImageSize        int64
+	pullRetries         int
+	pullRetryBackoff    time.Duration
+	pullTimeout         time.Duration
+	registryAuth        *RegistryAuthBroker
+	registryMirrors     map[string][]string
+	sandboxBinary       string
+	scanProfile         string
+	scanTimeout         time.Duration
+	workDir             string
 }
 
 var _ ports.SBOMCreator = (*SyftAdapter)(nil)
+var _ ports.ImageResolver = (*SyftAdapter)(nil)
 var ErrImageTooLarge = fmt.Errorf("image size exceeds maximum allowed size")
 
+// SyftAdapterOption configures optional behaviors of the SyftAdapter
+type SyftAdapterOption func(*SyftAdapter)
+
+// WithPullTimeout sets the timeout budget of the image pull, zero disables it
+func WithPullTimeout(pullTimeout time.Duration) SyftAdapterOption {
+	return func(s *SyftAdapter) {
+		s.pullTimeout = pullTimeout
+	}
+}
+
+// WithoutFiles excludes the file-level entries (file lists, digests, snippets) from the generated SBOMs,
+// keeping only the package-level data needed for matching; file relevancy is not possible on such SBOMs
+func WithoutFiles() SyftAdapterOption {
+	return func(s *SyftAdapter) {
+		s.excludeFiles = true
+	}
+}
+
+// WithWorkDir sets the directory in which per-scan workspaces are created, instead of the default temporary directory
+func WithWorkDir(workDir string) SyftAdapterOption {
+	return func(s *SyftAdapter) {
+		s.workDir = workDir
+	}
+}
+
 // NewSyftAdapter initializes the SyftAdapter struct
-func NewSyftAdapter(scanTimeout time.Duration, maxImageSize int64) *SyftAdapter {
-	return &SyftAdapter{
+func NewSyftAdapter(scanTimeout time.Duration, maxImageSize int64, opts ...SyftAdapterOption) *SyftAdapter {
+	s := &SyftAdapter{
 		maxImageSize: maxImageSize,
 		scanTimeout:  scanTimeout,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // CreateSBOM creates an SBOM for a given imageID, restrict parallelism to prevent disk space issues,
@@ -57,6 +103,15 @@ func NewSyftAdapter(scanTimeout time.Duration, maxImageSize int64) *SyftAdapter
 func (s *SyftAdapter) CreateSBOM(ctx context.Context, name, imageID string, options domain.RegistryOptions) (domain.SBOM, error) {
 	ctx, span := otel.Tracer("").Start(ctx, "SyftAdapter.CreateSBOM")
 	defer span.End()
+	sbom, err := s.createSBOM(ctx, name, imageID, options, nil)
+	return sbom, domain.Categorize(domain.ErrSBOM, err)
+}
+
+// imageWrapper restricts what is read of the pulled image
+type imageWrapper func(img containerregistryV1.Image) (containerregistryV1.Image, error)
+
+// createSBOM catalogs the image as wrapped by wrap, if any
+func (s *SyftAdapter) createSBOM(ctx context.Context, name, imageID string, options domain.RegistryOptions, wrap imageWrapper) (domain.SBOM, error) {
 	// prepare an SBOM and fill it progressively
 	domainSBOM := domain.SBOM{
 		Name:               name,
@@ -74,74 +129,120 @@ func (s *SyftAdapter) CreateSBOM(ctx context.Context, name, imageID string, opti
 	if err != nil {
 		return domainSBOM, err
 	}
-	credentials := make([]image.RegistryCredentials, len(options.Credentials))
-	for i, v := range options.Credentials {
-		credentials[i] = image.RegistryCredentials{
-			Authority: v.Authority,
-			Username:  v.Username,
-			Password:  v.Password,
-			Token:     v.Token,
-		}
-	}
-	registryOptions := image.RegistryOptions{
-		InsecureSkipTLSVerify: options.InsecureSkipTLSVerify,
-		InsecureUseHTTP:       options.InsecureUseHTTP,
-		Credentials:           credentials,
-		Platform:              options.Platform,
+	registryOptions := s.withRegistryAuth(ctx, imageID, domainToRegistryOptions(options))
+	// prepare an isolated workspace for image download, removed even if the scan panics
+	t, err := newWorkspace(s.workDir)
+	if err != nil {
+		return domainSBOM, fmt.Errorf("failed to create scan workspace: %w", err)
 	}
-	// prepare temporary directory for image download
-	t := file.NewTempDirGenerator("stereoscope")
-	defer func(t *file.TempDirGenerator) {
+	defer func(t *workspace) {
 		err := t.Cleanup()
 		if err != nil {
-			logger.L().Ctx(ctx).Warning("failed to cleanup temp dir", helpers.Error(err),
+			logger.L().Ctx(ctx).Warning("failed to cleanup scan workspace", helpers.Error(err),
 				helpers.String("imageID", imageID))
 		}
 	}(t)
-	// download image
-	logger.L().Debug("downloading image",
-		helpers.String("imageID", imageID))
-	src, err := newFromRegistry(t, sourceInput, registryOptions, s.maxImageSize)
-	// check for 401 error and retry without credentials
-	var transportError *transport.Error
-	if errors.As(err, &transportError) && transportError.StatusCode == http.StatusUnauthorized {
-		logger.L().Debug("got 401, retrying without credentials",
-			helpers.String("imageID", imageID))
-		registryOptions.Credentials = nil
-		src, err = newFromRegistry(t, sourceInput, registryOptions, s.maxImageSize)
-	}
+	// pull the image within its timeout budget, the extraction sandbox reads it from an OCI layout
+	// the abandoned pull keeps writing into its own pulledImage and holds the workspace until it is over
+	release := t.hold()
+	pulled, err := tools.RunWithTimeout(ctx, domain.StagePull, s.pullTimeout, func(ctx context.Context) (pulledImage, error) {
+		defer release()
+		p := pulledImage{artifactType: domain.ArtifactTypeImage}
+		err := s.pull(ctx, t, sourceInput, registryOptions, imageID, s.loadInto(ctx, t, sourceInput, wrap, &p), &p.imageSource)
+		return p, err
+	})
 	switch {
 	case errors.Is(err, ErrImageTooLarge):
 		logger.L().Ctx(ctx).Warning("Image exceeds size limit",
 			helpers.Int("maxImageSize", int(s.maxImageSize)),
 			helpers.String("imageID", imageID))
 		domainSBOM.Status = instanceidhandler.Incomplete
+		domainSBOM.Annotations[domain.AnnotationIncompleteReason] = domain.FailureImageTooLarge
 		return domainSBOM, nil
 	case err != nil:
 		return domainSBOM, err
 	}
+	// record whether the image was read from the container runtime or the registry
+	if s.criExportFunc != nil && pulled.imageSource != "" {
+		domainSBOM.Annotations[domain.AnnotationImageSource] = pulled.imageSource
+	}
+	if pulled.artifactType != domain.ArtifactTypeImage {
+		return s.createArtifactSBOM(ctx, domainSBOM, imageID, options, pulled.artifactType, pulled.artifact)
+	}
+	// record image provenance, the extraction sandbox reports it with the packages
+	if s.sandboxBinary == "" {
+		for key, value := range imageProvenance(pulled.src) {
+			domainSBOM.Annotations[key] = value
+		}
+	}
 	// extract packages
 	// use a deadline to prevent the process from hanging for too long
 	// TODO check memory usage and see if we can kill the goroutine
-	var pkgCatalog *pkg.Catalog
-	var relationships []artifact.Relationship
-	var actualDistro *linux.Release
-	dl := deadline.New(s.scanTimeout)
-	err = dl.Run(func(stopper <-chan struct{}) error {
-		logger.L().Debug("extracting packages",
+	catalog := func(s *SyftAdapter, timeout time.Duration) (sbom.SBOM, map[string]string, error) {
+		// the extraction sandbox is killed once the deadline is exceeded
+		sandboxCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		dl := deadline.New(timeout)
+		// the catalogers cannot be interrupted, catalogers abandoned past the deadline hold the workspace
+		release := t.hold()
+		extracted := make(chan extractedSBOM, 1)
+		err := dl.Run(func(stopper <-chan struct{}) (err error) {
+			defer release()
+			// catalogers run in their own goroutine, a panic on a malformed archive must not crash the pod
+			defer tools.RecoverPanic(ctx, &err)
+			logger.L().Debug("extracting packages",
+				helpers.String("imageID", imageID))
+			var e extractedSBOM
+			if s.sandboxBinary != "" {
+				e.sbom, e.annotations, err = s.extractInSandbox(sandboxCtx, t, pulled.layoutDir, imageID, pulled.repoDigest)
+			} else {
+				e.sbom, e.annotations, err = s.extractSBOM(ctx, pulled.src)
+			}
+			if err == nil {
+				extracted <- e
+			}
+			return err
+		})
+		// the results of abandoned catalogers are discarded
+		if err != nil {
+			return sbom.SBOM{}, nil, err
+		}
+		e := <-extracted
+		return e.sbom, e.annotations, nil
+	}
+	var syftSBOM sbom.SBOM
+	var annotations map[string]string
+	// a scan already past its deadline goes straight to the OS packages
+	timeout, partial := s.catalogTimeout(ctx)
+	if timeout > 0 || !partial {
+		syftSBOM, annotations, err = catalog(s, timeout)
+	} else {
+		err = deadline.ErrTimedOut
+	}
+	// past the deadline of the scan, the OS packages are cataloged rather than returning nothing
+	if err == deadline.ErrTimedOut && partial {
+		logger.L().Ctx(ctx).Warning("scan deadline exceeded, cataloging the OS packages only",
 			helpers.String("imageID", imageID))
-		catalogOptions := cataloger.Config{
-			Search:      cataloger.DefaultSearchConfig(),
-			Parallelism: 4, // TODO assess this value
+		osPackages := *s
+		osPackages.scanProfile = ScanProfileFast
+		syftSBOM, annotations, err = catalog(&osPackages, s.scanTimeout)
+		if err == nil {
+			domainSBOM.Annotations[domain.AnnotationPartial] = "true"
 		}
-		pkgCatalog, relationships, actualDistro, err = syft.CatalogPackages(&src, catalogOptions)
-		return err
-	})
+	}
 	switch err {
 	case deadline.ErrTimedOut:
 		logger.L().Ctx(ctx).Warning("Syft timed out",
 			helpers.String("imageID", imageID))
 		domainSBOM.Status = instanceidhandler.Incomplete
+		domainSBOM.Annotations[domain.AnnotationIncompleteReason] = domain.FailureTimeout
+		return domainSBOM, nil
+	case ErrImageTooLarge:
+		logger.L().Ctx(ctx).Warning("Image exceeds size limit",
+			helpers.Int("maxImageSize", int(s.maxImageSize)),
+			helpers.String("imageID", imageID))
+		domainSBOM.Status = instanceidhandler.Incomplete
+		domainSBOM.Annotations[domain.AnnotationIncompleteReason] = domain.FailureImageTooLarge
 		return domainSBOM, nil
 	case nil:
 		// continue
@@ -150,16 +251,8 @@ func (s *SyftAdapter) CreateSBOM(ctx context.Context, name, imageID string, opti
 		domainSBOM.Status = instanceidhandler.Incomplete
 		return domainSBOM, err
 	}
-	// generate SBOM
-	logger.L().Debug("generating SBOM",
-		helpers.String("imageID", imageID))
-	syftSBOM := sbom.SBOM{
-		Source:        src.Metadata,
-		Relationships: relationships,
-		Artifacts: sbom.Artifacts{
-			PackageCatalog:    pkgCatalog,
-			LinuxDistribution: actualDistro,
-		},
+	for key, value := range annotations {
+		domainSBOM.Annotations[key] = value
 	}
 	// convert SBOM
 	logger.L().Debug("converting SBOM",
@@ -171,28 +264,115 @@ func (s *SyftAdapter) CreateSBOM(ctx context.Context, name, imageID string, opti
 	return domainSBOM, err
 }
 
-func newFromRegistry(t *file.TempDirGenerator, sourceInput *source.Input, registryOptions image.RegistryOptions, maxImageSize int64) (source.Source, error) {
-	imageTempDir, err := t.NewDirectory("oci-registry-image")
+// extractSBOM catalogs the packages of the source into a Syft SBOM, annotated with the frameworks
+// targeted by .NET applications and with the images embedded in the source, whose packages are added once scanned
+func (s *SyftAdapter) extractSBOM(ctx context.Context, src source.Source) (sbom.SBOM, map[string]string, error) {
+	pkgCatalog, relationships, actualDistro, resolver, statuses, err := s.catalogPackages(ctx, &src)
 	if err != nil {
-		return source.Source{}, err
+		return sbom.SBOM{}, nil, err
+	}
+	annotations := map[string]string{}
+	// the packages of the catalogers which succeeded are kept
+	if catalogersFailed(statuses) {
+		value, err := json.Marshal(statuses)
+		if err != nil {
+			return sbom.SBOM{}, nil, err
+		}
+		annotations[domain.AnnotationPartial] = "true"
+		annotations[domain.AnnotationCatalogerStatus] = string(value)
+	}
+	if frameworks := dotnetTargetFrameworks(resolver); len(frameworks) > 0 {
+		annotations[domain.AnnotationTargetFrameworks] = strings.Join(frameworks, ",")
 	}
+	if embedded, packages := s.embeddedImages(ctx, resolver, s.embeddedImagesDepth); len(embedded) > 0 {
+		value, err := json.Marshal(embedded)
+		if err != nil {
+			return sbom.SBOM{}, nil, err
+		}
+		annotations[domain.AnnotationEmbeddedImages] = string(value)
+		for _, p := range packages {
+			pkgCatalog.Add(p)
+			relationships = append(relationships, artifact.Relationship{From: &src, To: p, Type: artifact.ContainsRelationship})
+		}
+	}
+	return sbom.SBOM{
+		Source:        src.Metadata,
+		Relationships: relationships,
+		Artifacts: sbom.Artifacts{
+			PackageCatalog:    pkgCatalog,
+			LinuxDistribution: actualDistro,
+		},
+	}, annotations, nil
+}
+
+// ResolveDigest returns the digest reference the given image tag currently points to
+func (s *SyftAdapter) ResolveDigest(ctx context.Context, imageTag string, options domain.RegistryOptions) (string, error) {
+	_, span := otel.Tracer("").Start(ctx, "SyftAdapter.ResolveDigest")
+	defer span.End()
+	digest, err := s.resolveDigest(ctx, imageTag, options)
+	return digest, domain.Categorize(domain.ErrImagePull, err)
+}
+
+// resolveDigest reads the digest of an image tag from the registry
+func (s *SyftAdapter) resolveDigest(ctx context.Context, imageTag string, options domain.RegistryOptions) (string, error) {
+	if options.Platform == "" {
+		options.Platform = runtime.GOARCH
+	}
+	registryOptions := s.withRegistryAuth(ctx, imageTag, domainToRegistryOptions(options))
+	ref, err := name.ParseReference(imageTag, prepareReferenceOptions(registryOptions)...)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse registry reference=%q: %w", imageTag, err)
+	}
+	platform, err := image.NewPlatform(registryOptions.Platform)
+	if err != nil {
+		return "", fmt.Errorf("unable to create platform reference=%q: %w", imageTag, err)
+	}
+	descriptor, err := remote.Head(ref, prepareRemoteOptions(ctx, ref, registryOptions, platform)...)
+	if err != nil {
+		return "", s.authError(fmt.Errorf("failed to get image descriptor from registry: %w", err), imageTag, registryOptions)
+	}
+	return fmt.Sprintf("%s@%s", ref.Context().Name(), descriptor.Digest.String()), nil
+}
+
+// domainToRegistryOptions translates business registry options into Stereoscope registry options
+func domainToRegistryOptions(options domain.RegistryOptions) image.RegistryOptions {
+	credentials := make([]image.RegistryCredentials, len(options.Credentials))
+	for i, v := range options.Credentials {
+		credentials[i] = image.RegistryCredentials{
+			Authority: v.Authority,
+			Username:  v.Username,
+			Password:  v.Password,
+			Token:     v.Token,
+		}
+	}
+	return image.RegistryOptions{
+		InsecureSkipTLSVerify: options.InsecureSkipTLSVerify,
+		InsecureUseHTTP:       options.InsecureUseHTTP,
+		Credentials:           credentials,
+		Platform:              options.Platform,
+	}
+}
+
+// fetchFromRegistry gets the image from the registry and passes it to the loader, which downloads its layers until ctx
+// is cancelled
+func fetchFromRegistry(ctx context.Context, sourceInput *source.Input, registryOptions image.RegistryOptions, load imageLoader) error {
 	// download image
 	ref, err := name.ParseReference(sourceInput.UserInput, prepareReferenceOptions(registryOptions)...)
 	if err != nil {
-		return source.Source{}, fmt.Errorf("unable to parse registry reference=%q: %w", sourceInput.UserInput, err)
+		return fmt.Errorf("unable to parse registry reference=%q: %w", sourceInput.UserInput, err)
 	}
 	platform, err := image.NewPlatform(registryOptions.Platform)
 	if err != nil {
-		return source.Source{}, fmt.Errorf("unable to create platform reference=%q: %w", sourceInput.UserInput, err)
+		return fmt.Errorf("unable to create platform reference=%q: %w", sourceInput.UserInput, err)
 	}
-	descriptor, err := remote.Get(ref, prepareRemoteOptions(ref, registryOptions, platform)...)
+	descriptor, err := remote.Get(ref, prepareRemoteOptions(ctx, ref, registryOptions, platform)...)
 	if err != nil {
-		return source.Source{}, fmt.Errorf("failed to get image descriptor from registry: %w", err)
+		return fmt.Errorf("failed to get image descriptor from registry: %w", err)
 	}
 
 	imgRemote, err := descriptor.Image()
 	if err != nil {
-		return source.Source{}, fmt.Errorf("failed to get image from registry: %w", err)
+		return fmt.Errorf("failed to get image from registry: %w", err)
 	}
 
 	// craft a repo digest from the registry reference and the known digest
@@ -215,7 +395,22 @@ func newFromRegistry(t *file.TempDirGenerator, sourceInput *source.Input, regist
 		)
 	}
 
-	img := image.New(imgRemote, t, imageTempDir, metadata...)
+	return load(registryImage{Image: imgRemote, blobs: newRegistryBlobs(ref, registryOptions)}, metadata)
+}
+
+// imageLoader reads a fetched image, with the metadata its source adds
+type imageLoader func(img containerregistryV1.Image, metadata []image.AdditionalMetadata) error
+
+// newFromImage reads the layers of a go-containerregistry image and wraps it into a Syft source
+func newFromImage(t *workspace, sourceInput *source.Input, imgRemote containerregistryV1.Image, metadata []image.AdditionalMetadata, maxImageSize int64) (source.Source, error) {
+	imageTempDir, err := t.NewDirectory("oci-registry-image")
+	if err != nil {
+		return source.Source{}, err
+	}
+
+	// stereoscope only knows the gzip and uncompressed layers
+	imgRemote = withReadableLayers(imgRemote)
+	img := image.New(imgRemote, nil, imageTempDir, metadata...)
 
 	err = read(img, imgRemote, imageTempDir, maxImageSize)
 	if err != nil {
@@ -237,16 +432,21 @@ func prepareReferenceOptions(registryOptions image.RegistryOptions) []name.Optio
 	return options
 }
 
-func prepareRemoteOptions(ref name.Reference, registryOptions image.RegistryOptions, p *image.Platform) (options []remote.Option) {
-	options = append(options, remote.WithContext(context.TODO()))
-
+// registryTransport returns the transport of the registry requests, which may skip the TLS verification, sending the
+// request headers
+func registryTransport(registryOptions image.RegistryOptions) http.RoundTripper {
 	if registryOptions.InsecureSkipTLSVerify {
-		t := &http.Transport{
+		return withRequestHeaders(&http.Transport{
 			//nolint: gosec
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-		options = append(options, remote.WithTransport(t))
+		})
 	}
+	return withRequestHeaders(remote.DefaultTransport)
+}
+
+// prepareRemoteOptions returns the options of the registry calls, cancelled with ctx
+func prepareRemoteOptions(ctx context.Context, ref name.Reference, registryOptions image.RegistryOptions, p *image.Platform) (options []remote.Option) {
+	options = append(options, remote.WithContext(ctx), remote.WithTransport(registryTransport(registryOptions)))
 
 	if p != nil {
 		options = append(options, remote.WithPlatform(containerregistryV1.Platform{
@@ -366,6 +566,135 @@ func squash(i *image.Image, catalog *image.FileCatalog) error {
 	return nil
 }
 
+// pull fetches the image from the container runtime if enabled, or from the registry, and passes it to the loader;
+// imageSource is set to the container runtime or the registry the image was read from
+func (s *SyftAdapter) pull(ctx context.Context, t *workspace, sourceInput *source.Input, registryOptions image.RegistryOptions, imageID string, load imageLoader, imageSource *string) error {
+	// read image from the container runtime if it is already present on the node
+	err := errCRIDisabled
+	if s.criExportFunc != nil {
+		logger.L().Debug("reading image from container runtime",
+			helpers.String("imageID", imageID),
+			helpers.String("runtime", s.criRuntime))
+		*imageSource = s.criRuntime
+		tools.RecordScanEvent(ctx, "pull started", map[string]string{"source": s.criRuntime})
+		err = fetchFromCRI(ctx, t, sourceInput, s.criExportFunc, load)
+		if err != nil && !errors.Is(err, ErrImageTooLarge) {
+			logger.L().Debug("failed to read image from container runtime, falling back to registry", helpers.Error(err),
+				helpers.String("imageID", imageID))
+		}
+	}
+	// download image
+	if err != nil && !errors.Is(err, ErrImageTooLarge) {
+		logger.L().Debug("downloading image",
+			helpers.String("imageID", imageID))
+		*imageSource = imageSourceRegistry
+		tools.RecordScanEvent(ctx, "pull started", map[string]string{"source": imageSourceRegistry})
+		err = s.fetchWithRetries(ctx, sourceInput, registryOptions, load)
+	}
+	// the registry and its mirrors failed with transient errors
+	var pullErr *domain.RegistryPullError
+	if errors.As(err, &pullErr) {
+		return err
+	}
+	// check for 401 error and retry without credentials
+	var transportError *transport.Error
+	if errors.As(err, &transportError) && transportError.StatusCode == http.StatusUnauthorized {
+		logger.L().Debug("got 401, retrying without credentials",
+			helpers.String("imageID", imageID))
+		authErr := s.authError(err, sourceInput.UserInput, registryOptions)
+		registryOptions.Credentials = nil
+		err = fetchFromRegistry(ctx, sourceInput, registryOptions, load)
+		// the failure of the credentials tells more than the anonymous one
+		if errors.As(err, &transportError) && (transportError.StatusCode == http.StatusUnauthorized || transportError.StatusCode == http.StatusForbidden) {
+			return authErr
+		}
+		return err
+	}
+	return s.authError(err, sourceInput.UserInput, registryOptions)
+}
+
+// pulledImage is the result of the pull stage, owned by the stage until it returns
+type pulledImage struct {
+	src          source.Source
+	layoutDir    string
+	repoDigest   string
+	artifactType string
+	artifact     []byte
+	imageSource  string
+}
+
+// extractedSBOM is the result of the catalog stage, owned by the stage until it returns
+type extractedSBOM struct {
+	sbom        sbom.SBOM
+	annotations map[string]string
+}
+
+// loadInto returns the loader of the pulled image into p, Helm charts and WASM modules are read from their
+// content layer rather than as a filesystem
+func (s *SyftAdapter) loadInto(ctx context.Context, t *workspace, sourceInput *source.Input, wrap imageWrapper, p *pulledImage) imageLoader {
+	return func(img containerregistryV1.Image, metadata []image.AdditionalMetadata) (err error) {
+		if p.artifactType = ociArtifactType(img); p.artifactType != domain.ArtifactTypeImage {
+			p.artifact, err = readArtifact(img, p.artifactType, s.maxImageSize)
+			return err
+		}
+		// the OCI layout of the extraction sandbox needs the whole layers, it filters them itself
+		if s.scanProfile == ScanProfileFast && s.sandboxBinary == "" {
+			img = withOSPackageFiles(ctx, img, s.maxImageSize)
+		}
+		if wrap != nil {
+			if img, err = wrap(img); err != nil {
+				return err
+			}
+		}
+		img = withPullProgress(ctx, img)
+		if s.sandboxBinary != "" {
+			p.layoutDir, p.repoDigest, err = writeLayout(t, img, metadata)
+			return err
+		}
+		p.src, err = newFromImage(t, sourceInput, img, metadata, s.maxImageSize)
+		return err
+	}
+}
+
+// catalogConfig returns the Syft cataloger configuration used to extract packages
+func catalogConfig() cataloger.Config {
+	return cataloger.Config{
+		Search:      cataloger.DefaultSearchConfig(),
+		Parallelism: 4, // TODO assess this value
+	}
+}
+
+// imageProvenance returns the image creation timestamp, the size of its layers, its OCI labels and its base image as
+// annotations
+func imageProvenance(src source.Source) map[string]string {
+	annotations := map[string]string{}
+	if src.Image == nil {
+		return annotations
+	}
+	config := src.Image.Metadata.Config
+	if !config.Created.IsZero() {
+		annotations[domain.AnnotationImageCreated] = config.Created.UTC().Format(time.RFC3339)
+	}
+	if size := src.Image.Metadata.Size; size > 0 {
+		annotations[domain.AnnotationImageSize] = strconv.FormatInt(size, 10)
+	}
+	for key, value := range config.Config.Labels {
+		if strings.HasPrefix(key, domain.OCILabelPrefix) {
+			annotations[key] = value
+		}
+	}
+	// builders record the base image in the manifest annotations rather than in the labels
+	var manifest containerregistryV1.Manifest
+	if err := json.Unmarshal(src.Image.Metadata.RawManifest, &manifest); err == nil {
+		for _, key := range []string{domain.OCIBaseDigest, domain.OCIBaseName} {
+			if value, ok := manifest.Annotations[key]; ok {
+				annotations[key] = value
+			}
+		}
+	}
+	return annotations
+}
+
 // Version returns Syft's version which is used to tag SBOMs
 func (s *SyftAdapter) Version() string {
 	return tools.PackageVersion("github.com/anchore/syft")
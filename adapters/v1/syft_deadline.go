@@ -0,0 +1,22 @@
+package v1
+
+import (
+	"context"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// catalogTimeout returns the time allowed to catalog the packages of the image, shortened to meet the deadline of the
+// scan given by the operator; partial tells whether the OS packages are cataloged instead once it is exceeded, which
+// the fast profile already restricts the scan to
+func (s *SyftAdapter) catalogTimeout(ctx context.Context) (timeout time.Duration, partial bool) {
+	workload, ok := ctx.Value(domain.WorkloadKey{}).(domain.ScanCommand)
+	if !ok || workload.Deadline.IsZero() || s.scanProfile == ScanProfileFast {
+		return s.scanTimeout, false
+	}
+	if remaining := time.Until(workload.Deadline); remaining < s.scanTimeout {
+		return remaining, true
+	}
+	return s.scanTimeout, false
+}
@@ -0,0 +1,46 @@
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyftAdapter_catalogTimeout(t *testing.T) {
+	withDeadline := func(deadline time.Time) context.Context {
+		return context.WithValue(context.TODO(), domain.WorkloadKey{}, domain.ScanCommand{Deadline: deadline})
+	}
+	s := NewSyftAdapter(time.Hour, 0)
+
+	// no deadline
+	timeout, partial := s.catalogTimeout(context.TODO())
+	assert.Equal(t, time.Hour, timeout)
+	assert.False(t, partial)
+	timeout, partial = s.catalogTimeout(withDeadline(time.Time{}))
+	assert.Equal(t, time.Hour, timeout)
+	assert.False(t, partial)
+
+	// the deadline leaves enough time
+	timeout, partial = s.catalogTimeout(withDeadline(time.Now().Add(2 * time.Hour)))
+	assert.Equal(t, time.Hour, timeout)
+	assert.False(t, partial)
+
+	// the deadline is before the scan timeout
+	timeout, partial = s.catalogTimeout(withDeadline(time.Now().Add(10 * time.Minute)))
+	assert.InDelta(t, 10*time.Minute, timeout, float64(time.Minute))
+	assert.True(t, partial)
+
+	// the deadline is exceeded
+	timeout, partial = s.catalogTimeout(withDeadline(time.Now().Add(-time.Minute)))
+	assert.LessOrEqual(t, timeout, time.Duration(0))
+	assert.True(t, partial)
+
+	// the fast profile is not restricted further
+	s = NewSyftAdapter(time.Hour, 0, WithScanProfile(ScanProfileFast))
+	timeout, partial = s.catalogTimeout(withDeadline(time.Now().Add(-time.Minute)))
+	assert.Equal(t, time.Hour, timeout)
+	assert.False(t, partial)
+}
@@ -0,0 +1,227 @@
+package v1
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/google/go-containerregistry/pkg/name"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// opaqueWhiteout marks the directories whose content in lower layers is deleted by a layer
+const opaqueWhiteout = whiteoutPrefix + whiteoutPrefix + ".opq"
+
+var _ ports.LayerDiffSBOMCreator = (*SyftAdapter)(nil)
+
+// CreateLayerDiffSBOMs catalogs the layers of the image missing from the base and those of the base missing from the
+// image, the layers they share are not downloaded unless the image overwrites package databases or deletes files of
+// them, whose version in the base is then read from its shared layers. The extraction sandbox reads the images whole.
+func (s *SyftAdapter) CreateLayerDiffSBOMs(ctx context.Context, baseID, imageID string, options domain.RegistryOptions) (domain.LayerDiffSBOMs, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "SyftAdapter.CreateLayerDiffSBOMs")
+	defer span.End()
+
+	var diff domain.LayerDiffSBOMs
+	baseLayers, err := s.imageLayers(ctx, baseID, options)
+	if err != nil {
+		return diff, domain.Categorize(domain.ErrImagePull, err)
+	}
+	inBase := map[string]bool{}
+	for _, layer := range baseLayers {
+		inBase[layer] = true
+	}
+	shared := map[string]bool{}
+	overwritten := newOverwrittenPaths()
+	diff.Image, err = s.createSBOM(ctx, imageID, imageID, options, func(img containerregistryV1.Image) (containerregistryV1.Image, error) {
+		layers, err := layerDiffIDs(img)
+		if err != nil {
+			return nil, err
+		}
+		for _, layer := range layers {
+			if inBase[layer] {
+				shared[layer] = true
+				diff.SharedLayers++
+			} else {
+				diff.AddedLayers = append(diff.AddedLayers, layer)
+			}
+		}
+		if s.sandboxBinary != "" {
+			return img, nil
+		}
+		return layerDiffImage{Image: img, whole: func(layer string) bool { return !shared[layer] }, record: overwritten.record}, nil
+	})
+	if err != nil || diff.Image.Status == instanceidhandler.Incomplete {
+		return diff, domain.Categorize(domain.ErrSBOM, err)
+	}
+	for _, layer := range baseLayers {
+		if !shared[layer] {
+			diff.RemovedLayers = append(diff.RemovedLayers, layer)
+		}
+	}
+	diff.Base, err = s.createSBOM(ctx, baseID, baseID, options, func(img containerregistryV1.Image) (containerregistryV1.Image, error) {
+		if s.sandboxBinary != "" {
+			return img, nil
+		}
+		return layerDiffImage{Image: img, whole: func(layer string) bool { return !shared[layer] }, filter: overwritten}, nil
+	})
+	return diff, domain.Categorize(domain.ErrSBOM, err)
+}
+
+// imageLayers returns the diff IDs of the layers of the image, read from its config
+func (s *SyftAdapter) imageLayers(ctx context.Context, imageID string, options domain.RegistryOptions) ([]string, error) {
+	if options.Platform == "" {
+		options.Platform = runtime.GOARCH
+	}
+	registryOptions := s.withRegistryAuth(ctx, imageID, domainToRegistryOptions(options))
+	ref, err := name.ParseReference(imageID, prepareReferenceOptions(registryOptions)...)
+	if err != nil {
+		return nil, err
+	}
+	platform, err := image.NewPlatform(registryOptions.Platform)
+	if err != nil {
+		return nil, err
+	}
+	img, err := remote.Image(ref, prepareRemoteOptions(ctx, ref, registryOptions, platform)...)
+	if err != nil {
+		return nil, err
+	}
+	return layerDiffIDs(img)
+}
+
+func layerDiffIDs(img containerregistryV1.Image) ([]string, error) {
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	layers := make([]string, 0, len(config.RootFS.DiffIDs))
+	for _, diffID := range config.RootFS.DiffIDs {
+		layers = append(layers, diffID.String())
+	}
+	return layers, nil
+}
+
+// overwrittenPaths collects the package databases the added layers of an image overwrite and the paths they delete
+type overwrittenPaths struct {
+	files map[string]bool
+	mu    sync.Mutex
+	trees []string
+}
+
+func newOverwrittenPaths() *overwrittenPaths {
+	return &overwrittenPaths{files: map[string]bool{}}
+}
+
+// record notes the entry of an added layer if it overwrites a package database or deletes a path
+func (o *overwrittenPaths) record(header *tar.Header) {
+	name := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+	dir, base := path.Split(name)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	switch {
+	case base == opaqueWhiteout:
+		o.trees = append(o.trees, strings.TrimSuffix(dir, "/"))
+	case strings.HasPrefix(base, whiteoutPrefix):
+		o.trees = append(o.trees, dir+strings.TrimPrefix(base, whiteoutPrefix))
+	case header.Typeflag != tar.TypeDir && header.Typeflag != tar.TypeSymlink && keepOSPackageEntry(name, header.Typeflag):
+		o.files[name] = true
+	}
+}
+
+func (o *overwrittenPaths) empty() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.files) == 0 && len(o.trees) == 0
+}
+
+// keep tells whether an entry of a shared layer is read, the directories, symlinks and whiteouts are kept so that
+// the overwritten paths resolve as in the whole image
+func (o *overwrittenPaths) keep(header *tar.Header) bool {
+	if header.Typeflag == tar.TypeDir || header.Typeflag == tar.TypeSymlink {
+		return true
+	}
+	name := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+	if strings.HasPrefix(path.Base(name), whiteoutPrefix) {
+		return true
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.files[name] {
+		return true
+	}
+	for _, tree := range o.trees {
+		if tree == "" || name == tree || strings.HasPrefix(name, tree+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// layerDiffImage reads whole the layers selected by whole, passing their entries to record, and of the others only
+// the entries kept by filter, without downloading them when filter keeps nothing
+type layerDiffImage struct {
+	containerregistryV1.Image
+	filter *overwrittenPaths
+	record func(*tar.Header)
+	whole  func(layer string) bool
+}
+
+func (i layerDiffImage) Layers() ([]containerregistryV1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	wrapped := make([]containerregistryV1.Layer, 0, len(layers))
+	for _, layer := range layers {
+		diffID, err := layer.DiffID()
+		if err != nil {
+			return nil, err
+		}
+		wrapped = append(wrapped, layerDiffLayer{Layer: layer, image: i, whole: i.whole(diffID.String())})
+	}
+	return wrapped, nil
+}
+
+type layerDiffLayer struct {
+	containerregistryV1.Layer
+	image layerDiffImage
+	whole bool
+}
+
+func (l layerDiffLayer) Uncompressed() (io.ReadCloser, error) {
+	if !l.whole && (l.image.filter == nil || l.image.filter.empty()) {
+		return emptyLayer()
+	}
+	rc, err := l.Layer.Uncompressed()
+	switch {
+	case err != nil:
+		return nil, err
+	case !l.whole:
+		return filterEntries(rc, l.image.filter.keep), nil
+	case l.image.record != nil:
+		return filterEntries(rc, func(header *tar.Header) bool {
+			l.image.record(header)
+			return true
+		}), nil
+	}
+	return rc, nil
+}
+
+// emptyLayer returns an empty tar archive
+func emptyLayer() (io.ReadCloser, error) {
+	var buf bytes.Buffer
+	if err := tar.NewWriter(&buf).Close(); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(&buf), nil
+}
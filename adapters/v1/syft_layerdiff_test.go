@@ -0,0 +1,168 @@
+package v1
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_overwrittenPaths(t *testing.T) {
+	o := newOverwrittenPaths()
+	assert.True(t, o.empty())
+	for _, header := range []*tar.Header{
+		{Name: "app/vendor/composer/installed.json", Typeflag: tar.TypeReg},
+		{Name: "lib/", Typeflag: tar.TypeDir},
+		{Name: "lib/apk/db/installed", Typeflag: tar.TypeReg},
+		{Name: "usr/lib/python3/.wh.requests", Typeflag: tar.TypeReg},
+		{Name: "opt/.wh..wh..opq", Typeflag: tar.TypeReg},
+	} {
+		o.record(header)
+	}
+	assert.False(t, o.empty())
+	tests := []struct {
+		header *tar.Header
+		want   bool
+	}{
+		{header: &tar.Header{Name: "lib/apk/db/installed", Typeflag: tar.TypeReg}, want: true},
+		{header: &tar.Header{Name: "/usr/lib/python3/requests/__init__.py", Typeflag: tar.TypeReg}, want: true},
+		{header: &tar.Header{Name: "opt/app.jar", Typeflag: tar.TypeReg}, want: true},
+		{header: &tar.Header{Name: "usr/", Typeflag: tar.TypeDir}, want: true},
+		{header: &tar.Header{Name: "usr/lib/.wh.libssl.so", Typeflag: tar.TypeReg}, want: true},
+		// the files of the added layers which are no package databases are cataloged in the image only
+		{header: &tar.Header{Name: "app/vendor/composer/installed.json", Typeflag: tar.TypeReg}},
+		{header: &tar.Header{Name: "usr/lib/python3/requests-2.31.0.dist-info/METADATA", Typeflag: tar.TypeReg}},
+		{header: &tar.Header{Name: "optional", Typeflag: tar.TypeReg}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header.Name, func(t *testing.T) {
+			assert.Equal(t, tt.want, o.keep(tt.header))
+		})
+	}
+}
+
+// layersImage returns an OCI image made of gzip layers built from the tar archives
+func layersImage(t *testing.T, contents ...[]byte) containerregistryV1.Image {
+	img := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	for _, content := range contents {
+		content := content
+		layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(content)), nil
+		}, tarball.WithMediaType(types.OCILayer))
+		require.NoError(t, err)
+		img, err = mutate.AppendLayers(img, layer)
+		require.NoError(t, err)
+	}
+	return img
+}
+
+// sbomPackages lists the packages of an SBOM as name@version
+func sbomPackages(sbom domain.SBOM) []string {
+	var packages []string
+	for _, p := range sbom.Content.Packages {
+		packages = append(packages, p.PackageName+"@"+p.PackageVersion)
+	}
+	sort.Strings(packages)
+	return packages
+}
+
+func TestSyftAdapter_CreateLayerDiffSBOMs(t *testing.T) {
+	// count the blobs downloaded from the registry
+	var blobs atomic.Int64
+	handler := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/blobs/") {
+			blobs.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	push := func(tag string, img containerregistryV1.Image) string {
+		ref, err := name.ParseReference(strings.TrimPrefix(srv.URL, "http://") + "/app:" + tag)
+		require.NoError(t, err)
+		require.NoError(t, remote.Write(ref, img))
+		digest, err := img.Digest()
+		require.NoError(t, err)
+		return ref.Context().Digest(digest.String()).String()
+	}
+	osLayer := layerTar(t, map[string]string{
+		"etc/os-release":       "ID=alpine\nVERSION_ID=3.17.3\n",
+		"lib/apk/db/installed": apkInstalled,
+	})
+	baseApp := layerTar(t, map[string]string{
+		"app/vendor/composer/installed.json": `{"packages":[{"name":"monolog/monolog","version":"2.9.1"}]}`,
+	})
+	imageApp := layerTar(t, map[string]string{
+		"app/vendor/composer/installed.json": `{"packages":[{"name":"monolog/monolog","version":"2.9.2"}]}`,
+	})
+	// the image installs a package, rewriting the apk database of the shared layer
+	packages := layerTar(t, map[string]string{
+		"lib/apk/db/installed": apkInstalled + "P:zlib\nV:1.2.13-r0\nA:x86_64\nL:Zlib\no:zlib\n\n",
+	})
+	v1 := push("v1", layersImage(t, osLayer, baseApp))
+	v2 := push("v2", layersImage(t, osLayer, imageApp))
+	v3 := push("v3", layersImage(t, osLayer, packages, imageApp))
+	options := domain.RegistryOptions{InsecureUseHTTP: true}
+
+	tests := []struct {
+		name          string
+		image         string
+		wantBase      []string
+		wantImage     []string
+		wantAdded     int
+		wantBlobsRead int64
+	}{
+		{
+			name:      "the shared layer is not downloaded",
+			image:     v2,
+			wantBase:  []string{"monolog/monolog@2.9.1"},
+			wantImage: []string{"monolog/monolog@2.9.2"},
+			wantAdded: 1,
+			// the configs of the images and their app layers
+			wantBlobsRead: 5,
+		},
+		{
+			name:      "the overwritten package database is read from the shared layer",
+			image:     v3,
+			wantBase:  []string{"monolog/monolog@2.9.1", "musl@1.2.3-r4"},
+			wantImage: []string{"monolog/monolog@2.9.2", "musl@1.2.3-r4", "zlib@1.2.13-r0"},
+			wantAdded: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blobs.Store(0)
+			s := NewSyftAdapter(time.Minute, 1<<30)
+			sboms, err := s.CreateLayerDiffSBOMs(context.TODO(), v1, tt.image, options)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBase, sbomPackages(sboms.Base))
+			assert.Equal(t, tt.wantImage, sbomPackages(sboms.Image))
+			assert.Len(t, sboms.AddedLayers, tt.wantAdded)
+			assert.Len(t, sboms.RemovedLayers, 1)
+			assert.Equal(t, 1, sboms.SharedLayers)
+			if tt.wantBlobsRead > 0 {
+				assert.Equal(t, tt.wantBlobsRead, blobs.Load())
+			}
+		})
+	}
+}
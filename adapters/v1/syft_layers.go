@@ -0,0 +1,140 @@
+package v1
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/containerd/stargz-snapshotter/estargz"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// ociRestrictedLayerZStd is the media type of the non-distributable zstd layers, go-containerregistry has no constant for it
+const ociRestrictedLayerZStd types.MediaType = "application/vnd.oci.image.layer.nondistributable.v1.tar+zstd"
+
+// ErrUnsupportedLayer is returned for images with layers stereoscope cannot read
+var ErrUnsupportedLayer = fmt.Errorf("unsupported layer media type")
+
+// zstdMediaTypes maps the zstd layer media types to the gzip ones stereoscope reads, go-containerregistry
+// decompresses the layers whatever their compression
+var zstdMediaTypes = map[types.MediaType]types.MediaType{
+	types.OCILayerZStd:     types.OCILayer,
+	ociRestrictedLayerZStd: types.OCIRestrictedLayer,
+}
+
+// readableMediaTypes are the layer media types stereoscope reads
+var readableMediaTypes = map[types.MediaType]bool{
+	types.OCILayer:                       true,
+	types.OCIUncompressedLayer:           true,
+	types.OCIRestrictedLayer:             true,
+	types.OCIUncompressedRestrictedLayer: true,
+	types.DockerLayer:                    true,
+	types.DockerForeignLayer:             true,
+	types.DockerUncompressedLayer:        true,
+	image.SingularitySquashFSLayer:       true,
+}
+
+// estargzEntries are the metadata entries eStargz layers add for lazy pulling, they are not part of the image filesystem
+var estargzEntries = map[string]bool{
+	estargz.TOCTarName:         true,
+	estargz.PrefetchLandmark:   true,
+	estargz.NoPrefetchLandmark: true,
+}
+
+// withReadableLayers wraps the image so that stereoscope reads its zstd and eStargz layers
+func withReadableLayers(img containerregistryV1.Image) containerregistryV1.Image {
+	return readableImage{Image: img}
+}
+
+// readableImage presents the zstd layers as gzip ones and strips the eStargz metadata entries
+type readableImage struct {
+	containerregistryV1.Image
+}
+
+func (i readableImage) Layers() ([]containerregistryV1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	// the layers are listed in the order of the manifest, which holds their annotations
+	manifest, err := i.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	wrapped := make([]containerregistryV1.Layer, 0, len(layers))
+	for idx, layer := range layers {
+		mediaType, err := layer.MediaType()
+		if err != nil {
+			return nil, err
+		}
+		if readAs, ok := zstdMediaTypes[mediaType]; ok {
+			mediaType = readAs
+		}
+		if !readableMediaTypes[mediaType] {
+			digest, _ := layer.Digest()
+			return nil, fmt.Errorf("%w %q of layer %s, supported layers are uncompressed, gzip, zstd or eStargz tar archives", ErrUnsupportedLayer, mediaType, digest)
+		}
+		var estargzLayer bool
+		if idx < len(manifest.Layers) {
+			_, estargzLayer = manifest.Layers[idx].Annotations[estargz.TOCJSONDigestAnnotation]
+		}
+		wrapped = append(wrapped, readableLayer{Layer: layer, mediaType: mediaType, estargz: estargzLayer})
+	}
+	return wrapped, nil
+}
+
+// readableLayer reports the media type stereoscope reads the layer as
+type readableLayer struct {
+	containerregistryV1.Layer
+	estargz   bool
+	mediaType types.MediaType
+}
+
+func (l readableLayer) MediaType() (types.MediaType, error) {
+	return l.mediaType, nil
+}
+
+func (l readableLayer) Uncompressed() (io.ReadCloser, error) {
+	rc, err := l.Layer.Uncompressed()
+	if err != nil || !l.estargz {
+		return rc, err
+	}
+	return filterEntries(rc, func(header *tar.Header) bool {
+		return !estargzEntries[header.Name]
+	}), nil
+}
+
+// filterEntries copies a tar archive with the entries kept by the given function only
+func filterEntries(rc io.ReadCloser, keep func(*tar.Header) bool) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		defer rc.Close()
+		tr := tar.NewReader(rc)
+		tw := tar.NewWriter(pw)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				pw.CloseWithError(tw.Close())
+				return
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if !keep(header) {
+				continue
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return pr
+}
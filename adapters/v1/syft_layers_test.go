@@ -0,0 +1,132 @@
+package v1
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/anchore/syft/syft/source"
+	"github.com/containerd/stargz-snapshotter/estargz"
+	"github.com/google/go-containerregistry/pkg/compression"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// layerTar returns a tar archive holding the given files
+func layerTar(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+// layerImage returns an OCI image made of a single gzip layer built from a tar archive, with the given annotations,
+// opts may change its compression
+func layerImage(t *testing.T, content []byte, annotations map[string]string, opts ...tarball.LayerOption) containerregistryV1.Image {
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}, append([]tarball.LayerOption{tarball.WithMediaType(types.OCILayer)}, opts...)...)
+	require.NoError(t, err)
+	img, err := mutate.Append(mutate.MediaType(empty.Image, types.OCIManifestSchema1), mutate.Addendum{Layer: layer, Annotations: annotations})
+	require.NoError(t, err)
+	return img
+}
+
+// tarNames lists the entries of a tar archive
+func tarNames(t *testing.T, rc io.ReadCloser) []string {
+	defer rc.Close()
+	var names []string
+	tr := tar.NewReader(rc)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return names
+		}
+		require.NoError(t, err)
+		names = append(names, header.Name)
+	}
+}
+
+func Test_withReadableLayers(t *testing.T) {
+	content := layerTar(t, map[string]string{"etc/os-release": "ID=alpine\n"})
+	// eStargz layers are gzip tar archives with the TOC and landmark entries
+	estargzContent := layerTar(t, map[string]string{
+		"etc/os-release":         "ID=alpine\n",
+		estargz.PrefetchLandmark: "\x0f",
+		estargz.TOCTarName:       "{}",
+	})
+	tests := []struct {
+		name        string
+		content     []byte
+		annotations map[string]string
+		opts        []tarball.LayerOption
+	}{
+		{
+			name:    "gzip",
+			content: content,
+		},
+		{
+			name:    "zstd",
+			content: content,
+			opts:    []tarball.LayerOption{tarball.WithCompression(compression.ZStd), tarball.WithMediaType(types.OCILayerZStd)},
+		},
+		{
+			name:        "eStargz",
+			content:     estargzContent,
+			annotations: map[string]string{estargz.TOCJSONDigestAnnotation: "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layers, err := withReadableLayers(layerImage(t, tt.content, tt.annotations, tt.opts...)).Layers()
+			require.NoError(t, err)
+			require.Len(t, layers, 1)
+			mediaType, err := layers[0].MediaType()
+			require.NoError(t, err)
+			// stereoscope reads them all as gzip ones
+			assert.Equal(t, types.OCILayer, mediaType)
+			rc, err := layers[0].Uncompressed()
+			require.NoError(t, err)
+			// the eStargz metadata entries are stripped
+			assert.Equal(t, []string{"etc/os-release"}, tarNames(t, rc))
+		})
+	}
+}
+
+func Test_withReadableLayers_unsupported(t *testing.T) {
+	layer, err := random.Layer(64, "application/vnd.oci.image.layer.v1.tar+lz4")
+	require.NoError(t, err)
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	require.NoError(t, err)
+	_, err = withReadableLayers(img).Layers()
+	assert.ErrorIs(t, err, ErrUnsupportedLayer)
+}
+
+func Test_newFromImage_zstd(t *testing.T) {
+	w, err := newWorkspace(t.TempDir())
+	require.NoError(t, err)
+	defer func() { _ = w.Cleanup() }()
+	sourceInput, err := source.ParseInput("alpine:3.17", "amd64")
+	require.NoError(t, err)
+	content := layerTar(t, map[string]string{"etc/os-release": "ID=alpine\n"})
+	img := layerImage(t, content, nil, tarball.WithCompression(compression.ZStd), tarball.WithMediaType(types.OCILayerZStd))
+	src, err := newFromImage(w, sourceInput, img, nil, 1<<30)
+	require.NoError(t, err)
+	resolver, err := src.FileResolver(source.SquashedScope)
+	require.NoError(t, err)
+	locations, err := resolver.FilesByPath("/etc/os-release")
+	require.NoError(t, err)
+	assert.Len(t, locations, 1)
+}
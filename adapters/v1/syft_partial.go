@@ -0,0 +1,326 @@
+package v1
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/containerd/stargz-snapshotter/estargz"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/internal/tools"
+)
+
+// scan profiles selectable in the configuration
+const (
+	// ScanProfileFull catalogs all the packages of the images (default)
+	ScanProfileFull = "full"
+	// ScanProfileFast only catalogs the OS packages, reading their databases instead of whole layers when possible
+	ScanProfileFast = "fast"
+)
+
+// osPackageCatalogers are the catalogers run by the fast profile
+var osPackageCatalogers = map[string]bool{
+	"apkdb-cataloger":  true,
+	"dpkgdb-cataloger": true,
+	"rpm-db-cataloger": true,
+}
+
+// osPackagePaths are the files read by the fast profile: the OS package databases and the release files
+// identifying the distribution
+var osPackagePaths = []string{
+	"bin/busybox",
+	"etc/os-release",
+	"etc/redhat-release",
+	"etc/system-release-cpe",
+	"lib/apk/db/installed",
+	"usr/lib/os-release",
+	"usr/lib/sysimage/rpm",
+	"var/lib/dpkg/status",
+	"var/lib/dpkg/status.d",
+	"var/lib/rpm",
+}
+
+// whiteoutPrefix marks the files of lower layers deleted by a layer
+const whiteoutPrefix = ".wh."
+
+// estargzTypes maps the eStargz TOC entry types to the tar ones, devices and fifos are never read
+var estargzTypes = map[string]byte{
+	"dir":      tar.TypeDir,
+	"hardlink": tar.TypeLink,
+	"reg":      tar.TypeReg,
+	"symlink":  tar.TypeSymlink,
+}
+
+// WithScanProfile sets the scan profile, see ScanProfileFull and ScanProfileFast
+func WithScanProfile(profile string) SyftAdapterOption {
+	return func(s *SyftAdapter) {
+		s.scanProfile = profile
+	}
+}
+
+// keepOSPackageEntry tells whether the fast profile reads a layer entry, the directories, symlinks and whiteouts
+// are kept so that the paths of the package databases resolve as in the whole image
+func keepOSPackageEntry(name string, typeflag byte) bool {
+	if typeflag == tar.TypeDir || typeflag == tar.TypeSymlink {
+		return true
+	}
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if strings.HasPrefix(path.Base(name), whiteoutPrefix) {
+		return true
+	}
+	for _, p := range osPackagePaths {
+		if name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// registryImage is an image fetched from a registry, whose layer blobs can be read by ranges
+type registryImage struct {
+	containerregistryV1.Image
+	blobs *registryBlobs
+}
+
+// registryBlobs reads ranges of the blobs of a registry repository
+type registryBlobs struct {
+	client          *http.Client
+	err             error
+	once            sync.Once
+	ref             name.Reference
+	registryOptions image.RegistryOptions
+}
+
+// newRegistryBlobs initializes the registryBlobs struct, the registry is only contacted on the first read
+func newRegistryBlobs(ref name.Reference, registryOptions image.RegistryOptions) *registryBlobs {
+	return &registryBlobs{ref: ref, registryOptions: registryOptions}
+}
+
+// readerAt returns a reader of the blob of the given digest, each of its reads is a ranged request
+func (b *registryBlobs) readerAt(ctx context.Context, digest containerregistryV1.Hash) (io.ReaderAt, error) {
+	repository := b.ref.Context()
+	b.once.Do(func() {
+		authenticator := b.registryOptions.Authenticator(repository.RegistryStr())
+		if authenticator == nil {
+			authenticator, b.err = authn.DefaultKeychain.Resolve(repository)
+			if b.err != nil {
+				return
+			}
+		}
+		var rt http.RoundTripper
+		rt, b.err = transport.NewWithContext(ctx, repository.Registry, authenticator, registryTransport(b.registryOptions), []string{repository.Scope(transport.PullScope)})
+		b.client = &http.Client{Transport: rt}
+	})
+	if b.err != nil {
+		return nil, b.err
+	}
+	return blobReaderAt{
+		client: b.client,
+		ctx:    ctx,
+		url:    fmt.Sprintf("%s://%s/v2/%s/blobs/%s", repository.Scheme(), repository.RegistryStr(), repository.RepositoryStr(), digest),
+	}, nil
+}
+
+// blobReaderAt reads a registry blob with HTTP range requests
+type blobReaderAt struct {
+	client *http.Client
+	ctx    context.Context
+	url    string
+}
+
+func (b blobReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	req, err := http.NewRequestWithContext(b.ctx, http.MethodGet, b.url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("registry did not serve the range of blob %s: status %d", b.url, resp.StatusCode)
+	}
+	n, err := io.ReadFull(resp.Body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// withOSPackageFiles wraps the image so that only the entries read by the fast profile are extracted from its layers,
+// the eStargz layers of registry images are read by ranges to only download the kept files
+func withOSPackageFiles(ctx context.Context, img containerregistryV1.Image, maxImageSize int64) containerregistryV1.Image {
+	wrapped := osPackageImage{Image: img, ctx: ctx, maxImageSize: maxImageSize}
+	if ranged, ok := img.(registryImage); ok {
+		wrapped.blobs = ranged.blobs
+	}
+	return wrapped
+}
+
+// osPackageImage restricts its layers to the entries read by the fast profile
+type osPackageImage struct {
+	containerregistryV1.Image
+	blobs        *registryBlobs
+	ctx          context.Context
+	maxImageSize int64
+}
+
+func (i osPackageImage) Layers() ([]containerregistryV1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := i.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	wrapped := make([]containerregistryV1.Layer, 0, len(layers))
+	for idx, layer := range layers {
+		var estargzLayer bool
+		if idx < len(manifest.Layers) {
+			_, estargzLayer = manifest.Layers[idx].Annotations[estargz.TOCJSONDigestAnnotation]
+		}
+		wrapped = append(wrapped, osPackageLayer{Layer: layer, blobs: i.blobs, ctx: i.ctx, estargz: estargzLayer, maxImageSize: i.maxImageSize})
+	}
+	return wrapped, nil
+}
+
+// osPackageLayer extracts the entries read by the fast profile
+type osPackageLayer struct {
+	containerregistryV1.Layer
+	blobs        *registryBlobs
+	ctx          context.Context
+	estargz      bool
+	maxImageSize int64
+}
+
+func (l osPackageLayer) Uncompressed() (io.ReadCloser, error) {
+	if l.estargz && l.blobs != nil {
+		rc, err := l.readPartially()
+		if err == nil {
+			return rc, nil
+		}
+		digest, _ := l.Digest()
+		logger.L().Debug("failed to read the package databases of the eStargz layer, reading it whole", helpers.Error(err),
+			helpers.String("layer", digest.String()))
+	}
+	rc, err := l.Layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	return filterEntries(rc, func(header *tar.Header) bool {
+		return keepOSPackageEntry(header.Name, header.Typeflag)
+	}), nil
+}
+
+// readPartially reads the TOC of the eStargz layer and the chunks of the kept files, which it returns as a tar archive
+func (l osPackageLayer) readPartially() (io.ReadCloser, error) {
+	digest, err := l.Digest()
+	if err != nil {
+		return nil, err
+	}
+	size, err := l.Size()
+	if err != nil {
+		return nil, err
+	}
+	ra, err := l.blobs.readerAt(l.ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+	r, err := estargz.Open(io.NewSectionReader(ra, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	root, ok := r.Lookup("")
+	if !ok {
+		return nil, fmt.Errorf("eStargz layer %s has no root entry", digest)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		var err error
+		defer func() { pw.CloseWithError(err) }()
+		defer tools.RecoverPanic(l.ctx, &err)
+		tw := tar.NewWriter(pw)
+		if err = writeOSPackageEntries(r, root, tw, l.maxImageSize); err != nil {
+			return
+		}
+		err = tw.Close()
+	}()
+	return pr, nil
+}
+
+// writeOSPackageEntries writes the entries of the directory kept by the fast profile, in name order, the sizes come
+// from the TOC of the layer and are checked against maxImageSize before anything is read
+func writeOSPackageEntries(r *estargz.Reader, dir *estargz.TOCEntry, tw *tar.Writer, maxImageSize int64) error {
+	var names []string
+	dir.ForeachChild(func(baseName string, _ *estargz.TOCEntry) bool {
+		names = append(names, baseName)
+		return true
+	})
+	sort.Strings(names)
+	for _, baseName := range names {
+		entry, _ := dir.LookupChild(baseName)
+		typeflag, ok := estargzTypes[entry.Type]
+		if !ok || estargzEntries[entry.Name] || !keepOSPackageEntry(entry.Name, typeflag) {
+			continue
+		}
+		header := &tar.Header{
+			Typeflag: typeflag,
+			Name:     entry.Name,
+			Linkname: entry.LinkName,
+			Mode:     entry.Mode,
+			Uid:      entry.UID,
+			Gid:      entry.GID,
+			Uname:    entry.Uname,
+			Gname:    entry.Gname,
+			ModTime:  entry.ModTime(),
+		}
+		switch typeflag {
+		case tar.TypeDir:
+			header.Name += "/"
+		case tar.TypeReg:
+			if entry.Size < 0 || entry.Size > maxImageSize {
+				return fmt.Errorf("eStargz entry %s has an invalid size of %d bytes", entry.Name, entry.Size)
+			}
+			header.Size = entry.Size
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		switch typeflag {
+		case tar.TypeDir:
+			if err := writeOSPackageEntries(r, entry, tw, maxImageSize); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if entry.Size == 0 {
+				continue
+			}
+			sr, err := r.OpenFile(entry.Name)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(tw, io.NewSectionReader(sr, 0, entry.Size)); err != nil {
+				return fmt.Errorf("failed to read %s: %w", entry.Name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package v1
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/anchore/syft/syft/source"
+	"github.com/containerd/stargz-snapshotter/estargz"
+	"github.com/google/go-containerregistry/pkg/name"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apkInstalled is an apk database holding a single package
+const apkInstalled = "P:musl\nV:1.2.3-r4\nA:x86_64\nL:MIT\no:musl\n\n"
+
+// estargzCompressor writes the eStargz footer by hand, the gzip writer of recent Go versions no longer fits it
+// in the 51 bytes the estargz writer expects
+type estargzCompressor struct {
+	*estargz.GzipCompressor
+}
+
+func (c estargzCompressor) WriteTOCAndFooter(w io.Writer, off int64, toc *estargz.JTOC, diffHash hash.Hash) (digest.Digest, error) {
+	tocJSON, err := json.Marshal(toc)
+	if err != nil {
+		return "", err
+	}
+	gz, err := c.Writer(w)
+	if err != nil {
+		return "", err
+	}
+	tw := tar.NewWriter(io.MultiWriter(gz, diffHash))
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: estargz.TOCTarName, Size: int64(len(tocJSON))}); err != nil {
+		return "", err
+	}
+	if _, err := tw.Write(tocJSON); err != nil {
+		return "", err
+	}
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+	// an empty gzip member whose extra field holds the TOC offset
+	subfield := fmt.Sprintf("%016xSTARGZ", off)
+	footer := []byte{0x1f, 0x8b, 8, 4, 0, 0, 0, 0, 0, 0xff}
+	footer = binary.LittleEndian.AppendUint16(footer, uint16(4+len(subfield)))
+	footer = append(footer, 'S', 'G')
+	footer = binary.LittleEndian.AppendUint16(footer, uint16(len(subfield)))
+	footer = append(footer, subfield...)
+	footer = append(footer, 1, 0, 0, 0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0)
+	if _, err := w.Write(footer); err != nil {
+		return "", err
+	}
+	return digest.FromBytes(tocJSON), nil
+}
+
+// estargzBlob converts a tar archive into an eStargz blob
+func estargzBlob(t *testing.T, content []byte) []byte {
+	var buf bytes.Buffer
+	w := estargz.NewWriterWithCompressor(&buf, estargzCompressor{estargz.NewGzipCompressor()})
+	require.NoError(t, w.AppendTar(bytes.NewReader(content)))
+	_, err := w.Close()
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+// blobServer serves a registry holding a single blob, with range requests when ranged, and counts the bytes sent
+func blobServer(t *testing.T, blob []byte, ranged bool, sent *atomic.Int64) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, "/v2/repo/blobs/") {
+			http.NotFound(w, r)
+			return
+		}
+		cw := &countingWriter{ResponseWriter: w, sent: sent}
+		if !ranged {
+			_, _ = cw.Write(blob)
+			return
+		}
+		http.ServeContent(cw, r, "", time.Time{}, bytes.NewReader(blob))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	sent *atomic.Int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.sent.Add(int64(len(p)))
+	return w.ResponseWriter.Write(p)
+}
+
+// registryLayerImage returns the image of a single layer made of the blob, as fetched from the registry of srv
+func registryLayerImage(t *testing.T, srv *httptest.Server, blob []byte, annotations map[string]string) containerregistryV1.Image {
+	ref, err := name.ParseReference(strings.TrimPrefix(srv.URL, "http://")+"/repo:latest", name.Insecure)
+	require.NoError(t, err)
+	return registryImage{
+		Image: layerImage(t, blob, annotations),
+		blobs: newRegistryBlobs(ref, image.RegistryOptions{InsecureUseHTTP: true}),
+	}
+}
+
+func Test_keepOSPackageEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		typeflag byte
+		want     bool
+	}{
+		{name: "var/lib/dpkg/status", typeflag: tar.TypeReg, want: true},
+		{name: "./var/lib/dpkg/status.d/base", typeflag: tar.TypeReg, want: true},
+		{name: "/lib/apk/db/installed", typeflag: tar.TypeReg, want: true},
+		{name: "usr/lib/sysimage/rpm/rpmdb.sqlite", typeflag: tar.TypeReg, want: true},
+		{name: "etc/os-release", typeflag: tar.TypeSymlink, want: true},
+		{name: "usr/bin", typeflag: tar.TypeDir, want: true},
+		{name: "var/lib/dpkg/.wh.status", typeflag: tar.TypeReg, want: true},
+		{name: "var/lib/dpkg/status-old", typeflag: tar.TypeReg},
+		{name: "usr/bin/python3", typeflag: tar.TypeReg},
+		{name: "app/vendor/composer/installed.json", typeflag: tar.TypeLink},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, keepOSPackageEntry(tt.name, tt.typeflag))
+		})
+	}
+}
+
+func Test_withOSPackageFiles(t *testing.T) {
+	// the uncompressible binary makes up most of the layer
+	binary := make([]byte, 1<<20)
+	_, err := rand.Read(binary)
+	require.NoError(t, err)
+	content := layerTar(t, map[string]string{
+		"etc/os-release":       "ID=alpine\n",
+		"lib/apk/db/installed": apkInstalled,
+		"usr/bin/app":          string(binary),
+	})
+	want := []string{"etc/os-release", "lib/apk/db/installed"}
+	blob := estargzBlob(t, content)
+	annotations := map[string]string{estargz.TOCJSONDigestAnnotation: "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"}
+	tests := []struct {
+		name        string
+		img         func(t *testing.T, sent *atomic.Int64) containerregistryV1.Image
+		wantEntries []string
+	}{
+		{
+			name: "gzip layer is filtered",
+			img: func(t *testing.T, _ *atomic.Int64) containerregistryV1.Image {
+				return layerImage(t, content, nil)
+			},
+		},
+		{
+			name: "eStargz layer is read by ranges",
+			img: func(t *testing.T, sent *atomic.Int64) containerregistryV1.Image {
+				return registryLayerImage(t, blobServer(t, blob, true, sent), blob, annotations)
+			},
+			wantEntries: []string{"etc/", "etc/os-release", "lib/", "lib/apk/", "lib/apk/db/", "lib/apk/db/installed", "usr/", "usr/bin/"},
+		},
+		{
+			name: "eStargz layer is read whole without range support",
+			img: func(t *testing.T, sent *atomic.Int64) containerregistryV1.Image {
+				return registryLayerImage(t, blobServer(t, blob, false, sent), blob, annotations)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent atomic.Int64
+			layers, err := withOSPackageFiles(context.TODO(), tt.img(t, &sent), 1<<30).Layers()
+			require.NoError(t, err)
+			require.Len(t, layers, 1)
+			rc, err := layers[0].Uncompressed()
+			require.NoError(t, err)
+			names := tarNames(t, rc)
+			var files []string
+			for _, n := range names {
+				if !strings.HasSuffix(n, "/") && !estargzEntries[n] {
+					files = append(files, n)
+				}
+			}
+			assert.ElementsMatch(t, want, files)
+			// the partial reads list the directories of the TOC and only download a fraction of the blob
+			if tt.wantEntries != nil {
+				assert.Equal(t, tt.wantEntries, names)
+				assert.Less(t, sent.Load(), int64(len(blob)/10))
+			}
+		})
+	}
+}
+
+func Test_osPackageLayer_readPartially_oversized(t *testing.T) {
+	content := layerTar(t, map[string]string{
+		"etc/os-release":       "ID=alpine\n",
+		"lib/apk/db/installed": apkInstalled,
+	})
+	blob := estargzBlob(t, content)
+	var sent atomic.Int64
+	img := registryLayerImage(t, blobServer(t, blob, true, &sent), blob, map[string]string{estargz.TOCJSONDigestAnnotation: "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"})
+	// the entries larger than the maximum size of the image are refused before being read
+	layers, err := withOSPackageFiles(context.TODO(), img, 8).Layers()
+	require.NoError(t, err)
+	require.Len(t, layers, 1)
+	rc, err := layers[0].(osPackageLayer).readPartially()
+	require.NoError(t, err)
+	_, err = io.Copy(io.Discard, rc)
+	assert.ErrorContains(t, err, "invalid size")
+}
+
+func TestSyftAdapter_catalogPackages_fast(t *testing.T) {
+	w, err := newWorkspace(t.TempDir())
+	require.NoError(t, err)
+	defer func() { _ = w.Cleanup() }()
+	sourceInput, err := source.ParseInput("alpine:3.17", "amd64")
+	require.NoError(t, err)
+	content := layerTar(t, map[string]string{
+		"etc/os-release":                     "ID=alpine\nVERSION_ID=3.17.3\n",
+		"lib/apk/db/installed":               apkInstalled,
+		"app/vendor/composer/installed.json": `{"packages":[{"name":"monolog/monolog","version":"2.9.1"}]}`,
+	})
+	img := withOSPackageFiles(context.TODO(), layerImage(t, content, nil), 1<<30)
+	src, err := newFromImage(w, sourceInput, img, nil, 1<<30)
+	require.NoError(t, err)
+	s := NewSyftAdapter(0, 0, WithScanProfile(ScanProfileFast))
+	catalog, _, release, _, _, err := s.catalogPackages(context.TODO(), &src)
+	require.NoError(t, err)
+	var got []string
+	for _, p := range catalog.Sorted() {
+		got = append(got, p.Name+"@"+p.Version)
+	}
+	assert.Equal(t, []string{"musl@1.2.3-r4"}, got)
+	require.NotNil(t, release)
+	assert.Equal(t, "alpine", release.ID)
+}
@@ -0,0 +1,164 @@
+package v1
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/anchore/syft/syft/artifact"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/source"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+)
+
+// pullProgress reports the pull progress of an image in compressed bytes, as each of its layers is read
+type pullProgress struct {
+	ctx context.Context
+	// layers keeps the wrapped layers by digest, so that layers listed again are reported once
+	layers map[containerregistryV1.Hash]*progressLayer
+	mu     sync.Mutex
+	read   int64
+	total  int64
+}
+
+// withPullProgress wraps the image to report its pull progress and record its layers read, the image is returned as
+// is when neither progress nor events are reported for the scan
+func withPullProgress(ctx context.Context, img containerregistryV1.Image) containerregistryV1.Image {
+	_, progress := ctx.Value(domain.ProgressKey{}).(func(domain.Progress))
+	_, events := ctx.Value(domain.ScanEventKey{}).(func(domain.ScanEvent))
+	if !progress && !events {
+		return img
+	}
+	return progressImage{Image: img, progress: &pullProgress{ctx: ctx, layers: map[containerregistryV1.Hash]*progressLayer{}}}
+}
+
+// layerRead adds a layer to the bytes read, reports the progress and records the layer read
+func (p *pullProgress) layerRead(layer *progressLayer) {
+	tools.RecordScanEvent(p.ctx, "layer read", map[string]string{
+		"layer":  strconv.Itoa(layer.number),
+		"digest": layer.digest.String(),
+		"size":   strconv.FormatInt(layer.size, 10),
+	})
+	p.mu.Lock()
+	p.read += layer.size
+	progress := domain.Progress{Stage: domain.StagePull, Unit: domain.ProgressBytes, Current: p.read, Total: p.total}
+	p.mu.Unlock()
+	tools.ReportProgress(p.ctx, progress)
+}
+
+// progressImage reports the pull progress of the layers of an image
+type progressImage struct {
+	containerregistryV1.Image
+	progress *pullProgress
+}
+
+func (i progressImage) Layers() ([]containerregistryV1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+	i.progress.mu.Lock()
+	defer i.progress.mu.Unlock()
+	wrapped := make([]containerregistryV1.Layer, 0, len(layers))
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := i.progress.layers[digest]; !ok {
+			size, err := layer.Size()
+			if err != nil {
+				return nil, err
+			}
+			i.progress.layers[digest] = &progressLayer{Layer: layer, digest: digest, number: len(i.progress.layers) + 1, progress: i.progress, size: size}
+			i.progress.total += size
+		}
+		wrapped = append(wrapped, i.progress.layers[digest])
+	}
+	return wrapped, nil
+}
+
+// progressLayer reports itself read once one of its readers is closed, number is its position in the image from 1
+type progressLayer struct {
+	containerregistryV1.Layer
+	digest   containerregistryV1.Hash
+	number   int
+	once     sync.Once
+	progress *pullProgress
+	size     int64
+}
+
+func (l *progressLayer) Compressed() (io.ReadCloser, error) {
+	rc, err := l.Layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	return progressReadCloser{ReadCloser: rc, layer: l}, nil
+}
+
+func (l *progressLayer) Uncompressed() (io.ReadCloser, error) {
+	rc, err := l.Layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	return progressReadCloser{ReadCloser: rc, layer: l}, nil
+}
+
+type progressReadCloser struct {
+	io.ReadCloser
+	layer *progressLayer
+}
+
+func (r progressReadCloser) Close() error {
+	r.layer.once.Do(func() {
+		r.layer.progress.layerRead(r.layer)
+	})
+	return r.ReadCloser.Close()
+}
+
+// catalogProgress reports the cataloging progress as each cataloger completes, with the packages discovered so far
+type catalogProgress struct {
+	ctx      context.Context
+	mu       sync.Mutex
+	done     int64
+	packages int64
+	total    int64
+}
+
+// withCatalogProgress wraps the catalogers to report the cataloging progress
+func withCatalogProgress(ctx context.Context, catalogers []pkg.Cataloger) []pkg.Cataloger {
+	if _, ok := ctx.Value(domain.ProgressKey{}).(func(domain.Progress)); !ok {
+		return catalogers
+	}
+	progress := &catalogProgress{ctx: ctx, total: int64(len(catalogers))}
+	wrapped := make([]pkg.Cataloger, 0, len(catalogers))
+	for _, cataloger := range catalogers {
+		wrapped = append(wrapped, progressCataloger{Cataloger: cataloger, progress: progress})
+	}
+	return wrapped
+}
+
+type progressCataloger struct {
+	pkg.Cataloger
+	progress *catalogProgress
+}
+
+func (c progressCataloger) Catalog(resolver source.FileResolver) ([]pkg.Package, []artifact.Relationship, error) {
+	packages, relationships, err := c.Cataloger.Catalog(resolver)
+	c.progress.mu.Lock()
+	c.progress.done++
+	c.progress.packages += int64(len(packages))
+	progress := domain.Progress{
+		Stage:    domain.StageCatalog,
+		Unit:     domain.ProgressCatalogers,
+		Current:  c.progress.done,
+		Total:    c.progress.total,
+		Packages: c.progress.packages,
+	}
+	c.progress.mu.Unlock()
+	tools.ReportProgress(c.progress.ctx, progress)
+	return packages, relationships, err
+}
@@ -0,0 +1,105 @@
+package v1
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/anchore/syft/syft/artifact"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/source"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordProgress returns a context collecting the reported progress in events
+func recordProgress(events *[]domain.Progress) context.Context {
+	return context.WithValue(context.Background(), domain.ProgressKey{}, func(progress domain.Progress) {
+		*events = append(*events, progress)
+	})
+}
+
+func Test_withPullProgress(t *testing.T) {
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+	// nothing is wrapped without a progress reporter
+	assert.Equal(t, img, withPullProgress(context.Background(), img))
+
+	var events []domain.Progress
+	wrapped := withPullProgress(recordProgress(&events), img)
+	layers, err := wrapped.Layers()
+	require.NoError(t, err)
+	var total int64
+	for _, layer := range layers {
+		size, err := layer.Size()
+		require.NoError(t, err)
+		total += size
+	}
+	// layers listed again and read twice are counted once
+	_, err = wrapped.Layers()
+	require.NoError(t, err)
+	for _, layer := range append(layers, layers[0]) {
+		rc, err := layer.Compressed()
+		require.NoError(t, err)
+		_, err = io.Copy(io.Discard, rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+	}
+	require.Len(t, events, 2)
+	assert.Equal(t, domain.StagePull, events[1].Stage)
+	assert.Equal(t, domain.ProgressBytes, events[1].Unit)
+	assert.Equal(t, total, events[1].Current)
+	assert.Equal(t, total, events[1].Total)
+}
+
+func Test_withPullProgress_events(t *testing.T) {
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+	var events []domain.ScanEvent
+	ctx := context.WithValue(context.Background(), domain.ScanEventKey{}, func(event domain.ScanEvent) {
+		events = append(events, event)
+	})
+	// the layers are recorded without a progress reporter
+	layers, err := withPullProgress(ctx, img).Layers()
+	require.NoError(t, err)
+	for _, layer := range layers {
+		rc, err := layer.Compressed()
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+	}
+	require.Len(t, events, 2)
+	digest, err := layers[1].Digest()
+	require.NoError(t, err)
+	size, err := layers[1].Size()
+	require.NoError(t, err)
+	assert.Equal(t, "layer read", events[1].Message)
+	assert.Equal(t, map[string]string{"layer": "2", "digest": digest.String(), "size": strconv.FormatInt(size, 10)}, events[1].Attributes)
+}
+
+type staticCataloger []pkg.Package
+
+func (c staticCataloger) Name() string {
+	return "static-cataloger"
+}
+
+func (c staticCataloger) Catalog(source.FileResolver) ([]pkg.Package, []artifact.Relationship, error) {
+	return c, nil, nil
+}
+
+func Test_withCatalogProgress(t *testing.T) {
+	catalogers := []pkg.Cataloger{staticCataloger{{Name: "a"}, {Name: "b"}}, staticCataloger{{Name: "c"}}}
+	assert.Equal(t, catalogers, withCatalogProgress(context.Background(), catalogers))
+
+	var events []domain.Progress
+	for _, cataloger := range withCatalogProgress(recordProgress(&events), catalogers) {
+		_, _, err := cataloger.Catalog(nil)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []domain.Progress{
+		{Stage: domain.StageCatalog, Unit: domain.ProgressCatalogers, Current: 1, Total: 2, Packages: 2},
+		{Stage: domain.StageCatalog, Unit: domain.ProgressCatalogers, Current: 2, Total: 2, Packages: 3},
+	}, events)
+}
@@ -3,7 +3,10 @@ package v1
 import (
 	"context"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -92,6 +95,51 @@ func Test_syftAdapter_CreateSBOM(t *testing.T) {
 	}
 }
 
+func Test_syftAdapter_cancellation(t *testing.T) {
+	// a registry never answering
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	imageID := strings.TrimPrefix(srv.URL, "http://") + "/library/nginx:1.14.1"
+	options := domain.RegistryOptions{InsecureUseHTTP: true}
+	s := NewSyftAdapter(5*time.Minute, 512*1024*1024)
+	tests := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{
+			name: "resolve digest",
+			call: func(ctx context.Context) error {
+				_, err := s.ResolveDigest(ctx, imageID, options)
+				return err
+			},
+		},
+		{
+			name: "create SBOM",
+			call: func(ctx context.Context) error {
+				_, err := s.CreateSBOM(ctx, "name", imageID, options)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.TODO())
+			time.AfterFunc(100*time.Millisecond, cancel)
+			start := time.Now()
+			err := tt.call(ctx)
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Less(t, time.Since(start), 5*time.Second, "call not aborted within 5s of the cancellation")
+		})
+	}
+}
+
 func Test_syftAdapter_Version(t *testing.T) {
 	s := NewSyftAdapter(5*time.Minute, 512*1024*1024)
 	version := s.Version()
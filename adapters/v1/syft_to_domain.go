@@ -10,7 +10,41 @@ import (
 
 func (s *SyftAdapter) syftToDomain(syftSBOM sbom.SBOM) (*v1beta1.Document, error) {
 	spdxDoc := spdxhelpers.ToFormatModel(syftSBOM)
-	return s.spdxToDomain(spdxDoc)
+	doc, err := s.spdxToDomain(spdxDoc)
+	if err == nil && s.excludeFiles {
+		stripFiles(doc)
+	}
+	return doc, err
+}
+
+// stripFiles removes the file-level entries of the document (files, snippets, per-package file lists,
+// verification codes and the relationships referencing files), keeping the package-level data used for matching
+func stripFiles(doc *v1beta1.Document) {
+	fileIDs := map[v1beta1.ElementID]struct{}{}
+	for _, f := range doc.Files {
+		fileIDs[f.FileSPDXIdentifier] = struct{}{}
+	}
+	for _, p := range doc.Packages {
+		for _, f := range p.Files {
+			fileIDs[f.FileSPDXIdentifier] = struct{}{}
+		}
+		p.Files = nil
+		p.FilesAnalyzed = false
+		p.IsFilesAnalyzedTagPresent = false
+		p.PackageLicenseInfoFromFiles = nil
+		p.PackageVerificationCode = nil
+	}
+	doc.Files = nil
+	doc.Snippets = nil
+	var relationships []*v1beta1.Relationship
+	for _, r := range doc.Relationships {
+		_, fileA := fileIDs[r.RefA.ElementRefID]
+		_, fileB := fileIDs[r.RefB.ElementRefID]
+		if !fileA && !fileB {
+			relationships = append(relationships, r)
+		}
+	}
+	doc.Relationships = relationships
 }
 
 func (s *SyftAdapter) spdxToDomain(spdxDoc *v2_3.Document) (*v1beta1.Document, error) {
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -95,3 +96,25 @@ func TestSyftAdapter_spdxToDomain(t *testing.T) {
 		})
 	}
 }
+
+func Test_stripFiles(t *testing.T) {
+	doc := fileToSBOM("testdata/nginx-sbom.json")
+	assert.NotEmpty(t, doc.Files)
+	packages := len(doc.Packages)
+	before, err := json.Marshal(doc)
+	assert.NoError(t, err)
+	stripFiles(doc)
+	after, err := json.Marshal(doc)
+	assert.NoError(t, err)
+	assert.Empty(t, doc.Files)
+	assert.Len(t, doc.Packages, packages)
+	assert.NotEmpty(t, doc.Relationships)
+	for _, p := range doc.Packages {
+		assert.Empty(t, p.Files)
+		assert.Nil(t, p.PackageVerificationCode)
+	}
+	for _, r := range doc.Relationships {
+		assert.NotContains(t, string(r.RefB.ElementRefID), "File-")
+	}
+	assert.Less(t, len(after), len(before)/5)
+}
@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+var ErrTrustedDigestsSignatureInvalid = errors.New("invalid trusted digests signature")
+
+var digestPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// ParseTrustedDigests reads a JSON allowlist of trusted image digests once its signature is verified with the PEM
+// public key of keyFile, the signature file holds the base64 signature of the allowlist file, as
+// "cosign sign-blob --key" outputs it
+func ParseTrustedDigests(path, signatureFile, keyFile string) (domain.TrustedDigests, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return domain.TrustedDigests{}, err
+	}
+	encodedSignature, err := os.ReadFile(signatureFile)
+	if err != nil {
+		return domain.TrustedDigests{}, err
+	}
+	signature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encodedSignature)))
+	if err != nil {
+		return domain.TrustedDigests{}, fmt.Errorf("%w: %v", ErrTrustedDigestsSignatureInvalid, err)
+	}
+	publicKey, err := readPublicKey(keyFile)
+	if err != nil {
+		return domain.TrustedDigests{}, err
+	}
+	valid, err := verifySignature(publicKey, content, signature)
+	if err != nil {
+		return domain.TrustedDigests{}, err
+	}
+	if !valid {
+		return domain.TrustedDigests{}, fmt.Errorf("%w: %s", ErrTrustedDigestsSignatureInvalid, path)
+	}
+	var allowlist domain.TrustedDigests
+	if err := json.Unmarshal(content, &allowlist); err != nil {
+		return domain.TrustedDigests{}, fmt.Errorf("failed to parse trusted digests file %s: %w", path, err)
+	}
+	if allowlist.Version == "" {
+		return domain.TrustedDigests{}, fmt.Errorf("trusted digests file %s has no version", path)
+	}
+	for i, trusted := range allowlist.Digests {
+		if !digestPattern.MatchString(trusted.Digest) {
+			return domain.TrustedDigests{}, fmt.Errorf("invalid trusted digest %d of %s: expected sha256:<hex>, got %q", i, path, trusted.Digest)
+		}
+	}
+	return allowlist, nil
+}
+
+// readPublicKey reads a PEM public key
+func readPublicKey(keyFile string) (interface{}, error) {
+	content, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM public key in %s", keyFile)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the public key of %s: %w", keyFile, err)
+	}
+	return key, nil
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const trustedDigest = "sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137"
+
+// signedAllowlist writes the allowlist, its signature made with key and the PEM public key of key into the test
+// directory, and returns their paths
+func signedAllowlist(t *testing.T, key interface{}, content string) (path, signatureFile, publicKeyFile string) {
+	dir := t.TempDir()
+	path = filepath.Join(dir, "digests.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	signer, err := newSBOMSigner(key)
+	require.NoError(t, err)
+	signature, err := signer.sign([]byte(content))
+	require.NoError(t, err)
+	signatureFile = path + ".sig"
+	require.NoError(t, os.WriteFile(signatureFile, []byte(base64.StdEncoding.EncodeToString(signature)+"\n"), 0600))
+	der, err := x509.MarshalPKIXPublicKey(signer.key.Public())
+	require.NoError(t, err)
+	publicKeyFile = filepath.Join(dir, "pub.pem")
+	require.NoError(t, os.WriteFile(publicKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+	return path, signatureFile, publicKeyFile
+}
+
+func TestParseTrustedDigests(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	valid := `{"version": "2023.04", "digests": [{"digest": "` + trustedDigest + `", "image": "golden/base:1.0"}]}`
+	want := domain.TrustedDigests{Version: "2023.04", Digests: []domain.TrustedDigest{{Digest: trustedDigest, Image: "golden/base:1.0"}}}
+
+	for name, key := range map[string]interface{}{"ecdsa": ecdsaKey, "ed25519": ed25519Key} {
+		t.Run(name, func(t *testing.T) {
+			allowlist, err := ParseTrustedDigests(signedAllowlist(t, key, valid))
+			require.NoError(t, err)
+			assert.Equal(t, want, allowlist)
+		})
+	}
+
+	t.Run("tampered allowlist", func(t *testing.T) {
+		path, signatureFile, publicKeyFile := signedAllowlist(t, ecdsaKey, valid)
+		require.NoError(t, os.WriteFile(path, []byte(`{"version": "2023.04", "digests": []}`), 0600))
+		_, err := ParseTrustedDigests(path, signatureFile, publicKeyFile)
+		assert.ErrorIs(t, err, ErrTrustedDigestsSignatureInvalid)
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		path, signatureFile, _ := signedAllowlist(t, ecdsaKey, valid)
+		_, _, publicKeyFile := signedAllowlist(t, ed25519Key, valid)
+		_, err := ParseTrustedDigests(path, signatureFile, publicKeyFile)
+		assert.ErrorIs(t, err, ErrTrustedDigestsSignatureInvalid)
+	})
+
+	for name, content := range map[string]string{
+		"invalid digest": `{"version": "2023.04", "digests": [{"digest": "golden/base:1.0"}]}`,
+		"no version":     `{"digests": [{"digest": "` + trustedDigest + `"}]}`,
+		"not JSON":       `digests`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseTrustedDigests(signedAllowlist(t, ecdsaKey, content))
+			assert.Error(t, err)
+			assert.NotErrorIs(t, err, ErrTrustedDigestsSignatureInvalid)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/armosec/utils-go/httputils"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// WebhookAdapter implements ResultSink from ports by posting the routed findings as JSON to a fixed URL, with a
+// bearer token if any
+type WebhookAdapter struct {
+	httpPostFunc func(httputils.IHttpClient, string, map[string]string, []byte) (*http.Response, error)
+	token        string
+	url          string
+}
+
+var _ ports.ResultSink = (*WebhookAdapter)(nil)
+
+// webhookPayload is the JSON payload posted by the WebhookAdapter
+type webhookPayload struct {
+	Report   domain.ScanReport `json:"report"`
+	Findings []domain.Finding  `json:"findings"`
+}
+
+// NewWebhookAdapter initializes the WebhookAdapter posting to the given URL, token is sent as bearer token unless empty
+func NewWebhookAdapter(url, token string) *WebhookAdapter {
+	return &WebhookAdapter{
+		httpPostFunc: httputils.HttpPost,
+		token:        token,
+		url:          url,
+	}
+}
+
+// SendResults posts the findings of a scan with its report
+func (w *WebhookAdapter) SendResults(ctx context.Context, report domain.ScanReport, findings []domain.Finding) error {
+	ctx, span := otel.Tracer("").Start(ctx, "WebhookAdapter.SendResults")
+	defer span.End()
+	if findings == nil {
+		findings = []domain.Finding{}
+	}
+	payload, err := json.Marshal(webhookPayload{Report: report, Findings: findings})
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{"Content-Type": "application/json"}
+	if w.token != "" {
+		headers["Authorization"] = "Bearer " + w.token
+	}
+	resp, err := w.httpPostFunc(clientWithContext(ctx), w.url, headers, payload)
+	if err != nil {
+		return err
+	}
+	body, err := httputils.HttpRespToString(resp)
+	if err != nil {
+		return fmt.Errorf("posting the results to %s failed: %w, body: %s", w.url, err, body)
+	}
+	logger.L().Debug("posted scan results to webhook",
+		helpers.String("scanID", report.ScanID),
+		helpers.Int("findings", len(findings)),
+		helpers.String("url", w.url))
+	return nil
+}
@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookAdapter_SendResults(t *testing.T) {
+	var got webhookPayload
+	var gotAuthorization string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuthorization = r.Header.Get("Authorization")
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &got)
+		if got.Report.Verdict == domain.VerdictError {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+	report := domain.ScanReport{ScanID: "scanID", Verdict: domain.VerdictFail, Summary: map[string]int{"Critical": 1}}
+	findings := []domain.Finding{{ID: "CVE-2022-37434", Package: "zlib", Version: "1.2.12", Severity: "Critical"}}
+
+	require.NoError(t, NewWebhookAdapter(ts.URL, "secret").SendResults(context.TODO(), report, findings))
+	assert.Equal(t, "Bearer secret", gotAuthorization)
+	assert.Equal(t, webhookPayload{Report: report, Findings: findings}, got)
+
+	require.NoError(t, NewWebhookAdapter(ts.URL, "").SendResults(context.TODO(), report, nil))
+	assert.Empty(t, gotAuthorization)
+	assert.Equal(t, []domain.Finding{}, got.Findings)
+
+	report.Verdict = domain.VerdictError
+	assert.Error(t, NewWebhookAdapter(ts.URL, "").SendResults(context.TODO(), report, findings))
+}
@@ -0,0 +1,208 @@
+package v1
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// WorkloadAdapter implements WorkloadLister and NamespaceLabeler from ports by listing the running pods and reading
+// the namespaces of the cluster
+type WorkloadAdapter struct {
+	client                 kubernetes.Interface
+	getNamespaceLabelsFunc func(context.Context, string) (map[string]string, error)
+}
+
+var _ ports.WorkloadLister = (*WorkloadAdapter)(nil)
+var _ ports.NamespaceLabeler = (*WorkloadAdapter)(nil)
+
+// NewWorkloadAdapter initializes the WorkloadAdapter struct
+func NewWorkloadAdapter(client kubernetes.Interface) *WorkloadAdapter {
+	return &WorkloadAdapter{client: client, getNamespaceLabelsFunc: NamespaceLabelsGetter(client)}
+}
+
+// NamespaceLabels returns the labels of a namespace, cached for a few minutes
+func (w *WorkloadAdapter) NamespaceLabels(ctx context.Context, namespace string) (map[string]string, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "WorkloadAdapter.NamespaceLabels")
+	defer span.End()
+	return w.getNamespaceLabelsFunc(ctx, namespace)
+}
+
+// ListWorkloadImages returns the container images of all running pods, deduplicated by workload and container
+func (w *WorkloadAdapter) ListWorkloadImages(ctx context.Context) ([]domain.WorkloadImage, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "WorkloadAdapter.ListWorkloadImages")
+	defer span.End()
+
+	pods, err := w.client.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: "status.phase=" + string(corev1.PodRunning),
+	})
+	if err != nil {
+		return nil, err
+	}
+	seen := map[domain.WorkloadImage]bool{}
+	var images []domain.WorkloadImage
+	for _, pod := range pods.Items {
+		kind, name := podOwner(pod)
+		for _, status := range pod.Status.ContainerStatuses {
+			image := domain.WorkloadImage{
+				Namespace:     pod.Namespace,
+				Kind:          kind,
+				Name:          name,
+				ContainerName: status.Name,
+				ImageTag:      status.Image,
+				ImageHash:     normalizeImageID(status.ImageID),
+			}
+			if !seen[image] {
+				seen[image] = true
+				images = append(images, image)
+			}
+		}
+	}
+	return images, nil
+}
+
+// WorkloadTemplateAdapter implements WorkloadLister from ports by listing the pod templates of the workloads of the
+// cluster, along with the images of their pods which are not terminated, so that the workloads scaled to zero or
+// between two runs of a CronJob are still listed
+type WorkloadTemplateAdapter struct {
+	client kubernetes.Interface
+}
+
+var _ ports.WorkloadLister = (*WorkloadTemplateAdapter)(nil)
+
+// NewWorkloadTemplateAdapter initializes the WorkloadTemplateAdapter struct
+func NewWorkloadTemplateAdapter(client kubernetes.Interface) *WorkloadTemplateAdapter {
+	return &WorkloadTemplateAdapter{client: client}
+}
+
+// ListWorkloadImages returns the containers of the workload templates, which only know the image tags, and the
+// containers of the pods which are not terminated, with the digests of their images
+func (w *WorkloadTemplateAdapter) ListWorkloadImages(ctx context.Context) ([]domain.WorkloadImage, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "WorkloadTemplateAdapter.ListWorkloadImages")
+	defer span.End()
+
+	seen := map[domain.WorkloadImage]bool{}
+	var images []domain.WorkloadImage
+	add := func(image domain.WorkloadImage) {
+		if !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+	addTemplate := func(meta metav1.ObjectMeta, kind string, spec corev1.PodSpec) {
+		// the templates of the workloads controlled by another one are listed with their owner
+		if metav1.GetControllerOf(&meta) != nil {
+			return
+		}
+		for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+			for _, container := range containers {
+				add(domain.WorkloadImage{
+					Namespace:     meta.Namespace,
+					Kind:          kind,
+					Name:          meta.Name,
+					ContainerName: container.Name,
+					ImageTag:      container.Image,
+				})
+			}
+		}
+	}
+
+	deployments, err := w.client.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, deployment := range deployments.Items {
+		addTemplate(deployment.ObjectMeta, "Deployment", deployment.Spec.Template.Spec)
+	}
+	replicaSets, err := w.client.AppsV1().ReplicaSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, replicaSet := range replicaSets.Items {
+		addTemplate(replicaSet.ObjectMeta, "ReplicaSet", replicaSet.Spec.Template.Spec)
+	}
+	statefulSets, err := w.client.AppsV1().StatefulSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, statefulSet := range statefulSets.Items {
+		addTemplate(statefulSet.ObjectMeta, "StatefulSet", statefulSet.Spec.Template.Spec)
+	}
+	daemonSets, err := w.client.AppsV1().DaemonSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, daemonSet := range daemonSets.Items {
+		addTemplate(daemonSet.ObjectMeta, "DaemonSet", daemonSet.Spec.Template.Spec)
+	}
+	jobs, err := w.client.BatchV1().Jobs("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs.Items {
+		addTemplate(job.ObjectMeta, "Job", job.Spec.Template.Spec)
+	}
+	cronJobs, err := w.client.BatchV1().CronJobs("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, cronJob := range cronJobs.Items {
+		addTemplate(cronJob.ObjectMeta, "CronJob", cronJob.Spec.JobTemplate.Spec.Template.Spec)
+	}
+
+	pods, err := w.client.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: "status.phase!=" + string(corev1.PodSucceeded) + ",status.phase!=" + string(corev1.PodFailed),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		// standalone pods have no other template
+		addTemplate(pod.ObjectMeta, "Pod", pod.Spec)
+		kind, name := podOwner(pod)
+		for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+			for _, status := range statuses {
+				if status.ImageID == "" {
+					continue
+				}
+				add(domain.WorkloadImage{
+					Namespace:     pod.Namespace,
+					Kind:          kind,
+					Name:          name,
+					ContainerName: status.Name,
+					ImageTag:      status.Image,
+					ImageHash:     normalizeImageID(status.ImageID),
+				})
+			}
+		}
+	}
+	return images, nil
+}
+
+// podOwner returns the kind and name of the workload controlling a pod, ReplicaSets are attributed to their Deployment
+func podOwner(pod corev1.Pod) (string, string) {
+	owner := metav1.GetControllerOf(&pod)
+	if owner == nil {
+		return "Pod", pod.Name
+	}
+	if owner.Kind == "ReplicaSet" {
+		if i := strings.LastIndex(owner.Name, "-"); i > 0 {
+			return "Deployment", owner.Name[:i]
+		}
+	}
+	return owner.Kind, owner.Name
+}
+
+// normalizeImageID removes the runtime specific prefix of a container status image ID
+func normalizeImageID(imageID string) string {
+	if i := strings.Index(imageID, "://"); i != -1 {
+		return imageID[i+3:]
+	}
+	return imageID
+}
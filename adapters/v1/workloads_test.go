@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newPod(name, ownerKind, ownerName string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:    "nginx",
+				Image:   "nginx:1.14.1",
+				ImageID: "docker-pullable://nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7",
+			}},
+		},
+	}
+	if ownerKind != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: ownerName, Controller: &controller}}
+	}
+	return pod
+}
+
+func TestWorkloadAdapter_ListWorkloadImages(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newPod("nginx-7c5ddbdf54-abcde", "ReplicaSet", "nginx-7c5ddbdf54"),
+		newPod("nginx-7c5ddbdf54-fghij", "ReplicaSet", "nginx-7c5ddbdf54"),
+		newPod("web-0", "StatefulSet", "web"),
+		newPod("standalone", "", ""),
+	)
+	w := NewWorkloadAdapter(client)
+	images, err := w.ListWorkloadImages(context.TODO())
+	assert.NoError(t, err)
+	workloads := map[string]bool{}
+	for _, image := range images {
+		assert.Equal(t, "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7", image.ImageHash)
+		workloads[image.Kind+"/"+image.Name] = true
+	}
+	assert.Len(t, images, 3)
+	assert.Equal(t, map[string]bool{"Deployment/nginx": true, "StatefulSet/web": true, "Pod/standalone": true}, workloads)
+}
+
+func TestWorkloadTemplateAdapter_ListWorkloadImages(t *testing.T) {
+	controller := true
+	template := corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx:1.14.1"}}}}
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"}, Spec: appsv1.DeploymentSpec{Template: template}},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx-7c5ddbdf54", Namespace: "default",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "nginx", Controller: &controller}}},
+			Spec: appsv1.ReplicaSetSpec{Template: template},
+		},
+		&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default"},
+			Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: template}}}},
+		newPod("nginx-7c5ddbdf54-abcde", "ReplicaSet", "nginx-7c5ddbdf54"),
+	)
+	w := NewWorkloadTemplateAdapter(client)
+	images, err := w.ListWorkloadImages(context.TODO())
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []domain.WorkloadImage{
+		{Namespace: "default", Kind: "Deployment", Name: "nginx", ContainerName: "nginx", ImageTag: "nginx:1.14.1"},
+		{Namespace: "default", Kind: "CronJob", Name: "backup", ContainerName: "nginx", ImageTag: "nginx:1.14.1"},
+		{Namespace: "default", Kind: "Deployment", Name: "nginx", ContainerName: "nginx", ImageTag: "nginx:1.14.1",
+			ImageHash: "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"},
+	}, images)
+}
+
+func TestWorkloadAdapter_NamespaceLabels(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"env": "prod"}}})
+	w := NewWorkloadAdapter(client)
+	labels, err := w.NamespaceLabels(context.TODO(), "payments")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod"}, labels)
+	_, err = w.NamespaceLabels(context.TODO(), "unknown")
+	assert.Error(t, err)
+}
+
+func Test_normalizeImageID(t *testing.T) {
+	assert.Equal(t, "nginx@sha256:1234", normalizeImageID("docker-pullable://nginx@sha256:1234"))
+	assert.Equal(t, "sha256:1234", normalizeImageID("sha256:1234"))
+	assert.Equal(t, domain.WorkloadImage{}.ImageHash, normalizeImageID(""))
+}
@@ -0,0 +1,103 @@
+package v1
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+const workspacePrefix = "kubevuln-scan-"
+
+// workspace is a temporary directory dedicated to a single scan, only readable by the current user,
+// so that concurrent scans never share files
+type workspace struct {
+	root string
+	mu   sync.Mutex
+	// holders counts the stages using the workspace, which may outlive the scan once abandoned on a timeout
+	holders int
+	// removeOnRelease is set by Cleanup while the workspace is held, the last holder removes it
+	removeOnRelease bool
+}
+
+// newWorkspace creates a new scan workspace inside baseDir, the default temporary directory is used if empty
+func newWorkspace(baseDir string) (*workspace, error) {
+	root, err := os.MkdirTemp(baseDir, workspacePrefix)
+	if err != nil {
+		return nil, err
+	}
+	// os.MkdirTemp honors umask, enforce strict permissions explicitly
+	if err := os.Chmod(root, 0o700); err != nil {
+		_ = os.RemoveAll(root)
+		return nil, err
+	}
+	return &workspace{root: root}, nil
+}
+
+// NewDirectory creates a new directory inside the workspace
+func (w *workspace) NewDirectory(name string) (string, error) {
+	return os.MkdirTemp(w.root, name+"-")
+}
+
+// hold marks the workspace as used by a stage which may be abandoned, it must be called before the stage starts and
+// the returned release once it is over, even when abandoned
+func (w *workspace) hold() (release func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.holders++
+	var once sync.Once
+	return func() {
+		once.Do(w.release)
+	}
+}
+
+// release removes the workspace once the last holder is done if its cleanup was requested meanwhile
+func (w *workspace) release() {
+	w.mu.Lock()
+	w.holders--
+	remove := w.holders == 0 && w.removeOnRelease
+	w.mu.Unlock()
+	if !remove {
+		return
+	}
+	if err := os.RemoveAll(w.root); err != nil {
+		logger.L().Warning("failed to cleanup scan workspace", helpers.Error(err),
+			helpers.String("workspace", w.root))
+	}
+}
+
+// Cleanup deletes the workspace and everything it contains, the deletion is deferred until the abandoned stages
+// still holding it are over
+func (w *workspace) Cleanup() error {
+	w.mu.Lock()
+	if w.holders > 0 {
+		w.removeOnRelease = true
+		w.mu.Unlock()
+		return nil
+	}
+	w.mu.Unlock()
+	return os.RemoveAll(w.root)
+}
+
+// CleanupWorkspaces deletes the scan workspaces left inside baseDir by a previous process,
+// it must be called before any scan starts
+func CleanupWorkspaces(baseDir string) error {
+	if baseDir == "" {
+		baseDir = os.TempDir()
+	}
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), workspacePrefix) {
+			errs = append(errs, os.RemoveAll(filepath.Join(baseDir, entry.Name())))
+		}
+	}
+	return errors.Join(errs...)
+}
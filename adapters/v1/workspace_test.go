@@ -0,0 +1,70 @@
+package v1
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_workspace(t *testing.T) {
+	baseDir := t.TempDir()
+	w1, err := newWorkspace(baseDir)
+	assert.NoError(t, err)
+	w2, err := newWorkspace(baseDir)
+	assert.NoError(t, err)
+	assert.NotEqual(t, w1.root, w2.root)
+	info, err := os.Stat(w1.root)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+	dir, err := w1.NewDirectory("image")
+	assert.NoError(t, err)
+	assert.Equal(t, w1.root, filepath.Dir(dir))
+	assert.NoError(t, w1.Cleanup())
+	_, err = os.Stat(w1.root)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(w2.root)
+	assert.NoError(t, err)
+}
+
+func TestCleanupWorkspaces(t *testing.T) {
+	baseDir := t.TempDir()
+	w, err := newWorkspace(baseDir)
+	assert.NoError(t, err)
+	_, err = w.NewDirectory("image")
+	assert.NoError(t, err)
+	other := filepath.Join(baseDir, "other")
+	assert.NoError(t, os.Mkdir(other, 0o755))
+	assert.NoError(t, CleanupWorkspaces(baseDir))
+	_, err = os.Stat(w.root)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(other)
+	assert.NoError(t, err)
+}
+
+func Test_workspace_hold(t *testing.T) {
+	w, err := newWorkspace(t.TempDir())
+	assert.NoError(t, err)
+	release := w.hold()
+	// an abandoned stage still writes into the workspace after the scan cleaned it up
+	assert.NoError(t, w.Cleanup())
+	_, err = os.Stat(w.root)
+	assert.NoError(t, err)
+	release()
+	_, err = os.Stat(w.root)
+	assert.True(t, os.IsNotExist(err))
+	// releasing twice must not remove the workspace of another holder
+	w, err = newWorkspace(t.TempDir())
+	assert.NoError(t, err)
+	release = w.hold()
+	release()
+	release()
+	other := w.hold()
+	assert.NoError(t, w.Cleanup())
+	_, err = os.Stat(w.root)
+	assert.NoError(t, err)
+	other()
+	_, err = os.Stat(w.root)
+	assert.True(t, os.IsNotExist(err))
+}
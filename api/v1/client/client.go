@@ -0,0 +1,399 @@
+// Package client is a Go client of the kubevuln HTTP API described by its OpenAPI document
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	wssc "github.com/armosec/armoapi-go/apis"
+	apiv1 "github.com/kubescape/kubevuln/api/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// Client calls the kubevuln HTTP API, the errors answered by kubevuln are returned as *apiv1.Problem
+type Client struct {
+	baseURL     string
+	bearerToken string
+	httpClient  *http.Client
+}
+
+// Option configures optional settings of the Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for the requests, http.DefaultClient by default
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithBearerToken authenticates the requests with a bearer token, such as the relay token of an edge cluster
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.bearerToken = token
+	}
+}
+
+// New initializes the Client struct calling kubevuln at baseURL, such as "http://kubevuln:8080"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Alive calls the liveness probe
+func (c *Client) Alive(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/v1/liveness", nil, nil, nil)
+}
+
+// Ready calls the readiness probe, which fails until the vulnerability database is loaded
+func (c *Client) Ready(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/v1/readiness", nil, nil, nil)
+}
+
+// Version returns the versions of the scanners, of their libraries and of the vulnerability database
+func (c *Client) Version(ctx context.Context) (domain.VersionInfo, error) {
+	var version domain.VersionInfo
+	err := c.do(ctx, http.MethodGet, "/v1/version", nil, nil, &version)
+	return version, err
+}
+
+// Cache returns the statistics of the caches of the scan pipeline
+func (c *Client) Cache(ctx context.Context) (domain.CacheReport, error) {
+	var report domain.CacheReport
+	err := c.do(ctx, http.MethodGet, "/v1/cache", nil, nil, &report)
+	return report, err
+}
+
+// Coverage returns the scan coverage of the running container images
+func (c *Client) Coverage(ctx context.Context) (domain.CoverageReport, error) {
+	var report domain.CoverageReport
+	err := c.do(ctx, http.MethodGet, "/v1/coverage", nil, nil, &report)
+	return report, err
+}
+
+// SelfTest scans the reference image end-to-end and returns the outcome of each stage, the report of a failed
+// self-test is returned along with the *apiv1.Problem of the status 503
+func (c *Client) SelfTest(ctx context.Context) (domain.SelfTestReport, error) {
+	var report domain.SelfTestReport
+	err := c.do(ctx, http.MethodGet, "/v1/selftest", nil, nil, &report)
+	return report, err
+}
+
+// Diff returns the vulnerability difference between two scans, selected by scanID or as the latest scans of
+// a workload at the given times
+func (c *Client) Diff(ctx context.Context, request domain.DiffRequest) (domain.ScanDiff, error) {
+	query := url.Values{}
+	for key, value := range map[string]string{"fromScanID": request.FromScanID, "toScanID": request.ToScanID, "wlid": request.Wlid} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	for key, value := range map[string]time.Time{"from": request.From, "to": request.To} {
+		if !value.IsZero() {
+			query.Set(key, value.Format(time.RFC3339))
+		}
+	}
+	var diff domain.ScanDiff
+	err := c.do(ctx, http.MethodGet, "/v1/diff", query, nil, &diff)
+	return diff, err
+}
+
+// ScanFailures returns the last failure of the images whose last scan failed, optionally restricted to an image
+func (c *Client) ScanFailures(ctx context.Context, image string) ([]domain.ScanFailure, error) {
+	query := url.Values{}
+	if image != "" {
+		query.Set("image", image)
+	}
+	var failures []domain.ScanFailure
+	err := c.do(ctx, http.MethodGet, "/v1/failures", query, nil, &failures)
+	return failures, err
+}
+
+// ScanEvents returns the detailed events recorded for a recent scan
+func (c *Client) ScanEvents(ctx context.Context, scanID string) (domain.ScanEvents, error) {
+	var events domain.ScanEvents
+	err := c.do(ctx, http.MethodGet, "/v1/scans/"+url.PathEscape(scanID)+"/events", nil, nil, &events)
+	return events, err
+}
+
+// GenerateSBOM submits the generation of the SBOM of an image
+func (c *Client) GenerateSBOM(ctx context.Context, command wssc.WebsocketScanCommand) error {
+	return c.do(ctx, http.MethodPost, "/v1/"+wssc.SBOMCalculationCommandPath, nil, command, nil)
+}
+
+// ScanCVE submits the vulnerability scan of the image of a workload container
+func (c *Client) ScanCVE(ctx context.Context, command wssc.WebsocketScanCommand) error {
+	return c.do(ctx, http.MethodPost, "/v1/"+wssc.ContainerScanCommandPath, nil, command, nil)
+}
+
+// ScanRegistry submits the vulnerability scan of a registry image
+func (c *Client) ScanRegistry(ctx context.Context, command wssc.RegistryScanCommand) error {
+	return c.do(ctx, http.MethodPost, "/v1/"+wssc.RegistryScanCommandPath, nil, command, nil)
+}
+
+// ScanRepository submits the vulnerability scan of a git repository
+func (c *Client) ScanRepository(ctx context.Context, request apiv1.RepositoryScanRequest) error {
+	return c.do(ctx, http.MethodPost, "/v1/scanRepository", nil, request, nil)
+}
+
+// RelaySBOM submits the SBOM of a container of the edge cluster of the bearer token to a kubevuln in relay mode
+func (c *Client) RelaySBOM(ctx context.Context, request apiv1.RelaySBOMRequest) error {
+	return c.do(ctx, http.MethodPost, "/v1/relaySBOM", nil, request, nil)
+}
+
+// ScanBatch submits a batch of scan commands and returns whether each of them was accepted
+func (c *Client) ScanBatch(ctx context.Context, batch apiv1.BatchScanRequest) (domain.BatchReport, error) {
+	var report domain.BatchReport
+	err := c.do(ctx, http.MethodPost, "/v1/scanBatch", nil, batch, &report)
+	return report, err
+}
+
+// ScanPlan returns which unique images of the scan commands need scanning, without scanning them
+func (c *Client) ScanPlan(ctx context.Context, request apiv1.ScanPlanRequest) (domain.ScanPlan, error) {
+	var plan domain.ScanPlan
+	err := c.do(ctx, http.MethodPost, "/v1/scanPlan", nil, request, &plan)
+	return plan, err
+}
+
+// ScanLayerDiff returns the vulnerabilities the new release of an image introduces and removes, scanning only the
+// layers which differ between the releases
+func (c *Client) ScanLayerDiff(ctx context.Context, request apiv1.LayerDiffRequest) (domain.LayerDiff, error) {
+	var diff domain.LayerDiff
+	err := c.do(ctx, http.MethodPost, "/v1/scanLayerDiff", nil, request, &diff)
+	return diff, err
+}
+
+// ExportResults returns the findings of the latest scans of a workload, or of the cluster without wlid, as CSV with
+// the columns, or the default ones
+func (c *Client) ExportResults(ctx context.Context, wlid string, columns []string) ([]byte, error) {
+	query := url.Values{}
+	if wlid != "" {
+		query.Set("wlid", wlid)
+	}
+	if len(columns) > 0 {
+		query.Set("columns", strings.Join(columns, ","))
+	}
+	var export []byte
+	err := c.do(ctx, http.MethodGet, "/v1/export", query, nil, &export)
+	return export, err
+}
+
+// Report returns the HTML report of the latest scans of a workload, of a registry image, or of the cluster without
+// either
+func (c *Client) Report(ctx context.Context, wlid, image string) ([]byte, error) {
+	query := url.Values{}
+	for key, value := range map[string]string{"wlid": wlid, "image": image} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	var report []byte
+	err := c.do(ctx, http.MethodGet, "/v1/report", query, nil, &report)
+	return report, err
+}
+
+// ExportBundle returns a bundle of the SBOMs stored by an offline kubevuln
+func (c *Client) ExportBundle(ctx context.Context) (domain.SBOMBundle, error) {
+	var export []byte
+	if err := c.do(ctx, http.MethodGet, "/v1/bundle", nil, nil, &export); err != nil {
+		return domain.SBOMBundle{}, err
+	}
+	bundle, err := domain.ReadSBOMBundle(bytes.NewReader(export))
+	if err != nil {
+		return domain.SBOMBundle{}, fmt.Errorf("failed to decode the response of GET /v1/bundle: %w", err)
+	}
+	return bundle, nil
+}
+
+// ScanBundle returns the vulnerability manifests a connected kubevuln matched for the SBOMs of a bundle
+func (c *Client) ScanBundle(ctx context.Context, bundle domain.SBOMBundle) (domain.ResultsBundle, error) {
+	var payload bytes.Buffer
+	if err := domain.WriteSBOMBundle(&payload, bundle); err != nil {
+		return domain.ResultsBundle{}, err
+	}
+	var results domain.ResultsBundle
+	err := c.do(ctx, http.MethodPost, "/v1/bundle", nil, payload.Bytes(), &results)
+	return results, err
+}
+
+// ImportResults stores in an offline kubevuln the vulnerability manifests of a scanned bundle
+func (c *Client) ImportResults(ctx context.Context, results domain.ResultsBundle) (domain.BundleImportReport, error) {
+	var report domain.BundleImportReport
+	err := c.do(ctx, http.MethodPost, "/v1/bundle/results", nil, results, &report)
+	return report, err
+}
+
+// DeleteWorkload cancels the pending scans of a deleted workload and deletes its scan results
+func (c *Client) DeleteWorkload(ctx context.Context, wlid string) error {
+	return c.do(ctx, http.MethodPost, "/v1/deleteWorkload", nil, wssc.WebsocketScanCommand{Wlid: wlid}, nil)
+}
+
+// AddFalsePositive marks the matches of a vulnerability in a package as a false positive and returns the stored
+// feedback
+func (c *Client) AddFalsePositive(ctx context.Context, falsePositive domain.FalsePositive) (domain.FalsePositive, error) {
+	var stored domain.FalsePositive
+	err := c.do(ctx, http.MethodPost, "/v1/falsePositives", nil, falsePositive, &stored)
+	return stored, err
+}
+
+// ListFalsePositives returns the findings marked as false positives
+func (c *Client) ListFalsePositives(ctx context.Context) ([]domain.FalsePositive, error) {
+	var falsePositives []domain.FalsePositive
+	err := c.do(ctx, http.MethodGet, "/v1/falsePositives", nil, nil, &falsePositives)
+	return falsePositives, err
+}
+
+// ExportFalsePositives returns the false positives as a Grype ignore rules file or an OpenVEX document, depending on
+// the format
+func (c *Client) ExportFalsePositives(ctx context.Context, format string) ([]byte, error) {
+	var export []byte
+	err := c.do(ctx, http.MethodGet, "/v1/falsePositives", url.Values{"format": {format}}, nil, &export)
+	return export, err
+}
+
+// DeleteFalsePositive forgets a false positive, its matches are reported again
+func (c *Client) DeleteFalsePositive(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/v1/falsePositives/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// AddTriage acknowledges a finding of a workload, or snoozes it until a date, and returns the stored triage
+func (c *Client) AddTriage(ctx context.Context, triage domain.Triage) (domain.Triage, error) {
+	var stored domain.Triage
+	err := c.do(ctx, http.MethodPost, "/v1/triage", nil, triage, &stored)
+	return stored, err
+}
+
+// ListTriage returns the triage of the findings of a workload, of all the workloads when wlid is empty
+func (c *Client) ListTriage(ctx context.Context, wlid string) ([]domain.Triage, error) {
+	var query url.Values
+	if wlid != "" {
+		query = url.Values{"wlid": {wlid}}
+	}
+	var triage []domain.Triage
+	err := c.do(ctx, http.MethodGet, "/v1/triage", query, nil, &triage)
+	return triage, err
+}
+
+// DeleteTriage forgets the triage of a finding, it counts again with the following scans
+func (c *Client) DeleteTriage(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/v1/triage/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// SyncInventory replaces the image and workload inventory of the cluster, and returns how the caches were reconciled
+func (c *Client) SyncInventory(ctx context.Context, inventory domain.Inventory) (domain.InventorySync, error) {
+	var sync domain.InventorySync
+	err := c.do(ctx, http.MethodPost, "/v1/inventory", nil, inventory, &sync)
+	return sync, err
+}
+
+// Maintenance returns whether the background scans are paused
+func (c *Client) Maintenance(ctx context.Context) (domain.MaintenanceStatus, error) {
+	var status domain.MaintenanceStatus
+	err := c.do(ctx, http.MethodGet, "/v1/maintenance", nil, nil, &status)
+	return status, err
+}
+
+// EnterMaintenance pauses the background scans, the on-demand scans are still accepted
+func (c *Client) EnterMaintenance(ctx context.Context, request apiv1.MaintenanceRequest) (domain.MaintenanceStatus, error) {
+	var status domain.MaintenanceStatus
+	err := c.do(ctx, http.MethodPost, "/v1/maintenance", nil, request, &status)
+	return status, err
+}
+
+// LeaveMaintenance resumes the background scans
+func (c *Client) LeaveMaintenance(ctx context.Context) (domain.MaintenanceStatus, error) {
+	var status domain.MaintenanceStatus
+	err := c.do(ctx, http.MethodDelete, "/v1/maintenance", nil, nil, &status)
+	return status, err
+}
+
+// CaptureProfile captures the CPU and heap profiles of a running scan for seconds at most, 0 for the default
+// duration, and returns where they are stored
+func (c *Client) CaptureProfile(ctx context.Context, scanID string, seconds int) (domain.ScanProfile, error) {
+	query := url.Values{"scanID": []string{scanID}}
+	if seconds > 0 {
+		query.Set("seconds", strconv.Itoa(seconds))
+	}
+	var profile domain.ScanProfile
+	err := c.do(ctx, http.MethodPost, "/v1/profiles", query, nil, &profile)
+	return profile, err
+}
+
+// do sends the request with the JSON body if any and decodes the response into out if not nil, the error responses
+// are returned as *apiv1.Problem, their body is decoded into out unless it is a problem, a *[]byte out receives the
+// raw body
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var reader io.Reader
+	contentType := "application/json"
+	switch payload := body.(type) {
+	case nil:
+	case []byte:
+		// the raw payloads, such as the NDJSON bundles, are sent as is
+		reader = bytes.NewReader(payload)
+		contentType = "application/x-ndjson"
+	default:
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(content)
+	}
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		problem := &apiv1.Problem{}
+		if json.Unmarshal(content, problem) != nil || problem.Status == 0 {
+			problem = &apiv1.Problem{Status: resp.StatusCode, Title: http.StatusText(resp.StatusCode)}
+			if out != nil {
+				_ = json.Unmarshal(content, out)
+			}
+		}
+		return problem
+	}
+	switch raw := out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*raw = content
+		return nil
+	}
+	if err := json.Unmarshal(content, out); err != nil {
+		return fmt.Errorf("failed to decode the response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
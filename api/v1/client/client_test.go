@@ -0,0 +1,230 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	wssc "github.com/armosec/armoapi-go/apis"
+	"github.com/gin-gonic/gin"
+	apiv1 "github.com/kubescape/kubevuln/api/v1"
+	"github.com/kubescape/kubevuln/controllers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newServer serves the kubevuln API backed by a mock scan service
+func newServer(t *testing.T, happy bool, opts ...controllers.HTTPControllerOption) *Client {
+	h := controllers.NewHTTPController(services.NewMockScanService(happy), 1, opts...)
+	router := gin.New()
+	h.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	t.Cleanup(func() {
+		srv.Close()
+		h.Shutdown()
+	})
+	return New(srv.URL+"/", WithHTTPClient(srv.Client()))
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.TODO()
+	c := newServer(t, true)
+	assert.NoError(t, c.Alive(ctx))
+	assert.NoError(t, c.Ready(ctx))
+
+	version, err := c.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "mock", version.Release)
+
+	var command wssc.WebsocketScanCommand
+	command.Wlid = "wlid://cluster-minikube/namespace-default/deployment-nginx"
+	command.ImageTag = "nginx:1.14.1"
+	command.ImageHash = "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"
+	assert.NoError(t, c.ScanCVE(ctx, command))
+	assert.NoError(t, c.GenerateSBOM(ctx, command))
+	var registryCommand wssc.RegistryScanCommand
+	registryCommand.ImageTag = "nginx:1.14.1"
+	assert.NoError(t, c.ScanRegistry(ctx, registryCommand))
+	assert.NoError(t, c.ScanRepository(ctx, apiv1.RepositoryScanRequest{URL: "https://github.com/kubescape/kubevuln", Ref: "main"}))
+
+	report, err := c.ScanBatch(ctx, apiv1.BatchScanRequest{Commands: []wssc.WebsocketScanCommand{command}})
+	require.NoError(t, err)
+	assert.NotEmpty(t, report.BatchID)
+	assert.Equal(t, []domain.BatchItemStatus{{Wlid: command.Wlid, ImageTag: command.ImageTag, ImageHash: command.ImageHash, Accepted: true}}, report.Items)
+
+	_, err = c.ScanPlan(ctx, apiv1.ScanPlanRequest{Commands: []wssc.WebsocketScanCommand{command}})
+	assert.NoError(t, err)
+	diff, err := c.ScanLayerDiff(ctx, apiv1.LayerDiffRequest{Base: "nginx:1.24", Image: "nginx:1.25"})
+	require.NoError(t, err)
+	assert.Equal(t, "nginx:1.25", diff.Image)
+	_, err = c.Cache(ctx)
+	assert.NoError(t, err)
+	_, err = c.Coverage(ctx)
+	assert.NoError(t, err)
+	_, err = c.ScanFailures(ctx, command.ImageHash)
+	assert.NoError(t, err)
+	events, err := c.ScanEvents(ctx, "scan")
+	require.NoError(t, err)
+	assert.Equal(t, "scan", events.ScanID)
+	status, err := c.EnterMaintenance(ctx, apiv1.MaintenanceRequest{Reason: "peak"})
+	require.NoError(t, err)
+	assert.True(t, status.Paused)
+	status, err = c.Maintenance(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "peak", status.Reason)
+	status, err = c.LeaveMaintenance(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.Paused)
+	selfTest, err := c.SelfTest(ctx)
+	require.NoError(t, err)
+	assert.True(t, selfTest.Success)
+	export, err := c.ExportResults(ctx, command.Wlid, []string{domain.ColumnName, domain.ColumnVulnerability})
+	require.NoError(t, err)
+	assert.Equal(t, "name,vulnerability\nnginx,CVE-2023-0001\n", string(export))
+	htmlReport, err := c.Report(ctx, command.Wlid, "")
+	require.NoError(t, err)
+	assert.Equal(t, "<!DOCTYPE html>\n", string(htmlReport))
+	assert.NoError(t, c.DeleteWorkload(ctx, command.Wlid))
+
+	falsePositive, err := c.AddFalsePositive(ctx, domain.FalsePositive{Vulnerability: "CVE-2021-44228", Package: "log4j-core"})
+	require.NoError(t, err)
+	assert.Equal(t, "id", falsePositive.ID)
+	falsePositives, err := c.ListFalsePositives(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, falsePositives)
+	rules, err := c.ExportFalsePositives(ctx, domain.FalsePositiveFormatGrype)
+	require.NoError(t, err)
+	assert.Equal(t, "ignore: []\n", string(rules))
+	assert.NoError(t, c.DeleteFalsePositive(ctx, falsePositive.ID))
+
+	triage, err := c.AddTriage(ctx, domain.Triage{Wlid: command.Wlid, Vulnerability: "CVE-2023-0286", State: domain.TriageAcknowledged, Reason: "compensating control"})
+	require.NoError(t, err)
+	assert.Equal(t, "id", triage.ID)
+	triaged, err := c.ListTriage(ctx, command.Wlid)
+	require.NoError(t, err)
+	assert.Empty(t, triaged)
+	assert.NoError(t, c.DeleteTriage(ctx, triage.ID))
+
+	sync, err := c.SyncInventory(ctx, domain.Inventory{Images: []domain.WorkloadImage{{Namespace: "default", Kind: "Deployment", Name: "nginx", ContainerName: "nginx", ImageTag: "nginx:1.25"}}})
+	require.NoError(t, err)
+	assert.Equal(t, domain.InventorySync{Images: 1, Added: 1}, sync)
+}
+
+func TestClient_RelaySBOM(t *testing.T) {
+	ctx := context.TODO()
+	tokensDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tokensDir, "minikube"), []byte("s3cr3t\n"), 0600))
+	c := newServer(t, true, controllers.WithRelay(tokensDir))
+	request := apiv1.RelaySBOMRequest{
+		Wlid:      "wlid://cluster-minikube/namespace-default/deployment-nginx",
+		ImageTag:  "nginx:1.14.1",
+		ImageHash: "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7",
+		SBOM:      &v1beta1.Document{},
+	}
+	var problem *apiv1.Problem
+	require.ErrorAs(t, c.RelaySBOM(ctx, request), &problem)
+	assert.Equal(t, http.StatusUnauthorized, problem.Status)
+
+	WithBearerToken("s3cr3t")(c)
+	assert.NoError(t, c.RelaySBOM(ctx, request))
+}
+
+func TestClient_bundles(t *testing.T) {
+	ctx := context.TODO()
+	tokensDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tokensDir, "offline"), []byte("s3cr3t\n"), 0600))
+	c := newServer(t, true, controllers.WithBundleTokens(tokensDir))
+
+	bundle, err := c.ExportBundle(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BundleFormatVersion, bundle.FormatVersion)
+	bundle.SBOMs = []domain.BundledSBOM{{ImageSlug: "nginx-1-14-1", Content: &v1beta1.Document{}}}
+	results, err := c.ScanBundle(ctx, bundle)
+	require.NoError(t, err)
+	var problem *apiv1.Problem
+	_, err = c.ImportResults(ctx, results)
+	require.ErrorAs(t, err, &problem)
+	assert.Equal(t, http.StatusUnauthorized, problem.Status)
+
+	WithBearerToken("s3cr3t")(c)
+	_, err = c.ImportResults(ctx, results)
+	assert.NoError(t, err)
+}
+
+func TestClient_problems(t *testing.T) {
+	ctx := context.TODO()
+	tests := []struct {
+		name string
+		call func(c *Client) error
+		want *apiv1.Problem
+	}{
+		{
+			name: "not ready",
+			call: func(c *Client) error {
+				return c.Ready(ctx)
+			},
+			want: &apiv1.Problem{Status: http.StatusServiceUnavailable, Title: "Service Unavailable"},
+		},
+		{
+			name: "rejected scan",
+			call: func(c *Client) error {
+				var command wssc.WebsocketScanCommand
+				command.ImageHash = "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"
+				return c.ScanCVE(ctx, command)
+			},
+			want: &apiv1.Problem{Status: http.StatusInternalServerError, Title: "Internal Server Error", Detail: "Wlid=, ImageHash=nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"},
+		},
+		{
+			name: "invalid diff",
+			call: func(c *Client) error {
+				_, err := c.Diff(ctx, domain.DiffRequest{Wlid: "wlid://cluster-minikube/namespace-default/deployment-nginx"})
+				return err
+			},
+			want: &apiv1.Problem{Status: http.StatusBadRequest, Title: "Bad Request", Detail: "either fromScanID or wlid and from are required"},
+		},
+		{
+			name: "empty batch",
+			call: func(c *Client) error {
+				_, err := c.ScanBatch(ctx, apiv1.BatchScanRequest{})
+				return err
+			},
+			want: &apiv1.Problem{Status: http.StatusBadRequest, Title: "Bad Request", Detail: "commands must not be empty"},
+		},
+		{
+			name: "profiling not enabled",
+			call: func(c *Client) error {
+				_, err := c.CaptureProfile(ctx, "scanID", 1)
+				return err
+			},
+			want: &apiv1.Problem{Status: http.StatusNotFound, Title: "Not Found", Detail: "profiling is not enabled"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(newServer(t, false))
+			var problem *apiv1.Problem
+			require.ErrorAs(t, err, &problem)
+			assert.Equal(t, tt.want, problem)
+		})
+	}
+}
+
+func TestClient_SelfTest_failed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"success":false,"stages":[{"name":"database","success":false,"duration":"1ms","error":"vulnerability DB is not initialized, run readiness probe"}]}`))
+	}))
+	defer srv.Close()
+	report, err := New(srv.URL).SelfTest(context.TODO())
+	var problem *apiv1.Problem
+	require.ErrorAs(t, err, &problem)
+	assert.Equal(t, http.StatusServiceUnavailable, problem.Status)
+	assert.Equal(t, []domain.SelfTestStage{{Name: domain.SelfTestDatabase, Duration: "1ms", Error: domain.ErrInitVulnDB.Error()}}, report.Stages)
+}
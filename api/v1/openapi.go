@@ -0,0 +1,83 @@
+// Package v1 holds the OpenAPI document of the kubevuln HTTP API and the request types it defines besides the
+// scan commands and the domain reports
+package v1
+
+import (
+	_ "embed"
+	"fmt"
+	"time"
+
+	wssc "github.com/armosec/armoapi-go/apis"
+	"github.com/docker/docker/api/types"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+)
+
+// OpenAPI is the OpenAPI 3 document of the HTTP API, served at /openapi.json
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// BatchScanRequest is a batch of container scan commands sharing the same kind, scanCVE by default
+type BatchScanRequest struct {
+	Kind     string                      `json:"kind,omitempty"`
+	Commands []wssc.WebsocketScanCommand `json:"commands"`
+}
+
+// ScanPlanRequest lists the scan commands the operator is about to send
+type ScanPlanRequest struct {
+	Commands []wssc.WebsocketScanCommand `json:"commands"`
+}
+
+// RepositoryScanRequest is the vulnerability scan of the manifests and lockfiles of a git repository at a ref, its
+// default branch when empty; the credentials for its host, or without server address, authenticate the clone
+type RepositoryScanRequest struct {
+	URL             string                 `json:"url"`
+	Ref             string                 `json:"ref,omitempty"`
+	Credentialslist []types.AuthConfig     `json:"credentialsList,omitempty"`
+	JobID           string                 `json:"jobID,omitempty"`
+	ParentJobID     string                 `json:"parentJobID,omitempty"`
+	Args            map[string]interface{} `json:"args,omitempty"`
+}
+
+// LayerDiffRequest compares two releases of an image by scanning the layers which differ between them, the credentials
+// authenticate the pulls of both
+type LayerDiffRequest struct {
+	Base            string             `json:"base"`
+	Image           string             `json:"image"`
+	Credentialslist []types.AuthConfig `json:"credentialsList,omitempty"`
+}
+
+// RelaySBOMRequest is an SBOM created by the kubevuln instance of an edge cluster for a container of one of its
+// workloads, relayed to a central kubevuln matching and submitting its vulnerabilities
+type RelaySBOMRequest struct {
+	Wlid               string                 `json:"wlid"`
+	ContainerName      string                 `json:"containerName,omitempty"`
+	ImageTag           string                 `json:"imageTag"`
+	ImageHash          string                 `json:"imageHash"`
+	JobID              string                 `json:"jobID,omitempty"`
+	ParentJobID        string                 `json:"parentJobID,omitempty"`
+	SBOMCreatorVersion string                 `json:"sbomCreatorVersion,omitempty"`
+	SBOMStatus         string                 `json:"sbomStatus,omitempty"`
+	SBOM               *v1beta1.Document      `json:"sbom"`
+	Args               map[string]interface{} `json:"args,omitempty"`
+}
+
+// MaintenanceRequest enters the maintenance mode, until it is left or, if set, until the given time
+type MaintenanceRequest struct {
+	Reason string     `json:"reason,omitempty"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+// Problem is the RFC 7807 answer to the scan commands and the errors
+type Problem struct {
+	Status int    `json:"status"`
+	Title  string `json:"title"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return fmt.Sprintf("%d %s", p.Status, p.Title)
+	}
+	return fmt.Sprintf("%d %s: %s", p.Status, p.Title, p.Detail)
+}
@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	wssc "github.com/armosec/armoapi-go/apis"
+	"github.com/docker/docker/api/types"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type schema struct {
+	Properties map[string]json.RawMessage `json:"properties"`
+	Required   []string                   `json:"required"`
+}
+
+type document struct {
+	OpenAPI    string                         `json:"openapi"`
+	Paths      map[string]map[string]struct{} `json:"paths"`
+	Components struct {
+		Schemas map[string]schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// jsonFields returns the names of the JSON fields of a struct type, including the ones of its embedded structs
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case name == "-" || !field.IsExported():
+		case field.Anonymous && name == "":
+			fields = append(fields, jsonFields(field.Type)...)
+		case name == "":
+			fields = append(fields, field.Name)
+		default:
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestOpenAPI(t *testing.T) {
+	var doc document
+	require.NoError(t, json.Unmarshal(OpenAPI, &doc))
+	assert.True(t, strings.HasPrefix(doc.OpenAPI, "3."), doc.OpenAPI)
+	assert.NotEmpty(t, doc.Paths)
+	// every reference resolves
+	for _, ref := range schemaRefs(string(OpenAPI)) {
+		_, ok := doc.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+		assert.True(t, ok, ref)
+	}
+	// the schemas match the types the API is implemented with
+	implementations := map[string]interface{}{
+		"AuthConfig":          types.AuthConfig{},
+		"BatchItemStatus":     domain.BatchItemStatus{},
+		"BatchReport":         domain.BatchReport{},
+		"BatchScanRequest":    BatchScanRequest{},
+		"CacheAgeBucket":      domain.CacheAgeBucket{},
+		"CacheReport":         domain.CacheReport{},
+		"CacheStats":          domain.CacheStats{},
+		"CoverageReport":      domain.CoverageReport{},
+		"FalsePositive":       domain.FalsePositive{},
+		"Inventory":           domain.Inventory{},
+		"InventorySync":       domain.InventorySync{},
+		"Finding":             domain.Finding{},
+		"LayerDiff":           domain.LayerDiff{},
+		"LayerDiffRequest":    LayerDiffRequest{},
+		"PlannedImage":        domain.PlannedImage{},
+		"MaintenanceRequest":  MaintenanceRequest{},
+		"MaintenanceStatus":   domain.MaintenanceStatus{},
+		"Problem":             Problem{},
+		"Progress":            domain.Progress{},
+		"RegistryScanCommand": wssc.RegistryScanCommand{},
+		"ScanCommand":         wssc.WebsocketScanCommand{},
+		"ScanDiff":            domain.ScanDiff{},
+		"ScanEvent":           domain.ScanEvent{},
+		"ScanEvents":          domain.ScanEvents{},
+		"ScanFailure":         domain.ScanFailure{},
+		"ScanPlan":            domain.ScanPlan{},
+		"ScanPlanRequest":     ScanPlanRequest{},
+		"ScanProfile":         domain.ScanProfile{},
+		"SelfTestReport":      domain.SelfTestReport{},
+		"SelfTestStage":       domain.SelfTestStage{},
+		"SessionChain":        wssc.SessionChain{},
+		"SeverityChange":      domain.SeverityChange{},
+		"Triage":              domain.Triage{},
+		"VersionInfo":         domain.VersionInfo{},
+		"WorkloadCoverage":    domain.WorkloadCoverage{},
+		"WorkloadImage":       domain.WorkloadImage{},
+	}
+	for name, value := range implementations {
+		t.Run(name, func(t *testing.T) {
+			s, ok := doc.Components.Schemas[name]
+			require.True(t, ok)
+			var properties []string
+			for property := range s.Properties {
+				properties = append(properties, property)
+			}
+			sort.Strings(properties)
+			assert.Equal(t, jsonFields(reflect.TypeOf(value)), properties)
+			assert.Subset(t, properties, s.Required)
+		})
+	}
+}
+
+// schemaRefs returns the schema references of the document
+func schemaRefs(doc string) []string {
+	var refs []string
+	for _, part := range strings.Split(doc, `"$ref": "`)[1:] {
+		ref, _, _ := strings.Cut(part, `"`)
+		refs = append(refs, ref)
+	}
+	return refs
+}
@@ -1,7 +1,134 @@
 package main
 
-import "github.com/kubescape/go-logger"
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/kubescape/kubevuln/api/v1/client"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+const usage = `usage: kubevuln-cli bundle <command> [flags]
+
+commands:
+  export  export the SBOMs stored by an offline kubevuln to an NDJSON bundle
+  scan    scan a bundle with a connected kubevuln and write the results
+  import  import the results of a bundle back into the offline kubevuln
+`
 
 func main() {
-	logger.L().Fatal("CLI not yet implemented")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run runs a CLI command, reading the files "-" from stdin and writing them to stdout, and returns the exit code
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) < 2 || args[0] != "bundle" {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	command := args[1]
+	flags := flag.NewFlagSet("bundle "+command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	url := flags.String("url", "http://localhost:8080", "base URL of the kubevuln API")
+	token := flags.String("token", "", "bearer token of the requests")
+	in := flags.String("in", "-", "file the bundle or the results are read from")
+	out := flags.String("out", "-", "file the bundle, the results or the import report are written to")
+	if err := flags.Parse(args[2:]); err != nil {
+		return 2
+	}
+	var opts []client.Option
+	if *token != "" {
+		opts = append(opts, client.WithBearerToken(*token))
+	}
+	c := client.New(*url, opts...)
+
+	// the bundles are NDJSON, the results and the import reports JSON
+	var encode func(io.Writer) error
+	var err error
+	switch command {
+	case "export":
+		var bundle domain.SBOMBundle
+		if bundle, err = c.ExportBundle(ctx); err == nil {
+			encode = func(w io.Writer) error {
+				return domain.WriteSBOMBundle(w, bundle)
+			}
+		}
+	case "scan":
+		var bundle domain.SBOMBundle
+		err = readFile(*in, stdin, func(r io.Reader) (err error) {
+			bundle, err = domain.ReadSBOMBundle(r)
+			return err
+		})
+		if err == nil {
+			var results domain.ResultsBundle
+			results, err = c.ScanBundle(ctx, bundle)
+			encode = encodeJSON(results)
+		}
+	case "import":
+		var results domain.ResultsBundle
+		err = readFile(*in, stdin, func(r io.Reader) error {
+			return json.NewDecoder(r).Decode(&results)
+		})
+		if err == nil {
+			var report domain.BundleImportReport
+			report, err = c.ImportResults(ctx, results)
+			encode = encodeJSON(report)
+		}
+	default:
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	if err == nil {
+		err = writeFile(*out, stdout, encode)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "bundle %s: %v\n", command, err)
+		return 1
+	}
+	return 0
+}
+
+// encodeJSON encodes v as JSON
+func encodeJSON(v interface{}) func(io.Writer) error {
+	return func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(v)
+	}
+}
+
+// readFile decodes the file at path, or stdin for "-"
+func readFile(path string, stdin io.Reader, decode func(io.Reader) error) error {
+	reader := stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		reader = f
+	}
+	return decode(reader)
+}
+
+// writeFile encodes the file at path, or stdout for "-"
+func writeFile(path string, stdout io.Writer, encode func(io.Writer) error) error {
+	if path == "-" {
+		return encode(stdout)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if err := encode(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
 }
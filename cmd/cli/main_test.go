@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/controllers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_run(t *testing.T) {
+	tokensDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tokensDir, "offline"), []byte("s3cr3t\n"), 0600))
+	h := controllers.NewHTTPController(services.NewMockScanService(true), 1, controllers.WithBundleTokens(tokensDir))
+	router := gin.New()
+	h.RegisterRoutes(router)
+	srv := httptest.NewServer(router)
+	t.Cleanup(func() {
+		srv.Close()
+		h.Shutdown()
+	})
+	ctx := context.TODO()
+	dir := t.TempDir()
+	bundleFile := filepath.Join(dir, "bundle.ndjson")
+
+	var stdout, stderr bytes.Buffer
+	require.Zero(t, run(ctx, []string{"bundle", "export", "-url", srv.URL, "-out", bundleFile}, nil, &stdout, &stderr), stderr.String())
+	f, err := os.Open(bundleFile)
+	require.NoError(t, err)
+	bundle, err := domain.ReadSBOMBundle(f)
+	_ = f.Close()
+	require.NoError(t, err)
+	assert.Equal(t, domain.BundleFormatVersion, bundle.FormatVersion)
+
+	stdout.Reset()
+	require.Zero(t, run(ctx, []string{"bundle", "scan", "-url", srv.URL, "-in", bundleFile}, nil, &stdout, &stderr), stderr.String())
+	var results domain.ResultsBundle
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &results))
+	assert.Equal(t, domain.BundleFormatVersion, results.FormatVersion)
+
+	stdin := bytes.NewReader(stdout.Bytes())
+	stdout.Reset()
+	require.Zero(t, run(ctx, []string{"bundle", "import", "-url", srv.URL, "-token", "s3cr3t"}, stdin, &stdout, &stderr), stderr.String())
+	assert.JSONEq(t, `{"stored":0,"skipped":0,"failed":0}`, stdout.String())
+}
+
+func Test_run_errors(t *testing.T) {
+	ctx := context.TODO()
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 2, run(ctx, nil, nil, &stdout, &stderr))
+	assert.Equal(t, 2, run(ctx, []string{"bundle", "unknown"}, nil, &stdout, &stderr))
+	assert.Equal(t, 1, run(ctx, []string{"bundle", "scan", "-url", "http://localhost:1"}, strings.NewReader("{"), &stdout, &stderr))
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"os"
+
+	v1 "github.com/kubescape/kubevuln/adapters/v1"
+)
+
+// the extractor catalogs an image saved by the scanner, see v1.WithExtractionSandbox
+func main() {
+	os.Exit(v1.RunExtractor(os.Args[1:], os.Stdout, os.Stderr))
+}
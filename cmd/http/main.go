@@ -6,10 +6,11 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
-	"github.com/armosec/armoapi-go/apis"
 	"github.com/gin-gonic/gin"
 	"github.com/kubescape/go-logger"
 	"github.com/kubescape/go-logger/helpers"
@@ -17,10 +18,14 @@ import (
 	v1 "github.com/kubescape/kubevuln/adapters/v1"
 	"github.com/kubescape/kubevuln/config"
 	"github.com/kubescape/kubevuln/controllers"
+	"github.com/kubescape/kubevuln/core/domain"
 	"github.com/kubescape/kubevuln/core/ports"
 	"github.com/kubescape/kubevuln/core/services"
+	"github.com/kubescape/kubevuln/internal/tools"
 	"github.com/kubescape/kubevuln/repositories"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 )
 
 func main() {
@@ -30,14 +35,17 @@ func main() {
 	if err != nil {
 		logger.L().Ctx(ctx).Fatal("load config error", helpers.Error(err))
 	}
+	for _, warning := range c.Warnings() {
+		logger.L().Warning(warning)
+	}
 
 	// to enable otel, set OTEL_COLLECTOR_SVC=otel-collector:4317
-	if otelHost, present := os.LookupEnv("OTEL_COLLECTOR_SVC"); present {
+	if c.OtelCollectorSvc != "" {
 		ctx = logger.InitOtel("kubevuln",
-			os.Getenv("RELEASE"),
+			c.Release,
 			c.AccountID,
 			c.ClusterName,
-			url.URL{Host: otelHost})
+			url.URL{Host: c.OtelCollectorSvc})
 		defer logger.ShutdownOtel(ctx)
 	}
 
@@ -45,38 +53,534 @@ func main() {
 	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// write only to the scratch volume, so that kubevuln runs with a read-only root filesystem
+	var grypeOptions []v1.GrypeAdapterOption
+	profilesDir := filepath.Join(os.TempDir(), "profiles")
+	if c.ScratchDir != "" {
+		scratch := tools.NewScratchDirs(c.ScratchDir)
+		if err := scratch.Prepare(); err != nil {
+			logger.L().Ctx(ctx).Fatal("scratch volume is not usable", helpers.Error(err),
+				helpers.String("scratchDir", c.ScratchDir))
+		}
+		if c.WorkDir == "" {
+			c.WorkDir = scratch.Workspaces
+		}
+		grypeOptions = append(grypeOptions, v1.WithDBRootDir(scratch.DB))
+		profilesDir = scratch.Profiles
+	}
+	// fail fast rather than on the first scan when the scan workspaces cannot be written
+	workDir := c.WorkDir
+	if workDir == "" {
+		workDir = os.TempDir()
+	}
+	if err := tools.CheckWritable(workDir); err != nil {
+		logger.L().Ctx(ctx).Fatal("scan workspaces directory is not usable, set scratchDir to a writable volume", helpers.Error(err))
+	}
+	if os.Geteuid() == 0 {
+		logger.L().Warning("running as root, kubevuln needs neither root nor added capabilities")
+	}
+	// identify kubevuln to the registries and the backend, the requests of a scan also carry its scanID
+	userAgent := c.UserAgent
+	if userAgent == "" {
+		userAgent = v1.UserAgent("kubevuln", c.Release, c.ClusterName)
+	}
+	v1.SetRequestHeaders(v1.RequestHeaders{UserAgent: userAgent, Headers: c.RequestHeaders})
+
 	var storage *repositories.APIServerStore
 	if c.Storage {
-		storage, err = repositories.NewAPIServerStorage("kubescape")
+		storage, err = repositories.NewAPIServerStorage("kubescape",
+			repositories.WithContentCompression(repositories.Compression(c.StorageCompression)),
+			repositories.WithContentEncoding(repositories.Encoding(c.StorageEncoding)))
 		if err != nil {
 			logger.L().Ctx(ctx).Fatal("storage initialization error", helpers.Error(err))
 		}
 	}
-	sbomAdapter := v1.NewSyftAdapter(c.ScanTimeout, c.MaxImageSize)
-	cveAdapter := v1.NewGrypeAdapter(c.ListingURL)
+	// remove the scan workspaces leaked by a previous crash
+	if err := v1.CleanupWorkspaces(c.WorkDir); err != nil {
+		logger.L().Ctx(ctx).Warning("failed to cleanup scan workspaces", helpers.Error(err))
+	}
+	syftOptions := []v1.SyftAdapterOption{v1.WithPullTimeout(c.PullTimeout), v1.WithPullRetries(c.PullRetries, c.PullRetryBackoff), v1.WithWorkDir(c.WorkDir)}
+	// read the images already pulled on the node from its container runtime, or else from the registry
+	if c.CRIRuntime != "" || c.CRISocket != "" {
+		runtime, err := v1.DetectCRIRuntime(c.CRIRuntime, c.CRISocket)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("container runtime image access unavailable, images are pulled from the registry", helpers.Error(err))
+		} else if exportFunc, err := runtime.ExportFunc(); err != nil {
+			logger.L().Ctx(ctx).Warning("container runtime image access unavailable, images are pulled from the registry", helpers.Error(err),
+				helpers.String("runtime", runtime.Name))
+		} else {
+			logger.L().Info("reading images from the container runtime",
+				helpers.String("runtime", runtime.Name),
+				helpers.String("socket", runtime.Socket))
+			syftOptions = append(syftOptions, v1.WithCRIRuntime(runtime, exportFunc))
+		}
+	}
+	if len(c.CatalogerModes) > 0 {
+		syftOptions = append(syftOptions, v1.WithCatalogerModes(c.CatalogerModes))
+	}
+	if len(c.RegistryAuth) > 0 {
+		auths := make([]v1.RegistryAuth, 0, len(c.RegistryAuth))
+		for _, auth := range c.RegistryAuth {
+			auths = append(auths, v1.RegistryAuth{
+				Provider:         auth.Provider,
+				Registry:         auth.Registry,
+				Repositories:     auth.Repositories,
+				Username:         auth.Username,
+				PasswordFile:     auth.PasswordFile,
+				TokenFile:        auth.TokenFile,
+				RefreshTokenFile: auth.RefreshTokenFile,
+				URL:              auth.URL,
+			})
+		}
+		broker, err := v1.NewRegistryAuthBroker(auths)
+		if err != nil {
+			logger.L().Ctx(ctx).Fatal("registry auth initialization error", helpers.Error(err))
+		}
+		syftOptions = append(syftOptions, v1.WithRegistryAuth(broker))
+	}
+	if len(c.RegistryMirrors) > 0 {
+		syftOptions = append(syftOptions, v1.WithRegistryMirrors(c.RegistryMirrors))
+	}
+	if c.ScanProfile != "" {
+		syftOptions = append(syftOptions, v1.WithScanProfile(c.ScanProfile))
+	}
+	if c.ExtractionSandbox != "" {
+		syftOptions = append(syftOptions, v1.WithExtractionSandbox(c.ExtractionSandbox))
+	}
+	if c.ExcludeSBOMFiles {
+		syftOptions = append(syftOptions, v1.WithoutFiles())
+	}
+	if c.EmbeddedImagesDepth > 0 {
+		syftOptions = append(syftOptions, v1.WithEmbeddedImages(c.EmbeddedImagesDepth))
+	}
+	if c.CatalogerRetries > 0 {
+		syftOptions = append(syftOptions, v1.WithCatalogerRetries(c.CatalogerRetries))
+	}
+	if len(c.Ecosystems) > 0 {
+		syftOptions = append(syftOptions, v1.WithEcosystems(c.Ecosystems))
+		if c.OSVURL != "" {
+			grypeOptions = append(grypeOptions, v1.WithOSV(v1.NewOSVAdapter(c.OSVURL, c.Ecosystems)))
+		}
+	}
+	if c.PackageOverridesFile != "" {
+		overrides, err := v1.ParsePackageOverrides(c.PackageOverridesFile)
+		if err != nil {
+			logger.L().Ctx(ctx).Fatal("package overrides error", helpers.Error(err))
+		}
+		grypeOptions = append(grypeOptions, v1.WithPackageOverrides(overrides))
+	}
+	// the vulnerabilities DB may be hosted on an internal artifact server
+	grypeOptions = append(grypeOptions,
+		v1.WithDBCACert(c.DBCACertFile),
+		v1.WithDBChecksumPolicy(c.DBChecksumPolicy),
+		v1.WithDBMaxAge(c.DBMaxAge),
+		v1.WithDBURLRewrites(c.DBURLRewrites))
+	sbomAdapter := v1.NewSyftAdapter(c.ScanTimeout, c.MaxImageSize, syftOptions...)
+	cveAdapter := v1.NewGrypeAdapter(c.ListingURL, grypeOptions...)
 	var platform ports.Platform
 	if c.KeepLocal {
 		platform = adapters.NewMockPlatform()
 	} else {
-		platform = v1.NewArmoAdapter(c.AccountID, c.BackendOpenAPI, c.EventReceiverRestURL)
+		var getNamespaceLabelsFunc func(context.Context, string) (map[string]string, error)
+		if len(c.NamespaceLabelAttributes) > 0 {
+			getNamespaceLabelsFunc = v1.NamespaceLabelsGetter(kubernetesClient(ctx))
+		}
+		armoOptions := []v1.ArmoAdapterOption{
+			v1.WithContextAttributes(c.ContextAttributes, c.NamespaceLabelAttributes, getNamespaceLabelsFunc),
+			v1.WithFilterTimeout(c.FilterTimeout),
+			v1.WithReportVersion(c.ReportVersion),
+			v1.WithLegacySBOM(c.LegacySBOMMaxSize),
+			v1.WithSubmitOrdering(c.SubmitOrdering),
+			v1.WithCircuitBreaker(c.SubmitBreakerThreshold, c.SubmitBreakerCoolDown),
+		}
+		// keep the reports on disk while the event receiver is unavailable, they are submitted once it is back
+		if c.SubmitSpoolDir != "" {
+			armoOptions = append(armoOptions, v1.WithSubmissionSpool(c.SubmitSpoolDir, c.SubmitSpoolMaxReports, c.SubmitSpoolMaxSize))
+		}
+		// submit pseudonymized workload identifiers, resolvable in-cluster with the pseudonyms ConfigMap
+		if c.AnonymizationSaltFile != "" {
+			salt, err := os.ReadFile(c.AnonymizationSaltFile)
+			if err != nil {
+				logger.L().Ctx(ctx).Fatal("anonymization salt error", helpers.Error(err))
+			}
+			if strings.TrimSpace(string(salt)) == "" {
+				logger.L().Ctx(ctx).Fatal("anonymization salt is empty", helpers.String("file", c.AnonymizationSaltFile))
+			}
+			armoOptions = append(armoOptions, v1.WithAnonymization([]byte(strings.TrimSpace(string(salt))),
+				repositories.NewConfigMapPseudonymStore(kubernetesClient(ctx), "kubescape", c.PseudonymConfigMap)))
+		}
+		// fail over to the other event receiver endpoints when the primary one is down
+		if len(c.EventReceiverFailoverURLs) > 0 {
+			armoOptions = append(armoOptions, v1.WithFailoverEndpoints(c.EventReceiverFailoverURLs))
+		}
+		if c.EventReceiverCompression != "" {
+			armoOptions = append(armoOptions, v1.WithCompression(c.EventReceiverCompression))
+		}
+		// submit the vulnerabilities of each image digest once, the other workloads of the image reference them
+		if c.UniqueImageReportInterval > 0 {
+			armoOptions = append(armoOptions, v1.WithUniqueImageReports(c.UniqueImageReportInterval))
+		}
+		platform = v1.NewArmoAdapter(c.AccountID, c.BackendOpenAPI, c.EventReceiverRestURL, armoOptions...)
+	}
+	var callbackOptions []v1.CallbackAdapterOption
+	if c.CallbackTemplateFile != "" {
+		tmpl, err := v1.ParsePayloadTemplate(c.CallbackTemplateFile)
+		if err != nil {
+			logger.L().Ctx(ctx).Fatal("callback template error", helpers.Error(err))
+		}
+		callbackOptions = append(callbackOptions, v1.WithPayloadTemplate(tmpl, c.CallbackContentType))
+	}
+	scanProfile := v1.ScanProfileFull
+	if c.ScanProfile != "" {
+		scanProfile = c.ScanProfile
+	}
+	serviceOptions := []services.ScanServiceOption{
+		services.WithNotifier(v1.NewCallbackAdapter(callbackOptions...)),
+		services.WithImageResolver(sbomAdapter),
+		services.WithMatchTimeout(c.MatchTimeout),
+		services.WithAllowedRegistries(c.AllowedRegistries),
+		services.WithMaxImageAge(c.MaxImageAge),
+		services.WithRelease(c.Release),
+		services.WithScanProvenance(c.NodeName, scanProfile),
+		services.WithSelfTest(v1.NewCanaryAdapter(c.SelfTestImage)),
+		services.WithSubmitTimeout(c.SubmitTimeout),
+	}
+	if c.EnrichmentSource != "" {
+		serviceOptions = append(serviceOptions, services.WithEnrichment(v1.NewEnrichmentAdapter(c.EnrichmentSource, c.EnrichmentRefreshInterval)))
+	}
+	if c.AnnotationGating {
+		serviceOptions = append(serviceOptions, services.WithAnnotationGating(v1.NewWorkloadAdapter(kubernetesClient(ctx))))
+	}
+	if c.IgnoreUnfixed {
+		serviceOptions = append(serviceOptions, services.WithIgnoreUnfixed())
+	}
+	if c.MatchExplanations {
+		serviceOptions = append(serviceOptions, services.WithMatchExplanations())
+	}
+	if c.RepositoryScans {
+		serviceOptions = append(serviceOptions, services.WithRepositoryScans(sbomAdapter))
+	}
+	if c.LayerDiffScans {
+		serviceOptions = append(serviceOptions, services.WithLayerDiffScans(sbomAdapter))
+	}
+	if c.RelayTokensDir != "" {
+		serviceOptions = append(serviceOptions, services.WithRelay())
+	}
+	// export the stored SBOMs of an offline cluster and scan the bundles of the offline ones
+	if c.Bundles {
+		var bundleResults ports.ScanResultRepository
+		if c.Storage {
+			bundleResults = storage
+		}
+		serviceOptions = append(serviceOptions, services.WithBundles(bundleResults))
+	}
+	// keep the findings of the past scans for the retention of their severity
+	if len(c.HistoryRetention) > 0 {
+		serviceOptions = append(serviceOptions, services.WithHistoryRetention(c.HistoryRetention))
+	}
+	// keep the false positive feedback in a ConfigMap, its matches are ignored by the following reports
+	if c.FalsePositiveConfigMap != "" {
+		serviceOptions = append(serviceOptions, services.WithFalsePositives(
+			repositories.NewConfigMapFalsePositiveStore(kubernetesClient(ctx), "kubescape", c.FalsePositiveConfigMap)))
+	}
+	// keep the triage of the findings in a ConfigMap, the snoozed findings are left out of the verdicts until their date
+	if c.TriageConfigMap != "" {
+		serviceOptions = append(serviceOptions, services.WithTriage(
+			repositories.NewConfigMapTriageStore(kubernetesClient(ctx), "kubescape", c.TriageConfigMap)))
+	}
+	// keep the last scan failure of each image in a ConfigMap, the attempt counts survive the restarts
+	if c.ScanFailuresConfigMap != "" {
+		serviceOptions = append(serviceOptions, services.WithScanFailureRepository(
+			repositories.NewConfigMapScanFailureStore(kubernetesClient(ctx), "kubescape", c.ScanFailuresConfigMap)))
+	}
+	// sign the generated SBOMs, cosign verifies the attestations with the public key
+	if c.SBOMSigningKeyFile != "" {
+		signer, err := v1.NewSBOMSigner(c.SBOMSigningKeyFile)
+		if err != nil {
+			logger.L().Ctx(ctx).Fatal("SBOM signing key error", helpers.Error(err))
+		}
+		serviceOptions = append(serviceOptions, services.WithSBOMSigning(signer, c.SBOMAttestationPush))
+	}
+	// skip the scans of the vetted images, once the allowlist signature is verified
+	if c.TrustedDigestsFile != "" {
+		signatureFile := c.TrustedDigestsSignatureFile
+		if signatureFile == "" {
+			signatureFile = c.TrustedDigestsFile + ".sig"
+		}
+		allowlist, err := v1.ParseTrustedDigests(c.TrustedDigestsFile, signatureFile, c.TrustedDigestsKeyFile)
+		if err != nil {
+			logger.L().Ctx(ctx).Fatal("trusted digests error", helpers.Error(err))
+		}
+		logger.L().Info("trusted digests loaded", helpers.String("version", allowlist.Version),
+			helpers.Int("digests", len(allowlist.Digests)))
+		serviceOptions = append(serviceOptions, services.WithTrustedDigests(allowlist))
+	}
+	// flag the images whose base drifted from the golden base catalog
+	if c.GoldenBasesFile != "" {
+		catalog, err := v1.ParseGoldenBases(c.GoldenBasesFile)
+		if err != nil {
+			logger.L().Ctx(ctx).Fatal("golden bases error", helpers.Error(err))
+		}
+		logger.L().Info("golden bases loaded", helpers.String("version", catalog.Version),
+			helpers.Int("bases", len(catalog.Bases)))
+		serviceOptions = append(serviceOptions, services.WithGoldenBases(catalog))
+	}
+	if c.ExploitMapping {
+		serviceOptions = append(serviceOptions, services.WithExploits(v1.NewExploitAdapter(c.ExploitDBURL, c.MetasploitURL, c.ExploitBundle, c.ExploitRefreshInterval)))
+	}
+	if len(c.Suppressions) > 0 {
+		suppressions := make([]domain.Suppression, 0, len(c.Suppressions))
+		for _, suppression := range c.Suppressions {
+			suppressions = append(suppressions, domain.Suppression{
+				Vulnerability: suppression.Vulnerability,
+				Package:       suppression.Package,
+				Location:      suppression.Location,
+			})
+		}
+		serviceOptions = append(serviceOptions, services.WithSuppressions(suppressions))
+	}
+	if len(c.SeverityThresholds) > 0 {
+		thresholds := make([]domain.SeverityThresholds, 0, len(c.SeverityThresholds))
+		for _, threshold := range c.SeverityThresholds {
+			thresholds = append(thresholds, domain.SeverityThresholds{
+				Name:              threshold.Name,
+				Namespaces:        threshold.Namespaces,
+				NamespaceSelector: threshold.NamespaceSelector,
+				Fail:              threshold.Fail,
+				Alert:             threshold.Alert,
+			})
+		}
+		serviceOptions = append(serviceOptions, services.WithSeverityThresholds(thresholds, v1.NewWorkloadAdapter(kubernetesClient(ctx))))
+	}
+	// forward the findings of the scans matching the routes to other backends, such as a SIEM
+	if len(c.ResultRoutes) > 0 {
+		sinks := map[string]ports.ResultSink{}
+		for _, sink := range c.ResultSinks {
+			var token string
+			if sink.TokenFile != "" {
+				b, err := os.ReadFile(sink.TokenFile)
+				if err != nil {
+					logger.L().Ctx(ctx).Fatal("result sink token error", helpers.Error(err), helpers.String("sink", sink.Name))
+				}
+				token = strings.TrimSpace(string(b))
+			}
+			switch sink.Type {
+			case "splunk":
+				sinks[sink.Name] = v1.NewSplunkAdapter(sink.URL, token, sink.Index, sink.BatchSize)
+			case "elasticsearch":
+				sinks[sink.Name] = v1.NewElasticsearchAdapter(sink.URL, token, sink.Index, sink.BatchSize)
+			default:
+				sinks[sink.Name] = v1.NewWebhookAdapter(sink.URL, token)
+			}
+		}
+		routes := make([]domain.ResultRoute, 0, len(c.ResultRoutes))
+		for _, route := range c.ResultRoutes {
+			routes = append(routes, domain.ResultRoute{
+				Name:       route.Name,
+				Sink:       route.Sink,
+				Severities: route.Severities,
+				Namespaces: route.Namespaces,
+				Verdicts:   route.Verdicts,
+			})
+		}
+		serviceOptions = append(serviceOptions, services.WithResultRoutes(routes, sinks))
+	}
+	// show the results of the scans on the workloads, needs the patch permission on them
+	if c.WorkloadAnnotations {
+		serviceOptions = append(serviceOptions, services.WithResultAnnotations(v1.NewWorkloadPatchAdapter(kubernetesClient(ctx), c.WorkloadAnnotationsQPS)))
+	}
+	// score the risk of the images with the configured weights
+	riskWeights := domain.DefaultRiskWeights()
+	for severity, points := range c.RiskWeights.Severities {
+		for known := range riskWeights.Severities {
+			if strings.EqualFold(known, severity) {
+				delete(riskWeights.Severities, known)
+			}
+		}
+		riskWeights.Severities[severity] = points
+	}
+	for _, weight := range []struct {
+		value  *float64
+		target *float64
+	}{
+		{c.RiskWeights.Exploit, &riskWeights.Exploit},
+		{c.RiskWeights.Exposed, &riskWeights.Exposed},
+		{c.RiskWeights.Fixable, &riskWeights.Fixable},
+		{c.RiskWeights.Irrelevant, &riskWeights.Irrelevant},
+	} {
+		if weight.value != nil {
+			*weight.target = *weight.value
+		}
+	}
+	var exposureChecker ports.ExposureChecker
+	if c.RiskExposure {
+		exposureChecker = v1.NewWorkloadAdapter(kubernetesClient(ctx))
+	}
+	serviceOptions = append(serviceOptions, services.WithRiskScore(riskWeights, exposureChecker))
+	// the running images are the inventory posted by the operator instead of the workloads listed from the cluster
+	var inventory *repositories.MemoryInventory
+	if c.InventorySync {
+		inventory = repositories.NewMemoryInventory()
+		serviceOptions = append(serviceOptions, services.WithInventory(inventory))
+	}
+	workloadLister := func() ports.WorkloadLister {
+		if inventory != nil {
+			return inventory
+		}
+		return v1.NewWorkloadAdapter(kubernetesClient(ctx))
+	}
+	if c.CoverageTracking {
+		serviceOptions = append(serviceOptions, services.WithCoverageTracking(workloadLister(), c.CoverageWindow))
+		// the images scanned before a restart are covered by their stored results
+		if c.Storage {
+			serviceOptions = append(serviceOptions, services.WithCoverageHistory(storage))
+		}
+	}
+	if c.Storage && c.GCGracePeriod > 0 {
+		// the templates keep the results of the workloads scaled to zero, which the inventory does not list, the
+		// inventory the ones of the images its pods run
+		serviceOptions = append(serviceOptions, services.WithGarbageCollection(v1.NewWorkloadTemplateAdapter(kubernetesClient(ctx)), storage, c.GCGracePeriod))
+	}
+	// discard the stored SBOMs of other scanner versions and generate them again
+	if c.Storage {
+		serviceOptions = append(serviceOptions, services.WithSBOMCompatibilityCheck(storage, workloadLister()))
+	}
+	// upgrade the stored SBOMs of older schema versions in place
+	if c.Storage && c.SBOMMigrationInterval > 0 {
+		serviceOptions = append(serviceOptions, services.WithSBOMMigration(storage, storage, c.SBOMMigrationInterval))
+	}
+	// mark the stored results of the deleted workloads as orphaned
+	var orphanRepository ports.ScanResultRepository
+	if c.Storage {
+		orphanRepository = storage
+	}
+	serviceOptions = append(serviceOptions, services.WithWorkloadDeletion(orphanRepository, c.SendTombstones))
+	// select the source of the runtime relevancy
+	switch c.RelevancyProvider {
+	case v1.RelevancyFile:
+		var sbomRepository ports.SBOMRepository
+		if c.Storage {
+			sbomRepository = storage
+		}
+		relevancy, err := v1.NewFileRelevancy(c.RelevancyFile, sbomRepository, sbomAdapter.Version())
+		if err != nil {
+			logger.L().Ctx(ctx).Fatal("relevancy file error", helpers.Error(err))
+		}
+		serviceOptions = append(serviceOptions, services.WithRelevancyProvider(relevancy))
+	case v1.RelevancyNone:
+		serviceOptions = append(serviceOptions, services.WithRelevancyProvider(v1.NewNoRelevancy()))
+	default:
+		if c.Storage {
+			serviceOptions = append(serviceOptions, services.WithRelevancyProvider(v1.NewApplicationProfileRelevancy(storage, sbomAdapter.Version())))
+		}
+	}
+	// stream the progress of the scans to the UI integrations
+	var progressBroker ports.ProgressBroker
+	if c.ProgressEvents {
+		progressBroker = v1.NewProgressBroker()
+		serviceOptions = append(serviceOptions, services.WithProgressBroker(progressBroker))
+	}
+	// keep the detailed events of the last scans for troubleshooting
+	if c.ScanEventLog > 0 {
+		serviceOptions = append(serviceOptions, services.WithScanEvents(c.ScanEventLog))
+	}
+	service := services.NewScanService(sbomAdapter, storage, cveAdapter, storage, platform, c.Storage, serviceOptions...)
+	controllerOptions := []controllers.HTTPControllerOption{controllers.WithConfig(c.Redacted())}
+	if c.ScanQueueConfigMap != "" {
+		controllerOptions = append(controllerOptions, controllers.WithScanQueue(
+			repositories.NewConfigMapQueueStore(kubernetesClient(ctx), "kubescape", c.ScanQueueConfigMap)))
+	}
+	if progressBroker != nil {
+		controllerOptions = append(controllerOptions, controllers.WithProgressBroker(progressBroker))
+	}
+	if c.RegistryWebhook {
+		controllerOptions = append(controllerOptions, controllers.WithRegistryWebhook(c.RegistryWebhookRepositories, c.RegistryWebhookSecretFile))
+	}
+	if c.RelayTokensDir != "" {
+		controllerOptions = append(controllerOptions, controllers.WithRelay(c.RelayTokensDir))
+	}
+	if c.Bundles && c.BundleTokensDir != "" {
+		controllerOptions = append(controllerOptions, controllers.WithBundleTokens(c.BundleTokensDir))
+	}
+	// the scheduled scans and the scans triggered by workload changes only run within the scan windows
+	if len(c.ScanWindows) > 0 {
+		windows := make([]controllers.ScanWindow, 0, len(c.ScanWindows))
+		for _, window := range c.ScanWindows {
+			windows = append(windows, controllers.ScanWindow{Start: window.Start, Duration: window.Duration})
+		}
+		controllerOptions = append(controllerOptions, controllers.WithScanWindows(windows))
+	}
+	// a namespace flooding the queue does not starve the others
+	if c.NamespaceFairness {
+		controllerOptions = append(controllerOptions, controllers.WithNamespaceFairness(c.NamespaceWeights))
+	}
+	// the profiles of slow scans are captured on demand to the scratch volume
+	if c.Profiling {
+		if err := tools.CheckWritable(profilesDir); err != nil {
+			logger.L().Ctx(ctx).Fatal("profiles directory is not usable", helpers.Error(err))
+		}
+		controllerOptions = append(controllerOptions, controllers.WithProfiling(profilesDir))
+	}
+	controller := controllers.NewHTTPController(service, c.ScanConcurrency, controllerOptions...)
+	// resume the scans interrupted by a restart
+	controller.ResumeQueue(ctx)
+	// the SBOMs of other scanner versions are discarded before migrating the remaining ones
+	go func() {
+		controller.CheckSBOMCompatibility(ctx)
+		controller.MigrateSBOMs(ctx)
+	}()
+
+	// scan new images as soon as workloads are updated
+	if c.WatchWorkloads {
+		go func() {
+			if err := controller.WatchWorkloads(ctx, kubernetesClient(ctx), c.ClusterName, sbomAdapter); err != nil {
+				logger.L().Ctx(ctx).Error("workload watcher error", helpers.Error(err))
+			}
+		}()
+	}
+
+	// scan again the images of the workloads on the built-in schedule
+	if c.ScanSchedule != "" || len(c.ScanScheduleNamespaces) > 0 {
+		go func() {
+			schedule := controllers.ScanSchedule{
+				Concurrency: c.ScanScheduleConcurrency,
+				Default:     c.ScanSchedule,
+				Jitter:      c.ScanScheduleJitter,
+				Namespaces:  c.ScanScheduleNamespaces,
+			}
+			if inventory != nil {
+				schedule.Inventory = inventory
+			}
+			if err := controller.ScheduleScans(ctx, kubernetesClient(ctx), c.ClusterName, sbomAdapter, schedule); err != nil {
+				logger.L().Ctx(ctx).Error("scan scheduler error", helpers.Error(err))
+			}
+		}()
+	}
+
+	if err := controller.RegisterCacheMetrics(); err != nil {
+		logger.L().Ctx(ctx).Warning("failed to register cache metrics", helpers.Error(err))
+	}
+	if c.CoverageTracking {
+		if err := controller.RegisterCoverageMetrics(); err != nil {
+			logger.L().Ctx(ctx).Warning("failed to register coverage metrics", helpers.Error(err))
+		}
+	}
+
+	// delete the stored scan results of the images no longer running
+	if c.Storage && c.GCGracePeriod > 0 {
+		go controller.CollectGarbage(ctx, c.GCInterval)
+	}
+
+	// lower the scan concurrency under memory pressure to prevent OOMKills
+	if memoryLimit, err := tools.MemoryLimit(); err == nil && c.MemoryHighWatermark > 0 {
+		go controller.AdaptConcurrency(ctx, 10*time.Second, c.MemoryHighWatermark, c.MemoryLowWatermark, memoryLimit, tools.MemoryUsage)
 	}
-	service := services.NewScanService(sbomAdapter, storage, cveAdapter, storage, platform, c.Storage)
-	controller := controllers.NewHTTPController(service, c.ScanConcurrency)
 
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 	router.Use(gin.Recovery())
 
-	router.GET("/v1/liveness", controller.Alive)
-	router.GET("/v1/readiness", controller.Ready)
-
-	group := router.Group(apis.VulnerabilityScanCommandVersion)
-	{
-		group.Use(otelgin.Middleware("kubevuln-svc"))
-		group.POST("/"+apis.SBOMCalculationCommandPath, controller.GenerateSBOM)
-		group.POST("/"+apis.ContainerScanCommandPath, controller.ScanCVE)
-		group.POST("/"+apis.RegistryScanCommandPath, controller.ScanRegistry)
-	}
+	controller.RegisterRoutes(router, otelgin.Middleware("kubevuln-svc"))
 
 	srv := &http.Server{
 		Addr:    ":8080",
@@ -112,3 +616,12 @@ func main() {
 
 	logger.L().Info("kubevuln exiting")
 }
+
+// kubernetesClient returns a client for the cluster kubevuln runs in
+func kubernetesClient(ctx context.Context) kubernetes.Interface {
+	k8sConfig, err := rest.InClusterConfig()
+	if err != nil {
+		logger.L().Ctx(ctx).Fatal("kubernetes config error", helpers.Error(err))
+	}
+	return kubernetes.NewForConfigOrDie(k8sConfig)
+}
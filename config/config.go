@@ -1,22 +1,229 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/mitchellh/mapstructure"
+	"github.com/robfig/cron/v3"
 	"github.com/spf13/viper"
 )
 
+// redacted replaces sensitive values in Redacted
+const redacted = "<redacted>"
+
+// envAliases binds the environment variables historically used by the deployments to their configuration keys
+var envAliases = map[string][]string{
+	"accountID":            {"ACCOUNT_ID", "CA_CUSTOMER_GUID"},
+	"clusterName":          {"CLUSTER_NAME", "CA_CLUSTER_NAME"},
+	"eventReceiverRestURL": {"EVENT_RECEIVER_REST_URL", "CA_EVENT_RECEIVER_HTTP"},
+	"backendOpenAPI":       {"BACKEND_OPEN_API", "CA_BACKEND_OPENAPI"},
+	"nodeName":             {"NODE_NAME"},
+	"otelCollectorSvc":     {"OTEL_COLLECTOR_SVC"},
+	"release":              {"RELEASE"},
+}
+
+// sensitiveKeys are the configuration keys never exposed by Redacted
+var sensitiveKeys = []string{"accountID", "requestHeaders"}
+
+// RegistryAuth holds the Harbor robot account or the Artifactory access token of the repositories of a registry,
+// secrets are read from the given files
+type RegistryAuth struct {
+	PasswordFile     string   `mapstructure:"passwordFile"`
+	Provider         string   `mapstructure:"provider"`
+	RefreshTokenFile string   `mapstructure:"refreshTokenFile"`
+	Registry         string   `mapstructure:"registry"`
+	Repositories     []string `mapstructure:"repositories"`
+	TokenFile        string   `mapstructure:"tokenFile"`
+	URL              string   `mapstructure:"url"`
+	Username         string   `mapstructure:"username"`
+}
+
+// ResultRoute forwards the findings of the completed scans matching all its filters to the sink of the given name,
+// besides the backend; empty filters match any scan
+type ResultRoute struct {
+	Name       string   `mapstructure:"name"`
+	Namespaces []string `mapstructure:"namespaces"`
+	Severities []string `mapstructure:"severities"`
+	Sink       string   `mapstructure:"sink"`
+	Verdicts   []string `mapstructure:"verdicts"`
+}
+
+// ResultSink is a destination of the routed findings, authenticated with the token of the given file if any; Index and
+// BatchSize are the index of the findings and the number of findings per request of the Splunk and Elasticsearch sinks
+type ResultSink struct {
+	BatchSize int    `mapstructure:"batchSize"`
+	Index     string `mapstructure:"index"`
+	Name      string `mapstructure:"name"`
+	TokenFile string `mapstructure:"tokenFile"`
+	Type      string `mapstructure:"type"`
+	URL       string `mapstructure:"url"`
+}
+
+// RiskWeights overrides the default weights of the risk score of the images, the omitted ones keep their default
+type RiskWeights struct {
+	Exploit    *float64           `mapstructure:"exploit"`
+	Exposed    *float64           `mapstructure:"exposed"`
+	Fixable    *float64           `mapstructure:"fixable"`
+	Irrelevant *float64           `mapstructure:"irrelevant"`
+	Severities map[string]float64 `mapstructure:"severities"`
+}
+
+// SeverityThresholds is the pass/fail and alert policy of the namespaces matching one of its glob patterns and having
+// all the labels of its selector, Fail and Alert are the maximum counts of vulnerabilities keyed by severity
+type SeverityThresholds struct {
+	Alert             map[string]int    `mapstructure:"alert"`
+	Fail              map[string]int    `mapstructure:"fail"`
+	Name              string            `mapstructure:"name"`
+	NamespaceSelector map[string]string `mapstructure:"namespaceSelector"`
+	Namespaces        []string          `mapstructure:"namespaces"`
+}
+
+// ScanWindow allows the background scans for the duration from each start of the cron expression
+type ScanWindow struct {
+	Duration time.Duration `mapstructure:"duration"`
+	Start    string        `mapstructure:"start"`
+}
+
+// Suppression ignores the vulnerabilities matching all its glob patterns, such as the ones of vendored test fixtures
+type Suppression struct {
+	Location      string `mapstructure:"location"`
+	Package       string `mapstructure:"package"`
+	Vulnerability string `mapstructure:"vulnerability"`
+}
+
+// Config holds all kubevuln settings, read from clusterData.json and overridden by environment variables
 type Config struct {
-	AccountID            string        `mapstructure:"accountID"`
-	BackendOpenAPI       string        `mapstructure:"backendOpenAPI"`
-	ClusterName          string        `mapstructure:"clusterName"`
-	EventReceiverRestURL string        `mapstructure:"eventReceiverRestURL"`
-	KeepLocal            bool          `mapstructure:"keepLocal"`
-	ListingURL           string        `mapstructure:"listingURL"`
-	MaxImageSize         int64         `mapstructure:"maxImageSize"`
-	ScanConcurrency      int           `mapstructure:"scanConcurrency"`
-	ScanTimeout          time.Duration `mapstructure:"scanTimeout"`
-	Storage              bool          `mapstructure:"storage"`
+	AccountID                   string                   `mapstructure:"accountID"`
+	AllowedRegistries           []string                 `mapstructure:"allowedRegistries"`
+	AnnotationGating            bool                     `mapstructure:"annotationGating"`
+	AnonymizationSaltFile       string                   `mapstructure:"anonymizationSaltFile"`
+	BackendOpenAPI              string                   `mapstructure:"backendOpenAPI"`
+	BundleTokensDir             string                   `mapstructure:"bundleTokensDir"`
+	Bundles                     bool                     `mapstructure:"bundles"`
+	CallbackContentType         string                   `mapstructure:"callbackContentType"`
+	CallbackTemplateFile        string                   `mapstructure:"callbackTemplateFile"`
+	CatalogerModes              map[string]string        `mapstructure:"catalogerModes"`
+	CatalogerRetries            int                      `mapstructure:"catalogerRetries"`
+	ClusterName                 string                   `mapstructure:"clusterName"`
+	ContextAttributes           map[string]string        `mapstructure:"contextAttributes"`
+	CoverageTracking            bool                     `mapstructure:"coverageTracking"`
+	CoverageWindow              time.Duration            `mapstructure:"coverageWindow"`
+	CRIRuntime                  string                   `mapstructure:"criRuntime"`
+	CRISocket                   string                   `mapstructure:"criSocket"`
+	DBCACertFile                string                   `mapstructure:"dbCACertFile"`
+	DBChecksumPolicy            string                   `mapstructure:"dbChecksumPolicy"`
+	DBMaxAge                    time.Duration            `mapstructure:"dbMaxAge"`
+	DBURLRewrites               map[string]string        `mapstructure:"dbURLRewrites"`
+	Ecosystems                  []string                 `mapstructure:"ecosystems"`
+	EmbeddedImagesDepth         int                      `mapstructure:"embeddedImagesDepth"`
+	EnrichmentRefreshInterval   time.Duration            `mapstructure:"enrichmentRefreshInterval"`
+	EnrichmentSource            string                   `mapstructure:"enrichmentSource"`
+	EventReceiverCompression    string                   `mapstructure:"eventReceiverCompression"`
+	EventReceiverFailoverURLs   []string                 `mapstructure:"eventReceiverFailoverURLs"`
+	EventReceiverRestURL        string                   `mapstructure:"eventReceiverRestURL"`
+	ExcludeSBOMFiles            bool                     `mapstructure:"excludeSBOMFiles"`
+	ExploitBundle               string                   `mapstructure:"exploitBundle"`
+	ExploitDBURL                string                   `mapstructure:"exploitDBURL"`
+	ExploitMapping              bool                     `mapstructure:"exploitMapping"`
+	ExploitRefreshInterval      time.Duration            `mapstructure:"exploitRefreshInterval"`
+	ExtractionSandbox           string                   `mapstructure:"extractionSandbox"`
+	FalsePositiveConfigMap      string                   `mapstructure:"falsePositiveConfigMap"`
+	FilterTimeout               time.Duration            `mapstructure:"filterTimeout"`
+	GCGracePeriod               time.Duration            `mapstructure:"gcGracePeriod"`
+	GoldenBasesFile             string                   `mapstructure:"goldenBasesFile"`
+	GCInterval                  time.Duration            `mapstructure:"gcInterval"`
+	HistoryRetention            map[string]time.Duration `mapstructure:"historyRetention"`
+	IgnoreUnfixed               bool                     `mapstructure:"ignoreUnfixed"`
+	InventorySync               bool                     `mapstructure:"inventorySync"`
+	KeepLocal                   bool                     `mapstructure:"keepLocal"`
+	LayerDiffScans              bool                     `mapstructure:"layerDiffScans"`
+	LegacySBOMMaxSize           int64                    `mapstructure:"legacySBOMMaxSize"`
+	ListingURL                  string                   `mapstructure:"listingURL"`
+	MatchExplanations           bool                     `mapstructure:"matchExplanations"`
+	MatchTimeout                time.Duration            `mapstructure:"matchTimeout"`
+	MaxImageAge                 time.Duration            `mapstructure:"maxImageAge"`
+	MaxImageSize                int64                    `mapstructure:"maxImageSize"`
+	MemoryHighWatermark         float64                  `mapstructure:"memoryHighWatermark"`
+	MemoryLowWatermark          float64                  `mapstructure:"memoryLowWatermark"`
+	MetasploitURL               string                   `mapstructure:"metasploitURL"`
+	NamespaceFairness           bool                     `mapstructure:"namespaceFairness"`
+	NamespaceLabelAttributes    map[string]string        `mapstructure:"namespaceLabelAttributes"`
+	NamespaceWeights            map[string]int           `mapstructure:"namespaceWeights"`
+	NodeName                    string                   `mapstructure:"nodeName"`
+	OSVURL                      string                   `mapstructure:"osvURL"`
+	OtelCollectorSvc            string                   `mapstructure:"otelCollectorSvc"`
+	PackageOverridesFile        string                   `mapstructure:"packageOverridesFile"`
+	Profiling                   bool                     `mapstructure:"profiling"`
+	ProgressEvents              bool                     `mapstructure:"progressEvents"`
+	PseudonymConfigMap          string                   `mapstructure:"pseudonymConfigMap"`
+	PullRetries                 int                      `mapstructure:"pullRetries"`
+	PullRetryBackoff            time.Duration            `mapstructure:"pullRetryBackoff"`
+	PullTimeout                 time.Duration            `mapstructure:"pullTimeout"`
+	RegistryAuth                []RegistryAuth           `mapstructure:"registryAuth"`
+	RegistryMirrors             map[string][]string      `mapstructure:"registryMirrors"`
+	RegistryWebhook             bool                     `mapstructure:"registryWebhook"`
+	RegistryWebhookRepositories []string                 `mapstructure:"registryWebhookRepositories"`
+	RegistryWebhookSecretFile   string                   `mapstructure:"registryWebhookSecretFile"`
+	RelayTokensDir              string                   `mapstructure:"relayTokensDir"`
+	RelevancyFile               string                   `mapstructure:"relevancyFile"`
+	RelevancyProvider           string                   `mapstructure:"relevancyProvider"`
+	Release                     string                   `mapstructure:"release"`
+	ReportVersion               string                   `mapstructure:"reportVersion"`
+	RepositoryScans             bool                     `mapstructure:"repositoryScans"`
+	RequestHeaders              map[string]string        `mapstructure:"requestHeaders"`
+	ResultRoutes                []ResultRoute            `mapstructure:"resultRoutes"`
+	ResultSinks                 []ResultSink             `mapstructure:"resultSinks"`
+	RiskExposure                bool                     `mapstructure:"riskExposure"`
+	RiskWeights                 RiskWeights              `mapstructure:"riskWeights"`
+	SBOMAttestationPush         bool                     `mapstructure:"sbomAttestationPush"`
+	SBOMMigrationInterval       time.Duration            `mapstructure:"sbomMigrationInterval"`
+	SBOMSigningKeyFile          string                   `mapstructure:"sbomSigningKeyFile"`
+	ScanConcurrency             int                      `mapstructure:"scanConcurrency"`
+	ScanEventLog                int                      `mapstructure:"scanEventLog"`
+	ScanFailuresConfigMap       string                   `mapstructure:"scanFailuresConfigMap"`
+	ScanProfile                 string                   `mapstructure:"scanProfile"`
+	ScanQueueConfigMap          string                   `mapstructure:"scanQueueConfigMap"`
+	ScanSchedule                string                   `mapstructure:"scanSchedule"`
+	ScanScheduleConcurrency     int                      `mapstructure:"scanScheduleConcurrency"`
+	ScanScheduleJitter          time.Duration            `mapstructure:"scanScheduleJitter"`
+	ScanScheduleNamespaces      map[string]string        `mapstructure:"scanScheduleNamespaces"`
+	ScanTimeout                 time.Duration            `mapstructure:"scanTimeout"`
+	ScanWindows                 []ScanWindow             `mapstructure:"scanWindows"`
+	ScratchDir                  string                   `mapstructure:"scratchDir"`
+	SelfTestImage               string                   `mapstructure:"selfTestImage"`
+	SendTombstones              bool                     `mapstructure:"sendTombstones"`
+	SeverityThresholds          []SeverityThresholds     `mapstructure:"severityThresholds"`
+	Storage                     bool                     `mapstructure:"storage"`
+	StorageCompression          string                   `mapstructure:"storageCompression"`
+	StorageEncoding             string                   `mapstructure:"storageEncoding"`
+	SubmitBreakerCoolDown       time.Duration            `mapstructure:"submitBreakerCoolDown"`
+	SubmitBreakerThreshold      int                      `mapstructure:"submitBreakerThreshold"`
+	SubmitOrdering              string                   `mapstructure:"submitOrdering"`
+	SubmitSpoolDir              string                   `mapstructure:"submitSpoolDir"`
+	SubmitSpoolMaxReports       int                      `mapstructure:"submitSpoolMaxReports"`
+	SubmitSpoolMaxSize          int64                    `mapstructure:"submitSpoolMaxSize"`
+	SubmitTimeout               time.Duration            `mapstructure:"submitTimeout"`
+	Suppressions                []Suppression            `mapstructure:"suppressions"`
+	TriageConfigMap             string                   `mapstructure:"triageConfigMap"`
+	TrustedDigestsFile          string                   `mapstructure:"trustedDigestsFile"`
+	TrustedDigestsKeyFile       string                   `mapstructure:"trustedDigestsKeyFile"`
+	TrustedDigestsSignatureFile string                   `mapstructure:"trustedDigestsSignatureFile"`
+	UniqueImageReportInterval   time.Duration            `mapstructure:"uniqueImageReportInterval"`
+	UserAgent                   string                   `mapstructure:"userAgent"`
+	WatchWorkloads              bool                     `mapstructure:"watchWorkloads"`
+	WorkloadAnnotations         bool                     `mapstructure:"workloadAnnotations"`
+	WorkloadAnnotationsQPS      float64                  `mapstructure:"workloadAnnotationsQPS"`
+	WorkDir                     string                   `mapstructure:"workDir"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
@@ -25,12 +232,33 @@ func LoadConfig(path string) (Config, error) {
 	viper.SetConfigName("clusterData")
 	viper.SetConfigType("json")
 
+	viper.SetDefault("exploitDBURL", "https://gitlab.com/exploit-database/exploitdb/-/raw/main/files_exploits.csv")
+	viper.SetDefault("gcInterval", time.Hour)
 	viper.SetDefault("listingURL", "https://toolbox-data.anchore.io/grype/databases/listing.json")
 	viper.SetDefault("maxImageSize", 512*1024*1024)
+	viper.SetDefault("memoryHighWatermark", 0.8)
+	viper.SetDefault("memoryLowWatermark", 0.6)
+	viper.SetDefault("metasploitURL", "https://raw.githubusercontent.com/rapid7/metasploit-framework/master/db/modules_metadata_base.json")
+	viper.SetDefault("osvURL", "https://api.osv.dev")
+	viper.SetDefault("pseudonymConfigMap", "kubevuln-pseudonyms")
+	viper.SetDefault("pullRetries", 2)
+	viper.SetDefault("pullRetryBackoff", 2*time.Second)
+	viper.SetDefault("sbomMigrationInterval", time.Second)
 	viper.SetDefault("scanConcurrency", 1)
+	viper.SetDefault("scanScheduleConcurrency", 1)
 	viper.SetDefault("scanTimeout", 5*time.Minute)
+	viper.SetDefault("submitBreakerCoolDown", time.Minute)
+	viper.SetDefault("submitBreakerThreshold", 5)
+	viper.SetDefault("submitSpoolMaxReports", 1000)
+	viper.SetDefault("submitSpoolMaxSize", 1024*1024*1024)
+	viper.SetDefault("workloadAnnotationsQPS", 5)
 
 	viper.AutomaticEnv()
+	for key, envs := range envAliases {
+		if err := viper.BindEnv(append([]string{key, key}, envs...)...); err != nil {
+			return Config{}, err
+		}
+	}
 
 	err := viper.ReadInConfig()
 	if err != nil {
@@ -39,5 +267,455 @@ func LoadConfig(path string) (Config, error) {
 
 	var config Config
 	err = viper.Unmarshal(&config)
-	return config, err
+	if err != nil {
+		return Config{}, err
+	}
+	return config, config.Validate()
+}
+
+// Validate checks the consistency of the configuration, all problems are reported at once
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("invalid %q: %s", key, fmt.Sprintf(format, args...)))
+	}
+	if c.ScanConcurrency < 1 {
+		invalid("scanConcurrency", "must be at least 1, got %d", c.ScanConcurrency)
+	}
+	if c.MaxImageSize <= 0 {
+		invalid("maxImageSize", "must be a positive number of bytes, got %d", c.MaxImageSize)
+	}
+	if c.ScanTimeout <= 0 {
+		invalid("scanTimeout", "must be a positive duration such as \"5m\", got %s", c.ScanTimeout)
+	}
+	if c.PullRetries < 0 {
+		invalid("pullRetries", "must not be negative, use 0 to disable the retries, got %d", c.PullRetries)
+	}
+	if len(c.NamespaceWeights) > 0 && !c.NamespaceFairness {
+		invalid("namespaceWeights", "needs namespaceFairness")
+	}
+	for namespace, weight := range c.NamespaceWeights {
+		if weight < 1 {
+			invalid("namespaceWeights", "weight of %q must be positive, got %d", namespace, weight)
+		}
+	}
+	for key, value := range c.RequestHeaders {
+		if key == "" || strings.ContainsAny(key, " \t\r\n:") {
+			invalid("requestHeaders", "%q is not a header name", key)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			invalid("requestHeaders", "value of %q must be a single line", key)
+		}
+	}
+	if strings.ContainsAny(c.UserAgent, "\r\n") {
+		invalid("userAgent", "must be a single line")
+	}
+	if c.DBChecksumPolicy != "" && c.DBChecksumPolicy != "download" && c.DBChecksumPolicy != "always" {
+		invalid("dbChecksumPolicy", "must be \"download\", \"always\" or empty for the default, got %q", c.DBChecksumPolicy)
+	}
+	for prefix, replacement := range c.DBURLRewrites {
+		for _, value := range []string{prefix, replacement} {
+			if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+				invalid("dbURLRewrites", "must map absolute URL prefixes such as \"https://example.com/grype/\", got %q", value)
+			}
+		}
+	}
+	if c.WorkloadAnnotations && c.WorkloadAnnotationsQPS <= 0 {
+		invalid("workloadAnnotationsQPS", "must be a positive number of patches per second, got %v", c.WorkloadAnnotationsQPS)
+	}
+	if c.LegacySBOMMaxSize < 0 {
+		invalid("legacySBOMMaxSize", "must not be negative, use 0 to leave the SBOM out of the v1 reports, got %d", c.LegacySBOMMaxSize)
+	}
+	if c.CatalogerRetries < 0 {
+		invalid("catalogerRetries", "must not be negative, use 0 to never retry the failed catalogers, got %d", c.CatalogerRetries)
+	}
+	if c.ScanEventLog < 0 {
+		invalid("scanEventLog", "must not be negative, use 0 to disable the scan event log, got %d", c.ScanEventLog)
+	}
+	if c.EmbeddedImagesDepth < 0 {
+		invalid("embeddedImagesDepth", "must not be negative, use 0 to only list the embedded images, got %d", c.EmbeddedImagesDepth)
+	}
+	for key, value := range map[string]time.Duration{
+		"coverageWindow":            c.CoverageWindow,
+		"dbMaxAge":                  c.DBMaxAge,
+		"enrichmentRefreshInterval": c.EnrichmentRefreshInterval,
+		"exploitRefreshInterval":    c.ExploitRefreshInterval,
+		"filterTimeout":             c.FilterTimeout,
+		"gcGracePeriod":             c.GCGracePeriod,
+		"matchTimeout":              c.MatchTimeout,
+		"maxImageAge":               c.MaxImageAge,
+		"pullRetryBackoff":          c.PullRetryBackoff,
+		"pullTimeout":               c.PullTimeout,
+		"sbomMigrationInterval":     c.SBOMMigrationInterval,
+		"scanScheduleJitter":        c.ScanScheduleJitter,
+		"submitTimeout":             c.SubmitTimeout,
+	} {
+		if value < 0 {
+			invalid(key, "must not be negative, use 0 to disable, got %s", value)
+		}
+	}
+	if c.SubmitBreakerThreshold < 0 {
+		invalid("submitBreakerThreshold", "must not be negative, use 0 to disable the circuit breaker, got %d", c.SubmitBreakerThreshold)
+	}
+	if c.SubmitBreakerThreshold > 0 && c.SubmitBreakerCoolDown <= 0 {
+		invalid("submitBreakerCoolDown", "must be a positive duration such as \"1m\" when submitBreakerThreshold is set, got %s", c.SubmitBreakerCoolDown)
+	}
+	if c.SubmitSpoolDir != "" && c.SubmitBreakerThreshold <= 0 {
+		invalid("submitSpoolDir", "is only used with the circuit breaker, set submitBreakerThreshold")
+	}
+	if c.SubmitSpoolDir != "" && !filepath.IsAbs(c.SubmitSpoolDir) {
+		invalid("submitSpoolDir", "must be an absolute path, got %q", c.SubmitSpoolDir)
+	}
+	if c.SubmitSpoolDir != "" && c.SubmitSpoolMaxReports < 1 {
+		invalid("submitSpoolMaxReports", "must be at least 1 when submitSpoolDir is set, got %d", c.SubmitSpoolMaxReports)
+	}
+	if c.SubmitSpoolDir != "" && c.SubmitSpoolMaxSize <= 0 {
+		invalid("submitSpoolMaxSize", "must be a positive number of bytes when submitSpoolDir is set, got %d", c.SubmitSpoolMaxSize)
+	}
+	if c.GCGracePeriod > 0 && c.GCInterval <= 0 {
+		invalid("gcInterval", "must be a positive duration when gcGracePeriod is set, got %s", c.GCInterval)
+	}
+	if c.MemoryHighWatermark < 0 || c.MemoryHighWatermark > 1 {
+		invalid("memoryHighWatermark", "must be a ratio between 0 and 1, use 0 to disable, got %v", c.MemoryHighWatermark)
+	}
+	if c.MemoryLowWatermark < 0 || c.MemoryLowWatermark > c.MemoryHighWatermark {
+		invalid("memoryLowWatermark", "must be a ratio between 0 and memoryHighWatermark (%v), got %v", c.MemoryHighWatermark, c.MemoryLowWatermark)
+	}
+	if c.ReportVersion != "" && c.ReportVersion != "v1" && c.ReportVersion != "v2" {
+		invalid("reportVersion", "must be \"v1\", \"v2\" or empty to negotiate it with the event receiver, got %q", c.ReportVersion)
+	}
+	if c.SubmitOrdering != "" && c.SubmitOrdering != "concurrent" && c.SubmitOrdering != "sequential" && c.SubmitOrdering != "lastChunkBarrier" {
+		invalid("submitOrdering", "must be \"concurrent\", \"sequential\", \"lastChunkBarrier\" or empty for the default, got %q", c.SubmitOrdering)
+	}
+	switch c.RelevancyProvider {
+	case "", "applicationProfile", "none":
+	case "file":
+		if c.RelevancyFile == "" {
+			invalid("relevancyFile", "is required when relevancyProvider is \"file\"")
+		}
+	default:
+		invalid("relevancyProvider", "must be \"applicationProfile\", \"file\", \"none\" or empty for the default, got %q", c.RelevancyProvider)
+	}
+	if c.StorageCompression != "" && c.StorageCompression != "gzip" && c.StorageCompression != "zstd" {
+		invalid("storageCompression", "must be \"gzip\", \"zstd\" or empty to store the contents uncompressed, got %q", c.StorageCompression)
+	}
+	if c.StorageEncoding != "" && c.StorageEncoding != "json" && c.StorageEncoding != "cbor" {
+		invalid("storageEncoding", "must be \"json\", \"cbor\" or empty for the default, got %q", c.StorageEncoding)
+	}
+	if c.ScanProfile != "" && c.ScanProfile != "full" && c.ScanProfile != "fast" {
+		invalid("scanProfile", "must be \"full\", \"fast\" or empty for the default, got %q", c.ScanProfile)
+	}
+	schedules := map[string]string{"": c.ScanSchedule}
+	for namespace, spec := range c.ScanScheduleNamespaces {
+		schedules[namespace] = spec
+	}
+	scheduled := false
+	for namespace, spec := range schedules {
+		if spec == "" || spec == "disabled" {
+			continue
+		}
+		scheduled = true
+		if _, err := cron.ParseStandard(spec); err != nil {
+			key := "scanSchedule"
+			if namespace != "" {
+				key = "scanScheduleNamespaces"
+			}
+			invalid(key, "must be a cron expression such as \"0 3 * * *\" or \"@daily\", or \"disabled\", got %q: %v", spec, err)
+		}
+	}
+	if scheduled && c.ScanScheduleConcurrency < 1 {
+		invalid("scanScheduleConcurrency", "must be at least 1, got %d", c.ScanScheduleConcurrency)
+	}
+	for _, window := range c.ScanWindows {
+		if _, err := cron.ParseStandard(window.Start); err != nil {
+			invalid("scanWindows", "start must be a cron expression such as \"0 1 * * *\", got %q: %v", window.Start, err)
+		}
+		if window.Duration <= 0 {
+			invalid("scanWindows", "duration of %q must be a positive duration such as \"4h\", got %s", window.Start, window.Duration)
+		}
+	}
+	for severity, retention := range c.HistoryRetention {
+		if !isSeverity(severity) {
+			invalid("historyRetention", "severities must be \"critical\", \"high\", \"medium\", \"low\", \"negligible\" or \"unknown\", got %q", severity)
+		}
+		if retention <= 0 {
+			invalid("historyRetention", "retention of %s must be a positive duration such as \"720h\", got %s", severity, retention)
+		}
+	}
+	switch c.CRIRuntime {
+	case "", "auto", "containerd", "crio", "docker":
+	default:
+		invalid("criRuntime", "must be \"auto\", \"containerd\", \"crio\" or \"docker\", got %q", c.CRIRuntime)
+	}
+	for ecosystem, mode := range c.CatalogerModes {
+		if ecosystem != "php" && ecosystem != "ruby" {
+			invalid("catalogerModes", "ecosystem must be \"php\" or \"ruby\", got %q", ecosystem)
+		}
+		if mode != "installed" && mode != "lockfile" && mode != "disabled" {
+			invalid("catalogerModes", "mode of %q must be \"installed\", \"lockfile\" or \"disabled\", got %q", ecosystem, mode)
+		}
+	}
+	for _, ecosystem := range c.Ecosystems {
+		if ecosystem != "dart" && ecosystem != "rust" && ecosystem != "swift" {
+			invalid("ecosystems", "entries must be \"dart\", \"rust\" or \"swift\", got %q", ecosystem)
+		}
+	}
+	if c.OSVURL != "" {
+		if u, err := url.Parse(c.OSVURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("osvURL", "must be an http(s) URL such as \"https://api.osv.dev\", or empty to disable the OSV matching, got %q", c.OSVURL)
+		}
+	}
+	for _, registry := range c.AllowedRegistries {
+		if strings.TrimSpace(registry) == "" || strings.Contains(registry, "://") {
+			invalid("allowedRegistries", "entries must be a registry host optionally followed by a repository path, got %q", registry)
+		}
+	}
+	for _, auth := range c.RegistryAuth {
+		if strings.TrimSpace(auth.Registry) == "" || strings.Contains(auth.Registry, "://") || strings.Contains(auth.Registry, "/") {
+			invalid("registryAuth", "registry must be a registry host, got %q", auth.Registry)
+		}
+		switch auth.Provider {
+		case "harbor":
+			if auth.Username == "" || auth.PasswordFile == "" {
+				invalid("registryAuth", "harbor robot account of %q needs a username and a passwordFile", auth.Registry)
+			}
+		case "artifactory":
+			if auth.TokenFile == "" {
+				invalid("registryAuth", "artifactory access token of %q needs a tokenFile", auth.Registry)
+			}
+		default:
+			invalid("registryAuth", "provider of %q must be \"harbor\" or \"artifactory\", got %q", auth.Registry, auth.Provider)
+		}
+		for _, file := range []string{auth.PasswordFile, auth.TokenFile, auth.RefreshTokenFile} {
+			if file != "" && !filepath.IsAbs(file) {
+				invalid("registryAuth", "secret files of %q must be absolute paths, got %q", auth.Registry, file)
+			}
+		}
+		if auth.URL != "" {
+			if u, err := url.Parse(auth.URL); err != nil || u.Scheme == "" || u.Host == "" {
+				invalid("registryAuth", "url of %q must be an absolute URL such as \"https://example.jfrog.io/artifactory\", got %q", auth.Registry, auth.URL)
+			}
+		}
+	}
+	for registry, mirrors := range c.RegistryMirrors {
+		if strings.TrimSpace(registry) == "" || strings.Contains(registry, "://") || strings.Contains(registry, "/") {
+			invalid("registryMirrors", "registry must be a registry host, got %q", registry)
+		}
+		for _, mirror := range mirrors {
+			if strings.TrimSpace(mirror) == "" || strings.Contains(mirror, "://") {
+				invalid("registryMirrors", "mirrors of %q must be a registry host optionally followed by a path prefix, got %q", registry, mirror)
+			}
+		}
+	}
+	if c.RegistryWebhook && c.RegistryWebhookSecretFile == "" {
+		invalid("registryWebhookSecretFile", "is required when registryWebhook is enabled")
+	}
+	if c.AnonymizationSaltFile != "" && c.PseudonymConfigMap == "" {
+		invalid("pseudonymConfigMap", "is required when anonymizationSaltFile is set")
+	}
+	if c.SBOMAttestationPush && c.SBOMSigningKeyFile == "" {
+		invalid("sbomSigningKeyFile", "is required when sbomAttestationPush is enabled")
+	}
+	if c.TrustedDigestsFile != "" && c.TrustedDigestsKeyFile == "" {
+		invalid("trustedDigestsKeyFile", "is required when trustedDigestsFile is set")
+	}
+	if c.TrustedDigestsFile == "" && (c.TrustedDigestsKeyFile != "" || c.TrustedDigestsSignatureFile != "") {
+		invalid("trustedDigestsFile", "is required when trustedDigestsKeyFile or trustedDigestsSignatureFile is set")
+	}
+	for _, repository := range c.RegistryWebhookRepositories {
+		if strings.TrimSpace(repository) == "" || strings.Contains(repository, "://") {
+			invalid("registryWebhookRepositories", "entries must be a registry host optionally followed by a repository path, got %q", repository)
+		}
+	}
+	if c.CallbackContentType != "" {
+		if _, _, err := mime.ParseMediaType(c.CallbackContentType); err != nil {
+			invalid("callbackContentType", "must be a media type such as \"application/json\", got %q", c.CallbackContentType)
+		}
+	}
+	for _, suppression := range c.Suppressions {
+		if suppression.Vulnerability == "" && suppression.Package == "" && suppression.Location == "" {
+			invalid("suppressions", "entries need at least a vulnerability, package or location pattern")
+		}
+		for _, pattern := range []string{suppression.Vulnerability, suppression.Package, suppression.Location} {
+			if !doublestar.ValidatePattern(pattern) {
+				invalid("suppressions", "entries must be glob patterns such as \"CVE-*\" or \"/usr/lib/test/**\", got %q", pattern)
+			}
+		}
+	}
+	for key, weight := range map[string]*float64{"exploit": c.RiskWeights.Exploit, "exposed": c.RiskWeights.Exposed, "fixable": c.RiskWeights.Fixable, "irrelevant": c.RiskWeights.Irrelevant} {
+		if weight != nil && *weight < 0 {
+			invalid("riskWeights", "%s must not be negative, got %v", key, *weight)
+		}
+	}
+	for severity, points := range c.RiskWeights.Severities {
+		if !isSeverity(severity) {
+			invalid("riskWeights", "severities must be \"critical\", \"high\", \"medium\", \"low\", \"negligible\" or \"unknown\", got %q", severity)
+		}
+		if points < 0 {
+			invalid("riskWeights", "points of %s must not be negative, got %v", severity, points)
+		}
+	}
+	for _, thresholds := range c.SeverityThresholds {
+		if thresholds.Name == "" {
+			invalid("severityThresholds", "entries need a name")
+		}
+		if len(thresholds.Fail) == 0 && len(thresholds.Alert) == 0 {
+			invalid("severityThresholds", "%q needs fail or alert thresholds", thresholds.Name)
+		}
+		for _, counts := range []map[string]int{thresholds.Fail, thresholds.Alert} {
+			for severity, maximum := range counts {
+				if !isSeverity(severity) {
+					invalid("severityThresholds", "severities of %q must be \"critical\", \"high\", \"medium\", \"low\", \"negligible\" or \"unknown\", got %q", thresholds.Name, severity)
+				}
+				if maximum < 0 {
+					invalid("severityThresholds", "maximum count of %s of %q must not be negative, got %d", severity, thresholds.Name, maximum)
+				}
+			}
+		}
+		for _, pattern := range thresholds.Namespaces {
+			if !doublestar.ValidatePattern(pattern) {
+				invalid("severityThresholds", "namespaces of %q must be glob patterns such as \"prod-*\", got %q", thresholds.Name, pattern)
+			}
+		}
+	}
+	sinks := map[string]bool{}
+	for _, sink := range c.ResultSinks {
+		if sink.Name == "" {
+			invalid("resultSinks", "entries need a name")
+		} else if sinks[sink.Name] {
+			invalid("resultSinks", "names must be unique, got %q twice", sink.Name)
+		}
+		sinks[sink.Name] = true
+		switch sink.Type {
+		case "webhook":
+			if sink.Index != "" || sink.BatchSize != 0 {
+				invalid("resultSinks", "index and batchSize of %q are only used by the splunk and elasticsearch sinks", sink.Name)
+			}
+		case "splunk", "elasticsearch":
+			if sink.BatchSize < 0 {
+				invalid("resultSinks", "batchSize of %q must not be negative, use 0 for the default, got %d", sink.Name, sink.BatchSize)
+			}
+		default:
+			invalid("resultSinks", "type of %q must be \"webhook\", \"splunk\" or \"elasticsearch\", got %q", sink.Name, sink.Type)
+		}
+		if u, err := url.Parse(sink.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			invalid("resultSinks", "url of %q must be an absolute URL such as \"https://siem.example.com/events\", got %q", sink.Name, sink.URL)
+		}
+		if sink.TokenFile != "" && !filepath.IsAbs(sink.TokenFile) {
+			invalid("resultSinks", "tokenFile of %q must be an absolute path, got %q", sink.Name, sink.TokenFile)
+		}
+	}
+	for _, route := range c.ResultRoutes {
+		if route.Name == "" {
+			invalid("resultRoutes", "entries need a name")
+		}
+		if !sinks[route.Sink] {
+			invalid("resultRoutes", "sink of %q must name one of the resultSinks, got %q", route.Name, route.Sink)
+		}
+		for _, severity := range route.Severities {
+			if !isSeverity(severity) {
+				invalid("resultRoutes", "severities of %q must be \"critical\", \"high\", \"medium\", \"low\", \"negligible\" or \"unknown\", got %q", route.Name, severity)
+			}
+		}
+		for _, pattern := range route.Namespaces {
+			if !doublestar.ValidatePattern(pattern) {
+				invalid("resultRoutes", "namespaces of %q must be glob patterns such as \"prod-*\", got %q", route.Name, pattern)
+			}
+		}
+		for _, verdict := range route.Verdicts {
+			if verdict != "success" && verdict != "fail" && verdict != "error" {
+				invalid("resultRoutes", "verdicts of %q must be \"success\", \"fail\" or \"error\", got %q", route.Name, verdict)
+			}
+		}
+	}
+	if c.EnrichmentSource != "" && !filepath.IsAbs(c.EnrichmentSource) {
+		if u, err := url.Parse(c.EnrichmentSource); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("enrichmentSource", "must be an absolute path or an http(s) URL, got %q", c.EnrichmentSource)
+		}
+	}
+	if c.SelfTestImage != "" {
+		if _, err := name.ParseReference(c.SelfTestImage); err != nil {
+			invalid("selfTestImage", "must be an image reference such as \"quay.io/kubescape/canary:v1\", got %q", c.SelfTestImage)
+		}
+	}
+	for key, value := range map[string]string{"anonymizationSaltFile": c.AnonymizationSaltFile, "bundleTokensDir": c.BundleTokensDir, "callbackTemplateFile": c.CallbackTemplateFile, "dbCACertFile": c.DBCACertFile, "exploitBundle": c.ExploitBundle, "extractionSandbox": c.ExtractionSandbox, "goldenBasesFile": c.GoldenBasesFile, "packageOverridesFile": c.PackageOverridesFile, "registryWebhookSecretFile": c.RegistryWebhookSecretFile, "relayTokensDir": c.RelayTokensDir, "relevancyFile": c.RelevancyFile, "sbomSigningKeyFile": c.SBOMSigningKeyFile, "scratchDir": c.ScratchDir, "trustedDigestsFile": c.TrustedDigestsFile, "trustedDigestsKeyFile": c.TrustedDigestsKeyFile, "trustedDigestsSignatureFile": c.TrustedDigestsSignatureFile, "workDir": c.WorkDir} {
+		if value != "" && !filepath.IsAbs(value) {
+			invalid(key, "must be an absolute path, got %q", value)
+		}
+	}
+	urls := map[string]string{"listingURL": c.ListingURL}
+	if c.ExploitMapping && c.ExploitBundle == "" {
+		if c.ExploitDBURL == "" && c.MetasploitURL == "" {
+			invalid("exploitBundle", "is required when exploitMapping is enabled without exploitDBURL nor metasploitURL")
+		}
+		urls["exploitDBURL"] = c.ExploitDBURL
+		urls["metasploitURL"] = c.MetasploitURL
+	}
+	if !c.KeepLocal {
+		urls["backendOpenAPI"] = c.BackendOpenAPI
+		urls["eventReceiverRestURL"] = c.EventReceiverRestURL
+		if c.EventReceiverCompression != "" && c.EventReceiverCompression != "auto" && c.EventReceiverCompression != "none" {
+			invalid("eventReceiverCompression", "must be \"auto\", \"none\" or empty for the default, got %q", c.EventReceiverCompression)
+		}
+		if c.UniqueImageReportInterval < 0 {
+			invalid("uniqueImageReportInterval", "must not be negative, got %s", c.UniqueImageReportInterval)
+		}
+		for _, failover := range c.EventReceiverFailoverURLs {
+			if u, err := url.Parse(failover); err != nil || u.Scheme == "" || u.Host == "" {
+				invalid("eventReceiverFailoverURLs", "must hold absolute URLs such as \"https://example.com\", got %q", failover)
+			}
+		}
+	}
+	for key, value := range urls {
+		if value == "" {
+			continue
+		}
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			invalid(key, "must be an absolute URL such as \"https://example.com\", got %q", value)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Warnings reports the settings which do not prevent kubevuln from starting but leave a feature without effect, such
+// as the missing platform settings which leave the scan results unreported
+func (c Config) Warnings() []string {
+	var warnings []string
+	if !c.KeepLocal {
+		for key, value := range map[string]string{"accountID": c.AccountID, "eventReceiverRestURL": c.EventReceiverRestURL} {
+			if value == "" {
+				warnings = append(warnings, fmt.Sprintf("%q is not set, the scan results cannot be reported to the platform, set it or enable keepLocal", key))
+			}
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// isSeverity tells whether a severity threshold key names a vulnerability severity, in any case
+func isSeverity(severity string) bool {
+	for _, known := range []string{"critical", "high", "medium", "low", "negligible", "unknown"} {
+		if strings.EqualFold(severity, known) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns the configuration keyed like the configuration file, with sensitive values hidden
+// and durations in human-readable form, suitable for introspection
+func (c Config) Redacted() map[string]interface{} {
+	values := map[string]interface{}{}
+	_ = mapstructure.Decode(c, &values)
+	for key, value := range values {
+		if duration, ok := value.(time.Duration); ok {
+			values[key] = duration.String()
+		}
+	}
+	for _, key := range sensitiveKeys {
+		if value := values[key]; value != nil && !reflect.ValueOf(value).IsZero() {
+			values[key] = redacted
+		}
+	}
+	return values
 }
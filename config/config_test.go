@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -18,3 +19,790 @@ func TestLoadConfigNotFound(t *testing.T) {
 	_, err := LoadConfig("testdataInvalid")
 	assert.Error(t, err)
 }
+
+func TestLoadConfigEnvAliases(t *testing.T) {
+	viper.Reset()
+	t.Setenv("CA_CUSTOMER_GUID", "67890")
+	t.Setenv("OTEL_COLLECTOR_SVC", "otel-collector:4317")
+	t.Setenv("NODE_NAME", "node-1")
+	c, err := LoadConfig("testdata")
+	assert.NoError(t, err)
+	assert.Equal(t, "67890", c.AccountID)
+	assert.Equal(t, "otel-collector:4317", c.OtelCollectorSvc)
+	assert.Equal(t, "node-1", c.NodeName)
+	assert.Equal(t, "clusterName", c.ClusterName)
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	viper.Reset()
+	t.Setenv("SCANCONCURRENCY", "0")
+	_, err := LoadConfig("testdata")
+	assert.ErrorContains(t, err, `invalid "scanConcurrency"`)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := Config{
+		AccountID:            "12345",
+		BackendOpenAPI:       "https://api.armosec.io/api",
+		EventReceiverRestURL: "https://report.armo.cloud",
+		ListingURL:           "https://toolbox-data.anchore.io/grype/databases/listing.json",
+		MaxImageSize:         512 * 1024 * 1024,
+		MemoryHighWatermark:  0.8,
+		MemoryLowWatermark:   0.6,
+		ScanConcurrency:      1,
+		ScanTimeout:          5 * time.Minute,
+	}
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		wantErr []string
+	}{
+		{
+			name:   "valid",
+			mutate: func(*Config) {},
+		},
+		{
+			name: "keep local without platform settings",
+			mutate: func(c *Config) {
+				c.KeepLocal = true
+				c.AccountID = ""
+				c.EventReceiverRestURL = ""
+			},
+		},
+		{
+			name: "missing platform settings",
+			mutate: func(c *Config) {
+				c.AccountID = ""
+				c.EventReceiverRestURL = ""
+			},
+		},
+		{
+			name: "event receiver failover",
+			mutate: func(c *Config) {
+				c.EventReceiverFailoverURLs = []string{"https://report-eu.armo.cloud"}
+			},
+		},
+		{
+			name: "invalid event receiver failover",
+			mutate: func(c *Config) {
+				c.EventReceiverFailoverURLs = []string{"https://report-eu.armo.cloud", "report-us.armo.cloud"}
+			},
+			wantErr: []string{`invalid "eventReceiverFailoverURLs"`, `"report-us.armo.cloud"`},
+		},
+		{
+			name: "event receiver compression disabled",
+			mutate: func(c *Config) {
+				c.EventReceiverCompression = "none"
+			},
+		},
+		{
+			name: "invalid event receiver compression",
+			mutate: func(c *Config) {
+				c.EventReceiverCompression = "br"
+			},
+			wantErr: []string{`invalid "eventReceiverCompression"`, `"br"`},
+		},
+		{
+			name: "lockfile only cataloging",
+			mutate: func(c *Config) {
+				c.CatalogerModes = map[string]string{"php": "lockfile", "ruby": "disabled"}
+			},
+		},
+		{
+			name: "invalid cataloger modes",
+			mutate: func(c *Config) {
+				c.CatalogerModes = map[string]string{"php": "vendored", "python": "lockfile"}
+			},
+			wantErr: []string{`mode of "php"`, `ecosystem must be "php" or "ruby", got "python"`},
+		},
+		{
+			name: "optional ecosystems",
+			mutate: func(c *Config) {
+				c.Ecosystems = []string{"dart", "rust", "swift"}
+				c.OSVURL = "https://api.osv.dev"
+			},
+		},
+		{
+			name: "invalid optional ecosystems",
+			mutate: func(c *Config) {
+				c.Ecosystems = []string{"rust", "kotlin"}
+				c.OSVURL = "api.osv.dev"
+			},
+			wantErr: []string{`entries must be "dart", "rust" or "swift", got "kotlin"`, `invalid "osvURL"`},
+		},
+		{
+			name: "garbage collection without interval",
+			mutate: func(c *Config) {
+				c.GCGracePeriod = 24 * time.Hour
+			},
+			wantErr: []string{`invalid "gcInterval"`},
+		},
+		{
+			name: "allowed registries",
+			mutate: func(c *Config) {
+				c.AllowedRegistries = []string{"docker.io", "ghcr.io/kubescape", "registry.local:5000"}
+			},
+		},
+		{
+			name: "cataloger retries",
+			mutate: func(c *Config) {
+				c.CatalogerRetries = 2
+			},
+		},
+		{
+			name: "invalid cataloger retries",
+			mutate: func(c *Config) {
+				c.CatalogerRetries = -1
+			},
+			wantErr: []string{`invalid "catalogerRetries"`},
+		},
+		{
+			name: "scan event log",
+			mutate: func(c *Config) {
+				c.ScanEventLog = 100
+			},
+		},
+		{
+			name: "invalid scan event log",
+			mutate: func(c *Config) {
+				c.ScanEventLog = -1
+			},
+			wantErr: []string{`invalid "scanEventLog"`},
+		},
+		{
+			name: "embedded images depth",
+			mutate: func(c *Config) {
+				c.EmbeddedImagesDepth = 2
+			},
+		},
+		{
+			name: "invalid embedded images depth",
+			mutate: func(c *Config) {
+				c.EmbeddedImagesDepth = -1
+			},
+			wantErr: []string{`invalid "embeddedImagesDepth"`},
+		},
+		{
+			name: "request headers",
+			mutate: func(c *Config) {
+				c.RequestHeaders = map[string]string{"X-Waf-Token": "s3cr3t"}
+				c.UserAgent = "kubevuln/v0.3.0 (cluster production)"
+			},
+		},
+		{
+			name: "invalid request headers",
+			mutate: func(c *Config) {
+				c.RequestHeaders = map[string]string{"X Waf Token": "s3cr3t\r\nHost: evil"}
+				c.UserAgent = "kubevuln\n"
+			},
+			wantErr: []string{`"X Waf Token" is not a header name`, `value of "X Waf Token" must be a single line`, `invalid "userAgent"`},
+		},
+		{
+			name: "vulnerability DB hosting",
+			mutate: func(c *Config) {
+				c.DBCACertFile = "/etc/ssl/artifactory/ca.crt"
+				c.DBChecksumPolicy = "always"
+				c.DBMaxAge = 120 * time.Hour
+				c.DBURLRewrites = map[string]string{"https://toolbox-data.anchore.io/grype/databases/": "https://artifactory.example.com/grype/"}
+				c.ListingURL = "https://artifactory.example.com/grype/listing.json"
+			},
+		},
+		{
+			name: "invalid vulnerability DB hosting",
+			mutate: func(c *Config) {
+				c.DBCACertFile = "ca.crt"
+				c.DBChecksumPolicy = "never"
+				c.DBMaxAge = -time.Hour
+				c.DBURLRewrites = map[string]string{"toolbox-data.anchore.io": "https://artifactory.example.com/grype/"}
+			},
+			wantErr: []string{`invalid "dbCACertFile"`, `invalid "dbChecksumPolicy"`, `invalid "dbMaxAge"`, `invalid "dbURLRewrites"`},
+		},
+		{
+			name: "workload annotations",
+			mutate: func(c *Config) {
+				c.WorkloadAnnotations = true
+				c.WorkloadAnnotationsQPS = 0.5
+			},
+		},
+		{
+			name: "invalid workload annotations rate",
+			mutate: func(c *Config) {
+				c.WorkloadAnnotations = true
+				c.WorkloadAnnotationsQPS = 0
+			},
+			wantErr: []string{`invalid "workloadAnnotationsQPS"`},
+		},
+		{
+			name: "namespace fairness",
+			mutate: func(c *Config) {
+				c.NamespaceFairness = true
+				c.NamespaceWeights = map[string]int{"ci": 1, "production": 4}
+			},
+		},
+		{
+			name: "invalid namespace weights",
+			mutate: func(c *Config) {
+				c.NamespaceWeights = map[string]int{"ci": 0}
+			},
+			wantErr: []string{`invalid "namespaceWeights": needs namespaceFairness`, `weight of "ci" must be positive, got 0`},
+		},
+		{
+			name: "invalid legacy SBOM max size",
+			mutate: func(c *Config) {
+				c.LegacySBOMMaxSize = -1
+			},
+			wantErr: []string{`invalid "legacySBOMMaxSize"`},
+		},
+		{
+			name: "invalid allowed registries",
+			mutate: func(c *Config) {
+				c.AllowedRegistries = []string{"https://ghcr.io", " "}
+			},
+			wantErr: []string{`got "https://ghcr.io"`, `got " "`},
+		},
+		{
+			name: "relevancy file",
+			mutate: func(c *Config) {
+				c.RelevancyProvider = "file"
+				c.RelevancyFile = "/etc/kubevuln/relevancy.json"
+			},
+		},
+		{
+			name: "invalid relevancy provider",
+			mutate: func(c *Config) {
+				c.RelevancyProvider = "ebpf"
+			},
+			wantErr: []string{`invalid "relevancyProvider"`},
+		},
+		{
+			name: "relevancy file missing",
+			mutate: func(c *Config) {
+				c.RelevancyProvider = "file"
+			},
+			wantErr: []string{`invalid "relevancyFile"`},
+		},
+		{
+			name: "registry auth",
+			mutate: func(c *Config) {
+				c.RegistryAuth = []RegistryAuth{
+					{Provider: "harbor", Registry: "harbor.example.com", Repositories: []string{"team-a"}, Username: "robot$team-a+kubevuln", PasswordFile: "/etc/kubevuln/harbor/secret"},
+					{Provider: "artifactory", Registry: "example.jfrog.io", TokenFile: "/etc/kubevuln/jfrog/token", RefreshTokenFile: "/etc/kubevuln/jfrog/refresh"},
+				}
+			},
+		},
+		{
+			name: "invalid registry auth",
+			mutate: func(c *Config) {
+				c.RegistryAuth = []RegistryAuth{
+					{Provider: "quay", Registry: "quay.io"},
+					{Provider: "harbor", Registry: "https://harbor.example.com", Username: "robot$kubevuln"},
+					{Provider: "artifactory", Registry: "example.jfrog.io", TokenFile: "token", URL: "example.jfrog.io"},
+				}
+			},
+			wantErr: []string{`provider of "quay.io"`, `got "https://harbor.example.com"`, `needs a username and a passwordFile`, `got "token"`, `url of "example.jfrog.io"`},
+		},
+		{
+			name: "registry mirrors",
+			mutate: func(c *Config) {
+				c.PullRetries = 3
+				c.PullRetryBackoff = time.Second
+				c.RegistryMirrors = map[string][]string{"docker.io": {"mirror.gcr.io", "harbor.example.com/dockerhub-proxy"}}
+			},
+		},
+		{
+			name: "invalid registry mirrors",
+			mutate: func(c *Config) {
+				c.PullRetries = -1
+				c.PullRetryBackoff = -time.Second
+				c.RegistryMirrors = map[string][]string{"https://docker.io": {"mirror.gcr.io"}, "quay.io": {"https://quay-mirror.example.com"}}
+			},
+			wantErr: []string{`invalid "pullRetries"`, `invalid "pullRetryBackoff"`, `got "https://docker.io"`, `mirrors of "quay.io"`},
+		},
+		{
+			name: "submission circuit breaker",
+			mutate: func(c *Config) {
+				c.SubmitBreakerCoolDown = time.Minute
+				c.SubmitBreakerThreshold = 5
+				c.SubmitSpoolDir = "/var/lib/kubevuln/spool"
+				c.SubmitSpoolMaxReports = 1000
+				c.SubmitSpoolMaxSize = 1 << 30
+			},
+		},
+		{
+			name: "invalid submission circuit breaker",
+			mutate: func(c *Config) {
+				c.SubmitBreakerThreshold = -1
+				c.SubmitSpoolDir = "spool"
+				c.SubmitSpoolMaxReports = 0
+				c.SubmitSpoolMaxSize = -1
+			},
+			wantErr: []string{`invalid "submitBreakerThreshold"`, `invalid "submitSpoolDir"`, `invalid "submitSpoolMaxReports"`, `invalid "submitSpoolMaxSize"`},
+		},
+		{
+			name: "circuit breaker without cool-down",
+			mutate: func(c *Config) {
+				c.SubmitBreakerThreshold = 5
+			},
+			wantErr: []string{`invalid "submitBreakerCoolDown"`},
+		},
+		{
+			name: "registry webhook",
+			mutate: func(c *Config) {
+				c.RegistryWebhook = true
+				c.RegistryWebhookRepositories = []string{"harbor.example.com/team-a", "quay.io/kubescape"}
+				c.RegistryWebhookSecretFile = "/etc/kubevuln/webhook/secret"
+			},
+		},
+		{
+			name: "invalid registry webhook",
+			mutate: func(c *Config) {
+				c.RegistryWebhook = true
+				c.RegistryWebhookRepositories = []string{"https://harbor.example.com"}
+			},
+			wantErr: []string{`invalid "registryWebhookSecretFile"`, `invalid "registryWebhookRepositories"`},
+		},
+		{
+			name: "relay mode",
+			mutate: func(c *Config) {
+				c.RelayTokensDir = "/etc/kubevuln/relay"
+			},
+		},
+		{
+			name: "invalid relay mode",
+			mutate: func(c *Config) {
+				c.RelayTokensDir = "relay"
+			},
+			wantErr: []string{`invalid "relayTokensDir"`},
+		},
+		{
+			name: "bundles",
+			mutate: func(c *Config) {
+				c.Bundles = true
+				c.BundleTokensDir = "/etc/kubevuln/bundle-tokens"
+			},
+		},
+		{
+			name: "invalid bundle tokens",
+			mutate: func(c *Config) {
+				c.Bundles = true
+				c.BundleTokensDir = "bundle-tokens"
+			},
+			wantErr: []string{`invalid "bundleTokensDir"`},
+		},
+		{
+			name: "privacy mode",
+			mutate: func(c *Config) {
+				c.AnonymizationSaltFile = "/etc/kubevuln/anonymization/salt"
+				c.PseudonymConfigMap = "kubevuln-pseudonyms"
+			},
+		},
+		{
+			name: "invalid privacy mode",
+			mutate: func(c *Config) {
+				c.AnonymizationSaltFile = "salt"
+			},
+			wantErr: []string{`invalid "anonymizationSaltFile"`, `invalid "pseudonymConfigMap"`},
+		},
+		{
+			name: "callback template",
+			mutate: func(c *Config) {
+				c.CallbackContentType = "application/json; charset=utf-8"
+				c.CallbackTemplateFile = "/etc/kubevuln/templates/slack.tmpl"
+			},
+		},
+		{
+			name: "invalid callback template",
+			mutate: func(c *Config) {
+				c.CallbackContentType = "text plain"
+				c.CallbackTemplateFile = "slack.tmpl"
+			},
+			wantErr: []string{`invalid "callbackContentType"`, `invalid "callbackTemplateFile"`},
+		},
+		{
+			name: "enrichment URL",
+			mutate: func(c *Config) {
+				c.EnrichmentRefreshInterval = 15 * time.Minute
+				c.EnrichmentSource = "https://risk.example.com/v1/cves.csv"
+			},
+		},
+		{
+			name: "enrichment file",
+			mutate: func(c *Config) {
+				c.EnrichmentSource = "/etc/kubevuln/enrichment/risk.json"
+			},
+		},
+		{
+			name: "invalid enrichment",
+			mutate: func(c *Config) {
+				c.EnrichmentRefreshInterval = -time.Minute
+				c.EnrichmentSource = "risk.json"
+			},
+			wantErr: []string{`invalid "enrichmentRefreshInterval"`, `invalid "enrichmentSource"`},
+		},
+		{
+			name: "exploit mapping",
+			mutate: func(c *Config) {
+				c.ExploitDBURL = "https://gitlab.com/exploit-database/exploitdb/-/raw/main/files_exploits.csv"
+				c.ExploitMapping = true
+				c.ExploitRefreshInterval = 12 * time.Hour
+			},
+		},
+		{
+			name: "offline exploit bundle",
+			mutate: func(c *Config) {
+				c.ExploitBundle = "/etc/kubevuln/exploits"
+				c.ExploitMapping = true
+			},
+		},
+		{
+			name: "invalid exploit mapping",
+			mutate: func(c *Config) {
+				c.ExploitMapping = true
+				c.ExploitRefreshInterval = -time.Hour
+			},
+			wantErr: []string{`invalid "exploitBundle"`, `invalid "exploitRefreshInterval"`},
+		},
+		{
+			name: "invalid exploit sources",
+			mutate: func(c *Config) {
+				c.ExploitDBURL = "files_exploits.csv"
+				c.ExploitMapping = true
+			},
+			wantErr: []string{`invalid "exploitDBURL"`},
+		},
+		{
+			name: "golden bases file",
+			mutate: func(c *Config) {
+				c.GoldenBasesFile = "/etc/kubevuln/golden-bases.json"
+			},
+		},
+		{
+			name: "relative golden bases file",
+			mutate: func(c *Config) {
+				c.GoldenBasesFile = "golden-bases.json"
+			},
+			wantErr: []string{`invalid "goldenBasesFile"`},
+		},
+		{
+			name: "relative package overrides file",
+			mutate: func(c *Config) {
+				c.PackageOverridesFile = "overrides.json"
+			},
+			wantErr: []string{`invalid "packageOverridesFile"`},
+		},
+		{
+			name: "relative exploit bundle",
+			mutate: func(c *Config) {
+				c.ExploitBundle = "exploits"
+				c.ExploitMapping = true
+			},
+			wantErr: []string{`invalid "exploitBundle"`},
+		},
+		{
+			name: "SBOM signing",
+			mutate: func(c *Config) {
+				c.SBOMAttestationPush = true
+				c.SBOMSigningKeyFile = "/etc/kubevuln/signing/key.pem"
+			},
+		},
+		{
+			name: "invalid SBOM signing",
+			mutate: func(c *Config) {
+				c.SBOMAttestationPush = true
+			},
+			wantErr: []string{`invalid "sbomSigningKeyFile"`},
+		},
+		{
+			name: "relative SBOM signing key file",
+			mutate: func(c *Config) {
+				c.SBOMSigningKeyFile = "key.pem"
+			},
+			wantErr: []string{`invalid "sbomSigningKeyFile"`},
+		},
+		{
+			name: "trusted digests",
+			mutate: func(c *Config) {
+				c.TrustedDigestsFile = "/etc/kubevuln/trusted/digests.json"
+				c.TrustedDigestsKeyFile = "/etc/kubevuln/trusted/pub.pem"
+			},
+		},
+		{
+			name: "trusted digests without key",
+			mutate: func(c *Config) {
+				c.TrustedDigestsFile = "/etc/kubevuln/trusted/digests.json"
+			},
+			wantErr: []string{`invalid "trustedDigestsKeyFile"`},
+		},
+		{
+			name: "trusted digests signature without file",
+			mutate: func(c *Config) {
+				c.TrustedDigestsSignatureFile = "/etc/kubevuln/trusted/digests.json.sig"
+			},
+			wantErr: []string{`invalid "trustedDigestsFile"`},
+		},
+		{
+			name: "relative trusted digests file",
+			mutate: func(c *Config) {
+				c.TrustedDigestsFile = "digests.json"
+				c.TrustedDigestsKeyFile = "/etc/kubevuln/trusted/pub.pem"
+			},
+			wantErr: []string{`invalid "trustedDigestsFile"`},
+		},
+		{
+			name: "suppressions",
+			mutate: func(c *Config) {
+				c.Suppressions = []Suppression{
+					{Vulnerability: "CVE-*", Package: "golang.org/x/text", Location: "/usr/lib/test/**"},
+					{Vulnerability: "GHSA-vvpx-j8f3-3w6h"},
+				}
+			},
+		},
+		{
+			name: "invalid suppressions",
+			mutate: func(c *Config) {
+				c.Suppressions = []Suppression{{}, {Location: "/usr/lib/[test/**"}}
+			},
+			wantErr: []string{`need at least a vulnerability, package or location pattern`, `got "/usr/lib/[test/**"`},
+		},
+		{
+			name: "risk weights",
+			mutate: func(c *Config) {
+				exploit := 3.0
+				c.RiskExposure = true
+				c.RiskWeights = RiskWeights{Exploit: &exploit, Severities: map[string]float64{"critical": 20, "Negligible": 0}}
+			},
+		},
+		{
+			name: "invalid risk weights",
+			mutate: func(c *Config) {
+				irrelevant := -0.5
+				c.RiskWeights = RiskWeights{Irrelevant: &irrelevant, Severities: map[string]float64{"severe": 20, "high": -1}}
+			},
+			wantErr: []string{`irrelevant must not be negative`, `got "severe"`, `points of high must not be negative`},
+		},
+		{
+			name: "severity thresholds",
+			mutate: func(c *Config) {
+				c.SeverityThresholds = []SeverityThresholds{
+					{Name: "production", NamespaceSelector: map[string]string{"env": "prod"}, Fail: map[string]int{"critical": 0, "High": 5}},
+					{Name: "development", Namespaces: []string{"dev-*"}, Alert: map[string]int{"critical": 10}},
+				}
+			},
+		},
+		{
+			name: "invalid severity thresholds",
+			mutate: func(c *Config) {
+				c.SeverityThresholds = []SeverityThresholds{
+					{Fail: map[string]int{"critical": 0}},
+					{Name: "empty"},
+					{Name: "production", Namespaces: []string{"prod-[*"}, Fail: map[string]int{"severe": 0, "high": -1}},
+				}
+			},
+			wantErr: []string{`entries need a name`, `"empty" needs fail or alert thresholds`, `got "severe"`, `maximum count of high of "production" must not be negative`, `got "prod-[*"`},
+		},
+		{
+			name: "result routes",
+			mutate: func(c *Config) {
+				c.ResultSinks = []ResultSink{
+					{Name: "siem", Type: "webhook", URL: "https://siem.example.com/events", TokenFile: "/etc/kubevuln/siem-token"},
+					{Name: "splunk", Type: "splunk", URL: "https://splunk.example.com:8088/services/collector/event", Index: "vulnerabilities", BatchSize: 100},
+					{Name: "elasticsearch", Type: "elasticsearch", URL: "https://elasticsearch.example.com:9200", Index: "kubevuln-{date}"},
+				}
+				c.ResultRoutes = []ResultRoute{{Name: "critical", Sink: "siem", Severities: []string{"critical", "High"}, Namespaces: []string{"prod-*"}, Verdicts: []string{"fail"}}}
+			},
+		},
+		{
+			name: "invalid result routes",
+			mutate: func(c *Config) {
+				c.ResultSinks = []ResultSink{
+					{Name: "siem", Type: "syslog", URL: "siem.example.com", TokenFile: "siem-token"},
+					{Name: "siem", Type: "webhook", URL: "https://siem.example.com/events"},
+					{Name: "hook", Type: "webhook", URL: "https://siem.example.com/events", Index: "vulnerabilities"},
+					{Name: "elasticsearch", Type: "elasticsearch", URL: "https://elasticsearch.example.com:9200", BatchSize: -1},
+				}
+				c.ResultRoutes = []ResultRoute{
+					{Sink: "siem"},
+					{Name: "critical", Sink: "splunk", Severities: []string{"severe"}, Namespaces: []string{"prod-[*"}, Verdicts: []string{"failed"}},
+				}
+			},
+			wantErr: []string{`got "siem" twice`, `got "syslog"`, `got "siem.example.com"`, `got "siem-token"`, `index and batchSize of "hook"`, `batchSize of "elasticsearch" must not be negative`, `entries need a name`, `got "splunk"`, `got "severe"`, `got "prod-[*"`, `got "failed"`},
+		},
+		{
+			name: "self-test image",
+			mutate: func(c *Config) {
+				c.SelfTestImage = "quay.io/kubescape/canary:v1"
+			},
+		},
+		{
+			name: "invalid self-test image",
+			mutate: func(c *Config) {
+				c.SelfTestImage = "quay.io/kubescape/Canary"
+			},
+			wantErr: []string{`invalid "selfTestImage"`},
+		},
+		{
+			name: "scan schedule",
+			mutate: func(c *Config) {
+				c.ScanSchedule = "0 3 * * *"
+				c.ScanScheduleConcurrency = 2
+				c.ScanScheduleJitter = 30 * time.Minute
+				c.ScanScheduleNamespaces = map[string]string{"kube-system": "disabled", "payments": "@every 6h"}
+			},
+		},
+		{
+			name: "invalid scan schedule",
+			mutate: func(c *Config) {
+				c.ScanSchedule = "daily"
+				c.ScanScheduleJitter = -time.Minute
+				c.ScanScheduleNamespaces = map[string]string{"payments": "0 3 * *"}
+			},
+			wantErr: []string{`invalid "scanSchedule"`, `invalid "scanScheduleNamespaces"`, `invalid "scanScheduleConcurrency"`, `invalid "scanScheduleJitter"`},
+		},
+		{
+			name: "scan windows",
+			mutate: func(c *Config) {
+				c.ScanWindows = []ScanWindow{{Start: "0 1 * * *", Duration: 4 * time.Hour}, {Start: "0 12 * * 6,0", Duration: 6 * time.Hour}}
+			},
+		},
+		{
+			name: "invalid scan windows",
+			mutate: func(c *Config) {
+				c.ScanWindows = []ScanWindow{{Start: "nightly", Duration: time.Hour}, {Start: "0 1 * * *"}}
+			},
+			wantErr: []string{`got "nightly"`, `duration of "0 1 * * *"`},
+		},
+		{
+			name: "unique image reports",
+			mutate: func(c *Config) {
+				c.UniqueImageReportInterval = 24 * time.Hour
+			},
+		},
+		{
+			name: "invalid unique image reports",
+			mutate: func(c *Config) {
+				c.UniqueImageReportInterval = -time.Hour
+			},
+			wantErr: []string{`invalid "uniqueImageReportInterval"`},
+		},
+		{
+			name: "container runtime autodetection",
+			mutate: func(c *Config) {
+				c.CRIRuntime = "auto"
+			},
+		},
+		{
+			name: "invalid container runtime",
+			mutate: func(c *Config) {
+				c.CRIRuntime = "podman"
+			},
+			wantErr: []string{`invalid "criRuntime"`},
+		},
+		{
+			name: "history retention",
+			mutate: func(c *Config) {
+				c.HistoryRetention = map[string]time.Duration{"Critical": 8760 * time.Hour, "low": 720 * time.Hour}
+			},
+		},
+		{
+			name: "invalid history retention",
+			mutate: func(c *Config) {
+				c.HistoryRetention = map[string]time.Duration{"severe": time.Hour, "Low": 0}
+			},
+			wantErr: []string{`got "severe"`, `retention of Low`},
+		},
+		{
+			name: "last chunk barrier",
+			mutate: func(c *Config) {
+				c.SubmitOrdering = "lastChunkBarrier"
+			},
+		},
+		{
+			name: "invalid submit ordering",
+			mutate: func(c *Config) {
+				c.SubmitOrdering = "ordered"
+			},
+			wantErr: []string{`invalid "submitOrdering"`},
+		},
+		{
+			name: "fast scan profile",
+			mutate: func(c *Config) {
+				c.ScanProfile = "fast"
+			},
+		},
+		{
+			name: "invalid scan profile",
+			mutate: func(c *Config) {
+				c.ScanProfile = "os"
+			},
+			wantErr: []string{`invalid "scanProfile"`},
+		},
+		{
+			name: "zstd storage compression",
+			mutate: func(c *Config) {
+				c.StorageCompression = "zstd"
+			},
+		},
+		{
+			name: "invalid storage compression",
+			mutate: func(c *Config) {
+				c.StorageCompression = "lz4"
+			},
+			wantErr: []string{`invalid "storageCompression"`, `"lz4"`},
+		},
+		{
+			name: "invalid storage encoding",
+			mutate: func(c *Config) {
+				c.StorageEncoding = "protobuf"
+			},
+			wantErr: []string{`invalid "storageEncoding"`, `"protobuf"`},
+		},
+		{
+			name: "invalid values are all reported",
+			mutate: func(c *Config) {
+				c.ScanConcurrency = 0
+				c.MaxImageSize = -1
+				c.PullTimeout = -time.Second
+				c.SBOMMigrationInterval = -time.Second
+				c.MemoryLowWatermark = 0.9
+				c.ListingURL = "listing.json"
+				c.ReportVersion = "v3"
+				c.ScratchDir = "scratch"
+				c.ExtractionSandbox = "kubevuln-extractor"
+			},
+			wantErr: []string{`invalid "reportVersion"`, `invalid "extractionSandbox"`, `invalid "scratchDir"`, `invalid "scanConcurrency"`, `invalid "maxImageSize"`, `invalid "pullTimeout"`, `invalid "sbomMigrationInterval"`, `invalid "memoryLowWatermark"`, `invalid "listingURL"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid
+			tt.mutate(&c)
+			err := c.Validate()
+			if len(tt.wantErr) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			for _, want := range tt.wantErr {
+				assert.ErrorContains(t, err, want)
+			}
+		})
+	}
+}
+
+func TestConfig_Warnings(t *testing.T) {
+	assert.Empty(t, Config{AccountID: "12345", EventReceiverRestURL: "https://report.armo.cloud"}.Warnings())
+	assert.Empty(t, Config{KeepLocal: true}.Warnings())
+	assert.Equal(t, []string{
+		`"accountID" is not set, the scan results cannot be reported to the platform, set it or enable keepLocal`,
+		`"eventReceiverRestURL" is not set, the scan results cannot be reported to the platform, set it or enable keepLocal`,
+	}, Config{}.Warnings())
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	c := Config{AccountID: "12345", ClusterName: "clusterName", RequestHeaders: map[string]string{"X-Waf-Token": "s3cr3t"}, ScanTimeout: 5 * time.Minute}
+	redactedConfig := c.Redacted()
+	assert.Equal(t, redacted, redactedConfig["accountID"])
+	assert.Equal(t, redacted, redactedConfig["requestHeaders"])
+	assert.Equal(t, "clusterName", redactedConfig["clusterName"])
+	assert.Equal(t, "5m0s", redactedConfig["scanTimeout"])
+	assert.Equal(t, "", Config{}.Redacted()["accountID"])
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	apiv1 "github.com/kubescape/kubevuln/api/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"schneider.vip/problem"
+)
+
+// maxBatchSize bounds the number of scan commands of a batch
+const maxBatchSize = 1000
+
+// ScanBatch unmarshalls a batch of scan commands, validates and submits each of them under a single batch ID,
+// and returns whether each command was accepted
+func (h HTTPController) ScanBatch(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var batch apiv1.BatchScanRequest
+	err := c.ShouldBindJSON(&batch)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("handler error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusBadRequest).WriteTo(c.Writer)
+		return
+	}
+	if batch.Kind == "" {
+		batch.Kind = domain.ScanKindScanCVE
+	}
+	switch {
+	case batch.Kind != domain.ScanKindScanCVE && batch.Kind != domain.ScanKindGenerateSBOM:
+		err = fmt.Errorf("kind must be %s or %s, got %q", domain.ScanKindScanCVE, domain.ScanKindGenerateSBOM, batch.Kind)
+	case len(batch.Commands) == 0:
+		err = fmt.Errorf("commands must not be empty")
+	case len(batch.Commands) > maxBatchSize:
+		err = fmt.Errorf("commands must not exceed %d items, got %d", maxBatchSize, len(batch.Commands))
+	}
+	if err != nil {
+		_, _ = problem.Of(http.StatusBadRequest).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	}
+
+	report := domain.BatchReport{
+		BatchID: uuid.NewString(),
+		Items:   make([]domain.BatchItemStatus, 0, len(batch.Commands)),
+	}
+	for i, websocketScanCommand := range batch.Commands {
+		newScan := websocketScanCommandToScanCommand(websocketScanCommand)
+		newScan.BatchID = report.BatchID
+		status := domain.BatchItemStatus{
+			Index:     i,
+			Wlid:      newScan.Wlid,
+			ImageTag:  newScan.ImageTag,
+			ImageHash: newScan.ImageHash,
+		}
+		scanCtx, err := h.validate(ctx, batch.Kind, newScan)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("validation error", helpers.Error(err),
+				helpers.String("batchID", report.BatchID),
+				helpers.String("imageSlug", newScan.ImageSlug),
+				helpers.String("imageTag", newScan.ImageTag),
+				helpers.String("imageHash", newScan.ImageHash))
+			status.Error = err.Error()
+			report.Rejected++
+		} else {
+			h.submit(scanCtx, batch.Kind, newScan)
+			status.Accepted = true
+			report.Accepted++
+		}
+		report.Items = append(report.Items, status)
+	}
+	logger.L().Info("batch of scans submitted",
+		helpers.String("batchID", report.BatchID),
+		helpers.Int("accepted", report.Accepted),
+		helpers.Int("rejected", report.Rejected))
+
+	c.JSON(http.StatusOK, report)
+}
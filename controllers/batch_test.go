@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gammazero/workerpool"
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPController_ScanBatch(t *testing.T) {
+	scanService := services.NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false,
+		services.WithAllowedRegistries([]string{"docker.io"}))
+	tests := []struct {
+		name         string
+		scanService  ports.ScanService
+		body         string
+		expectedCode int
+		expectedBody string
+		wantItems    []domain.BatchItemStatus
+	}{
+		{
+			name:         "invalid request",
+			scanService:  services.NewMockScanService(true),
+			body:         `{"commands": "nginx"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "{\"status\":400,\"title\":\"Bad Request\"}",
+		},
+		{
+			name:         "empty batch",
+			scanService:  services.NewMockScanService(true),
+			body:         `{"commands": []}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "{\"detail\":\"commands must not be empty\",\"status\":400,\"title\":\"Bad Request\"}",
+		},
+		{
+			name:         "unsupported kind",
+			scanService:  services.NewMockScanService(true),
+			body:         `{"kind": "scanRegistry", "commands": [{"imageTag": "nginx:1.14.1"}]}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "{\"detail\":\"kind must be scanCVE or generateSBOM, got \\\"scanRegistry\\\"\",\"status\":400,\"title\":\"Bad Request\"}",
+		},
+		{
+			name:        "accepted and rejected items",
+			scanService: scanService,
+			body: `{"commands": [
+				{"wlid": "wlid://cluster-minikube/namespace-default/deployment-nginx", "imageTag": "nginx:1.14.1", "imageHash": "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"},
+				{"wlid": "wlid://cluster-minikube/namespace-default/deployment-kube-proxy", "imageTag": "k8s.gcr.io/kube-proxy:v1.24.3", "imageHash": "k8s.gcr.io/kube-proxy@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137"},
+				{"wlid": "wlid://cluster-minikube/namespace-default/deployment-empty"}
+			]}`,
+			expectedCode: http.StatusOK,
+			wantItems: []domain.BatchItemStatus{
+				{
+					Index:     0,
+					Wlid:      "wlid://cluster-minikube/namespace-default/deployment-nginx",
+					ImageTag:  "nginx:1.14.1",
+					ImageHash: "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7",
+					Accepted:  true,
+				},
+				{
+					Index:     1,
+					Wlid:      "wlid://cluster-minikube/namespace-default/deployment-kube-proxy",
+					ImageTag:  "k8s.gcr.io/kube-proxy:v1.24.3",
+					ImageHash: "k8s.gcr.io/kube-proxy@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+					Error:     "image registry is not allowed by policy: k8s.gcr.io/kube-proxy",
+				},
+				{
+					Index: 2,
+					Wlid:  "wlid://cluster-minikube/namespace-default/deployment-empty",
+					Error: domain.ErrMissingImageInfo.Error(),
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{
+				scanService: tt.scanService,
+				workerPool:  workerpool.New(1),
+			}
+			router := gin.Default()
+			path := "/v1/scanBatch"
+			router.POST(path, c.ScanBatch)
+			req, _ := http.NewRequest("POST", path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			c.Shutdown()
+			assert.Equal(t, tt.expectedCode, w.Code, w.Code)
+			if tt.wantItems == nil {
+				assert.Equal(t, tt.expectedBody, w.Body.String(), w.Body.String())
+				return
+			}
+			var report domain.BatchReport
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.NotEmpty(t, report.BatchID)
+			assert.Equal(t, 1, report.Accepted)
+			assert.Equal(t, 2, report.Rejected)
+			assert.Equal(t, tt.wantItems, report.Items)
+		})
+	}
+}
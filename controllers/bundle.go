@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"schneider.vip/problem"
+)
+
+// WithBundleTokens authenticates the imports of the results bundles with the tokens read from tokensDir, holding a
+// file per client named after it
+func WithBundleTokens(tokensDir string) HTTPControllerOption {
+	return func(h *HTTPController) {
+		h.bundleTokens = &bearerTokens{tokensDir: tokensDir}
+	}
+}
+
+// ExportBundle returns a bundle of the stored SBOMs as NDJSON, to be scanned by a connected kubevuln. The SBOMs are
+// written as they are read.
+func (h HTTPController) ExportBundle(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	encoder := json.NewEncoder(c.Writer)
+	started := false
+	// the header is written once the SBOMs are listed, so that the errors before it are answered as such
+	err := h.scanService.ExportBundle(ctx, func(bundle domain.SBOMBundle) error {
+		started = true
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Header("Content-Disposition", `attachment; filename="bundle.ndjson"`)
+		c.Status(http.StatusOK)
+		return encoder.Encode(bundle)
+	}, func(sbom domain.BundledSBOM) error {
+		if err := encoder.Encode(sbom); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	switch {
+	case started:
+		if err != nil {
+			// the response is already under way, it is cut short
+			logger.L().Ctx(ctx).Warning("bundle export interrupted", helpers.Error(err))
+		}
+	case err != nil:
+		writeBundleError(c, "bundle export error", err)
+	}
+}
+
+// ScanBundle reads an NDJSON bundle of SBOMs exported by an offline kubevuln and returns the vulnerability manifests
+// matched for them, the SBOMs are scanned before answering
+func (h HTTPController) ScanBundle(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	bundle, err := domain.ReadSBOMBundle(c.Request.Body)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("handler error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusBadRequest).WriteTo(c.Writer)
+		return
+	}
+
+	results, err := h.scanService.ScanBundle(ctx, bundle)
+	if err != nil {
+		writeBundleError(c, "bundle scan error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// ImportResults unmarshalls the results of a bundle scanned by a connected kubevuln and stores them, the requests must
+// carry one of the bundle tokens
+func (h HTTPController) ImportResults(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if h.bundleTokens == nil {
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail("results import needs bundle tokens")).WriteTo(c.Writer)
+		return
+	}
+	_, err := h.bundleTokens.authorize(c.Request)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("results import rejected", helpers.Error(err))
+		_, _ = problem.Of(http.StatusUnauthorized).WriteTo(c.Writer)
+		return
+	}
+
+	var results domain.ResultsBundle
+	err = c.ShouldBindJSON(&results)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("handler error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusBadRequest).WriteTo(c.Writer)
+		return
+	}
+
+	report, err := h.scanService.ImportResults(ctx, results)
+	if err != nil {
+		writeBundleError(c, "results import error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// writeBundleError answers a failed bundle operation, bundles not enabled are not found and bundles of another format
+// are bad requests
+func writeBundleError(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, domain.ErrNoBundles), errors.Is(err, domain.ErrNoBundleStore):
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+	case errors.Is(err, domain.ErrBundleFormat):
+		_, _ = problem.Of(http.StatusBadRequest).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+	default:
+		logger.L().Ctx(c.Request.Context()).Error(msg, helpers.Error(err))
+		_, _ = problem.Of(http.StatusInternalServerError).WriteTo(c.Writer)
+	}
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bundleErrorScanService fails the bundle operations with err
+type bundleErrorScanService struct {
+	*services.MockScanService
+	err error
+}
+
+func (s bundleErrorScanService) ExportBundle(context.Context, func(domain.SBOMBundle) error, func(domain.BundledSBOM) error) error {
+	return s.err
+}
+
+func (s bundleErrorScanService) ScanBundle(context.Context, domain.SBOMBundle) (domain.ResultsBundle, error) {
+	return domain.ResultsBundle{}, s.err
+}
+
+func TestHTTPController_bundles(t *testing.T) {
+	tests := []struct {
+		name         string
+		scanService  ports.ScanService
+		method       string
+		path         string
+		token        string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "export",
+			scanService:  services.NewMockScanService(true),
+			method:       http.MethodGet,
+			path:         "/v1/bundle",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"formatVersion":2,"createdAt":"0001-01-01T00:00:00Z","sbomCreatorVersion":""}` + "\n",
+		},
+		{
+			name:         "export not enabled",
+			scanService:  bundleErrorScanService{services.NewMockScanService(true), domain.ErrNoBundles},
+			method:       http.MethodGet,
+			path:         "/v1/bundle",
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"detail":"bundles are not enabled","status":404,"title":"Not Found"}`,
+		},
+		{
+			name:         "export error",
+			scanService:  services.NewMockScanService(false),
+			method:       http.MethodGet,
+			path:         "/v1/bundle",
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"status":500,"title":"Internal Server Error"}`,
+		},
+		{
+			name:         "invalid bundle",
+			scanService:  services.NewMockScanService(true),
+			method:       http.MethodPost,
+			path:         "/v1/bundle",
+			body:         "{",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"status":400,"title":"Bad Request"}`,
+		},
+		{
+			name:         "unsupported bundle",
+			scanService:  bundleErrorScanService{services.NewMockScanService(true), fmt.Errorf("%w %d", domain.ErrBundleFormat, 1)},
+			method:       http.MethodPost,
+			path:         "/v1/bundle",
+			body:         `{"formatVersion":1,"sboms":[]}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"detail":"unsupported bundle format version 1","status":400,"title":"Bad Request"}`,
+		},
+		{
+			name:         "scan",
+			scanService:  services.NewMockScanService(true),
+			method:       http.MethodPost,
+			path:         "/v1/bundle",
+			body:         `{"formatVersion":2}` + "\n" + `{"imageSlug":"nginx-1-14-1","sbomCreatorVersion":"v0.101.1","content":{}}` + "\n",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "import",
+			scanService:  services.NewMockScanService(true),
+			method:       http.MethodPost,
+			path:         "/v1/bundle/results",
+			token:        "s3cr3t",
+			body:         `{"formatVersion":2,"results":[]}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"stored":0,"skipped":0,"failed":0}`,
+		},
+		{
+			name:         "import without token",
+			scanService:  services.NewMockScanService(true),
+			method:       http.MethodPost,
+			path:         "/v1/bundle/results",
+			body:         `{"formatVersion":2,"results":[]}`,
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"status":401,"title":"Unauthorized"}`,
+		},
+	}
+	tokensDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tokensDir, "offline"), []byte("s3cr3t\n"), 0600))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{scanService: tt.scanService, bundleTokens: &bearerTokens{tokensDir: tokensDir}}
+			router := gin.Default()
+			router.GET("/v1/bundle", c.ExportBundle)
+			router.POST("/v1/bundle", c.ScanBundle)
+			router.POST("/v1/bundle/results", c.ImportResults)
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String(), w.Body.String())
+			}
+		})
+	}
+}
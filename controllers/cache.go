@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Cache returns the statistics of the caches of the scan pipeline
+func (h HTTPController) Cache(c *gin.Context) {
+	c.JSON(http.StatusOK, h.scanService.CacheStats(c.Request.Context()))
+}
+
+// RegisterCacheMetrics exposes the statistics of the caches of the scan pipeline as metrics, by cache
+func (h HTTPController) RegisterCacheMetrics() error {
+	meter := otel.Meter("")
+	hitsCounter, err := meter.Int64ObservableCounter("kubevuln_cache_hits",
+		metric.WithDescription("Number of cached documents served instead of being computed, by cache"))
+	if err != nil {
+		return err
+	}
+	missesCounter, err := meter.Int64ObservableCounter("kubevuln_cache_misses",
+		metric.WithDescription("Number of documents computed for lack of a cached one, by cache"))
+	if err != nil {
+		return err
+	}
+	ratioGauge, err := meter.Float64ObservableGauge("kubevuln_cache_hit_ratio",
+		metric.WithDescription("Ratio of the lookups served by the cache, by cache"))
+	if err != nil {
+		return err
+	}
+	bytesCounter, err := meter.Int64ObservableCounter("kubevuln_cache_bytes_saved",
+		metric.WithDescription("Bytes of the images spared pulling by the cache hits, by cache"), metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	agesCounter, err := meter.Int64ObservableCounter("kubevuln_cache_hits_by_age",
+		metric.WithDescription("Number of cache hits by age bucket of the documents served, by cache"))
+	if err != nil {
+		return err
+	}
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		for _, stats := range h.scanService.CacheStats(ctx).Caches {
+			cache := attribute.String("cache", stats.Cache)
+			o.ObserveInt64(hitsCounter, stats.Hits, metric.WithAttributes(cache))
+			o.ObserveInt64(missesCounter, stats.Misses, metric.WithAttributes(cache))
+			o.ObserveFloat64(ratioGauge, stats.HitRate, metric.WithAttributes(cache))
+			o.ObserveInt64(bytesCounter, stats.BytesSaved, metric.WithAttributes(cache))
+			for _, bucket := range stats.Ages {
+				o.ObserveInt64(agesCounter, bucket.Hits, metric.WithAttributes(cache, attribute.String("max_age", bucket.MaxAge)))
+			}
+		}
+		return nil
+	}, hitsCounter, missesCounter, ratioGauge, bytesCounter, agesCounter)
+	return err
+}
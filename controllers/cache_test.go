@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPController_Cache(t *testing.T) {
+	c := HTTPController{scanService: services.NewMockScanService(true)}
+	assert.NoError(t, c.RegisterCacheMetrics())
+	router := gin.Default()
+	path := "/v1/cache"
+	router.GET(path, c.Cache)
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, w.Code)
+	assert.Equal(t, `{"since":"0001-01-01T00:00:00Z","caches":null}`, w.Body.String(), w.Body.String())
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"schneider.vip/problem"
+)
+
+// Coverage returns the scan coverage report of the running container images
+func (h HTTPController) Coverage(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	report, err := h.scanService.Coverage(ctx)
+	switch {
+	case errors.Is(err, domain.ErrNoWorkloadLister):
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	case errors.Is(err, domain.ErrInventoryNotSynced):
+		_, _ = problem.Of(http.StatusServiceUnavailable).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	case err != nil:
+		logger.L().Ctx(ctx).Error("coverage error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusInternalServerError).WriteTo(c.Writer)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// coverageMetricsInterval is how long the coverage report of the metrics is reused, so that each scrape does not
+// list the workloads of the cluster
+const coverageMetricsInterval = time.Minute
+
+// RegisterCoverageMetrics exposes the scan coverage report as metrics, computed at most once per interval
+func (h HTTPController) RegisterCoverageMetrics() error {
+	meter := otel.Meter("")
+	ratioGauge, err := meter.Float64ObservableGauge("kubevuln_scan_coverage_ratio",
+		metric.WithDescription("Ratio of running container images scanned within the coverage window"))
+	if err != nil {
+		return err
+	}
+	imagesGauge, err := meter.Int64ObservableGauge("kubevuln_scan_coverage_images",
+		metric.WithDescription("Number of running container images by coverage status"))
+	if err != nil {
+		return err
+	}
+	var mu sync.Mutex
+	var report domain.CoverageReport
+	var reportedAt time.Time
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Since(reportedAt) >= coverageMetricsInterval {
+			latest, err := h.scanService.Coverage(ctx)
+			if err != nil {
+				return err
+			}
+			report, reportedAt = latest, time.Now()
+		}
+		o.ObserveFloat64(ratioGauge, report.Ratio)
+		for status, count := range map[string]int{
+			domain.CoverageScanned:   report.Scanned,
+			domain.CoverageStale:     report.Stale,
+			domain.CoverageUnscanned: report.Unscanned,
+		} {
+			o.ObserveInt64(imagesGauge, int64(count), metric.WithAttributes(attribute.String("status", status)))
+		}
+		return nil
+	}, ratioGauge, imagesGauge)
+	return err
+}
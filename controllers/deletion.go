@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	wssc "github.com/armosec/armoapi-go/apis"
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"schneider.vip/problem"
+)
+
+// pendingScans tracks the scans of workloads waiting in the worker pool, so that the scans of deleted workloads are skipped,
+// and the running ones, so that they are aborted
+type pendingScans struct {
+	mu        sync.Mutex
+	cancelled map[string]bool
+	running   map[string]context.CancelFunc
+	wlids     map[string]string
+}
+
+func newPendingScans() *pendingScans {
+	return &pendingScans{
+		cancelled: map[string]bool{},
+		running:   map[string]context.CancelFunc{},
+		wlids:     map[string]string{},
+	}
+}
+
+// add tracks the scan id of a workload
+func (p *pendingScans) add(wlid, id string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.wlids[id] = wlid
+}
+
+// cancel cancels the pending scans of a workload, aborts its running ones, and returns how many were cancelled
+func (p *pendingScans) cancel(wlid string) int {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var count int
+	for id, w := range p.wlids {
+		if w != wlid || p.cancelled[id] {
+			continue
+		}
+		if abort, ok := p.running[id]; ok {
+			abort()
+			delete(p.running, id)
+		} else {
+			p.cancelled[id] = true
+		}
+		count++
+	}
+	return count
+}
+
+// take marks a scan as running, abort cancelling its context, and tells whether it was cancelled while pending;
+// cancelled scans are no longer tracked
+func (p *pendingScans) take(id string, abort context.CancelFunc) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancelled[id] {
+		delete(p.cancelled, id)
+		delete(p.wlids, id)
+		return true
+	}
+	if _, ok := p.wlids[id]; ok {
+		p.running[id] = abort
+	}
+	return false
+}
+
+// done stops tracking a processed scan
+func (p *pendingScans) done(id string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.running, id)
+	delete(p.wlids, id)
+}
+
+// DeleteWorkload unmarshalls the payload and forgets the workload it designates
+func (h HTTPController) DeleteWorkload(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var websocketScanCommand wssc.WebsocketScanCommand
+	err := c.ShouldBindJSON(&websocketScanCommand)
+	if err != nil || websocketScanCommand.Wlid == "" {
+		logger.L().Ctx(ctx).Error("handler error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusBadRequest).WriteTo(c.Writer)
+		return
+	}
+
+	details := problem.Detailf("Wlid=%s", websocketScanCommand.Wlid)
+
+	err = h.deleteWorkload(ctx, websocketScanCommand.Wlid)
+	switch {
+	case errors.Is(err, domain.ErrInvalidWlid):
+		_, _ = problem.Of(http.StatusBadRequest).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+	case err != nil:
+		_, _ = problem.Of(http.StatusInternalServerError).Append(details).WriteTo(c.Writer)
+	default:
+		_, _ = problem.Of(http.StatusOK).Append(details).WriteTo(c.Writer)
+	}
+}
+
+// deleteWorkload cancels the pending and running scans of a deleted workload, removes them from the persistent queue
+// and calls scanService.DeleteWorkload
+func (h HTTPController) deleteWorkload(ctx context.Context, wlid string) error {
+	cancelled := h.pending.cancel(wlid)
+	if h.queue != nil {
+		scans, err := h.queue.ListQueued(ctx)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to list queued scans", helpers.Error(err))
+		}
+		for _, scan := range scans {
+			if scan.Command.Wlid != wlid {
+				continue
+			}
+			if err := h.queue.Dequeue(ctx, scan.ID); err != nil {
+				logger.L().Ctx(ctx).Warning("failed to remove scan from queue", helpers.Error(err),
+					helpers.String("imageSlug", scan.Command.ImageSlug))
+			}
+		}
+	}
+	err := h.scanService.DeleteWorkload(ctx, wlid)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("service error", helpers.Error(err),
+			helpers.String("wlid", wlid))
+	}
+	logger.L().Info("cancelled scans of deleted workload",
+		helpers.String("wlid", wlid),
+		helpers.Int("cancelled", cancelled))
+	return err
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// blockingScanService blocks SBOM generations until released and counts the CVE scans
+type blockingScanService struct {
+	*services.MockScanService
+	release chan struct{}
+	scans   *int32
+}
+
+func (b blockingScanService) GenerateSBOM(context.Context) error {
+	<-b.release
+	return nil
+}
+
+func (b blockingScanService) ScanCVE(context.Context) error {
+	atomic.AddInt32(b.scans, 1)
+	return nil
+}
+
+func Test_pendingScans(t *testing.T) {
+	p := newPendingScans()
+	p.add("wlid1", "1")
+	p.add("wlid1", "2")
+	p.add("wlid2", "3")
+	assert.Equal(t, 2, p.cancel("wlid1"))
+	assert.Equal(t, 0, p.cancel("wlid1"))
+	assert.True(t, p.take("1", nil))
+	assert.True(t, p.take("2", nil))
+	// running scans are aborted
+	ctx, abort := context.WithCancel(context.TODO())
+	assert.False(t, p.take("3", abort))
+	assert.Equal(t, 1, p.cancel("wlid2"))
+	assert.Error(t, ctx.Err())
+	p.done("3")
+	assert.Empty(t, p.wlids)
+	assert.Empty(t, p.cancelled)
+	assert.Empty(t, p.running)
+	// untracked scans are never cancelled
+	var nilPending *pendingScans
+	assert.Equal(t, 0, nilPending.cancel("wlid1"))
+	assert.False(t, nilPending.take("1", nil))
+	nilPending.done("1")
+}
+
+// hangingScanService blocks CVE scans until their context is cancelled
+type hangingScanService struct {
+	*services.MockScanService
+	started chan struct{}
+	aborted chan error
+}
+
+func (h hangingScanService) ScanCVE(ctx context.Context) error {
+	close(h.started)
+	<-ctx.Done()
+	h.aborted <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestHTTPController_deleteWorkload_running(t *testing.T) {
+	wlid := "wlid://cluster-minikube/namespace-default/deployment-nginx"
+	scanService := hangingScanService{
+		MockScanService: services.NewMockScanService(true),
+		started:         make(chan struct{}),
+		aborted:         make(chan error, 1),
+	}
+	c := NewHTTPController(scanService, 1)
+	// the scan survives the cancellation of the request submitting it
+	requestCtx, cancel := context.WithCancel(context.TODO())
+	c.submit(requestCtx, domain.ScanKindScanCVE, domain.ScanCommand{Wlid: wlid, ImageTag: "nginx:1.14.1"})
+	<-scanService.started
+	cancel()
+	select {
+	case <-scanService.aborted:
+		t.Fatal("scan aborted by the request context")
+	case <-time.After(100 * time.Millisecond):
+	}
+	// the deletion of its workload aborts it
+	assert.NoError(t, c.deleteWorkload(context.TODO(), wlid))
+	select {
+	case err := <-scanService.aborted:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("scan not aborted within 5s of the workload deletion")
+	}
+	c.Shutdown()
+}
+
+func TestHTTPController_deleteWorkload(t *testing.T) {
+	ctx := context.TODO()
+	wlid := "wlid://cluster-minikube/namespace-default/deployment-nginx"
+	queue := repositories.NewConfigMapQueueStore(fake.NewSimpleClientset(), "kubescape", "kubevuln-queue")
+	scanService := blockingScanService{
+		MockScanService: services.NewMockScanService(true),
+		release:         make(chan struct{}),
+		scans:           new(int32),
+	}
+	c := NewHTTPController(scanService, 1, WithScanQueue(queue))
+	// occupy the only worker so that the following scans stay pending
+	c.submit(ctx, domain.ScanKindGenerateSBOM, domain.ScanCommand{ImageTag: "nginx:1.14.1"})
+	c.submit(ctx, domain.ScanKindScanCVE, domain.ScanCommand{Wlid: wlid, ImageTag: "nginx:1.14.1"})
+	c.submit(ctx, domain.ScanKindScanCVE, domain.ScanCommand{Wlid: "wlid://cluster-minikube/namespace-default/deployment-redis", ImageTag: "redis:7"})
+
+	assert.NoError(t, c.deleteWorkload(ctx, wlid))
+	scans, err := queue.ListQueued(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, scans, 2)
+	for _, scan := range scans {
+		assert.NotEqual(t, wlid, scan.Command.Wlid)
+	}
+
+	close(scanService.release)
+	c.Shutdown()
+	assert.Equal(t, int32(1), atomic.LoadInt32(scanService.scans))
+}
+
+func TestHTTPController_DeleteWorkload(t *testing.T) {
+	tests := []struct {
+		name         string
+		scanService  ports.ScanService
+		expectedCode int
+		expectedBody string
+		yamlFile     string
+	}{
+		{
+			name:         "invalid request",
+			scanService:  services.NewMockScanService(true),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "{\"status\":400,\"title\":\"Bad Request\"}",
+			yamlFile:     "../api/v1/testdata/scan-invalid.yaml",
+		},
+		{
+			name:         "service error",
+			scanService:  services.NewMockScanService(false),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "{\"detail\":\"Wlid=wlid://cluster-minikube/namespace-kube-system/daemonset-kube-proxy\",\"status\":500,\"title\":\"Internal Server Error\"}",
+			yamlFile:     "../api/v1/testdata/scan.yaml",
+		},
+		{
+			name:         "deleted",
+			scanService:  services.NewMockScanService(true),
+			expectedCode: http.StatusOK,
+			expectedBody: "{\"detail\":\"Wlid=wlid://cluster-minikube/namespace-kube-system/daemonset-kube-proxy\",\"status\":200,\"title\":\"OK\"}",
+			yamlFile:     "../api/v1/testdata/scan.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{scanService: tt.scanService}
+			router := gin.Default()
+			path := "/v1/deleteWorkload"
+			router.POST(path, c.DeleteWorkload)
+			file, err := os.Open(tt.yamlFile)
+			tools.EnsureSetup(t, err == nil)
+			req, _ := http.NewRequest("POST", path, file)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String(), w.Body.String())
+		})
+	}
+}
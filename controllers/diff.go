@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"schneider.vip/problem"
+)
+
+// Diff returns the vulnerability difference between two scans, selected either by scanID
+// (fromScanID, toScanID) or as the latest scans of a workload at the given RFC 3339 times (wlid, from, to)
+func (h HTTPController) Diff(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	request, err := diffRequest(c)
+	if err != nil {
+		_, _ = problem.Of(http.StatusBadRequest).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	}
+
+	diff, err := h.scanService.CompareScans(ctx, request)
+	switch {
+	case errors.Is(err, domain.ErrScanNotFound):
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	case err != nil:
+		logger.L().Ctx(ctx).Error("diff error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusInternalServerError).WriteTo(c.Writer)
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// diffRequest reads the scans to compare from the query, each of them given by scanID or by time
+func diffRequest(c *gin.Context) (domain.DiffRequest, error) {
+	request := domain.DiffRequest{
+		FromScanID: c.Query("fromScanID"),
+		ToScanID:   c.Query("toScanID"),
+		Wlid:       c.Query("wlid"),
+	}
+	for _, side := range []struct {
+		scanID string
+		param  string
+		time   *time.Time
+	}{
+		{request.FromScanID, "from", &request.From},
+		{request.ToScanID, "to", &request.To},
+	} {
+		if side.scanID != "" {
+			continue
+		}
+		value := c.Query(side.param)
+		if value == "" || request.Wlid == "" {
+			return request, fmt.Errorf("either %sScanID or wlid and %s are required", side.param, side.param)
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return request, fmt.Errorf("%s must be an RFC 3339 time: %w", side.param, err)
+		}
+		*side.time = t
+	}
+	return request, nil
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"schneider.vip/problem"
+)
+
+// ScanEvents returns the detailed events recorded for the scan with the given id, such as the layers read, the
+// catalogers finished and the report chunks posted
+func (h HTTPController) ScanEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	events, err := h.scanService.ScanEvents(ctx, c.Param("id"))
+	switch {
+	case errors.Is(err, domain.ErrNoScanEvents), errors.Is(err, domain.ErrScanEventsNotFound):
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	case err != nil:
+		logger.L().Ctx(ctx).Error("scan events error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusInternalServerError).WriteTo(c.Writer)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+)
+
+// scanEventsErrorScanService fails the scan events lookup with err
+type scanEventsErrorScanService struct {
+	*services.MockScanService
+	err error
+}
+
+func (s scanEventsErrorScanService) ScanEvents(context.Context, string) (domain.ScanEvents, error) {
+	return domain.ScanEvents{}, s.err
+}
+
+func TestHTTPController_ScanEvents(t *testing.T) {
+	tests := []struct {
+		name                string
+		scanService         ports.ScanService
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "events",
+			scanService:         services.NewMockScanService(true),
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"scanID":"scan","events":[],"dropped":0}`,
+		},
+		{
+			name:                "not enabled",
+			scanService:         scanEventsErrorScanService{services.NewMockScanService(true), domain.ErrNoScanEvents},
+			expectedCode:        http.StatusNotFound,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"detail":"scan event log is not enabled","status":404,"title":"Not Found"}`,
+		},
+		{
+			name:                "unknown scan",
+			scanService:         scanEventsErrorScanService{services.NewMockScanService(true), domain.ErrScanEventsNotFound},
+			expectedCode:        http.StatusNotFound,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"detail":"no events recorded for the scan","status":404,"title":"Not Found"}`,
+		},
+		{
+			name:                "error",
+			scanService:         services.NewMockScanService(false),
+			expectedCode:        http.StatusInternalServerError,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"status":500,"title":"Internal Server Error"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{scanService: tt.scanService}
+			router := gin.Default()
+			router.GET("/v1/scans/:id/events", c.ScanEvents)
+			req, _ := http.NewRequest(http.MethodGet, "/v1/scans/scan/events", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Code)
+			assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedBody, w.Body.String(), w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"schneider.vip/problem"
+)
+
+// ExportResults returns the findings of the latest scans as CSV, of a workload (wlid) or of the cluster, with the
+// comma-separated columns, or the default ones. The rows are written as the vulnerability manifests are loaded.
+func (h HTTPController) ExportResults(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	columns := domain.DefaultResultColumns
+	if value := c.Query("columns"); value != "" {
+		columns = strings.Split(value, ",")
+	}
+	if err := domain.ValidateColumns(columns); err != nil {
+		_, _ = problem.Of(http.StatusBadRequest).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	}
+
+	w := csv.NewWriter(c.Writer)
+	started := false
+	// the header is written with the first row, so that the errors before it are answered as such
+	start := func() error {
+		started = true
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", `attachment; filename="results.csv"`)
+		c.Status(http.StatusOK)
+		return w.Write(columns)
+	}
+	record := make([]string, len(columns))
+	err := h.scanService.ExportResults(ctx, c.Query("wlid"), func(row domain.ResultRow) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		for i, column := range columns {
+			record[i] = csvCell(row.Value(column))
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+		w.Flush()
+		return w.Error()
+	})
+	switch {
+	case started:
+		if err != nil {
+			// the response is already under way, it is cut short
+			logger.L().Ctx(ctx).Warning("results export interrupted", helpers.Error(err))
+		}
+	case errors.Is(err, domain.ErrScanNotFound):
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+	case err != nil:
+		logger.L().Ctx(ctx).Error("results export error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusInternalServerError).WriteTo(c.Writer)
+	default:
+		// no findings, only the header
+		_ = start()
+		w.Flush()
+	}
+}
+
+// csvCell escapes the values which spreadsheets would evaluate as formulas
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
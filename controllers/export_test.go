@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+)
+
+// exportScanService exports the given rows, or fails with err after them
+type exportScanService struct {
+	*services.MockScanService
+	rows []domain.ResultRow
+	err  error
+}
+
+func (s exportScanService) ExportResults(_ context.Context, _ string, write func(domain.ResultRow) error) error {
+	for _, row := range s.rows {
+		if err := write(row); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+func TestHTTPController_ExportResults(t *testing.T) {
+	formula := domain.ResultRow{Wlid: "wlid://cluster-minikube/namespace-default/deployment-nginx", Package: "=cmd|' /C calc'!A0", FixVersions: []string{"1.0", "1.1"}}
+	tests := []struct {
+		name         string
+		scanService  ports.ScanService
+		query        string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "default columns",
+			scanService:  services.NewMockScanService(true),
+			expectedCode: http.StatusOK,
+			expectedBody: "namespace,kind,name,container,image,vulnerability,severity,package,version,fixVersions\ndefault,Deployment,nginx,nginx,nginx:latest,CVE-2023-0001,High,openssl,1.1.1,\n",
+		},
+		{
+			name:         "columns",
+			scanService:  exportScanService{MockScanService: services.NewMockScanService(true), rows: []domain.ResultRow{formula}},
+			query:        "?columns=name,package,fixVersions",
+			expectedCode: http.StatusOK,
+			expectedBody: "name,package,fixVersions\nnginx,'=cmd|' /C calc'!A0,1.0 1.1\n",
+		},
+		{
+			name:         "no findings",
+			scanService:  exportScanService{MockScanService: services.NewMockScanService(true)},
+			query:        "?columns=wlid",
+			expectedCode: http.StatusOK,
+			expectedBody: "wlid\n",
+		},
+		{
+			name:         "unknown column",
+			scanService:  services.NewMockScanService(true),
+			query:        "?columns=name,cvss",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown workload",
+			scanService:  exportScanService{MockScanService: services.NewMockScanService(true), err: domain.ErrScanNotFound},
+			query:        "?wlid=wlid://cluster-minikube/namespace-default/deployment-redis",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "error",
+			scanService:  services.NewMockScanService(false),
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:         "error after the first row",
+			scanService:  exportScanService{MockScanService: services.NewMockScanService(true), rows: []domain.ResultRow{formula}, err: domain.ErrMockError},
+			query:        "?columns=name",
+			expectedCode: http.StatusOK,
+			expectedBody: "name\nnginx\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{scanService: tt.scanService}
+			router := gin.Default()
+			router.GET("/v1/export", c.ExportResults)
+			req, _ := http.NewRequest(http.MethodGet, "/v1/export"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.expectedBody != "" {
+				assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
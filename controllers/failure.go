@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"schneider.vip/problem"
+)
+
+// ScanFailures returns the last failure of the images whose last scan failed, optionally restricted to the image
+// query parameter, a digest, an image ID or a tag
+func (h HTTPController) ScanFailures(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	failures, err := h.scanService.ScanFailures(ctx, c.Query("image"))
+	if err != nil {
+		logger.L().Ctx(ctx).Error("scan failures error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusInternalServerError).WriteTo(c.Writer)
+		return
+	}
+
+	c.JSON(http.StatusOK, failures)
+}
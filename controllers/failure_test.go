@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingImageScanService returns a failure for the image it is asked for
+type failingImageScanService struct {
+	*services.MockScanService
+}
+
+func (failingImageScanService) ScanFailures(_ context.Context, image string) ([]domain.ScanFailure, error) {
+	return []domain.ScanFailure{{Image: image, Timestamp: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC), Stage: domain.StageSBOM, Reason: domain.FailureAuth, Error: "denied", Attempts: 3}}, nil
+}
+
+func TestHTTPController_ScanFailures(t *testing.T) {
+	tests := []struct {
+		name         string
+		scanService  ports.ScanService
+		path         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "no failure",
+			scanService:  services.NewMockScanService(true),
+			path:         "/v1/failures",
+			expectedCode: http.StatusOK,
+			expectedBody: `[]`,
+		},
+		{
+			name:         "failure of an image",
+			scanService:  failingImageScanService{services.NewMockScanService(true)},
+			path:         "/v1/failures?image=nginx:1.25",
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"image":"nginx:1.25","timestamp":"2023-04-01T00:00:00Z","stage":"sbom","reason":"auth","error":"denied","attempts":3}]`,
+		},
+		{
+			name:         "error",
+			scanService:  services.NewMockScanService(false),
+			path:         "/v1/failures",
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{scanService: tt.scanService}
+			router := gin.Default()
+			router.GET("/v1/failures", c.ScanFailures)
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
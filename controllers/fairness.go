@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+
+	wlidpkg "github.com/armosec/utils-k8s-go/wlid"
+	"github.com/kubescape/kubevuln/core/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// fairQueue orders the scans waiting for a worker by smooth weighted round-robin over their namespaces, so that a
+// namespace flooding the queue does not starve the others; each namespace with waiting scans gets a share of the
+// workers proportional to its weight, 1 unless configured
+type fairQueue struct {
+	mu      sync.Mutex
+	weights map[string]int
+	order   []string
+	waiting map[string][]func()
+	credit  map[string]int
+}
+
+// WithNamespaceFairness schedules the scans fairly across namespaces, weighted by weights; the scans without
+// workload, such as the registry and repository scans, share the "" namespace
+func WithNamespaceFairness(weights map[string]int) HTTPControllerOption {
+	return func(h *HTTPController) {
+		h.fairness = &fairQueue{
+			weights: weights,
+			waiting: map[string][]func(){},
+			credit:  map[string]int{},
+		}
+		meter := otel.Meter("")
+		waitingGauge, _ := meter.Int64ObservableGauge("kubevuln_scans_waiting",
+			metric.WithDescription("Number of scans waiting for a worker by namespace"))
+		if waitingGauge != nil {
+			_, _ = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+				for namespace, waiting := range h.fairness.waitingScans() {
+					o.ObserveInt64(waitingGauge, int64(waiting), metric.WithAttributes(attribute.String("namespace", namespace)))
+				}
+				return nil
+			}, waitingGauge)
+		}
+	}
+}
+
+// scanNamespace returns the namespace of the workload of a scan, empty without workload
+func scanNamespace(command domain.ScanCommand) string {
+	if command.Wlid == "" {
+		return ""
+	}
+	return wlidpkg.GetNamespaceFromWlid(command.Wlid)
+}
+
+// weight returns the weight of a namespace
+func (q *fairQueue) weight(namespace string) int {
+	if weight, ok := q.weights[namespace]; ok && weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// push adds a scan waiting for a worker
+func (q *fairQueue) push(namespace string, scan func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.waiting[namespace]) == 0 {
+		q.order = append(q.order, namespace)
+		q.credit[namespace] = 0
+	}
+	q.waiting[namespace] = append(q.waiting[namespace], scan)
+}
+
+// pop removes the next scan to run: every namespace with waiting scans earns its weight in credit, the richest one,
+// the first in arrival order on ties, runs a scan and pays the weights earned
+func (q *fairQueue) pop() func() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.order) == 0 {
+		return nil
+	}
+	total, next := 0, 0
+	for i, namespace := range q.order {
+		weight := q.weight(namespace)
+		q.credit[namespace] += weight
+		total += weight
+		if q.credit[namespace] > q.credit[q.order[next]] {
+			next = i
+		}
+	}
+	namespace := q.order[next]
+	q.credit[namespace] -= total
+	scan := q.waiting[namespace][0]
+	q.waiting[namespace] = q.waiting[namespace][1:]
+	if len(q.waiting[namespace]) == 0 {
+		delete(q.waiting, namespace)
+		delete(q.credit, namespace)
+		q.order = append(q.order[:next], q.order[next+1:]...)
+	}
+	return scan
+}
+
+// runNext runs the next scan, each worker pool task runs the scan fairly picked once the task starts
+func (q *fairQueue) runNext() {
+	if scan := q.pop(); scan != nil {
+		scan()
+	}
+}
+
+// waitingScans counts the scans waiting for a worker by namespace
+func (q *fairQueue) waitingScans() map[string]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	waiting := make(map[string]int, len(q.waiting))
+	for namespace, scans := range q.waiting {
+		waiting[namespace] = len(scans)
+	}
+	return waiting
+}
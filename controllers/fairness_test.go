@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFairQueue_pop(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]int
+		want    []string
+	}{
+		{
+			name: "round-robin",
+			want: []string{"ci", "production", "ci", "production", "ci", "production", "ci", "production", "ci", "ci", "ci", "ci"},
+		},
+		{
+			name:    "weighted",
+			weights: map[string]int{"production": 3},
+			want:    []string{"production", "ci", "production", "production", "production", "ci", "ci", "ci", "ci", "ci", "ci", "ci"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &fairQueue{weights: tt.weights, waiting: map[string][]func(){}, credit: map[string]int{}}
+			var got []string
+			// the CI namespace floods the queue before the production scans arrive
+			for i := 0; i < 8; i++ {
+				q.push("ci", func() { got = append(got, "ci") })
+			}
+			for i := 0; i < 4; i++ {
+				q.push("production", func() { got = append(got, "production") })
+			}
+			assert.Equal(t, map[string]int{"ci": 8, "production": 4}, q.waitingScans())
+			for range tt.want {
+				q.runNext()
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Nil(t, q.pop())
+			assert.Empty(t, q.waitingScans())
+		})
+	}
+}
+
+func Test_scanNamespace(t *testing.T) {
+	assert.Equal(t, "ci", scanNamespace(domain.ScanCommand{Wlid: "wlid://cluster-minikube/namespace-ci/deployment-app"}))
+	assert.Equal(t, "", scanNamespace(domain.ScanCommand{ImageTag: "nginx:1.24"}))
+}
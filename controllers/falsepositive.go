@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"schneider.vip/problem"
+)
+
+// AddFalsePositive unmarshalls the feedback marking a finding as a false positive and stores it, the matches it
+// applies to are ignored by the next reports
+func (h HTTPController) AddFalsePositive(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var falsePositive domain.FalsePositive
+	err := c.ShouldBindJSON(&falsePositive)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("handler error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusBadRequest).WriteTo(c.Writer)
+		return
+	}
+
+	falsePositive, err = h.scanService.AddFalsePositive(ctx, falsePositive)
+	if err != nil {
+		writeFalsePositiveError(c, "false positive error", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, falsePositive)
+}
+
+// DeleteFalsePositive removes the feedback with the given id, its matches are reported again by the next reports
+func (h HTTPController) DeleteFalsePositive(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := h.scanService.DeleteFalsePositive(ctx, c.Param("id"))
+	if err != nil {
+		writeFalsePositiveError(c, "false positive deletion error", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListFalsePositives returns the stored false positives, exported as a Grype ignore rules file or an OpenVEX document
+// when a format is given
+func (h HTTPController) ListFalsePositives(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	format := c.Query("format")
+	if format == "" {
+		falsePositives, err := h.scanService.ListFalsePositives(ctx)
+		if err != nil {
+			writeFalsePositiveError(c, "false positive list error", err)
+			return
+		}
+		c.JSON(http.StatusOK, falsePositives)
+		return
+	}
+
+	export, err := h.scanService.ExportFalsePositives(ctx, format)
+	if err != nil {
+		writeFalsePositiveError(c, "false positive export error", err)
+		return
+	}
+
+	contentType := "application/json"
+	if format == domain.FalsePositiveFormatGrype {
+		contentType = "application/yaml"
+	}
+	c.Data(http.StatusOK, contentType, export)
+}
+
+// writeFalsePositiveError answers a failed false positive operation, feedback not enabled or unknown is not found and
+// incomplete feedback or unknown export formats are bad requests
+func writeFalsePositiveError(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, domain.ErrNoFalsePositives), errors.Is(err, domain.ErrFalsePositiveNotFound):
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+	case errors.Is(err, domain.ErrInvalidFalsePositive), errors.Is(err, domain.ErrFalsePositiveFormat):
+		_, _ = problem.Of(http.StatusBadRequest).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+	default:
+		logger.L().Ctx(c.Request.Context()).Error(msg, helpers.Error(err))
+		_, _ = problem.Of(http.StatusInternalServerError).WriteTo(c.Writer)
+	}
+}
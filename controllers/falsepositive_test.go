@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+)
+
+// falsePositiveErrorScanService fails the false positive operations with err
+type falsePositiveErrorScanService struct {
+	*services.MockScanService
+	err error
+}
+
+func (s falsePositiveErrorScanService) AddFalsePositive(context.Context, domain.FalsePositive) (domain.FalsePositive, error) {
+	return domain.FalsePositive{}, s.err
+}
+
+func (s falsePositiveErrorScanService) DeleteFalsePositive(context.Context, string) error {
+	return s.err
+}
+
+func (s falsePositiveErrorScanService) ExportFalsePositives(context.Context, string) ([]byte, error) {
+	return nil, s.err
+}
+
+func (s falsePositiveErrorScanService) ListFalsePositives(context.Context) ([]domain.FalsePositive, error) {
+	return nil, s.err
+}
+
+func TestHTTPController_falsePositives(t *testing.T) {
+	tests := []struct {
+		name                string
+		scanService         ports.ScanService
+		method              string
+		path                string
+		body                string
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "add",
+			scanService:         services.NewMockScanService(true),
+			method:              http.MethodPost,
+			path:                "/v1/falsePositives",
+			body:                `{"vulnerability":"CVE-2021-44228","package":"log4j-core"}`,
+			expectedCode:        http.StatusCreated,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"id":"id","vulnerability":"CVE-2021-44228","package":"log4j-core","createdAt":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name:                "add invalid body",
+			scanService:         services.NewMockScanService(true),
+			method:              http.MethodPost,
+			path:                "/v1/falsePositives",
+			body:                "{",
+			expectedCode:        http.StatusBadRequest,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"status":400,"title":"Bad Request"}`,
+		},
+		{
+			name:                "add incomplete",
+			scanService:         falsePositiveErrorScanService{services.NewMockScanService(true), domain.ErrInvalidFalsePositive},
+			method:              http.MethodPost,
+			path:                "/v1/falsePositives",
+			body:                `{"vulnerability":"CVE-2021-44228"}`,
+			expectedCode:        http.StatusBadRequest,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"detail":"a false positive needs a vulnerability and a package","status":400,"title":"Bad Request"}`,
+		},
+		{
+			name:                "add not enabled",
+			scanService:         falsePositiveErrorScanService{services.NewMockScanService(true), domain.ErrNoFalsePositives},
+			method:              http.MethodPost,
+			path:                "/v1/falsePositives",
+			body:                `{"vulnerability":"CVE-2021-44228","package":"log4j-core"}`,
+			expectedCode:        http.StatusNotFound,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"detail":"false positive feedback is not enabled","status":404,"title":"Not Found"}`,
+		},
+		{
+			name:                "list",
+			scanService:         services.NewMockScanService(true),
+			method:              http.MethodGet,
+			path:                "/v1/falsePositives",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `[]`,
+		},
+		{
+			name:                "list error",
+			scanService:         services.NewMockScanService(false),
+			method:              http.MethodGet,
+			path:                "/v1/falsePositives",
+			expectedCode:        http.StatusInternalServerError,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"status":500,"title":"Internal Server Error"}`,
+		},
+		{
+			name:                "export grype",
+			scanService:         services.NewMockScanService(true),
+			method:              http.MethodGet,
+			path:                "/v1/falsePositives?format=grype",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/yaml",
+			expectedBody:        "ignore: []\n",
+		},
+		{
+			name:                "export unknown format",
+			scanService:         falsePositiveErrorScanService{services.NewMockScanService(true), fmt.Errorf("%w: %q", domain.ErrFalsePositiveFormat, "csv")},
+			method:              http.MethodGet,
+			path:                "/v1/falsePositives?format=csv",
+			expectedCode:        http.StatusBadRequest,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"detail":"unsupported false positive export format: \"csv\"","status":400,"title":"Bad Request"}`,
+		},
+		{
+			name:         "delete",
+			scanService:  services.NewMockScanService(true),
+			method:       http.MethodDelete,
+			path:         "/v1/falsePositives/id",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:                "delete unknown",
+			scanService:         falsePositiveErrorScanService{services.NewMockScanService(true), domain.ErrFalsePositiveNotFound},
+			method:              http.MethodDelete,
+			path:                "/v1/falsePositives/unknown",
+			expectedCode:        http.StatusNotFound,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"detail":"false positive not found","status":404,"title":"Not Found"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{scanService: tt.scanService}
+			router := gin.Default()
+			router.GET("/v1/falsePositives", c.ListFalsePositives)
+			router.POST("/v1/falsePositives", c.AddFalsePositive)
+			router.DELETE("/v1/falsePositives/:id", c.DeleteFalsePositive)
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Code)
+			assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedBody, w.Body.String(), w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+// CollectGarbage periodically deletes the stored scan results of the images no longer running, until ctx is done
+func (h HTTPController) CollectGarbage(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := h.scanService.CollectGarbage(ctx); err != nil {
+				logger.L().Ctx(ctx).Warning("scan results garbage collection failed", helpers.Error(err))
+			}
+		}
+	}
+}
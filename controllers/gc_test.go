@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingScanService counts the garbage collections
+type countingScanService struct {
+	ports.ScanService
+	collections chan struct{}
+}
+
+func (c countingScanService) CollectGarbage(context.Context) (domain.GCReport, error) {
+	select {
+	case c.collections <- struct{}{}:
+	default:
+	}
+	return domain.GCReport{}, domain.ErrMockError
+}
+
+func TestHTTPController_CollectGarbage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	scanService := countingScanService{collections: make(chan struct{}, 1)}
+	h := HTTPController{scanService: scanService}
+	done := make(chan struct{})
+	go func() {
+		h.CollectGarbage(ctx, time.Millisecond)
+		close(done)
+	}()
+	// errors do not stop the collections
+	for i := 0; i < 2; i++ {
+		select {
+		case <-scanService.collections:
+		case <-time.After(5 * time.Second):
+			t.Fatal("garbage collection did not run")
+		}
+	}
+	cancel()
+	<-done
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
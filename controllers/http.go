@@ -1,12 +1,17 @@
 package controllers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	wssc "github.com/armosec/armoapi-go/apis"
 	"github.com/gammazero/workerpool"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/kubescape/go-logger"
 	"github.com/kubescape/go-logger/helpers"
 	"github.com/kubescape/k8s-interface/names"
@@ -19,16 +24,51 @@ import (
 // HTTPController maps ScanService ports to gin handlers that can be mapped to paths and methods
 // this mapping is usually done in main()
 type HTTPController struct {
-	scanService ports.ScanService
-	workerPool  *workerpool.WorkerPool
+	background      *backgroundScans
+	bundleTokens    *bearerTokens
+	config          map[string]interface{}
+	fairness        *fairQueue
+	limiter         *concurrencyLimiter
+	pending         *pendingScans
+	profiler        *scanProfiler
+	progressBroker  ports.ProgressBroker
+	queue           ports.ScanQueueRepository
+	registryWebhook *registryWebhook
+	relay           *bearerTokens
+	scanService     ports.ScanService
+	workerPool      *workerpool.WorkerPool
+}
+
+// HTTPControllerOption configures optional dependencies of the HTTPController
+type HTTPControllerOption func(*HTTPController)
+
+// WithScanQueue injects the ScanQueueRepository used to persist pending scans across restarts
+func WithScanQueue(queue ports.ScanQueueRepository) HTTPControllerOption {
+	return func(h *HTTPController) {
+		h.queue = queue
+	}
+}
+
+// WithConfig injects the redacted configuration exposed for introspection
+func WithConfig(config map[string]interface{}) HTTPControllerOption {
+	return func(h *HTTPController) {
+		h.config = config
+	}
 }
 
 // NewHTTPController initializes the HTTPController struct with the injected scanService
-func NewHTTPController(scanService ports.ScanService, concurrency int) *HTTPController {
-	return &HTTPController{
+func NewHTTPController(scanService ports.ScanService, concurrency int, opts ...HTTPControllerOption) *HTTPController {
+	h := &HTTPController{
+		background:  newBackgroundScans(),
+		limiter:     newConcurrencyLimiter(concurrency),
+		pending:     newPendingScans(),
 		scanService: scanService,
 		workerPool:  workerpool.New(concurrency),
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // GenerateSBOM unmarshalls the payload and calls scanService.GenerateSBOM
@@ -53,21 +93,13 @@ func (h HTTPController) GenerateSBOM(c *gin.Context) {
 			helpers.String("imageSlug", newScan.ImageSlug),
 			helpers.String("imageTag", newScan.ImageTag),
 			helpers.String("imageHash", newScan.ImageHash))
-		_, _ = problem.Of(http.StatusInternalServerError).Append(details).WriteTo(c.Writer)
+		writeValidationError(c, err, details)
 		return
 	}
 
 	_, _ = problem.Of(http.StatusOK).Append(details).WriteTo(c.Writer)
 
-	h.workerPool.Submit(func() {
-		err = h.scanService.GenerateSBOM(ctx)
-		if err != nil {
-			logger.L().Ctx(ctx).Error("service error", helpers.Error(err),
-				helpers.String("imageSlug", newScan.ImageSlug),
-				helpers.String("imageTag", newScan.ImageTag),
-				helpers.String("imageHash", newScan.ImageHash))
-		}
-	})
+	h.submit(ctx, domain.ScanKindGenerateSBOM, newScan)
 }
 
 // Alive returns 200 OK
@@ -75,6 +107,11 @@ func (h HTTPController) Alive(c *gin.Context) {
 	_, _ = problem.Of(http.StatusOK).WriteTo(c.Writer)
 }
 
+// Config returns the redacted configuration
+func (h HTTPController) Config(c *gin.Context) {
+	c.JSON(http.StatusOK, h.config)
+}
+
 // Ready calls scanService.Ready
 func (h HTTPController) Ready(c *gin.Context) {
 	if !h.scanService.Ready(c.Request.Context()) {
@@ -102,27 +139,29 @@ func (h HTTPController) ScanCVE(c *gin.Context) {
 	details := problem.Detailf("Wlid=%s, ImageHash=%s", newScan.Wlid, newScan.ImageHash)
 
 	ctx, err = h.scanService.ValidateScanCVE(ctx, newScan)
+	// the workloads opted out of the scans and the trusted images are not retried
+	if skippedScan(err) {
+		_, _ = problem.Of(http.StatusOK).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	}
 	if err != nil {
 		logger.L().Ctx(ctx).Error("validation error", helpers.Error(err),
 			helpers.String("imageSlug", newScan.ImageSlug),
 			helpers.String("imageTag", newScan.ImageTag),
 			helpers.String("imageHash", newScan.ImageHash))
-		_, _ = problem.Of(http.StatusInternalServerError).Append(details).WriteTo(c.Writer)
+		writeValidationError(c, err, details)
 		return
 	}
 
 	_, _ = problem.Of(http.StatusOK).Append(details).WriteTo(c.Writer)
 
-	h.workerPool.Submit(func() {
-		err = h.scanService.ScanCVE(ctx)
-		if err != nil {
-			logger.L().Ctx(ctx).Error("service error", helpers.Error(err),
-				helpers.String("wlid", newScan.Wlid),
-				helpers.String("imageSlug", newScan.ImageSlug),
-				helpers.String("imageTag", newScan.ImageTag),
-				helpers.String("imageHash", newScan.ImageHash))
-		}
-	})
+	h.submit(ctx, domain.ScanKindScanCVE, newScan)
+}
+
+// skippedScan tells whether the validation skipped the scan, for an opted out workload or a trusted image, which the
+// service already reported
+func skippedScan(err error) bool {
+	return errors.Is(err, domain.ErrSkippedByAnnotation) || errors.Is(err, domain.ErrTrustedDigest)
 }
 
 func websocketScanCommandToScanCommand(c wssc.WebsocketScanCommand) domain.ScanCommand {
@@ -142,12 +181,27 @@ func websocketScanCommandToScanCommand(c wssc.WebsocketScanCommand) domain.ScanC
 	if slug, err := names.ImageInfoToSlug(c.ImageTag, c.ImageHash); err == nil {
 		command.ImageSlug = slug
 	}
+	if val, ok := c.Args[domain.AttributeCallbackURL].(string); ok {
+		command.CallbackURL = val
+	}
+	command.Deadline = scanDeadline(c.Args)
 	if c.InstanceID != nil {
 		command.InstanceID = *c.InstanceID
 	}
 	return command
 }
 
+// scanDeadline parses the deadline given by the operator in the arguments of a scan command, a missing or invalid one
+// leaves the scan without deadline
+func scanDeadline(args map[string]interface{}) time.Time {
+	if val, ok := args[domain.AttributeDeadline].(string); ok {
+		if deadline, err := time.Parse(time.RFC3339, val); err == nil {
+			return deadline
+		}
+	}
+	return time.Time{}
+}
+
 func sessionChainToSession(s wssc.SessionChain) domain.Session {
 	return domain.Session{
 		JobIDs: s.JobIDs,
@@ -170,26 +224,22 @@ func (h HTTPController) ScanRegistry(c *gin.Context) {
 	details := problem.Detailf("ImageTag=%s", newScan.ImageTag)
 
 	ctx, err = h.scanService.ValidateScanRegistry(ctx, newScan)
+	if skippedScan(err) {
+		_, _ = problem.Of(http.StatusOK).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	}
 	if err != nil {
 		logger.L().Ctx(ctx).Error("validation error", helpers.Error(err),
 			helpers.String("imageSlug", newScan.ImageSlug),
 			helpers.String("imageTag", newScan.ImageTag),
 			helpers.String("imageHash", newScan.ImageHash))
-		_, _ = problem.Of(http.StatusInternalServerError).Append(details).WriteTo(c.Writer)
+		writeValidationError(c, err, details)
 		return
 	}
 
 	_, _ = problem.Of(http.StatusOK).Append(details).WriteTo(c.Writer)
 
-	h.workerPool.Submit(func() {
-		err = h.scanService.ScanRegistry(ctx)
-		if err != nil {
-			logger.L().Ctx(ctx).Error("service error", helpers.Error(err),
-				helpers.String("imageSlug", newScan.ImageSlug),
-				helpers.String("imageTag", newScan.ImageTag),
-				helpers.String("imageHash", newScan.ImageHash))
-		}
-	})
+	h.submit(ctx, domain.ScanKindScanRegistry, newScan)
 }
 
 func registryScanCommandToScanCommand(c wssc.RegistryScanCommand) domain.ScanCommand {
@@ -205,9 +255,188 @@ func registryScanCommandToScanCommand(c wssc.RegistryScanCommand) domain.ScanCom
 	if slug, err := names.ImageInfoToSlug(c.ImageTag, "nohash"); err == nil {
 		command.ImageSlug = slug
 	}
+	if val, ok := c.Args[domain.AttributeCallbackURL].(string); ok {
+		command.CallbackURL = val
+	}
+	command.Deadline = scanDeadline(c.Args)
 	return command
 }
 
+// submit queues a validated scan in the worker pool, and in the persistent queue if enabled until it is processed
+func (h HTTPController) submit(ctx context.Context, kind string, command domain.ScanCommand) {
+	h.submitWithDone(ctx, kind, command, nil)
+}
+
+// submitWithDone is submit calling done, if not nil, once the scan is processed, the relayed scans are not persisted
+// since their SBOM is too large for the queue
+func (h HTTPController) submitWithDone(ctx context.Context, kind string, command domain.ScanCommand, done func()) {
+	id := uuid.NewString()
+	if h.queue != nil && kind != domain.ScanKindScanRelayedSBOM {
+		err := h.queue.Enqueue(ctx, domain.QueuedScan{
+			ID:       id,
+			Kind:     kind,
+			Command:  command,
+			QueuedAt: time.Now(),
+		})
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to persist scan in queue", helpers.Error(err),
+				helpers.String("imageSlug", command.ImageSlug))
+		}
+	}
+
+	if command.Wlid != "" {
+		h.pending.add(command.Wlid, id)
+	}
+
+	scan := func() {
+		if done != nil {
+			defer done()
+		}
+		h.limiter.acquire()
+		defer h.limiter.release()
+		// the scan outlives the request submitting it, it is only aborted by the deletion of its workload
+		scanCtx, abort := context.WithCancel(tools.WithoutCancel(ctx))
+		defer abort()
+		var err error
+		if h.pending.take(id, abort) {
+			logger.L().Info("skipping scan of deleted workload",
+				helpers.String("wlid", command.Wlid),
+				helpers.String("imageSlug", command.ImageSlug))
+		} else {
+			err = h.profiler.run(scanCtx, func(ctx context.Context) error {
+				return h.run(ctx, kind)
+			})
+			h.pending.done(id)
+		}
+		if err != nil && scanCtx.Err() != nil {
+			logger.L().Info("aborted scan of deleted workload",
+				helpers.String("wlid", command.Wlid),
+				helpers.String("imageSlug", command.ImageSlug))
+		} else if err != nil {
+			logger.L().Ctx(ctx).Error("service error", helpers.Error(err),
+				helpers.String("errorCode", domain.ErrorCode(err)),
+				helpers.String("wlid", command.Wlid),
+				helpers.String("imageSlug", command.ImageSlug),
+				helpers.String("imageTag", command.ImageTag),
+				helpers.String("imageHash", command.ImageHash))
+		}
+		if h.queue != nil && kind != domain.ScanKindScanRelayedSBOM {
+			err = h.queue.Dequeue(ctx, id)
+			if err != nil {
+				logger.L().Ctx(ctx).Warning("failed to remove scan from queue", helpers.Error(err),
+					helpers.String("imageSlug", command.ImageSlug))
+			}
+		}
+	}
+	// the workers run the scans picked fairly across namespaces once they are free, instead of in submission order
+	if h.fairness != nil {
+		h.fairness.push(scanNamespace(command), scan)
+		scan = h.fairness.runNext
+	}
+	h.workerPool.Submit(scan)
+}
+
+// writeValidationError answers a rejected scan command, commands denied by policy are forbidden and invalid
+// repositories and relayed SBOMs are bad requests
+func writeValidationError(c *gin.Context, err error, details problem.Option) {
+	switch {
+	case errors.Is(err, domain.ErrRegistryDenied), errors.Is(err, domain.ErrClusterDenied):
+		_, _ = problem.Of(http.StatusForbidden).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	case errors.Is(err, domain.ErrInvalidRepository), errors.Is(err, domain.ErrNoRelayedSBOM):
+		_, _ = problem.Of(http.StatusBadRequest).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	}
+	_, _ = problem.Of(http.StatusInternalServerError).Append(details).WriteTo(c.Writer)
+}
+
+// validate calls the scanService validation matching the kind of scan
+func (h HTTPController) validate(ctx context.Context, kind string, command domain.ScanCommand) (context.Context, error) {
+	switch kind {
+	case domain.ScanKindGenerateSBOM:
+		return h.scanService.ValidateGenerateSBOM(ctx, command)
+	case domain.ScanKindScanCVE:
+		return h.scanService.ValidateScanCVE(ctx, command)
+	case domain.ScanKindScanRegistry:
+		return h.scanService.ValidateScanRegistry(ctx, command)
+	case domain.ScanKindScanRelayedSBOM:
+		return h.scanService.ValidateScanRelayedSBOM(ctx, command)
+	case domain.ScanKindScanRepository:
+		return h.scanService.ValidateScanRepository(ctx, command)
+	}
+	return ctx, fmt.Errorf("unknown scan kind %q", kind)
+}
+
+// run calls the scanService flow matching the kind of scan
+func (h HTTPController) run(ctx context.Context, kind string) error {
+	switch kind {
+	case domain.ScanKindGenerateSBOM:
+		return h.scanService.GenerateSBOM(ctx)
+	case domain.ScanKindScanCVE:
+		return h.scanService.ScanCVE(ctx)
+	case domain.ScanKindScanRegistry:
+		return h.scanService.ScanRegistry(ctx)
+	case domain.ScanKindScanRelayedSBOM:
+		return h.scanService.ScanRelayedSBOM(ctx)
+	case domain.ScanKindScanRepository:
+		return h.scanService.ScanRepository(ctx)
+	}
+	return fmt.Errorf("unknown scan kind %q", kind)
+}
+
+// ResumeQueue submits again the scans left in the persistent queue by a previous process, the scans are only removed
+// from the queue once validated, or once invalid for good
+func (h HTTPController) ResumeQueue(ctx context.Context) {
+	if h.queue == nil {
+		return
+	}
+	scans, err := h.queue.ListQueued(ctx)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to list queued scans", helpers.Error(err))
+		return
+	}
+	dequeue := func(scan domain.QueuedScan) {
+		if err := h.queue.Dequeue(ctx, scan.ID); err != nil {
+			logger.L().Ctx(ctx).Warning("failed to remove scan from queue", helpers.Error(err),
+				helpers.String("imageSlug", scan.Command.ImageSlug))
+		}
+	}
+	var resumed int
+	for _, scan := range scans {
+		// the relayed SBOMs are not persisted, the edge cluster relays them again
+		if scan.Kind == domain.ScanKindScanRelayedSBOM {
+			logger.L().Ctx(ctx).Warning("dropping queued relayed scan, its SBOM is not persisted",
+				helpers.String("imageSlug", scan.Command.ImageSlug))
+			dequeue(scan)
+			continue
+		}
+		scanCtx, err := h.validate(ctx, scan.Kind, scan.Command)
+		switch {
+		case errors.Is(err, domain.ErrTooManyRequests):
+			logger.L().Ctx(ctx).Warning("keeping throttled queued scan for the next resume", helpers.Error(err),
+				helpers.String("imageSlug", scan.Command.ImageSlug))
+			continue
+		case err != nil:
+			logger.L().Ctx(ctx).Warning("dropping invalid queued scan", helpers.Error(err),
+				helpers.String("imageSlug", scan.Command.ImageSlug))
+			dequeue(scan)
+			continue
+		}
+		if scan.HadCredentials {
+			logger.L().Ctx(ctx).Warning("resuming queued scan without its registry credentials, which are not persisted, private images fail to pull",
+				helpers.String("imageSlug", scan.Command.ImageSlug),
+				helpers.String("imageTag", scan.Command.ImageTag))
+		}
+		// the entry is replaced when the scan is submitted again
+		dequeue(scan)
+		h.submit(scanCtx, scan.Kind, scan.Command)
+		resumed++
+	}
+	logger.L().Info("resumed queued scans",
+		helpers.Int("count", resumed),
+		helpers.Int("queued", len(scans)))
+}
+
 func (h HTTPController) Shutdown() {
 	logger.L().Info("purging SBOM creation queue",
 		helpers.String("remaining jobs", strconv.Itoa(h.workerPool.WaitingQueueSize())))
@@ -1,19 +1,26 @@
 package controllers
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
 	"testing"
+	"time"
 
 	wssc "github.com/armosec/armoapi-go/apis"
 	"github.com/docker/docker/api/types"
 	"github.com/gammazero/workerpool"
 	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
 	"github.com/kubescape/kubevuln/core/ports"
 	"github.com/kubescape/kubevuln/core/services"
 	"github.com/kubescape/kubevuln/internal/tools"
+	"github.com/kubescape/kubevuln/repositories"
 	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestHTTPController_Alive(t *testing.T) {
@@ -113,6 +120,13 @@ func TestHTTPController_Ready(t *testing.T) {
 	}
 }
 
+// workloadAnnotations is a WorkloadAnnotator giving the same annotations to all the workloads
+type workloadAnnotations map[string]string
+
+func (w workloadAnnotations) WorkloadAnnotations(context.Context, string) (map[string]string, error) {
+	return w, nil
+}
+
 func TestHTTPController_ScanCVE(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -135,6 +149,45 @@ func TestHTTPController_ScanCVE(t *testing.T) {
 			expectedBody: "{\"detail\":\"Wlid=wlid://cluster-minikube/namespace-kube-system/daemonset-kube-proxy, ImageHash=k8s.gcr.io/kube-proxy@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137\",\"status\":500,\"title\":\"Internal Server Error\"}",
 			yamlFile:     "../api/v1/testdata/scan.yaml",
 		},
+		{
+			name: "registry denied",
+			scanService: services.NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockCVEAdapter(),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockPlatform(),
+				false,
+				services.WithAllowedRegistries([]string{"ghcr.io"})),
+			expectedCode: http.StatusForbidden,
+			expectedBody: "{\"detail\":\"image registry is not allowed by policy: k8s.gcr.io/kube-proxy\",\"status\":403,\"title\":\"Forbidden\"}",
+			yamlFile:     "../api/v1/testdata/scan.yaml",
+		},
+		{
+			name: "skipped by annotation",
+			scanService: services.NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockCVEAdapter(),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockPlatform(),
+				false,
+				services.WithAnnotationGating(workloadAnnotations{domain.AnnotationScan: "false"})),
+			expectedCode: http.StatusOK,
+			expectedBody: "{\"detail\":\"scan disabled by workload annotation\",\"status\":200,\"title\":\"OK\"}",
+			yamlFile:     "../api/v1/testdata/scan.yaml",
+		},
+		{
+			name: "trusted digest",
+			scanService: services.NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockCVEAdapter(),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockPlatform(),
+				false,
+				services.WithTrustedDigests(domain.TrustedDigests{Version: "2023.04", Digests: []domain.TrustedDigest{{Digest: "sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137"}}})),
+			expectedCode: http.StatusOK,
+			expectedBody: "{\"detail\":\"image digest trusted, scan skipped\",\"status\":200,\"title\":\"OK\"}",
+			yamlFile:     "../api/v1/testdata/scan.yaml",
+		},
 		{
 			name:         "ready",
 			scanService:  services.NewMockScanService(true),
@@ -278,3 +331,137 @@ func Test_registryScanCommandToScanCommand(t *testing.T) {
 		assert.Equal(t, tests[i].ParentJobID, scanComm.ParentJobID)
 	}
 }
+
+func Test_scanDeadline(t *testing.T) {
+	deadline := time.Date(2023, 5, 4, 12, 30, 0, 0, time.UTC)
+	assert.Equal(t, deadline, scanDeadline(map[string]interface{}{domain.AttributeDeadline: "2023-05-04T12:30:00Z"}))
+	assert.True(t, scanDeadline(map[string]interface{}{domain.AttributeDeadline: "in 5 minutes"}).IsZero())
+	assert.True(t, scanDeadline(nil).IsZero())
+
+	command := websocketScanCommandToScanCommand(wssc.WebsocketScanCommand{
+		ImageScanParams: wssc.ImageScanParams{
+			ImageTag: "nginx:latest",
+			Args:     map[string]interface{}{domain.AttributeDeadline: "2023-05-04T12:30:00Z"},
+		},
+	})
+	assert.Equal(t, deadline, command.Deadline)
+}
+
+func TestHTTPController_ResumeQueue(t *testing.T) {
+	ctx := context.TODO()
+	queue := repositories.NewConfigMapQueueStore(fake.NewSimpleClientset(), "kubescape", "kubevuln-queue")
+	for i, kind := range []string{domain.ScanKindGenerateSBOM, domain.ScanKindScanCVE, domain.ScanKindScanRegistry, domain.ScanKindScanRelayedSBOM, "unknown"} {
+		err := queue.Enqueue(ctx, domain.QueuedScan{
+			ID:       strconv.Itoa(i),
+			Kind:     kind,
+			QueuedAt: time.Now(),
+			Command:  domain.ScanCommand{ImageTag: "k8s.gcr.io/kube-proxy:v1.24.3"},
+		})
+		tools.EnsureSetup(t, err == nil)
+	}
+	c := NewHTTPController(services.NewMockScanService(true), 1, WithScanQueue(queue))
+	c.ResumeQueue(ctx)
+	c.Shutdown()
+	scans, err := queue.ListQueued(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, scans)
+}
+
+func TestHTTPController_Coverage(t *testing.T) {
+	tests := []struct {
+		name         string
+		scanService  ports.ScanService
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "error",
+			scanService:  services.NewMockScanService(false),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "{\"status\":500,\"title\":\"Internal Server Error\"}",
+		},
+		{
+			name:         "report",
+			scanService:  services.NewMockScanService(true),
+			expectedCode: http.StatusOK,
+			expectedBody: "{\"window\":\"\",\"total\":0,\"scanned\":0,\"stale\":0,\"unscanned\":0,\"failing\":0,\"ratio\":0,\"workloads\":null}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{scanService: tt.scanService}
+			router := gin.Default()
+			path := "/v1/coverage"
+			router.GET(path, c.Coverage)
+			req, _ := http.NewRequest("GET", path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String(), w.Body.String())
+		})
+	}
+}
+
+func TestHTTPController_Config(t *testing.T) {
+	c := NewHTTPController(services.NewMockScanService(true), 1, WithConfig(map[string]interface{}{"accountID": "<redacted>", "scanConcurrency": 1}))
+	router := gin.Default()
+	path := "/v1/config"
+	router.GET(path, c.Config)
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, w.Code)
+	assert.Equal(t, "{\"accountID\":\"\\u003credacted\\u003e\",\"scanConcurrency\":1}", w.Body.String(), w.Body.String())
+}
+
+func TestHTTPController_Diff(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		scanService  ports.ScanService
+		expectedCode int
+	}{
+		{
+			name:         "by scanIDs",
+			query:        "?fromScanID=a&toScanID=b",
+			scanService:  services.NewMockScanService(true),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "by workload and times",
+			query:        "?wlid=wlid://cluster-minikube/namespace-default/deployment-nginx&from=2023-04-01T00:00:00Z&to=2023-04-02T00:00:00Z",
+			scanService:  services.NewMockScanService(true),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "missing workload",
+			query:        "?fromScanID=a&to=2023-04-02T00:00:00Z",
+			scanService:  services.NewMockScanService(true),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid time",
+			query:        "?wlid=wlid://cluster-minikube/namespace-default/deployment-nginx&from=yesterday&toScanID=b",
+			scanService:  services.NewMockScanService(true),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "error",
+			query:        "?fromScanID=a&toScanID=b",
+			scanService:  services.NewMockScanService(false),
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{scanService: tt.scanService}
+			router := gin.Default()
+			path := "/v1/diff"
+			router.GET(path, c.Diff)
+			req, _ := http.NewRequest("GET", path+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"schneider.vip/problem"
+)
+
+// SyncInventory unmarshalls the image and workload inventory posted by the operator and reconciles the caches with
+// it, the scan times and failures of the images no longer running are forgotten
+func (h HTTPController) SyncInventory(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var inventory domain.Inventory
+	err := c.ShouldBindJSON(&inventory)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("handler error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusBadRequest).WriteTo(c.Writer)
+		return
+	}
+
+	sync, err := h.scanService.SyncInventory(ctx, inventory)
+	switch {
+	case errors.Is(err, domain.ErrNoInventory):
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	case errors.Is(err, domain.ErrInvalidInventory):
+		_, _ = problem.Of(http.StatusBadRequest).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	case err != nil:
+		logger.L().Ctx(ctx).Error("inventory sync error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusInternalServerError).WriteTo(c.Writer)
+		return
+	}
+
+	c.JSON(http.StatusOK, sync)
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+)
+
+// inventoryErrorScanService fails the inventory sync with err
+type inventoryErrorScanService struct {
+	*services.MockScanService
+	err error
+}
+
+func (s inventoryErrorScanService) SyncInventory(context.Context, domain.Inventory) (domain.InventorySync, error) {
+	return domain.InventorySync{}, s.err
+}
+
+func TestHTTPController_SyncInventory(t *testing.T) {
+	tests := []struct {
+		name                string
+		scanService         ports.ScanService
+		body                string
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "sync",
+			scanService:         services.NewMockScanService(true),
+			body:                `{"images":[{"namespace":"default","kind":"Deployment","name":"nginx","containerName":"nginx","imageTag":"nginx:1.25"}]}`,
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"images":1,"added":1,"removed":0,"forgottenScans":0,"forgottenFailures":0}`,
+		},
+		{
+			name:                "invalid body",
+			scanService:         services.NewMockScanService(true),
+			body:                "{",
+			expectedCode:        http.StatusBadRequest,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"status":400,"title":"Bad Request"}`,
+		},
+		{
+			name:                "incomplete entries",
+			scanService:         inventoryErrorScanService{services.NewMockScanService(true), domain.ErrInvalidInventory},
+			body:                `{"images":[{"name":"nginx"}]}`,
+			expectedCode:        http.StatusBadRequest,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"detail":"the inventory entries need a namespace, a kind, a name, a container name, an image tag and an image hash","status":400,"title":"Bad Request"}`,
+		},
+		{
+			name:                "not enabled",
+			scanService:         inventoryErrorScanService{services.NewMockScanService(true), domain.ErrNoInventory},
+			body:                `{"images":[]}`,
+			expectedCode:        http.StatusNotFound,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"detail":"inventory sync is not enabled","status":404,"title":"Not Found"}`,
+		},
+		{
+			name:                "sync error",
+			scanService:         services.NewMockScanService(false),
+			body:                `{"images":[]}`,
+			expectedCode:        http.StatusInternalServerError,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"status":500,"title":"Internal Server Error"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{scanService: tt.scanService}
+			router := gin.Default()
+			router.POST("/v1/inventory", c.SyncInventory)
+			req, _ := http.NewRequest(http.MethodPost, "/v1/inventory", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Code)
+			assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedBody, w.Body.String(), w.Body.String())
+		})
+	}
+}
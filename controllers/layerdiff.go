@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	apiv1 "github.com/kubescape/kubevuln/api/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"schneider.vip/problem"
+)
+
+// ScanLayerDiff unmarshalls a layer diff request and returns the vulnerabilities the image introduces and removes
+// compared to the base, scanning only the layers which differ between them
+func (h HTTPController) ScanLayerDiff(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var request apiv1.LayerDiffRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("handler error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusBadRequest).WriteTo(c.Writer)
+		return
+	}
+
+	diff, err := h.scanService.ScanLayerDiff(ctx, domain.LayerDiffRequest{
+		Base:            request.Base,
+		Image:           request.Image,
+		Credentialslist: request.Credentialslist,
+	})
+	switch {
+	case errors.Is(err, domain.ErrNoLayerDiff):
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	case errors.Is(err, domain.ErrInvalidLayerDiff):
+		_, _ = problem.Of(http.StatusBadRequest).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	case errors.Is(err, domain.ErrRegistryDenied):
+		_, _ = problem.Of(http.StatusForbidden).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	case errors.Is(err, domain.ErrInitVulnDB):
+		_, _ = problem.Of(http.StatusServiceUnavailable).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	case err != nil:
+		logger.L().Ctx(ctx).Error("layer diff error", helpers.Error(err),
+			helpers.String("base", request.Base),
+			helpers.String("image", request.Image))
+		_, _ = problem.Of(http.StatusInternalServerError).WriteTo(c.Writer)
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
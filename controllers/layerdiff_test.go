@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPController_ScanLayerDiff(t *testing.T) {
+	tests := []struct {
+		name         string
+		scanService  ports.ScanService
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "invalid request",
+			scanService:  services.NewMockScanService(true),
+			body:         `{"base": ["nginx:1.24"]}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "{\"status\":400,\"title\":\"Bad Request\"}",
+		},
+		{
+			name:         "scan error",
+			scanService:  services.NewMockScanService(false),
+			body:         `{"base": "nginx:1.24", "image": "nginx:1.25"}`,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "{\"status\":500,\"title\":\"Internal Server Error\"}",
+		},
+		{
+			name:         "diff",
+			scanService:  services.NewMockScanService(true),
+			body:         `{"base": "nginx:1.24", "image": "nginx:1.25"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: "{\"base\":\"nginx:1.24\",\"image\":\"nginx:1.25\",\"addedLayers\":null,\"removedLayers\":null,\"sharedLayers\":0,\"introduced\":null,\"removed\":null}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{scanService: tt.scanService}
+			router := gin.Default()
+			path := "/v1/scanLayerDiff"
+			router.POST(path, c.ScanLayerDiff)
+			req, _ := http.NewRequest("POST", path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String(), w.Body.String())
+		})
+	}
+}
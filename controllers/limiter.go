@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// concurrencyLimiter bounds the number of scans running at once, its limit can be changed at runtime
+// between 1 and the size of the worker pool
+type concurrencyLimiter struct {
+	cond     *sync.Cond
+	limit    int
+	maxLimit int
+	running  int
+}
+
+func newConcurrencyLimiter(maxLimit int) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		cond:     sync.NewCond(&sync.Mutex{}),
+		limit:    maxLimit,
+		maxLimit: maxLimit,
+	}
+}
+
+// acquire blocks until a scan slot is available
+func (l *concurrencyLimiter) acquire() {
+	if l == nil {
+		return
+	}
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+	for l.running >= l.limit {
+		l.cond.Wait()
+	}
+	l.running++
+}
+
+// release frees a scan slot
+func (l *concurrencyLimiter) release() {
+	if l == nil {
+		return
+	}
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+	l.running--
+	l.cond.Broadcast()
+}
+
+// adjust changes the limit by delta within bounds and returns the new limit
+func (l *concurrencyLimiter) adjust(delta int) int {
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+	l.limit += delta
+	if l.limit < 1 {
+		l.limit = 1
+	}
+	if l.limit > l.maxLimit {
+		l.limit = l.maxLimit
+	}
+	l.cond.Broadcast()
+	return l.limit
+}
+
+// current returns the current limit
+func (l *concurrencyLimiter) current() int {
+	l.cond.L.Lock()
+	defer l.cond.L.Unlock()
+	return l.limit
+}
+
+// AdaptConcurrency periodically compares the memory usage with the container memory limit, it lowers the scan
+// concurrency by one when the usage is above highWatermark and raises it back when the usage drops below lowWatermark,
+// it returns when ctx is done
+func (h HTTPController) AdaptConcurrency(ctx context.Context, interval time.Duration, highWatermark, lowWatermark float64, memoryLimit uint64, memoryUsageFunc func() (uint64, error)) {
+	meter := otel.Meter("")
+	usageGauge, _ := meter.Int64ObservableGauge("kubevuln_memory_usage_bytes",
+		metric.WithDescription("Memory used by the container"))
+	limitGauge, _ := meter.Int64ObservableGauge("kubevuln_memory_limit_bytes",
+		metric.WithDescription("Memory limit of the container"))
+	concurrencyGauge, _ := meter.Int64ObservableGauge("kubevuln_scan_concurrency",
+		metric.WithDescription("Number of scans allowed to run at once"))
+	if usageGauge != nil && limitGauge != nil && concurrencyGauge != nil {
+		registration, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+			if usage, err := memoryUsageFunc(); err == nil {
+				o.ObserveInt64(usageGauge, int64(usage))
+			}
+			o.ObserveInt64(limitGauge, int64(memoryLimit))
+			o.ObserveInt64(concurrencyGauge, int64(h.limiter.current()))
+			return nil
+		}, usageGauge, limitGauge, concurrencyGauge)
+		if err == nil {
+			defer func() { _ = registration.Unregister() }()
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			usage, err := memoryUsageFunc()
+			if err != nil {
+				logger.L().Ctx(ctx).Warning("failed to read memory usage", helpers.Error(err))
+				continue
+			}
+			h.adaptConcurrency(ctx, float64(usage)/float64(memoryLimit), highWatermark, lowWatermark)
+		}
+	}
+}
+
+// adaptConcurrency adjusts the scan concurrency to the given memory pressure
+func (h HTTPController) adaptConcurrency(ctx context.Context, pressure, highWatermark, lowWatermark float64) {
+	previous := h.limiter.current()
+	var limit int
+	switch {
+	case pressure > highWatermark:
+		limit = h.limiter.adjust(-1)
+	case pressure < lowWatermark:
+		limit = h.limiter.adjust(1)
+	default:
+		return
+	}
+	if limit != previous {
+		logger.L().Ctx(ctx).Info("adapting scan concurrency to memory pressure",
+			helpers.Int("concurrency", limit),
+			helpers.String("pressure", strconv.FormatFloat(pressure, 'f', 2, 64)))
+	}
+}
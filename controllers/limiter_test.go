@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPController_adaptConcurrency(t *testing.T) {
+	h := HTTPController{limiter: newConcurrencyLimiter(3)}
+	steps := []struct {
+		pressure float64
+		want     int
+	}{
+		{0.5, 3},
+		{0.9, 2},
+		{0.9, 1},
+		{0.95, 1},
+		{0.7, 1},
+		{0.5, 2},
+		{0.5, 3},
+		{0.5, 3},
+	}
+	for _, step := range steps {
+		h.adaptConcurrency(context.TODO(), step.pressure, 0.8, 0.6)
+		assert.Equal(t, step.want, h.limiter.current(), step.pressure)
+	}
+}
+
+func Test_concurrencyLimiter(t *testing.T) {
+	l := newConcurrencyLimiter(4)
+	l.adjust(-2)
+	var running, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.acquire()
+			defer l.release()
+			current := atomic.AddInt32(&running, 1)
+			for {
+				observed := atomic.LoadInt32(&peak)
+				if current <= observed || atomic.CompareAndSwapInt32(&peak, observed, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, peak, int32(2))
+	// a nil limiter does not limit
+	var nilLimiter *concurrencyLimiter
+	nilLimiter.acquire()
+	nilLimiter.release()
+}
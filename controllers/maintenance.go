@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	apiv1 "github.com/kubescape/kubevuln/api/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/robfig/cron/v3"
+	"schneider.vip/problem"
+)
+
+// ScanWindow allows the background scans for Duration from each start of the cron expression Start
+type ScanWindow struct {
+	Start    string
+	Duration time.Duration
+}
+
+type scanWindow struct {
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// deferredScan is a scan triggered by a workload change while the background scans were paused
+type deferredScan struct {
+	ctx     context.Context
+	command domain.ScanCommand
+}
+
+// backgroundScans pauses the background scans, scheduled or triggered by workload changes, in maintenance mode and
+// outside of the scan windows; a nil backgroundScans never pauses them
+type backgroundScans struct {
+	mu          sync.Mutex
+	windows     []scanWindow
+	maintenance bool
+	reason      string
+	since       time.Time
+	until       time.Time
+	deferred    map[string]deferredScan
+}
+
+func newBackgroundScans() *backgroundScans {
+	return &backgroundScans{deferred: map[string]deferredScan{}}
+}
+
+// WithScanWindows allows the background scans only within the windows, at any time without windows
+func WithScanWindows(windows []ScanWindow) HTTPControllerOption {
+	return func(h *HTTPController) {
+		for _, window := range windows {
+			schedule, err := cron.ParseStandard(window.Start)
+			if err != nil {
+				logger.L().Warning("ignoring invalid scan window", helpers.Error(err),
+					helpers.String("start", window.Start))
+				continue
+			}
+			h.background.windows = append(h.background.windows, scanWindow{schedule: schedule, duration: window.Duration})
+		}
+	}
+}
+
+// statusAt returns the status of the background scans at now, leaving the maintenance mode past its end, with b.mu held
+func (b *backgroundScans) statusAt(now time.Time) domain.MaintenanceStatus {
+	if b.maintenance && !b.until.IsZero() && !now.Before(b.until) {
+		b.maintenance = false
+	}
+	status := domain.MaintenanceStatus{
+		Maintenance: b.maintenance,
+		InWindow:    len(b.windows) == 0,
+		Deferred:    len(b.deferred),
+	}
+	if b.maintenance {
+		since := b.since
+		status.Reason, status.Since = b.reason, &since
+		if !b.until.IsZero() {
+			until := b.until
+			status.Until = &until
+		}
+	}
+	for _, window := range b.windows {
+		// within the window if it started during the last duration
+		if !window.schedule.Next(now.Add(-window.duration)).After(now) {
+			status.InWindow = true
+		}
+	}
+	if !status.InWindow {
+		for _, window := range b.windows {
+			if next := window.schedule.Next(now); status.NextWindow == nil || next.Before(*status.NextWindow) {
+				status.NextWindow = &next
+			}
+		}
+	}
+	status.Paused = status.Maintenance || !status.InWindow
+	return status
+}
+
+// status returns the status of the background scans at now
+func (b *backgroundScans) status(now time.Time) domain.MaintenanceStatus {
+	if b == nil {
+		return domain.MaintenanceStatus{InWindow: true}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.statusAt(now)
+}
+
+// paused tells whether the background scans are paused
+func (b *backgroundScans) paused() bool {
+	return b.status(time.Now()).Paused
+}
+
+// deferScan keeps the scan of a container, replacing the one already deferred, if the background scans are paused,
+// which it reports
+func (b *backgroundScans) deferScan(ctx context.Context, command domain.ScanCommand) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.statusAt(time.Now()).Paused {
+		return false
+	}
+	b.deferred[command.Wlid+"/"+command.ContainerName] = deferredScan{ctx: ctx, command: command}
+	return true
+}
+
+// resumed returns and forgets the deferred scans once the background scans are no longer paused
+func (b *backgroundScans) resumed() []deferredScan {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.deferred) == 0 || b.statusAt(time.Now()).Paused {
+		return nil
+	}
+	scans := make([]deferredScan, 0, len(b.deferred))
+	for _, scan := range b.deferred {
+		scans = append(scans, scan)
+	}
+	b.deferred = map[string]deferredScan{}
+	return scans
+}
+
+// enter pauses the background scans until leave is called or, if set, until
+func (b *backgroundScans) enter(reason string, until *time.Time) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.maintenance {
+		b.since = time.Now().UTC()
+	}
+	b.maintenance, b.reason, b.until = true, reason, time.Time{}
+	if until != nil {
+		b.until = *until
+	}
+}
+
+// leave resumes the background scans, unless outside of the scan windows
+func (b *backgroundScans) leave() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.maintenance = false
+}
+
+// resumeDeferredScans submits the scans deferred while the background scans were paused, once they are no longer
+func (h HTTPController) resumeDeferredScans() {
+	scans := h.background.resumed()
+	if len(scans) == 0 {
+		return
+	}
+	logger.L().Info("resuming deferred background scans", helpers.Int("scans", len(scans)))
+	for _, scan := range scans {
+		h.scanWorkloadImage(scan.ctx, scan.command)
+	}
+}
+
+// Maintenance returns whether the background scans are paused, by the maintenance mode or outside of the scan windows
+func (h HTTPController) Maintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, h.background.status(time.Now()))
+}
+
+// EnterMaintenance pauses the background scans, the scheduled runs are skipped and the scans triggered by workload
+// changes are deferred, while the on-demand scans are still accepted
+func (h HTTPController) EnterMaintenance(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// the request is optional
+	var request apiv1.MaintenanceRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		logger.L().Ctx(ctx).Error("handler error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusBadRequest).WriteTo(c.Writer)
+		return
+	}
+	if request.Until != nil && !request.Until.After(time.Now()) {
+		_, _ = problem.Of(http.StatusBadRequest).Append(problem.Detail("until must be in the future")).WriteTo(c.Writer)
+		return
+	}
+
+	h.background.enter(request.Reason, request.Until)
+	logger.L().Ctx(ctx).Info("entering maintenance mode, background scans paused",
+		helpers.String("reason", request.Reason))
+	c.JSON(http.StatusOK, h.background.status(time.Now()))
+}
+
+// LeaveMaintenance resumes the background scans, submitting the deferred ones unless outside of the scan windows
+func (h HTTPController) LeaveMaintenance(c *gin.Context) {
+	h.background.leave()
+	logger.L().Ctx(c.Request.Context()).Info("leaving maintenance mode")
+	h.resumeDeferredScans()
+	c.JSON(http.StatusOK, h.background.status(time.Now()))
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gammazero/workerpool"
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_backgroundScans_status(t *testing.T) {
+	h := HTTPController{background: newBackgroundScans()}
+	WithScanWindows([]ScanWindow{{Start: "0 1 * * *", Duration: 4 * time.Hour}, {Start: "0 22 * * 6", Duration: time.Hour}})(&h)
+	saturday := func(hour int) time.Time {
+		return time.Date(2023, 4, 1, hour, 30, 0, 0, time.Local)
+	}
+	tests := []struct {
+		name           string
+		now            time.Time
+		wantInWindow   bool
+		wantNextWindow time.Time
+	}{
+		{name: "within the nightly window", now: saturday(3), wantInWindow: true},
+		{name: "after the nightly window", now: saturday(5), wantNextWindow: time.Date(2023, 4, 1, 22, 0, 0, 0, time.Local)},
+		{name: "within the weekly window", now: saturday(22), wantInWindow: true},
+		{name: "after the weekly window", now: saturday(23), wantNextWindow: time.Date(2023, 4, 2, 1, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := h.background.status(tt.now)
+			assert.Equal(t, tt.wantInWindow, status.InWindow)
+			assert.Equal(t, !tt.wantInWindow, status.Paused)
+			if tt.wantInWindow {
+				assert.Nil(t, status.NextWindow)
+			} else {
+				require.NotNil(t, status.NextWindow)
+				assert.Equal(t, tt.wantNextWindow, *status.NextWindow)
+			}
+		})
+	}
+
+	// without windows, the background scans only pause in maintenance mode
+	b := newBackgroundScans()
+	assert.False(t, b.paused())
+	until := time.Now().Add(time.Hour)
+	b.enter("peak", &until)
+	status := b.status(time.Now())
+	assert.True(t, status.Paused)
+	assert.Equal(t, "peak", status.Reason)
+	assert.NotNil(t, status.Since)
+	// the maintenance mode ends at until
+	assert.False(t, b.status(until).Paused)
+	assert.False(t, b.paused())
+
+	// a nil backgroundScans never pauses
+	var disabled *backgroundScans
+	assert.False(t, disabled.paused())
+	assert.False(t, disabled.deferScan(context.TODO(), domain.ScanCommand{}))
+}
+
+func Test_backgroundScans_deferScan(t *testing.T) {
+	b := newBackgroundScans()
+	command := domain.ScanCommand{Wlid: "wlid://cluster-minikube/namespace-default/deployment-nginx", ContainerName: "nginx", ImageTag: "nginx:1.25"}
+	assert.False(t, b.deferScan(context.TODO(), command))
+
+	b.enter("", nil)
+	assert.True(t, b.deferScan(context.TODO(), command))
+	// the latest image of a container replaces the deferred one
+	command.ImageTag = "nginx:1.26"
+	assert.True(t, b.deferScan(context.TODO(), command))
+	assert.Equal(t, 1, b.status(time.Now()).Deferred)
+	assert.Empty(t, b.resumed())
+
+	b.leave()
+	scans := b.resumed()
+	require.Len(t, scans, 1)
+	assert.Equal(t, "nginx:1.26", scans[0].command.ImageTag)
+	assert.Empty(t, b.resumed())
+}
+
+func TestHTTPController_Maintenance(t *testing.T) {
+	scanService := recordingScanService{
+		MockScanService: services.NewMockScanService(true),
+		commands:        make(chan domain.ScanCommand, 10),
+	}
+	h := HTTPController{
+		background:  newBackgroundScans(),
+		limiter:     newConcurrencyLimiter(1),
+		pending:     newPendingScans(),
+		scanService: scanService,
+		workerPool:  workerpool.New(1),
+	}
+	defer h.Shutdown()
+	router := gin.Default()
+	router.GET("/v1/maintenance", h.Maintenance)
+	router.POST("/v1/maintenance", h.EnterMaintenance)
+	router.DELETE("/v1/maintenance", h.LeaveMaintenance)
+	request := func(method, body string) (int, domain.MaintenanceStatus) {
+		req, _ := http.NewRequest(method, "/v1/maintenance", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var status domain.MaintenanceStatus
+		_ = json.Unmarshal(w.Body.Bytes(), &status)
+		return w.Code, status
+	}
+
+	code, status := request(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, status.Paused)
+	code, _ = request(http.MethodPost, `{"until":"2020-01-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, status = request(http.MethodPost, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, status.Paused)
+	code, status = request(http.MethodPost, `{"reason":"quarter close"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "quarter close", status.Reason)
+
+	// the scheduled runs are skipped
+	client := fake.NewSimpleClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpec("nginx:1.14.1")}},
+	})
+	h.scanScheduled(context.TODO(), client, "minikube", nil, ScanSchedule{Default: "@daily"}, "@daily")
+	assert.Empty(t, scanService.commands)
+
+	// the scans triggered by workload changes are deferred, then submitted once the maintenance mode is left
+	command := domain.ScanCommand{Wlid: "wlid://cluster-minikube/namespace-default/deployment-nginx", ContainerName: "nginx", ImageTag: "nginx:1.25"}
+	assert.True(t, h.background.deferScan(context.TODO(), command))
+	code, status = request(http.MethodDelete, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, status.Paused)
+	assert.Equal(t, 0, status.Deferred)
+	select {
+	case submitted := <-scanService.commands:
+		assert.Equal(t, command.Wlid, submitted.Wlid)
+	case <-time.After(5 * time.Second):
+		t.Fatal("deferred scan not submitted")
+	}
+}
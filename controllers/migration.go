@@ -0,0 +1,18 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// MigrateSBOMs upgrades the stored SBOMs of older schema versions in place, it is meant to run in the background
+func (h HTTPController) MigrateSBOMs(ctx context.Context) {
+	_, err := h.scanService.MigrateSBOMs(ctx)
+	if err != nil && !errors.Is(err, domain.ErrNoSBOMMigration) {
+		logger.L().Ctx(ctx).Warning("SBOM migration error", helpers.Error(err))
+	}
+}
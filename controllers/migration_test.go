@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+)
+
+type migratingScanService struct {
+	*services.MockScanService
+	calls chan struct{}
+}
+
+func (m migratingScanService) MigrateSBOMs(context.Context) (domain.SBOMMigrationReport, error) {
+	m.calls <- struct{}{}
+	return domain.SBOMMigrationReport{}, domain.ErrNoSBOMMigration
+}
+
+func TestHTTPController_MigrateSBOMs(t *testing.T) {
+	scanService := migratingScanService{
+		MockScanService: services.NewMockScanService(true),
+		calls:           make(chan struct{}, 1),
+	}
+	HTTPController{scanService: scanService}.MigrateSBOMs(context.TODO())
+	assert.Len(t, scanService.calls, 1)
+
+	// errors are only logged
+	HTTPController{scanService: services.NewMockScanService(false)}.MigrateSBOMs(context.TODO())
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/armosec/armoapi-go/apis"
+	"github.com/gin-gonic/gin"
+	apiv1 "github.com/kubescape/kubevuln/api/v1"
+)
+
+// OpenAPI returns the OpenAPI document of the HTTP API
+func (h HTTPController) OpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", apiv1.OpenAPI)
+}
+
+// RegisterRoutes maps the handlers of the controller to the paths of the OpenAPI document, the scan commands go
+// through the given middlewares
+func (h HTTPController) RegisterRoutes(router gin.IRouter, middlewares ...gin.HandlerFunc) {
+	router.GET("/openapi.json", h.OpenAPI)
+	router.GET("/v1/liveness", h.Alive)
+	router.GET("/v1/readiness", h.Ready)
+	router.GET("/v1/cache", h.Cache)
+	router.GET("/v1/coverage", h.Coverage)
+	router.GET("/v1/diff", h.Diff)
+	router.GET("/v1/failures", h.ScanFailures)
+	router.GET("/v1/config", h.Config)
+	router.GET("/v1/progress", h.Progress)
+	router.GET("/v1/scans/:id/events", h.ScanEvents)
+	router.GET("/v1/selftest", h.SelfTest)
+	router.GET("/v1/version", h.Version)
+	router.GET("/debug/pprof/", h.Pprof)
+	router.GET("/debug/pprof/:profile", h.Pprof)
+
+	group := router.Group(apis.VulnerabilityScanCommandVersion)
+	{
+		group.Use(middlewares...)
+		group.POST("/"+apis.SBOMCalculationCommandPath, h.GenerateSBOM)
+		group.POST("/"+apis.ContainerScanCommandPath, h.ScanCVE)
+		group.POST("/"+apis.RegistryScanCommandPath, h.ScanRegistry)
+		group.POST("/scanRepository", h.ScanRepository)
+		group.POST("/scanBatch", h.ScanBatch)
+		group.POST("/scanPlan", h.ScanPlan)
+		group.POST("/scanLayerDiff", h.ScanLayerDiff)
+		group.POST("/deleteWorkload", h.DeleteWorkload)
+		group.POST("/registryWebhook", h.RegistryWebhook)
+		group.POST("/relaySBOM", h.RelaySBOM)
+		group.GET("/export", h.ExportResults)
+		group.GET("/report", h.Report)
+		group.GET("/bundle", h.ExportBundle)
+		group.POST("/bundle", h.ScanBundle)
+		group.POST("/bundle/results", h.ImportResults)
+		group.GET("/falsePositives", h.ListFalsePositives)
+		group.POST("/falsePositives", h.AddFalsePositive)
+		group.DELETE("/falsePositives/:id", h.DeleteFalsePositive)
+		group.GET("/triage", h.ListTriage)
+		group.POST("/triage", h.AddTriage)
+		group.DELETE("/triage/:id", h.DeleteTriage)
+		group.POST("/inventory", h.SyncInventory)
+		group.GET("/maintenance", h.Maintenance)
+		group.POST("/maintenance", h.EnterMaintenance)
+		group.DELETE("/maintenance", h.LeaveMaintenance)
+		group.POST("/profiles", h.CaptureProfile)
+	}
+}
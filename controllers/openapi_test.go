@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	apiv1 "github.com/kubescape/kubevuln/api/v1"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pathParameters = regexp.MustCompile(`{(\w+)}`)
+
+func TestHTTPController_RegisterRoutes(t *testing.T) {
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(apiv1.OpenAPI, &doc))
+	var documented []string
+	for path, operations := range doc.Paths {
+		// the path parameters are matched by name by gin
+		path = pathParameters.ReplaceAllString(path, ":$1")
+		for method := range operations {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(documented)
+
+	h := NewHTTPController(services.NewMockScanService(true), 1)
+	defer h.Shutdown()
+	router := gin.New()
+	h.RegisterRoutes(router)
+	var registered []string
+	for _, route := range router.Routes() {
+		registered = append(registered, route.Method+" "+route.Path)
+	}
+	sort.Strings(registered)
+	assert.Equal(t, documented, registered)
+
+	// the webhook events are decoded with the documented fields
+	var properties []string
+	for property := range doc.Components.Schemas["RegistryEvent"].Properties {
+		properties = append(properties, property)
+	}
+	var fields []string
+	eventType := reflect.TypeOf(registryEvent{})
+	for i := 0; i < eventType.NumField(); i++ {
+		fields = append(fields, eventType.Field(i).Tag.Get("json"))
+	}
+	assert.ElementsMatch(t, fields, properties)
+
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, string(apiv1.OpenAPI), w.Body.String())
+}
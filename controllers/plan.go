@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	apiv1 "github.com/kubescape/kubevuln/api/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"schneider.vip/problem"
+)
+
+// ScanPlan unmarshalls a list of scan commands and returns which of their unique images need scanning
+func (h HTTPController) ScanPlan(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var request apiv1.ScanPlanRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("handler error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusBadRequest).WriteTo(c.Writer)
+		return
+	}
+
+	commands := make([]domain.ScanCommand, 0, len(request.Commands))
+	for _, websocketScanCommand := range request.Commands {
+		commands = append(commands, websocketScanCommandToScanCommand(websocketScanCommand))
+	}
+	plan, err := h.scanService.PlanScans(ctx, commands)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("scan plan error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusInternalServerError).WriteTo(c.Writer)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPController_ScanPlan(t *testing.T) {
+	tests := []struct {
+		name         string
+		scanService  ports.ScanService
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "invalid request",
+			scanService:  services.NewMockScanService(true),
+			body:         `{"commands": "nginx"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "{\"status\":400,\"title\":\"Bad Request\"}",
+		},
+		{
+			name:         "plan error",
+			scanService:  services.NewMockScanService(false),
+			body:         `{"commands": [{"imageTag": "nginx:1.14.1"}]}`,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "{\"status\":500,\"title\":\"Internal Server Error\"}",
+		},
+		{
+			name:         "plan",
+			scanService:  services.NewMockScanService(true),
+			body:         `{"commands": [{"imageTag": "nginx:1.14.1"}]}`,
+			expectedCode: http.StatusOK,
+			expectedBody: "{\"dbVersion\":\"\",\"commands\":0,\"invalid\":0,\"unique\":0,\"toScan\":0,\"images\":null}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{scanService: tt.scanService}
+			router := gin.Default()
+			path := "/v1/scanPlan"
+			router.POST(path, c.ScanPlan)
+			req, _ := http.NewRequest("POST", path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String(), w.Body.String())
+		})
+	}
+}
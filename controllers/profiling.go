@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	httppprof "net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"schneider.vip/problem"
+)
+
+const (
+	defaultProfileDuration = 30 * time.Second
+	maxProfileDuration     = 5 * time.Minute
+)
+
+// scanProfiler labels the goroutines of the running scans with their scanID and captures the profiles of a scan on
+// demand into dir; a nil scanProfiler runs the scans unlabeled
+type scanProfiler struct {
+	dir       string
+	mu        sync.Mutex
+	running   map[string]chan struct{}
+	capturing bool
+}
+
+// WithProfiling serves the net/http/pprof endpoints and the captures of the profiles of a running scan into dir
+func WithProfiling(dir string) HTTPControllerOption {
+	return func(h *HTTPController) {
+		h.profiler = &scanProfiler{dir: dir, running: map[string]chan struct{}{}}
+	}
+}
+
+// run runs a scan with its goroutines labeled with its scanID, tracking it until it is over
+func (p *scanProfiler) run(ctx context.Context, scan func(context.Context) error) error {
+	scanID, _ := ctx.Value(domain.ScanIDKey{}).(string)
+	if p == nil || scanID == "" {
+		return scan(ctx)
+	}
+	done := make(chan struct{})
+	p.mu.Lock()
+	p.running[scanID] = done
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.running, scanID)
+		p.mu.Unlock()
+		close(done)
+	}()
+	var err error
+	pprof.Do(ctx, pprof.Labels("scanID", scanID), func(ctx context.Context) {
+		err = scan(ctx)
+	})
+	return err
+}
+
+// capture records a CPU profile of the process until the scan is over, duration elapsed or ctx is done, then a heap
+// profile; the CPU profile can be restricted to the scan with "go tool pprof -tagfocus scanID=<scanID>"
+func (p *scanProfiler) capture(ctx context.Context, scanID string, duration time.Duration) (domain.ScanProfile, error) {
+	p.mu.Lock()
+	done, ok := p.running[scanID]
+	if ok && p.capturing {
+		p.mu.Unlock()
+		return domain.ScanProfile{}, domain.ErrProfileBusy
+	}
+	p.capturing = ok
+	p.mu.Unlock()
+	if !ok {
+		return domain.ScanProfile{}, domain.ErrScanNotFound
+	}
+	defer func() {
+		p.mu.Lock()
+		p.capturing = false
+		p.mu.Unlock()
+	}()
+
+	profile := domain.ScanProfile{ScanID: scanID, StartedAt: time.Now().UTC()}
+	name := filepath.Join(p.dir, fmt.Sprintf("%s-%d", scanID, profile.StartedAt.Unix()))
+	profile.CPUProfile = name + ".cpu.pprof"
+	cpu, err := os.Create(profile.CPUProfile)
+	if err != nil {
+		return domain.ScanProfile{}, err
+	}
+	defer cpu.Close()
+	// the CPU profile of /debug/pprof/profile may be running
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		_ = os.Remove(profile.CPUProfile)
+		return domain.ScanProfile{}, fmt.Errorf("%w: %v", domain.ErrProfileBusy, err)
+	}
+	timer := time.NewTimer(duration)
+	select {
+	case <-done:
+		profile.ScanDone = true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	timer.Stop()
+	pprof.StopCPUProfile()
+	profile.Seconds = time.Since(profile.StartedAt).Seconds()
+
+	profile.HeapProfile = name + ".heap.pprof"
+	heap, err := os.Create(profile.HeapProfile)
+	if err != nil {
+		return domain.ScanProfile{}, err
+	}
+	defer heap.Close()
+	if err := pprof.Lookup("heap").WriteTo(heap, 0); err != nil {
+		return domain.ScanProfile{}, err
+	}
+	return profile, nil
+}
+
+// Pprof serves the runtime profiles of net/http/pprof
+func (h HTTPController) Pprof(c *gin.Context) {
+	if h.profiler == nil {
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail(domain.ErrNoProfiling.Error())).WriteTo(c.Writer)
+		return
+	}
+	switch c.Param("profile") {
+	case "cmdline":
+		httppprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		httppprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		httppprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		httppprof.Trace(c.Writer, c.Request)
+	default:
+		// the index and the named profiles, such as heap or goroutine
+		httppprof.Index(c.Writer, c.Request)
+	}
+}
+
+// CaptureProfile captures the CPU and heap profiles of the running scan of the scanID query parameter into the
+// profiles directory, for the seconds query parameter at most or until the scan is over
+func (h HTTPController) CaptureProfile(c *gin.Context) {
+	ctx := c.Request.Context()
+	if h.profiler == nil {
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail(domain.ErrNoProfiling.Error())).WriteTo(c.Writer)
+		return
+	}
+	scanID := c.Query("scanID")
+	if scanID == "" {
+		_, _ = problem.Of(http.StatusBadRequest).Append(problem.Detail("missing scanID")).WriteTo(c.Writer)
+		return
+	}
+	duration := defaultProfileDuration
+	if value := c.Query("seconds"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 1 || time.Duration(seconds)*time.Second > maxProfileDuration {
+			_, _ = problem.Of(http.StatusBadRequest).Append(problem.Detailf("seconds must be between 1 and %d, got %q", int(maxProfileDuration.Seconds()), value)).WriteTo(c.Writer)
+			return
+		}
+		duration = time.Duration(seconds) * time.Second
+	}
+	profile, err := h.profiler.capture(ctx, scanID, duration)
+	switch {
+	case errors.Is(err, domain.ErrScanNotFound):
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detailf("no running scan %s", scanID)).WriteTo(c.Writer)
+		return
+	case errors.Is(err, domain.ErrProfileBusy):
+		_, _ = problem.Of(http.StatusConflict).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	case err != nil:
+		logger.L().Ctx(ctx).Error("profile capture error", helpers.Error(err),
+			helpers.String("scanID", scanID))
+		_, _ = problem.Of(http.StatusInternalServerError).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	}
+	logger.L().Info("scan profile captured",
+		helpers.String("scanID", scanID),
+		helpers.String("cpuProfile", profile.CPUProfile),
+		helpers.String("heapProfile", profile.HeapProfile))
+	c.JSON(http.StatusOK, profile)
+}
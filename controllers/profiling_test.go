@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanProfiler_run(t *testing.T) {
+	ctx := context.WithValue(context.TODO(), domain.ScanIDKey{}, "scanID")
+	var nilProfiler *scanProfiler
+	assert.ErrorIs(t, nilProfiler.run(ctx, func(context.Context) error { return domain.ErrMockError }), domain.ErrMockError)
+
+	p := &scanProfiler{dir: t.TempDir(), running: map[string]chan struct{}{}}
+	captured := make(chan domain.ScanProfile)
+	err := p.run(ctx, func(ctx context.Context) error {
+		go func() {
+			profile, err := p.capture(context.TODO(), "scanID", time.Minute)
+			assert.NoError(t, err)
+			captured <- profile
+		}()
+		// the scan is over once the capture started
+		require.Eventually(t, func() bool {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			return p.capturing
+		}, time.Second, 10*time.Millisecond)
+		return nil
+	})
+	assert.NoError(t, err)
+	profile := <-captured
+	assert.True(t, profile.ScanDone)
+	assert.Equal(t, "scanID", profile.ScanID)
+	for _, name := range []string{profile.CPUProfile, profile.HeapProfile} {
+		info, err := os.Stat(name)
+		require.NoError(t, err)
+		assert.NotZero(t, info.Size())
+	}
+	assert.Empty(t, p.running)
+}
+
+func TestHTTPController_CaptureProfile(t *testing.T) {
+	tests := []struct {
+		name         string
+		profiling    bool
+		running      string
+		capturing    bool
+		query        string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "not enabled",
+			query:        "?scanID=scanID",
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"detail":"profiling is not enabled","status":404,"title":"Not Found"}`,
+		},
+		{
+			name:         "missing scanID",
+			profiling:    true,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"detail":"missing scanID","status":400,"title":"Bad Request"}`,
+		},
+		{
+			name:         "invalid seconds",
+			profiling:    true,
+			query:        "?scanID=scanID&seconds=301",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"detail":"seconds must be between 1 and 300, got \"301\"","status":400,"title":"Bad Request"}`,
+		},
+		{
+			name:         "no running scan",
+			profiling:    true,
+			query:        "?scanID=scanID",
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"detail":"no running scan scanID","status":404,"title":"Not Found"}`,
+		},
+		{
+			name:         "capture running",
+			profiling:    true,
+			running:      "scanID",
+			capturing:    true,
+			query:        "?scanID=scanID",
+			expectedCode: http.StatusConflict,
+			expectedBody: `{"detail":"a CPU profile is already being captured","status":409,"title":"Conflict"}`,
+		},
+		{
+			name:         "captured",
+			profiling:    true,
+			running:      "scanID",
+			query:        "?scanID=scanID&seconds=1",
+			expectedCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := HTTPController{}
+			if tt.profiling {
+				WithProfiling(t.TempDir())(&h)
+				h.profiler.capturing = tt.capturing
+			}
+			if tt.running != "" {
+				h.profiler.running[tt.running] = make(chan struct{})
+			}
+			router := gin.Default()
+			router.POST("/v1/profiles", h.CaptureProfile)
+			req, _ := http.NewRequest("POST", "/v1/profiles"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+				return
+			}
+			var profile domain.ScanProfile
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &profile))
+			assert.False(t, profile.ScanDone)
+			assert.FileExists(t, profile.CPUProfile)
+			assert.FileExists(t, profile.HeapProfile)
+		})
+	}
+}
+
+func TestHTTPController_Pprof(t *testing.T) {
+	for _, profiling := range []bool{false, true} {
+		h := HTTPController{}
+		if profiling {
+			WithProfiling(t.TempDir())(&h)
+		}
+		router := gin.Default()
+		router.GET("/debug/pprof/:profile", h.Pprof)
+		req, _ := http.NewRequest("GET", "/debug/pprof/cmdline", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if profiling {
+			assert.Equal(t, http.StatusOK, w.Code)
+		} else {
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		}
+	}
+}
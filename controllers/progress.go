@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"schneider.vip/problem"
+)
+
+// WithProgressBroker injects the ProgressBroker whose events are streamed to the clients
+func WithProgressBroker(broker ports.ProgressBroker) HTTPControllerOption {
+	return func(h *HTTPController) {
+		h.progressBroker = broker
+	}
+}
+
+// Progress streams the progress of the scans as Server-Sent Events until the client disconnects,
+// optionally restricted to the scanID, wlid or imageSlug query parameters
+func (h HTTPController) Progress(c *gin.Context) {
+	if h.progressBroker == nil {
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail("progress reporting is not enabled")).WriteTo(c.Writer)
+		return
+	}
+	filter := domain.Progress{
+		ScanID:    c.Query("scanID"),
+		Wlid:      c.Query("wlid"),
+		ImageSlug: c.Query("imageSlug"),
+	}
+	events := h.progressBroker.Subscribe(c.Request.Context())
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+	c.Stream(func(io.Writer) bool {
+		progress, ok := <-events
+		if !ok {
+			return false
+		}
+		if matchesProgress(filter, progress) {
+			c.SSEvent("progress", progress)
+		}
+		return true
+	})
+}
+
+// matchesProgress tells whether the event belongs to the scans selected by the non-empty fields of filter
+func matchesProgress(filter, progress domain.Progress) bool {
+	return (filter.ScanID == "" || filter.ScanID == progress.ScanID) &&
+		(filter.Wlid == "" || filter.Wlid == progress.Wlid) &&
+		(filter.ImageSlug == "" || filter.ImageSlug == progress.ImageSlug)
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	v1 "github.com/kubescape/kubevuln/adapters/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPController_Progress_disabled(t *testing.T) {
+	c := HTTPController{}
+	router := gin.Default()
+	router.GET("/v1/progress", c.Progress)
+	req, _ := http.NewRequest("GET", "/v1/progress", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "{\"detail\":\"progress reporting is not enabled\",\"status\":404,\"title\":\"Not Found\"}", w.Body.String())
+}
+
+func TestHTTPController_Progress(t *testing.T) {
+	broker := v1.NewProgressBroker()
+	c := HTTPController{}
+	WithProgressBroker(broker)(&c)
+	router := gin.Default()
+	router.GET("/v1/progress", c.Progress)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/v1/progress?scanID=scan", nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// the headers are flushed once subscribed, only the events of the selected scan are streamed
+	broker.Publish(domain.Progress{ScanID: "other", Stage: domain.StagePull})
+	broker.Publish(domain.Progress{ScanID: "scan", Stage: domain.StageMatch, Current: 1})
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event:progress\n", line)
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Contains(t, line, `"scanID":"scan","stage":"match"`)
+}
+
+func Test_matchesProgress(t *testing.T) {
+	progress := domain.Progress{ScanID: "scan", Wlid: "wlid", ImageSlug: "slug"}
+	tests := []struct {
+		name   string
+		filter domain.Progress
+		want   bool
+	}{
+		{name: "no filter", want: true},
+		{name: "scanID", filter: domain.Progress{ScanID: "scan"}, want: true},
+		{name: "wlid and image", filter: domain.Progress{Wlid: "wlid", ImageSlug: "slug"}, want: true},
+		{name: "other scan", filter: domain.Progress{ScanID: "other"}},
+		{name: "other image", filter: domain.Progress{Wlid: "wlid", ImageSlug: "other"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchesProgress(tt.filter, progress))
+		})
+	}
+}
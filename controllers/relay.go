@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/k8s-interface/names"
+	apiv1 "github.com/kubescape/kubevuln/api/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"schneider.vip/problem"
+)
+
+// WithRelay enables the receiver of the SBOMs relayed by the edge clusters, authenticated by the tokens read from
+// tokensDir, holding a file per cluster named after it
+func WithRelay(tokensDir string) HTTPControllerOption {
+	return func(h *HTTPController) {
+		h.relay = &bearerTokens{tokensDir: tokensDir}
+	}
+}
+
+// RelaySBOM unmarshalls an SBOM relayed by an edge cluster, checks that its workload belongs to the cluster of the
+// bearer token and submits the matching of its vulnerabilities
+func (h HTTPController) RelaySBOM(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if h.relay == nil {
+		_, _ = problem.Of(http.StatusNotFound).WriteTo(c.Writer)
+		return
+	}
+	cluster, err := h.relay.authorize(c.Request)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("relayed SBOM rejected", helpers.Error(err))
+		_, _ = problem.Of(http.StatusUnauthorized).WriteTo(c.Writer)
+		return
+	}
+
+	var request apiv1.RelaySBOMRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("handler error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusBadRequest).WriteTo(c.Writer)
+		return
+	}
+
+	newScan := relaySBOMRequestToScanCommand(request, cluster)
+
+	details := problem.Detailf("Wlid=%s", newScan.Wlid)
+
+	ctx, err = h.scanService.ValidateScanRelayedSBOM(ctx, newScan)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("validation error", helpers.Error(err),
+			helpers.String("cluster", cluster),
+			helpers.String("wlid", newScan.Wlid),
+			helpers.String("imageSlug", newScan.ImageSlug))
+		writeValidationError(c, err, details)
+		return
+	}
+
+	_, _ = problem.Of(http.StatusOK).Append(details).WriteTo(c.Writer)
+
+	h.submit(ctx, domain.ScanKindScanRelayedSBOM, newScan)
+}
+
+func relaySBOMRequestToScanCommand(r apiv1.RelaySBOMRequest, cluster string) domain.ScanCommand {
+	command := domain.ScanCommand{
+		ImageHash:          r.ImageHash,
+		Wlid:               r.Wlid,
+		ImageTag:           r.ImageTag,
+		ImageTagNormalized: tools.NormalizeReference(r.ImageTag),
+		JobID:              r.JobID,
+		ContainerName:      r.ContainerName,
+		ParentJobID:        r.ParentJobID,
+		Args:               r.Args,
+		RelayCluster:       cluster,
+	}
+	if slug, err := names.ImageInfoToSlug(r.ImageTag, r.ImageHash); err == nil {
+		command.ImageSlug = slug
+	}
+	if val, ok := r.Args[domain.AttributeCallbackURL].(string); ok {
+		command.CallbackURL = val
+	}
+	if r.SBOM != nil {
+		command.RelayedSBOM = &domain.SBOM{
+			SBOMCreatorName:    "syft",
+			SBOMCreatorVersion: r.SBOMCreatorVersion,
+			Status:             r.SBOMStatus,
+			Content:            r.SBOM,
+		}
+	}
+	return command
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gammazero/workerpool"
+	"github.com/gin-gonic/gin"
+	apiv1 "github.com/kubescape/kubevuln/api/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deniedClusterScanService rejects the workloads of the other clusters
+type deniedClusterScanService struct {
+	*services.MockScanService
+}
+
+func (deniedClusterScanService) ValidateScanRelayedSBOM(ctx context.Context, _ domain.ScanCommand) (context.Context, error) {
+	return ctx, domain.ErrClusterDenied
+}
+
+func TestHTTPController_RelaySBOM(t *testing.T) {
+	tokensDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tokensDir, "edge-1"), []byte("token-1\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tokensDir, "edge-2"), []byte("token-2"), 0600))
+	const body = `{"wlid":"wlid://cluster-edge-1/namespace-default/deployment-nginx","imageTag":"nginx:1.14.1","imageHash":"nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7","sbom":{}}`
+	tests := []struct {
+		name         string
+		scanService  ports.ScanService
+		relay        bool
+		token        string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "relay not enabled",
+			scanService:  services.NewMockScanService(true),
+			token:        "token-1",
+			body:         body,
+			expectedCode: http.StatusNotFound,
+			expectedBody: "{\"status\":404,\"title\":\"Not Found\"}",
+		},
+		{
+			name:         "missing token",
+			scanService:  services.NewMockScanService(true),
+			relay:        true,
+			body:         body,
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: "{\"status\":401,\"title\":\"Unauthorized\"}",
+		},
+		{
+			name:         "invalid token",
+			scanService:  services.NewMockScanService(true),
+			relay:        true,
+			token:        "token-3",
+			body:         body,
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: "{\"status\":401,\"title\":\"Unauthorized\"}",
+		},
+		{
+			name:         "invalid request",
+			scanService:  services.NewMockScanService(true),
+			relay:        true,
+			token:        "token-1",
+			body:         "{",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "{\"status\":400,\"title\":\"Bad Request\"}",
+		},
+		{
+			name:         "cluster denied",
+			scanService:  deniedClusterScanService{services.NewMockScanService(true)},
+			relay:        true,
+			token:        "token-2",
+			body:         body,
+			expectedCode: http.StatusForbidden,
+			expectedBody: "{\"detail\":\"workload cluster does not match the relay token\",\"status\":403,\"title\":\"Forbidden\"}",
+		},
+		{
+			name:         "validation error",
+			scanService:  services.NewMockScanService(false),
+			relay:        true,
+			token:        "token-1",
+			body:         body,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "{\"detail\":\"Wlid=wlid://cluster-edge-1/namespace-default/deployment-nginx\",\"status\":500,\"title\":\"Internal Server Error\"}",
+		},
+		{
+			name:         "ready",
+			scanService:  services.NewMockScanService(true),
+			relay:        true,
+			token:        "token-1",
+			body:         body,
+			expectedCode: http.StatusOK,
+			expectedBody: "{\"detail\":\"Wlid=wlid://cluster-edge-1/namespace-default/deployment-nginx\",\"status\":200,\"title\":\"OK\"}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{
+				scanService: tt.scanService,
+				workerPool:  workerpool.New(1),
+			}
+			if tt.relay {
+				WithRelay(tokensDir)(&c)
+			}
+			router := gin.Default()
+			path := "/v1/relaySBOM"
+			router.POST(path, c.RelaySBOM)
+			req, _ := http.NewRequest("POST", path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String(), w.Body.String())
+		})
+	}
+}
+
+func Test_relaySBOMRequestToScanCommand(t *testing.T) {
+	sbom := &v1beta1.Document{}
+	got := relaySBOMRequestToScanCommand(apiv1.RelaySBOMRequest{
+		Wlid:               "wlid://cluster-edge-1/namespace-default/deployment-nginx",
+		ContainerName:      "nginx",
+		ImageTag:           "nginx:1.14.1",
+		ImageHash:          "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7",
+		SBOMCreatorVersion: "v0.101.1",
+		SBOM:               sbom,
+		Args:               map[string]interface{}{domain.AttributeCallbackURL: "http://operator/callback"},
+	}, "edge-1")
+	assert.Equal(t, "edge-1", got.RelayCluster)
+	assert.Equal(t, "http://operator/callback", got.CallbackURL)
+	assert.NotEmpty(t, got.ImageSlug)
+	require.NotNil(t, got.RelayedSBOM)
+	assert.Equal(t, "v0.101.1", got.RelayedSBOM.SBOMCreatorVersion)
+	assert.Same(t, sbom, got.RelayedSBOM.Content)
+}
@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"schneider.vip/problem"
+)
+
+// Report returns a self-contained HTML report of the latest scans of a workload (wlid), of a registry image (image)
+// or of the cluster, with their severity breakdown and fix recommendations
+func (h HTTPController) Report(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	scope := c.Query("wlid")
+	if image := c.Query("image"); scope == "" && image != "" {
+		// the registry scans are kept by normalized image
+		scope = tools.NormalizeReference(image)
+	}
+	report, err := h.scanService.HTMLReport(ctx, scope)
+	switch {
+	case errors.Is(err, domain.ErrScanNotFound):
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	case err != nil:
+		logger.L().Ctx(ctx).Error("report error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusInternalServerError).WriteTo(c.Writer)
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", report)
+}
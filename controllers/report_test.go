@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+)
+
+// scopedReportScanService renders the scope it is asked for
+type scopedReportScanService struct {
+	*services.MockScanService
+}
+
+func (scopedReportScanService) HTMLReport(_ context.Context, scope string) ([]byte, error) {
+	if scope == "wlid://cluster-minikube/namespace-default/deployment-redis" {
+		return nil, domain.ErrScanNotFound
+	}
+	return []byte(scope), nil
+}
+
+func TestHTTPController_Report(t *testing.T) {
+	tests := []struct {
+		name         string
+		scanService  ports.ScanService
+		query        string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "cluster",
+			scanService:  services.NewMockScanService(true),
+			expectedCode: http.StatusOK,
+			expectedBody: "<!DOCTYPE html>\n",
+		},
+		{
+			name:         "workload",
+			scanService:  scopedReportScanService{services.NewMockScanService(true)},
+			query:        "?wlid=wlid://cluster-minikube/namespace-default/deployment-nginx",
+			expectedCode: http.StatusOK,
+			expectedBody: "wlid://cluster-minikube/namespace-default/deployment-nginx",
+		},
+		{
+			name:         "normalized image",
+			scanService:  scopedReportScanService{services.NewMockScanService(true)},
+			query:        "?image=nginx:1.25",
+			expectedCode: http.StatusOK,
+			expectedBody: "docker.io/library/nginx:1.25",
+		},
+		{
+			name:         "unknown workload",
+			scanService:  scopedReportScanService{services.NewMockScanService(true)},
+			query:        "?wlid=wlid://cluster-minikube/namespace-default/deployment-redis",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "error",
+			scanService:  services.NewMockScanService(false),
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{scanService: tt.scanService}
+			router := gin.Default()
+			router.GET("/v1/report", c.Report)
+			req, _ := http.NewRequest(http.MethodGet, "/v1/report"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.expectedBody != "" {
+				assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
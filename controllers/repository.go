@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	apiv1 "github.com/kubescape/kubevuln/api/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"schneider.vip/problem"
+)
+
+// maxRepositorySlugPrefix bounds the readable part of the slugs of the repositories
+const maxRepositorySlugPrefix = 48
+
+var nonSlugCharacters = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ScanRepository unmarshalls a repository scan request and submits the scan of the repository at its ref
+func (h HTTPController) ScanRepository(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var request apiv1.RepositoryScanRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("handler error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusBadRequest).WriteTo(c.Writer)
+		return
+	}
+
+	newScan := repositoryScanRequestToScanCommand(request)
+
+	details := problem.Detailf("Repository=%s", newScan.RepositoryURL)
+
+	ctx, err = h.scanService.ValidateScanRepository(ctx, newScan)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("validation error", helpers.Error(err),
+			helpers.String("repository", newScan.RepositoryURL),
+			helpers.String("ref", newScan.RepositoryRef))
+		writeValidationError(c, err, details)
+		return
+	}
+
+	_, _ = problem.Of(http.StatusOK).Append(details).WriteTo(c.Writer)
+
+	h.submit(ctx, domain.ScanKindScanRepository, newScan)
+}
+
+func repositoryScanRequestToScanCommand(r apiv1.RepositoryScanRequest) domain.ScanCommand {
+	command := domain.ScanCommand{
+		Credentialslist: r.Credentialslist,
+		ImageSlug:       repositorySlug(r.URL, r.Ref),
+		JobID:           r.JobID,
+		ParentJobID:     r.ParentJobID,
+		RepositoryRef:   r.Ref,
+		RepositoryURL:   r.URL,
+		Args:            r.Args,
+	}
+	if val, ok := r.Args[domain.AttributeCallbackURL].(string); ok {
+		command.CallbackURL = val
+	}
+	return command
+}
+
+// repositorySlug names the scan results of a repository at a ref after its host and path, suffixed by a hash telling
+// apart the refs and the names shortened or differing only by their special characters
+func repositorySlug(repositoryURL, ref string) string {
+	if repositoryURL == "" {
+		return ""
+	}
+	name := repositoryURL
+	if u, err := url.Parse(repositoryURL); err == nil && u.Host != "" {
+		name = u.Host + u.Path
+	}
+	name = strings.Trim(nonSlugCharacters.ReplaceAllString(strings.ToLower(strings.TrimSuffix(name, ".git")), "-"), "-")
+	if len(name) > maxRepositorySlugPrefix {
+		name = strings.TrimRight(name[:maxRepositorySlugPrefix], "-")
+	}
+	sum := sha256.Sum256([]byte(repositoryURL + "@" + ref))
+	return name + "-" + hex.EncodeToString(sum[:])[:8]
+}
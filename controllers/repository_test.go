@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gammazero/workerpool"
+	"github.com/gin-gonic/gin"
+	apiv1 "github.com/kubescape/kubevuln/api/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+)
+
+// invalidRepositoryScanService rejects the repositories
+type invalidRepositoryScanService struct {
+	*services.MockScanService
+}
+
+func (invalidRepositoryScanService) ValidateScanRepository(ctx context.Context, _ domain.ScanCommand) (context.Context, error) {
+	return ctx, domain.ErrInvalidRepository
+}
+
+func TestHTTPController_ScanRepository(t *testing.T) {
+	tests := []struct {
+		name         string
+		scanService  ports.ScanService
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "invalid request",
+			scanService:  services.NewMockScanService(true),
+			body:         "{",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "{\"status\":400,\"title\":\"Bad Request\"}",
+		},
+		{
+			name:         "invalid repository",
+			scanService:  invalidRepositoryScanService{services.NewMockScanService(true)},
+			body:         `{"url":"git@github.com:kubescape/kubevuln.git"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "{\"detail\":\"invalid repository, expected an https URL\",\"status\":400,\"title\":\"Bad Request\"}",
+		},
+		{
+			name:         "validation error",
+			scanService:  services.NewMockScanService(false),
+			body:         `{"url":"https://github.com/kubescape/kubevuln"}`,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "{\"detail\":\"Repository=https://github.com/kubescape/kubevuln\",\"status\":500,\"title\":\"Internal Server Error\"}",
+		},
+		{
+			name:         "ready",
+			scanService:  services.NewMockScanService(true),
+			body:         `{"url":"https://github.com/kubescape/kubevuln","ref":"v0.2.0"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: "{\"detail\":\"Repository=https://github.com/kubescape/kubevuln\",\"status\":200,\"title\":\"OK\"}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{
+				scanService: tt.scanService,
+				workerPool:  workerpool.New(1),
+			}
+			router := gin.Default()
+			path := "/v1/scanRepository"
+			router.POST(path, c.ScanRepository)
+			req, _ := http.NewRequest("POST", path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String(), w.Body.String())
+		})
+	}
+}
+
+func Test_repositoryScanRequestToScanCommand(t *testing.T) {
+	command := repositoryScanRequestToScanCommand(apiv1.RepositoryScanRequest{
+		URL:         "https://github.com/kubescape/kubevuln.git",
+		Ref:         "main",
+		JobID:       "jobID",
+		ParentJobID: "parentJobID",
+		Args:        map[string]interface{}{domain.AttributeCallbackURL: "https://example.com/callback"},
+	})
+	assert.Equal(t, "https://github.com/kubescape/kubevuln.git", command.RepositoryURL)
+	assert.Equal(t, "main", command.RepositoryRef)
+	assert.Equal(t, "jobID", command.JobID)
+	assert.Equal(t, "parentJobID", command.ParentJobID)
+	assert.Equal(t, "https://example.com/callback", command.CallbackURL)
+	assert.Regexp(t, `^github-com-kubescape-kubevuln-[0-9a-f]{8}$`, command.ImageSlug)
+}
+
+func Test_repositorySlug(t *testing.T) {
+	main := repositorySlug("https://github.com/kubescape/kubevuln", "main")
+	assert.Regexp(t, `^github-com-kubescape-kubevuln-[0-9a-f]{8}$`, main)
+	// the refs and the URLs differing by their special characters have their own slugs
+	assert.NotEqual(t, main, repositorySlug("https://github.com/kubescape/kubevuln", "release"))
+	assert.NotEqual(t, main, repositorySlug("https://github.com/kubescape/kube.vuln", "main"))
+	long := repositorySlug("https://gitlab.example.com/"+strings.Repeat("group/", 20)+"project", "")
+	assert.LessOrEqual(t, len(long), maxRepositorySlugPrefix+9)
+	assert.Empty(t, repositorySlug("", "main"))
+}
@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/k8s-interface/names"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ScheduleDisabled excludes a namespace from the scheduled scans
+const ScheduleDisabled = "disabled"
+
+// ScanSchedule configures the periodic scans of the images of the workloads
+type ScanSchedule struct {
+	// Concurrency caps the scans of a scheduled run submitted at once, 1 when lower
+	Concurrency int
+	// Default is the cron expression of the namespaces without their own, none are scanned when empty
+	Default string
+	// Inventory lists the running images instead of the workloads of the cluster when set, pinning their digests
+	Inventory ports.WorkloadLister
+	// Jitter is the maximum random delay of the start of each run, spreading the load of several replicas and clusters
+	Jitter time.Duration
+	// Namespaces overrides the cron expression of some namespaces, ScheduleDisabled excludes them
+	Namespaces map[string]string
+}
+
+// specFor returns the cron expression of a namespace, empty if it is not scheduled
+func (s ScanSchedule) specFor(namespace string) string {
+	spec, ok := s.Namespaces[namespace]
+	if !ok {
+		spec = s.Default
+	}
+	if spec == ScheduleDisabled {
+		return ""
+	}
+	return spec
+}
+
+// ScheduleScans scans again the images of the Deployments, StatefulSets and DaemonSets of each namespace, or of the
+// inventory posted by the operator, on its cron expression, independently of the schedule of the operator; image tags
+// are pinned to digests with resolver when possible, a run still in progress skips the next one, it blocks until ctx is done
+func (h HTTPController) ScheduleScans(ctx context.Context, client kubernetes.Interface, clusterName string, resolver ports.ImageResolver, schedule ScanSchedule) error {
+	specs := map[string]bool{}
+	if schedule.Default != "" && schedule.Default != ScheduleDisabled {
+		specs[schedule.Default] = true
+	}
+	for _, spec := range schedule.Namespaces {
+		if spec != "" && spec != ScheduleDisabled {
+			specs[spec] = true
+		}
+	}
+	scheduler := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DiscardLogger)))
+	for spec := range specs {
+		spec := spec
+		_, err := scheduler.AddFunc(spec, func() {
+			h.scanScheduled(ctx, client, clusterName, resolver, schedule, spec)
+		})
+		if err != nil {
+			return fmt.Errorf("invalid scan schedule %q: %w", spec, err)
+		}
+	}
+	scheduler.Start()
+	logger.L().Info("scheduled image scans", helpers.Int("schedules", len(specs)))
+	<-ctx.Done()
+	<-scheduler.Stop().Done()
+	return nil
+}
+
+// scanScheduled submits a scan of each container of the workloads of the namespaces scheduled on spec, at most
+// schedule.Concurrency at once, and returns once they are all processed
+func (h HTTPController) scanScheduled(ctx context.Context, client kubernetes.Interface, clusterName string, resolver ports.ImageResolver, schedule ScanSchedule, spec string) {
+	if schedule.Jitter > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(rand.Int63n(int64(schedule.Jitter)))):
+		}
+	}
+	if h.background.paused() {
+		logger.L().Info("background scans paused, skipping scheduled scans", helpers.String("schedule", spec))
+		return
+	}
+	images, err := scheduledImages(ctx, client, schedule)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to list workloads for scheduled scans", helpers.Error(err))
+		return
+	}
+	concurrency := schedule.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var submitted int
+	for _, image := range images {
+		if schedule.specFor(image.Namespace) != spec {
+			continue
+		}
+		// the run stops once the background scans are paused
+		if h.background.paused() {
+			wg.Wait()
+			logger.L().Info("background scans paused, stopping scheduled scans",
+				helpers.String("schedule", spec),
+				helpers.Int("scans", submitted))
+			return
+		}
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case slots <- struct{}{}:
+		}
+		command := scheduledScanCommand(ctx, resolver, clusterName, image)
+		scanCtx, err := h.scanService.ValidateScanCVE(ctx, command)
+		if err != nil {
+			// the workloads opted out of the scans and the trusted images are already reported by the service
+			if !skippedScan(err) {
+				logger.L().Ctx(ctx).Warning("validation error", helpers.Error(err),
+					helpers.String("wlid", command.Wlid),
+					helpers.String("imageTag", command.ImageTag))
+			}
+			<-slots
+			continue
+		}
+		wg.Add(1)
+		h.submitWithDone(scanCtx, domain.ScanKindScanCVE, command, func() {
+			<-slots
+			wg.Done()
+		})
+		submitted++
+	}
+	wg.Wait()
+	logger.L().Info("scheduled scans done",
+		helpers.String("schedule", spec),
+		helpers.Int("scans", submitted))
+}
+
+// scheduledImages returns the images of the inventory posted by the operator when set, the images of the containers of
+// the workloads of the cluster otherwise
+func scheduledImages(ctx context.Context, client kubernetes.Interface, schedule ScanSchedule) ([]domain.WorkloadImage, error) {
+	if schedule.Inventory != nil {
+		return schedule.Inventory.ListWorkloadImages(ctx)
+	}
+	workloads, err := listWorkloads(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	var images []domain.WorkloadImage
+	for _, workload := range workloads {
+		kind, namespace, name, pod, _ := workloadPodSpec(workload)
+		for _, container := range append(pod.InitContainers, pod.Containers...) {
+			images = append(images, domain.WorkloadImage{
+				Namespace:     namespace,
+				Kind:          kind,
+				Name:          name,
+				ContainerName: container.Name,
+				ImageTag:      container.Image,
+			})
+		}
+	}
+	return images, nil
+}
+
+// scheduledScanCommand returns the scan command of a scheduled image, its tag is pinned to the digest of the inventory
+// when known and resolved with resolver otherwise
+func scheduledScanCommand(ctx context.Context, resolver ports.ImageResolver, clusterName string, image domain.WorkloadImage) domain.ScanCommand {
+	command := workloadCommand(clusterName, image.Kind, image.Namespace, image.Name, image.ContainerName, image.ImageTag)
+	command.ImageHash = image.ImageHash
+	if command.ImageHash == "" {
+		resolved, err := workloadScanCommand(ctx, resolver, clusterName, image.Kind, image.Namespace, image.Name, image.ContainerName, image.ImageTag, nil)
+		if err == nil {
+			return resolved
+		}
+		// the image is scanned by tag, the next scheduled run may resolve it
+		if resolver != nil {
+			logger.L().Ctx(ctx).Warning("scanning scheduled image by tag", helpers.Error(err),
+				helpers.String("imageTag", image.ImageTag))
+		}
+		command.ImageHash = command.ImageTagNormalized
+	}
+	if slug, err := names.ImageInfoToSlug(command.ImageTag, command.ImageHash); err == nil {
+		command.ImageSlug = slug
+	}
+	return command
+}
+
+// listWorkloads lists the Deployments, StatefulSets and DaemonSets of all namespaces
+func listWorkloads(ctx context.Context, client kubernetes.Interface) ([]interface{}, error) {
+	var workloads []interface{}
+	deployments, err := client.AppsV1().Deployments(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		workloads = append(workloads, &deployments.Items[i])
+	}
+	statefulSets, err := client.AppsV1().StatefulSets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		workloads = append(workloads, &statefulSets.Items[i])
+	}
+	daemonSets, err := client.AppsV1().DaemonSets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		workloads = append(workloads, &daemonSets.Items[i])
+	}
+	return workloads, nil
+}
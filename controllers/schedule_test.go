@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gammazero/workerpool"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestScanSchedule_specFor(t *testing.T) {
+	schedule := ScanSchedule{
+		Default:    "@daily",
+		Namespaces: map[string]string{"kube-system": ScheduleDisabled, "payments": "@every 6h"},
+	}
+	assert.Equal(t, "@daily", schedule.specFor("default"))
+	assert.Equal(t, "", schedule.specFor("kube-system"))
+	assert.Equal(t, "@every 6h", schedule.specFor("payments"))
+	// without a default, only the listed namespaces are scheduled
+	schedule.Default = ""
+	assert.Equal(t, "", schedule.specFor("default"))
+}
+
+func TestHTTPController_ScheduleScans_invalid(t *testing.T) {
+	h := HTTPController{scanService: services.NewMockScanService(true), workerPool: workerpool.New(1)}
+	err := h.ScheduleScans(context.TODO(), fake.NewSimpleClientset(), "minikube", nil, ScanSchedule{Default: "daily"})
+	assert.ErrorContains(t, err, `invalid scan schedule "daily"`)
+}
+
+func TestHTTPController_scanScheduled(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpec("nginx:1.14.1", "envoy:1.25")}},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy", Namespace: "kube-system"},
+			Spec:       appsv1.DaemonSetSpec{Template: corev1.PodTemplateSpec{Spec: podSpec("k8s.gcr.io/kube-proxy:v1.24.3")}},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "payments"},
+			Spec:       appsv1.StatefulSetSpec{Template: corev1.PodTemplateSpec{Spec: podSpec("redis:7.0")}},
+		},
+	)
+	schedule := ScanSchedule{
+		Concurrency: 1,
+		Default:     "@daily",
+		Namespaces:  map[string]string{"kube-system": ScheduleDisabled, "payments": "@every 6h"},
+	}
+	tests := []struct {
+		spec      string
+		wantWlids []string
+	}{
+		{
+			spec:      "@daily",
+			wantWlids: []string{"wlid://cluster-minikube/namespace-default/deployment-nginx", "wlid://cluster-minikube/namespace-default/deployment-nginx"},
+		},
+		{
+			spec:      "@every 6h",
+			wantWlids: []string{"wlid://cluster-minikube/namespace-payments/statefulset-redis"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			scanService := recordingScanService{
+				MockScanService: services.NewMockScanService(true),
+				commands:        make(chan domain.ScanCommand, 10),
+			}
+			h := HTTPController{
+				limiter:     newConcurrencyLimiter(1),
+				pending:     newPendingScans(),
+				scanService: scanService,
+				workerPool:  workerpool.New(2),
+			}
+			// returns once the scans are processed
+			h.scanScheduled(context.TODO(), client, "minikube", nil, schedule, tt.spec)
+			assert.Equal(t, 0, h.workerPool.WaitingQueueSize())
+			close(scanService.commands)
+			var wlids []string
+			for command := range scanService.commands {
+				wlids = append(wlids, command.Wlid)
+			}
+			assert.Equal(t, tt.wantWlids, wlids)
+			h.Shutdown()
+		})
+	}
+}
+
+func TestHTTPController_scanScheduled_inventory(t *testing.T) {
+	inventory := repositories.NewMemoryInventory()
+	require.NoError(t, inventory.StoreInventory(context.TODO(), []domain.WorkloadImage{
+		{Namespace: "default", Kind: "Deployment", Name: "nginx", ContainerName: "nginx", ImageTag: "nginx:1.25",
+			ImageHash: "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"},
+		{Namespace: "kube-system", Kind: "DaemonSet", Name: "kube-proxy", ContainerName: "kube-proxy", ImageTag: "k8s.gcr.io/kube-proxy:v1.24.3"},
+	}))
+	scanService := recordingScanService{
+		MockScanService: services.NewMockScanService(true),
+		commands:        make(chan domain.ScanCommand, 10),
+	}
+	h := HTTPController{
+		limiter:     newConcurrencyLimiter(1),
+		pending:     newPendingScans(),
+		scanService: scanService,
+		workerPool:  workerpool.New(2),
+	}
+	// the workloads of the cluster are not listed
+	schedule := ScanSchedule{Concurrency: 1, Default: "@daily", Inventory: inventory}
+	h.scanScheduled(context.TODO(), fake.NewSimpleClientset(), "minikube", nil, schedule, "@daily")
+	close(scanService.commands)
+	var commands []domain.ScanCommand
+	for command := range scanService.commands {
+		commands = append(commands, command)
+	}
+	require.Len(t, commands, 2)
+	assert.Equal(t, "wlid://cluster-minikube/namespace-default/deployment-nginx", commands[0].Wlid)
+	assert.Equal(t, "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7", commands[0].ImageHash)
+	assert.Equal(t, "wlid://cluster-minikube/namespace-kube-system/daemonset-kube-proxy", commands[1].Wlid)
+	assert.Equal(t, "k8s.gcr.io/kube-proxy:v1.24.3", commands[1].ImageHash)
+	h.Shutdown()
+}
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"schneider.vip/problem"
+)
+
+// SelfTest scans the reference image end-to-end and returns the outcome of each stage, with the status 503 when
+// a stage failed
+func (h HTTPController) SelfTest(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	report, err := h.scanService.SelfTest(ctx)
+	switch {
+	case errors.Is(err, domain.ErrNoSelfTest):
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+		return
+	case err != nil:
+		logger.L().Ctx(ctx).Error("self-test error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusInternalServerError).WriteTo(c.Writer)
+		return
+	case !report.Success:
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// selfTestScanService returns a static self-test report
+type selfTestScanService struct {
+	ports.ScanService
+	report domain.SelfTestReport
+	err    error
+}
+
+func (s selfTestScanService) SelfTest(context.Context) (domain.SelfTestReport, error) {
+	return s.report, s.err
+}
+
+func TestHTTPController_SelfTest(t *testing.T) {
+	failed := domain.SelfTestReport{
+		Stages: []domain.SelfTestStage{
+			{Name: domain.SelfTestDatabase, Success: true, Duration: "1ms"},
+			{Name: domain.SelfTestImage, Duration: "2ms", Error: "connection refused"},
+		},
+	}
+	tests := []struct {
+		name         string
+		scanService  selfTestScanService
+		expectedCode int
+		wantReport   *domain.SelfTestReport
+	}{
+		{
+			name:         "not enabled",
+			scanService:  selfTestScanService{err: domain.ErrNoSelfTest},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "error",
+			scanService:  selfTestScanService{err: domain.ErrMockError},
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:         "failed stage",
+			scanService:  selfTestScanService{report: failed},
+			expectedCode: http.StatusServiceUnavailable,
+			wantReport:   &failed,
+		},
+		{
+			name:         "success",
+			scanService:  selfTestScanService{report: domain.SelfTestReport{Success: true}},
+			expectedCode: http.StatusOK,
+			wantReport:   &domain.SelfTestReport{Success: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := HTTPController{scanService: tt.scanService}
+			router := gin.Default()
+			router.GET("/v1/selftest", h.SelfTest)
+			req, _ := http.NewRequest("GET", "/v1/selftest", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.wantReport == nil {
+				return
+			}
+			var report domain.SelfTestReport
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.Equal(t, *tt.wantReport, report)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errInvalidToken is returned for the bearer tokens matching none of the token files
+var errInvalidToken = errors.New("invalid bearer token")
+
+// bearerTokens authenticates the requests by their bearer token, tokensDir holds a file per client named after it and
+// containing its token, such as a mounted Secret, read again on each request to follow its rotations
+type bearerTokens struct {
+	tokensDir string
+}
+
+// authorize returns the client of the bearer token of the request
+func (r *bearerTokens) authorize(req *http.Request) (string, error) {
+	got, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || got == "" {
+		return "", errInvalidToken
+	}
+	entries, err := os.ReadDir(r.tokensDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the bearer tokens: %w", err)
+	}
+	for _, entry := range entries {
+		// skip the hidden entries of the mounted Secrets, such as ..data
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		token, err := os.ReadFile(filepath.Join(r.tokensDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		want := strings.TrimSpace(string(token))
+		if want != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1 {
+			return entry.Name(), nil
+		}
+	}
+	return "", errInvalidToken
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_bearerTokens_authorize(t *testing.T) {
+	tokensDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tokensDir, "edge-1"), []byte("token-1\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(tokensDir, "empty"), []byte("\n"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(tokensDir, "..data"), 0700))
+	r := &bearerTokens{tokensDir: tokensDir}
+	tests := []struct {
+		name          string
+		authorization string
+		want          string
+		wantErr       bool
+	}{
+		{name: "valid token", authorization: "Bearer token-1", want: "edge-1"},
+		{name: "not a bearer token", authorization: "token-1", wantErr: true},
+		{name: "empty token", authorization: "Bearer ", wantErr: true},
+		{name: "unknown token", authorization: "Bearer token-2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/relaySBOM", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			got, err := r.authorize(req)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"schneider.vip/problem"
+)
+
+// AddTriage unmarshalls the acknowledgement or the snooze of a finding of a workload and stores it, it applies to the
+// next scans of the workload
+func (h HTTPController) AddTriage(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var triage domain.Triage
+	err := c.ShouldBindJSON(&triage)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("handler error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusBadRequest).WriteTo(c.Writer)
+		return
+	}
+
+	triage, err = h.scanService.AddTriage(ctx, triage)
+	if err != nil {
+		writeTriageError(c, "triage error", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, triage)
+}
+
+// DeleteTriage removes the triage with the given id, its finding counts again with the next scans
+func (h HTTPController) DeleteTriage(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := h.scanService.DeleteTriage(ctx, c.Param("id"))
+	if err != nil {
+		writeTriageError(c, "triage deletion error", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListTriage returns the stored triage, of the findings of the given workload if any
+func (h HTTPController) ListTriage(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	triage, err := h.scanService.ListTriage(ctx, c.Query("wlid"))
+	if err != nil {
+		writeTriageError(c, "triage list error", err)
+		return
+	}
+	c.JSON(http.StatusOK, triage)
+}
+
+// writeTriageError answers a failed triage operation, triage not enabled or unknown is not found and incomplete triage
+// is a bad request
+func writeTriageError(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, domain.ErrNoTriage), errors.Is(err, domain.ErrTriageNotFound):
+		_, _ = problem.Of(http.StatusNotFound).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+	case errors.Is(err, domain.ErrInvalidTriage):
+		_, _ = problem.Of(http.StatusBadRequest).Append(problem.Detail(err.Error())).WriteTo(c.Writer)
+	default:
+		logger.L().Ctx(c.Request.Context()).Error(msg, helpers.Error(err))
+		_, _ = problem.Of(http.StatusInternalServerError).WriteTo(c.Writer)
+	}
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+)
+
+// triageErrorScanService fails the triage operations with err
+type triageErrorScanService struct {
+	*services.MockScanService
+	err error
+}
+
+func (s triageErrorScanService) AddTriage(context.Context, domain.Triage) (domain.Triage, error) {
+	return domain.Triage{}, s.err
+}
+
+func (s triageErrorScanService) DeleteTriage(context.Context, string) error {
+	return s.err
+}
+
+func (s triageErrorScanService) ListTriage(context.Context, string) ([]domain.Triage, error) {
+	return nil, s.err
+}
+
+func TestHTTPController_triage(t *testing.T) {
+	tests := []struct {
+		name                string
+		scanService         ports.ScanService
+		method              string
+		path                string
+		body                string
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "snooze",
+			scanService:         services.NewMockScanService(true),
+			method:              http.MethodPost,
+			path:                "/v1/triage",
+			body:                `{"wlid":"wlid://cluster-minikube/namespace-default/deployment-nginx","vulnerability":"CVE-2023-0286","state":"snoozed","until":"2030-01-01T00:00:00Z"}`,
+			expectedCode:        http.StatusCreated,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"id":"id","wlid":"wlid://cluster-minikube/namespace-default/deployment-nginx","vulnerability":"CVE-2023-0286","state":"snoozed","until":"2030-01-01T00:00:00Z","createdAt":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name:                "add invalid body",
+			scanService:         services.NewMockScanService(true),
+			method:              http.MethodPost,
+			path:                "/v1/triage",
+			body:                "{",
+			expectedCode:        http.StatusBadRequest,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"status":400,"title":"Bad Request"}`,
+		},
+		{
+			name:                "add incomplete",
+			scanService:         triageErrorScanService{services.NewMockScanService(true), domain.ErrInvalidTriage},
+			method:              http.MethodPost,
+			path:                "/v1/triage",
+			body:                `{"vulnerability":"CVE-2023-0286","state":"snoozed"}`,
+			expectedCode:        http.StatusBadRequest,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"detail":"a triage needs a workload, a vulnerability and an acknowledged or snoozed state, snoozed until a future date","status":400,"title":"Bad Request"}`,
+		},
+		{
+			name:                "add not enabled",
+			scanService:         triageErrorScanService{services.NewMockScanService(true), domain.ErrNoTriage},
+			method:              http.MethodPost,
+			path:                "/v1/triage",
+			body:                `{"wlid":"wlid://cluster-minikube/namespace-default/deployment-nginx","vulnerability":"CVE-2023-0286","state":"acknowledged"}`,
+			expectedCode:        http.StatusNotFound,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"detail":"triage is not enabled","status":404,"title":"Not Found"}`,
+		},
+		{
+			name:                "list",
+			scanService:         services.NewMockScanService(true),
+			method:              http.MethodGet,
+			path:                "/v1/triage?wlid=wlid://cluster-minikube/namespace-default/deployment-nginx",
+			expectedCode:        http.StatusOK,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `[]`,
+		},
+		{
+			name:                "list error",
+			scanService:         services.NewMockScanService(false),
+			method:              http.MethodGet,
+			path:                "/v1/triage",
+			expectedCode:        http.StatusInternalServerError,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"status":500,"title":"Internal Server Error"}`,
+		},
+		{
+			name:         "delete",
+			scanService:  services.NewMockScanService(true),
+			method:       http.MethodDelete,
+			path:         "/v1/triage/id",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:                "delete unknown",
+			scanService:         triageErrorScanService{services.NewMockScanService(true), domain.ErrTriageNotFound},
+			method:              http.MethodDelete,
+			path:                "/v1/triage/unknown",
+			expectedCode:        http.StatusNotFound,
+			expectedContentType: "application/problem+json",
+			expectedBody:        `{"detail":"triage not found","status":404,"title":"Not Found"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{scanService: tt.scanService}
+			router := gin.Default()
+			router.GET("/v1/triage", c.ListTriage)
+			router.POST("/v1/triage", c.AddTriage)
+			router.DELETE("/v1/triage/:id", c.DeleteTriage)
+			req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code, w.Code)
+			assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedBody, w.Body.String(), w.Body.String())
+		})
+	}
+}
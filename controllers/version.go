@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// Version returns the versions of the scanners, of their libraries and of the vulnerabilities database
+func (h HTTPController) Version(c *gin.Context) {
+	c.JSON(http.StatusOK, h.scanService.Version(c.Request.Context()))
+}
+
+// CheckSBOMCompatibility discards the stored SBOMs of other scanner versions and submits the generation of the SBOMs
+// of the running images among them
+func (h HTTPController) CheckSBOMCompatibility(ctx context.Context) {
+	report, err := h.scanService.CheckSBOMCompatibility(ctx)
+	switch {
+	case errors.Is(err, domain.ErrNoSBOMCheck):
+		return
+	case err != nil:
+		logger.L().Ctx(ctx).Warning("SBOM compatibility check error", helpers.Error(err))
+	}
+	for _, command := range report.Regenerate {
+		scanCtx, err := h.validate(ctx, domain.ScanKindGenerateSBOM, command)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to regenerate SBOM", helpers.Error(err),
+				helpers.String("imageSlug", command.ImageSlug))
+			continue
+		}
+		h.submit(scanCtx, domain.ScanKindGenerateSBOM, command)
+	}
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+)
+
+type incompatibleScanService struct {
+	*services.MockScanService
+	commands chan domain.ScanCommand
+}
+
+func (i incompatibleScanService) CheckSBOMCompatibility(context.Context) (domain.SBOMCompatibilityReport, error) {
+	return domain.SBOMCompatibilityReport{
+		Checked:      2,
+		Incompatible: 1,
+		Regenerate:   []domain.ScanCommand{{ImageSlug: "nginx-slug", ImageTag: "nginx:1.14.1"}},
+	}, nil
+}
+
+func (i incompatibleScanService) ValidateGenerateSBOM(ctx context.Context, workload domain.ScanCommand) (context.Context, error) {
+	i.commands <- workload
+	return ctx, nil
+}
+
+func TestHTTPController_Version(t *testing.T) {
+	c := HTTPController{scanService: services.NewMockScanService(true)}
+	router := gin.Default()
+	router.GET("/v1/version", c.Version)
+	req, _ := http.NewRequest("GET", "/v1/version", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{\"release\":\"mock\",\"sbomCreatorVersion\":\"\",\"cveScannerVersion\":\"\",\"cveDBVersion\":\"\",\"libraries\":null}", w.Body.String())
+}
+
+func TestHTTPController_CheckSBOMCompatibility(t *testing.T) {
+	scanService := incompatibleScanService{
+		MockScanService: services.NewMockScanService(true),
+		commands:        make(chan domain.ScanCommand, 1),
+	}
+	c := NewHTTPController(scanService, 1)
+	c.CheckSBOMCompatibility(context.TODO())
+	c.Shutdown()
+	assert.Equal(t, domain.ScanCommand{ImageSlug: "nginx-slug", ImageTag: "nginx:1.14.1"}, <-scanService.commands)
+
+	// errors are only logged
+	c = NewHTTPController(services.NewMockScanService(false), 1)
+	c.CheckSBOMCompatibility(context.TODO())
+	c.Shutdown()
+}
@@ -0,0 +1,328 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	wlidpkg "github.com/armosec/utils-k8s-go/wlid"
+	"github.com/docker/docker/api/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/k8s-interface/names"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/internal/tools"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// resumeInterval is how often the scans deferred while the background scans were paused are checked
+	resumeInterval = time.Minute
+	// watchWorkers is the number of workers handling the workload changes, which call the registries and the API server
+	watchWorkers = 4
+)
+
+// workloadDeletion is a deleted workload, queued for the watch workers
+type workloadDeletion struct {
+	wlid string
+}
+
+// workloadImageChange is a container image changed in the pod template of a workload, queued for the watch workers
+type workloadImageChange struct {
+	kind          string
+	namespace     string
+	name          string
+	containerName string
+	image         string
+}
+
+// WatchWorkloads scans the new images of Deployments, StatefulSets and DaemonSets as soon as their pod template
+// changes, without waiting for the operator to trigger a scan, and forgets them once deleted; image tags are pinned
+// to digests with resolver and the pull secrets of the workload, the images without a digest are not scanned;
+// it blocks until ctx is done
+func (h HTTPController) WatchWorkloads(ctx context.Context, client kubernetes.Interface, clusterName string, resolver ports.ImageResolver) error {
+	factory := informers.NewSharedInformerFactory(client, 0)
+	// the informer handlers only queue the changes, the workers resolve the digests, submit the scans and forget the
+	// deleted workloads
+	queue := workqueue.New()
+	handler := cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldKind, _, _, oldSpec, ok := workloadPodSpec(oldObj)
+			if !ok {
+				return
+			}
+			kind, namespace, name, spec, ok := workloadPodSpec(newObj)
+			if !ok || kind != oldKind {
+				return
+			}
+			for containerName, image := range changedImages(oldSpec, spec) {
+				queue.Add(workloadImageChange{kind: kind, namespace: namespace, name: name, containerName: containerName, image: image})
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			kind, namespace, name, _, ok := workloadPodSpec(obj)
+			if !ok {
+				return
+			}
+			queue.Add(workloadDeletion{wlid: wlidpkg.GetWLID(clusterName, namespace, strings.ToLower(kind), name)})
+		},
+	}
+	// the workers read the pull secrets of the workloads from the informer stores
+	stores := map[string]cache.Store{}
+	for kind, informer := range map[string]cache.SharedIndexInformer{
+		"Deployment":  factory.Apps().V1().Deployments().Informer(),
+		"StatefulSet": factory.Apps().V1().StatefulSets().Informer(),
+		"DaemonSet":   factory.Apps().V1().DaemonSets().Informer(),
+	} {
+		if _, err := informer.AddEventHandler(handler); err != nil {
+			return err
+		}
+		stores[kind] = informer.GetStore()
+	}
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+	for i := 0; i < watchWorkers; i++ {
+		go h.watchWorker(ctx, client, clusterName, resolver, queue, stores)
+	}
+	logger.L().Info("watching workloads for image changes")
+	// the scans deferred while the background scans were paused are submitted once they resume
+	ticker := time.NewTicker(resumeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			queue.ShutDown()
+			factory.Shutdown()
+			return nil
+		case <-ticker.C:
+			h.resumeDeferredScans()
+		}
+	}
+}
+
+// watchWorker handles the queued workload changes until the queue is shut down
+func (h HTTPController) watchWorker(ctx context.Context, client kubernetes.Interface, clusterName string, resolver ports.ImageResolver, queue workqueue.Interface, stores map[string]cache.Store) {
+	for {
+		item, shutdown := queue.Get()
+		if shutdown {
+			return
+		}
+		switch event := item.(type) {
+		case workloadImageChange:
+			h.workloadImageChanged(ctx, client, clusterName, resolver, stores[event.kind], event)
+		case workloadDeletion:
+			_ = h.deleteWorkload(ctx, event.wlid)
+		}
+		queue.Done(item)
+	}
+}
+
+// workloadImageChanged scans the changed image of a workload container, or defers its scan while the background
+// scans are paused
+func (h HTTPController) workloadImageChanged(ctx context.Context, client kubernetes.Interface, clusterName string, resolver ports.ImageResolver, store cache.Store, change workloadImageChange) {
+	obj, exists, err := store.GetByKey(change.namespace + "/" + change.name)
+	if err != nil || !exists {
+		return
+	}
+	_, _, _, spec, _ := workloadPodSpec(obj)
+	// the image changed again since, its own change is queued
+	if containerImage(spec, change.containerName) != change.image {
+		return
+	}
+	credentials := pullSecretCredentials(ctx, client, change.namespace, spec, change.image)
+	command, err := workloadScanCommand(ctx, resolver, clusterName, change.kind, change.namespace, change.name, change.containerName, change.image, credentials)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("skipping workload image scan", helpers.Error(err),
+			helpers.String("wlid", command.Wlid),
+			helpers.String("imageTag", change.image))
+		return
+	}
+	if h.background.deferScan(ctx, command) {
+		logger.L().Debug("background scans paused, deferring workload image scan",
+			helpers.String("wlid", command.Wlid),
+			helpers.String("imageTag", command.ImageTag))
+		return
+	}
+	h.scanWorkloadImage(ctx, command)
+}
+
+// scanWorkloadImage validates and submits a scan triggered by a workload image change
+func (h HTTPController) scanWorkloadImage(ctx context.Context, command domain.ScanCommand) {
+	scanCtx, err := h.scanService.ValidateScanCVE(ctx, command)
+	if err != nil {
+		// the workloads opted out of the scans and the trusted images are already reported by the service
+		if !skippedScan(err) {
+			logger.L().Ctx(ctx).Warning("validation error", helpers.Error(err),
+				helpers.String("wlid", command.Wlid),
+				helpers.String("imageTag", command.ImageTag))
+		}
+		return
+	}
+	logger.L().Info("workload image changed, scanning",
+		helpers.String("wlid", command.Wlid),
+		helpers.String("containerName", command.ContainerName),
+		helpers.String("imageTag", command.ImageTag))
+	h.submit(scanCtx, domain.ScanKindScanCVE, command)
+}
+
+// workloadPodSpec extracts the pod template of the supported workload kinds
+func workloadPodSpec(obj interface{}) (string, string, string, corev1.PodSpec, bool) {
+	switch workload := obj.(type) {
+	case *appsv1.Deployment:
+		return "Deployment", workload.Namespace, workload.Name, workload.Spec.Template.Spec, true
+	case *appsv1.StatefulSet:
+		return "StatefulSet", workload.Namespace, workload.Name, workload.Spec.Template.Spec, true
+	case *appsv1.DaemonSet:
+		return "DaemonSet", workload.Namespace, workload.Name, workload.Spec.Template.Spec, true
+	}
+	return "", "", "", corev1.PodSpec{}, false
+}
+
+// changedImages returns the images of the containers added or modified between two pod specs, by container name
+func changedImages(oldSpec, newSpec corev1.PodSpec) map[string]string {
+	previous := map[string]string{}
+	for _, container := range append(oldSpec.InitContainers, oldSpec.Containers...) {
+		previous[container.Name] = container.Image
+	}
+	changed := map[string]string{}
+	for _, container := range append(newSpec.InitContainers, newSpec.Containers...) {
+		if previous[container.Name] != container.Image {
+			changed[container.Name] = container.Image
+		}
+	}
+	return changed
+}
+
+// containerImage returns the image of a container of the pod spec
+func containerImage(spec corev1.PodSpec, containerName string) string {
+	for _, container := range append(spec.InitContainers, spec.Containers...) {
+		if container.Name == containerName {
+			return container.Image
+		}
+	}
+	return ""
+}
+
+// workloadScanCommand builds the scan command of a workload container, pinning the image to its digest with the
+// given registry credentials, an image without a digest fails as its scan could not be attributed
+func workloadScanCommand(ctx context.Context, resolver ports.ImageResolver, clusterName, kind, namespace, name, containerName, image string, credentials []types.AuthConfig) (domain.ScanCommand, error) {
+	command := workloadCommand(clusterName, kind, namespace, name, containerName, image)
+	command.Credentialslist = credentials
+	switch {
+	case strings.Contains(image, "@"):
+		command.ImageHash = command.ImageTagNormalized
+	case resolver == nil:
+		return command, errors.New("no image digest resolver")
+	default:
+		options := domain.RegistryOptions{}
+		for _, c := range credentials {
+			options.Credentials = append(options.Credentials, domain.RegistryCredentials{Authority: c.ServerAddress, Username: c.Username, Password: c.Password})
+		}
+		digest, err := resolver.ResolveDigest(ctx, command.ImageTagNormalized, options)
+		if err != nil {
+			return command, fmt.Errorf("failed to resolve image digest: %w", err)
+		}
+		command.ImageHash = digest
+	}
+	if slug, err := names.ImageInfoToSlug(command.ImageTag, command.ImageHash); err == nil {
+		command.ImageSlug = slug
+	}
+	return command, nil
+}
+
+// workloadCommand builds the scan command of a workload container, without the digest of its image
+func workloadCommand(clusterName, kind, namespace, name, containerName, image string) domain.ScanCommand {
+	return domain.ScanCommand{
+		ContainerName:      containerName,
+		ImageTag:           image,
+		ImageTagNormalized: tools.NormalizeReference(image),
+		Wlid:               wlidpkg.GetWLID(clusterName, namespace, strings.ToLower(kind), name),
+	}
+}
+
+// pullSecretCredentials returns the credentials of the image registry found in the image pull secrets of the pod spec
+func pullSecretCredentials(ctx context.Context, client kubernetes.Interface, namespace string, spec corev1.PodSpec, image string) []types.AuthConfig {
+	if len(spec.ImagePullSecrets) == 0 {
+		return nil
+	}
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return nil
+	}
+	registry := ref.Context().RegistryStr()
+	var credentials []types.AuthConfig
+	for _, secretRef := range spec.ImagePullSecrets {
+		secret, err := client.CoreV1().Secrets(namespace).Get(ctx, secretRef.Name, metav1.GetOptions{})
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to read image pull secret", helpers.Error(err),
+				helpers.String("namespace", namespace),
+				helpers.String("secret", secretRef.Name))
+			continue
+		}
+		auths, err := dockerConfigAuths(secret)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to parse image pull secret", helpers.Error(err),
+				helpers.String("namespace", namespace),
+				helpers.String("secret", secretRef.Name))
+			continue
+		}
+		for server, auth := range auths {
+			if registryHost(server) != registry {
+				continue
+			}
+			// the credentials are only sent to their registry
+			if auth.Username == "" && auth.Auth != "" {
+				if decoded, err := base64.StdEncoding.DecodeString(auth.Auth); err == nil {
+					auth.Username, auth.Password, _ = strings.Cut(string(decoded), ":")
+				}
+			}
+			if auth.Username == "" {
+				continue
+			}
+			credentials = append(credentials, types.AuthConfig{Username: auth.Username, Password: auth.Password, ServerAddress: registry})
+		}
+	}
+	return credentials
+}
+
+// dockerConfigAuths returns the registry credentials of an image pull secret, by registry
+func dockerConfigAuths(secret *corev1.Secret) (map[string]types.AuthConfig, error) {
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		var config struct {
+			Auths map[string]types.AuthConfig `json:"auths"`
+		}
+		err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config)
+		return config.Auths, err
+	case corev1.SecretTypeDockercfg:
+		var auths map[string]types.AuthConfig
+		err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths)
+		return auths, err
+	}
+	return nil, fmt.Errorf("unsupported secret type %s", secret.Type)
+}
+
+// registryHost returns the registry of a docker config entry, which may be a URL, as named by go-containerregistry
+func registryHost(server string) string {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	server, _, _ = strings.Cut(server, "/")
+	if server == "docker.io" {
+		return name.DefaultRegistry
+	}
+	return server
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gammazero/workerpool"
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type recordingScanService struct {
+	*services.MockScanService
+	commands chan domain.ScanCommand
+	deleted  chan string
+}
+
+func (r recordingScanService) DeleteWorkload(_ context.Context, wlid string) error {
+	r.deleted <- wlid
+	return nil
+}
+
+func (r recordingScanService) ValidateScanCVE(ctx context.Context, workload domain.ScanCommand) (context.Context, error) {
+	r.commands <- workload
+	return ctx, nil
+}
+
+func podSpec(images ...string) corev1.PodSpec {
+	spec := corev1.PodSpec{}
+	for i, image := range images {
+		spec.Containers = append(spec.Containers, corev1.Container{Name: []string{"app", "sidecar"}[i], Image: image})
+	}
+	return spec
+}
+
+func Test_changedImages(t *testing.T) {
+	assert.Empty(t, changedImages(podSpec("nginx:1.14.1"), podSpec("nginx:1.14.1")))
+	assert.Equal(t, map[string]string{"app": "nginx:1.14.2"}, changedImages(podSpec("nginx:1.14.1"), podSpec("nginx:1.14.2")))
+	assert.Equal(t, map[string]string{"sidecar": "envoy:1.25"}, changedImages(podSpec("nginx:1.14.1"), podSpec("nginx:1.14.1", "envoy:1.25")))
+}
+
+func Test_workloadScanCommand(t *testing.T) {
+	credentials := []types.AuthConfig{{Username: "user", Password: "secret", ServerAddress: "index.docker.io"}}
+	command, err := workloadScanCommand(context.TODO(), adapters.NewMockSBOMAdapter(false, false, false), "minikube", "Deployment", "default", "nginx", "app", "nginx:1.14.2", credentials)
+	assert.NoError(t, err)
+	assert.Equal(t, "wlid://cluster-minikube/namespace-default/deployment-nginx", command.Wlid)
+	assert.Equal(t, "docker.io/library/nginx:1.14.2", command.ImageTagNormalized)
+	assert.Equal(t, "docker.io/library/nginx:1.14.2@"+adapters.MockDigest, command.ImageHash)
+	assert.Equal(t, credentials, command.Credentialslist)
+	assert.NotEmpty(t, command.ImageSlug)
+	// images pinned to a digest need no resolution
+	command, err = workloadScanCommand(context.TODO(), nil, "minikube", "Deployment", "default", "nginx", "app", "nginx@"+adapters.MockDigest, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "docker.io/library/nginx@"+adapters.MockDigest, command.ImageHash)
+	// unresolvable images are not scanned
+	_, err = workloadScanCommand(context.TODO(), adapters.NewMockSBOMAdapter(true, false, false), "minikube", "DaemonSet", "kube-system", "kube-proxy", "kube-proxy", "k8s.gcr.io/kube-proxy:v1.24.3", nil)
+	assert.Error(t, err)
+	_, err = workloadScanCommand(context.TODO(), nil, "minikube", "DaemonSet", "kube-system", "kube-proxy", "kube-proxy", "k8s.gcr.io/kube-proxy:v1.24.3", nil)
+	assert.Error(t, err)
+}
+
+func Test_pullSecretCredentials(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{
+				"https://index.docker.io/v1/":{"auth":"` + base64.StdEncoding.EncodeToString([]byte("user:secret")) + `"},
+				"quay.io":{"username":"other","password":"other"}}}`)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "opaque", Namespace: "default"},
+			Type:       corev1.SecretTypeOpaque,
+		},
+	)
+	spec := podSpec("nginx:1.14.2")
+	spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "opaque"}, {Name: "missing"}, {Name: "registry"}}
+	// only the credentials of the image registry are used
+	assert.Equal(t, []types.AuthConfig{{Username: "user", Password: "secret", ServerAddress: "index.docker.io"}},
+		pullSecretCredentials(context.TODO(), client, "default", spec, "nginx:1.14.2"))
+	assert.Empty(t, pullSecretCredentials(context.TODO(), client, "default", spec, "ghcr.io/kubescape/kubevuln:latest"))
+}
+
+func TestHTTPController_WatchWorkloads(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{Spec: podSpec("nginx:1.14.1")},
+		},
+	}
+	client := fake.NewSimpleClientset(deployment)
+	scanService := recordingScanService{
+		MockScanService: services.NewMockScanService(true),
+		commands:        make(chan domain.ScanCommand, 1),
+	}
+	h := HTTPController{scanService: scanService, workerPool: workerpool.New(1)}
+	go func() {
+		_ = h.WatchWorkloads(ctx, client, "minikube", adapters.NewMockSBOMAdapter(false, false, false))
+	}()
+	// wait for the informers to sync before updating
+	time.Sleep(500 * time.Millisecond)
+	deployment.Spec.Template.Spec = podSpec("nginx:1.14.2")
+	_, err := client.AppsV1().Deployments("default").Update(ctx, deployment, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	select {
+	case command := <-scanService.commands:
+		assert.Equal(t, "wlid://cluster-minikube/namespace-default/deployment-nginx", command.Wlid)
+		assert.Equal(t, "app", command.ContainerName)
+		assert.Equal(t, "nginx:1.14.2", command.ImageTag)
+		assert.Equal(t, "docker.io/library/nginx:1.14.2@"+adapters.MockDigest, command.ImageHash)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no scan triggered")
+	}
+}
+
+func TestHTTPController_WatchWorkloads_delete(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	statefulSet := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "default"}}
+	client := fake.NewSimpleClientset(statefulSet)
+	scanService := recordingScanService{
+		MockScanService: services.NewMockScanService(true),
+		deleted:         make(chan string, 1),
+	}
+	h := HTTPController{scanService: scanService, workerPool: workerpool.New(1)}
+	go func() {
+		_ = h.WatchWorkloads(ctx, client, "minikube", nil)
+	}()
+	// wait for the informers to sync before deleting
+	time.Sleep(500 * time.Millisecond)
+	err := client.AppsV1().StatefulSets("default").Delete(ctx, "redis", metav1.DeleteOptions{})
+	assert.NoError(t, err)
+	select {
+	case wlid := <-scanService.deleted:
+		assert.Equal(t, "wlid://cluster-minikube/namespace-default/statefulset-redis", wlid)
+	case <-time.After(5 * time.Second):
+		t.Fatal("deletion not handled")
+	}
+}
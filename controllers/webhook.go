@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	wssc "github.com/armosec/armoapi-go/apis"
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/uuid"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"schneider.vip/problem"
+)
+
+// push event types of the Harbor webhooks, PUSH_ARTIFACT since Harbor 2.0
+const (
+	harborPushArtifact = "PUSH_ARTIFACT"
+	harborPushImage    = "pushImage"
+)
+
+// errNotSelected is reported for the pushed images outside of the repository filters of the webhook
+var errNotSelected = errors.New("repository is not selected by the registry webhook")
+
+// registryWebhook configures the receiver of the registry push events
+type registryWebhook struct {
+	repositories []string
+	secretFile   string
+}
+
+// registryEvent is the union of the Harbor and Quay push event payloads
+type registryEvent struct {
+	// Harbor
+	Type      string `json:"type"`
+	EventData struct {
+		Resources []struct {
+			Digest      string `json:"digest"`
+			ResourceURL string `json:"resource_url"`
+			Tag         string `json:"tag"`
+		} `json:"resources"`
+	} `json:"event_data"`
+	// Quay
+	DockerURL   string   `json:"docker_url"`
+	UpdatedTags []string `json:"updated_tags"`
+}
+
+// WithRegistryWebhook enables the receiver of the Harbor and Quay push events, scanning the pushed images of the
+// given repositories (all when empty), the requests must carry the secret read from secretFile
+func WithRegistryWebhook(repositories []string, secretFile string) HTTPControllerOption {
+	return func(h *HTTPController) {
+		webhook := &registryWebhook{secretFile: secretFile}
+		for _, repository := range repositories {
+			webhook.repositories = append(webhook.repositories, tools.NormalizeRegistry(repository))
+		}
+		h.registryWebhook = webhook
+	}
+}
+
+// RegistryWebhook unmarshalls a Harbor or Quay push event, validates and submits a registry scan of each pushed image
+// of the selected repositories under a single batch ID, and returns whether each image was accepted
+func (h HTTPController) RegistryWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if h.registryWebhook == nil {
+		_, _ = problem.Of(http.StatusNotFound).WriteTo(c.Writer)
+		return
+	}
+	if err := h.registryWebhook.authorize(c.Request); err != nil {
+		logger.L().Ctx(ctx).Warning("registry webhook rejected", helpers.Error(err))
+		_, _ = problem.Of(http.StatusUnauthorized).WriteTo(c.Writer)
+		return
+	}
+
+	var event registryEvent
+	err := c.ShouldBindJSON(&event)
+	if err != nil {
+		logger.L().Ctx(ctx).Error("handler error", helpers.Error(err))
+		_, _ = problem.Of(http.StatusBadRequest).WriteTo(c.Writer)
+		return
+	}
+
+	images := event.pushedImages()
+	report := domain.BatchReport{
+		BatchID: uuid.NewString(),
+		Items:   make([]domain.BatchItemStatus, 0, len(images)),
+	}
+	for i, image := range images {
+		var registryScanCommand wssc.RegistryScanCommand
+		registryScanCommand.ImageTag = image
+		newScan := registryScanCommandToScanCommand(registryScanCommand)
+		newScan.BatchID = report.BatchID
+		status := domain.BatchItemStatus{
+			Index:    i,
+			ImageTag: newScan.ImageTag,
+		}
+		err := h.registryWebhook.selects(image)
+		scanCtx := ctx
+		if err == nil {
+			scanCtx, err = h.validate(ctx, domain.ScanKindScanRegistry, newScan)
+		}
+		if err != nil {
+			logger.L().Ctx(ctx).Debug("pushed image not scanned", helpers.Error(err),
+				helpers.String("batchID", report.BatchID),
+				helpers.String("imageTag", newScan.ImageTag))
+			status.Error = err.Error()
+			report.Rejected++
+		} else {
+			h.submit(scanCtx, domain.ScanKindScanRegistry, newScan)
+			status.Accepted = true
+			report.Accepted++
+		}
+		report.Items = append(report.Items, status)
+	}
+	logger.L().Info("registry push event received",
+		helpers.String("batchID", report.BatchID),
+		helpers.Int("accepted", report.Accepted),
+		helpers.Int("rejected", report.Rejected))
+
+	c.JSON(http.StatusOK, report)
+}
+
+// authorize checks the secret of the request, Harbor sends it as the Authorization header and Quay in the token
+// query parameter of the notification URL
+func (w *registryWebhook) authorize(req *http.Request) error {
+	secret, err := os.ReadFile(w.secretFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the webhook secret: %w", err)
+	}
+	want := strings.TrimSpace(string(secret))
+	if want == "" {
+		return fmt.Errorf("the webhook secret is empty")
+	}
+	for _, got := range []string{
+		req.Header.Get("Authorization"),
+		strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "),
+		req.URL.Query().Get("token"),
+	} {
+		if subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1 {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid webhook secret")
+}
+
+// selects checks the repository of the image against the repository filters
+func (w *registryWebhook) selects(image string) error {
+	if len(w.repositories) == 0 {
+		return nil
+	}
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return fmt.Errorf("invalid image reference %q: %w", image, err)
+	}
+	repository := ref.Context().Name()
+	for _, selected := range w.repositories {
+		if repository == selected || strings.HasPrefix(repository, selected+"/") {
+			return nil
+		}
+	}
+	return errNotSelected
+}
+
+// pushedImages returns the references of the pushed images, by tag when tagged and by digest otherwise,
+// the events other than pushes have none
+func (e registryEvent) pushedImages() []string {
+	var images []string
+	switch {
+	case e.Type == harborPushArtifact || e.Type == harborPushImage:
+		for _, resource := range e.EventData.Resources {
+			repository := resource.ResourceURL
+			// the resource URL ends with the tag or the digest of the artifact
+			if i := strings.LastIndex(repository, "@"); i >= 0 {
+				repository = repository[:i]
+			} else if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+				repository = repository[:i]
+			}
+			switch {
+			case repository == "":
+			case resource.Tag != "":
+				images = append(images, repository+":"+resource.Tag)
+			case resource.Digest != "":
+				images = append(images, repository+"@"+resource.Digest)
+			}
+		}
+	case e.Type == "" && e.DockerURL != "":
+		for _, tag := range e.UpdatedTags {
+			images = append(images, e.DockerURL+":"+tag)
+		}
+	}
+	return images
+}
@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gammazero/workerpool"
+	"github.com/gin-gonic/gin"
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/services"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const harborPushEvent = `{
+	"type": "PUSH_ARTIFACT",
+	"occur_at": 1680501893,
+	"operator": "robot$team-a+ci",
+	"event_data": {
+		"resources": [
+			{"digest": "sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7", "tag": "1.25", "resource_url": "harbor.example.com/team-a/nginx:1.25"},
+			{"digest": "sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137", "resource_url": "harbor.example.com/team-a/nginx@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137"}
+		],
+		"repository": {"name": "nginx", "namespace": "team-a", "repo_full_name": "team-a/nginx", "repo_type": "private"}
+	}
+}`
+
+const quayPushEvent = `{
+	"repository": "kubescape/kubevuln",
+	"namespace": "kubescape",
+	"name": "kubevuln",
+	"docker_url": "quay.io/kubescape/kubevuln",
+	"homepage": "https://quay.io/repository/kubescape/kubevuln",
+	"updated_tags": ["v0.2.100", "latest"]
+}`
+
+func Test_registryEvent_pushedImages(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+		want  []string
+	}{
+		{
+			name:  "harbor push",
+			event: harborPushEvent,
+			want: []string{
+				"harbor.example.com/team-a/nginx:1.25",
+				"harbor.example.com/team-a/nginx@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+			},
+		},
+		{
+			name:  "harbor 1.x push on a registry port",
+			event: `{"type": "pushImage", "event_data": {"resources": [{"tag": "v1", "resource_url": "harbor.example.com:8443/team-a/app:v1"}]}}`,
+			want:  []string{"harbor.example.com:8443/team-a/app:v1"},
+		},
+		{
+			name:  "harbor deletion",
+			event: `{"type": "DELETE_ARTIFACT", "event_data": {"resources": [{"tag": "v1", "resource_url": "harbor.example.com/team-a/app:v1"}]}}`,
+		},
+		{
+			name:  "quay push",
+			event: quayPushEvent,
+			want:  []string{"quay.io/kubescape/kubevuln:v0.2.100", "quay.io/kubescape/kubevuln:latest"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event registryEvent
+			require.NoError(t, json.Unmarshal([]byte(tt.event), &event))
+			assert.Equal(t, tt.want, event.pushedImages())
+		})
+	}
+}
+
+func TestHTTPController_RegistryWebhook(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("s3cr3t\n"), 0600))
+	scanService := services.NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false,
+		services.WithAllowedRegistries([]string{"harbor.example.com", "quay.io"}))
+	tests := []struct {
+		name          string
+		webhook       HTTPControllerOption
+		query         string
+		authorization string
+		body          string
+		expectedCode  int
+		wantItems     []domain.BatchItemStatus
+	}{
+		{
+			name:         "disabled",
+			body:         harborPushEvent,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:          "wrong secret",
+			webhook:       WithRegistryWebhook(nil, secretFile),
+			authorization: "secret",
+			body:          harborPushEvent,
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "invalid event",
+			webhook:       WithRegistryWebhook(nil, secretFile),
+			authorization: "s3cr3t",
+			body:          `{"type": 1}`,
+			expectedCode:  http.StatusBadRequest,
+		},
+		{
+			name:          "harbor push",
+			webhook:       WithRegistryWebhook([]string{"harbor.example.com/team-a"}, secretFile),
+			authorization: "Bearer s3cr3t",
+			body:          harborPushEvent,
+			expectedCode:  http.StatusOK,
+			wantItems: []domain.BatchItemStatus{
+				{Index: 0, ImageTag: "harbor.example.com/team-a/nginx:1.25", Accepted: true},
+				{Index: 1, ImageTag: "harbor.example.com/team-a/nginx@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137", Accepted: true},
+			},
+		},
+		{
+			name:         "quay push with repository filters",
+			webhook:      WithRegistryWebhook([]string{"harbor.example.com/team-a", "quay.io/kubescape/kubevuln"}, secretFile),
+			query:        "?token=s3cr3t",
+			body:         strings.Replace(quayPushEvent, `"docker_url": "quay.io/kubescape/kubevuln"`, `"docker_url": "quay.io/kubescape/kubescape"`, 1),
+			expectedCode: http.StatusOK,
+			wantItems: []domain.BatchItemStatus{
+				{Index: 0, ImageTag: "quay.io/kubescape/kubescape:v0.2.100", Error: errNotSelected.Error()},
+				{Index: 1, ImageTag: "quay.io/kubescape/kubescape:latest", Error: errNotSelected.Error()},
+			},
+		},
+		{
+			name:         "quay push denied by policy",
+			webhook:      WithRegistryWebhook(nil, secretFile),
+			query:        "?token=s3cr3t",
+			body:         strings.Replace(quayPushEvent, `"docker_url": "quay.io/`, `"docker_url": "ghcr.io/`, 1),
+			expectedCode: http.StatusOK,
+			wantItems: []domain.BatchItemStatus{
+				{Index: 0, ImageTag: "ghcr.io/kubescape/kubevuln:v0.2.100", Error: "image registry is not allowed by policy: ghcr.io/kubescape/kubevuln"},
+				{Index: 1, ImageTag: "ghcr.io/kubescape/kubevuln:latest", Error: "image registry is not allowed by policy: ghcr.io/kubescape/kubevuln"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := HTTPController{
+				scanService: scanService,
+				workerPool:  workerpool.New(1),
+			}
+			if tt.webhook != nil {
+				tt.webhook(&c)
+			}
+			router := gin.Default()
+			path := "/v1/registryWebhook"
+			router.POST(path, c.RegistryWebhook)
+			req, _ := http.NewRequest("POST", path+tt.query, strings.NewReader(tt.body))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			c.Shutdown()
+			assert.Equal(t, tt.expectedCode, w.Code, w.Body.String())
+			if tt.wantItems == nil {
+				return
+			}
+			var report domain.BatchReport
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.NotEmpty(t, report.BatchID)
+			assert.Equal(t, tt.wantItems, report.Items)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// AnnotationScanResultPrefix followed by a container name annotates a workload with the WorkloadScanResult of the
+// latest scan of the image of that container, as JSON
+const AnnotationScanResultPrefix = "vulnerabilities.kubescape.io/"
+
+// WorkloadScanResult summarizes the latest scan of the image of a container on its workload
+type WorkloadScanResult struct {
+	ImageHash  string         `json:"imageHash,omitempty"`
+	ScannedAt  time.Time      `json:"scannedAt"`
+	Verdict    string         `json:"verdict"`
+	Severities map[string]int `json:"severities,omitempty"`
+	Code       string         `json:"code,omitempty"`
+}
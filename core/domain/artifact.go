@@ -0,0 +1,13 @@
+package domain
+
+// AnnotationArtifactType is the type of the scanned OCI artifact, missing for container images
+const AnnotationArtifactType = "kubescape.io/artifact-type"
+
+// AnnotationChartImages lists the images referenced by a Helm chart, separated by commas
+const AnnotationChartImages = "kubescape.io/chart-images"
+
+const (
+	ArtifactTypeImage     = "image"
+	ArtifactTypeHelmChart = "helmChart"
+	ArtifactTypeWasm      = "wasm"
+)
@@ -0,0 +1,21 @@
+package domain
+
+const (
+	// AnnotationSBOMSignature records the base64 signature of the in-toto attestation of an SBOM
+	AnnotationSBOMSignature = "kubescape.io/sbom-signature"
+	// AnnotationSBOMSignatureKeyID identifies the key which signed the attestation of an SBOM
+	AnnotationSBOMSignatureKeyID = "kubescape.io/sbom-signature-key-id"
+	// AnnotationSBOMAttestationDigest records the "sha256:" digest of the in-toto statement signed for an SBOM
+	AnnotationSBOMAttestationDigest = "kubescape.io/sbom-attestation-digest"
+)
+
+// SBOMAttestation is the in-toto statement of the SBOM of an image signed in a DSSE envelope, as cosign attests them
+type SBOMAttestation struct {
+	// Envelope is the JSON DSSE envelope, pushed along with the image
+	Envelope []byte
+	// Signature is the base64 signature of the envelope
+	Signature string
+	KeyID     string
+	// StatementDigest is the "sha256:" digest of the statement signed
+	StatementDigest string
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "fmt"
+
+// sources of the registry credentials used to pull an image
+const (
+	AuthProviderArtifactory = "artifactory"
+	AuthProviderHarbor      = "harbor"
+	AuthProviderKeychain    = "keychain"
+	AuthProviderPullSecret  = "pullSecret"
+)
+
+// reasons of the registry authentication failures reported in the scan status
+const (
+	// AuthReasonDenied is reported when the credentials lack the permission to pull the repository
+	AuthReasonDenied = "denied"
+	// AuthReasonExpired is reported when the configured token expired and cannot be refreshed
+	AuthReasonExpired = "expired"
+	// AuthReasonOutOfScope is reported when the repository is outside of the repositories of the registry credentials
+	AuthReasonOutOfScope = "outOfScope"
+	// AuthReasonRefreshFailed is reported when the configured token could not be refreshed
+	AuthReasonRefreshFailed = "refreshFailed"
+	// AuthReasonUnauthorized is reported when the registry rejects the credentials
+	AuthReasonUnauthorized = "unauthorized"
+)
+
+// RegistryAuthError is returned when an image cannot be pulled for lack of valid registry credentials
+type RegistryAuthError struct {
+	Registry string
+	Provider string
+	Reason   string
+	Err      error
+}
+
+func (e *RegistryAuthError) Error() string {
+	return fmt.Sprintf("registry authentication failed for %s using %s credentials (%s): %v", e.Registry, e.Provider, e.Reason, e.Err)
+}
+
+// Is puts the authentication errors in the ErrImagePull category
+func (e *RegistryAuthError) Is(target error) bool {
+	return target == ErrImagePull
+}
+
+func (e *RegistryAuthError) Unwrap() error {
+	return e.Err
+}
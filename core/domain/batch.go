@@ -0,0 +1,19 @@
+package domain
+
+// BatchItemStatus tells whether a scan command of a batch was accepted, by its index in the batch
+type BatchItemStatus struct {
+	Index     int    `json:"index"`
+	Wlid      string `json:"wlid,omitempty"`
+	ImageTag  string `json:"imageTag,omitempty"`
+	ImageHash string `json:"imageHash,omitempty"`
+	Accepted  bool   `json:"accepted"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BatchReport is the outcome of the submission of a batch of scan commands
+type BatchReport struct {
+	BatchID  string            `json:"batchID"`
+	Accepted int               `json:"accepted"`
+	Rejected int               `json:"rejected"`
+	Items    []BatchItemStatus `json:"items"`
+}
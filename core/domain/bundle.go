@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+)
+
+// BundleFormatVersion is the format version of the bundles written by this kubevuln version, bundles of other format
+// versions are rejected
+const BundleFormatVersion = 2
+
+var (
+	ErrBundleFormat  = errors.New("unsupported bundle format version")
+	ErrNoBundles     = errors.New("bundles are not enabled")
+	ErrNoBundleStore = errors.New("bundle export and result import need the storage")
+)
+
+// SBOMBundle is a portable export of the SBOMs stored by an offline kubevuln, to be scanned by a connected one. It is
+// written as NDJSON, a header line followed by a line per SBOM, so that the SBOMs are streamed one at a time.
+type SBOMBundle struct {
+	FormatVersion      int           `json:"formatVersion"`
+	CreatedAt          time.Time     `json:"createdAt"`
+	Release            string        `json:"release,omitempty"`
+	SBOMCreatorVersion string        `json:"sbomCreatorVersion"`
+	SBOMs              []BundledSBOM `json:"-"`
+}
+
+// ReadSBOMBundle decodes an NDJSON bundle, its header line followed by the lines of its SBOMs
+func ReadSBOMBundle(r io.Reader) (SBOMBundle, error) {
+	decoder := json.NewDecoder(r)
+	var bundle SBOMBundle
+	if err := decoder.Decode(&bundle); err != nil {
+		return SBOMBundle{}, err
+	}
+	for {
+		var sbom BundledSBOM
+		err := decoder.Decode(&sbom)
+		if errors.Is(err, io.EOF) {
+			return bundle, nil
+		}
+		if err != nil {
+			return SBOMBundle{}, err
+		}
+		bundle.SBOMs = append(bundle.SBOMs, sbom)
+	}
+}
+
+// WriteSBOMBundle encodes a bundle as NDJSON, its header line followed by the lines of its SBOMs
+func WriteSBOMBundle(w io.Writer, bundle SBOMBundle) error {
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(bundle); err != nil {
+		return err
+	}
+	for _, sbom := range bundle.SBOMs {
+		if err := encoder.Encode(sbom); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BundledSBOM is a stored SBOM of a bundle, named after its image slug
+type BundledSBOM struct {
+	ImageSlug          string            `json:"imageSlug"`
+	SBOMCreatorName    string            `json:"sbomCreatorName,omitempty"`
+	SBOMCreatorVersion string            `json:"sbomCreatorVersion"`
+	Status             string            `json:"status,omitempty"`
+	Annotations        map[string]string `json:"annotations,omitempty"`
+	Labels             map[string]string `json:"labels,omitempty"`
+	Content            *v1beta1.Document `json:"content"`
+}
+
+// ResultsBundle holds the vulnerability manifests a connected kubevuln matched for the SBOMs of a bundle, to be
+// imported back by the offline one
+type ResultsBundle struct {
+	FormatVersion     int             `json:"formatVersion"`
+	CreatedAt         time.Time       `json:"createdAt"`
+	Release           string          `json:"release,omitempty"`
+	CVEScannerVersion string          `json:"cveScannerVersion"`
+	CVEDBVersion      string          `json:"cveDBVersion"`
+	Scanned           int             `json:"scanned"`
+	Failed            int             `json:"failed"`
+	Results           []BundledResult `json:"results"`
+}
+
+// BundledResult is the vulnerability manifest of a bundled SBOM, or why it could not be matched
+type BundledResult struct {
+	ImageSlug          string                 `json:"imageSlug"`
+	Error              string                 `json:"error,omitempty"`
+	SBOMCreatorName    string                 `json:"sbomCreatorName,omitempty"`
+	SBOMCreatorVersion string                 `json:"sbomCreatorVersion,omitempty"`
+	CVEScannerName     string                 `json:"cveScannerName,omitempty"`
+	CVEScannerVersion  string                 `json:"cveScannerVersion,omitempty"`
+	CVEDBVersion       string                 `json:"cveDBVersion,omitempty"`
+	Annotations        map[string]string      `json:"annotations,omitempty"`
+	Labels             map[string]string      `json:"labels,omitempty"`
+	Content            *v1beta1.GrypeDocument `json:"content,omitempty"`
+}
+
+// BundleImportReport summarizes the import of a results bundle
+type BundleImportReport struct {
+	// Stored counts the vulnerability manifests stored
+	Stored int `json:"stored"`
+	// Skipped counts the results without vulnerability manifest, the SBOMs which could not be matched
+	Skipped int `json:"skipped"`
+	// Failed counts the vulnerability manifests which could not be stored
+	Failed int `json:"failed"`
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// caches of the scan pipeline, the documents stored by previous scans
+const (
+	CacheCVE  = "cve"
+	CacheSBOM = "sbom"
+)
+
+// CacheAgeBucket counts the hits served by entries at most MaxAge old and older than the previous bucket, "+Inf"
+// being the last bucket
+type CacheAgeBucket struct {
+	MaxAge string `json:"maxAge"`
+	Hits   int64  `json:"hits"`
+}
+
+// CacheStats describes the efficacy of a cache: how often it spared a computation, the bytes of the images it
+// spared pulling and how old the entries it served were, the hits of entries of unknown age are not in Ages
+type CacheStats struct {
+	Cache      string           `json:"cache"`
+	Hits       int64            `json:"hits"`
+	Misses     int64            `json:"misses"`
+	HitRate    float64          `json:"hitRate"`
+	BytesSaved int64            `json:"bytesSaved"`
+	Ages       []CacheAgeBucket `json:"ages"`
+}
+
+// CacheReport gathers the statistics of the caches since Since
+type CacheReport struct {
+	Since  time.Time    `json:"since"`
+	Caches []CacheStats `json:"caches"`
+}
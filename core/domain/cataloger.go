@@ -0,0 +1,11 @@
+package domain
+
+// AnnotationCatalogerStatus records on a partial SBOM the CatalogerStatus of each of its catalogers, as a JSON array
+const AnnotationCatalogerStatus = "kubescape.io/cataloger-status"
+
+// CatalogerStatus is the outcome of a cataloger, Error being empty when it succeeded
+type CatalogerStatus struct {
+	Name     string `json:"name"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+}
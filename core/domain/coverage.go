@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// coverage statuses of a running container image
+const (
+	CoverageScanned   = "scanned"
+	CoverageStale     = "stale"
+	CoverageUnscanned = "unscanned"
+)
+
+// WorkloadImage is a container image running in the cluster
+type WorkloadImage struct {
+	Namespace     string `json:"namespace"`
+	Kind          string `json:"kind"`
+	Name          string `json:"name"`
+	ContainerName string `json:"containerName"`
+	ImageTag      string `json:"imageTag"`
+	ImageHash     string `json:"imageHash"`
+}
+
+// WorkloadCoverage is the scan coverage status of a running container image
+type WorkloadCoverage struct {
+	WorkloadImage
+	LastScan    *time.Time   `json:"lastScan,omitempty"`
+	LastFailure *ScanFailure `json:"lastFailure,omitempty"`
+	Status      string       `json:"status"`
+}
+
+// CoverageReport summarizes which running container images have a recent successful scan, Failing counts the images
+// whose last scan failed
+type CoverageReport struct {
+	Window    string             `json:"window"`
+	Total     int                `json:"total"`
+	Scanned   int                `json:"scanned"`
+	Stale     int                `json:"stale"`
+	Unscanned int                `json:"unscanned"`
+	Failing   int                `json:"failing"`
+	Ratio     float64            `json:"ratio"`
+	Workloads []WorkloadCoverage `json:"workloads"`
+}
@@ -14,6 +14,17 @@ const (
 	UnknownSeverity    = "Unknown"
 )
 
+const (
+	ExploitSourceExploitDB  = "exploitdb"
+	ExploitSourceMetasploit = "metasploit"
+)
+
+const (
+	AnnotationImageTooOld    = "kubescape.io/image-too-old"
+	AnnotationPreviousDigest = "kubescape.io/previous-image-digest"
+	AnnotationTagMutated     = "kubescape.io/tag-mutated"
+)
+
 type CVEExceptions []armotypes.VulnerabilityExceptionPolicy
 
 // CVEManifest contains a JSON CVE report manifest with some metadata
@@ -28,4 +39,15 @@ type CVEManifest struct {
 	Content            *v1beta1.GrypeDocument
 	Annotations        map[string]string
 	Labels             map[string]string
+	// Enrichment holds the attributes of the organization keyed by vulnerability ID, attached to the reports only
+	Enrichment map[string]map[string]string
+	// Exploits holds the sources of the public exploits keyed by vulnerability ID, attached to the reports only
+	Exploits map[string][]string
+	// Explanations holds why the packages matched keyed by vulnerability ID, attached to the reports only
+	Explanations map[string][]MatchExplanation
+	// FalsePositives holds the feedback applied to the ignored matches, reported with a distinct status
+	FalsePositives []FalsePositive
+	// Triage holds the active triage of the workload applied to the matches, the snoozed matches are left out of the
+	// verdicts and notifications
+	Triage []Triage
 }
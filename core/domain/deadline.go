@@ -0,0 +1,9 @@
+package domain
+
+// AttributeDeadline is the argument of a scan command giving, as an RFC 3339 time, when the operator expects its result
+const AttributeDeadline = "deadline"
+
+// AnnotationPartial set to "true" flags an SBOM, and the CVE manifest matched from it, as missing packages of the
+// image: only its OS packages are listed because cataloging all of them would have exceeded the deadline of the scan,
+// or some of its catalogers failed
+const AnnotationPartial = "kubescape.io/partial"
@@ -0,0 +1,57 @@
+package domain
+
+import "time"
+
+// Finding is a vulnerability affecting a package of a scanned image
+type Finding struct {
+	ID       string `json:"id"`
+	Package  string `json:"package"`
+	Version  string `json:"version"`
+	Severity string `json:"severity"`
+}
+
+// Key identifies the finding regardless of its severity
+func (f Finding) Key() string {
+	return f.ID + "+" + f.Package + "@" + f.Version
+}
+
+// ScanRecord is the outcome of a completed scan, kept to compare scans
+type ScanRecord struct {
+	ScanID        string
+	Wlid          string
+	ContainerName string
+	ImageTag      string
+	ImageHash     string
+	ImageSlug     string
+	Timestamp     time.Time
+	Findings      map[string]Finding
+}
+
+// DiffRequest selects the two scans to compare, either by scanID or as the latest scans of a workload
+// at the given times
+type DiffRequest struct {
+	FromScanID string
+	ToScanID   string
+	Wlid       string
+	From       time.Time
+	To         time.Time
+}
+
+// SeverityChange is a finding present in both scans whose severity changed
+type SeverityChange struct {
+	Finding
+	PreviousSeverity string `json:"previousSeverity"`
+}
+
+// ScanDiff is the vulnerability difference between two scans
+type ScanDiff struct {
+	FromScanID      string           `json:"fromScanID"`
+	FromTimestamp   time.Time        `json:"fromTimestamp"`
+	FromImageHash   string           `json:"fromImageHash"`
+	ToScanID        string           `json:"toScanID"`
+	ToTimestamp     time.Time        `json:"toTimestamp"`
+	ToImageHash     string           `json:"toImageHash"`
+	New             []Finding        `json:"new"`
+	Fixed           []Finding        `json:"fixed"`
+	SeverityChanged []SeverityChange `json:"severityChanged"`
+}
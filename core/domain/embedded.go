@@ -0,0 +1,24 @@
+package domain
+
+// AnnotationEmbeddedImages lists the images embedded in the scanned image, as a JSON array of EmbeddedImage
+const AnnotationEmbeddedImages = "kubescape.io/embedded-images"
+
+const (
+	EmbeddedFormatDocker = "docker-archive"
+	EmbeddedFormatOCI    = "oci-archive"
+)
+
+// EmbeddedImage is an image archive found in the filesystem of a scanned image, such as the images preloaded by
+// installers or kind node images; the packages of the scanned ones are added to the SBOM of their parent, located
+// under Path
+type EmbeddedImage struct {
+	// Path is the archive path in the parent image, prefixed to the locations of its packages
+	Path string `json:"path"`
+	// Image is the tag of the embedded image, or its digest when untagged
+	Image  string `json:"image"`
+	Format string `json:"format"`
+	// Parent is the path of the archive holding this one for the images embedded at a deeper level
+	Parent   string `json:"parent,omitempty"`
+	Scanned  bool   `json:"scanned"`
+	Packages int    `json:"packages,omitempty"`
+}
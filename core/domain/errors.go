@@ -0,0 +1,69 @@
+package domain
+
+import "errors"
+
+// categories of the scan errors, matched with errors.Is whatever the adapter returning them
+var (
+	ErrImagePull = errors.New("image pull failed")
+	ErrSBOM      = errors.New("SBOM creation failed")
+	ErrMatching  = errors.New("vulnerability matching failed")
+	ErrSubmit    = errors.New("report submission failed")
+	ErrPolicy    = errors.New("scan denied by policy")
+)
+
+// codes of the scan error categories, reported in the statuses, the metric labels and the logs
+const (
+	ErrorCodeImagePull = "imagePull"
+	ErrorCodeMatching  = "matching"
+	ErrorCodePolicy    = "policy"
+	ErrorCodeSBOM      = "sbom"
+	ErrorCodeSubmit    = "submit"
+	ErrorCodeUnknown   = "unknown"
+)
+
+// ErrorCodeKey is the context key of the error code of a failed scan
+type ErrorCodeKey struct{}
+
+// errorCodes lists the categories by precedence: a pull error wrapped by the SBOM creation is a pull error
+var errorCodes = []struct {
+	category error
+	code     string
+}{
+	{ErrPolicy, ErrorCodePolicy},
+	{ErrImagePull, ErrorCodeImagePull},
+	{ErrSBOM, ErrorCodeSBOM},
+	{ErrMatching, ErrorCodeMatching},
+	{ErrSubmit, ErrorCodeSubmit},
+}
+
+// CategorizedError puts an error in a category, keeping its message
+type CategorizedError struct {
+	Category error
+	Err      error
+}
+
+func (e *CategorizedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CategorizedError) Unwrap() []error {
+	return []error{e.Category, e.Err}
+}
+
+// Categorize puts err in category unless it already is in one, nil stays nil
+func Categorize(category, err error) error {
+	if err == nil || ErrorCode(err) != ErrorCodeUnknown {
+		return err
+	}
+	return &CategorizedError{Category: category, Err: err}
+}
+
+// ErrorCode returns the code of the category of err, ErrorCodeUnknown if it is in none
+func ErrorCode(err error) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.category) {
+			return c.code
+		}
+	}
+	return ErrorCodeUnknown
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNoScanEvents       = errors.New("scan event log is not enabled")
+	ErrScanEventsNotFound = errors.New("no events recorded for the scan")
+)
+
+// ScanEventKey carries in the context of a scan the function its detailed events are recorded with
+type ScanEventKey struct{}
+
+// ScanEvent is a detailed event of a scan, such as a layer read or a report chunk posted
+type ScanEvent struct {
+	Time       time.Time         `json:"time"`
+	Message    string            `json:"message"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// ScanEvents is the event log of a scan, Dropped counts the oldest events dropped past its capacity and the events
+// dropped past its rate limit
+type ScanEvents struct {
+	ScanID  string      `json:"scanID"`
+	Events  []ScanEvent `json:"events"`
+	Dropped int         `json:"dropped"`
+}
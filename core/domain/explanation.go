@@ -0,0 +1,30 @@
+package domain
+
+// the searches through which a package matched a vulnerability
+const (
+	SearchedByCPE  = "cpe"
+	SearchedByPURL = "purl"
+)
+
+// MatchTypeCPE is the type of the Grype matches found by the CPEs of the packages
+const MatchTypeCPE = "cpe-match"
+
+// MatchExplanation tells why a package matched a vulnerability, to triage the suspected false positives
+type MatchExplanation struct {
+	Package string `json:"package"`
+	Version string `json:"version"`
+	// Matcher is the Grype matcher of the package type, such as "dpkg-matcher" or "java-matcher"
+	Matcher string `json:"matcher"`
+	// MatchType is "exact-direct-match", "exact-indirect-match" when the package matched through its source
+	// package, or MatchTypeCPE
+	MatchType string `json:"matchType"`
+	// Constraint is the version constraint of the vulnerability satisfied by the package version
+	Constraint string `json:"constraint,omitempty"`
+	// Namespace is the source of the vulnerability record, such as "debian:distro:debian:11" or "github:language:java"
+	Namespace string `json:"namespace"`
+	// SearchedBy is SearchedByCPE when the package matched by its CPEs, SearchedByPURL when it matched by its name in
+	// its distribution or ecosystem
+	SearchedBy string `json:"searchedBy"`
+	// Identifiers are the CPEs found or the package URL
+	Identifiers []string `json:"identifiers,omitempty"`
+}
@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	wlidpkg "github.com/armosec/utils-k8s-go/wlid"
+)
+
+// ErrInvalidColumn is returned for the export columns which do not exist
+var ErrInvalidColumn = errors.New("unknown export column")
+
+// columns of the scan result exports
+const (
+	ColumnWlid          = "wlid"
+	ColumnNamespace     = "namespace"
+	ColumnKind          = "kind"
+	ColumnName          = "name"
+	ColumnContainer     = "container"
+	ColumnImage         = "image"
+	ColumnImageHash     = "imageHash"
+	ColumnScanID        = "scanID"
+	ColumnScannedAt     = "scannedAt"
+	ColumnVulnerability = "vulnerability"
+	ColumnSeverity      = "severity"
+	ColumnPackage       = "package"
+	ColumnVersion       = "version"
+	ColumnType          = "type"
+	ColumnFixState      = "fixState"
+	ColumnFixVersions   = "fixVersions"
+	ColumnLocations     = "locations"
+	ColumnURLs          = "urls"
+)
+
+// ResultColumns lists the export columns in their default order
+var ResultColumns = []string{ColumnWlid, ColumnNamespace, ColumnKind, ColumnName, ColumnContainer, ColumnImage,
+	ColumnImageHash, ColumnScanID, ColumnScannedAt, ColumnVulnerability, ColumnSeverity, ColumnPackage, ColumnVersion,
+	ColumnType, ColumnFixState, ColumnFixVersions, ColumnLocations, ColumnURLs}
+
+// DefaultResultColumns are the export columns used when none are requested
+var DefaultResultColumns = []string{ColumnNamespace, ColumnKind, ColumnName, ColumnContainer, ColumnImage,
+	ColumnVulnerability, ColumnSeverity, ColumnPackage, ColumnVersion, ColumnFixVersions}
+
+// ResultRow is a finding of the latest scan of a workload container, or of a registry image, as exported
+type ResultRow struct {
+	Wlid          string
+	Container     string
+	Image         string
+	ImageHash     string
+	ScanID        string
+	ScannedAt     time.Time
+	Vulnerability string
+	Severity      string
+	Package       string
+	Version       string
+	Type          string
+	FixState      string
+	FixVersions   []string
+	Locations     []string
+	URLs          []string
+}
+
+// ValidateColumns checks that the export columns exist
+func ValidateColumns(columns []string) error {
+	for _, column := range columns {
+		if !containsColumn(column) {
+			return errors.Join(ErrInvalidColumn, errors.New(strconv.Quote(column)))
+		}
+	}
+	return nil
+}
+
+func containsColumn(column string) bool {
+	for _, c := range ResultColumns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns the value of a column of the row, the lists are separated by spaces
+func (r ResultRow) Value(column string) string {
+	switch column {
+	case ColumnWlid:
+		return r.Wlid
+	case ColumnNamespace:
+		if r.Wlid == "" {
+			return ""
+		}
+		return wlidpkg.GetNamespaceFromWlid(r.Wlid)
+	case ColumnKind:
+		if r.Wlid == "" {
+			return ""
+		}
+		return wlidpkg.GetKindFromWlid(r.Wlid)
+	case ColumnName:
+		if r.Wlid == "" {
+			return ""
+		}
+		return wlidpkg.GetNameFromWlid(r.Wlid)
+	case ColumnContainer:
+		return r.Container
+	case ColumnImage:
+		return r.Image
+	case ColumnImageHash:
+		return r.ImageHash
+	case ColumnScanID:
+		return r.ScanID
+	case ColumnScannedAt:
+		if r.ScannedAt.IsZero() {
+			return ""
+		}
+		return r.ScannedAt.UTC().Format(time.RFC3339)
+	case ColumnVulnerability:
+		return r.Vulnerability
+	case ColumnSeverity:
+		return r.Severity
+	case ColumnPackage:
+		return r.Package
+	case ColumnVersion:
+		return r.Version
+	case ColumnType:
+		return r.Type
+	case ColumnFixState:
+		return r.FixState
+	case ColumnFixVersions:
+		return strings.Join(r.FixVersions, " ")
+	case ColumnLocations:
+		return strings.Join(r.Locations, " ")
+	case ColumnURLs:
+		return strings.Join(r.URLs, " ")
+	}
+	return ""
+}
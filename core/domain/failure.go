@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// AnnotationIncompleteReason records on an incomplete SBOM why it is incomplete
+const AnnotationIncompleteReason = "kubescape.io/incomplete-reason"
+
+// reasons of the scan failures
+const (
+	FailureAuth          = "auth"
+	FailureError         = "error"
+	FailureImageTooLarge = "imageTooLarge"
+	FailurePull          = "pull"
+	FailureRateLimited   = "rateLimited"
+	FailureTimeout       = "timeout"
+)
+
+// ScanFailure is the last failure of the scans of an image, Attempts counts the scans failed in a row
+type ScanFailure struct {
+	Image     string    `json:"image"`
+	ImageSlug string    `json:"imageSlug,omitempty"`
+	Wlid      string    `json:"wlid,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Stage     string    `json:"stage"`
+	Reason    string    `json:"reason"`
+	Code      string    `json:"code,omitempty"`
+	Error     string    `json:"error"`
+	Attempts  int       `json:"attempts"`
+}
+
+// IncompleteSBOMError qualifies ErrIncompleteSBOM with the reason the SBOM is incomplete
+type IncompleteSBOMError struct {
+	Reason string
+}
+
+func (e *IncompleteSBOMError) Error() string {
+	return fmt.Sprintf("%v (%s)", ErrIncompleteSBOM, e.Reason)
+}
+
+func (e *IncompleteSBOMError) Unwrap() error {
+	return ErrIncompleteSBOM
+}
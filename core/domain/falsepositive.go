@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+)
+
+// the export formats of the false positives
+const (
+	FalsePositiveFormatGrype   = "grype"
+	FalsePositiveFormatOpenVEX = "openvex"
+)
+
+var (
+	ErrFalsePositiveFormat   = errors.New("unsupported false positive export format")
+	ErrFalsePositiveNotFound = errors.New("false positive not found")
+	ErrInvalidFalsePositive  = errors.New("a false positive needs a vulnerability and a package")
+	ErrNoFalsePositives      = errors.New("false positive feedback is not enabled")
+)
+
+// FalsePositive is the feedback of a user marking the matches of a vulnerability in a package as a false positive, in
+// any version of the package when Version is empty
+type FalsePositive struct {
+	ID            string `json:"id"`
+	Vulnerability string `json:"vulnerability"`
+	Package       string `json:"package"`
+	Version       string `json:"version,omitempty"`
+	// PURL identifies the package in the VEX statements, its name and version otherwise
+	PURL      string    `json:"purl,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Matches tells whether the feedback applies to a match
+func (f FalsePositive) Matches(match v1beta1.Match) bool {
+	return f.Vulnerability == match.Vulnerability.ID && f.Package == match.Artifact.Name &&
+		(f.Version == "" || f.Version == match.Artifact.Version)
+}
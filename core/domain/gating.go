@@ -0,0 +1,8 @@
+package domain
+
+import "errors"
+
+// AnnotationScan set to "false" on a workload or on its pod template disables the scans of its images
+const AnnotationScan = "kubescape.io/scan"
+
+var ErrSkippedByAnnotation = errors.New("scan disabled by workload annotation")
@@ -0,0 +1,47 @@
+package domain
+
+// AnnotationImageSlug records the image a stored scan result was computed for
+const AnnotationImageSlug = "kubescape.io/image-slug"
+
+// AnnotationImageDigest records the digest of the image a workload scan stored a result for, the results without it,
+// like the ones of the registry scans, are never garbage collected
+const AnnotationImageDigest = "kubescape.io/image-digest"
+
+// kinds of the scan results stored by kubevuln
+const (
+	StoredSBOM                  = "SBOMSPDXv2p3"
+	StoredSBOMSummary           = "SBOMSummary"
+	StoredVulnerabilityManifest = "VulnerabilityManifest"
+	StoredVulnerabilitySummary  = "VulnerabilityManifestSummary"
+)
+
+// StoredResult identifies a scan result kept in storage, with the image or the workload container it belongs to
+type StoredResult struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// ImageSlug is set for the results computed for an image
+	ImageSlug string `json:"imageSlug,omitempty"`
+	// ImageDigest is set for the results computed for the image of a workload
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// the workload container is set for the results summarizing a workload
+	WorkloadNamespace string `json:"workloadNamespace,omitempty"`
+	WorkloadKind      string `json:"workloadKind,omitempty"`
+	WorkloadName      string `json:"workloadName,omitempty"`
+	ContainerName     string `json:"containerName,omitempty"`
+	// SBOMCreatorVersion is set for the SBOMs recording the version they were created with
+	SBOMCreatorVersion string `json:"sbomCreatorVersion,omitempty"`
+	// SBOMSchemaVersion is set for the SBOMs recording the schema version they were stored with
+	SBOMSchemaVersion string `json:"sbomSchemaVersion,omitempty"`
+}
+
+// GCReport summarizes a garbage collection of the stored scan results
+type GCReport struct {
+	// Unreferenced counts the results no running workload references, including the ones still in their grace period
+	Unreferenced int `json:"unreferenced"`
+	// Deleted counts the results deleted once their grace period was over
+	Deleted int `json:"deleted"`
+}
+
+// AnnotationOrphaned records when the workload a stored scan result summarizes was deleted
+const AnnotationOrphaned = "kubescape.io/orphaned-at"
@@ -0,0 +1,36 @@
+package domain
+
+// OCI annotations identifying the base image of an image, recorded from its manifest or its labels
+const (
+	OCIBaseDigest = "org.opencontainers.image.base.digest"
+	OCIBaseName   = "org.opencontainers.image.base.name"
+)
+
+const (
+	// AnnotationGoldenBase records the status of the base image of the scanned image against the golden base catalog
+	AnnotationGoldenBase = "kubescape.io/golden-base"
+	// AnnotationGoldenBaseVersion records the version of the golden base catalog the base image was checked against
+	AnnotationGoldenBaseVersion = "kubescape.io/golden-base-version"
+)
+
+// statuses of the base image of a scanned image against the golden base catalog
+const (
+	GoldenBaseApproved = "approved"
+	// GoldenBaseStale is an approved base image at another digest than its approved one
+	GoldenBaseStale      = "stale"
+	GoldenBaseUnapproved = "unapproved"
+	// GoldenBaseUnknown is an image which does not record its base image
+	GoldenBaseUnknown = "unknown"
+)
+
+// GoldenBases is the catalog of the base images approved by the platform team, at their approved digests
+type GoldenBases struct {
+	Version string       `json:"version"`
+	Bases   []GoldenBase `json:"bases"`
+}
+
+// GoldenBase is an approved base image, Name being its repository and tag
+type GoldenBase struct {
+	Name   string `json:"name"`
+	Digest string `json:"digest"`
+}
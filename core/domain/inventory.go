@@ -0,0 +1,24 @@
+package domain
+
+import "errors"
+
+var (
+	ErrInvalidInventory   = errors.New("the inventory entries need a namespace, a kind, a name, a container name, an image tag and an image hash")
+	ErrInventoryNotSynced = errors.New("the operator has not posted the inventory yet")
+	ErrNoInventory        = errors.New("inventory sync is not enabled")
+)
+
+// Inventory is the image and workload inventory of the cluster posted by the operator, it replaces the previous one
+type Inventory struct {
+	Images []WorkloadImage `json:"images"`
+}
+
+// InventorySync summarizes the reconciliation of an inventory: the workload images added and removed since the
+// previous one, and the scan times and failures forgotten for the images no longer running
+type InventorySync struct {
+	Images            int `json:"images"`
+	Added             int `json:"added"`
+	Removed           int `json:"removed"`
+	ForgottenScans    int `json:"forgottenScans"`
+	ForgottenFailures int `json:"forgottenFailures"`
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"errors"
+
+	"github.com/docker/docker/api/types"
+)
+
+var (
+	ErrInvalidLayerDiff = errors.New("a layer diff needs the base and the image references")
+	ErrNoLayerDiff      = errors.New("layer diff scans are not enabled")
+)
+
+// LayerDiffRequest selects the two releases of an image whose differing layers are scanned, the credentials
+// authenticate the pulls of both
+type LayerDiffRequest struct {
+	Base            string
+	Image           string
+	Credentialslist []types.AuthConfig
+}
+
+// LayerDiffSBOMs are the SBOMs of the layers which differ between two releases of an image, the layers they share
+// are not cataloged but for the files the image overwrites
+type LayerDiffSBOMs struct {
+	Base          SBOM
+	Image         SBOM
+	AddedLayers   []string
+	RemovedLayers []string
+	SharedLayers  int
+}
+
+// LayerDiff is the vulnerability difference between two releases of an image, found by scanning the layers which
+// differ between them
+type LayerDiff struct {
+	Base          string    `json:"base"`
+	Image         string    `json:"image"`
+	AddedLayers   []string  `json:"addedLayers"`
+	RemovedLayers []string  `json:"removedLayers"`
+	SharedLayers  int       `json:"sharedLayers"`
+	Introduced    []Finding `json:"introduced"`
+	Removed       []Finding `json:"removed"`
+}
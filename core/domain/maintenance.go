@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// MaintenanceStatus tells whether the background scans, scheduled or triggered by workload changes, are paused by
+// the maintenance mode or outside of the scan windows; the on-demand scans are never paused
+type MaintenanceStatus struct {
+	Maintenance bool       `json:"maintenance"`
+	Reason      string     `json:"reason,omitempty"`
+	Since       *time.Time `json:"since,omitempty"`
+	Until       *time.Time `json:"until,omitempty"`
+	InWindow    bool       `json:"inWindow"`
+	NextWindow  *time.Time `json:"nextWindow,omitempty"`
+	Paused      bool       `json:"paused"`
+	Deferred    int        `json:"deferred"`
+}
@@ -0,0 +1,17 @@
+package domain
+
+// AnnotationSBOMSchemaVersion records the schema version of a stored SBOM, SBOMs stored without it have schema version 1
+const AnnotationSBOMSchemaVersion = "kubescape.io/sbom-schema-version"
+
+// SBOMSchemaVersion is the schema version of the SBOMs stored by this kubevuln version, older ones are migrated
+const SBOMSchemaVersion = 2
+
+// SBOMMigrationReport summarizes a migration of the stored SBOMs to the current schema version
+type SBOMMigrationReport struct {
+	// Checked counts the SBOMs stored with an older or unknown schema version
+	Checked int `json:"checked"`
+	// Migrated counts the SBOMs upgraded in place
+	Migrated int `json:"migrated"`
+	// Failed counts the SBOMs which could not be read or updated, they are migrated again at the next startup
+	Failed int `json:"failed"`
+}
@@ -0,0 +1,39 @@
+package domain
+
+const (
+	VerdictError   = "error"
+	VerdictFail    = "fail"
+	VerdictSuccess = "success"
+)
+
+const (
+	PolicyGoldenBaseDrift = "goldenBaseDrift"
+	PolicyImageTooOld     = "imageTooOld"
+	PolicySeverityAlert   = "severityAlert"
+	PolicySeverityFail    = "severityFail"
+)
+
+// ScanReport contains a compact scan status sent back to the caller on completion
+type ScanReport struct {
+	ScanID         string            `json:"scanID"`
+	Wlid           string            `json:"wlid,omitempty"`
+	ImageSlug      string            `json:"imageSlug,omitempty"`
+	ImageTag       string            `json:"imageTag,omitempty"`
+	ImageHash      string            `json:"imageHash,omitempty"`
+	TagMutated     bool              `json:"tagMutated,omitempty"`
+	PreviousDigest string            `json:"previousDigest,omitempty"`
+	ImageCreated   string            `json:"imageCreated,omitempty"`
+	ImageSource    string            `json:"imageSource,omitempty"`
+	ImageLabels    map[string]string `json:"imageLabels,omitempty"`
+	GoldenBase     string            `json:"goldenBase,omitempty"`
+	Partial        bool              `json:"partial,omitempty"`
+	Violations     []string          `json:"violations,omitempty"`
+	Policy         string            `json:"policy,omitempty"`
+	Verdict        string            `json:"verdict"`
+	Error          string            `json:"error,omitempty"`
+	AuthFailure    string            `json:"authFailure,omitempty"`
+	PullEndpoints  []string          `json:"pullEndpoints,omitempty"`
+	Summary        map[string]int    `json:"summary,omitempty"`
+	Snoozed        int               `json:"snoozed,omitempty"`
+	RiskScore      float64           `json:"riskScore,omitempty"`
+}
@@ -0,0 +1,30 @@
+package domain
+
+// scan statuses of a unique image of a scan plan
+const (
+	PlanCached    = "cached"
+	PlanNotCached = "notCached"
+	PlanStaleDB   = "staleDB"
+)
+
+// PlannedImage is a unique image of a scan plan, with the number of scan commands referencing it
+type PlannedImage struct {
+	ImageSlug string `json:"imageSlug"`
+	ImageTag  string `json:"imageTag"`
+	ImageHash string `json:"imageHash"`
+	Commands  int    `json:"commands"`
+	// NeedsScan is false for the images whose vulnerability manifest is stored for the current scanner and DB versions
+	NeedsScan bool   `json:"needsScan"`
+	Status    string `json:"status"`
+}
+
+// ScanPlan tells which unique images of a list of scan commands actually need scanning
+type ScanPlan struct {
+	DBVersion string `json:"dbVersion"`
+	Commands  int    `json:"commands"`
+	// Invalid counts the commands missing image information, which would be rejected
+	Invalid int            `json:"invalid"`
+	Unique  int            `json:"unique"`
+	ToScan  int            `json:"toScan"`
+	Images  []PlannedImage `json:"images"`
+}
@@ -5,4 +5,10 @@ const (
 	Started
 	Success
 	Done
+	// Skipped reports the scans disabled by the annotation of their workload
+	Skipped
+	// Trusted reports the scans skipped for the images of the trusted digests allowlist
+	Trusted
+	// Failed reports the failed scans, with the code of their error
+	Failed
 )
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNoProfiling = errors.New("profiling is not enabled")
+	ErrProfileBusy = errors.New("a CPU profile is already being captured")
+)
+
+// ScanProfile locates the CPU and heap profiles captured during a scan, the samples of the scan are labeled with its
+// scanID in the CPU profile
+type ScanProfile struct {
+	ScanID      string    `json:"scanID"`
+	CPUProfile  string    `json:"cpuProfile"`
+	HeapProfile string    `json:"heapProfile"`
+	StartedAt   time.Time `json:"startedAt"`
+	Seconds     float64   `json:"seconds"`
+	ScanDone    bool      `json:"scanDone"`
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// stages reported in addition to the ones with a timeout budget
+const (
+	StageCatalog = "catalog"
+	StageDone    = "done"
+)
+
+// units of the progress of a stage
+const (
+	ProgressBytes           = "bytes"
+	ProgressCatalogers      = "catalogers"
+	ProgressPackages        = "packages"
+	ProgressVulnerabilities = "vulnerabilities"
+)
+
+// ProgressKey carries in the context of a scan the function its progress is reported to
+type ProgressKey struct{}
+
+// Progress is a progress event of a scan stage, Total is zero when unknown
+type Progress struct {
+	ScanID    string `json:"scanID,omitempty"`
+	Wlid      string `json:"wlid,omitempty"`
+	ImageSlug string `json:"imageSlug,omitempty"`
+	Stage     string `json:"stage"`
+	Unit      string `json:"unit,omitempty"`
+	Current   int64  `json:"current"`
+	Total     int64  `json:"total,omitempty"`
+	// Packages counts the packages discovered so far while cataloging
+	Packages int64 `json:"packages,omitempty"`
+	// Error is set when the scan failed, on the StageDone event
+	Error string    `json:"error,omitempty"`
+	Time  time.Time `json:"time"`
+}
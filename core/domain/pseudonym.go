@@ -0,0 +1,6 @@
+package domain
+
+import "errors"
+
+// ErrPseudonymStoreFull is returned when the pseudonyms store cannot hold another pseudonym, which is still submitted
+var ErrPseudonymStoreFull = errors.New("pseudonym store is full")
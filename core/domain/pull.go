@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// classes of the transient registry errors, which are retried before failing over to the mirrors of the registry
+const (
+	// PullErrorNetwork is reported when the connection to the registry is refused, reset or closed early
+	PullErrorNetwork = "network"
+	// PullErrorServer is reported when the registry answers with a 5xx status
+	PullErrorServer = "server"
+	// PullErrorTimeout is reported when the registry does not answer in time
+	PullErrorTimeout = "timeout"
+	// PullErrorTLS is reported when the TLS handshake with the registry fails, it is not retried on the same endpoint
+	PullErrorTLS = "tls"
+)
+
+// PullAttempt records the pulls of an image from a registry endpoint
+type PullAttempt struct {
+	Endpoint string
+	Class    string
+	Tries    int
+	Err      error
+}
+
+// RegistryPullError is returned when an image cannot be pulled from its registry nor from any of its mirrors
+type RegistryPullError struct {
+	Attempts []PullAttempt
+}
+
+// Endpoints lists the registry endpoints attempted in order
+func (e *RegistryPullError) Endpoints() []string {
+	endpoints := make([]string, 0, len(e.Attempts))
+	for _, attempt := range e.Attempts {
+		endpoints = append(endpoints, attempt.Endpoint)
+	}
+	return endpoints
+}
+
+func (e *RegistryPullError) Error() string {
+	attempts := make([]string, 0, len(e.Attempts))
+	for _, attempt := range e.Attempts {
+		class := attempt.Class
+		if class == "" {
+			class = "error"
+		}
+		if attempt.Tries > 1 {
+			class = fmt.Sprintf("%s, %d tries", class, attempt.Tries)
+		}
+		attempts = append(attempts, fmt.Sprintf("%s (%s)", attempt.Endpoint, class))
+	}
+	return fmt.Sprintf("failed to pull image, attempted %s: %v", strings.Join(attempts, ", "), e.Unwrap())
+}
+
+// Is puts the pull errors in the ErrImagePull category
+func (e *RegistryPullError) Is(target error) bool {
+	return target == ErrImagePull
+}
+
+// Unwrap returns the error of the last attempt
+func (e *RegistryPullError) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1].Err
+}
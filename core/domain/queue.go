@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrScanQueueFull is returned when the persistent scan queue cannot hold another scan, which still runs
+var ErrScanQueueFull = errors.New("scan queue is full")
+
+// kinds of scan commands accepted by kubevuln
+const (
+	ScanKindGenerateSBOM    = "generateSBOM"
+	ScanKindScanCVE         = "scanCVE"
+	ScanKindScanRegistry    = "scanRegistry"
+	ScanKindScanRelayedSBOM = "scanRelayedSBOM"
+	ScanKindScanRepository  = "scanRepository"
+)
+
+// QueuedScan is a scan command waiting in the persistent scan queue
+type QueuedScan struct {
+	ID       string      `json:"id"`
+	Kind     string      `json:"kind"`
+	Command  ScanCommand `json:"command"`
+	QueuedAt time.Time   `json:"queuedAt"`
+	// HadCredentials tells that the scan was submitted with registry credentials, which are not persisted
+	HadCredentials bool `json:"hadCredentials,omitempty"`
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "errors"
+
+// AnnotationRelayedFrom records the edge cluster a relayed SBOM was created in
+const AnnotationRelayedFrom = "kubescape.io/relayed-from"
+
+var (
+	ErrClusterDenied = &CategorizedError{Category: ErrPolicy, Err: errors.New("workload cluster does not match the relay token")}
+	ErrNoRelay       = errors.New("relay mode is not enabled")
+	ErrNoRelayedSBOM = errors.New("missing relayed SBOM")
+)
@@ -0,0 +1,61 @@
+package domain
+
+import "time"
+
+// Severities lists the severities from the most to the least severe
+var Severities = []string{CriticalSeverity, HighSeverity, MediumSeverity, LowSeverity, NegligibleSeverity, UnknownSeverity}
+
+// SeverityRank orders the severities, the most severe first, the unknown ones last
+func SeverityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return len(Severities) - 1
+}
+
+// SeverityCount is the number of findings of a severity
+type SeverityCount struct {
+	Severity string
+	Count    int
+}
+
+// ImageReport is the latest scan of a workload container, or of a registry image, in a vulnerability report
+type ImageReport struct {
+	Wlid       string
+	Container  string
+	Image      string
+	ImageHash  string
+	ScanID     string
+	ScannedAt  time.Time
+	Severities []SeverityCount
+	Total      int
+	Fixable    int
+	// Findings are only listed in the reports of a single workload or image, the most severe first
+	Findings []ResultRow
+}
+
+// FixRecommendation is a package whose upgrade fixes vulnerabilities
+type FixRecommendation struct {
+	Package         string
+	Version         string
+	FixVersions     []string
+	Severity        string
+	Vulnerabilities int
+	Images          int
+}
+
+// VulnerabilityReport summarizes the latest scans of a workload or image, or of the cluster, for the people without
+// access to a dashboard
+type VulnerabilityReport struct {
+	// Scope is the wlid or the image of the report, empty for the cluster
+	Scope           string
+	GeneratedAt     time.Time
+	CVEDBVersion    string
+	Severities      []SeverityCount
+	Total           int
+	Fixable         int
+	Images          []ImageReport
+	Recommendations []FixRecommendation
+}
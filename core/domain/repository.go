@@ -0,0 +1,27 @@
+package domain
+
+import "errors"
+
+// annotations of the SBOMs and vulnerability manifests of the source repositories
+const (
+	AnnotationRepositoryCommit = "kubescape.io/repository-commit"
+	AnnotationRepositoryRef    = "kubescape.io/repository-ref"
+	AnnotationRepositoryURL    = "kubescape.io/repository-url"
+)
+
+// ArtifactTypeRepository is the artifact type of the source repositories scanned from their lockfiles
+const ArtifactTypeRepository = "repository"
+
+var (
+	ErrInvalidRepository = errors.New("invalid repository, expected an https URL")
+	ErrNoRepositoryScan  = errors.New("repository scans are not enabled")
+)
+
+// Repository is a git repository scanned at a ref, a branch, a tag or a commit, the default branch when empty;
+// Username and Password, or a token as password, authenticate the clone
+type Repository struct {
+	URL      string
+	Ref      string
+	Username string
+	Password string
+}
@@ -0,0 +1,35 @@
+package domain
+
+// AnnotationRiskScore is the risk score of the image in the context of its workload
+const AnnotationRiskScore = "kubescape.io/risk-score"
+
+// RiskWeights are the factors of the risk score of an image: each vulnerability is worth the points of its severity,
+// multiplied by Exploit when it has a public exploit, by Irrelevant when the relevancy data shows its package is not
+// loaded at runtime and by Fixable when a fix is available; the sum is multiplied by Exposed when the workload is
+// reachable from outside the cluster
+type RiskWeights struct {
+	// Severities are the points of a vulnerability keyed by severity, in any case
+	Severities map[string]float64
+	Exploit    float64
+	Exposed    float64
+	Fixable    float64
+	Irrelevant float64
+}
+
+// DefaultRiskWeights returns the weights of the risk score when none are configured
+func DefaultRiskWeights() RiskWeights {
+	return RiskWeights{
+		Severities: map[string]float64{
+			CriticalSeverity:   10,
+			HighSeverity:       5,
+			MediumSeverity:     2,
+			LowSeverity:        0.5,
+			NegligibleSeverity: 0.1,
+			UnknownSeverity:    0.5,
+		},
+		Exploit:    2,
+		Exposed:    1.5,
+		Fixable:    1.2,
+		Irrelevant: 0.25,
+	}
+}
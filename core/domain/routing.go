@@ -0,0 +1,16 @@
+package domain
+
+// ResultRoute forwards the findings of the completed scans matching all its filters to a sink, besides the backend,
+// such as the critical and high findings of the production namespaces to a SIEM
+type ResultRoute struct {
+	Name string
+	// Sink is the name of the sink of the route
+	Sink string
+	// Severities are the severities of the findings forwarded, in any case, any severity when empty; the scans without
+	// findings of these severities are not forwarded
+	Severities []string
+	// Namespaces are glob patterns of the namespaces of the workloads, any namespace when empty
+	Namespaces []string
+	// Verdicts are the verdicts of the scans forwarded, any verdict when empty
+	Verdicts []string
+}
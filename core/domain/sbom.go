@@ -4,6 +4,17 @@ import (
 	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
 )
 
+const (
+	AnnotationImageCreated     = "kubescape.io/image-created"
+	AnnotationImageSize        = "kubescape.io/image-size"
+	AnnotationImageSource      = "kubescape.io/image-source"
+	AnnotationTargetFrameworks = "kubescape.io/target-frameworks"
+	OCILabelPrefix             = "org.opencontainers.image."
+)
+
+// SBOMKey is the context key of the SBOM the CVE manifest submitted was matched from
+type SBOMKey struct{}
+
 // SBOM contains an SPDX SBOM in JSON format with some metadata
 type SBOM struct {
 	Name               string
@@ -2,26 +2,47 @@ package domain
 
 import (
 	"errors"
+	"time"
 
 	"github.com/armosec/armoapi-go/armotypes"
 	"github.com/docker/docker/api/types"
 )
 
 const (
+	AttributeCallbackURL   = "callbackURL"
 	AttributeUseHTTP       = armotypes.AttributeUseHTTP
 	AttributeSkipTLSVerify = armotypes.AttributeSkipTLSVerify
 )
 
+// pipeline stages having their own timeout budget
+const (
+	StagePull   = "pull"
+	StageMatch  = "match"
+	StageFilter = "filter"
+	StageSubmit = "submit"
+)
+
 var (
 	ErrExpectedError    = errors.New("expected error")
 	ErrInitVulnDB       = errors.New("vulnerability DB is not initialized, run readiness probe")
-	ErrIncompleteSBOM   = errors.New("incomplete SBOM, skipping CVE scan")
+	ErrIncompleteSBOM   = &CategorizedError{Category: ErrSBOM, Err: errors.New("incomplete SBOM, skipping CVE scan")}
 	ErrInvalidScanID    = errors.New("invalid scanID")
+	ErrInvalidWlid      = errors.New("invalid wlid")
 	ErrMissingImageInfo = errors.New("missing image information")
 	ErrMissingScanID    = errors.New("missing scanID")
 	ErrMissingTimestamp = errors.New("missing timestamp")
 	ErrCastingWorkload  = errors.New("casting workload")
+	ErrCircuitOpen      = &CategorizedError{Category: ErrSubmit, Err: errors.New("event receiver circuit breaker is open")}
 	ErrMockError        = errors.New("mock error")
+	ErrNoGC             = errors.New("garbage collection is not enabled")
+	ErrNoSBOMCheck      = errors.New("SBOM compatibility check is not enabled")
+	ErrNoSBOMMigration  = errors.New("SBOM migration is not enabled")
+	ErrNoSelfTest       = errors.New("self-test is not enabled")
+	ErrNoWorkloadLister = errors.New("coverage tracking is not enabled")
+	ErrPanic            = errors.New("recovered from panic")
+	ErrRegistryDenied   = &CategorizedError{Category: ErrPolicy, Err: errors.New("image registry is not allowed by policy")}
+	ErrScanNotFound     = errors.New("scan not found")
+	ErrStageTimeout     = errors.New("timeout budget exceeded")
 	ErrTooManyRequests  = errors.New("too many requests")
 )
 
@@ -30,7 +51,10 @@ type TimestampKey struct{}
 type WorkloadKey struct{}
 
 type ScanCommand struct {
+	BatchID            string
+	CallbackURL        string
 	Credentialslist    []types.AuthConfig
+	Deadline           time.Time
 	ImageHash          string
 	ImageSlug          string
 	InstanceID         string
@@ -41,6 +65,10 @@ type ScanCommand struct {
 	ContainerName      string
 	LastAction         int
 	ParentJobID        string
+	RelayCluster       string
+	RelayedSBOM        *SBOM
+	RepositoryRef      string
+	RepositoryURL      string
 	Args               map[string]interface{}
 	Session            Session
 }
@@ -0,0 +1,26 @@
+package domain
+
+// stages of the self-test, the pull of the reference image is part of the SBOM creation
+const (
+	SelfTestDatabase = "database"
+	SelfTestImage    = "image"
+	SelfTestSBOM     = "sbom"
+	SelfTestMatch    = "match"
+)
+
+// SelfTestStage is the outcome of a stage of the self-test
+type SelfTestStage struct {
+	Name     string `json:"name"`
+	Success  bool   `json:"success"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SelfTestReport is the outcome of the scan of the reference image, the stages following a failed one are not run
+type SelfTestReport struct {
+	ImageTag        string          `json:"imageTag"`
+	Success         bool            `json:"success"`
+	Packages        int             `json:"packages"`
+	Vulnerabilities int             `json:"vulnerabilities"`
+	Stages          []SelfTestStage `json:"stages"`
+}
@@ -0,0 +1,5 @@
+package domain
+
+// AnnotationEncodedContent carries the base64 content of the SBOMs and vulnerability manifests stored compressed or
+// CBOR encoded, their spec being left empty
+const AnnotationEncodedContent = "kubescape.io/encoded-content"
@@ -0,0 +1,14 @@
+package domain
+
+const (
+	FixStateFixed   = "fixed"
+	FixStateUnknown = "unknown"
+)
+
+// Suppression ignores the matches of the vulnerabilities whose ID, package name and package location all match its
+// glob patterns, "**" matching any number of path segments; an empty pattern matches anything
+type Suppression struct {
+	Vulnerability string
+	Package       string
+	Location      string
+}
@@ -0,0 +1,14 @@
+package domain
+
+// SeverityThresholds is the pass/fail and alert policy of the workloads of some namespaces, such as a stricter one for
+// production than for development; Fail and Alert are the maximum counts of vulnerabilities keyed by severity, in
+// any case; a scan above any Fail count fails and a scan above any Alert count raises PolicySeverityAlert
+type SeverityThresholds struct {
+	Name string
+	// Namespaces are glob patterns of the namespaces of the policy, any namespace when empty
+	Namespaces []string
+	// NamespaceSelector are the labels the namespaces of the policy must all have, any namespace when empty
+	NamespaceSelector map[string]string
+	Fail              map[string]int
+	Alert             map[string]int
+}
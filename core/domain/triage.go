@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+)
+
+// the states of the triaged findings
+const (
+	TriageAcknowledged = "acknowledged"
+	TriageSnoozed      = "snoozed"
+)
+
+var (
+	ErrInvalidTriage  = errors.New("a triage needs a workload, a vulnerability and an acknowledged or snoozed state, snoozed until a future date")
+	ErrNoTriage       = errors.New("triage is not enabled")
+	ErrTriageNotFound = errors.New("triage not found")
+)
+
+// Triage is the decision of a user on a finding of a workload, in any package when Package is empty: acknowledged
+// findings are only flagged, snoozed findings are left out of the verdicts and notifications until the given date
+type Triage struct {
+	ID            string     `json:"id"`
+	Wlid          string     `json:"wlid"`
+	Vulnerability string     `json:"vulnerability"`
+	Package       string     `json:"package,omitempty"`
+	State         string     `json:"state"`
+	Until         *time.Time `json:"until,omitempty"`
+	Reason        string     `json:"reason,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// Active tells whether the triage applies at the given time, the snoozes expire at their date
+func (t Triage) Active(now time.Time) bool {
+	return t.State != TriageSnoozed || (t.Until != nil && now.Before(*t.Until))
+}
+
+// Matches tells whether the triage applies to a match of its workload
+func (t Triage) Matches(match v1beta1.Match) bool {
+	return t.Vulnerability == match.Vulnerability.ID && (t.Package == "" || t.Package == match.Artifact.Name)
+}
+
+// Snoozed tells whether a match of a CVE manifest is snoozed by its triage
+func (c CVEManifest) Snoozed(match v1beta1.Match) bool {
+	for _, triage := range c.Triage {
+		if triage.State == TriageSnoozed && triage.Matches(match) {
+			return true
+		}
+	}
+	return false
+}
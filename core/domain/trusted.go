@@ -0,0 +1,21 @@
+package domain
+
+import "errors"
+
+var ErrTrustedDigest = errors.New("image digest trusted, scan skipped")
+
+// TrustedDigestsVersionKey is the context key of the version of the allowlist which trusted the image of a skipped
+// scan
+type TrustedDigestsVersionKey struct{}
+
+// TrustedDigests is an allowlist of vetted image digests, such as golden base images, whose scans are skipped
+type TrustedDigests struct {
+	Version string          `json:"version"`
+	Digests []TrustedDigest `json:"digests"`
+}
+
+// TrustedDigest is a vetted image digest, Image only documents the allowlist
+type TrustedDigest struct {
+	Digest string `json:"digest"`
+	Image  string `json:"image,omitempty"`
+}
@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+const (
+	// AnnotationCVEDBBuilt records the build date of the vulnerabilities database a report was matched against
+	AnnotationCVEDBBuilt = "kubescape.io/cve-db-built"
+	// AnnotationSBOMCreatorVersion records the version of the SBOM creator a stored SBOM was created with
+	AnnotationSBOMCreatorVersion = "kubescape.io/sbom-creator-version"
+	// AnnotationScanProvenance records the ScanProvenance of a CVE manifest as JSON
+	AnnotationScanProvenance = "kubescape.io/scan-provenance"
+	// StageSBOM is the creation of the SBOM, pull and catalog included, in the durations of a ScanProvenance
+	StageSBOM = "sbom"
+)
+
+// VersionInfo reports the versions of the scanners embedded in kubevuln and of its vulnerabilities database
+type VersionInfo struct {
+	Release               string            `json:"release"`
+	SBOMCreatorVersion    string            `json:"sbomCreatorVersion"`
+	CVEScannerVersion     string            `json:"cveScannerVersion"`
+	CVEDBVersion          string            `json:"cveDBVersion"`
+	CVEDBBuilt            *time.Time        `json:"cveDBBuilt,omitempty"`
+	Libraries             map[string]string `json:"libraries"`
+	TrustedDigestsVersion string            `json:"trustedDigestsVersion,omitempty"`
+}
+
+// SBOMCompatibilityReport summarizes the check of the stored SBOMs against the current SBOM creator
+type SBOMCompatibilityReport struct {
+	// Checked counts the stored SBOMs
+	Checked int `json:"checked"`
+	// Incompatible counts the SBOMs created by another SBOM creator version, they are deleted
+	Incompatible int `json:"incompatible"`
+	// Regenerate lists the commands generating again the SBOMs of the incompatible images still running
+	Regenerate []ScanCommand `json:"-"`
+}
+
+// ScanProvenance records which scanner produced a CVE manifest, how and where, it is stored along with the manifest
+// so that the cached results still tell it
+type ScanProvenance struct {
+	Release            string     `json:"release,omitempty"`
+	SBOMCreatorVersion string     `json:"sbomCreatorVersion"`
+	CVEScannerVersion  string     `json:"cveScannerVersion"`
+	CVEDBVersion       string     `json:"cveDBVersion"`
+	CVEDBBuilt         *time.Time `json:"cveDBBuilt,omitempty"`
+	ScanProfile        string     `json:"scanProfile,omitempty"`
+	NodeName           string     `json:"nodeName,omitempty"`
+	// StageDurations are the durations in milliseconds of the stages run by the scan, keyed by stage
+	StageDurations map[string]int64 `json:"stageDurations,omitempty"`
+	ScannedAt      time.Time        `json:"scannedAt"`
+}
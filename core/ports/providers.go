@@ -2,12 +2,14 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/kubescape/kubevuln/core/domain"
 )
 
 // CVEScanner is the port implemented by adapters to be used in ScanService to generate CVE manifests
 type CVEScanner interface {
+	DBBuilt(ctx context.Context) time.Time
 	DBVersion(ctx context.Context) string
 	Ready(ctx context.Context) bool
 	ScanSBOM(ctx context.Context, sbom domain.SBOM) (domain.CVEManifest, error)
@@ -20,9 +22,105 @@ type SBOMCreator interface {
 	Version() string
 }
 
+// SBOMSigner is the port implemented by adapters to be used in ScanService to sign the SBOMs of images as in-toto
+// attestations and to push the attestations to the registries of the images
+type SBOMSigner interface {
+	PushAttestation(ctx context.Context, attestation domain.SBOMAttestation, imageID string, options domain.RegistryOptions) error
+	SignSBOM(ctx context.Context, sbom domain.SBOM, imageID string) (domain.SBOMAttestation, error)
+}
+
+// RepositorySBOMCreator is the port implemented by adapters to be used in ScanService to generate the SBOM of a git
+// repository from its manifests and lockfiles
+type RepositorySBOMCreator interface {
+	CreateRepositorySBOM(ctx context.Context, name string, repository domain.Repository) (domain.SBOM, error)
+}
+
+// LayerDiffSBOMCreator is the port implemented by adapters to be used in ScanService to catalog the layers which
+// differ between two releases of an image
+type LayerDiffSBOMCreator interface {
+	CreateLayerDiffSBOMs(ctx context.Context, baseID, imageID string, options domain.RegistryOptions) (domain.LayerDiffSBOMs, error)
+}
+
+// ImageResolver is the port implemented by adapters to be used in ScanService to pin image tags to digests
+type ImageResolver interface {
+	ResolveDigest(ctx context.Context, imageTag string, options domain.RegistryOptions) (string, error)
+}
+
+// CanaryProvider is the port implemented by adapters to be used in ScanService to provide the reference image of the self-test
+type CanaryProvider interface {
+	CanaryImage(ctx context.Context) (string, domain.RegistryOptions, error)
+}
+
+// VulnerabilityEnricher is the port implemented by adapters to be used in ScanService to attach the attributes of the
+// organization, such as internal risk scores and owners, to the vulnerabilities of the reports, keyed by vulnerability ID
+type VulnerabilityEnricher interface {
+	Enrich(ctx context.Context, ids []string) (map[string]map[string]string, error)
+}
+
+// ExploitProvider is the port implemented by adapters to be used in ScanService to tell which vulnerabilities have
+// public exploits, returning the sources of their exploits keyed by vulnerability ID
+type ExploitProvider interface {
+	Exploits(ctx context.Context, ids []string) (map[string][]string, error)
+}
+
+// ExposureChecker is the port implemented by adapters to be used in ScanService to tell whether a workload is reachable
+// from outside the cluster, weighing the risk score of its images
+type ExposureChecker interface {
+	IsExposed(ctx context.Context, wlid string) (bool, error)
+}
+
+// WorkloadLister is the port implemented by adapters to be used in ScanService to list the container images running in the cluster
+type WorkloadLister interface {
+	ListWorkloadImages(ctx context.Context) ([]domain.WorkloadImage, error)
+}
+
+// NamespaceLabeler is the port implemented by adapters to be used in ScanService to read the labels of the namespaces
+// selected by the severity thresholds
+type NamespaceLabeler interface {
+	NamespaceLabels(ctx context.Context, namespace string) (map[string]string, error)
+}
+
+// WorkloadAnnotator is the port implemented by adapters to be used in ScanService to read the annotations of the
+// workloads, letting them opt out of the scans
+type WorkloadAnnotator interface {
+	WorkloadAnnotations(ctx context.Context, wlid string) (map[string]string, error)
+}
+
+// WorkloadPatcher is the port implemented by adapters to be used in ScanService to add annotations to the workloads,
+// summarizing the results of their scans
+type WorkloadPatcher interface {
+	PatchWorkloadAnnotations(ctx context.Context, wlid string, annotations map[string]string) error
+}
+
+// Notifier is the port implemented by adapters to be used in ScanService to notify the caller of a scan completion
+type Notifier interface {
+	Notify(ctx context.Context, report domain.ScanReport) error
+}
+
+// ResultSink is the port implemented by adapters to be used in ScanService to forward the findings of the completed
+// scans to other backends than the platform, such as a SIEM, as selected by the domain.ResultRoute
+type ResultSink interface {
+	SendResults(ctx context.Context, report domain.ScanReport, findings []domain.Finding) error
+}
+
+// ProgressBroker is the port implemented by adapters to be used in ScanService to publish the progress of scans,
+// and in HTTPController to stream it
+type ProgressBroker interface {
+	Publish(progress domain.Progress)
+	Subscribe(ctx context.Context) <-chan domain.Progress
+}
+
+// RelevancyProvider is the port implemented by adapters to be used in ScanService to retrieve the SBOM of the packages
+// a workload instance uses at runtime, sbom is the SBOM of its image when already at hand, the returned SBOM is empty
+// when there is no relevancy data for the instance
+type RelevancyProvider interface {
+	GetRelevantSBOM(ctx context.Context, workload domain.ScanCommand, sbom domain.SBOM) (domain.SBOM, error)
+}
+
 // Platform is the port implemented by adapters to be used in ScanService to report scan results and send telemetry data
 type Platform interface {
 	GetCVEExceptions(ctx context.Context) (domain.CVEExceptions, error)
 	SendStatus(ctx context.Context, step int) error
 	SubmitCVE(ctx context.Context, cve domain.CVEManifest, cvep domain.CVEManifest) error
+	SubmitTombstone(ctx context.Context, wlid string) error
 }
@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/kubescape/kubevuln/core/domain"
 )
@@ -19,3 +20,64 @@ type SBOMRepository interface {
 	GetSBOMp(ctx context.Context, name, SBOMCreatorVersion string) (domain.SBOM, error)
 	StoreSBOM(ctx context.Context, sbom domain.SBOM) error
 }
+
+// SBOMMigrationRepository is the port implemented by adapters to be used in ScanService to upgrade the stored SBOMs in place
+type SBOMMigrationRepository interface {
+	UpdateSBOM(ctx context.Context, sbom domain.SBOM) error
+}
+
+// ScanResultRepository is the port implemented by adapters to be used in ScanService to garbage collect the stored scan results
+type ScanResultRepository interface {
+	ListScanResults(ctx context.Context) ([]domain.StoredResult, error)
+	DeleteScanResult(ctx context.Context, result domain.StoredResult) error
+	MarkOrphaned(ctx context.Context, namespace, kind, name string) error
+}
+
+// ScanTimeRepository is the port implemented by adapters to be used in ScanService to read when the images were last scanned
+type ScanTimeRepository interface {
+	ListScanTimes(ctx context.Context) (map[string]time.Time, error)
+}
+
+// ScanQueueRepository is the port implemented by adapters to be used in HTTPController to persist pending scans across restarts
+type ScanQueueRepository interface {
+	Enqueue(ctx context.Context, scan domain.QueuedScan) error
+	Dequeue(ctx context.Context, id string) error
+	ListQueued(ctx context.Context) ([]domain.QueuedScan, error)
+}
+
+// PseudonymRepository is the port implemented by adapters to be used by the Platform to keep resolvable in-cluster the
+// pseudonyms of the workload identifiers submitted in privacy mode
+type PseudonymRepository interface {
+	StorePseudonym(ctx context.Context, pseudonym, original string) error
+}
+
+// FalsePositiveRepository is the port implemented by adapters to be used in ScanService to keep the false positive
+// feedback of the users
+type FalsePositiveRepository interface {
+	DeleteFalsePositive(ctx context.Context, id string) error
+	ListFalsePositives(ctx context.Context) ([]domain.FalsePositive, error)
+	StoreFalsePositive(ctx context.Context, falsePositive domain.FalsePositive) error
+}
+
+// TriageRepository is the port implemented by adapters to be used in ScanService to keep the triage of the findings
+// acknowledged or snoozed by the users
+type TriageRepository interface {
+	DeleteTriage(ctx context.Context, id string) error
+	ListTriage(ctx context.Context) ([]domain.Triage, error)
+	StoreTriage(ctx context.Context, triage domain.Triage) error
+}
+
+// InventoryRepository is the port implemented by adapters to be used in ScanService to keep the inventory of the
+// cluster posted by the operator, listed in place of the running pods until it is posted
+type InventoryRepository interface {
+	WorkloadLister
+	StoreInventory(ctx context.Context, images []domain.WorkloadImage) error
+}
+
+// ScanFailureRepository is the port implemented by adapters to be used in ScanService to keep the last scan failure
+// of each image across restarts
+type ScanFailureRepository interface {
+	DeleteScanFailure(ctx context.Context, image string) error
+	ListScanFailures(ctx context.Context) ([]domain.ScanFailure, error)
+	StoreScanFailure(ctx context.Context, failure domain.ScanFailure) error
+}
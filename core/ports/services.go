@@ -8,11 +8,41 @@ import (
 
 // ScanService is the port implemented by the business component ScanService
 type ScanService interface {
+	AddFalsePositive(ctx context.Context, falsePositive domain.FalsePositive) (domain.FalsePositive, error)
+	AddTriage(ctx context.Context, triage domain.Triage) (domain.Triage, error)
+	CacheStats(ctx context.Context) domain.CacheReport
+	CheckSBOMCompatibility(ctx context.Context) (domain.SBOMCompatibilityReport, error)
+	CollectGarbage(ctx context.Context) (domain.GCReport, error)
+	CompareScans(ctx context.Context, request domain.DiffRequest) (domain.ScanDiff, error)
+	Coverage(ctx context.Context) (domain.CoverageReport, error)
+	DeleteFalsePositive(ctx context.Context, id string) error
+	DeleteTriage(ctx context.Context, id string) error
+	DeleteWorkload(ctx context.Context, wlid string) error
+	ExportBundle(ctx context.Context, start func(domain.SBOMBundle) error, write func(domain.BundledSBOM) error) error
+	ExportFalsePositives(ctx context.Context, format string) ([]byte, error)
+	ExportResults(ctx context.Context, wlid string, write func(domain.ResultRow) error) error
 	GenerateSBOM(ctx context.Context) error
+	HTMLReport(ctx context.Context, scope string) ([]byte, error)
+	ImportResults(ctx context.Context, results domain.ResultsBundle) (domain.BundleImportReport, error)
+	ListFalsePositives(ctx context.Context) ([]domain.FalsePositive, error)
+	ListTriage(ctx context.Context, wlid string) ([]domain.Triage, error)
+	MigrateSBOMs(ctx context.Context) (domain.SBOMMigrationReport, error)
+	PlanScans(ctx context.Context, commands []domain.ScanCommand) (domain.ScanPlan, error)
 	Ready(ctx context.Context) bool
+	ScanBundle(ctx context.Context, bundle domain.SBOMBundle) (domain.ResultsBundle, error)
 	ScanCVE(ctx context.Context) error
+	ScanEvents(ctx context.Context, scanID string) (domain.ScanEvents, error)
+	ScanFailures(ctx context.Context, image string) ([]domain.ScanFailure, error)
+	ScanLayerDiff(ctx context.Context, request domain.LayerDiffRequest) (domain.LayerDiff, error)
 	ScanRegistry(ctx context.Context) error
+	ScanRelayedSBOM(ctx context.Context) error
+	ScanRepository(ctx context.Context) error
+	SelfTest(ctx context.Context) (domain.SelfTestReport, error)
+	SyncInventory(ctx context.Context, inventory domain.Inventory) (domain.InventorySync, error)
 	ValidateGenerateSBOM(ctx context.Context, workload domain.ScanCommand) (context.Context, error)
 	ValidateScanCVE(ctx context.Context, workload domain.ScanCommand) (context.Context, error)
 	ValidateScanRegistry(ctx context.Context, workload domain.ScanCommand) (context.Context, error)
+	ValidateScanRelayedSBOM(ctx context.Context, workload domain.ScanCommand) (context.Context, error)
+	ValidateScanRepository(ctx context.Context, workload domain.ScanCommand) (context.Context, error)
+	Version(ctx context.Context) domain.VersionInfo
 }
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+)
+
+// WithResultAnnotations annotates the scanned workloads with the result of the latest scan of each of their
+// containers, patched with patcher
+func WithResultAnnotations(patcher ports.WorkloadPatcher) ScanServiceOption {
+	return func(s *ScanService) {
+		s.workloadPatcher = patcher
+	}
+}
+
+// annotateWorkload patches the workload of a scan with its domain.WorkloadScanResult, errors are only logged; the
+// scans without workload or container and the workloads opted out of the scans are left alone
+func (s *ScanService) annotateWorkload(ctx context.Context, workload domain.ScanCommand, cve domain.CVEManifest, scanErr error) {
+	if s.workloadPatcher == nil || workload.Wlid == "" || workload.ContainerName == "" || errors.Is(scanErr, domain.ErrSkippedByAnnotation) {
+		return
+	}
+	report := s.scanReport(ctx, workload, cve, scanErr)
+	result := domain.WorkloadScanResult{
+		ImageHash:  workload.ImageHash,
+		ScannedAt:  time.Now().UTC().Truncate(time.Second),
+		Verdict:    report.Verdict,
+		Severities: report.Summary,
+	}
+	if scanErr != nil {
+		result.Code = domain.ErrorCode(scanErr)
+	}
+	value, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	annotations := map[string]string{domain.AnnotationScanResultPrefix + workload.ContainerName: string(value)}
+	if err := s.workloadPatcher.PatchWorkloadAnnotations(ctx, workload.Wlid, annotations); err != nil {
+		logger.L().Ctx(ctx).Warning("failed to annotate workload", helpers.Error(err),
+			helpers.String("wlid", workload.Wlid),
+			helpers.String("containerName", workload.ContainerName))
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// workloadPatches is a WorkloadPatcher recording the annotations patched by wlid
+type workloadPatches map[string]map[string]string
+
+func (w workloadPatches) PatchWorkloadAnnotations(_ context.Context, wlid string, annotations map[string]string) error {
+	w[wlid] = annotations
+	return nil
+}
+
+func TestScanService_annotateWorkload(t *testing.T) {
+	const wlid = "wlid://cluster-minikube/namespace-prod/deployment-nginx"
+	cve := manifestWithFindings(
+		domain.Finding{ID: "CVE-2023-0286", Package: "openssl", Version: "3.0.7", Severity: "High"},
+		domain.Finding{ID: "CVE-2022-37434", Package: "zlib", Version: "1.2.12", Severity: "Critical"},
+	)
+	tests := []struct {
+		name    string
+		wlid    string
+		scanErr error
+		want    *domain.WorkloadScanResult
+	}{
+		{
+			name: "failed thresholds",
+			wlid: wlid,
+			want: &domain.WorkloadScanResult{ImageHash: "sha256:abc", Verdict: domain.VerdictFail, Severities: map[string]int{"Critical": 1, "High": 1}},
+		},
+		{
+			name:    "submission error",
+			wlid:    wlid,
+			scanErr: domain.ErrCircuitOpen,
+			want:    &domain.WorkloadScanResult{ImageHash: "sha256:abc", Verdict: domain.VerdictError, Severities: map[string]int{"Critical": 1, "High": 1}, Code: domain.ErrorCodeSubmit},
+		},
+		{
+			name:    "opted out",
+			wlid:    wlid,
+			scanErr: domain.ErrSkippedByAnnotation,
+		},
+		{
+			name: "no workload",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patches := workloadPatches{}
+			s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockCVEAdapter(),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockPlatform(),
+				false,
+				WithResultAnnotations(patches),
+				WithSeverityThresholds([]domain.SeverityThresholds{{Name: "strict", Fail: map[string]int{"Critical": 0}}}, nil))
+			workload := domain.ScanCommand{Wlid: tt.wlid, ContainerName: "nginx", ImageHash: "sha256:abc"}
+			s.annotateWorkload(context.TODO(), workload, cve, tt.scanErr)
+			if tt.want == nil {
+				assert.Empty(t, patches)
+				return
+			}
+			value, ok := patches[wlid]["vulnerabilities.kubescape.io/nginx"]
+			require.True(t, ok)
+			var got domain.WorkloadScanResult
+			require.NoError(t, json.Unmarshal([]byte(value), &got))
+			assert.NotZero(t, got.ScannedAt)
+			got.ScannedAt = tt.want.ScannedAt
+			assert.Equal(t, *tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+)
+
+// WithSBOMSigning signs the generated SBOMs with signer, the signatures are stored with the SBOMs and, when push is
+// set, the attestations are pushed to the registries of the images
+func WithSBOMSigning(signer ports.SBOMSigner, push bool) ScanServiceOption {
+	return func(s *ScanService) {
+		s.sbomSigner = signer
+		s.pushAttestations = push
+	}
+}
+
+// signSBOM annotates a generated SBOM with the signature of its attestation, the SBOM goes unsigned when it cannot be
+// signed
+func (s *ScanService) signSBOM(ctx context.Context, workload domain.ScanCommand, sbom domain.SBOM) domain.SBOM {
+	if s.sbomSigner == nil || sbom.Content == nil || sbom.Status == instanceidhandler.Incomplete {
+		return sbom
+	}
+	attestation, err := s.sbomSigner.SignSBOM(ctx, sbom, workload.ImageHash)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to sign SBOM", helpers.Error(err),
+			helpers.String("imageSlug", workload.ImageSlug))
+		return sbom
+	}
+	annotations := make(map[string]string, len(sbom.Annotations)+3)
+	for key, value := range sbom.Annotations {
+		annotations[key] = value
+	}
+	annotations[domain.AnnotationSBOMSignature] = attestation.Signature
+	annotations[domain.AnnotationSBOMSignatureKeyID] = attestation.KeyID
+	annotations[domain.AnnotationSBOMAttestationDigest] = attestation.StatementDigest
+	sbom.Annotations = annotations
+	if s.pushAttestations {
+		err = s.sbomSigner.PushAttestation(ctx, attestation, workload.ImageHash, optionsFromWorkload(workload))
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to push SBOM attestation", helpers.Error(err),
+				helpers.String("imageSlug", workload.ImageSlug))
+		}
+	}
+	return sbom
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSigner signs any SBOM and records the attestations pushed
+type fakeSigner struct {
+	err    error
+	pushed []string
+}
+
+func (f *fakeSigner) PushAttestation(_ context.Context, _ domain.SBOMAttestation, imageID string, _ domain.RegistryOptions) error {
+	f.pushed = append(f.pushed, imageID)
+	return f.err
+}
+
+func (f *fakeSigner) SignSBOM(_ context.Context, _ domain.SBOM, _ string) (domain.SBOMAttestation, error) {
+	if f.err != nil {
+		return domain.SBOMAttestation{}, f.err
+	}
+	return domain.SBOMAttestation{Signature: "c2lnbmF0dXJl", KeyID: "keyID", StatementDigest: "sha256:digest"}, nil
+}
+
+func TestScanService_signSBOM(t *testing.T) {
+	workload := domain.ScanCommand{
+		ImageSlug: "imageSlug",
+		ImageHash: "k8s.gcr.io/kube-proxy@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+	}
+	sbom := domain.SBOM{
+		Name:        "imageSlug",
+		Content:     &v1beta1.Document{},
+		Annotations: map[string]string{domain.AnnotationImageSlug: "imageSlug"},
+	}
+
+	s := &ScanService{}
+	assert.Equal(t, sbom, s.signSBOM(context.TODO(), workload, sbom))
+
+	signer := &fakeSigner{}
+	WithSBOMSigning(signer, false)(s)
+	signed := s.signSBOM(context.TODO(), workload, sbom)
+	assert.Equal(t, map[string]string{
+		domain.AnnotationImageSlug:             "imageSlug",
+		domain.AnnotationSBOMSignature:         "c2lnbmF0dXJl",
+		domain.AnnotationSBOMSignatureKeyID:    "keyID",
+		domain.AnnotationSBOMAttestationDigest: "sha256:digest",
+	}, signed.Annotations)
+	// the annotations of the original SBOM are left untouched
+	assert.Len(t, sbom.Annotations, 1)
+	assert.Empty(t, signer.pushed)
+
+	// incomplete SBOMs and SBOMs without content are not signed
+	incomplete := sbom
+	incomplete.Status = instanceidhandler.Incomplete
+	assert.Equal(t, incomplete, s.signSBOM(context.TODO(), workload, incomplete))
+	assert.Equal(t, domain.SBOM{Name: "imageSlug"}, s.signSBOM(context.TODO(), workload, domain.SBOM{Name: "imageSlug"}))
+
+	WithSBOMSigning(signer, true)(s)
+	s.signSBOM(context.TODO(), workload, sbom)
+	assert.Equal(t, []string{workload.ImageHash}, signer.pushed)
+
+	// the SBOM goes unsigned when it cannot be signed
+	WithSBOMSigning(&fakeSigner{err: domain.ErrMockError}, true)(s)
+	assert.Equal(t, sbom, s.signSBOM(context.TODO(), workload, sbom))
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// errMissingSBOMContent is reported for the bundled SBOMs without content
+var errMissingSBOMContent = errors.New("missing SBOM content")
+
+// bundles holds the dependencies of the offline bundles
+type bundles struct {
+	// results lists the stored SBOMs to export, nil without storage
+	results ports.ScanResultRepository
+}
+
+// WithBundles enables the offline bundles: the export of the stored SBOMs listed by results, their scan by a
+// connected instance and the import of the vulnerability manifests back, results is nil without storage
+func WithBundles(results ports.ScanResultRepository) ScanServiceOption {
+	return func(s *ScanService) {
+		s.bundles = &bundles{results: results}
+	}
+}
+
+// ExportBundle exports the stored SBOMs created by the current SBOM creator, the incomplete ones and the ones which
+// cannot be read are left out. start is passed the header of the bundle, then write each SBOM as it is read, so that
+// the bundle is never held in memory.
+func (s *ScanService) ExportBundle(ctx context.Context, start func(domain.SBOMBundle) error, write func(domain.BundledSBOM) error) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.ExportBundle")
+	defer span.End()
+
+	if s.bundles == nil {
+		return domain.ErrNoBundles
+	}
+	if s.bundles.results == nil || !s.storage {
+		return domain.ErrNoBundleStore
+	}
+	results, err := s.bundles.results.ListScanResults(ctx)
+	if err != nil {
+		return err
+	}
+	version := s.sbomCreator.Version()
+	err = start(domain.SBOMBundle{
+		FormatVersion:      domain.BundleFormatVersion,
+		CreatedAt:          time.Now().UTC(),
+		Release:            s.release,
+		SBOMCreatorVersion: version,
+	})
+	if err != nil {
+		return err
+	}
+	var exported, failed int
+	for _, result := range results {
+		if result.Kind != domain.StoredSBOM {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		sbom, err := s.sbomRepository.GetSBOM(ctx, result.Name, version)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("SBOM not exported", helpers.Error(err),
+				helpers.String("imageSlug", result.Name))
+			failed++
+			continue
+		}
+		if sbom.Content == nil || sbom.Status == instanceidhandler.Incomplete {
+			continue
+		}
+		err = write(domain.BundledSBOM{
+			ImageSlug:          sbom.Name,
+			SBOMCreatorName:    sbom.SBOMCreatorName,
+			SBOMCreatorVersion: sbom.SBOMCreatorVersion,
+			Status:             sbom.Status,
+			Annotations:        sbom.Annotations,
+			Labels:             sbom.Labels,
+			Content:            sbom.Content,
+		})
+		if err != nil {
+			return err
+		}
+		exported++
+	}
+	logger.L().Info("exported SBOM bundle",
+		helpers.Int("sboms", exported),
+		helpers.Int("failed", failed))
+	return nil
+}
+
+// ScanBundle matches the vulnerabilities of the SBOMs of a bundle, the SBOMs which cannot be matched are reported in
+// their result instead of failing the whole bundle
+func (s *ScanService) ScanBundle(ctx context.Context, bundle domain.SBOMBundle) (domain.ResultsBundle, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.ScanBundle")
+	defer span.End()
+
+	if s.bundles == nil {
+		return domain.ResultsBundle{}, domain.ErrNoBundles
+	}
+	if bundle.FormatVersion != domain.BundleFormatVersion {
+		return domain.ResultsBundle{}, fmt.Errorf("%w %d", domain.ErrBundleFormat, bundle.FormatVersion)
+	}
+	results := domain.ResultsBundle{
+		FormatVersion:     domain.BundleFormatVersion,
+		CreatedAt:         time.Now().UTC(),
+		Release:           s.release,
+		CVEScannerVersion: s.cveScanner.Version(ctx),
+		CVEDBVersion:      s.cveScanner.DBVersion(ctx),
+		Results:           make([]domain.BundledResult, 0, len(bundle.SBOMs)),
+	}
+	for _, bundled := range bundle.SBOMs {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		result := domain.BundledResult{ImageSlug: bundled.ImageSlug}
+		cve, err := s.scanBundledSBOM(ctx, bundled)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("bundled SBOM not scanned", helpers.Error(err),
+				helpers.String("imageSlug", bundled.ImageSlug))
+			result.Error = err.Error()
+			results.Failed++
+		} else {
+			result.SBOMCreatorName = cve.SBOMCreatorName
+			result.SBOMCreatorVersion = cve.SBOMCreatorVersion
+			result.CVEScannerName = cve.CVEScannerName
+			result.CVEScannerVersion = cve.CVEScannerVersion
+			result.CVEDBVersion = cve.CVEDBVersion
+			result.Annotations = cve.Annotations
+			result.Labels = cve.Labels
+			result.Content = cve.Content
+			results.Scanned++
+		}
+		results.Results = append(results.Results, result)
+	}
+	logger.L().Info("scanned SBOM bundle",
+		helpers.Int("scanned", results.Scanned),
+		helpers.Int("failed", results.Failed))
+	return results, nil
+}
+
+// scanBundledSBOM matches the vulnerabilities of a bundled SBOM
+func (s *ScanService) scanBundledSBOM(ctx context.Context, bundled domain.BundledSBOM) (domain.CVEManifest, error) {
+	switch {
+	case bundled.ImageSlug == "":
+		return domain.CVEManifest{}, domain.ErrMissingImageInfo
+	case bundled.Content == nil:
+		return domain.CVEManifest{}, errMissingSBOMContent
+	case bundled.Status == instanceidhandler.Incomplete:
+		return domain.CVEManifest{}, domain.ErrIncompleteSBOM
+	}
+	return s.scanSBOM(ctx, domain.SBOM{
+		Name:               bundled.ImageSlug,
+		SBOMCreatorName:    bundled.SBOMCreatorName,
+		SBOMCreatorVersion: bundled.SBOMCreatorVersion,
+		Status:             bundled.Status,
+		Content:            bundled.Content,
+		Annotations:        bundled.Annotations,
+		Labels:             bundled.Labels,
+	})
+}
+
+// ImportResults stores the vulnerability manifests of a results bundle, as if the images had been scanned here
+func (s *ScanService) ImportResults(ctx context.Context, results domain.ResultsBundle) (domain.BundleImportReport, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.ImportResults")
+	defer span.End()
+
+	if s.bundles == nil {
+		return domain.BundleImportReport{}, domain.ErrNoBundles
+	}
+	if !s.storage {
+		return domain.BundleImportReport{}, domain.ErrNoBundleStore
+	}
+	if results.FormatVersion != domain.BundleFormatVersion {
+		return domain.BundleImportReport{}, fmt.Errorf("%w %d", domain.ErrBundleFormat, results.FormatVersion)
+	}
+	var report domain.BundleImportReport
+	for _, result := range results.Results {
+		if result.ImageSlug == "" || result.Content == nil {
+			report.Skipped++
+			continue
+		}
+		cve := domain.CVEManifest{
+			Name:               result.ImageSlug,
+			SBOMCreatorName:    result.SBOMCreatorName,
+			SBOMCreatorVersion: result.SBOMCreatorVersion,
+			CVEScannerName:     result.CVEScannerName,
+			CVEScannerVersion:  result.CVEScannerVersion,
+			CVEDBVersion:       result.CVEDBVersion,
+			Annotations:        result.Annotations,
+			Labels:             result.Labels,
+			Content:            result.Content,
+		}
+		if err := s.cveRepository.StoreCVE(ctx, cve, false); err != nil {
+			logger.L().Ctx(ctx).Warning("error storing CVE", helpers.Error(err),
+				helpers.String("imageSlug", result.ImageSlug))
+			report.Failed++
+			continue
+		}
+		if err := s.cveRepository.StoreCVESummary(ctx, cve, domain.CVEManifest{}, false); err != nil {
+			logger.L().Ctx(ctx).Warning("error storing CVE summary", helpers.Error(err),
+				helpers.String("imageSlug", result.ImageSlug))
+		}
+		report.Stored++
+	}
+	logger.L().Info("imported results bundle",
+		helpers.Int("stored", report.Stored),
+		helpers.Int("skipped", report.Skipped),
+		helpers.Int("failed", report.Failed))
+	return report, nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanService_bundles(t *testing.T) {
+	ctx := context.TODO()
+	sbomAdapter := adapters.NewMockSBOMAdapter(false, false, false)
+	cveAdapter := adapters.NewMockCVEAdapter()
+	offlineStorage := repositories.NewMemoryStorage(false, false)
+	offline := NewScanService(sbomAdapter, offlineStorage, cveAdapter, offlineStorage, adapters.NewMockPlatform(), true,
+		WithBundles(offlineStorage))
+	connected := NewScanService(sbomAdapter, nil, cveAdapter, nil, adapters.NewMockPlatform(), false,
+		WithBundles(nil))
+
+	version := sbomAdapter.Version()
+	require.NoError(t, offlineStorage.StoreSBOM(ctx, domain.SBOM{
+		Name:               "nginx-1-14-1",
+		SBOMCreatorVersion: version,
+		Annotations:        map[string]string{domain.AnnotationImageSlug: "nginx-1-14-1"},
+		Content:            &v1beta1.Document{},
+	}))
+	require.NoError(t, offlineStorage.StoreSBOM(ctx, domain.SBOM{
+		Name:               "huge-image",
+		SBOMCreatorVersion: version,
+		Status:             instanceidhandler.Incomplete,
+		Annotations:        map[string]string{},
+		Content:            &v1beta1.Document{},
+	}))
+
+	// the incomplete SBOMs are not exported
+	var bundle domain.SBOMBundle
+	err := offline.ExportBundle(ctx, func(header domain.SBOMBundle) error {
+		bundle = header
+		return nil
+	}, func(sbom domain.BundledSBOM) error {
+		bundle.SBOMs = append(bundle.SBOMs, sbom)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.BundleFormatVersion, bundle.FormatVersion)
+	assert.Equal(t, version, bundle.SBOMCreatorVersion)
+	require.Len(t, bundle.SBOMs, 1)
+	assert.Equal(t, "nginx-1-14-1", bundle.SBOMs[0].ImageSlug)
+
+	// the SBOMs which cannot be matched are reported in their result
+	bundle.SBOMs = append(bundle.SBOMs, domain.BundledSBOM{ImageSlug: "empty"})
+	results, err := connected.ScanBundle(ctx, bundle)
+	require.NoError(t, err)
+	assert.Equal(t, 1, results.Scanned)
+	assert.Equal(t, 1, results.Failed)
+	require.Len(t, results.Results, 2)
+	assert.Equal(t, "nginx-1-14-1", results.Results[0].ImageSlug)
+	assert.NotNil(t, results.Results[0].Content)
+	assert.Contains(t, results.Results[0].Annotations, domain.AnnotationScanProvenance)
+	assert.Equal(t, "missing SBOM content", results.Results[1].Error)
+
+	report, err := offline.ImportResults(ctx, results)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BundleImportReport{Stored: 1, Skipped: 1}, report)
+	cve, err := offlineStorage.GetCVE(ctx, "nginx-1-14-1", version, results.CVEScannerVersion, results.CVEDBVersion)
+	require.NoError(t, err)
+	assert.NotNil(t, cve.Content)
+}
+
+func TestScanService_bundles_errors(t *testing.T) {
+	ctx := context.TODO()
+	storage := repositories.NewMemoryStorage(false, false)
+	newService := func(storage bool, opts ...ScanServiceOption) *ScanService {
+		memoryStorage := repositories.NewMemoryStorage(false, false)
+		return NewScanService(adapters.NewMockSBOMAdapter(false, false, false), memoryStorage, adapters.NewMockCVEAdapter(),
+			memoryStorage, adapters.NewMockPlatform(), storage, opts...)
+	}
+
+	export := func(s *ScanService) error {
+		return s.ExportBundle(ctx, func(domain.SBOMBundle) error {
+			return nil
+		}, func(domain.BundledSBOM) error {
+			return nil
+		})
+	}
+
+	disabled := newService(true)
+	err := export(disabled)
+	assert.ErrorIs(t, err, domain.ErrNoBundles)
+	_, err = disabled.ScanBundle(ctx, domain.SBOMBundle{FormatVersion: domain.BundleFormatVersion})
+	assert.ErrorIs(t, err, domain.ErrNoBundles)
+	_, err = disabled.ImportResults(ctx, domain.ResultsBundle{FormatVersion: domain.BundleFormatVersion})
+	assert.ErrorIs(t, err, domain.ErrNoBundles)
+
+	withoutStorage := newService(false, WithBundles(nil))
+	err = export(withoutStorage)
+	assert.ErrorIs(t, err, domain.ErrNoBundleStore)
+	_, err = withoutStorage.ImportResults(ctx, domain.ResultsBundle{FormatVersion: domain.BundleFormatVersion})
+	assert.ErrorIs(t, err, domain.ErrNoBundleStore)
+
+	enabled := newService(true, WithBundles(storage))
+	_, err = enabled.ScanBundle(ctx, domain.SBOMBundle{FormatVersion: 1})
+	assert.ErrorIs(t, err, domain.ErrBundleFormat)
+	_, err = enabled.ImportResults(ctx, domain.ResultsBundle{})
+	assert.ErrorIs(t, err, domain.ErrBundleFormat)
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"go.opentelemetry.io/otel"
+)
+
+// cacheAgeBuckets are the upper bounds of the age buckets of the cache hits, the last one is unbounded
+var cacheAgeBuckets = []struct {
+	maxAge string
+	bound  time.Duration
+}{
+	{"1h", time.Hour},
+	{"1d", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+	{"+Inf", 0},
+}
+
+// cacheStats counts the hits and misses of the caches of the scan pipeline, it is safe for concurrent use
+type cacheStats struct {
+	mu     sync.Mutex
+	since  time.Time
+	caches map[string]*domain.CacheStats
+}
+
+func newCacheStats() *cacheStats {
+	c := &cacheStats{since: time.Now().UTC(), caches: map[string]*domain.CacheStats{}}
+	for _, cache := range []string{domain.CacheCVE, domain.CacheSBOM} {
+		c.caches[cache] = newCacheEntry(cache)
+	}
+	return c
+}
+
+func newCacheEntry(cache string) *domain.CacheStats {
+	stats := &domain.CacheStats{Cache: cache, Ages: make([]domain.CacheAgeBucket, len(cacheAgeBuckets))}
+	for i, bucket := range cacheAgeBuckets {
+		stats.Ages[i].MaxAge = bucket.maxAge
+	}
+	return stats
+}
+
+// hit records an entry of the cache served instead of computed, sparing to pull bytes of the image; the age of an
+// entry whose creation time is zero is unknown
+func (c *cacheStats) hit(cache string, bytes int64, created time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.entry(cache)
+	stats.Hits++
+	stats.BytesSaved += bytes
+	if created.IsZero() {
+		return
+	}
+	age := time.Since(created)
+	for i, bucket := range cacheAgeBuckets {
+		if bucket.bound == 0 || age <= bucket.bound {
+			stats.Ages[i].Hits++
+			break
+		}
+	}
+}
+
+// miss records an entry of the cache computed for lack of a stored one
+func (c *cacheStats) miss(cache string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entry(cache).Misses++
+}
+
+func (c *cacheStats) entry(cache string) *domain.CacheStats {
+	stats, ok := c.caches[cache]
+	if !ok {
+		stats = newCacheEntry(cache)
+		c.caches[cache] = stats
+	}
+	return stats
+}
+
+// report copies the statistics of the caches, in the order of their names
+func (c *cacheStats) report() domain.CacheReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	report := domain.CacheReport{Since: c.since}
+	for _, cache := range []string{domain.CacheCVE, domain.CacheSBOM} {
+		stats := *c.caches[cache]
+		stats.Ages = append([]domain.CacheAgeBucket{}, stats.Ages...)
+		if lookups := stats.Hits + stats.Misses; lookups > 0 {
+			stats.HitRate = float64(stats.Hits) / float64(lookups)
+		}
+		report.Caches = append(report.Caches, stats)
+	}
+	return report
+}
+
+// CacheStats returns the statistics of the stored SBOMs and CVE manifests served instead of being computed again,
+// since the service started
+func (s *ScanService) CacheStats(ctx context.Context) domain.CacheReport {
+	_, span := otel.Tracer("").Start(ctx, "ScanService.CacheStats")
+	defer span.End()
+	return s.caches.report()
+}
+
+// recordSBOMLookup records whether a stored SBOM was found, the SBOMs are timestamped at their creation
+func (s *ScanService) recordSBOMLookup(sbom domain.SBOM) {
+	if sbom.Content == nil {
+		s.caches.miss(domain.CacheSBOM)
+		return
+	}
+	var created time.Time
+	if sbom.Content.CreationInfo != nil {
+		created, _ = time.Parse(time.RFC3339, sbom.Content.CreationInfo.Created)
+	}
+	s.caches.hit(domain.CacheSBOM, imageSize(sbom.Annotations), created)
+}
+
+// recordCVELookup records whether a stored CVE manifest was found, the manifests are timestamped by their provenance
+func (s *ScanService) recordCVELookup(cve domain.CVEManifest) {
+	if cve.Content == nil {
+		s.caches.miss(domain.CacheCVE)
+		return
+	}
+	var provenance domain.ScanProvenance
+	_ = json.Unmarshal([]byte(cve.Annotations[domain.AnnotationScanProvenance]), &provenance)
+	s.caches.hit(domain.CacheCVE, imageSize(cve.Annotations), provenance.ScannedAt)
+}
+
+// imageSize returns the size of the layers of the image annotated on its documents, zero when unknown
+func imageSize(annotations map[string]string) int64 {
+	size, _ := strconv.ParseInt(annotations[domain.AnnotationImageSize], 10, 64)
+	return size
+}
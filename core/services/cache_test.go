@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanService_CacheStats(t *testing.T) {
+	ctx := context.TODO()
+	storage := repositories.NewMemoryStorage(false, false)
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false), storage, adapters.NewMockCVEAdapter(), storage, adapters.NewMockPlatform(), true)
+	s.Ready(ctx)
+	workload := domain.ScanCommand{
+		ImageSlug: "imageSlug",
+		ImageHash: "k8s.gcr.io/kube-proxy@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+		Wlid:      "wlid://cluster-minikube/namespace-kube-system/daemonset-kube-proxy",
+	}
+	// the first scan computes the documents, the second one is served the stored CVE manifest
+	for i := 0; i < 2; i++ {
+		scanCtx, err := s.ValidateScanCVE(ctx, workload)
+		tools.EnsureSetup(t, err == nil)
+		require.NoError(t, s.ScanCVE(scanCtx))
+	}
+	report := s.CacheStats(ctx)
+	assert.False(t, report.Since.IsZero())
+	require.Len(t, report.Caches, 2)
+	cve, sbom := report.Caches[0], report.Caches[1]
+	assert.Equal(t, domain.CacheCVE, cve.Cache)
+	assert.Equal(t, int64(1), cve.Hits)
+	assert.Equal(t, int64(1), cve.Misses)
+	assert.Equal(t, 0.5, cve.HitRate)
+	// the stored CVE manifest was just scanned
+	assert.Equal(t, domain.CacheAgeBucket{MaxAge: "1h", Hits: 1}, cve.Ages[0])
+	assert.Equal(t, domain.CacheSBOM, sbom.Cache)
+	assert.Equal(t, int64(0), sbom.Hits)
+	assert.Equal(t, int64(1), sbom.Misses)
+	assert.Zero(t, sbom.HitRate)
+}
+
+func TestScanService_recordLookups(t *testing.T) {
+	s := &ScanService{caches: newCacheStats()}
+	s.recordSBOMLookup(domain.SBOM{})
+	s.recordSBOMLookup(domain.SBOM{
+		Annotations: map[string]string{domain.AnnotationImageSize: "1024"},
+		Content:     &v1beta1.Document{CreationInfo: &v1beta1.CreationInfo{Created: time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339)}},
+	})
+	// of unknown age and size
+	s.recordSBOMLookup(domain.SBOM{Content: &v1beta1.Document{}})
+	provenance, err := json.Marshal(domain.ScanProvenance{ScannedAt: time.Now().Add(-60 * 24 * time.Hour)})
+	require.NoError(t, err)
+	s.recordCVELookup(domain.CVEManifest{
+		Annotations: map[string]string{domain.AnnotationImageSize: "2048", domain.AnnotationScanProvenance: string(provenance)},
+		Content:     &v1beta1.GrypeDocument{},
+	})
+
+	assert.Equal(t, []domain.CacheStats{
+		{
+			Cache:      domain.CacheCVE,
+			Hits:       1,
+			HitRate:    1,
+			BytesSaved: 2048,
+			Ages:       []domain.CacheAgeBucket{{MaxAge: "1h"}, {MaxAge: "1d"}, {MaxAge: "7d"}, {MaxAge: "30d"}, {MaxAge: "+Inf", Hits: 1}},
+		},
+		{
+			Cache:      domain.CacheSBOM,
+			Hits:       2,
+			Misses:     1,
+			HitRate:    2.0 / 3,
+			BytesSaved: 1024,
+			Ages:       []domain.CacheAgeBucket{{MaxAge: "1h"}, {MaxAge: "1d"}, {MaxAge: "7d", Hits: 1}, {MaxAge: "30d"}, {MaxAge: "+Inf"}},
+		},
+	}, s.CacheStats(context.TODO()).Caches)
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+const defaultCoverageWindow = 7 * 24 * time.Hour
+
+// WithCoverageHistory seeds the last scans of the coverage with the scan times of the results stored in repository,
+// so that the coverage survives restarts
+func WithCoverageHistory(repository ports.ScanTimeRepository) ScanServiceOption {
+	return func(s *ScanService) {
+		s.scanTimes = repository
+	}
+}
+
+// loadLastScans reads the scan times of the stored results once, the scans recorded since are kept
+func (s *ScanService) loadLastScans(ctx context.Context) {
+	s.lastScansLoad.Do(func() {
+		if s.scanTimes == nil {
+			return
+		}
+		scanTimes, err := s.scanTimes.ListScanTimes(ctx)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("error listing scan times", helpers.Error(err))
+			return
+		}
+		s.lastScansMu.Lock()
+		defer s.lastScansMu.Unlock()
+		for digest, scannedAt := range scanTimes {
+			if scannedAt.After(s.lastScans[digest]) {
+				s.lastScans[digest] = scannedAt
+			}
+		}
+	})
+}
+
+// recordScan records the time of the last successful scan of an image
+func (s *ScanService) recordScan(imageHash string) {
+	digest := digestFromImageHash(imageHash)
+	if digest == "" {
+		return
+	}
+	s.lastScansMu.Lock()
+	defer s.lastScansMu.Unlock()
+	s.lastScans[digest] = time.Now()
+}
+
+// lastScan returns the time of the last successful scan of an image
+func (s *ScanService) lastScan(imageHash string) (time.Time, bool) {
+	s.lastScansMu.RLock()
+	defer s.lastScansMu.RUnlock()
+	lastScan, ok := s.lastScans[digestFromImageHash(imageHash)]
+	return lastScan, ok
+}
+
+// Coverage reports which container images running in the cluster had a successful scan within the coverage window,
+// and the last failure of those whose last scan failed; scans are tracked since kubevuln started, and before from the
+// stored results with WithCoverageHistory
+func (s *ScanService) Coverage(ctx context.Context) (domain.CoverageReport, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.Coverage")
+	defer span.End()
+
+	if s.workloadLister == nil {
+		return domain.CoverageReport{}, domain.ErrNoWorkloadLister
+	}
+	images, err := s.workloadLister.ListWorkloadImages(ctx)
+	if err != nil {
+		return domain.CoverageReport{}, err
+	}
+	s.loadLastScans(ctx)
+	report := domain.CoverageReport{
+		Window:    s.coverageWindow.String(),
+		Total:     len(images),
+		Workloads: make([]domain.WorkloadCoverage, 0, len(images)),
+	}
+	for _, image := range images {
+		coverage := domain.WorkloadCoverage{
+			WorkloadImage: image,
+			Status:        domain.CoverageUnscanned,
+		}
+		if lastScan, ok := s.lastScan(image.ImageHash); ok {
+			coverage.LastScan = &lastScan
+			coverage.Status = domain.CoverageStale
+			if time.Since(lastScan) <= s.coverageWindow {
+				coverage.Status = domain.CoverageScanned
+			}
+		}
+		if failure, ok := s.lastFailure(ctx, domain.ScanCommand{ImageHash: image.ImageHash, ImageTag: image.ImageTag}); ok {
+			coverage.LastFailure = &failure
+			report.Failing++
+		}
+		switch coverage.Status {
+		case domain.CoverageScanned:
+			report.Scanned++
+		case domain.CoverageStale:
+			report.Stale++
+		default:
+			report.Unscanned++
+		}
+		report.Workloads = append(report.Workloads, coverage)
+	}
+	if report.Total > 0 {
+		report.Ratio = float64(report.Scanned) / float64(report.Total)
+	}
+	return report, nil
+}
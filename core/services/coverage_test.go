@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+type staticWorkloadLister []domain.WorkloadImage
+
+type staticScanTimes map[string]time.Time
+
+func (s staticScanTimes) ListScanTimes(context.Context) (map[string]time.Time, error) {
+	return s, nil
+}
+
+func (s staticWorkloadLister) ListWorkloadImages(context.Context) ([]domain.WorkloadImage, error) {
+	return s, nil
+}
+
+func TestScanService_Coverage(t *testing.T) {
+	scanned := "k8s.gcr.io/kube-proxy@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137"
+	stale := "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"
+	lister := staticWorkloadLister{
+		{Namespace: "kube-system", Kind: "DaemonSet", Name: "kube-proxy", ContainerName: "kube-proxy", ImageHash: scanned},
+		{Namespace: "default", Kind: "Deployment", Name: "nginx", ContainerName: "nginx", ImageHash: stale},
+		{Namespace: "default", Kind: "Deployment", Name: "redis", ContainerName: "redis", ImageHash: "redis@sha256:1234"},
+	}
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false)
+	_, err := s.Coverage(context.TODO())
+	assert.ErrorIs(t, err, domain.ErrNoWorkloadLister)
+
+	WithCoverageTracking(lister, 24*time.Hour)(s)
+	ctx, err := s.ValidateScanCVE(context.TODO(), domain.ScanCommand{
+		ImageSlug: "imageSlug",
+		ImageHash: scanned,
+		Wlid:      "wlid://cluster-minikube/namespace-kube-system/daemonset-kube-proxy",
+	})
+	tools.EnsureSetup(t, err == nil)
+	tools.EnsureSetup(t, s.ScanCVE(ctx) == nil)
+	s.lastScans[digestFromImageHash(stale)] = time.Now().Add(-48 * time.Hour)
+	s.failures[digestFromImageHash(stale)] = domain.ScanFailure{Image: digestFromImageHash(stale), Reason: domain.FailureAuth, Attempts: 4}
+
+	report, err := s.Coverage(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, 1, report.Scanned)
+	assert.Equal(t, 1, report.Stale)
+	assert.Equal(t, 1, report.Unscanned)
+	assert.Equal(t, 1, report.Failing)
+	assert.InDelta(t, 1.0/3, report.Ratio, 0.001)
+	if assert.NotNil(t, report.Workloads[1].LastFailure) {
+		assert.Equal(t, 4, report.Workloads[1].LastFailure.Attempts)
+	}
+	assert.Nil(t, report.Workloads[0].LastFailure)
+	statuses := []string{}
+	for _, workload := range report.Workloads {
+		statuses = append(statuses, workload.Status)
+	}
+	assert.Equal(t, []string{domain.CoverageScanned, domain.CoverageStale, domain.CoverageUnscanned}, statuses)
+}
+
+func TestScanService_Coverage_history(t *testing.T) {
+	image := "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"
+	lister := staticWorkloadLister{
+		{Namespace: "default", Kind: "Deployment", Name: "nginx", ContainerName: "nginx", ImageHash: image},
+	}
+	// the image was scanned before the restart
+	scannedAt := time.Now().Add(-time.Hour)
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false,
+		WithCoverageTracking(lister, 24*time.Hour),
+		WithCoverageHistory(staticScanTimes{digestFromImageHash(image): scannedAt}))
+	report, err := s.Coverage(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Scanned)
+	if assert.NotNil(t, report.Workloads[0].LastScan) {
+		assert.True(t, scannedAt.Equal(*report.Workloads[0].LastScan))
+	}
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// partialSBOM tells whether the SBOM misses packages of the image, the deadline of the scan having been exceeded or
+// some catalogers having failed; such SBOMs and the CVE manifests matched from them are not stored, so that the next
+// scan of the image catalogs all its packages again
+func partialSBOM(sbom domain.SBOM) bool {
+	return sbom.Annotations[domain.AnnotationPartial] == "true"
+}
+
+// warnDeadlineExceeded logs the scans submitting their results past the deadline given by the operator, which prefers
+// late results to none
+func warnDeadlineExceeded(ctx context.Context, workload domain.ScanCommand) {
+	if workload.Deadline.IsZero() || time.Now().Before(workload.Deadline) {
+		return
+	}
+	logger.L().Ctx(ctx).Warning("scan deadline exceeded, submitting the results anyway",
+		helpers.String("imageSlug", workload.ImageSlug),
+		helpers.String("deadline", workload.Deadline.Format(time.RFC3339)))
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// partialSBOMAdapter creates SBOMs flagged as only listing the OS packages
+type partialSBOMAdapter struct {
+	*adapters.MockSBOMAdapter
+}
+
+func (p partialSBOMAdapter) CreateSBOM(ctx context.Context, name, imageID string, options domain.RegistryOptions) (domain.SBOM, error) {
+	sbom, err := p.MockSBOMAdapter.CreateSBOM(ctx, name, imageID, options)
+	sbom.Annotations[domain.AnnotationPartial] = "true"
+	return sbom, err
+}
+
+func TestScanService_ScanCVE_partial(t *testing.T) {
+	ctx := context.TODO()
+	sbomAdapter := adapters.NewMockSBOMAdapter(false, false, false)
+	cveAdapter := adapters.NewMockCVEAdapter()
+	storage := repositories.NewMemoryStorage(false, false)
+	notifier := adapters.NewMockNotifier()
+	s := NewScanService(partialSBOMAdapter{sbomAdapter},
+		storage,
+		cveAdapter,
+		storage,
+		adapters.NewMockPlatform(),
+		true,
+		WithNotifier(notifier))
+	workload := domain.ScanCommand{
+		CallbackURL: "http://operator:4002/v1/callback",
+		// the deadline is already exceeded, the results are submitted anyway
+		Deadline:  time.Now().Add(-time.Minute),
+		ImageSlug: "imageSlug",
+		ImageHash: "k8s.gcr.io/kube-proxy@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+		Wlid:      "wlid://cluster-minikube/namespace-kube-system/daemonset-kube-proxy",
+	}
+	scanCtx, err := s.ValidateScanCVE(ctx, workload)
+	tools.EnsureSetup(t, err == nil)
+	require.NoError(t, s.ScanCVE(scanCtx))
+
+	// the partial results are flagged
+	reports := notifier.Reports()
+	require.Len(t, reports, 1)
+	assert.Equal(t, domain.VerdictSuccess, reports[0].Verdict)
+	assert.True(t, reports[0].Partial)
+
+	// but not stored, the next scan catalogs all the packages
+	sbom, err := storage.GetSBOM(ctx, workload.ImageSlug, sbomAdapter.Version())
+	require.NoError(t, err)
+	assert.Nil(t, sbom.Content)
+	cve, err := storage.GetCVE(ctx, workload.ImageSlug, sbomAdapter.Version(), cveAdapter.Version(ctx), cveAdapter.DBVersion(ctx))
+	require.NoError(t, err)
+	assert.Nil(t, cve.Content)
+}
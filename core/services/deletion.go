@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	wlidpkg "github.com/armosec/utils-k8s-go/wlid"
+	"github.com/hashicorp/go-multierror"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"go.opentelemetry.io/otel"
+)
+
+// DeleteWorkload marks the stored summaries of a deleted workload as orphaned and, if enabled,
+// sends a tombstone to the platform so that its findings are no longer reported
+func (s *ScanService) DeleteWorkload(ctx context.Context, wlid string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.DeleteWorkload")
+	defer span.End()
+
+	if err := wlidpkg.IsWlidValid(wlid); err != nil {
+		return fmt.Errorf("%w: %s", domain.ErrInvalidWlid, wlid)
+	}
+	var result *multierror.Error
+	if s.orphanRepository != nil {
+		namespace, kind, name := wlidpkg.GetNamespaceFromWlid(wlid), wlidpkg.GetKindFromWlid(wlid), wlidpkg.GetNameFromWlid(wlid)
+		if err := s.orphanRepository.MarkOrphaned(ctx, namespace, kind, name); err != nil {
+			result = multierror.Append(result, fmt.Errorf("failed to mark scan results as orphaned: %w", err))
+		}
+	}
+	if s.sendTombstones {
+		if err := s.platform.SubmitTombstone(ctx, wlid); err != nil {
+			result = multierror.Append(result, fmt.Errorf("failed to submit tombstone: %w", err))
+		}
+	}
+	logger.L().Info("workload deleted",
+		helpers.String("wlid", wlid))
+	return result.ErrorOrNil()
+}
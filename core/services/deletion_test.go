@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+// tombstonePlatform records the submitted tombstones
+type tombstonePlatform struct {
+	adapters.MockPlatform
+	err        error
+	tombstones []string
+}
+
+func (p *tombstonePlatform) SubmitTombstone(_ context.Context, wlid string) error {
+	p.tombstones = append(p.tombstones, wlid)
+	return p.err
+}
+
+func TestScanService_DeleteWorkload(t *testing.T) {
+	wlid := "wlid://cluster-minikube/namespace-default/deployment-nginx"
+	tests := []struct {
+		name           string
+		wlid           string
+		withDeletion   bool
+		sendTombstones bool
+		undeletable    bool
+		platformErr    error
+		wantErr        error
+		wantOrphaned   []string
+		wantTombstones []string
+	}{
+		{
+			name: "disabled",
+			wlid: wlid,
+		},
+		{
+			name:    "invalid wlid",
+			wlid:    "nginx",
+			wantErr: domain.ErrInvalidWlid,
+		},
+		{
+			name:         "results marked as orphaned",
+			wlid:         wlid,
+			withDeletion: true,
+			wantOrphaned: []string{"default/Deployment/nginx"},
+		},
+		{
+			name:           "tombstone sent",
+			wlid:           wlid,
+			withDeletion:   true,
+			sendTombstones: true,
+			wantOrphaned:   []string{"default/Deployment/nginx"},
+			wantTombstones: []string{wlid},
+		},
+		{
+			name:           "tombstone sent even if marking fails",
+			wlid:           wlid,
+			withDeletion:   true,
+			sendTombstones: true,
+			undeletable:    true,
+			wantErr:        domain.ErrMockError,
+			wantTombstones: []string{wlid},
+		},
+		{
+			name:           "tombstone error",
+			wlid:           wlid,
+			withDeletion:   true,
+			sendTombstones: true,
+			platformErr:    domain.ErrExpectedError,
+			wantErr:        domain.ErrExpectedError,
+			wantOrphaned:   []string{"default/Deployment/nginx"},
+			wantTombstones: []string{wlid},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &fakeResultRepository{undeletable: map[string]bool{"nginx": tt.undeletable}}
+			platform := &tombstonePlatform{err: tt.platformErr}
+			var opts []ScanServiceOption
+			if tt.withDeletion {
+				opts = append(opts, WithWorkloadDeletion(repository, tt.sendTombstones))
+			}
+			s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockCVEAdapter(),
+				repositories.NewMemoryStorage(false, false),
+				platform,
+				false,
+				opts...)
+			err := s.DeleteWorkload(context.TODO(), tt.wlid)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantOrphaned, repository.orphaned)
+			assert.Equal(t, tt.wantTombstones, platform.tombstones)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"go.opentelemetry.io/otel"
+)
+
+// maxScanHistory is the number of scans kept per workload (or registry image) for comparison, unless a retention
+// per severity is set
+const maxScanHistory = 10
+
+// recordResults keeps the findings of a completed scan so that it can be compared with later scans
+func (s *ScanService) recordResults(ctx context.Context, workload domain.ScanCommand, cve domain.CVEManifest) {
+	scanID, _ := ctx.Value(domain.ScanIDKey{}).(string)
+	if scanID == "" || cve.Content == nil {
+		return
+	}
+	timestamp := time.Now()
+	if ts, ok := ctx.Value(domain.TimestampKey{}).(int64); ok {
+		timestamp = time.Unix(ts, 0)
+	}
+	record := domain.ScanRecord{
+		ScanID:        scanID,
+		Wlid:          workload.Wlid,
+		ContainerName: workload.ContainerName,
+		ImageTag:      workload.ImageTag,
+		ImageHash:     workload.ImageHash,
+		ImageSlug:     workload.ImageSlug,
+		Timestamp:     timestamp,
+		Findings:      findings(cve),
+	}
+	// registry scans have no workload, their history is kept per image
+	key := workload.Wlid
+	if key == "" {
+		key = workload.ImageTagNormalized
+	}
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	s.scans[scanID] = record
+	history := append(s.scanHistory[key], scanID)
+	if len(s.historyRetention) > 0 {
+		// the history is kept by age instead of by count
+		s.scanHistory[key] = history
+		now := time.Now()
+		s.pruneHistory(key, now)
+		s.collectHistory(now)
+		return
+	}
+	if len(history) > maxScanHistory {
+		for _, id := range history[:len(history)-maxScanHistory] {
+			delete(s.scans, id)
+		}
+		history = append([]string{}, history[len(history)-maxScanHistory:]...)
+	}
+	s.scanHistory[key] = history
+}
+
+// findings returns the findings of a vulnerability manifest keyed by finding key
+func findings(cve domain.CVEManifest) map[string]domain.Finding {
+	findings := map[string]domain.Finding{}
+	if cve.Content == nil {
+		return findings
+	}
+	for _, match := range cve.Content.Matches {
+		finding := domain.Finding{
+			ID:       match.Vulnerability.ID,
+			Package:  match.Artifact.Name,
+			Version:  match.Artifact.Version,
+			Severity: match.Vulnerability.Severity,
+		}
+		findings[finding.Key()] = finding
+	}
+	return findings
+}
+
+// findScan returns the scan with the given scanID, or else the latest scan of the workload at the given time
+func (s *ScanService) findScan(scanID, wlid string, at time.Time) (domain.ScanRecord, error) {
+	if scanID != "" {
+		record, ok := s.scans[scanID]
+		if !ok {
+			return domain.ScanRecord{}, fmt.Errorf("%w: %s", domain.ErrScanNotFound, scanID)
+		}
+		return record, nil
+	}
+	var found *domain.ScanRecord
+	for _, id := range s.scanHistory[wlid] {
+		record := s.scans[id]
+		if !record.Timestamp.After(at) && (found == nil || record.Timestamp.After(found.Timestamp)) {
+			found = &record
+		}
+	}
+	if found == nil {
+		return domain.ScanRecord{}, fmt.Errorf("%w: no scan of %s at %s", domain.ErrScanNotFound, wlid, at.Format(time.RFC3339))
+	}
+	return *found, nil
+}
+
+// CompareScans returns the vulnerabilities introduced, fixed and whose severity changed between two scans,
+// scans are kept in memory since kubevuln started
+func (s *ScanService) CompareScans(ctx context.Context, request domain.DiffRequest) (domain.ScanDiff, error) {
+	_, span := otel.Tracer("").Start(ctx, "ScanService.CompareScans")
+	defer span.End()
+
+	s.historyMu.RLock()
+	defer s.historyMu.RUnlock()
+	from, err := s.findScan(request.FromScanID, request.Wlid, request.From)
+	if err != nil {
+		return domain.ScanDiff{}, err
+	}
+	to, err := s.findScan(request.ToScanID, request.Wlid, request.To)
+	if err != nil {
+		return domain.ScanDiff{}, err
+	}
+	return diffScans(from, to), nil
+}
+
+// diffScans compares the findings of two scans, sorted by finding key
+func diffScans(from, to domain.ScanRecord) domain.ScanDiff {
+	diff := domain.ScanDiff{
+		FromScanID:      from.ScanID,
+		FromTimestamp:   from.Timestamp,
+		FromImageHash:   from.ImageHash,
+		ToScanID:        to.ScanID,
+		ToTimestamp:     to.Timestamp,
+		ToImageHash:     to.ImageHash,
+		New:             []domain.Finding{},
+		Fixed:           []domain.Finding{},
+		SeverityChanged: []domain.SeverityChange{},
+	}
+	for _, key := range sortedKeys(to.Findings) {
+		finding := to.Findings[key]
+		previous, ok := from.Findings[key]
+		switch {
+		case !ok:
+			diff.New = append(diff.New, finding)
+		case previous.Severity != finding.Severity:
+			diff.SeverityChanged = append(diff.SeverityChanged, domain.SeverityChange{Finding: finding, PreviousSeverity: previous.Severity})
+		}
+	}
+	for _, key := range sortedKeys(from.Findings) {
+		if _, ok := to.Findings[key]; !ok {
+			diff.Fixed = append(diff.Fixed, from.Findings[key])
+		}
+	}
+	return diff
+}
+
+func sortedKeys(findings map[string]domain.Finding) []string {
+	keys := make([]string, 0, len(findings))
+	for key := range findings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func manifestWithFindings(findings ...domain.Finding) domain.CVEManifest {
+	content := &v1beta1.GrypeDocument{}
+	for _, f := range findings {
+		match := v1beta1.Match{}
+		match.Vulnerability.ID = f.ID
+		match.Vulnerability.Severity = f.Severity
+		match.Artifact.Name = f.Package
+		match.Artifact.Version = f.Version
+		content.Matches = append(content.Matches, match)
+	}
+	return domain.CVEManifest{Content: content}
+}
+
+func scanContext(scanID string, timestamp time.Time) context.Context {
+	ctx := context.WithValue(context.TODO(), domain.ScanIDKey{}, scanID)
+	return context.WithValue(ctx, domain.TimestampKey{}, timestamp.Unix())
+}
+
+func TestScanService_CompareScans(t *testing.T) {
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false)
+	wlid := "wlid://cluster-minikube/namespace-default/deployment-nginx"
+	workload := domain.ScanCommand{Wlid: wlid, ImageHash: "nginx@sha256:1"}
+	day1 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	openssl := domain.Finding{ID: "CVE-2023-0286", Package: "openssl", Version: "3.0.7", Severity: "High"}
+	zlib := domain.Finding{ID: "CVE-2022-37434", Package: "zlib", Version: "1.2.12", Severity: "Critical"}
+	curl := domain.Finding{ID: "CVE-2023-23914", Package: "curl", Version: "7.87.0", Severity: "Medium"}
+	s.recordResults(scanContext("scan1", day1), workload, manifestWithFindings(openssl, zlib))
+	workload.ImageHash = "nginx@sha256:2"
+	opensslCritical := openssl
+	opensslCritical.Severity = "Critical"
+	s.recordResults(scanContext("scan2", day2), workload, manifestWithFindings(opensslCritical, curl))
+	want := domain.ScanDiff{
+		FromScanID:      "scan1",
+		FromTimestamp:   day1.Local(),
+		FromImageHash:   "nginx@sha256:1",
+		ToScanID:        "scan2",
+		ToTimestamp:     day2.Local(),
+		ToImageHash:     "nginx@sha256:2",
+		New:             []domain.Finding{curl},
+		Fixed:           []domain.Finding{zlib},
+		SeverityChanged: []domain.SeverityChange{{Finding: opensslCritical, PreviousSeverity: "High"}},
+	}
+	tests := []struct {
+		name    string
+		request domain.DiffRequest
+		want    domain.ScanDiff
+		wantErr error
+	}{
+		{
+			name:    "by scanIDs",
+			request: domain.DiffRequest{FromScanID: "scan1", ToScanID: "scan2"},
+			want:    want,
+		},
+		{
+			name:    "by workload and times",
+			request: domain.DiffRequest{Wlid: wlid, From: day1.Add(time.Hour), To: day2.Add(time.Hour)},
+			want:    want,
+		},
+		{
+			name:    "unknown scanID",
+			request: domain.DiffRequest{FromScanID: "scan0", ToScanID: "scan2"},
+			wantErr: domain.ErrScanNotFound,
+		},
+		{
+			name:    "no scan before time",
+			request: domain.DiffRequest{Wlid: wlid, From: day1.Add(-time.Hour), ToScanID: "scan2"},
+			wantErr: domain.ErrScanNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.CompareScans(context.TODO(), tt.request)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestScanService_recordResults_history(t *testing.T) {
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false)
+	workload := domain.ScanCommand{ImageTagNormalized: "nginx:latest"}
+	for i := 0; i < maxScanHistory+2; i++ {
+		s.recordResults(scanContext(fmt.Sprintf("scan%d", i), time.Now()), workload, manifestWithFindings())
+	}
+	// scans without a scanID or results are not recorded
+	s.recordResults(context.TODO(), workload, manifestWithFindings())
+	s.recordResults(scanContext("empty", time.Now()), workload, domain.CVEManifest{})
+	assert.Len(t, s.scans, maxScanHistory)
+	assert.Len(t, s.scanHistory["nginx:latest"], maxScanHistory)
+	assert.NotContains(t, s.scans, "scan1")
+	assert.Contains(t, s.scans, "scan2")
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+const tagDigestTTL = 7 * 24 * time.Hour
+
+// digestFromImageHash extracts the digest part of an image hash (repo@sha256:... or sha256:...)
+func digestFromImageHash(imageHash string) string {
+	if i := strings.LastIndex(imageHash, "@"); i != -1 {
+		return imageHash[i+1:]
+	}
+	if strings.HasPrefix(imageHash, "sha256:") {
+		return imageHash
+	}
+	return ""
+}
+
+// resolveDigest pins the tag of a workload to a digest reference, resolution errors are only logged
+func (s *ScanService) resolveDigest(ctx context.Context, workload domain.ScanCommand) string {
+	if s.imageResolver == nil {
+		return ""
+	}
+	digest, err := s.imageResolver.ResolveDigest(ctx, workload.ImageTag, optionsFromWorkload(workload))
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to resolve image digest", helpers.Error(err),
+			helpers.String("imageTag", workload.ImageTag))
+		return ""
+	}
+	return digest
+}
+
+// checkTagMutation records the digest a tag points to and returns the previously seen digest
+// if the tag now points to a different one
+func (s *ScanService) checkTagMutation(tag, imageHash string) string {
+	digest := digestFromImageHash(imageHash)
+	if tag == "" || digest == "" {
+		return ""
+	}
+	var previous string
+	if value, ok := s.tagDigests.Get(tag); ok && value.(string) != digest {
+		previous = value.(string)
+	}
+	s.tagDigests.Set(tag, digest, tagDigestTTL)
+	return previous
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_digestFromImageHash(t *testing.T) {
+	tests := []struct {
+		imageHash string
+		want      string
+	}{
+		{"k8s.gcr.io/kube-proxy@sha256:c1b1", "sha256:c1b1"},
+		{"sha256:c1b1", "sha256:c1b1"},
+		{"k8s.gcr.io/kube-proxy:v1.24.3", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, digestFromImageHash(tt.imageHash), tt.imageHash)
+	}
+}
+
+func TestScanService_TagMutation(t *testing.T) {
+	notifier := adapters.NewMockNotifier()
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false,
+		WithNotifier(notifier))
+	for _, imageHash := range []string{
+		"k8s.gcr.io/kube-proxy@sha256:aaaa",
+		"k8s.gcr.io/kube-proxy@sha256:aaaa",
+		"k8s.gcr.io/kube-proxy@sha256:bbbb",
+	} {
+		ctx, err := s.ValidateScanCVE(context.TODO(), domain.ScanCommand{
+			CallbackURL:        "http://operator:4002/v1/callback",
+			ImageSlug:          "imageSlug",
+			ImageHash:          imageHash,
+			ImageTagNormalized: "k8s.gcr.io/kube-proxy:v1.24.3",
+			Wlid:               "wlid://cluster-minikube/namespace-kube-system/daemonset-kube-proxy",
+		})
+		tools.EnsureSetup(t, err == nil)
+		assert.NoError(t, s.ScanCVE(ctx))
+	}
+	reports := notifier.Reports()
+	assert.Len(t, reports, 3)
+	assert.False(t, reports[0].TagMutated)
+	assert.False(t, reports[1].TagMutated)
+	assert.True(t, reports[2].TagMutated)
+	assert.Equal(t, "sha256:aaaa", reports[2].PreviousDigest)
+}
+
+func TestScanService_ScanRegistryPinsDigest(t *testing.T) {
+	notifier := adapters.NewMockNotifier()
+	sbomAdapter := adapters.NewMockSBOMAdapter(false, false, false)
+	s := NewScanService(sbomAdapter,
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false,
+		WithNotifier(notifier),
+		WithImageResolver(sbomAdapter))
+	ctx, err := s.ValidateScanRegistry(context.TODO(), domain.ScanCommand{
+		CallbackURL: "http://operator:4002/v1/callback",
+		ImageSlug:   "imageSlug",
+		ImageTag:    "k8s.gcr.io/kube-proxy:v1.24.3",
+	})
+	tools.EnsureSetup(t, err == nil)
+	assert.NoError(t, s.ScanRegistry(ctx))
+	reports := notifier.Reports()
+	assert.Len(t, reports, 1)
+	assert.Equal(t, "k8s.gcr.io/kube-proxy:v1.24.3@"+adapters.MockDigest, reports[0].ImageHash)
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+)
+
+// WithEnrichment attaches the attributes provided by enricher to the vulnerabilities of the reports sent to the platform
+func WithEnrichment(enricher ports.VulnerabilityEnricher) ScanServiceOption {
+	return func(s *ScanService) {
+		s.enricher = enricher
+	}
+}
+
+// withEnrichment records the attributes of the vulnerabilities of a CVE manifest, the manifest goes without them when
+// they cannot be retrieved
+func (s *ScanService) withEnrichment(ctx context.Context, cve domain.CVEManifest) domain.CVEManifest {
+	if s.enricher == nil || cve.Content == nil || len(cve.Content.Matches) == 0 {
+		return cve
+	}
+	enrichment, err := s.enricher.Enrich(ctx, vulnerabilityIDs(cve))
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to enrich vulnerabilities", helpers.Error(err),
+			helpers.String("name", cve.Name))
+		return cve
+	}
+	cve.Enrichment = enrichment
+	return cve
+}
+
+// vulnerabilityIDs returns the unique IDs of the vulnerabilities matched in a CVE manifest, in order of appearance
+func vulnerabilityIDs(cve domain.CVEManifest) []string {
+	ids := make([]string, 0, len(cve.Content.Matches))
+	seen := map[string]bool{}
+	for _, match := range cve.Content.Matches {
+		if !seen[match.Vulnerability.ID] {
+			seen[match.Vulnerability.ID] = true
+			ids = append(ids, match.Vulnerability.ID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+// staticEnricher returns the attributes of the requested IDs it knows, and records the requested IDs
+type staticEnricher struct {
+	attributes map[string]map[string]string
+	err        error
+	ids        *[]string
+}
+
+func (s staticEnricher) Enrich(_ context.Context, ids []string) (map[string]map[string]string, error) {
+	*s.ids = ids
+	enrichment := map[string]map[string]string{}
+	for _, id := range ids {
+		if attributes, ok := s.attributes[id]; ok {
+			enrichment[id] = attributes
+		}
+	}
+	return enrichment, s.err
+}
+
+func TestScanService_withEnrichment(t *testing.T) {
+	cve := domain.CVEManifest{
+		Name: "imageSlug",
+		Content: &v1beta1.GrypeDocument{Matches: []v1beta1.Match{
+			newMatch("CVE-2023-0286", "openssl"),
+			newMatch("CVE-2023-0286", "libssl3"),
+			newMatch("CVE-2022-32149", "golang.org/x/text"),
+		}},
+	}
+	attributes := map[string]map[string]string{"CVE-2023-0286": {"riskScore": "8.1", "owner": "platform"}}
+
+	s := &ScanService{}
+	assert.Nil(t, s.withEnrichment(context.TODO(), cve).Enrichment)
+
+	var ids []string
+	WithEnrichment(staticEnricher{attributes: attributes, ids: &ids})(s)
+	assert.Equal(t, attributes, s.withEnrichment(context.TODO(), cve).Enrichment)
+	assert.Equal(t, []string{"CVE-2023-0286", "CVE-2022-32149"}, ids)
+
+	// the report goes without the attributes when they cannot be retrieved
+	WithEnrichment(staticEnricher{err: domain.ErrMockError, ids: &ids})(s)
+	assert.Nil(t, s.withEnrichment(context.TODO(), cve).Enrichment)
+}
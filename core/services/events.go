@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	// scanEventCapacity bounds the events kept per scan, the oldest ones are dropped first
+	scanEventCapacity = 256
+	// scanEventRate caps the events recorded per second for a scan, so that a chatty stage cannot flush the others
+	scanEventRate = 50
+)
+
+// WithScanEvents keeps the detailed events of the last scans in memory, to troubleshoot a scan without raising the
+// log verbosity; each scan keeps at most scanEventCapacity events recorded at most scanEventRate per second
+func WithScanEvents(scans int) ScanServiceOption {
+	return func(s *ScanService) {
+		s.scanEvents = &scanEventLog{
+			logs:  map[string]*scanEventBuffer{},
+			scans: scans,
+		}
+	}
+}
+
+// scanEventLog keeps the event buffers of the last scans, the oldest scans are forgotten first
+type scanEventLog struct {
+	logs map[string]*scanEventBuffer
+	mu   sync.Mutex
+	// order lists the scan IDs, oldest first
+	order []string
+	scans int
+}
+
+// scanEventBuffer is a ring buffer of the events of a scan
+type scanEventBuffer struct {
+	dropped int
+	events  []domain.ScanEvent
+	// next is the position of the oldest event once the buffer is full
+	next int
+	// window is the start of the second whose events are counted in windowEvents
+	window       time.Time
+	windowEvents int
+}
+
+// record adds an event to the log of a scan, unless the scan is past its rate limit
+func (l *scanEventLog) record(scanID string, event domain.ScanEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	buffer, ok := l.logs[scanID]
+	if !ok {
+		for len(l.order) > 0 && len(l.order) >= l.scans {
+			delete(l.logs, l.order[0])
+			l.order = l.order[1:]
+		}
+		buffer = &scanEventBuffer{}
+		l.logs[scanID] = buffer
+		l.order = append(l.order, scanID)
+	}
+	if event.Time.Sub(buffer.window) >= time.Second {
+		buffer.window = event.Time
+		buffer.windowEvents = 0
+	}
+	if buffer.windowEvents >= scanEventRate {
+		buffer.dropped++
+		return
+	}
+	buffer.windowEvents++
+	if len(buffer.events) < scanEventCapacity {
+		buffer.events = append(buffer.events, event)
+		return
+	}
+	buffer.events[buffer.next] = event
+	buffer.next = (buffer.next + 1) % scanEventCapacity
+	buffer.dropped++
+}
+
+// list returns the events of a scan, oldest first, and the number of events dropped
+func (l *scanEventLog) list(scanID string) ([]domain.ScanEvent, int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	buffer, ok := l.logs[scanID]
+	if !ok {
+		return nil, 0, false
+	}
+	events := make([]domain.ScanEvent, 0, len(buffer.events))
+	events = append(events, buffer.events[buffer.next:]...)
+	events = append(events, buffer.events[:buffer.next]...)
+	return events, buffer.dropped, true
+}
+
+// trackEvents carries in ctx the function recording the detailed events of the scan of workload, if enabled; the
+// start of the scan is the first event
+func (s *ScanService) trackEvents(ctx context.Context, workload domain.ScanCommand) context.Context {
+	if s.scanEvents == nil {
+		return ctx
+	}
+	scanID, _ := ctx.Value(domain.ScanIDKey{}).(string)
+	if scanID == "" {
+		return ctx
+	}
+	record := func(event domain.ScanEvent) {
+		event.Time = time.Now()
+		s.scanEvents.record(scanID, event)
+	}
+	record(domain.ScanEvent{Message: "scan started", Attributes: map[string]string{
+		"wlid":      workload.Wlid,
+		"imageTag":  workload.ImageTag,
+		"imageHash": workload.ImageHash,
+	}})
+	return context.WithValue(ctx, domain.ScanEventKey{}, record)
+}
+
+// ScanEvents returns the detailed events recorded for a scan
+func (s *ScanService) ScanEvents(ctx context.Context, scanID string) (domain.ScanEvents, error) {
+	_, span := otel.Tracer("").Start(ctx, "ScanService.ScanEvents")
+	defer span.End()
+
+	if s.scanEvents == nil {
+		return domain.ScanEvents{}, domain.ErrNoScanEvents
+	}
+	events, dropped, ok := s.scanEvents.list(scanID)
+	if !ok {
+		return domain.ScanEvents{}, domain.ErrScanEventsNotFound
+	}
+	return domain.ScanEvents{ScanID: scanID, Events: events, Dropped: dropped}, nil
+}
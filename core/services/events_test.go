@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanService_ScanEvents(t *testing.T) {
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false)
+	ctx := context.TODO()
+	_, err := s.ScanEvents(ctx, "scan")
+	assert.ErrorIs(t, err, domain.ErrNoScanEvents)
+
+	WithScanEvents(2)(s)
+	workload := domain.ScanCommand{Wlid: "wlid://cluster-minikube/namespace-default/deployment-nginx", ImageTag: "nginx:1.25"}
+	scanCtx := s.trackProgress(context.WithValue(ctx, domain.ScanIDKey{}, "scan"), workload)
+	tools.RecordScanEvent(scanCtx, "pull started", map[string]string{"source": "registry"})
+	finishProgress(scanCtx, nil)
+	events, err := s.ScanEvents(ctx, "scan")
+	require.NoError(t, err)
+	assert.Equal(t, "scan", events.ScanID)
+	assert.Zero(t, events.Dropped)
+	var messages []string
+	for _, event := range events.Events {
+		assert.False(t, event.Time.IsZero())
+		messages = append(messages, event.Message)
+	}
+	assert.Equal(t, []string{"scan started", "pull started", "scan done"}, messages)
+	assert.Equal(t, workload.Wlid, events.Events[0].Attributes["wlid"])
+
+	// the oldest scans are forgotten
+	s.trackProgress(context.WithValue(ctx, domain.ScanIDKey{}, "scan2"), workload)
+	s.trackProgress(context.WithValue(ctx, domain.ScanIDKey{}, "scan3"), workload)
+	_, err = s.ScanEvents(ctx, "scan")
+	assert.ErrorIs(t, err, domain.ErrScanEventsNotFound)
+	_, err = s.ScanEvents(ctx, "scan2")
+	assert.NoError(t, err)
+}
+
+func TestScanEventLog_record(t *testing.T) {
+	l := &scanEventLog{logs: map[string]*scanEventBuffer{}, scans: 1}
+	start := time.Now()
+	// the events past the rate limit are dropped
+	for i := 0; i < scanEventRate+10; i++ {
+		l.record("scan", domain.ScanEvent{Time: start, Message: "cataloger finished"})
+	}
+	events, dropped, ok := l.list("scan")
+	require.True(t, ok)
+	assert.Len(t, events, scanEventRate)
+	assert.Equal(t, 10, dropped)
+	// the oldest events are dropped past the capacity
+	for i := 0; i < scanEventCapacity; i++ {
+		l.record("scan", domain.ScanEvent{Time: start.Add(time.Duration(i+1) * time.Second), Message: "layer read"})
+	}
+	events, dropped, _ = l.list("scan")
+	assert.Len(t, events, scanEventCapacity)
+	assert.Equal(t, 10+scanEventRate, dropped)
+	assert.Equal(t, start.Add(time.Second), events[0].Time)
+	assert.Equal(t, start.Add(scanEventCapacity*time.Second), events[len(events)-1].Time)
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"encoding/json"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+)
+
+// WithMatchExplanations records why each package matched its vulnerabilities in the reports sent to the platform,
+// a debug option to triage the suspected false positives
+func WithMatchExplanations() ScanServiceOption {
+	return func(s *ScanService) {
+		s.matchExplanations = true
+	}
+}
+
+// matchFound holds the fields of the vulnerability records found by Grype explaining a match
+type matchFound struct {
+	CPEs              []string `json:"cpes"`
+	VersionConstraint string   `json:"versionConstraint"`
+}
+
+// explainMatches records why the packages of a CVE manifest matched their vulnerabilities, one explanation per
+// match detail
+func (s *ScanService) explainMatches(cve domain.CVEManifest) domain.CVEManifest {
+	if !s.matchExplanations || cve.Content == nil {
+		return cve
+	}
+	explanations := map[string][]domain.MatchExplanation{}
+	for _, match := range cve.Content.Matches {
+		for _, details := range match.MatchDetails {
+			explanations[match.Vulnerability.ID] = append(explanations[match.Vulnerability.ID], explainMatch(match, details))
+		}
+	}
+	cve.Explanations = explanations
+	return cve
+}
+
+// explainMatch explains a match detail, the packages found by their CPEs list them while the others list their
+// package URL
+func explainMatch(match v1beta1.Match, details v1beta1.MatchDetails) domain.MatchExplanation {
+	explanation := domain.MatchExplanation{
+		Package:    match.Artifact.Name,
+		Version:    match.Artifact.Version,
+		Matcher:    details.Matcher,
+		MatchType:  details.Type,
+		Namespace:  match.Vulnerability.Namespace,
+		SearchedBy: domain.SearchedByPURL,
+	}
+	var found matchFound
+	if len(details.Found) > 0 {
+		// the records found are opaque to the storage API, a malformed one only leaves the explanation incomplete
+		_ = json.Unmarshal(details.Found, &found)
+	}
+	explanation.Constraint = found.VersionConstraint
+	switch {
+	case details.Type == domain.MatchTypeCPE:
+		explanation.SearchedBy = domain.SearchedByCPE
+		explanation.Identifiers = found.CPEs
+	case match.Artifact.PURL != "":
+		explanation.Identifiers = []string{match.Artifact.PURL}
+	}
+	return explanation
+}
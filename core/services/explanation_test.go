@@ -0,0 +1,70 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanService_explainMatches(t *testing.T) {
+	openssl := newMatch("CVE-2023-0286", "openssl")
+	openssl.Artifact.Version = "1.1.1n-0+deb11u3"
+	openssl.Artifact.PURL = "pkg:deb/debian/openssl@1.1.1n-0+deb11u3?distro=debian-11"
+	openssl.Vulnerability.Namespace = "debian:distro:debian:11"
+	openssl.MatchDetails = []v1beta1.MatchDetails{{
+		Type:    "exact-indirect-match",
+		Matcher: "dpkg-matcher",
+		Found:   json.RawMessage(`{"vulnerabilityID":"CVE-2023-0286","versionConstraint":"< 1.1.1n-0+deb11u4 (deb)"}`),
+	}}
+	log4j := newMatch("CVE-2021-44228", "log4j-core")
+	log4j.Artifact.Version = "2.14.1"
+	log4j.Vulnerability.Namespace = "nvd:cpe"
+	log4j.MatchDetails = []v1beta1.MatchDetails{{
+		Type:    domain.MatchTypeCPE,
+		Matcher: "java-matcher",
+		Found:   json.RawMessage(`{"vulnerabilityID":"CVE-2021-44228","versionConstraint":">= 2.0.1, < 2.15.0 (unknown)","cpes":["cpe:2.3:a:apache:log4j:*:*:*:*:*:*:*:*"]}`),
+	}}
+	cve := domain.CVEManifest{Content: &v1beta1.GrypeDocument{Matches: []v1beta1.Match{openssl, log4j}}}
+
+	s := &ScanService{}
+	assert.Nil(t, s.explainMatches(cve).Explanations)
+
+	WithMatchExplanations()(s)
+	assert.Equal(t, map[string][]domain.MatchExplanation{
+		"CVE-2023-0286": {{
+			Package:     "openssl",
+			Version:     "1.1.1n-0+deb11u3",
+			Matcher:     "dpkg-matcher",
+			MatchType:   "exact-indirect-match",
+			Constraint:  "< 1.1.1n-0+deb11u4 (deb)",
+			Namespace:   "debian:distro:debian:11",
+			SearchedBy:  domain.SearchedByPURL,
+			Identifiers: []string{"pkg:deb/debian/openssl@1.1.1n-0+deb11u3?distro=debian-11"},
+		}},
+		"CVE-2021-44228": {{
+			Package:     "log4j-core",
+			Version:     "2.14.1",
+			Matcher:     "java-matcher",
+			MatchType:   domain.MatchTypeCPE,
+			Constraint:  ">= 2.0.1, < 2.15.0 (unknown)",
+			Namespace:   "nvd:cpe",
+			SearchedBy:  domain.SearchedByCPE,
+			Identifiers: []string{"cpe:2.3:a:apache:log4j:*:*:*:*:*:*:*:*"},
+		}},
+	}, s.explainMatches(cve).Explanations)
+
+	// a malformed record only leaves the explanation incomplete
+	log4j.MatchDetails[0].Found = json.RawMessage(`[]`)
+	cve.Content.Matches = []v1beta1.Match{log4j}
+	assert.Equal(t, []domain.MatchExplanation{{
+		Package:    "log4j-core",
+		Version:    "2.14.1",
+		Matcher:    "java-matcher",
+		MatchType:  domain.MatchTypeCPE,
+		Namespace:  "nvd:cpe",
+		SearchedBy: domain.SearchedByCPE,
+	}}, s.explainMatches(cve).Explanations["CVE-2021-44228"])
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+)
+
+// WithExploits flags the vulnerabilities of the reports sent to the platform with the public exploits known to provider
+func WithExploits(provider ports.ExploitProvider) ScanServiceOption {
+	return func(s *ScanService) {
+		s.exploitProvider = provider
+	}
+}
+
+// withExploits records the sources of the public exploits of the vulnerabilities of a CVE manifest, the manifest goes
+// without them when they cannot be retrieved
+func (s *ScanService) withExploits(ctx context.Context, cve domain.CVEManifest) domain.CVEManifest {
+	if s.exploitProvider == nil || cve.Content == nil || len(cve.Content.Matches) == 0 {
+		return cve
+	}
+	exploits, err := s.exploitProvider.Exploits(ctx, vulnerabilityIDs(cve))
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to look up exploits", helpers.Error(err),
+			helpers.String("name", cve.Name))
+		return cve
+	}
+	cve.Exploits = exploits
+	return cve
+}
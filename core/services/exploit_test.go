@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+// staticExploits returns the exploit sources of the requested IDs it knows
+type staticExploits struct {
+	exploits map[string][]string
+	err      error
+}
+
+func (s staticExploits) Exploits(_ context.Context, ids []string) (map[string][]string, error) {
+	exploits := map[string][]string{}
+	for _, id := range ids {
+		if sources, ok := s.exploits[id]; ok {
+			exploits[id] = sources
+		}
+	}
+	return exploits, s.err
+}
+
+func TestScanService_withExploits(t *testing.T) {
+	cve := domain.CVEManifest{
+		Name: "imageSlug",
+		Content: &v1beta1.GrypeDocument{Matches: []v1beta1.Match{
+			newMatch("CVE-2021-44228", "log4j-core"),
+			newMatch("CVE-2022-32149", "golang.org/x/text"),
+		}},
+	}
+	exploits := map[string][]string{
+		"CVE-2021-44228": {domain.ExploitSourceExploitDB, domain.ExploitSourceMetasploit},
+		"CVE-2014-0160":  {domain.ExploitSourceExploitDB},
+	}
+
+	s := &ScanService{}
+	assert.Nil(t, s.withExploits(context.TODO(), cve).Exploits)
+
+	WithExploits(staticExploits{exploits: exploits})(s)
+	assert.Equal(t, map[string][]string{"CVE-2021-44228": {domain.ExploitSourceExploitDB, domain.ExploitSourceMetasploit}},
+		s.withExploits(context.TODO(), cve).Exploits)
+
+	// the report goes without the exploits when they cannot be retrieved
+	WithExploits(staticExploits{err: domain.ErrMockError})(s)
+	assert.Nil(t, s.withExploits(context.TODO(), cve).Exploits)
+}
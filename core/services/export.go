@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"go.opentelemetry.io/otel"
+)
+
+// latestScans returns the latest scan of each container of the workload, or of every workload and registry image
+// without wlid, sorted by wlid (or image) and container
+func (s *ScanService) latestScans(wlid string) ([]domain.ScanRecord, error) {
+	s.historyMu.RLock()
+	defer s.historyMu.RUnlock()
+	keys := make([]string, 0, len(s.scanHistory))
+	for key := range s.scanHistory {
+		if wlid == "" || key == wlid {
+			keys = append(keys, key)
+		}
+	}
+	if wlid != "" && len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no scan of %s", domain.ErrScanNotFound, wlid)
+	}
+	sort.Strings(keys)
+	var records []domain.ScanRecord
+	for _, key := range keys {
+		latest := map[string]domain.ScanRecord{}
+		for _, id := range s.scanHistory[key] {
+			record := s.scans[id]
+			if found, ok := latest[record.ContainerName]; !ok || !record.Timestamp.Before(found.Timestamp) {
+				latest[record.ContainerName] = record
+			}
+		}
+		containers := make([]string, 0, len(latest))
+		for container := range latest {
+			containers = append(containers, container)
+		}
+		sort.Strings(containers)
+		for _, container := range containers {
+			records = append(records, latest[container])
+		}
+	}
+	return records, nil
+}
+
+// ExportResults passes write a row per finding of the latest scan of each container of the workload, or of every
+// workload and registry image without wlid. The vulnerability manifests are loaded from the storage one at a time,
+// so that the export does not hold them all in memory, and the scans kept without storage only have their findings.
+func (s *ScanService) ExportResults(ctx context.Context, wlid string, write func(domain.ResultRow) error) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.ExportResults")
+	defer span.End()
+
+	records, err := s.latestScans(wlid)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.exportRecord(ctx, record, write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportRecord writes the rows of a scan, from its vulnerability manifest if it is still in the storage
+func (s *ScanService) exportRecord(ctx context.Context, record domain.ScanRecord, write func(domain.ResultRow) error) error {
+	row := domain.ResultRow{
+		Wlid:      record.Wlid,
+		Container: record.ContainerName,
+		Image:     record.ImageTag,
+		ImageHash: record.ImageHash,
+		ScanID:    record.ScanID,
+		ScannedAt: record.Timestamp,
+	}
+	if s.storage && record.ImageSlug != "" {
+		cve, err := s.cveRepository.GetCVE(ctx, record.ImageSlug, s.sbomCreator.Version(), s.cveScanner.Version(ctx), s.cveScanner.DBVersion(ctx))
+		switch {
+		case err != nil:
+			logger.L().Ctx(ctx).Warning("failed to load the vulnerability manifest, exporting the recorded findings",
+				helpers.Error(err),
+				helpers.String("imageSlug", record.ImageSlug))
+		case cve.Content != nil:
+			for _, match := range cve.Content.Matches {
+				row := row
+				row.Vulnerability = match.Vulnerability.ID
+				row.Severity = match.Vulnerability.Severity
+				row.Package = match.Artifact.Name
+				row.Version = match.Artifact.Version
+				row.Type = string(match.Artifact.Type)
+				row.FixState = match.Vulnerability.Fix.State
+				row.FixVersions = match.Vulnerability.Fix.Versions
+				row.URLs = match.Vulnerability.URLs
+				for _, location := range match.Artifact.Locations {
+					row.Locations = append(row.Locations, location.RealPath)
+				}
+				if err := write(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	for _, key := range sortedKeys(record.Findings) {
+		finding := record.Findings[key]
+		row := row
+		row.Vulnerability = finding.ID
+		row.Severity = finding.Severity
+		row.Package = finding.Package
+		row.Version = finding.Version
+		if err := write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportAll(s *ScanService, wlid string) ([]domain.ResultRow, error) {
+	var rows []domain.ResultRow
+	err := s.ExportResults(context.TODO(), wlid, func(row domain.ResultRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	return rows, err
+}
+
+func TestScanService_ExportResults(t *testing.T) {
+	storage := repositories.NewMemoryStorage(false, false)
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		storage,
+		adapters.NewMockPlatform(),
+		true)
+	nginx := "wlid://cluster-minikube/namespace-default/deployment-nginx"
+	day1 := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	openssl := domain.Finding{ID: "CVE-2023-0286", Package: "openssl", Version: "3.0.7", Severity: "High"}
+	zlib := domain.Finding{ID: "CVE-2022-37434", Package: "zlib", Version: "1.2.12", Severity: "Critical"}
+	curl := domain.Finding{ID: "CVE-2023-23914", Package: "curl", Version: "7.87.0", Severity: "Medium"}
+
+	// only the latest scan of each container is exported
+	workload := domain.ScanCommand{Wlid: nginx, ContainerName: "nginx", ImageTag: "nginx:1.24", ImageHash: "sha256:1"}
+	s.recordResults(scanContext("scan1", day1), workload, manifestWithFindings(openssl, zlib))
+	workload.ImageHash = "sha256:2"
+	s.recordResults(scanContext("scan2", day2), workload, manifestWithFindings(zlib, openssl))
+	sidecar := domain.ScanCommand{Wlid: nginx, ContainerName: "envoy", ImageTag: "envoy:1.26", ImageSlug: "envoy-slug"}
+	s.recordResults(scanContext("scan3", day1), sidecar, manifestWithFindings(curl))
+	registry := domain.ScanCommand{ImageTagNormalized: "docker.io/library/alpine:3.18", ImageTag: "alpine:3.18"}
+	s.recordResults(scanContext("scan4", day2), registry, manifestWithFindings(curl))
+
+	// the manifest in the storage is exported with all the match details
+	manifest := manifestWithFindings(curl)
+	manifest.Content.Matches[0].Vulnerability.Fix.State = domain.FixStateFixed
+	manifest.Content.Matches[0].Vulnerability.Fix.Versions = []string{"7.88.0"}
+	manifest.Name = "envoy-slug"
+	manifest.SBOMCreatorVersion = s.sbomCreator.Version()
+	manifest.CVEScannerVersion = s.cveScanner.Version(context.TODO())
+	manifest.CVEDBVersion = s.cveScanner.DBVersion(context.TODO())
+	require.NoError(t, storage.StoreCVE(context.TODO(), manifest, false))
+
+	rows, err := exportAll(s, "")
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, "alpine:3.18", rows[0].Image)
+	assert.Equal(t, domain.ResultRow{Wlid: nginx, Container: "envoy", Image: "envoy:1.26", ScanID: "scan3",
+		ScannedAt: day1.Local(), Vulnerability: curl.ID, Severity: curl.Severity, Package: curl.Package,
+		Version: curl.Version, FixState: domain.FixStateFixed, FixVersions: []string{"7.88.0"}}, rows[1])
+	// the findings are sorted without manifest
+	assert.Equal(t, zlib.ID, rows[2].Vulnerability)
+	assert.Equal(t, "scan2", rows[2].ScanID)
+	assert.Equal(t, "sha256:2", rows[2].ImageHash)
+	assert.Equal(t, openssl.ID, rows[3].Vulnerability)
+
+	rows, err = exportAll(s, nginx)
+	require.NoError(t, err)
+	assert.Len(t, rows, 3)
+
+	_, err = exportAll(s, "wlid://cluster-minikube/namespace-default/deployment-redis")
+	assert.ErrorIs(t, err, domain.ErrScanNotFound)
+
+	// the export stops at the first write error
+	var written int
+	err = s.ExportResults(context.TODO(), "", func(domain.ResultRow) error {
+		written++
+		return domain.ErrMockError
+	})
+	assert.ErrorIs(t, err, domain.ErrMockError)
+	assert.Equal(t, 1, written)
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var scanErrors, _ = otel.Meter("").Int64Counter("kubevuln_scan_errors",
+	metric.WithDescription("Number of failed scans by error code and stage"))
+
+// WithScanFailureRepository persists the last scan failure of each image in repository, so that the failures and
+// their attempt counts survive restarts
+func WithScanFailureRepository(repository ports.ScanFailureRepository) ScanServiceOption {
+	return func(s *ScanService) {
+		s.failureRepository = repository
+	}
+}
+
+// failureImage returns the key of the failures of the image of a workload, its digest or else its tag
+func failureImage(workload domain.ScanCommand) string {
+	if digest := digestFromImageHash(workload.ImageHash); digest != "" {
+		return digest
+	}
+	if workload.ImageHash != "" {
+		return workload.ImageHash
+	}
+	return workload.ImageTag
+}
+
+// failureReason classifies the error of a failed scan
+func failureReason(err error) string {
+	var authErr *domain.RegistryAuthError
+	var pullErr *domain.RegistryPullError
+	var incompleteErr *domain.IncompleteSBOMError
+	var transportError *transport.Error
+	switch {
+	case errors.As(err, &authErr):
+		return domain.FailureAuth
+	case errors.As(err, &pullErr):
+		return domain.FailurePull
+	case errors.As(err, &incompleteErr):
+		return incompleteErr.Reason
+	case errors.As(err, &transportError) && transportError.StatusCode == http.StatusTooManyRequests:
+		return domain.FailureRateLimited
+	case errors.Is(err, domain.ErrStageTimeout):
+		return domain.FailureTimeout
+	}
+	return domain.FailureError
+}
+
+// stageCategories are the error categories of the scan stages, for the errors the adapters left uncategorized
+var stageCategories = map[string]error{
+	domain.StageFilter: domain.ErrMatching,
+	domain.StageMatch:  domain.ErrMatching,
+	domain.StagePull:   domain.ErrImagePull,
+	domain.StageSBOM:   domain.ErrSBOM,
+	domain.StageSubmit: domain.ErrSubmit,
+}
+
+// reportFailure puts the error of a scan failed at stage in the category of the stage unless it is in one, counts
+// it by error code and reports the failure to the platform, it returns the categorized error
+func (s *ScanService) reportFailure(ctx context.Context, workload domain.ScanCommand, stage string, scanErr error) error {
+	if scanErr == nil {
+		return nil
+	}
+	if category, ok := stageCategories[stage]; ok {
+		scanErr = domain.Categorize(category, scanErr)
+	}
+	code := domain.ErrorCode(scanErr)
+	if scanErrors != nil {
+		scanErrors.Add(ctx, 1, metric.WithAttributes(attribute.String("code", code), attribute.String("stage", stage)))
+	}
+	if err := s.platform.SendStatus(context.WithValue(ctx, domain.ErrorCodeKey{}, code), domain.Failed); err != nil {
+		logger.L().Ctx(ctx).Warning("telemetry error", helpers.Error(err),
+			helpers.String("imageSlug", workload.ImageSlug))
+	}
+	return scanErr
+}
+
+// incompleteSBOMError returns ErrIncompleteSBOM qualified with the reason the SBOM is incomplete, when known
+func incompleteSBOMError(sbom domain.SBOM) error {
+	if reason := sbom.Annotations[domain.AnnotationIncompleteReason]; reason != "" {
+		return &domain.IncompleteSBOMError{Reason: reason}
+	}
+	return domain.ErrIncompleteSBOM
+}
+
+// loadFailures reads the persisted scan failures once
+func (s *ScanService) loadFailures(ctx context.Context) {
+	s.failuresLoad.Do(func() {
+		if s.failureRepository == nil {
+			return
+		}
+		failures, err := s.failureRepository.ListScanFailures(ctx)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("error listing scan failures", helpers.Error(err))
+			return
+		}
+		s.failuresMu.Lock()
+		defer s.failuresMu.Unlock()
+		for _, failure := range failures {
+			if _, ok := s.failures[failure.Image]; !ok {
+				s.failures[failure.Image] = failure
+			}
+		}
+	})
+}
+
+// recordOutcome records the failure of a scan reached stage, counting the failures in a row, or forgets the last
+// failure of the image once a scan succeeds
+func (s *ScanService) recordOutcome(ctx context.Context, workload domain.ScanCommand, stage string, scanErr error) {
+	image := failureImage(workload)
+	if image == "" {
+		return
+	}
+	s.loadFailures(ctx)
+	s.failuresMu.Lock()
+	previous, failed := s.failures[image]
+	if scanErr == nil {
+		delete(s.failures, image)
+		s.failuresMu.Unlock()
+		if failed && s.failureRepository != nil {
+			if err := s.failureRepository.DeleteScanFailure(ctx, image); err != nil {
+				logger.L().Ctx(ctx).Warning("error deleting scan failure", helpers.Error(err),
+					helpers.String("image", image))
+			}
+		}
+		return
+	}
+	failure := domain.ScanFailure{
+		Image:     image,
+		ImageSlug: workload.ImageSlug,
+		Wlid:      workload.Wlid,
+		Timestamp: time.Now().UTC(),
+		Stage:     stage,
+		Reason:    failureReason(scanErr),
+		Code:      domain.ErrorCode(scanErr),
+		Error:     scanErr.Error(),
+		Attempts:  previous.Attempts + 1,
+	}
+	s.failures[image] = failure
+	s.failuresMu.Unlock()
+	if s.failureRepository != nil {
+		if err := s.failureRepository.StoreScanFailure(ctx, failure); err != nil {
+			logger.L().Ctx(ctx).Warning("error storing scan failure", helpers.Error(err),
+				helpers.String("image", image))
+		}
+	}
+}
+
+// lastFailure returns the last failure of the image of a workload if its last scan failed
+func (s *ScanService) lastFailure(ctx context.Context, workload domain.ScanCommand) (domain.ScanFailure, bool) {
+	s.loadFailures(ctx)
+	s.failuresMu.RLock()
+	defer s.failuresMu.RUnlock()
+	failure, ok := s.failures[failureImage(workload)]
+	return failure, ok
+}
+
+// ScanFailures returns the last failure of the images whose last scan failed, the most recent first, optionally
+// restricted to an image given by digest, image ID or tag
+func (s *ScanService) ScanFailures(ctx context.Context, image string) ([]domain.ScanFailure, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.ScanFailures")
+	defer span.End()
+
+	s.loadFailures(ctx)
+	if image != "" {
+		image = failureImage(domain.ScanCommand{ImageHash: image})
+	}
+	s.failuresMu.RLock()
+	failures := make([]domain.ScanFailure, 0, len(s.failures))
+	for key, failure := range s.failures {
+		if image == "" || key == image {
+			failures = append(failures, failure)
+		}
+	}
+	s.failuresMu.RUnlock()
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].Timestamp.Equal(failures[j].Timestamp) {
+			return failures[i].Image < failures[j].Image
+		}
+		return failures[i].Timestamp.After(failures[j].Timestamp)
+	})
+	return failures, nil
+}
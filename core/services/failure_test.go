@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingSBOMAdapter fails the SBOM creation with err while it is set
+type failingSBOMAdapter struct {
+	*adapters.MockSBOMAdapter
+	err *error
+}
+
+func (f failingSBOMAdapter) CreateSBOM(ctx context.Context, name, imageID string, options domain.RegistryOptions) (domain.SBOM, error) {
+	if *f.err != nil {
+		return domain.SBOM{}, *f.err
+	}
+	return f.MockSBOMAdapter.CreateSBOM(ctx, name, imageID, options)
+}
+
+// memoryFailureStore keeps the scan failures in memory
+type memoryFailureStore struct {
+	mu       sync.Mutex
+	failures map[string]domain.ScanFailure
+}
+
+func (m *memoryFailureStore) DeleteScanFailure(_ context.Context, image string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.failures, image)
+	return nil
+}
+
+func (m *memoryFailureStore) ListScanFailures(context.Context) ([]domain.ScanFailure, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var failures []domain.ScanFailure
+	for _, failure := range m.failures {
+		failures = append(failures, failure)
+	}
+	return failures, nil
+}
+
+func (m *memoryFailureStore) StoreScanFailure(_ context.Context, failure domain.ScanFailure) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures[failure.Image] = failure
+	return nil
+}
+
+func Test_failureReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: &domain.RegistryAuthError{Reason: domain.AuthReasonDenied}, want: domain.FailureAuth},
+		{err: &domain.RegistryPullError{}, want: domain.FailurePull},
+		{err: &domain.IncompleteSBOMError{Reason: domain.FailureImageTooLarge}, want: domain.FailureImageTooLarge},
+		{err: fmt.Errorf("failed to get image descriptor from registry: %w", &transport.Error{StatusCode: 429}), want: domain.FailureRateLimited},
+		{err: fmt.Errorf("%w: match stage exceeded 1m0s", domain.ErrStageTimeout), want: domain.FailureTimeout},
+		{err: domain.ErrIncompleteSBOM, want: domain.FailureError},
+		{err: errors.New("boom"), want: domain.FailureError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tt.want, failureReason(tt.err))
+		})
+	}
+}
+
+func TestScanService_ScanFailures(t *testing.T) {
+	var sbomErr error
+	store := &memoryFailureStore{failures: map[string]domain.ScanFailure{}}
+	newService := func() *ScanService {
+		return NewScanService(failingSBOMAdapter{MockSBOMAdapter: adapters.NewMockSBOMAdapter(false, false, false), err: &sbomErr},
+			repositories.NewMemoryStorage(false, false),
+			adapters.NewMockCVEAdapter(),
+			repositories.NewMemoryStorage(false, false),
+			adapters.NewMockPlatform(),
+			false,
+			WithScanFailureRepository(store))
+	}
+	workload := domain.ScanCommand{
+		ImageSlug: "imageSlug",
+		ImageHash: "harbor.example.com/team-a/app@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+		ImageTag:  "harbor.example.com/team-a/app:v1",
+		Wlid:      "wlid://cluster-minikube/namespace-team-a/deployment-app",
+	}
+	image := "sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137"
+	scan := func(s *ScanService) {
+		ctx, err := s.ValidateScanCVE(context.TODO(), workload)
+		tools.EnsureSetup(t, err == nil)
+		_ = s.ScanCVE(ctx)
+	}
+
+	s := newService()
+	sbomErr = &domain.RegistryAuthError{Registry: "harbor.example.com", Provider: domain.AuthProviderHarbor, Reason: domain.AuthReasonDenied, Err: errors.New("403 Forbidden")}
+	scan(s)
+	scan(s)
+	failures, err := s.ScanFailures(context.TODO(), "")
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, image, failures[0].Image)
+	assert.Equal(t, domain.StageSBOM, failures[0].Stage)
+	assert.Equal(t, domain.FailureAuth, failures[0].Reason)
+	assert.Equal(t, domain.ErrorCodeImagePull, failures[0].Code)
+	assert.Equal(t, 2, failures[0].Attempts)
+	assert.Contains(t, failures[0].Error, "registry authentication failed for harbor.example.com")
+
+	// the attempts are counted across restarts
+	s = newService()
+	scan(s)
+	failures, err = s.ScanFailures(context.TODO(), workload.ImageHash)
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, 3, failures[0].Attempts)
+	assert.Equal(t, 3, store.failures[image].Attempts)
+	failures, err = s.ScanFailures(context.TODO(), "sha256:0000000000000000000000000000000000000000000000000000000000000000")
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+
+	// the failure is forgotten once a scan succeeds
+	sbomErr = nil
+	scan(s)
+	failures, err = s.ScanFailures(context.TODO(), "")
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+	assert.Empty(t, store.failures)
+}
+
+func TestScanService_ScanFailures_incompleteSBOM(t *testing.T) {
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, true, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false)
+	ctx, err := s.ValidateScanRegistry(context.TODO(), domain.ScanCommand{
+		ImageSlug: "imageSlug",
+		ImageTag:  "quay.io/kubescape/kubevuln:latest",
+	})
+	tools.EnsureSetup(t, err == nil)
+	assert.ErrorIs(t, s.ScanRegistry(ctx), domain.ErrIncompleteSBOM)
+	failures, err := s.ScanFailures(context.TODO(), "")
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "quay.io/kubescape/kubevuln:latest", failures[0].Image)
+	assert.Equal(t, domain.StageSBOM, failures[0].Stage)
+	assert.Equal(t, domain.FailureTimeout, failures[0].Reason)
+	assert.Equal(t, domain.ErrorCodeSBOM, failures[0].Code)
+}
+
+func Test_errorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: &domain.RegistryAuthError{Reason: domain.AuthReasonDenied}, want: domain.ErrorCodeImagePull},
+		{err: domain.Categorize(domain.ErrSBOM, &domain.RegistryPullError{}), want: domain.ErrorCodeImagePull},
+		{err: &domain.IncompleteSBOMError{Reason: domain.FailureImageTooLarge}, want: domain.ErrorCodeSBOM},
+		{err: domain.Categorize(domain.ErrMatching, domain.ErrInitVulnDB), want: domain.ErrorCodeMatching},
+		{err: fmt.Errorf("spooling the report: %w", domain.ErrCircuitOpen), want: domain.ErrorCodeSubmit},
+		{err: domain.ErrRegistryDenied, want: domain.ErrorCodePolicy},
+		{err: domain.ErrClusterDenied, want: domain.ErrorCodePolicy},
+		{err: errors.New("boom"), want: domain.ErrorCodeUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tt.want, domain.ErrorCode(tt.err))
+		})
+	}
+	// the categorized errors keep their message and their chain
+	err := domain.Categorize(domain.ErrMatching, domain.ErrInitVulnDB)
+	assert.Equal(t, domain.ErrInitVulnDB.Error(), err.Error())
+	assert.ErrorIs(t, err, domain.ErrInitVulnDB)
+	assert.NoError(t, domain.Categorize(domain.ErrSubmit, nil))
+}
+
+// statusPlatform records the statuses sent with their error code
+type statusPlatform struct {
+	*adapters.MockPlatform
+	steps []int
+	codes []string
+}
+
+func (p *statusPlatform) SendStatus(ctx context.Context, step int) error {
+	p.steps = append(p.steps, step)
+	code, _ := ctx.Value(domain.ErrorCodeKey{}).(string)
+	p.codes = append(p.codes, code)
+	return nil
+}
+
+func TestScanService_reportFailure(t *testing.T) {
+	platform := &statusPlatform{MockPlatform: adapters.NewMockPlatform()}
+	sbomErr := error(errors.New("failed to catalog the image"))
+	s := NewScanService(failingSBOMAdapter{MockSBOMAdapter: adapters.NewMockSBOMAdapter(false, false, false), err: &sbomErr},
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		platform,
+		false)
+	ctx, err := s.ValidateScanRegistry(context.TODO(), domain.ScanCommand{
+		ImageSlug: "imageSlug",
+		ImageTag:  "quay.io/kubescape/kubevuln:latest",
+	})
+	tools.EnsureSetup(t, err == nil)
+	platform.steps, platform.codes = nil, nil
+
+	// the uncategorized errors take the category of the stage they failed at
+	err = s.ScanRegistry(ctx)
+	assert.ErrorIs(t, err, domain.ErrSBOM)
+	assert.Equal(t, "failed to catalog the image", err.Error())
+	require.NotEmpty(t, platform.steps)
+	assert.Equal(t, domain.Failed, platform.steps[len(platform.steps)-1])
+	assert.Equal(t, domain.ErrorCodeSBOM, platform.codes[len(platform.codes)-1])
+	assert.NoError(t, s.reportFailure(context.TODO(), domain.ScanCommand{}, domain.StageMatch, nil))
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"go.opentelemetry.io/otel"
+	"gopkg.in/yaml.v3"
+)
+
+// WithFalsePositives enables the false positive feedback kept by repository: the matches marked as false positives
+// are moved out of the CVE manifests of the scans, into their ignored matches, and reported with a distinct status
+func WithFalsePositives(repository ports.FalsePositiveRepository) ScanServiceOption {
+	return func(s *ScanService) {
+		s.falsePositives = repository
+	}
+}
+
+// AddFalsePositive records the feedback marking a finding as a false positive, it applies to the following reports
+func (s *ScanService) AddFalsePositive(ctx context.Context, falsePositive domain.FalsePositive) (domain.FalsePositive, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.AddFalsePositive")
+	defer span.End()
+
+	if s.falsePositives == nil {
+		return domain.FalsePositive{}, domain.ErrNoFalsePositives
+	}
+	if falsePositive.Vulnerability == "" || falsePositive.Package == "" {
+		return domain.FalsePositive{}, domain.ErrInvalidFalsePositive
+	}
+	falsePositive.ID = uuid.NewString()
+	falsePositive.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	if err := s.falsePositives.StoreFalsePositive(ctx, falsePositive); err != nil {
+		return domain.FalsePositive{}, err
+	}
+	logger.L().Info("marked finding as false positive",
+		helpers.String("vulnerability", falsePositive.Vulnerability),
+		helpers.String("package", falsePositive.Package),
+		helpers.String("version", falsePositive.Version))
+	return falsePositive, nil
+}
+
+// DeleteFalsePositive forgets a false positive feedback, the finding resurfaces with the following reports
+func (s *ScanService) DeleteFalsePositive(ctx context.Context, id string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.DeleteFalsePositive")
+	defer span.End()
+
+	if s.falsePositives == nil {
+		return domain.ErrNoFalsePositives
+	}
+	return s.falsePositives.DeleteFalsePositive(ctx, id)
+}
+
+// ListFalsePositives returns the false positive feedback ordered by creation time
+func (s *ScanService) ListFalsePositives(ctx context.Context) ([]domain.FalsePositive, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.ListFalsePositives")
+	defer span.End()
+
+	if s.falsePositives == nil {
+		return nil, domain.ErrNoFalsePositives
+	}
+	return s.falsePositives.ListFalsePositives(ctx)
+}
+
+// ExportFalsePositives renders the false positive feedback as a Grype ignore rules file or an OpenVEX document
+func (s *ScanService) ExportFalsePositives(ctx context.Context, format string) ([]byte, error) {
+	falsePositives, err := s.ListFalsePositives(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case domain.FalsePositiveFormatGrype:
+		return grypeIgnoreRules(falsePositives)
+	case domain.FalsePositiveFormatOpenVEX:
+		return openVEX(falsePositives)
+	}
+	return nil, fmt.Errorf("%w: %q", domain.ErrFalsePositiveFormat, format)
+}
+
+// grypeConfig is the ignore section of a Grype configuration file
+type grypeConfig struct {
+	Ignore []grypeIgnoreRule `yaml:"ignore"`
+}
+
+type grypeIgnoreRule struct {
+	Vulnerability string             `yaml:"vulnerability"`
+	Package       grypeIgnorePackage `yaml:"package"`
+}
+
+type grypeIgnorePackage struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version,omitempty"`
+}
+
+// grypeIgnoreRules renders the false positives as the ignore rules of a Grype configuration file
+func grypeIgnoreRules(falsePositives []domain.FalsePositive) ([]byte, error) {
+	rules := grypeConfig{Ignore: make([]grypeIgnoreRule, 0, len(falsePositives))}
+	for _, falsePositive := range falsePositives {
+		rules.Ignore = append(rules.Ignore, grypeIgnoreRule{
+			Vulnerability: falsePositive.Vulnerability,
+			Package: grypeIgnorePackage{
+				Name:    falsePositive.Package,
+				Version: falsePositive.Version,
+			},
+		})
+	}
+	return yaml.Marshal(rules)
+}
+
+const (
+	openVEXContext   = "https://openvex.dev/ns/v0.2.0"
+	openVEXIDPrefix  = "https://openvex.dev/docs/public/vex-"
+	openVEXAuthor    = "kubevuln"
+	vexNotAffected   = "not_affected"
+	vexDefaultImpact = "marked as a false positive"
+)
+
+// openVEXDocument is an OpenVEX document, its ID is derived from its statements
+type openVEXDocument struct {
+	Context    string             `json:"@context"`
+	ID         string             `json:"@id"`
+	Author     string             `json:"author"`
+	Timestamp  time.Time          `json:"timestamp"`
+	Version    int                `json:"version"`
+	Statements []openVEXStatement `json:"statements"`
+}
+
+type openVEXStatement struct {
+	Vulnerability   openVEXVulnerability `json:"vulnerability"`
+	Timestamp       time.Time            `json:"timestamp"`
+	Products        []openVEXProduct     `json:"products"`
+	Status          string               `json:"status"`
+	ImpactStatement string               `json:"impact_statement"`
+}
+
+type openVEXVulnerability struct {
+	Name string `json:"name"`
+}
+
+type openVEXProduct struct {
+	ID string `json:"@id"`
+}
+
+// openVEX renders the false positives as the not_affected statements of an OpenVEX document, the packages without
+// package URL are identified by a generic one
+func openVEX(falsePositives []domain.FalsePositive) ([]byte, error) {
+	doc := openVEXDocument{
+		Context:    openVEXContext,
+		Author:     openVEXAuthor,
+		Version:    1,
+		Statements: make([]openVEXStatement, 0, len(falsePositives)),
+	}
+	for _, falsePositive := range falsePositives {
+		product := falsePositive.PURL
+		if product == "" {
+			product = "pkg:generic/" + url.PathEscape(falsePositive.Package)
+			if falsePositive.Version != "" {
+				product += "@" + url.PathEscape(falsePositive.Version)
+			}
+		}
+		impact := falsePositive.Reason
+		if impact == "" {
+			impact = vexDefaultImpact
+		}
+		doc.Statements = append(doc.Statements, openVEXStatement{
+			Vulnerability:   openVEXVulnerability{Name: falsePositive.Vulnerability},
+			Timestamp:       falsePositive.CreatedAt,
+			Products:        []openVEXProduct{{ID: product}},
+			Status:          vexNotAffected,
+			ImpactStatement: impact,
+		})
+		if falsePositive.CreatedAt.After(doc.Timestamp) {
+			doc.Timestamp = falsePositive.CreatedAt
+		}
+	}
+	statements, err := json.Marshal(doc.Statements)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(statements)
+	doc.ID = openVEXIDPrefix + hex.EncodeToString(digest[:])
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// markFalsePositives moves the matches of a CVE manifest marked as false positives into its ignored matches, and
+// records the feedback applied to its ignored matches, including the ones moved by a previous scan; the manifest
+// goes unchanged when the feedback cannot be read
+func (s *ScanService) markFalsePositives(ctx context.Context, cve domain.CVEManifest) domain.CVEManifest {
+	if s.falsePositives == nil || cve.Content == nil {
+		return cve
+	}
+	falsePositives, err := s.falsePositives.ListFalsePositives(ctx)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to list false positives", helpers.Error(err),
+			helpers.String("name", cve.Name))
+		return cve
+	}
+	if len(falsePositives) == 0 {
+		return cve
+	}
+	content := *cve.Content
+	content.Matches = make([]v1beta1.Match, 0, len(cve.Content.Matches))
+	content.IgnoredMatches = append([]v1beta1.IgnoredMatch{}, cve.Content.IgnoredMatches...)
+	for _, match := range cve.Content.Matches {
+		var rules []v1beta1.IgnoreRule
+		for _, falsePositive := range falsePositives {
+			if falsePositive.Matches(match) {
+				rules = append(rules, v1beta1.IgnoreRule{
+					Vulnerability: falsePositive.Vulnerability,
+					Package: &v1beta1.IgnoreRulePackage{
+						Name:    falsePositive.Package,
+						Version: falsePositive.Version,
+					},
+				})
+			}
+		}
+		if len(rules) == 0 {
+			content.Matches = append(content.Matches, match)
+			continue
+		}
+		content.IgnoredMatches = append(content.IgnoredMatches, v1beta1.IgnoredMatch{Match: match, AppliedIgnoreRules: rules})
+	}
+	var applied []domain.FalsePositive
+	for _, falsePositive := range falsePositives {
+		for _, ignored := range content.IgnoredMatches {
+			if falsePositive.Matches(ignored.Match) {
+				applied = append(applied, falsePositive)
+				break
+			}
+		}
+	}
+	if marked := len(cve.Content.Matches) - len(content.Matches); marked > 0 {
+		logger.L().Ctx(ctx).Debug("marked false positives",
+			helpers.String("name", cve.Name),
+			helpers.Int("marked", marked))
+		cve.Content = &content
+	}
+	cve.FalsePositives = applied
+	return cve
+}
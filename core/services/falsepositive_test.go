@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// falsePositiveList keeps the false positives in memory, in insertion order
+type falsePositiveList struct {
+	falsePositives []domain.FalsePositive
+	err            error
+}
+
+func (l *falsePositiveList) DeleteFalsePositive(_ context.Context, id string) error {
+	for i, falsePositive := range l.falsePositives {
+		if falsePositive.ID == id {
+			l.falsePositives = append(l.falsePositives[:i], l.falsePositives[i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrFalsePositiveNotFound
+}
+
+func (l *falsePositiveList) ListFalsePositives(context.Context) ([]domain.FalsePositive, error) {
+	return l.falsePositives, l.err
+}
+
+func (l *falsePositiveList) StoreFalsePositive(_ context.Context, falsePositive domain.FalsePositive) error {
+	l.falsePositives = append(l.falsePositives, falsePositive)
+	return l.err
+}
+
+func TestScanService_falsePositives(t *testing.T) {
+	ctx := context.TODO()
+	s := &ScanService{}
+	_, err := s.AddFalsePositive(ctx, domain.FalsePositive{Vulnerability: "CVE-2021-44228", Package: "log4j-core"})
+	assert.ErrorIs(t, err, domain.ErrNoFalsePositives)
+	_, err = s.ExportFalsePositives(ctx, domain.FalsePositiveFormatGrype)
+	assert.ErrorIs(t, err, domain.ErrNoFalsePositives)
+
+	WithFalsePositives(&falsePositiveList{})(s)
+	_, err = s.AddFalsePositive(ctx, domain.FalsePositive{Vulnerability: "CVE-2021-44228"})
+	assert.ErrorIs(t, err, domain.ErrInvalidFalsePositive)
+	log4j, err := s.AddFalsePositive(ctx, domain.FalsePositive{Vulnerability: "CVE-2021-44228", Package: "log4j-core", Reason: "shaded copy without JndiLookup"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, log4j.ID)
+	assert.False(t, log4j.CreatedAt.IsZero())
+	openssl, err := s.AddFalsePositive(ctx, domain.FalsePositive{Vulnerability: "CVE-2023-0286", Package: "openssl", Version: "1.1.1n-0+deb11u3", PURL: "pkg:deb/debian/openssl@1.1.1n-0+deb11u3"})
+	require.NoError(t, err)
+	falsePositives, err := s.ListFalsePositives(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.FalsePositive{log4j, openssl}, falsePositives)
+
+	rules, err := s.ExportFalsePositives(ctx, domain.FalsePositiveFormatGrype)
+	require.NoError(t, err)
+	assert.Equal(t, `ignore:
+    - vulnerability: CVE-2021-44228
+      package:
+        name: log4j-core
+    - vulnerability: CVE-2023-0286
+      package:
+        name: openssl
+        version: 1.1.1n-0+deb11u3
+`, string(rules))
+
+	vex, err := s.ExportFalsePositives(ctx, domain.FalsePositiveFormatOpenVEX)
+	require.NoError(t, err)
+	var doc openVEXDocument
+	require.NoError(t, json.Unmarshal(vex, &doc))
+	assert.Equal(t, openVEXContext, doc.Context)
+	assert.Contains(t, doc.ID, openVEXIDPrefix)
+	assert.Equal(t, openssl.CreatedAt, doc.Timestamp)
+	assert.Equal(t, []openVEXStatement{
+		{
+			Vulnerability:   openVEXVulnerability{Name: "CVE-2021-44228"},
+			Timestamp:       log4j.CreatedAt,
+			Products:        []openVEXProduct{{ID: "pkg:generic/log4j-core"}},
+			Status:          "not_affected",
+			ImpactStatement: "shaded copy without JndiLookup",
+		},
+		{
+			Vulnerability:   openVEXVulnerability{Name: "CVE-2023-0286"},
+			Timestamp:       openssl.CreatedAt,
+			Products:        []openVEXProduct{{ID: "pkg:deb/debian/openssl@1.1.1n-0+deb11u3"}},
+			Status:          "not_affected",
+			ImpactStatement: "marked as a false positive",
+		},
+	}, doc.Statements)
+	// the document ID only depends on the statements
+	again, err := s.ExportFalsePositives(ctx, domain.FalsePositiveFormatOpenVEX)
+	require.NoError(t, err)
+	assert.Equal(t, string(vex), string(again))
+
+	_, err = s.ExportFalsePositives(ctx, "csv")
+	assert.ErrorIs(t, err, domain.ErrFalsePositiveFormat)
+
+	assert.NoError(t, s.DeleteFalsePositive(ctx, log4j.ID))
+	assert.ErrorIs(t, s.DeleteFalsePositive(ctx, log4j.ID), domain.ErrFalsePositiveNotFound)
+}
+
+func TestScanService_markFalsePositives(t *testing.T) {
+	ctx := context.TODO()
+	log4j := newMatch("CVE-2021-44228", "log4j-core")
+	log4j.Artifact.Version = "2.14.1"
+	opensslOld := newMatch("CVE-2023-0286", "openssl")
+	opensslOld.Artifact.Version = "1.1.1n-0+deb11u3"
+	opensslNew := newMatch("CVE-2023-0286", "openssl")
+	opensslNew.Artifact.Version = "1.1.1n-0+deb11u4"
+	cve := domain.CVEManifest{Name: "imageSlug", Content: &v1beta1.GrypeDocument{Matches: []v1beta1.Match{log4j, opensslOld, opensslNew}}}
+	falsePositive := domain.FalsePositive{ID: "1", Vulnerability: "CVE-2023-0286", Package: "openssl", Version: "1.1.1n-0+deb11u3", CreatedAt: time.Now()}
+
+	s := &ScanService{}
+	assert.Equal(t, cve, s.markFalsePositives(ctx, cve))
+
+	// the manifest goes unchanged when the feedback cannot be read
+	WithFalsePositives(&falsePositiveList{falsePositives: []domain.FalsePositive{falsePositive}, err: domain.ErrMockError})(s)
+	assert.Equal(t, cve, s.markFalsePositives(ctx, cve))
+
+	WithFalsePositives(&falsePositiveList{falsePositives: []domain.FalsePositive{falsePositive}})(s)
+	marked := s.markFalsePositives(ctx, cve)
+	assert.Equal(t, []v1beta1.Match{log4j, opensslNew}, marked.Content.Matches)
+	assert.Equal(t, []v1beta1.IgnoredMatch{{
+		Match: opensslOld,
+		AppliedIgnoreRules: []v1beta1.IgnoreRule{{
+			Vulnerability: "CVE-2023-0286",
+			Package:       &v1beta1.IgnoreRulePackage{Name: "openssl", Version: "1.1.1n-0+deb11u3"},
+		}},
+	}}, marked.Content.IgnoredMatches)
+	assert.Equal(t, []domain.FalsePositive{falsePositive}, marked.FalsePositives)
+	// the manifest of the scan is left untouched
+	assert.Len(t, cve.Content.Matches, 3)
+	// the manifests marked by a previous scan keep reporting the feedback
+	marked.FalsePositives = nil
+	again := s.markFalsePositives(ctx, marked)
+	assert.Equal(t, marked.Content, again.Content)
+	assert.Equal(t, []domain.FalsePositive{falsePositive}, again.FalsePositives)
+}
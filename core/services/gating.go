@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+)
+
+// WithAnnotationGating lets the workloads opt out of the scans with the domain.AnnotationScan annotation, read with
+// annotator when their scans are validated
+func WithAnnotationGating(annotator ports.WorkloadAnnotator) ScanServiceOption {
+	return func(s *ScanService) {
+		s.workloadAnnotator = annotator
+	}
+}
+
+// checkScanAnnotation returns domain.ErrSkippedByAnnotation when the workload opted out of the scans, after reporting
+// the scan as skipped; the workloads whose annotations cannot be read are scanned
+func (s *ScanService) checkScanAnnotation(ctx context.Context, workload domain.ScanCommand) error {
+	if s.workloadAnnotator == nil || workload.Wlid == "" {
+		return nil
+	}
+	annotations, err := s.workloadAnnotator.WorkloadAnnotations(ctx, workload.Wlid)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to read workload annotations", helpers.Error(err),
+			helpers.String("wlid", workload.Wlid))
+		return nil
+	}
+	if !strings.EqualFold(annotations[domain.AnnotationScan], "false") {
+		return nil
+	}
+	logger.L().Info("scan skipped by workload annotation",
+		helpers.String("wlid", workload.Wlid),
+		helpers.String("containerName", workload.ContainerName))
+	if err := s.platform.SendStatus(ctx, domain.Skipped); err != nil {
+		logger.L().Ctx(ctx).Error("telemetry error", helpers.Error(err))
+	}
+	return domain.ErrSkippedByAnnotation
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+// workloadAnnotations is a WorkloadAnnotator of fixed annotations keyed by wlid
+type workloadAnnotations map[string]map[string]string
+
+func (w workloadAnnotations) WorkloadAnnotations(_ context.Context, wlid string) (map[string]string, error) {
+	annotations, ok := w[wlid]
+	if !ok {
+		return nil, errors.New("forbidden")
+	}
+	return annotations, nil
+}
+
+func TestScanService_checkScanAnnotation(t *testing.T) {
+	annotator := workloadAnnotations{
+		"wlid://cluster-minikube/namespace-default/deployment-nginx":   {domain.AnnotationScan: "False"},
+		"wlid://cluster-minikube/namespace-default/deployment-redis":   {domain.AnnotationScan: "true"},
+		"wlid://cluster-minikube/namespace-default/deployment-unknown": nil,
+	}
+	tests := []struct {
+		name    string
+		wlid    string
+		gating  bool
+		wantErr error
+	}{
+		{name: "opted out", wlid: "wlid://cluster-minikube/namespace-default/deployment-nginx", gating: true, wantErr: domain.ErrSkippedByAnnotation},
+		{name: "opted in", wlid: "wlid://cluster-minikube/namespace-default/deployment-redis", gating: true},
+		{name: "not annotated", wlid: "wlid://cluster-minikube/namespace-default/deployment-unknown", gating: true},
+		{name: "unreadable annotations", wlid: "wlid://cluster-minikube/namespace-default/deployment-other", gating: true},
+		{name: "gating disabled", wlid: "wlid://cluster-minikube/namespace-default/deployment-nginx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []ScanServiceOption
+			if tt.gating {
+				opts = append(opts, WithAnnotationGating(annotator))
+			}
+			s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockCVEAdapter(),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockPlatform(),
+				false, opts...)
+			_, err := s.ValidateScanCVE(context.TODO(), domain.ScanCommand{
+				ImageSlug: "imageSlug",
+				ImageHash: "nginx@sha256:3cdf3b7b3c1a1e5b5f0e4f5ad1a0f7b6e4bd2e5ad4a5e3f3d7f1a0b5c2d4e6f8",
+				Wlid:      tt.wlid,
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
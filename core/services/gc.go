@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// garbageCollector tracks since when the stored scan results are unreferenced,
+// tracking restarts with kubevuln which only delays the deletions
+type garbageCollector struct {
+	gracePeriod       time.Duration
+	mu                sync.Mutex
+	repository        ports.ScanResultRepository
+	unreferencedSince map[domain.StoredResult]time.Time
+	workloadLister    ports.WorkloadLister
+}
+
+// workloadKey identifies a workload container, kinds are compared case-insensitively since wlids are lowercase
+func workloadKey(namespace, kind, name, containerName string) string {
+	return strings.Join([]string{namespace, strings.ToLower(kind), name, containerName}, "/")
+}
+
+// CollectGarbage deletes the stored scan results no workload referenced during the grace period, image results are
+// referenced by the digests of the pods, listed by the workload lister and the inventory if any, or by the workload
+// they were scanned for, results which cannot be attributed to a workload, like the ones of the registry scans, are
+// always kept
+func (s *ScanService) CollectGarbage(ctx context.Context) (domain.GCReport, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.CollectGarbage")
+	defer span.End()
+
+	if s.gc == nil {
+		return domain.GCReport{}, domain.ErrNoGC
+	}
+	// list the stored results first, so that results stored meanwhile are matched against fresh workloads
+	results, err := s.gc.repository.ListScanResults(ctx)
+	if err != nil {
+		return domain.GCReport{}, err
+	}
+	images, err := s.gc.workloadLister.ListWorkloadImages(ctx)
+	if err != nil {
+		return domain.GCReport{}, err
+	}
+	// the inventory posted by the operator references the images its pods run besides the ones of the templates
+	if s.inventory != nil {
+		inventory, err := s.inventory.ListWorkloadImages(ctx)
+		if err != nil && !errors.Is(err, domain.ErrInventoryNotSynced) {
+			return domain.GCReport{}, err
+		}
+		images = append(images, inventory...)
+	}
+	// container runtimes report normalized image names, only the digests identify the images reliably
+	digests := map[string]bool{}
+	workloads := map[string]bool{}
+	for _, image := range images {
+		if digest := digestFromImageHash(image.ImageHash); digest != "" {
+			digests[digest] = true
+		}
+		workloads[workloadKey(image.Namespace, image.Kind, image.Name, image.ContainerName)] = true
+	}
+
+	s.gc.mu.Lock()
+	defer s.gc.mu.Unlock()
+	now := time.Now()
+	var report domain.GCReport
+	unreferenced := make(map[domain.StoredResult]time.Time, len(s.gc.unreferencedSince))
+	for _, result := range results {
+		// a result is referenced by its workload or by the digest of its image, unattributed results are kept
+		var attributed, referenced bool
+		if result.WorkloadName != "" {
+			attributed = true
+			referenced = workloads[workloadKey(result.WorkloadNamespace, result.WorkloadKind, result.WorkloadName, result.ContainerName)]
+		}
+		if result.ImageDigest != "" {
+			attributed = true
+			referenced = referenced || digests[result.ImageDigest]
+		}
+		if !attributed || referenced {
+			continue
+		}
+		report.Unreferenced++
+		since, ok := s.gc.unreferencedSince[result]
+		if !ok {
+			since = now
+		}
+		if now.Sub(since) < s.gc.gracePeriod {
+			unreferenced[result] = since
+			continue
+		}
+		if err := s.gc.repository.DeleteScanResult(ctx, result); err != nil {
+			logger.L().Ctx(ctx).Warning("failed to delete unreferenced scan result", helpers.Error(err),
+				helpers.String("kind", result.Kind),
+				helpers.String("namespace", result.Namespace),
+				helpers.String("name", result.Name))
+			unreferenced[result] = since
+			continue
+		}
+		report.Deleted++
+	}
+	// forget the results deleted or referenced again
+	s.gc.unreferencedSince = unreferenced
+	if report.Deleted > 0 {
+		logger.L().Info("deleted unreferenced scan results",
+			helpers.Int("deleted", report.Deleted),
+			helpers.Int("unreferenced", report.Unreferenced))
+	}
+	return report, nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResultRepository keeps the stored results in a slice, the listed results can be made undeletable
+type fakeResultRepository struct {
+	orphaned    []string
+	results     []domain.StoredResult
+	undeletable map[string]bool
+}
+
+func (f *fakeResultRepository) ListScanResults(context.Context) ([]domain.StoredResult, error) {
+	return append([]domain.StoredResult(nil), f.results...), nil
+}
+
+func (f *fakeResultRepository) DeleteScanResult(_ context.Context, result domain.StoredResult) error {
+	if f.undeletable[result.Name] {
+		return domain.ErrMockError
+	}
+	for i, r := range f.results {
+		if r == result {
+			f.results = append(f.results[:i], f.results[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeResultRepository) MarkOrphaned(_ context.Context, namespace, kind, name string) error {
+	if f.undeletable[name] {
+		return domain.ErrMockError
+	}
+	f.orphaned = append(f.orphaned, namespace+"/"+kind+"/"+name)
+	return nil
+}
+
+func TestScanService_CollectGarbage(t *testing.T) {
+	// the runtime reports a normalized name, the stored results are matched by digest
+	running := "docker.io/library/nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"
+	lister := staticWorkloadLister{
+		{Namespace: "default", Kind: "Deployment", Name: "nginx", ContainerName: "nginx", ImageTag: "docker.io/library/nginx:1.14.1", ImageHash: running},
+		// a workload scaled to zero is only listed through its template
+		{Namespace: "default", Kind: "StatefulSet", Name: "postgres", ContainerName: "postgres", ImageTag: "postgres:15"},
+	}
+	runningSBOM := domain.StoredResult{Kind: domain.StoredSBOM, Name: "nginx-slug", ImageSlug: "nginx-slug",
+		ImageDigest:       "sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7",
+		WorkloadNamespace: "default", WorkloadKind: "Deployment", WorkloadName: "nginx-old", ContainerName: "nginx"}
+	scaledDownSBOM := domain.StoredResult{Kind: domain.StoredSBOM, Name: "postgres-slug", ImageSlug: "postgres-slug",
+		ImageDigest: "sha256:5678", WorkloadNamespace: "default", WorkloadKind: "statefulset", WorkloadName: "postgres", ContainerName: "postgres"}
+	goneSBOM := domain.StoredResult{Kind: domain.StoredSBOM, Name: "redis-slug", ImageSlug: "redis-slug",
+		ImageDigest: "sha256:1234", WorkloadNamespace: "default", WorkloadKind: "Deployment", WorkloadName: "redis", ContainerName: "redis"}
+	goneManifest := domain.StoredResult{Kind: domain.StoredVulnerabilityManifest, Name: "instance-id", ImageSlug: "redis-slug", ImageDigest: "sha256:1234"}
+	runningSummary := domain.StoredResult{
+		Kind: domain.StoredVulnerabilitySummary, Namespace: "default", Name: "deployment-nginx-nginx",
+		WorkloadNamespace: "default", WorkloadKind: "deployment", WorkloadName: "nginx", ContainerName: "nginx",
+	}
+	goneSummary := domain.StoredResult{
+		Kind: domain.StoredVulnerabilitySummary, Namespace: "default", Name: "deployment-redis-redis",
+		WorkloadNamespace: "default", WorkloadKind: "Deployment", WorkloadName: "redis", ContainerName: "redis",
+	}
+	// neither the results of a registry scan nor the legacy ones are attributed to a workload
+	registryManifest := domain.StoredResult{Kind: domain.StoredVulnerabilityManifest, Name: "mongo-slug", ImageSlug: "mongo-slug"}
+	unattributed := domain.StoredResult{Kind: domain.StoredVulnerabilityManifest, Name: "legacy-instance-id"}
+	repository := &fakeResultRepository{
+		results:     []domain.StoredResult{runningSBOM, scaledDownSBOM, goneSBOM, goneManifest, runningSummary, goneSummary, registryManifest, unattributed},
+		undeletable: map[string]bool{"instance-id": true},
+	}
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false)
+	_, err := s.CollectGarbage(context.TODO())
+	assert.ErrorIs(t, err, domain.ErrNoGC)
+
+	WithGarbageCollection(lister, repository, time.Hour)(s)
+	// unreferenced results are kept during the grace period
+	report, err := s.CollectGarbage(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, domain.GCReport{Unreferenced: 3}, report)
+	assert.Len(t, repository.results, 8)
+
+	// and deleted once it is over
+	for result := range s.gc.unreferencedSince {
+		s.gc.unreferencedSince[result] = time.Now().Add(-2 * time.Hour)
+	}
+	report, err = s.CollectGarbage(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, domain.GCReport{Unreferenced: 3, Deleted: 2}, report)
+	assert.ElementsMatch(t, []domain.StoredResult{runningSBOM, scaledDownSBOM, goneManifest, runningSummary, registryManifest, unattributed}, repository.results)
+	// failed deletions are retried
+	assert.Contains(t, s.gc.unreferencedSince, goneManifest)
+}
+
+func TestScanService_CollectGarbage_referencedAgain(t *testing.T) {
+	stored := domain.StoredResult{Kind: domain.StoredSBOM, Name: "nginx-slug", ImageSlug: "nginx-slug",
+		ImageDigest: "sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"}
+	repository := &fakeResultRepository{results: []domain.StoredResult{stored}}
+	s := &ScanService{}
+	WithGarbageCollection(staticWorkloadLister{}, repository, time.Hour)(s)
+	_, err := s.CollectGarbage(context.TODO())
+	require.NoError(t, err)
+	assert.Contains(t, s.gc.unreferencedSince, stored)
+
+	// the image runs again, its grace period restarts from scratch once unreferenced again
+	image := domain.WorkloadImage{ImageTag: "nginx:1.14.1", ImageHash: "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"}
+	s.gc.workloadLister = staticWorkloadLister{image}
+	report, err := s.CollectGarbage(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, domain.GCReport{}, report)
+	assert.Empty(t, s.gc.unreferencedSince)
+}
+
+func TestScanService_CollectGarbage_inventory(t *testing.T) {
+	stored := domain.StoredResult{Kind: domain.StoredSBOM, Name: "nginx-slug", ImageSlug: "nginx-slug",
+		ImageDigest: "sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"}
+	repository := &fakeResultRepository{results: []domain.StoredResult{stored}}
+	inventory := repositories.NewMemoryInventory()
+	s := &ScanService{}
+	WithInventory(inventory)(s)
+	WithGarbageCollection(staticWorkloadLister{}, repository, time.Hour)(s)
+	// the inventory is not synced yet, the templates reference nothing
+	report, err := s.CollectGarbage(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, domain.GCReport{Unreferenced: 1}, report)
+
+	// the inventory references the image a pod runs, although no template does
+	image := domain.WorkloadImage{Namespace: "default", Kind: "Pod", Name: "nginx", ContainerName: "nginx",
+		ImageTag: "nginx:1.14.1", ImageHash: "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"}
+	require.NoError(t, inventory.StoreInventory(context.TODO(), []domain.WorkloadImage{image}))
+	report, err = s.CollectGarbage(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, domain.GCReport{}, report)
+	assert.Empty(t, s.gc.unreferencedSince)
+}
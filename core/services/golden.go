@@ -0,0 +1,57 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// WithGoldenBases checks the base images of the scanned images against the catalog of golden base images, flagging
+// the images built on an unapproved base or on a stale digest of an approved one
+func WithGoldenBases(catalog domain.GoldenBases) ScanServiceOption {
+	return func(s *ScanService) {
+		s.goldenBases = make(map[string]string, len(catalog.Bases))
+		for _, base := range catalog.Bases {
+			s.goldenBases[goldenBaseName(base.Name)] = base.Digest
+		}
+		s.goldenBasesVersion = catalog.Version
+	}
+}
+
+// goldenBaseName normalizes the reference of a base image to its repository and tag, the default tag being latest;
+// its digest, if any, is dropped as it is checked apart
+func goldenBaseName(ref string) string {
+	ref, _, _ = strings.Cut(ref, "@")
+	tag, err := name.NewTag(ref)
+	if err != nil {
+		return ref
+	}
+	return tag.Name()
+}
+
+// goldenBaseStatus returns the status of the base image recorded in the annotations against the golden base catalog,
+// the digest of the base image being read from its reference when not recorded apart
+func (s *ScanService) goldenBaseStatus(annotations map[string]string) string {
+	baseName := annotations[domain.OCIBaseName]
+	if baseName == "" {
+		return domain.GoldenBaseUnknown
+	}
+	approvedDigest, ok := s.goldenBases[goldenBaseName(baseName)]
+	if !ok {
+		return domain.GoldenBaseUnapproved
+	}
+	digest := annotations[domain.OCIBaseDigest]
+	if digest == "" {
+		_, digest, _ = strings.Cut(baseName, "@")
+	}
+	if digest != approvedDigest {
+		return domain.GoldenBaseStale
+	}
+	return domain.GoldenBaseApproved
+}
+
+// goldenBaseDrift tells whether the status of a base image is a drift from the golden base catalog
+func goldenBaseDrift(status string) bool {
+	return status == domain.GoldenBaseStale || status == domain.GoldenBaseUnapproved
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanService_goldenBaseStatus(t *testing.T) {
+	const approved = "sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137"
+	const stale = "sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"
+	s := &ScanService{}
+	WithGoldenBases(domain.GoldenBases{Version: "2023-06", Bases: []domain.GoldenBase{
+		{Name: "debian:12", Digest: approved},
+		{Name: "registry.corp/golden/alpine", Digest: approved},
+	}})(s)
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        string
+	}{
+		{
+			name:        "approved base",
+			annotations: map[string]string{domain.OCIBaseName: "docker.io/library/debian:12", domain.OCIBaseDigest: approved},
+			want:        domain.GoldenBaseApproved,
+		},
+		{
+			name:        "approved base with the digest in its reference",
+			annotations: map[string]string{domain.OCIBaseName: "registry.corp/golden/alpine:latest@" + approved},
+			want:        domain.GoldenBaseApproved,
+		},
+		{
+			name:        "stale digest",
+			annotations: map[string]string{domain.OCIBaseName: "debian:12", domain.OCIBaseDigest: stale},
+			want:        domain.GoldenBaseStale,
+		},
+		{
+			name:        "unapproved tag",
+			annotations: map[string]string{domain.OCIBaseName: "debian:11", domain.OCIBaseDigest: approved},
+			want:        domain.GoldenBaseUnapproved,
+		},
+		{
+			name:        "no base recorded",
+			annotations: map[string]string{},
+			want:        domain.GoldenBaseUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.goldenBaseStatus(tt.annotations))
+		})
+	}
+
+	// the drift is flagged in the manifest and reported as a violation
+	cve := domain.CVEManifest{Annotations: map[string]string{domain.OCIBaseName: "debian:12", domain.OCIBaseDigest: stale}}
+	s.annotateChecks(&cve, "")
+	assert.Equal(t, domain.GoldenBaseStale, cve.Annotations[domain.AnnotationGoldenBase])
+	assert.Equal(t, "2023-06", cve.Annotations[domain.AnnotationGoldenBaseVersion])
+	assert.Equal(t, []string{domain.PolicyGoldenBaseDrift}, violations(cve))
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/k8s-interface/names"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"go.opentelemetry.io/otel"
+)
+
+// ScanImage creates the SBOM of an image and scans it for vulnerabilities, nothing is stored nor submitted to the
+// platform; it is the scan pipeline of the programs embedding kubevuln, the image tag is pinned to its digest when
+// possible
+func (s *ScanService) ScanImage(ctx context.Context, image string, options domain.RegistryOptions) (sbom domain.SBOM, cve domain.CVEManifest, err error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.ScanImage")
+	defer span.End()
+
+	ctx = addTimestamp(ctx)
+	ctx = trackStages(ctx)
+	// a panic fails the scan instead of crashing the program
+	defer tools.RecoverPanic(ctx, &err)
+
+	workload := domain.ScanCommand{
+		ImageTag:           image,
+		ImageTagNormalized: tools.NormalizeReference(image),
+	}
+	imageID := workload.ImageTagNormalized
+	if s.imageResolver != nil && !strings.Contains(image, "@") {
+		digest, err := s.imageResolver.ResolveDigest(ctx, workload.ImageTagNormalized, options)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to resolve image digest", helpers.Error(err),
+				helpers.String("imageTag", image))
+		} else {
+			imageID = digest
+		}
+	}
+	workload.ImageHash = imageID
+	workload.ImageSlug, err = names.ImageInfoToSlug(workload.ImageTagNormalized, imageID)
+	if err != nil {
+		return sbom, cve, domain.ErrMissingImageInfo
+	}
+	if err := s.checkRegistry(workload); err != nil {
+		return sbom, cve, err
+	}
+
+	// create SBOM
+	start := time.Now()
+	sbom, err = s.sbomCreator.CreateSBOM(ctx, workload.ImageSlug, imageID, options)
+	recordStage(ctx, domain.StageSBOM, start)
+	if err != nil {
+		return sbom, cve, err
+	}
+	// do not process timed out SBOM
+	if sbom.Status == instanceidhandler.Incomplete {
+		return sbom, cve, domain.ErrIncompleteSBOM
+	}
+
+	// scan for CVE
+	cve, err = s.scanSBOM(ctx, sbom)
+	if err != nil {
+		return sbom, cve, err
+	}
+	s.annotateChecks(&cve, "")
+	cve = s.withExploits(ctx, cve)
+	s.annotateRisk(ctx, workload, &cve, domain.CVEManifest{})
+	return sbom, cve, nil
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanService_ScanImage(t *testing.T) {
+	tests := []struct {
+		name       string
+		image      string
+		sbomError  bool
+		timeout    bool
+		registries []string
+		wantErr    error
+	}{
+		{name: "scan", image: "nginx:1.14.1"},
+		{name: "digest", image: "nginx@sha256:32fdf92b4e986e109e4db0865758020cb0c3b70d6ba80d02fe87bad5cc3dc228"},
+		{name: "SBOM error", image: "nginx:1.14.1", sbomError: true, wantErr: domain.ErrMockError},
+		{name: "incomplete SBOM", image: "nginx:1.14.1", timeout: true, wantErr: domain.ErrIncompleteSBOM},
+		{name: "registry denied", image: "nginx:1.14.1", registries: []string{"ghcr.io"}, wantErr: domain.ErrRegistryDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// nothing is stored nor submitted
+			s := NewScanService(adapters.NewMockSBOMAdapter(tt.sbomError, tt.timeout, false), nil,
+				adapters.NewMockCVEAdapter(), nil, nil, false,
+				WithAllowedRegistries(tt.registries))
+			sbom, cve, err := s.ScanImage(context.TODO(), tt.image, domain.RegistryOptions{})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, sbom.Content)
+			assert.NotNil(t, cve.Content)
+			assert.Equal(t, sbom.Name, cve.Name)
+			assert.Contains(t, cve.Annotations, domain.AnnotationScanProvenance)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// WithInventory keeps the inventory of the cluster posted by the operator in repository, the scan times and failures
+// of the images no longer running are forgotten at each sync
+func WithInventory(repository ports.InventoryRepository) ScanServiceOption {
+	return func(s *ScanService) {
+		s.inventory = repository
+	}
+}
+
+// SyncInventory replaces the inventory of the cluster with the one posted by the operator, and forgets the scan times
+// and the workload scan failures of the images no longer running
+func (s *ScanService) SyncInventory(ctx context.Context, inventory domain.Inventory) (domain.InventorySync, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.SyncInventory")
+	defer span.End()
+
+	if s.inventory == nil {
+		return domain.InventorySync{}, domain.ErrNoInventory
+	}
+	seen := map[domain.WorkloadImage]bool{}
+	images := make([]domain.WorkloadImage, 0, len(inventory.Images))
+	for _, image := range inventory.Images {
+		// without digest, the running images would have their scan times and failures forgotten
+		if image.Namespace == "" || image.Kind == "" || image.Name == "" || image.ContainerName == "" || image.ImageTag == "" || image.ImageHash == "" {
+			return domain.InventorySync{}, domain.ErrInvalidInventory
+		}
+		if !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+	previous, err := s.inventory.ListWorkloadImages(ctx)
+	if err != nil && !errors.Is(err, domain.ErrInventoryNotSynced) {
+		return domain.InventorySync{}, err
+	}
+	if err := s.inventory.StoreInventory(ctx, images); err != nil {
+		return domain.InventorySync{}, err
+	}
+	sync := domain.InventorySync{Images: len(images)}
+	for _, image := range previous {
+		if seen[image] {
+			delete(seen, image)
+		} else {
+			sync.Removed++
+		}
+	}
+	sync.Added = len(seen)
+	running := map[string]bool{}
+	for _, image := range images {
+		if digest := digestFromImageHash(image.ImageHash); digest != "" {
+			running[digest] = true
+		}
+	}
+	sync.ForgottenScans = s.forgetScans(running)
+	sync.ForgottenFailures = s.forgetFailures(ctx, running)
+	logger.L().Info("synced the cluster inventory",
+		helpers.Int("images", sync.Images),
+		helpers.Int("added", sync.Added),
+		helpers.Int("removed", sync.Removed))
+	return sync, nil
+}
+
+// forgetScans forgets the last scan times of the images not running, they only matter to the coverage
+func (s *ScanService) forgetScans(running map[string]bool) int {
+	s.lastScansMu.Lock()
+	defer s.lastScansMu.Unlock()
+	var forgotten int
+	for digest := range s.lastScans {
+		if !running[digest] {
+			delete(s.lastScans, digest)
+			forgotten++
+		}
+	}
+	return forgotten
+}
+
+// forgetFailures forgets the last failures of the workload scans of images not running, the failures of the registry
+// scans are kept until they are scanned again
+func (s *ScanService) forgetFailures(ctx context.Context, running map[string]bool) int {
+	s.loadFailures(ctx)
+	s.failuresMu.Lock()
+	var forgotten []string
+	for image, failure := range s.failures {
+		if digest := digestFromImageHash(image); failure.Wlid != "" && digest != "" && !running[digest] {
+			delete(s.failures, image)
+			forgotten = append(forgotten, image)
+		}
+	}
+	s.failuresMu.Unlock()
+	if s.failureRepository != nil {
+		for _, image := range forgotten {
+			if err := s.failureRepository.DeleteScanFailure(ctx, image); err != nil {
+				logger.L().Ctx(ctx).Warning("error deleting scan failure", helpers.Error(err),
+					helpers.String("image", image))
+			}
+		}
+	}
+	return len(forgotten)
+}
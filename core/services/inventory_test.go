@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanService_SyncInventory(t *testing.T) {
+	nginx := domain.WorkloadImage{Namespace: "default", Kind: "Deployment", Name: "nginx", ContainerName: "nginx",
+		ImageTag: "nginx:1.25", ImageHash: "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"}
+	redis := domain.WorkloadImage{Namespace: "default", Kind: "StatefulSet", Name: "redis", ContainerName: "redis",
+		ImageTag: "redis:7", ImageHash: "redis@sha256:1234"}
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false)
+	ctx := context.TODO()
+	_, err := s.SyncInventory(ctx, domain.Inventory{Images: []domain.WorkloadImage{nginx}})
+	assert.ErrorIs(t, err, domain.ErrNoInventory)
+
+	inventory := repositories.NewMemoryInventory()
+	WithInventory(inventory)(s)
+	WithCoverageTracking(inventory, 24*time.Hour)(s)
+	// nothing is reported until the operator posts the inventory
+	_, err = s.Coverage(ctx)
+	assert.ErrorIs(t, err, domain.ErrInventoryNotSynced)
+
+	sync, err := s.SyncInventory(ctx, domain.Inventory{Images: []domain.WorkloadImage{nginx, redis, nginx}})
+	require.NoError(t, err)
+	assert.Equal(t, domain.InventorySync{Images: 2, Added: 2}, sync)
+	report, err := s.Coverage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Total)
+
+	// the scan times and workload scan failures of the images no longer running are forgotten
+	s.recordScan(nginx.ImageHash)
+	s.recordScan(redis.ImageHash)
+	s.failures["sha256:1234"] = domain.ScanFailure{Image: "sha256:1234", Wlid: "wlid://cluster-minikube/namespace-default/statefulset-redis"}
+	s.failures["sha256:5678"] = domain.ScanFailure{Image: "sha256:5678"}
+	sync, err = s.SyncInventory(ctx, domain.Inventory{Images: []domain.WorkloadImage{nginx}})
+	require.NoError(t, err)
+	assert.Equal(t, domain.InventorySync{Images: 1, Removed: 1, ForgottenScans: 1, ForgottenFailures: 1}, sync)
+	assert.Contains(t, s.lastScans, "sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7")
+	assert.NotContains(t, s.failures, "sha256:1234")
+	// the failures of the registry scans are kept
+	assert.Contains(t, s.failures, "sha256:5678")
+
+	_, err = s.SyncInventory(ctx, domain.Inventory{Images: []domain.WorkloadImage{{Namespace: "default", Name: "nginx"}}})
+	assert.ErrorIs(t, err, domain.ErrInvalidInventory)
+	// the inventory is left unchanged by the entries without image hash
+	withoutHash := nginx
+	withoutHash.ImageHash = ""
+	_, err = s.SyncInventory(ctx, domain.Inventory{Images: []domain.WorkloadImage{withoutHash}})
+	assert.ErrorIs(t, err, domain.ErrInvalidInventory)
+	assert.Contains(t, s.lastScans, "sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7")
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// WithLayerDiffScans enables the layer diff scans, the differing layers of the images being cataloged by creator
+func WithLayerDiffScans(creator ports.LayerDiffSBOMCreator) ScanServiceOption {
+	return func(s *ScanService) {
+		s.layerDiffCreator = creator
+	}
+}
+
+// ScanLayerDiff scans the layers which differ between two releases of an image and returns the vulnerabilities the
+// image introduces and removes compared to the base, nothing is stored nor submitted
+func (s *ScanService) ScanLayerDiff(ctx context.Context, request domain.LayerDiffRequest) (domain.LayerDiff, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.ScanLayerDiff")
+	defer span.End()
+
+	if s.layerDiffCreator == nil {
+		return domain.LayerDiff{}, domain.ErrNoLayerDiff
+	}
+	if request.Base == "" || request.Image == "" {
+		return domain.LayerDiff{}, domain.ErrInvalidLayerDiff
+	}
+	workload := domain.ScanCommand{
+		Credentialslist: request.Credentialslist,
+		ImageHash:       request.Image,
+		ImageTag:        request.Base,
+	}
+	if err := s.checkRegistry(workload); err != nil {
+		return domain.LayerDiff{}, err
+	}
+	if !s.cveScanner.Ready(ctx) {
+		return domain.LayerDiff{}, domain.ErrInitVulnDB
+	}
+
+	sboms, err := s.layerDiffCreator.CreateLayerDiffSBOMs(ctx, request.Base, request.Image, optionsFromWorkload(workload))
+	if err != nil {
+		return domain.LayerDiff{}, err
+	}
+	for _, sbom := range []domain.SBOM{sboms.Base, sboms.Image} {
+		if sbom.Status == instanceidhandler.Incomplete {
+			return domain.LayerDiff{}, domain.ErrIncompleteSBOM
+		}
+	}
+	base, err := s.scanSBOM(ctx, sboms.Base)
+	if err != nil {
+		return domain.LayerDiff{}, err
+	}
+	image, err := s.scanSBOM(ctx, sboms.Image)
+	if err != nil {
+		return domain.LayerDiff{}, err
+	}
+	diff := diffScans(domain.ScanRecord{Findings: findings(base)}, domain.ScanRecord{Findings: findings(image)})
+
+	logger.L().Info("layer diff scan done",
+		helpers.String("base", request.Base),
+		helpers.String("image", request.Image),
+		helpers.Int("addedLayers", len(sboms.AddedLayers)),
+		helpers.Int("introduced", len(diff.New)),
+		helpers.Int("removed", len(diff.Fixed)))
+	return domain.LayerDiff{
+		Base:          request.Base,
+		Image:         request.Image,
+		AddedLayers:   nonNil(sboms.AddedLayers),
+		RemovedLayers: nonNil(sboms.RemovedLayers),
+		SharedLayers:  sboms.SharedLayers,
+		Introduced:    diff.New,
+		Removed:       diff.Fixed,
+	}, nil
+}
+
+// nonNil returns an empty list instead of nil, reported as [] rather than null
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
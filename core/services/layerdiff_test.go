@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+// layerCVEAdapter matches the SBOMs of the layer diffs with the vulnerabilities of their name
+type layerCVEAdapter struct {
+	*adapters.MockCVEAdapter
+	matches map[string][]v1beta1.Match
+}
+
+func (l layerCVEAdapter) ScanSBOM(ctx context.Context, sbom domain.SBOM) (domain.CVEManifest, error) {
+	cve, err := l.MockCVEAdapter.ScanSBOM(ctx, sbom)
+	cve.Content.Matches = l.matches[sbom.Name]
+	return cve, err
+}
+
+func TestScanService_ScanLayerDiff(t *testing.T) {
+	log4j := newMatch("CVE-2021-44228", "log4j-core")
+	log4j.Vulnerability.Severity = "Critical"
+	text := newMatch("CVE-2022-32149", "golang.org/x/text")
+	text.Vulnerability.Severity = "High"
+	openssl := newMatch("CVE-2023-0286", "openssl")
+	openssl.Vulnerability.Severity = "High"
+	cveAdapter := layerCVEAdapter{
+		MockCVEAdapter: adapters.NewMockCVEAdapter(),
+		matches: map[string][]v1beta1.Match{
+			"base":  {log4j, openssl},
+			"image": {openssl, text},
+		},
+	}
+	request := domain.LayerDiffRequest{
+		Base:  "quay.io/kubescape/kubevuln:v0.2.0",
+		Image: "quay.io/kubescape/kubevuln:v0.2.1",
+	}
+	tests := []struct {
+		name              string
+		createSBOMError   bool
+		timeout           bool
+		disabled          bool
+		allowedRegistries []string
+		request           domain.LayerDiffRequest
+		want              domain.LayerDiff
+		wantErr           error
+	}{
+		{
+			name:     "not enabled",
+			disabled: true,
+			request:  request,
+			wantErr:  domain.ErrNoLayerDiff,
+		},
+		{
+			name:    "missing base",
+			request: domain.LayerDiffRequest{Image: request.Image},
+			wantErr: domain.ErrInvalidLayerDiff,
+		},
+		{
+			name:              "registry not allowed",
+			allowedRegistries: []string{"ghcr.io"},
+			request:           request,
+			wantErr:           domain.ErrRegistryDenied,
+		},
+		{
+			name:            "create SBOM error",
+			createSBOMError: true,
+			request:         request,
+			wantErr:         domain.ErrMockError,
+		},
+		{
+			name:    "timeout SBOM",
+			timeout: true,
+			request: request,
+			wantErr: domain.ErrIncompleteSBOM,
+		},
+		{
+			name:    "diff",
+			request: request,
+			want: domain.LayerDiff{
+				Base:          request.Base,
+				Image:         request.Image,
+				AddedLayers:   []string{},
+				RemovedLayers: []string{},
+				Introduced:    []domain.Finding{{ID: "CVE-2022-32149", Package: "golang.org/x/text", Severity: "High"}},
+				Removed:       []domain.Finding{{ID: "CVE-2021-44228", Package: "log4j-core", Severity: "Critical"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sbomAdapter := adapters.NewMockSBOMAdapter(tt.createSBOMError, tt.timeout, false)
+			storage := repositories.NewMemoryStorage(false, false)
+			opts := []ScanServiceOption{WithAllowedRegistries(tt.allowedRegistries)}
+			if !tt.disabled {
+				opts = append(opts, WithLayerDiffScans(sbomAdapter))
+			}
+			s := NewScanService(sbomAdapter, storage, cveAdapter, storage, adapters.NewMockPlatform(), false, opts...)
+			got, err := s.ScanLayerDiff(context.TODO(), tt.request)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
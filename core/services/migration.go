@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// sbomMigrator holds the dependencies of the SBOM migration
+type sbomMigrator struct {
+	interval   time.Duration
+	repository ports.SBOMMigrationRepository
+	results    ports.ScanResultRepository
+}
+
+// WithSBOMMigration enables the migration of the stored SBOMs to the current schema version,
+// one SBOM is upgraded every interval to spare the storage
+func WithSBOMMigration(results ports.ScanResultRepository, repository ports.SBOMMigrationRepository, interval time.Duration) ScanServiceOption {
+	return func(s *ScanService) {
+		s.sbomMigrator = &sbomMigrator{interval: interval, repository: repository, results: results}
+	}
+}
+
+// sbomMigrations upgrade a SBOM from the schema version they are indexed by to the next one
+var sbomMigrations = map[int]func(domain.SBOM) domain.SBOM{
+	1: recordSBOMIdentity,
+}
+
+// recordSBOMIdentity records the image slug and the SBOM creator version in the annotations, which lets the SBOMs
+// be garbage collected and checked against the current SBOM creator without reading them
+func recordSBOMIdentity(sbom domain.SBOM) domain.SBOM {
+	if _, ok := sbom.Annotations[domain.AnnotationImageSlug]; !ok {
+		// SBOMs are named after their image slug
+		sbom.Annotations[domain.AnnotationImageSlug] = sbom.Name
+	}
+	if sbom.SBOMCreatorVersion != "" {
+		sbom.Annotations[domain.AnnotationSBOMCreatorVersion] = sbom.SBOMCreatorVersion
+	}
+	return sbom
+}
+
+// sbomSchemaVersion returns the schema version recorded in the annotations of a SBOM, 1 when missing
+func sbomSchemaVersion(annotations map[string]string) int {
+	version, err := strconv.Atoi(annotations[domain.AnnotationSBOMSchemaVersion])
+	if err != nil || version < 1 {
+		return 1
+	}
+	return version
+}
+
+// migrateSBOM upgrades a SBOM to the current schema version, it returns false when it is already up-to-date
+func migrateSBOM(sbom domain.SBOM) (domain.SBOM, bool) {
+	version := sbomSchemaVersion(sbom.Annotations)
+	if version >= domain.SBOMSchemaVersion {
+		return sbom, false
+	}
+	// the migrations work on a copy of the annotations
+	annotations := make(map[string]string, len(sbom.Annotations)+3)
+	for key, value := range sbom.Annotations {
+		annotations[key] = value
+	}
+	sbom.Annotations = annotations
+	for ; version < domain.SBOMSchemaVersion; version++ {
+		if migration, ok := sbomMigrations[version]; ok {
+			sbom = migration(sbom)
+		}
+	}
+	sbom.Annotations[domain.AnnotationSBOMSchemaVersion] = strconv.Itoa(domain.SBOMSchemaVersion)
+	return sbom, true
+}
+
+// MigrateSBOMs upgrades in place the stored SBOMs of older schema versions, instead of discarding them and scanning
+// their images again, SBOMs created by another version of the SBOM creator are left to CheckSBOMCompatibility
+func (s *ScanService) MigrateSBOMs(ctx context.Context) (domain.SBOMMigrationReport, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.MigrateSBOMs")
+	defer span.End()
+
+	if s.sbomMigrator == nil {
+		return domain.SBOMMigrationReport{}, domain.ErrNoSBOMMigration
+	}
+	results, err := s.sbomMigrator.results.ListScanResults(ctx)
+	if err != nil {
+		return domain.SBOMMigrationReport{}, err
+	}
+	version := s.sbomCreator.Version()
+	current := strconv.Itoa(domain.SBOMSchemaVersion)
+	var report domain.SBOMMigrationReport
+	var errs *multierror.Error
+	for _, result := range results {
+		if result.Kind != domain.StoredSBOM || result.SBOMSchemaVersion == current {
+			continue
+		}
+		// spread the migrations over time
+		if report.Checked > 0 {
+			select {
+			case <-ctx.Done():
+				return report, ctx.Err()
+			case <-time.After(s.sbomMigrator.interval):
+			}
+		}
+		report.Checked++
+		sbom, err := s.sbomRepository.GetSBOM(ctx, result.Name, version)
+		if err != nil {
+			report.Failed++
+			errs = multierror.Append(errs, fmt.Errorf("failed to get SBOM %s: %w", result.Name, err))
+			continue
+		}
+		if sbom.Content == nil {
+			continue
+		}
+		migrated, ok := migrateSBOM(sbom)
+		if !ok {
+			continue
+		}
+		if err := s.sbomMigrator.repository.UpdateSBOM(ctx, migrated); err != nil {
+			report.Failed++
+			errs = multierror.Append(errs, fmt.Errorf("failed to update SBOM %s: %w", result.Name, err))
+			continue
+		}
+		report.Migrated++
+	}
+	logger.L().Info("migrated stored SBOMs",
+		helpers.Int("schemaVersion", domain.SBOMSchemaVersion),
+		helpers.Int("checked", report.Checked),
+		helpers.Int("migrated", report.Migrated),
+		helpers.Int("failed", report.Failed))
+	return report, errs.ErrorOrNil()
+}
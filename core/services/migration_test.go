@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_migrateSBOM(t *testing.T) {
+	legacy := domain.SBOM{
+		Name:               "nginx-slug",
+		SBOMCreatorVersion: "v0.76.0",
+		Annotations:        map[string]string{"key": "value"},
+	}
+	migrated, ok := migrateSBOM(legacy)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{
+		"key":                               "value",
+		domain.AnnotationImageSlug:          "nginx-slug",
+		domain.AnnotationSBOMCreatorVersion: "v0.76.0",
+		domain.AnnotationSBOMSchemaVersion:  "2",
+	}, migrated.Annotations)
+	// the original annotations are left untouched
+	assert.Len(t, legacy.Annotations, 1)
+
+	// the recorded image slug is kept
+	migrated, ok = migrateSBOM(domain.SBOM{Name: "instance-id", Annotations: map[string]string{domain.AnnotationImageSlug: "nginx-slug"}})
+	assert.True(t, ok)
+	assert.Equal(t, "nginx-slug", migrated.Annotations[domain.AnnotationImageSlug])
+
+	_, ok = migrateSBOM(migrated)
+	assert.False(t, ok)
+}
+
+func Test_sbomSchemaVersion(t *testing.T) {
+	assert.Equal(t, 1, sbomSchemaVersion(nil))
+	assert.Equal(t, 1, sbomSchemaVersion(map[string]string{domain.AnnotationSBOMSchemaVersion: "latest"}))
+	assert.Equal(t, 2, sbomSchemaVersion(map[string]string{domain.AnnotationSBOMSchemaVersion: "2"}))
+}
+
+func TestScanService_MigrateSBOMs(t *testing.T) {
+	ctx := context.TODO()
+	legacy := domain.StoredResult{Kind: domain.StoredSBOM, Name: "nginx-slug", ImageSlug: "nginx-slug"}
+	current := domain.StoredResult{Kind: domain.StoredSBOM, Name: "redis-slug", ImageSlug: "redis-slug", SBOMSchemaVersion: "2"}
+	outdated := domain.StoredResult{Kind: domain.StoredSBOM, Name: "alpine-slug", ImageSlug: "alpine-slug", SBOMCreatorVersion: "Mock SBOM 0.9"}
+	manifest := domain.StoredResult{Kind: domain.StoredVulnerabilityManifest, Name: "instance-id", ImageSlug: "nginx-slug"}
+	results := &fakeResultRepository{results: []domain.StoredResult{legacy, current, outdated, manifest}}
+	storage := repositories.NewMemoryStorage(false, false)
+	for _, sbom := range []domain.SBOM{
+		{Name: "nginx-slug", SBOMCreatorVersion: "Mock SBOM 1.0", Content: &v1beta1.Document{}},
+		{Name: "alpine-slug", SBOMCreatorVersion: "Mock SBOM 0.9", Content: &v1beta1.Document{}},
+	} {
+		require.NoError(t, storage.StoreSBOM(ctx, sbom))
+	}
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		storage,
+		adapters.NewMockCVEAdapter(),
+		storage,
+		adapters.NewMockPlatform(),
+		true)
+	_, err := s.MigrateSBOMs(ctx)
+	assert.ErrorIs(t, err, domain.ErrNoSBOMMigration)
+
+	WithSBOMMigration(results, storage, time.Millisecond)(s)
+	report, err := s.MigrateSBOMs(ctx)
+	require.NoError(t, err)
+	// SBOMs of other SBOM creator versions are left to the compatibility check
+	assert.Equal(t, domain.SBOMMigrationReport{Checked: 2, Migrated: 1}, report)
+	sbom, err := storage.GetSBOM(ctx, "nginx-slug", "Mock SBOM 1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "2", sbom.Annotations[domain.AnnotationSBOMSchemaVersion])
+	assert.NotNil(t, sbom.Content)
+	sbom, err = storage.GetSBOM(ctx, "alpine-slug", "Mock SBOM 0.9")
+	require.NoError(t, err)
+	assert.Empty(t, sbom.Annotations)
+}
+
+func TestScanService_MigrateSBOMs_canceled(t *testing.T) {
+	results := &fakeResultRepository{results: []domain.StoredResult{
+		{Kind: domain.StoredSBOM, Name: "nginx-slug"},
+		{Kind: domain.StoredSBOM, Name: "redis-slug"},
+	}}
+	storage := repositories.NewMemoryStorage(false, false)
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		storage,
+		adapters.NewMockCVEAdapter(),
+		storage,
+		adapters.NewMockPlatform(),
+		true,
+		WithSBOMMigration(results, storage, time.Hour))
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	// the rate limit gives up waiting once canceled
+	report, err := s.MigrateSBOMs(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, domain.SBOMMigrationReport{Checked: 1}, report)
+}
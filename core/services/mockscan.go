@@ -17,6 +17,82 @@ func NewMockScanService(happy bool) *MockScanService {
 	return &MockScanService{happy: happy}
 }
 
+func (m MockScanService) AddFalsePositive(_ context.Context, falsePositive domain.FalsePositive) (domain.FalsePositive, error) {
+	if m.happy {
+		falsePositive.ID = "id"
+		return falsePositive, nil
+	}
+	return domain.FalsePositive{}, domain.ErrMockError
+}
+
+func (m MockScanService) AddTriage(_ context.Context, triage domain.Triage) (domain.Triage, error) {
+	if m.happy {
+		triage.ID = "id"
+		return triage, nil
+	}
+	return domain.Triage{}, domain.ErrMockError
+}
+
+func (m MockScanService) CacheStats(context.Context) domain.CacheReport {
+	return domain.CacheReport{}
+}
+
+func (m MockScanService) CheckSBOMCompatibility(context.Context) (domain.SBOMCompatibilityReport, error) {
+	if m.happy {
+		return domain.SBOMCompatibilityReport{}, nil
+	}
+	return domain.SBOMCompatibilityReport{}, domain.ErrMockError
+}
+
+func (m MockScanService) CollectGarbage(context.Context) (domain.GCReport, error) {
+	if m.happy {
+		return domain.GCReport{}, nil
+	}
+	return domain.GCReport{}, domain.ErrMockError
+}
+
+func (m MockScanService) CompareScans(context.Context, domain.DiffRequest) (domain.ScanDiff, error) {
+	if m.happy {
+		return domain.ScanDiff{}, nil
+	}
+	return domain.ScanDiff{}, domain.ErrMockError
+}
+
+func (m MockScanService) Coverage(context.Context) (domain.CoverageReport, error) {
+	if m.happy {
+		return domain.CoverageReport{}, nil
+	}
+	return domain.CoverageReport{}, domain.ErrMockError
+}
+
+func (m MockScanService) DeleteFalsePositive(context.Context, string) error {
+	if m.happy {
+		return nil
+	}
+	return domain.ErrMockError
+}
+
+func (m MockScanService) DeleteTriage(context.Context, string) error {
+	if m.happy {
+		return nil
+	}
+	return domain.ErrMockError
+}
+
+func (m MockScanService) DeleteWorkload(context.Context, string) error {
+	if m.happy {
+		return nil
+	}
+	return domain.ErrMockError
+}
+
+func (m MockScanService) ExportBundle(_ context.Context, start func(domain.SBOMBundle) error, _ func(domain.BundledSBOM) error) error {
+	if m.happy {
+		return start(domain.SBOMBundle{FormatVersion: domain.BundleFormatVersion})
+	}
+	return domain.ErrMockError
+}
+
 func (m MockScanService) GenerateSBOM(context.Context) error {
 	if m.happy {
 		return nil
@@ -24,10 +100,73 @@ func (m MockScanService) GenerateSBOM(context.Context) error {
 	return domain.ErrMockError
 }
 
+func (m MockScanService) ExportFalsePositives(context.Context, string) ([]byte, error) {
+	if m.happy {
+		return []byte("ignore: []\n"), nil
+	}
+	return nil, domain.ErrMockError
+}
+
+func (m MockScanService) ExportResults(_ context.Context, _ string, write func(domain.ResultRow) error) error {
+	if m.happy {
+		return write(domain.ResultRow{Wlid: "wlid://cluster-minikube/namespace-default/deployment-nginx", Container: "nginx", Image: "nginx:latest", Vulnerability: "CVE-2023-0001", Severity: "High", Package: "openssl", Version: "1.1.1"})
+	}
+	return domain.ErrMockError
+}
+
+func (m MockScanService) HTMLReport(context.Context, string) ([]byte, error) {
+	if m.happy {
+		return []byte("<!DOCTYPE html>\n"), nil
+	}
+	return nil, domain.ErrMockError
+}
+
+func (m MockScanService) ImportResults(context.Context, domain.ResultsBundle) (domain.BundleImportReport, error) {
+	if m.happy {
+		return domain.BundleImportReport{}, nil
+	}
+	return domain.BundleImportReport{}, domain.ErrMockError
+}
+
+func (m MockScanService) ListFalsePositives(context.Context) ([]domain.FalsePositive, error) {
+	if m.happy {
+		return []domain.FalsePositive{}, nil
+	}
+	return nil, domain.ErrMockError
+}
+
+func (m MockScanService) ListTriage(context.Context, string) ([]domain.Triage, error) {
+	if m.happy {
+		return []domain.Triage{}, nil
+	}
+	return nil, domain.ErrMockError
+}
+
+func (m MockScanService) MigrateSBOMs(context.Context) (domain.SBOMMigrationReport, error) {
+	if m.happy {
+		return domain.SBOMMigrationReport{}, nil
+	}
+	return domain.SBOMMigrationReport{}, domain.ErrMockError
+}
+
+func (m MockScanService) PlanScans(context.Context, []domain.ScanCommand) (domain.ScanPlan, error) {
+	if m.happy {
+		return domain.ScanPlan{}, nil
+	}
+	return domain.ScanPlan{}, domain.ErrMockError
+}
+
 func (m MockScanService) Ready(context.Context) bool {
 	return m.happy
 }
 
+func (m MockScanService) ScanBundle(context.Context, domain.SBOMBundle) (domain.ResultsBundle, error) {
+	if m.happy {
+		return domain.ResultsBundle{FormatVersion: domain.BundleFormatVersion}, nil
+	}
+	return domain.ResultsBundle{}, domain.ErrMockError
+}
+
 func (m MockScanService) ScanCVE(context.Context) error {
 	if m.happy {
 		return nil
@@ -35,6 +174,27 @@ func (m MockScanService) ScanCVE(context.Context) error {
 	return domain.ErrMockError
 }
 
+func (m MockScanService) ScanEvents(_ context.Context, scanID string) (domain.ScanEvents, error) {
+	if m.happy {
+		return domain.ScanEvents{ScanID: scanID, Events: []domain.ScanEvent{}}, nil
+	}
+	return domain.ScanEvents{}, domain.ErrMockError
+}
+
+func (m MockScanService) ScanFailures(context.Context, string) ([]domain.ScanFailure, error) {
+	if m.happy {
+		return []domain.ScanFailure{}, nil
+	}
+	return nil, domain.ErrMockError
+}
+
+func (m MockScanService) ScanLayerDiff(_ context.Context, request domain.LayerDiffRequest) (domain.LayerDiff, error) {
+	if m.happy {
+		return domain.LayerDiff{Base: request.Base, Image: request.Image}, nil
+	}
+	return domain.LayerDiff{}, domain.ErrMockError
+}
+
 func (m MockScanService) ScanRegistry(context.Context) error {
 	if m.happy {
 		return nil
@@ -42,6 +202,34 @@ func (m MockScanService) ScanRegistry(context.Context) error {
 	return domain.ErrMockError
 }
 
+func (m MockScanService) ScanRelayedSBOM(context.Context) error {
+	if m.happy {
+		return nil
+	}
+	return domain.ErrMockError
+}
+
+func (m MockScanService) ScanRepository(context.Context) error {
+	if m.happy {
+		return nil
+	}
+	return domain.ErrMockError
+}
+
+func (m MockScanService) SelfTest(context.Context) (domain.SelfTestReport, error) {
+	if m.happy {
+		return domain.SelfTestReport{Success: true}, nil
+	}
+	return domain.SelfTestReport{}, domain.ErrMockError
+}
+
+func (m MockScanService) SyncInventory(_ context.Context, inventory domain.Inventory) (domain.InventorySync, error) {
+	if m.happy {
+		return domain.InventorySync{Images: len(inventory.Images), Added: len(inventory.Images)}, nil
+	}
+	return domain.InventorySync{}, domain.ErrMockError
+}
+
 func (m MockScanService) ValidateGenerateSBOM(ctx context.Context, _ domain.ScanCommand) (context.Context, error) {
 	if m.happy {
 		return ctx, nil
@@ -62,3 +250,21 @@ func (m MockScanService) ValidateScanRegistry(ctx context.Context, _ domain.Scan
 	}
 	return ctx, domain.ErrMockError
 }
+
+func (m MockScanService) ValidateScanRelayedSBOM(ctx context.Context, _ domain.ScanCommand) (context.Context, error) {
+	if m.happy {
+		return ctx, nil
+	}
+	return ctx, domain.ErrMockError
+}
+
+func (m MockScanService) ValidateScanRepository(ctx context.Context, _ domain.ScanCommand) (context.Context, error) {
+	if m.happy {
+		return ctx, nil
+	}
+	return ctx, domain.ErrMockError
+}
+
+func (m MockScanService) Version(context.Context) domain.VersionInfo {
+	return domain.VersionInfo{Release: "mock"}
+}
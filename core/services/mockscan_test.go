@@ -187,3 +187,87 @@ func TestMockScanService_ValidateScanCVE(t *testing.T) {
 		})
 	}
 }
+
+func TestMockScanService_Coverage(t *testing.T) {
+	_, err := NewMockScanService(true).Coverage(context.TODO())
+	assert.NoError(t, err)
+	_, err = NewMockScanService(false).Coverage(context.TODO())
+	assert.ErrorIs(t, err, domain.ErrMockError)
+}
+
+func TestMockScanService_ScanFailures(t *testing.T) {
+	_, err := NewMockScanService(true).ScanFailures(context.TODO(), "")
+	assert.NoError(t, err)
+	_, err = NewMockScanService(false).ScanFailures(context.TODO(), "")
+	assert.ErrorIs(t, err, domain.ErrMockError)
+}
+
+func TestMockScanService_CompareScans(t *testing.T) {
+	_, err := NewMockScanService(true).CompareScans(context.TODO(), domain.DiffRequest{})
+	assert.NoError(t, err)
+	_, err = NewMockScanService(false).CompareScans(context.TODO(), domain.DiffRequest{})
+	assert.ErrorIs(t, err, domain.ErrMockError)
+}
+
+func TestMockScanService_CollectGarbage(t *testing.T) {
+	_, err := NewMockScanService(true).CollectGarbage(context.TODO())
+	assert.NoError(t, err)
+	_, err = NewMockScanService(false).CollectGarbage(context.TODO())
+	assert.ErrorIs(t, err, domain.ErrMockError)
+}
+
+func TestMockScanService_DeleteWorkload(t *testing.T) {
+	err := NewMockScanService(true).DeleteWorkload(context.TODO(), "")
+	assert.NoError(t, err)
+	err = NewMockScanService(false).DeleteWorkload(context.TODO(), "")
+	assert.ErrorIs(t, err, domain.ErrMockError)
+}
+
+func TestMockScanService_PlanScans(t *testing.T) {
+	_, err := NewMockScanService(true).PlanScans(context.TODO(), nil)
+	assert.NoError(t, err)
+	_, err = NewMockScanService(false).PlanScans(context.TODO(), nil)
+	assert.ErrorIs(t, err, domain.ErrMockError)
+}
+
+func TestMockScanService_ScanLayerDiff(t *testing.T) {
+	_, err := NewMockScanService(true).ScanLayerDiff(context.TODO(), domain.LayerDiffRequest{})
+	assert.NoError(t, err)
+	_, err = NewMockScanService(false).ScanLayerDiff(context.TODO(), domain.LayerDiffRequest{})
+	assert.ErrorIs(t, err, domain.ErrMockError)
+}
+
+func TestMockScanService_CheckSBOMCompatibility(t *testing.T) {
+	_, err := NewMockScanService(true).CheckSBOMCompatibility(context.TODO())
+	assert.NoError(t, err)
+	_, err = NewMockScanService(false).CheckSBOMCompatibility(context.TODO())
+	assert.ErrorIs(t, err, domain.ErrMockError)
+}
+
+func TestMockScanService_MigrateSBOMs(t *testing.T) {
+	_, err := NewMockScanService(true).MigrateSBOMs(context.TODO())
+	assert.NoError(t, err)
+	_, err = NewMockScanService(false).MigrateSBOMs(context.TODO())
+	assert.ErrorIs(t, err, domain.ErrMockError)
+}
+
+func TestMockScanService_Version(t *testing.T) {
+	assert.Equal(t, "mock", NewMockScanService(true).Version(context.TODO()).Release)
+}
+
+func TestMockScanService_ExportResults(t *testing.T) {
+	var rows []domain.ResultRow
+	assert.NoError(t, NewMockScanService(true).ExportResults(context.TODO(), "", func(row domain.ResultRow) error {
+		rows = append(rows, row)
+		return nil
+	}))
+	assert.Len(t, rows, 1)
+	assert.ErrorIs(t, NewMockScanService(false).ExportResults(context.TODO(), "", nil), domain.ErrMockError)
+}
+
+func TestMockScanService_HTMLReport(t *testing.T) {
+	_, err := NewMockScanService(true).HTMLReport(context.TODO(), "")
+	assert.NoError(t, err)
+	_, err = NewMockScanService(false).HTMLReport(context.TODO(), "")
+	assert.ErrorIs(t, err, domain.ErrMockError)
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"go.opentelemetry.io/otel"
+)
+
+// PlanScans deduplicates the images of scan commands and tells which of them need scanning, that is those
+// without a vulnerability manifest stored for the current scanner and DB versions; images whose SBOM is stored
+// are stale and only need matching against the current DB
+func (s *ScanService) PlanScans(ctx context.Context, commands []domain.ScanCommand) (domain.ScanPlan, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.PlanScans")
+	defer span.End()
+
+	plan := domain.ScanPlan{
+		DBVersion: s.cveScanner.DBVersion(ctx),
+		Commands:  len(commands),
+		Images:    []domain.PlannedImage{},
+	}
+	indices := map[string]int{}
+	for _, command := range commands {
+		if command.ImageSlug == "" || command.ImageHash == "" {
+			plan.Invalid++
+			continue
+		}
+		if i, ok := indices[command.ImageSlug]; ok {
+			plan.Images[i].Commands++
+			continue
+		}
+		indices[command.ImageSlug] = len(plan.Images)
+		image := domain.PlannedImage{
+			ImageSlug: command.ImageSlug,
+			ImageTag:  command.ImageTag,
+			ImageHash: command.ImageHash,
+			Commands:  1,
+			Status:    s.cacheStatus(ctx, command.ImageSlug, plan.DBVersion),
+		}
+		image.NeedsScan = image.Status != domain.PlanCached
+		if image.NeedsScan {
+			plan.ToScan++
+		}
+		plan.Images = append(plan.Images, image)
+	}
+	plan.Unique = len(plan.Images)
+	return plan, nil
+}
+
+// cacheStatus looks up the stored CVE manifest and SBOM of an image the same way ScanCVE does
+func (s *ScanService) cacheStatus(ctx context.Context, imageSlug, dbVersion string) string {
+	if !s.storage {
+		return domain.PlanNotCached
+	}
+	cve, err := s.cveRepository.GetCVE(ctx, imageSlug, s.sbomCreator.Version(), s.cveScanner.Version(ctx), dbVersion)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("error getting CVE", helpers.Error(err),
+			helpers.String("imageSlug", imageSlug))
+	}
+	if cve.Content != nil {
+		return domain.PlanCached
+	}
+	sbom, err := s.sbomRepository.GetSBOM(ctx, imageSlug, s.sbomCreator.Version())
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("error getting SBOM", helpers.Error(err),
+			helpers.String("imageSlug", imageSlug))
+	}
+	if sbom.Content != nil {
+		return domain.PlanStaleDB
+	}
+	return domain.PlanNotCached
+}
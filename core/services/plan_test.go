@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanService_PlanScans(t *testing.T) {
+	ctx := context.TODO()
+	storageSBOM := repositories.NewMemoryStorage(false, false)
+	storageCVE := repositories.NewMemoryStorage(false, false)
+	require.NoError(t, storageCVE.StoreCVE(ctx, domain.CVEManifest{
+		Name:               "cached",
+		SBOMCreatorVersion: "Mock SBOM 1.0",
+		CVEScannerVersion:  "Mock CVE 1.0",
+		CVEDBVersion:       "v1.0.0",
+		Content:            &v1beta1.GrypeDocument{},
+	}, false))
+	// scanned with a previous DB
+	require.NoError(t, storageSBOM.StoreSBOM(ctx, domain.SBOM{Name: "stale", SBOMCreatorVersion: "Mock SBOM 1.0", Content: &v1beta1.Document{}}))
+	require.NoError(t, storageCVE.StoreCVE(ctx, domain.CVEManifest{
+		Name:               "stale",
+		SBOMCreatorVersion: "Mock SBOM 1.0",
+		CVEScannerVersion:  "Mock CVE 1.0",
+		CVEDBVersion:       "v0.9.0",
+		Content:            &v1beta1.GrypeDocument{},
+	}, false))
+	commands := []domain.ScanCommand{
+		{Wlid: "wlid://cluster-minikube/namespace-default/deployment-a", ImageSlug: "cached", ImageTag: "nginx:1.14.1", ImageHash: "nginx@sha256:1"},
+		{Wlid: "wlid://cluster-minikube/namespace-default/deployment-b", ImageSlug: "stale", ImageTag: "redis:7", ImageHash: "redis@sha256:2"},
+		{Wlid: "wlid://cluster-minikube/namespace-default/deployment-c", ImageSlug: "new", ImageTag: "envoy:1.25", ImageHash: "envoy@sha256:3"},
+		{Wlid: "wlid://cluster-minikube/namespace-other/deployment-c", ImageSlug: "new", ImageTag: "envoy:1.25", ImageHash: "envoy@sha256:3"},
+		{Wlid: "wlid://cluster-minikube/namespace-default/deployment-d", ImageTag: "busybox"},
+	}
+	tests := []struct {
+		name    string
+		storage bool
+		want    domain.ScanPlan
+	}{
+		{
+			name:    "with storage",
+			storage: true,
+			want: domain.ScanPlan{
+				DBVersion: "v1.0.0",
+				Commands:  5,
+				Invalid:   1,
+				Unique:    3,
+				ToScan:    2,
+				Images: []domain.PlannedImage{
+					{ImageSlug: "cached", ImageTag: "nginx:1.14.1", ImageHash: "nginx@sha256:1", Commands: 1, Status: domain.PlanCached},
+					{ImageSlug: "stale", ImageTag: "redis:7", ImageHash: "redis@sha256:2", Commands: 1, NeedsScan: true, Status: domain.PlanStaleDB},
+					{ImageSlug: "new", ImageTag: "envoy:1.25", ImageHash: "envoy@sha256:3", Commands: 2, NeedsScan: true, Status: domain.PlanNotCached},
+				},
+			},
+		},
+		{
+			name: "without storage every image needs scanning",
+			want: domain.ScanPlan{
+				DBVersion: "v1.0.0",
+				Commands:  5,
+				Invalid:   1,
+				Unique:    3,
+				ToScan:    3,
+				Images: []domain.PlannedImage{
+					{ImageSlug: "cached", ImageTag: "nginx:1.14.1", ImageHash: "nginx@sha256:1", Commands: 1, NeedsScan: true, Status: domain.PlanNotCached},
+					{ImageSlug: "stale", ImageTag: "redis:7", ImageHash: "redis@sha256:2", Commands: 1, NeedsScan: true, Status: domain.PlanNotCached},
+					{ImageSlug: "new", ImageTag: "envoy:1.25", ImageHash: "envoy@sha256:3", Commands: 2, NeedsScan: true, Status: domain.PlanNotCached},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+				storageSBOM,
+				adapters.NewMockCVEAdapter(),
+				storageCVE,
+				adapters.NewMockPlatform(),
+				tt.storage)
+			plan, err := s.PlanScans(ctx, commands)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, plan)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// annotateChecks flags the results of the scan-time checks on the CVE manifest,
+// annotations are copied first since they can be shared with the stored manifests
+func (s *ScanService) annotateChecks(cve *domain.CVEManifest, previousDigest string) {
+	annotations := make(map[string]string, len(cve.Annotations))
+	for key, value := range cve.Annotations {
+		annotations[key] = value
+	}
+	delete(annotations, domain.AnnotationTagMutated)
+	delete(annotations, domain.AnnotationPreviousDigest)
+	delete(annotations, domain.AnnotationImageTooOld)
+	delete(annotations, domain.AnnotationGoldenBase)
+	delete(annotations, domain.AnnotationGoldenBaseVersion)
+	// flag silent image replacement
+	if previousDigest != "" {
+		annotations[domain.AnnotationTagMutated] = "true"
+		annotations[domain.AnnotationPreviousDigest] = previousDigest
+	}
+	// flag images older than the configured age
+	if s.isImageTooOld(annotations[domain.AnnotationImageCreated]) {
+		annotations[domain.AnnotationImageTooOld] = "true"
+	}
+	// flag the drift of the base image from the golden base catalog
+	if s.goldenBases != nil {
+		annotations[domain.AnnotationGoldenBase] = s.goldenBaseStatus(annotations)
+		annotations[domain.AnnotationGoldenBaseVersion] = s.goldenBasesVersion
+	}
+	cve.Annotations = annotations
+}
+
+// isImageTooOld checks the image creation timestamp against the maximum allowed image age
+func (s *ScanService) isImageTooOld(created string) bool {
+	if s.maxImageAge == 0 || created == "" {
+		return false
+	}
+	createdAt, err := time.Parse(time.RFC3339, created)
+	if err != nil {
+		return false
+	}
+	return time.Since(createdAt) > s.maxImageAge
+}
+
+// violations lists the policies violated according to the CVE manifest annotations
+func violations(cve domain.CVEManifest) []string {
+	var result []string
+	if cve.Annotations[domain.AnnotationImageTooOld] == "true" {
+		result = append(result, domain.PolicyImageTooOld)
+	}
+	if goldenBaseDrift(cve.Annotations[domain.AnnotationGoldenBase]) {
+		result = append(result, domain.PolicyGoldenBaseDrift)
+	}
+	return result
+}
+
+// imageLabels returns the OCI labels recorded in the CVE manifest annotations
+func imageLabels(cve domain.CVEManifest) map[string]string {
+	var labels map[string]string
+	for key, value := range cve.Annotations {
+		if strings.HasPrefix(key, domain.OCILabelPrefix) {
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// checkRegistry rejects the commands referencing an image outside of the allowed registries,
+// references which cannot be parsed are rejected as well since their registry is unknown
+func (s *ScanService) checkRegistry(workload domain.ScanCommand) error {
+	if len(s.allowedRegistries) == 0 {
+		return nil
+	}
+	for _, image := range []string{workload.ImageTag, workload.ImageHash} {
+		if image == "" {
+			continue
+		}
+		ref, err := name.ParseReference(image)
+		if err != nil {
+			return fmt.Errorf("%w: invalid image reference %q", domain.ErrRegistryDenied, image)
+		}
+		if !s.isRegistryAllowed(ref.Context().Name()) {
+			return fmt.Errorf("%w: %s", domain.ErrRegistryDenied, ref.Context().Name())
+		}
+	}
+	return nil
+}
+
+// isRegistryAllowed checks the fully qualified repository against the allowlist entries
+func (s *ScanService) isRegistryAllowed(repository string) bool {
+	for _, allowed := range s.allowedRegistries {
+		if repository == allowed || strings.HasPrefix(repository, allowed+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanService_annotateChecks(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339)
+	recent := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	tests := []struct {
+		name           string
+		maxImageAge    time.Duration
+		annotations    map[string]string
+		previousDigest string
+		want           map[string]string
+	}{
+		{
+			name:        "check disabled",
+			annotations: map[string]string{domain.AnnotationImageCreated: old},
+			want:        map[string]string{domain.AnnotationImageCreated: old},
+		},
+		{
+			name:        "old image",
+			maxImageAge: 24 * time.Hour,
+			annotations: map[string]string{domain.AnnotationImageCreated: old},
+			want:        map[string]string{domain.AnnotationImageCreated: old, domain.AnnotationImageTooOld: "true"},
+		},
+		{
+			name:        "recent image clears stale flags",
+			maxImageAge: 24 * time.Hour,
+			annotations: map[string]string{
+				domain.AnnotationImageCreated:   recent,
+				domain.AnnotationImageTooOld:    "true",
+				domain.AnnotationTagMutated:     "true",
+				domain.AnnotationPreviousDigest: "sha256:aaaa",
+			},
+			want: map[string]string{domain.AnnotationImageCreated: recent},
+		},
+		{
+			name:           "tag mutated",
+			annotations:    map[string]string{},
+			previousDigest: "sha256:aaaa",
+			want:           map[string]string{domain.AnnotationTagMutated: "true", domain.AnnotationPreviousDigest: "sha256:aaaa"},
+		},
+		{
+			name:        "invalid timestamp",
+			maxImageAge: 24 * time.Hour,
+			annotations: map[string]string{domain.AnnotationImageCreated: "yesterday"},
+			want:        map[string]string{domain.AnnotationImageCreated: "yesterday"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &ScanService{maxImageAge: tt.maxImageAge}
+			original := map[string]string{}
+			for k, v := range tt.annotations {
+				original[k] = v
+			}
+			cve := domain.CVEManifest{Annotations: tt.annotations}
+			s.annotateChecks(&cve, tt.previousDigest)
+			assert.Equal(t, tt.want, cve.Annotations)
+			assert.Equal(t, original, tt.annotations)
+		})
+	}
+}
+
+func Test_violations(t *testing.T) {
+	assert.Nil(t, violations(domain.CVEManifest{}))
+	assert.Equal(t, []string{domain.PolicyImageTooOld}, violations(domain.CVEManifest{
+		Annotations: map[string]string{domain.AnnotationImageTooOld: "true"},
+	}))
+}
+
+func Test_imageLabels(t *testing.T) {
+	assert.Nil(t, imageLabels(domain.CVEManifest{}))
+	assert.Equal(t, map[string]string{"org.opencontainers.image.source": "https://github.com/kubescape/kubevuln"}, imageLabels(domain.CVEManifest{
+		Annotations: map[string]string{
+			"org.opencontainers.image.source": "https://github.com/kubescape/kubevuln",
+			domain.AnnotationImageCreated:     "2023-01-01T00:00:00Z",
+		},
+	}))
+}
+
+func TestScanService_checkRegistry(t *testing.T) {
+	tests := []struct {
+		name              string
+		allowedRegistries []string
+		workload          domain.ScanCommand
+		wantErr           bool
+	}{
+		{
+			name:     "no allowlist",
+			workload: domain.ScanCommand{ImageTag: "evil.example.com/miner:latest"},
+		},
+		{
+			name:              "allowed registry",
+			allowedRegistries: []string{"quay.io", "ghcr.io"},
+			workload: domain.ScanCommand{
+				ImageTag:  "ghcr.io/kubescape/kubevuln:v0.2.0",
+				ImageHash: "ghcr.io/kubescape/kubevuln@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+			},
+		},
+		{
+			name:              "docker hub short names",
+			allowedRegistries: []string{"docker.io"},
+			workload:          domain.ScanCommand{ImageTag: "nginx:1.14.1"},
+		},
+		{
+			name:              "repository prefix",
+			allowedRegistries: []string{"ghcr.io/kubescape/"},
+			workload:          domain.ScanCommand{ImageTag: "ghcr.io/kubescape/kubevuln:v0.2.0"},
+		},
+		{
+			name:              "repository prefix is not a string prefix",
+			allowedRegistries: []string{"ghcr.io/kubescape"},
+			workload:          domain.ScanCommand{ImageTag: "ghcr.io/kubescape-fork/kubevuln:v0.2.0"},
+			wantErr:           true,
+		},
+		{
+			name:              "other registry",
+			allowedRegistries: []string{"ghcr.io"},
+			workload:          domain.ScanCommand{ImageTag: "evil.example.com/miner:latest"},
+			wantErr:           true,
+		},
+		{
+			name:              "digest from another registry",
+			allowedRegistries: []string{"ghcr.io"},
+			workload: domain.ScanCommand{
+				ImageTag:  "ghcr.io/kubescape/kubevuln:v0.2.0",
+				ImageHash: "evil.example.com/miner@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+			},
+			wantErr: true,
+		},
+		{
+			name:              "invalid reference",
+			allowedRegistries: []string{"ghcr.io"},
+			workload:          domain.ScanCommand{ImageTag: "ghcr.io/Kubescape/kubevuln:v0.2.0"},
+			wantErr:           true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &ScanService{}
+			WithAllowedRegistries(tt.allowedRegistries)(s)
+			err := s.checkRegistry(tt.workload)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, domain.ErrRegistryDenied)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+)
+
+// trackProgress carries in ctx the functions publishing the progress and recording the detailed events of the scan of
+// workload, if enabled
+func (s *ScanService) trackProgress(ctx context.Context, workload domain.ScanCommand) context.Context {
+	ctx = s.trackEvents(ctx, workload)
+	if s.progressBroker == nil {
+		return ctx
+	}
+	scanID, _ := ctx.Value(domain.ScanIDKey{}).(string)
+	return context.WithValue(ctx, domain.ProgressKey{}, func(progress domain.Progress) {
+		progress.ScanID = scanID
+		progress.Wlid = workload.Wlid
+		progress.ImageSlug = workload.ImageSlug
+		progress.Time = time.Now()
+		s.progressBroker.Publish(progress)
+	})
+}
+
+// finishProgress reports the end of a scan, with its error if it failed
+func finishProgress(ctx context.Context, err error) {
+	progress := domain.Progress{Stage: domain.StageDone}
+	if err != nil {
+		progress.Error = err.Error()
+		tools.RecordScanEvent(ctx, "scan failed", map[string]string{"error": progress.Error})
+	} else {
+		tools.RecordScanEvent(ctx, "scan done", nil)
+	}
+	tools.ReportProgress(ctx, progress)
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingBroker keeps the published events in a slice
+type recordingBroker struct {
+	events []domain.Progress
+}
+
+func (b *recordingBroker) Publish(progress domain.Progress) {
+	b.events = append(b.events, progress)
+}
+
+func (b *recordingBroker) Subscribe(context.Context) <-chan domain.Progress {
+	return nil
+}
+
+func TestScanService_trackProgress(t *testing.T) {
+	workload := domain.ScanCommand{ImageSlug: "imageSlug", Wlid: "wlid://cluster-minikube/namespace-default/deployment-nginx"}
+	ctx := context.WithValue(context.TODO(), domain.ScanIDKey{}, "scanID")
+	// without a broker the scan carries no reporter
+	s := &ScanService{}
+	assert.Equal(t, ctx, s.trackProgress(ctx, workload))
+
+	broker := &recordingBroker{}
+	WithProgressBroker(broker)(s)
+	ctx = s.trackProgress(ctx, workload)
+	tools.ReportProgress(ctx, domain.Progress{Stage: domain.StageMatch})
+	finishProgress(ctx, domain.ErrMockError)
+	require.Len(t, broker.events, 2)
+	for _, event := range broker.events {
+		assert.Equal(t, "scanID", event.ScanID)
+		assert.Equal(t, workload.Wlid, event.Wlid)
+		assert.Equal(t, workload.ImageSlug, event.ImageSlug)
+		assert.False(t, event.Time.IsZero())
+	}
+	assert.Equal(t, domain.StageMatch, broker.events[0].Stage)
+	assert.Equal(t, domain.StageDone, broker.events[1].Stage)
+	assert.Equal(t, domain.ErrMockError.Error(), broker.events[1].Error)
+}
+
+func TestScanService_ScanCVE_progress(t *testing.T) {
+	broker := &recordingBroker{}
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false,
+		WithProgressBroker(broker))
+	ctx := context.TODO()
+	s.Ready(ctx)
+	workload := domain.ScanCommand{
+		ImageSlug: "imageSlug",
+		ImageHash: "k8s.gcr.io/kube-proxy@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+		Wlid:      "wlid://cluster-minikube/namespace-kube-system/daemonset-kube-proxy",
+	}
+	ctx, err := s.ValidateScanCVE(ctx, workload)
+	require.NoError(t, err)
+	require.NoError(t, s.ScanCVE(ctx))
+	require.NotEmpty(t, broker.events)
+	done := broker.events[len(broker.events)-1]
+	assert.Equal(t, domain.StageDone, done.Stage)
+	assert.Equal(t, workload.Wlid, done.Wlid)
+	assert.Empty(t, done.Error)
+}
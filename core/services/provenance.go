@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// stageDurationsKey carries in the context of a scan the durations of the stages it ran
+type stageDurationsKey struct{}
+
+// stageDurations are the durations of the stages of a scan, recorded in the provenance of its CVE manifests
+type stageDurations struct {
+	mu        sync.Mutex
+	durations map[string]time.Duration
+}
+
+// WithScanProvenance records the node kubevuln runs on and its scan profile in the provenance of the CVE manifests
+func WithScanProvenance(nodeName, scanProfile string) ScanServiceOption {
+	return func(s *ScanService) {
+		s.nodeName = nodeName
+		s.scanProfile = scanProfile
+	}
+}
+
+// trackStages returns a context recording the durations of the stages of a scan
+func trackStages(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stageDurationsKey{}, &stageDurations{durations: map[string]time.Duration{}})
+}
+
+// recordStage records the duration of a stage started at start, the durations of a stage run several times add up
+func recordStage(ctx context.Context, stage string, start time.Time) {
+	stages, ok := ctx.Value(stageDurationsKey{}).(*stageDurations)
+	if !ok {
+		return
+	}
+	stages.mu.Lock()
+	defer stages.mu.Unlock()
+	stages.durations[stage] += time.Since(start)
+}
+
+// withScanProvenance records in the annotations of a CVE manifest the scanner which produced it, the stages recorded
+// in the context and the given matching duration
+func (s *ScanService) withScanProvenance(ctx context.Context, cve domain.CVEManifest, match time.Duration) domain.CVEManifest {
+	provenance := domain.ScanProvenance{
+		Release:            s.release,
+		SBOMCreatorVersion: s.sbomCreator.Version(),
+		CVEScannerVersion:  s.cveScanner.Version(ctx),
+		CVEDBVersion:       s.cveScanner.DBVersion(ctx),
+		ScanProfile:        s.scanProfile,
+		NodeName:           s.nodeName,
+		StageDurations:     map[string]int64{domain.StageMatch: match.Milliseconds()},
+		ScannedAt:          time.Now().UTC(),
+	}
+	if built := s.cveScanner.DBBuilt(ctx); !built.IsZero() {
+		provenance.CVEDBBuilt = &built
+	}
+	if stages, ok := ctx.Value(stageDurationsKey{}).(*stageDurations); ok {
+		stages.mu.Lock()
+		for stage, duration := range stages.durations {
+			provenance.StageDurations[stage] = duration.Milliseconds()
+		}
+		stages.mu.Unlock()
+	}
+	value, err := json.Marshal(provenance)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to encode scan provenance", helpers.Error(err))
+		return cve
+	}
+	annotations := make(map[string]string, len(cve.Annotations)+1)
+	for key, value := range cve.Annotations {
+		annotations[key] = value
+	}
+	annotations[domain.AnnotationScanProvenance] = string(value)
+	cve.Annotations = annotations
+	return cve
+}
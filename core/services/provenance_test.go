@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanService_withScanProvenance(t *testing.T) {
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false,
+		WithRelease("v0.2.0"),
+		WithScanProvenance("node-1", "fast"))
+	ctx := trackStages(context.TODO())
+	recordStage(ctx, domain.StageSBOM, time.Now().Add(-2*time.Second))
+	annotations := map[string]string{"key": "value"}
+	cve := s.withScanProvenance(ctx, domain.CVEManifest{Annotations: annotations}, 500*time.Millisecond)
+	assert.Equal(t, "value", cve.Annotations["key"])
+	// the original annotations are left untouched
+	assert.Len(t, annotations, 1)
+
+	var provenance domain.ScanProvenance
+	require.NoError(t, json.Unmarshal([]byte(cve.Annotations[domain.AnnotationScanProvenance]), &provenance))
+	assert.Equal(t, "v0.2.0", provenance.Release)
+	assert.Equal(t, "Mock SBOM 1.0", provenance.SBOMCreatorVersion)
+	assert.Equal(t, "Mock CVE 1.0", provenance.CVEScannerVersion)
+	assert.Equal(t, "v1.0.0", provenance.CVEDBVersion)
+	require.NotNil(t, provenance.CVEDBBuilt)
+	assert.Equal(t, time.Date(2023, 3, 24, 6, 54, 57, 0, time.UTC), provenance.CVEDBBuilt.UTC())
+	assert.Equal(t, "fast", provenance.ScanProfile)
+	assert.Equal(t, "node-1", provenance.NodeName)
+	assert.Equal(t, int64(500), provenance.StageDurations[domain.StageMatch])
+	assert.GreaterOrEqual(t, provenance.StageDurations[domain.StageSBOM], int64(2000))
+	assert.WithinDuration(t, time.Now(), provenance.ScannedAt, time.Minute)
+}
+
+func TestScanService_ScanCVE_provenance(t *testing.T) {
+	storage := repositories.NewMemoryStorage(false, false)
+	sbomAdapter := adapters.NewMockSBOMAdapter(false, false, false)
+	cveAdapter := adapters.NewMockCVEAdapter()
+	s := NewScanService(sbomAdapter,
+		storage,
+		cveAdapter,
+		storage,
+		adapters.NewMockPlatform(),
+		true,
+		WithScanProvenance("node-1", "full"))
+	ctx, err := s.ValidateScanCVE(context.TODO(), domain.ScanCommand{
+		ImageSlug: "imageSlug",
+		ImageHash: "k8s.gcr.io/kube-proxy@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+		Wlid:      "wlid://cluster-minikube/namespace-kube-system/daemonset-kube-proxy",
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.ScanCVE(ctx))
+
+	// the provenance is stored with the cached results
+	cve, err := storage.GetCVE(ctx, "imageSlug", sbomAdapter.Version(), cveAdapter.Version(ctx), cveAdapter.DBVersion(ctx))
+	require.NoError(t, err)
+	var provenance domain.ScanProvenance
+	require.NoError(t, json.Unmarshal([]byte(cve.Annotations[domain.AnnotationScanProvenance]), &provenance))
+	assert.Equal(t, "node-1", provenance.NodeName)
+	assert.Equal(t, "full", provenance.ScanProfile)
+	assert.Contains(t, provenance.StageDurations, domain.StageSBOM)
+	assert.Contains(t, provenance.StageDurations, domain.StageMatch)
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+
+	wlidpkg "github.com/armosec/utils-k8s-go/wlid"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithRelay enables the relay mode, matching and submitting the SBOMs created by the kubevuln instances of edge
+// clusters
+func WithRelay() ScanServiceOption {
+	return func(s *ScanService) {
+		s.relay = true
+	}
+}
+
+// ScanRelayedSBOM implements the "Scan relayed SBOM flow": the SBOM created in an edge cluster is matched here, and
+// its vulnerabilities are submitted on behalf of the workload of the edge cluster
+func (s *ScanService) ScanRelayedSBOM(ctx context.Context) (err error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.ScanRelayedSBOM")
+	defer span.End()
+
+	ctx = addTimestamp(ctx)
+	ctx = trackStages(ctx)
+
+	// retrieve workload from context
+	workload, ok := ctx.Value(domain.WorkloadKey{}).(domain.ScanCommand)
+	if !ok {
+		return domain.ErrCastingWorkload
+	}
+	if workload.RelayedSBOM == nil {
+		return domain.ErrNoRelayedSBOM
+	}
+	logger.L().Info("relayed SBOM scan started",
+		helpers.String("cluster", workload.RelayCluster),
+		helpers.String("imageSlug", workload.ImageSlug),
+		helpers.String("jobID", workload.JobID))
+
+	// notify the caller and report the progress until the scan is over
+	ctx = s.trackProgress(ctx, workload)
+	cve := domain.CVEManifest{}
+	defer func() {
+		err = s.reportFailure(ctx, workload, "", err)
+		s.notify(ctx, workload, cve, err)
+		s.annotateWorkload(ctx, workload, cve, err)
+		finishProgress(ctx, err)
+	}()
+	// a panic fails the scan instead of crashing the pod
+	defer tools.RecoverPanic(ctx, &err)
+
+	// report to platform
+	err = s.platform.SendStatus(ctx, domain.Started)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("telemetry error", helpers.Error(err),
+			helpers.String("imageSlug", workload.ImageSlug))
+	}
+
+	// do not process timed out SBOM
+	sbom := *workload.RelayedSBOM
+	sbom.Name = workload.ImageSlug
+	if sbom.Status == instanceidhandler.Incomplete {
+		return domain.ErrIncompleteSBOM
+	}
+
+	// scan for CVE
+	cve, err = s.scanSBOM(ctx, sbom)
+	if err != nil {
+		return err
+	}
+	cve.Wlid = workload.Wlid
+	cve = withRelayedFrom(cve, workload.RelayCluster)
+	// score the risk of the image, the relevancy data stays in the edge cluster
+	cve = s.withExploits(ctx, cve)
+	s.annotateRisk(ctx, workload, &cve, domain.CVEManifest{})
+
+	// report scan success to platform
+	err = s.platform.SendStatus(ctx, domain.Success)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("telemetry error", helpers.Error(err),
+			helpers.String("imageSlug", workload.ImageSlug))
+	}
+	// submit CVE manifest to platform
+	err = s.submitCVE(ctx, cve, domain.CVEManifest{})
+	if err != nil {
+		return err
+	}
+	// report submit success to platform
+	err = s.platform.SendStatus(ctx, domain.Done)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("telemetry error", helpers.Error(err),
+			helpers.String("imageSlug", workload.ImageSlug))
+	}
+
+	s.recordScan(workload.ImageHash)
+	s.recordResults(ctx, workload, cve)
+	logger.L().Info("relayed SBOM scan complete",
+		helpers.String("cluster", workload.RelayCluster),
+		helpers.String("imageSlug", workload.ImageSlug),
+		helpers.String("jobID", workload.JobID))
+	return nil
+}
+
+// ValidateScanRelayedSBOM checks that the relay mode is enabled and that the relayed SBOM belongs to a workload of
+// the edge cluster it was relayed by
+func (s *ScanService) ValidateScanRelayedSBOM(ctx context.Context, workload domain.ScanCommand) (context.Context, error) {
+	_, span := otel.Tracer("").Start(ctx, "ScanService.ValidateScanRelayedSBOM")
+	defer span.End()
+
+	ctx = enrichContext(ctx, workload)
+	if !s.relay {
+		return ctx, domain.ErrNoRelay
+	}
+	// validate inputs
+	if workload.ImageSlug == "" || workload.Wlid == "" {
+		return ctx, domain.ErrMissingImageInfo
+	}
+	if workload.RelayedSBOM == nil || workload.RelayedSBOM.Content == nil {
+		return ctx, domain.ErrNoRelayedSBOM
+	}
+	if workload.RelayCluster == "" || wlidpkg.GetClusterFromWlid(workload.Wlid) != workload.RelayCluster {
+		return ctx, domain.ErrClusterDenied
+	}
+	// add cluster and imageSlug to parent span
+	if parentSpan := trace.SpanFromContext(ctx); parentSpan != nil {
+		parentSpan.SetAttributes(attribute.String("cluster", workload.RelayCluster))
+		parentSpan.SetAttributes(attribute.String("imageSlug", workload.ImageSlug))
+		parentSpan.SetAttributes(attribute.String("version", s.release))
+		parentSpan.SetAttributes(attribute.String("wlid", workload.Wlid))
+		ctx = trace.ContextWithSpan(ctx, parentSpan)
+	}
+	return ctx, nil
+}
+
+// withRelayedFrom records in the annotations of a CVE manifest the edge cluster its SBOM was relayed by
+func withRelayedFrom(cve domain.CVEManifest, cluster string) domain.CVEManifest {
+	annotations := make(map[string]string, len(cve.Annotations)+1)
+	for key, value := range cve.Annotations {
+		annotations[key] = value
+	}
+	annotations[domain.AnnotationRelayedFrom] = cluster
+	cve.Annotations = annotations
+	return cve
+}
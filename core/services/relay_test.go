@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const relayedWlid = "wlid://cluster-edge-1/namespace-default/deployment-nginx"
+
+func TestScanService_ScanRelayedSBOM(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		workload bool
+		wantErr  error
+	}{
+		{
+			name:    "no workload",
+			wantErr: domain.ErrCastingWorkload,
+		},
+		{
+			name:     "incomplete SBOM",
+			status:   instanceidhandler.Incomplete,
+			workload: true,
+			wantErr:  domain.ErrIncompleteSBOM,
+		},
+		{
+			name:     "scan",
+			workload: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := repositories.NewMemoryStorage(false, false)
+			s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+				storage,
+				adapters.NewMockCVEAdapter(),
+				storage,
+				adapters.NewMockPlatform(),
+				false,
+				WithRelay())
+			ctx := context.TODO()
+			if tt.workload {
+				var err error
+				ctx, err = s.ValidateScanRelayedSBOM(ctx, domain.ScanCommand{
+					ImageSlug:    "nginx-1-14-1-73e957",
+					Wlid:         relayedWlid,
+					RelayCluster: "edge-1",
+					RelayedSBOM: &domain.SBOM{
+						Status:  tt.status,
+						Content: &v1beta1.Document{},
+					},
+				})
+				require.NoError(t, err)
+			}
+			err := s.ScanRelayedSBOM(ctx)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestScanService_ValidateScanRelayedSBOM(t *testing.T) {
+	sbom := &domain.SBOM{Content: &v1beta1.Document{}}
+	tests := []struct {
+		name     string
+		disabled bool
+		workload domain.ScanCommand
+		wantErr  error
+	}{
+		{
+			name:     "disabled",
+			disabled: true,
+			workload: domain.ScanCommand{ImageSlug: "slug", Wlid: relayedWlid, RelayCluster: "edge-1", RelayedSBOM: sbom},
+			wantErr:  domain.ErrNoRelay,
+		},
+		{
+			name:     "missing wlid",
+			workload: domain.ScanCommand{ImageSlug: "slug", RelayCluster: "edge-1", RelayedSBOM: sbom},
+			wantErr:  domain.ErrMissingImageInfo,
+		},
+		{
+			name:     "missing SBOM",
+			workload: domain.ScanCommand{ImageSlug: "slug", Wlid: relayedWlid, RelayCluster: "edge-1", RelayedSBOM: &domain.SBOM{}},
+			wantErr:  domain.ErrNoRelayedSBOM,
+		},
+		{
+			name:     "workload of another cluster",
+			workload: domain.ScanCommand{ImageSlug: "slug", Wlid: relayedWlid, RelayCluster: "edge-2", RelayedSBOM: sbom},
+			wantErr:  domain.ErrClusterDenied,
+		},
+		{
+			name:     "workload of the relay cluster",
+			workload: domain.ScanCommand{ImageSlug: "slug", Wlid: relayedWlid, RelayCluster: "edge-1", RelayedSBOM: sbom},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []ScanServiceOption
+			if !tt.disabled {
+				opts = append(opts, WithRelay())
+			}
+			s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockCVEAdapter(),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockPlatform(),
+				false,
+				opts...)
+			_, err := s.ValidateScanRelayedSBOM(context.TODO(), tt.workload)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func Test_withRelayedFrom(t *testing.T) {
+	annotations := map[string]string{domain.AnnotationScanProvenance: "{}"}
+	cve := withRelayedFrom(domain.CVEManifest{Annotations: annotations}, "edge-1")
+	assert.Equal(t, map[string]string{domain.AnnotationScanProvenance: "{}", domain.AnnotationRelayedFrom: "edge-1"}, cve.Annotations)
+	// the annotations of the original manifest are left untouched
+	assert.Len(t, annotations, 1)
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"context"
+
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// relevantSBOM retrieves the SBOM' of a workload instance from the relevancy provider, or from the storage by default
+func (s *ScanService) relevantSBOM(ctx context.Context, workload domain.ScanCommand, sbom domain.SBOM) (domain.SBOM, error) {
+	if s.relevancyProvider != nil {
+		return s.relevancyProvider.GetRelevantSBOM(ctx, workload, sbom)
+	}
+	if s.storage {
+		return s.sbomRepository.GetSBOMp(ctx, workload.InstanceID, s.sbomCreator.Version())
+	}
+	return domain.SBOM{}, nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticRelevancy returns its SBOM for every instance and records the image SBOM it was given
+type staticRelevancy struct {
+	err       error
+	imageSBOM domain.SBOM
+	sbomp     domain.SBOM
+}
+
+func (r *staticRelevancy) GetRelevantSBOM(_ context.Context, _ domain.ScanCommand, sbom domain.SBOM) (domain.SBOM, error) {
+	r.imageSBOM = sbom
+	return r.sbomp, r.err
+}
+
+func TestScanService_ScanCVE_relevancyProvider(t *testing.T) {
+	imageHash := "k8s.gcr.io/kube-proxy@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137"
+	instanceID := "ee9bdd0adec9ce004572faf3492f583aa82042a8b3a9d5c7d9179dc03c531eef"
+	sbomAdapter := adapters.NewMockSBOMAdapter(false, false, false)
+	sbomp, err := sbomAdapter.CreateSBOM(context.TODO(), instanceID, instanceID, domain.RegistryOptions{})
+	require.NoError(t, err)
+	tests := []struct {
+		name     string
+		provider *staticRelevancy
+		wantCVEp bool
+	}{
+		{
+			name:     "relevant SBOM",
+			provider: &staticRelevancy{sbomp: sbomp},
+			wantCVEp: true,
+		},
+		{
+			name:     "no relevancy",
+			provider: &staticRelevancy{},
+		},
+		{
+			name:     "provider error",
+			provider: &staticRelevancy{err: domain.ErrMockError},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cveAdapter := adapters.NewMockCVEAdapter()
+			storageCVE := repositories.NewMemoryStorage(false, false)
+			s := NewScanService(sbomAdapter,
+				repositories.NewMemoryStorage(false, false),
+				cveAdapter,
+				storageCVE,
+				adapters.NewMockPlatform(),
+				true,
+				WithRelevancyProvider(tt.provider))
+			ctx := context.TODO()
+			s.Ready(ctx)
+			ctx, err := s.ValidateScanCVE(ctx, domain.ScanCommand{
+				ImageSlug:  "imageSlug",
+				ImageHash:  imageHash,
+				InstanceID: instanceID,
+				Wlid:       "wlid://cluster-minikube/namespace-kube-system/daemonset-kube-proxy",
+			})
+			require.NoError(t, err)
+			require.NoError(t, s.ScanCVE(ctx))
+			// the provider is given the image SBOM created for the scan
+			assert.NotNil(t, tt.provider.imageSBOM.Content)
+			cvep, err := storageCVE.GetCVE(ctx, instanceID, sbomAdapter.Version(), cveAdapter.Version(ctx), cveAdapter.DBVersion(ctx))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCVEp, cvep.Content != nil)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"go.opentelemetry.io/otel"
+)
+
+// maxRecommendations caps the fix recommendations of a report, the most severe first
+const maxRecommendations = 20
+
+//go:embed report.html.tmpl
+var reportTemplateText string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"percent": func(count, total int) int {
+		if total == 0 {
+			return 0
+		}
+		return count * 100 / total
+	},
+	"time": func(t time.Time) string {
+		return t.UTC().Format(time.RFC3339)
+	},
+}).Parse(reportTemplateText))
+
+// severityCounter counts the findings by severity
+type severityCounter map[string]int
+
+func (c severityCounter) counts() []domain.SeverityCount {
+	counts := make([]domain.SeverityCount, 0, len(domain.Severities))
+	for _, severity := range domain.Severities {
+		counts = append(counts, domain.SeverityCount{Severity: severity, Count: c[severity]})
+	}
+	return counts
+}
+
+// recommendations gathers the fixable findings by package
+type recommendations map[string]*recommendation
+
+type recommendation struct {
+	domain.FixRecommendation
+	vulnerabilities map[string]bool
+	images          map[string]bool
+}
+
+func (r recommendations) add(image string, row domain.ResultRow) {
+	key := row.Package + "@" + row.Version
+	rec, ok := r[key]
+	if !ok {
+		rec = &recommendation{
+			FixRecommendation: domain.FixRecommendation{Package: row.Package, Version: row.Version, Severity: row.Severity},
+			vulnerabilities:   map[string]bool{},
+			images:            map[string]bool{},
+		}
+		r[key] = rec
+	}
+	for _, version := range row.FixVersions {
+		if !containsString(rec.FixVersions, version) {
+			rec.FixVersions = append(rec.FixVersions, version)
+		}
+	}
+	if domain.SeverityRank(row.Severity) < domain.SeverityRank(rec.Severity) {
+		rec.Severity = row.Severity
+	}
+	rec.vulnerabilities[row.Vulnerability] = true
+	rec.images[image] = true
+}
+
+// list returns the recommendations, the most severe and the most fixing first
+func (r recommendations) list() []domain.FixRecommendation {
+	list := make([]domain.FixRecommendation, 0, len(r))
+	for _, rec := range r {
+		rec.Vulnerabilities, rec.Images = len(rec.vulnerabilities), len(rec.images)
+		list = append(list, rec.FixRecommendation)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if a, b := domain.SeverityRank(list[i].Severity), domain.SeverityRank(list[j].Severity); a != b {
+			return a < b
+		}
+		if list[i].Vulnerabilities != list[j].Vulnerabilities {
+			return list[i].Vulnerabilities > list[j].Vulnerabilities
+		}
+		return list[i].Package+"@"+list[i].Version < list[j].Package+"@"+list[j].Version
+	})
+	if len(list) > maxRecommendations {
+		list = list[:maxRecommendations]
+	}
+	return list
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// buildReport summarizes the latest scans of a workload, or of a registry image by its normalized tag, with their
+// findings, or of every workload and registry image without scope, reading the vulnerability manifests one at a time
+func (s *ScanService) buildReport(ctx context.Context, scope string) (domain.VulnerabilityReport, error) {
+	records, err := s.latestScans(scope)
+	if err != nil {
+		return domain.VulnerabilityReport{}, err
+	}
+	report := domain.VulnerabilityReport{
+		Scope:        scope,
+		GeneratedAt:  time.Now().UTC(),
+		CVEDBVersion: s.cveScanner.DBVersion(ctx),
+		Images:       make([]domain.ImageReport, 0, len(records)),
+	}
+	total := severityCounter{}
+	fixes := recommendations{}
+	for _, record := range records {
+		image := domain.ImageReport{
+			Wlid:      record.Wlid,
+			Container: record.ContainerName,
+			Image:     record.ImageTag,
+			ImageHash: record.ImageHash,
+			ScanID:    record.ScanID,
+			ScannedAt: record.Timestamp,
+		}
+		counter := severityCounter{}
+		err := s.exportRecord(ctx, record, func(row domain.ResultRow) error {
+			counter[domain.Severities[domain.SeverityRank(row.Severity)]]++
+			image.Total++
+			if row.FixState == domain.FixStateFixed || len(row.FixVersions) > 0 {
+				image.Fixable++
+				fixes.add(record.Wlid+"/"+record.ContainerName+"/"+record.ImageTag, row)
+			}
+			if scope != "" {
+				image.Findings = append(image.Findings, row)
+			}
+			return nil
+		})
+		if err != nil {
+			return domain.VulnerabilityReport{}, err
+		}
+		sort.SliceStable(image.Findings, func(i, j int) bool {
+			return domain.SeverityRank(image.Findings[i].Severity) < domain.SeverityRank(image.Findings[j].Severity)
+		})
+		image.Severities = counter.counts()
+		for severity, count := range counter {
+			total[severity] += count
+		}
+		report.Total += image.Total
+		report.Fixable += image.Fixable
+		report.Images = append(report.Images, image)
+	}
+	report.Severities = total.counts()
+	report.Recommendations = fixes.list()
+	return report, nil
+}
+
+// HTMLReport renders the report of a workload, a registry image or the cluster as a self-contained HTML page
+func (s *ScanService) HTMLReport(ctx context.Context, scope string) ([]byte, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.HTMLReport")
+	defer span.End()
+
+	report, err := s.buildReport(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, report); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
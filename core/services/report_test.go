@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanService_HTMLReport(t *testing.T) {
+	storage := repositories.NewMemoryStorage(false, false)
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		storage,
+		adapters.NewMockPlatform(),
+		true)
+	nginx := "wlid://cluster-minikube/namespace-default/deployment-nginx"
+	day := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	openssl := domain.Finding{ID: "CVE-2023-0286", Package: "openssl", Version: "3.0.7", Severity: domain.HighSeverity}
+	opensslCritical := domain.Finding{ID: "CVE-2023-0215", Package: "openssl", Version: "3.0.7", Severity: domain.CriticalSeverity}
+	script := domain.Finding{ID: "CVE-2023-0001", Package: "<script>alert(1)</script>", Version: "1.0", Severity: "Bogus"}
+
+	workload := domain.ScanCommand{Wlid: nginx, ContainerName: "nginx", ImageTag: "nginx:1.24", ImageSlug: "nginx-slug"}
+	s.recordResults(scanContext("scan1", day), workload, manifestWithFindings(openssl))
+	registry := domain.ScanCommand{ImageTagNormalized: "docker.io/library/alpine:3.18", ImageTag: "alpine:3.18"}
+	s.recordResults(scanContext("scan2", day), registry, manifestWithFindings(script))
+
+	// the fixes come from the manifest in the storage
+	manifest := manifestWithFindings(openssl, opensslCritical)
+	for i := range manifest.Content.Matches {
+		manifest.Content.Matches[i].Vulnerability.Fix.State = domain.FixStateFixed
+		manifest.Content.Matches[i].Vulnerability.Fix.Versions = []string{"3.0.8"}
+	}
+	manifest.Name = "nginx-slug"
+	manifest.SBOMCreatorVersion = s.sbomCreator.Version()
+	manifest.CVEScannerVersion = s.cveScanner.Version(context.TODO())
+	manifest.CVEDBVersion = s.cveScanner.DBVersion(context.TODO())
+	require.NoError(t, storage.StoreCVE(context.TODO(), manifest, false))
+
+	report, err := s.buildReport(context.TODO(), "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, 2, report.Fixable)
+	assert.Equal(t, []domain.SeverityCount{
+		{Severity: domain.CriticalSeverity, Count: 1},
+		{Severity: domain.HighSeverity, Count: 1},
+		{Severity: domain.MediumSeverity},
+		{Severity: domain.LowSeverity},
+		{Severity: domain.NegligibleSeverity},
+		{Severity: domain.UnknownSeverity, Count: 1},
+	}, report.Severities)
+	require.Len(t, report.Images, 2)
+	assert.Empty(t, report.Images[1].Findings)
+	assert.Equal(t, []domain.FixRecommendation{{Package: "openssl", Version: "3.0.7", FixVersions: []string{"3.0.8"},
+		Severity: domain.CriticalSeverity, Vulnerabilities: 2, Images: 1}}, report.Recommendations)
+
+	// the findings of a workload are listed, the most severe first
+	report, err = s.buildReport(context.TODO(), nginx)
+	require.NoError(t, err)
+	require.Len(t, report.Images, 1)
+	require.Len(t, report.Images[0].Findings, 2)
+	assert.Equal(t, opensslCritical.ID, report.Images[0].Findings[0].Vulnerability)
+
+	page, err := s.HTMLReport(context.TODO(), "")
+	require.NoError(t, err)
+	assert.Contains(t, string(page), "<!DOCTYPE html>")
+	assert.Contains(t, string(page), "Cluster summary")
+	assert.Contains(t, string(page), "3.0.8")
+
+	page, err = s.HTMLReport(context.TODO(), "docker.io/library/alpine:3.18")
+	require.NoError(t, err)
+	assert.NotContains(t, string(page), "<script>")
+	assert.Contains(t, string(page), "&lt;script&gt;")
+
+	_, err = s.HTMLReport(context.TODO(), "wlid://cluster-minikube/namespace-default/deployment-redis")
+	assert.ErrorIs(t, err, domain.ErrScanNotFound)
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tokenUsername is the user of the clones authenticated by a token, accepted by the git hosting services
+const tokenUsername = "x-access-token"
+
+// WithRepositoryScans enables the scans of git repositories, cataloged by creator
+func WithRepositoryScans(creator ports.RepositorySBOMCreator) ScanServiceOption {
+	return func(s *ScanService) {
+		s.repositoryCreator = creator
+	}
+}
+
+// ScanRepository implements the "Scan repository flow": the manifests and lockfiles of a git repository are
+// cataloged at the requested ref, and its vulnerabilities are submitted like those of a registry scan
+func (s *ScanService) ScanRepository(ctx context.Context) (err error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.ScanRepository")
+	defer span.End()
+
+	ctx = addTimestamp(ctx)
+	ctx = trackStages(ctx)
+
+	// retrieve workload from context
+	workload, ok := ctx.Value(domain.WorkloadKey{}).(domain.ScanCommand)
+	if !ok {
+		return domain.ErrCastingWorkload
+	}
+	logger.L().Info("repository scan started",
+		helpers.String("repository", workload.RepositoryURL),
+		helpers.String("ref", workload.RepositoryRef),
+		helpers.String("jobID", workload.JobID))
+
+	// notify the caller and report the progress until the scan is over
+	ctx = s.trackProgress(ctx, workload)
+	cve := domain.CVEManifest{}
+	defer func() {
+		err = s.reportFailure(ctx, workload, "", err)
+		s.notify(ctx, workload, cve, err)
+		s.annotateWorkload(ctx, workload, cve, err)
+		finishProgress(ctx, err)
+	}()
+	// a panic fails the scan instead of crashing the pod
+	defer tools.RecoverPanic(ctx, &err)
+
+	// report to platform
+	err = s.platform.SendStatus(ctx, domain.Started)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("telemetry error", helpers.Error(err),
+			helpers.String("repository", workload.RepositoryURL))
+	}
+
+	// create SBOM
+	start := time.Now()
+	sbom, err := s.repositoryCreator.CreateRepositorySBOM(ctx, workload.ImageSlug, repositoryFromWorkload(workload))
+	recordStage(ctx, domain.StageSBOM, start)
+	if err != nil {
+		return err
+	}
+	// do not process timed out SBOM
+	if sbom.Status == instanceidhandler.Incomplete {
+		return domain.ErrIncompleteSBOM
+	}
+
+	// scan for CVE
+	cve, err = s.scanSBOM(ctx, sbom)
+	if err != nil {
+		return err
+	}
+	// score the risk of the repository, which has neither relevancy data nor workload
+	cve = s.withExploits(ctx, cve)
+	s.annotateRisk(ctx, workload, &cve, domain.CVEManifest{})
+
+	// report scan success to platform
+	err = s.platform.SendStatus(ctx, domain.Success)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("telemetry error", helpers.Error(err),
+			helpers.String("repository", workload.RepositoryURL))
+	}
+	// submit CVE manifest to platform
+	err = s.submitCVE(ctx, cve, domain.CVEManifest{})
+	if err != nil {
+		return err
+	}
+	// report submit success to platform
+	err = s.platform.SendStatus(ctx, domain.Done)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("telemetry error", helpers.Error(err),
+			helpers.String("repository", workload.RepositoryURL))
+	}
+
+	s.recordResults(ctx, workload, cve)
+	logger.L().Info("repository scan complete",
+		helpers.String("repository", workload.RepositoryURL),
+		helpers.String("ref", workload.RepositoryRef),
+		helpers.String("jobID", workload.JobID))
+	return nil
+}
+
+// ValidateScanRepository checks that repository scans are enabled and that the repository is an https URL, without
+// credentials which belong to the credentials list
+func (s *ScanService) ValidateScanRepository(ctx context.Context, workload domain.ScanCommand) (context.Context, error) {
+	_, span := otel.Tracer("").Start(ctx, "ScanService.ValidateScanRepository")
+	defer span.End()
+
+	ctx = enrichContext(ctx, workload)
+	if s.repositoryCreator == nil {
+		return ctx, domain.ErrNoRepositoryScan
+	}
+	// validate inputs
+	if workload.RepositoryURL == "" || workload.ImageSlug == "" {
+		return ctx, domain.ErrMissingImageInfo
+	}
+	u, err := url.Parse(workload.RepositoryURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil {
+		return ctx, domain.ErrInvalidRepository
+	}
+	// add repository to parent span
+	if parentSpan := trace.SpanFromContext(ctx); parentSpan != nil {
+		parentSpan.SetAttributes(attribute.String("repository", workload.RepositoryURL))
+		parentSpan.SetAttributes(attribute.String("version", s.release))
+		ctx = trace.ContextWithSpan(ctx, parentSpan)
+	}
+	return ctx, nil
+}
+
+// repositoryFromWorkload returns the repository of a scan command, authenticated by the first credentials either
+// without server address or for the host of the repository, a token being sent as password
+func repositoryFromWorkload(workload domain.ScanCommand) domain.Repository {
+	repository := domain.Repository{
+		URL: workload.RepositoryURL,
+		Ref: workload.RepositoryRef,
+	}
+	var host string
+	if u, err := url.Parse(workload.RepositoryURL); err == nil {
+		host = u.Host
+	}
+	for _, cred := range workload.Credentialslist {
+		if cred.ServerAddress != "" && cred.ServerAddress != host {
+			continue
+		}
+		switch {
+		case cred.Username != "" && cred.Password != "":
+			repository.Username, repository.Password = cred.Username, cred.Password
+		case cred.RegistryToken != "":
+			repository.Username, repository.Password = tokenUsername, cred.RegistryToken
+		default:
+			continue
+		}
+		break
+	}
+	return repository
+}
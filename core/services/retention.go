@@ -0,0 +1,105 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// historyPruneInterval is the minimum interval between two pruning of the whole scan history, the history of a
+// workload is pruned each time it is scanned
+const historyPruneInterval = time.Hour
+
+// WithHistoryRetention keeps the findings of the past scans for the retention of their severity instead of keeping
+// the last scans of each workload, severities without retention are kept as long as the longest one
+func WithHistoryRetention(retention map[string]time.Duration) ScanServiceOption {
+	return func(s *ScanService) {
+		s.historyRetention = retention
+	}
+}
+
+// retentionOf returns the retention of the findings of a severity, in any case
+func (s *ScanService) retentionOf(severity string) time.Duration {
+	for known, retention := range s.historyRetention {
+		if strings.EqualFold(known, severity) {
+			return retention
+		}
+	}
+	return s.longestRetention()
+}
+
+// longestRetention returns the retention of the scans, which are forgotten once none of their findings is retained
+func (s *ScanService) longestRetention() time.Duration {
+	var longest time.Duration
+	for _, retention := range s.historyRetention {
+		if retention > longest {
+			longest = retention
+		}
+	}
+	return longest
+}
+
+// pruneHistory forgets the findings of the past scans of a workload (or registry image) past their retention, and
+// the scans past the longest retention or left without findings; the latest scan is kept whole to be compared with
+// the next one; historyMu must be held
+func (s *ScanService) pruneHistory(key string, now time.Time) (prunedScans, prunedFindings int) {
+	history := s.scanHistory[key]
+	if len(history) == 0 {
+		return 0, 0
+	}
+	longest := s.longestRetention()
+	kept := make([]string, 0, len(history))
+	for _, id := range history[:len(history)-1] {
+		record := s.scans[id]
+		age := now.Sub(record.Timestamp)
+		if age > longest {
+			delete(s.scans, id)
+			prunedScans++
+			prunedFindings += len(record.Findings)
+			continue
+		}
+		// the records are shared with the running comparisons, the findings are copied
+		retained := make(map[string]domain.Finding, len(record.Findings))
+		for key, finding := range record.Findings {
+			if age <= s.retentionOf(finding.Severity) {
+				retained[key] = finding
+			}
+		}
+		if pruned := len(record.Findings) - len(retained); pruned > 0 {
+			prunedFindings += pruned
+			if len(retained) == 0 {
+				delete(s.scans, id)
+				prunedScans++
+				continue
+			}
+			record.Findings = retained
+			s.scans[id] = record
+		}
+		kept = append(kept, id)
+	}
+	s.scanHistory[key] = append(kept, history[len(history)-1])
+	return prunedScans, prunedFindings
+}
+
+// collectHistory prunes the history of all the workloads, at most once per historyPruneInterval so that the history
+// of the workloads which are no longer scanned expires too; historyMu must be held
+func (s *ScanService) collectHistory(now time.Time) {
+	if now.Sub(s.historyPrunedAt) < historyPruneInterval {
+		return
+	}
+	s.historyPrunedAt = now
+	var prunedScans, prunedFindings int
+	for key := range s.scanHistory {
+		scans, findings := s.pruneHistory(key, now)
+		prunedScans += scans
+		prunedFindings += findings
+	}
+	if prunedFindings > 0 || prunedScans > 0 {
+		logger.L().Info("pruned the scan history past its retention",
+			helpers.Int("scans", prunedScans),
+			helpers.Int("findings", prunedFindings))
+	}
+}
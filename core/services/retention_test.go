@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanService_recordResults_retention(t *testing.T) {
+	day := 24 * time.Hour
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false,
+		WithHistoryRetention(map[string]time.Duration{"Critical": 365 * day, "low": 30 * day}))
+	critical := domain.Finding{ID: "CVE-2023-0286", Package: "openssl", Version: "3.0.7", Severity: "Critical"}
+	medium := domain.Finding{ID: "CVE-2023-0465", Package: "openssl", Version: "3.0.7", Severity: "Medium"}
+	low := domain.Finding{ID: "CVE-2022-3996", Package: "openssl", Version: "3.0.7", Severity: "Low"}
+	workload := domain.ScanCommand{Wlid: "wlid://cluster-minikube/namespace-default/deployment-nginx"}
+	now := time.Now()
+	s.recordResults(scanContext("expired", now.Add(-400*day)), workload, manifestWithFindings(critical, low))
+	s.recordResults(scanContext("pruned", now.Add(-60*day)), workload, manifestWithFindings(critical, medium, low))
+	s.recordResults(scanContext("emptied", now.Add(-40*day)), workload, manifestWithFindings(low))
+	s.recordResults(scanContext("clean", now.Add(-35*day)), workload, manifestWithFindings())
+	s.recordResults(scanContext("latest", now.Add(-31*day)), workload, manifestWithFindings(low))
+
+	assert.Equal(t, []string{"pruned", "clean", "latest"}, s.scanHistory[workload.Wlid])
+	assert.NotContains(t, s.scans, "expired")
+	assert.NotContains(t, s.scans, "emptied")
+	// the severities without retention are kept as long as the longest one
+	assert.Equal(t, findings(manifestWithFindings(critical, medium)), s.scans["pruned"].Findings)
+	assert.Empty(t, s.scans["clean"].Findings)
+	// the latest scan is kept whole
+	assert.Equal(t, findings(manifestWithFindings(low)), s.scans["latest"].Findings)
+
+	// the history of the workloads no longer scanned expires with the scans of the other workloads
+	s.recordResults(scanContext("next", now.Add(-day)), workload, manifestWithFindings(low))
+	assert.Equal(t, []string{"pruned", "clean", "next"}, s.scanHistory[workload.Wlid])
+	s.scans["pruned"] = domain.ScanRecord{ScanID: "pruned", Timestamp: now.Add(-366 * day), Findings: s.scans["pruned"].Findings}
+	other := domain.ScanCommand{ImageTagNormalized: "nginx:latest"}
+	s.recordResults(scanContext("other", now), other, manifestWithFindings(low))
+	assert.Contains(t, s.scans, "pruned")
+	s.historyPrunedAt = time.Time{}
+	s.recordResults(scanContext("other2", now), other, manifestWithFindings(low))
+	assert.NotContains(t, s.scans, "pruned")
+	assert.Equal(t, []string{"other", "other2"}, s.scanHistory["nginx:latest"])
+}
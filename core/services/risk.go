@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+)
+
+// WithRiskScore replaces the default weights of the risk score of the images, exposure tells whether the workloads are
+// reachable from outside the cluster, the workloads are considered unexposed without it
+func WithRiskScore(weights domain.RiskWeights, exposure ports.ExposureChecker) ScanServiceOption {
+	return func(s *ScanService) {
+		s.riskWeights = weights
+		s.exposureChecker = exposure
+	}
+}
+
+// annotateRisk records the risk score of the image on the CVE manifest, cvep holds the relevant vulnerabilities when
+// relevancy data is available; annotations are copied first since they can be shared with the stored manifests
+func (s *ScanService) annotateRisk(ctx context.Context, workload domain.ScanCommand, cve *domain.CVEManifest, cvep domain.CVEManifest) {
+	if cve.Content == nil {
+		return
+	}
+	exposed := false
+	if s.exposureChecker != nil && workload.Wlid != "" {
+		var err error
+		exposed, err = s.exposureChecker.IsExposed(ctx, workload.Wlid)
+		if err != nil {
+			logger.L().Ctx(ctx).Warning("failed to check the exposure of the workload", helpers.Error(err),
+				helpers.String("wlid", workload.Wlid))
+		}
+	}
+	annotations := make(map[string]string, len(cve.Annotations)+1)
+	for key, value := range cve.Annotations {
+		annotations[key] = value
+	}
+	annotations[domain.AnnotationRiskScore] = strconv.FormatFloat(riskScore(s.riskWeights, *cve, cvep, exposed), 'f', -1, 64)
+	cve.Annotations = annotations
+}
+
+// riskScore computes the risk score of an image with the given weights, rounded to one decimal
+func riskScore(weights domain.RiskWeights, cve, cvep domain.CVEManifest, exposed bool) float64 {
+	severities := make(map[string]float64, len(weights.Severities))
+	for severity, points := range weights.Severities {
+		severities[strings.ToLower(severity)] = points
+	}
+	var relevant map[string]bool
+	if cvep.Content != nil {
+		relevant = make(map[string]bool, len(cvep.Content.Matches))
+		for _, match := range cvep.Content.Matches {
+			relevant[matchKey(match)] = true
+		}
+	}
+	var score float64
+	for _, match := range cve.Content.Matches {
+		points := severities[strings.ToLower(match.Vulnerability.Severity)]
+		if len(cve.Exploits[match.Vulnerability.ID]) > 0 {
+			points *= weights.Exploit
+		}
+		if relevant != nil && !relevant[matchKey(match)] {
+			points *= weights.Irrelevant
+		}
+		if match.Vulnerability.Fix.State == domain.FixStateFixed {
+			points *= weights.Fixable
+		}
+		score += points
+	}
+	if exposed {
+		score *= weights.Exposed
+	}
+	return math.Round(score*10) / 10
+}
+
+// matchKey identifies a vulnerability of a package version
+func matchKey(match v1beta1.Match) string {
+	return domain.Finding{ID: match.Vulnerability.ID, Package: match.Artifact.Name, Version: match.Artifact.Version}.Key()
+}
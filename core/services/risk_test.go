@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSeverityMatch returns a match of a vulnerability of a package with a severity and a fix state
+func newSeverityMatch(id, name, severity, fixState string) v1beta1.Match {
+	match := newMatch(id, name)
+	match.Vulnerability.Severity = severity
+	match.Vulnerability.Fix.State = fixState
+	return match
+}
+
+// staticExposure tells the workloads are exposed, or fails
+type staticExposure struct {
+	exposed bool
+	err     error
+}
+
+func (s staticExposure) IsExposed(context.Context, string) (bool, error) {
+	return s.exposed, s.err
+}
+
+func Test_riskScore(t *testing.T) {
+	log4j := newSeverityMatch("CVE-2021-44228", "log4j-core", domain.CriticalSeverity, "fixed")
+	text := newSeverityMatch("CVE-2022-32149", "golang.org/x/text", domain.HighSeverity, "not-fixed")
+	busybox := newSeverityMatch("CVE-2022-28391", "busybox", domain.MediumSeverity, "unknown")
+	cve := domain.CVEManifest{Content: &v1beta1.GrypeDocument{Matches: []v1beta1.Match{log4j, text, busybox}}}
+	weights := domain.DefaultRiskWeights()
+	tests := []struct {
+		name     string
+		weights  domain.RiskWeights
+		exploits map[string][]string
+		cvep     domain.CVEManifest
+		exposed  bool
+		want     float64
+	}{
+		{
+			name:    "severities and fixes",
+			weights: weights,
+			// 10*1.2 + 5 + 2
+			want: 19,
+		},
+		{
+			name:     "public exploit",
+			weights:  weights,
+			exploits: map[string][]string{"CVE-2021-44228": {domain.ExploitSourceMetasploit}},
+			// 10*1.2*2 + 5 + 2
+			want: 31,
+		},
+		{
+			name:    "relevancy",
+			weights: weights,
+			cvep:    domain.CVEManifest{Content: &v1beta1.GrypeDocument{Matches: []v1beta1.Match{log4j}}},
+			// 10*1.2 + (5 + 2)*0.25
+			want: 13.8,
+		},
+		{
+			name:    "exposed workload",
+			weights: weights,
+			exposed: true,
+			// (10*1.2 + 5 + 2)*1.5
+			want: 28.5,
+		},
+		{
+			name:    "custom weights in any case",
+			weights: domain.RiskWeights{Severities: map[string]float64{"critical": 1, "HIGH": 1}, Exploit: 1, Exposed: 1, Fixable: 1, Irrelevant: 1},
+			want:    2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cve := cve
+			cve.Exploits = tt.exploits
+			assert.Equal(t, tt.want, riskScore(tt.weights, cve, tt.cvep, tt.exposed))
+		})
+	}
+}
+
+func TestScanService_annotateRisk(t *testing.T) {
+	annotations := map[string]string{domain.AnnotationImageCreated: "2023-01-01T00:00:00Z"}
+	cve := domain.CVEManifest{
+		Annotations: annotations,
+		Content:     &v1beta1.GrypeDocument{Matches: []v1beta1.Match{newSeverityMatch("CVE-2022-32149", "golang.org/x/text", domain.HighSeverity, "")}},
+	}
+	workload := domain.ScanCommand{Wlid: "wlid://cluster-minikube/namespace-default/deployment-nginx"}
+
+	s := &ScanService{riskWeights: domain.DefaultRiskWeights()}
+	s.annotateRisk(context.TODO(), workload, &cve, domain.CVEManifest{})
+	assert.Equal(t, "5", cve.Annotations[domain.AnnotationRiskScore])
+	assert.Equal(t, "2023-01-01T00:00:00Z", cve.Annotations[domain.AnnotationImageCreated])
+	// the annotations of the stored manifest are left untouched
+	assert.NotContains(t, annotations, domain.AnnotationRiskScore)
+
+	WithRiskScore(domain.DefaultRiskWeights(), staticExposure{exposed: true})(s)
+	s.annotateRisk(context.TODO(), workload, &cve, domain.CVEManifest{})
+	assert.Equal(t, "7.5", cve.Annotations[domain.AnnotationRiskScore])
+
+	// the workload is considered unexposed when its exposure is unknown
+	WithRiskScore(domain.DefaultRiskWeights(), staticExposure{err: domain.ErrMockError})(s)
+	s.annotateRisk(context.TODO(), workload, &cve, domain.CVEManifest{})
+	assert.Equal(t, "5", cve.Annotations[domain.AnnotationRiskScore])
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	wlidpkg "github.com/armosec/utils-k8s-go/wlid"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+)
+
+// WithResultRoutes forwards the findings of the completed scans to the sinks, keyed by name, of all the routes they
+// match, besides submitting them to the platform
+func WithResultRoutes(routes []domain.ResultRoute, sinks map[string]ports.ResultSink) ScanServiceOption {
+	return func(s *ScanService) {
+		s.routes = routes
+		s.resultSinks = sinks
+	}
+}
+
+// routeResults forwards the findings of a scan to the sinks of the routes it matches, errors are only logged; the
+// scans of the workloads opted out of the scans are not forwarded
+func (s *ScanService) routeResults(ctx context.Context, workload domain.ScanCommand, cve domain.CVEManifest, scanErr error) {
+	if len(s.routes) == 0 || errors.Is(scanErr, domain.ErrSkippedByAnnotation) {
+		return
+	}
+	report := s.scanReport(ctx, workload, cve, scanErr)
+	var namespace string
+	if workload.Wlid != "" {
+		namespace = wlidpkg.GetNamespaceFromWlid(workload.Wlid)
+	}
+	all := sortedFindings(cve)
+	for _, route := range s.routes {
+		if !routesScan(route, report.Verdict, namespace) {
+			continue
+		}
+		selected := all
+		if len(route.Severities) > 0 {
+			selected = findingsOfSeverities(all, route.Severities)
+			if len(selected) == 0 {
+				continue
+			}
+		}
+		sink, ok := s.resultSinks[route.Sink]
+		if !ok {
+			continue
+		}
+		if err := sink.SendResults(ctx, report, selected); err != nil {
+			logger.L().Ctx(ctx).Warning("failed to forward the scan results", helpers.Error(err),
+				helpers.String("route", route.Name),
+				helpers.String("sink", route.Sink),
+				helpers.String("imageSlug", workload.ImageSlug))
+		}
+	}
+}
+
+// routesScan tells whether a route selects the scans of the given verdict and namespace, the scans without a workload
+// such as the registry scans only match the routes selecting any namespace
+func routesScan(route domain.ResultRoute, verdict, namespace string) bool {
+	if len(route.Verdicts) > 0 && !containsFold(route.Verdicts, verdict) {
+		return false
+	}
+	if len(route.Namespaces) > 0 && (namespace == "" || !matchesNamespace(route.Namespaces, namespace)) {
+		return false
+	}
+	return true
+}
+
+// sortedFindings returns the findings of a vulnerability manifest but the snoozed ones, sorted by finding key
+func sortedFindings(cve domain.CVEManifest) []domain.Finding {
+	byKey := findings(cve)
+	if cve.Content != nil && len(cve.Triage) > 0 {
+		for _, match := range cve.Content.Matches {
+			if cve.Snoozed(match) {
+				delete(byKey, domain.Finding{ID: match.Vulnerability.ID, Package: match.Artifact.Name, Version: match.Artifact.Version}.Key())
+			}
+		}
+	}
+	sorted := make([]domain.Finding, 0, len(byKey))
+	for _, finding := range byKey {
+		sorted = append(sorted, finding)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Key() < sorted[j].Key()
+	})
+	return sorted
+}
+
+// findingsOfSeverities returns the findings of the given severities, in any case
+func findingsOfSeverities(findings []domain.Finding, severities []string) []domain.Finding {
+	var selected []domain.Finding
+	for _, finding := range findings {
+		if containsFold(severities, finding.Severity) {
+			selected = append(selected, finding)
+		}
+	}
+	return selected
+}
+
+// containsFold tells whether the values contain the value, in any case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
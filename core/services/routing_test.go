@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+// sentResults is a ResultSink recording the findings sent by scanID
+type sentResults map[string][]domain.Finding
+
+func (r sentResults) SendResults(_ context.Context, report domain.ScanReport, findings []domain.Finding) error {
+	r[report.ScanID] = findings
+	return nil
+}
+
+// failingSink is a ResultSink which cannot be reached
+type failingSink struct{}
+
+func (failingSink) SendResults(context.Context, domain.ScanReport, []domain.Finding) error {
+	return errors.New("connection refused")
+}
+
+func TestScanService_routeResults(t *testing.T) {
+	openssl := domain.Finding{ID: "CVE-2023-0286", Package: "openssl", Version: "3.0.7", Severity: "High"}
+	zlib := domain.Finding{ID: "CVE-2022-37434", Package: "zlib", Version: "1.2.12", Severity: "Critical"}
+	curl := domain.Finding{ID: "CVE-2023-23914", Package: "curl", Version: "7.87.0", Severity: "Medium"}
+	cve := manifestWithFindings(openssl, zlib, curl)
+	routes := []domain.ResultRoute{
+		{Name: "siem", Sink: "siem", Severities: []string{"critical", "high"}, Namespaces: []string{"prod-*"}},
+		{Name: "failures", Sink: "archive", Verdicts: []string{domain.VerdictFail}},
+		{Name: "unreachable", Sink: "down"},
+		{Name: "unknown sink", Sink: "missing"},
+	}
+	tests := []struct {
+		name        string
+		wlid        string
+		cve         domain.CVEManifest
+		scanErr     error
+		wantSIEM    []domain.Finding
+		wantArchive []domain.Finding
+	}{
+		{
+			name:        "production workload",
+			wlid:        "wlid://cluster-minikube/namespace-prod-eu/deployment-nginx",
+			cve:         cve,
+			wantSIEM:    []domain.Finding{zlib, openssl},
+			wantArchive: []domain.Finding{zlib, openssl, curl},
+		},
+		{
+			name:        "other namespace",
+			wlid:        "wlid://cluster-minikube/namespace-dev/deployment-nginx",
+			cve:         cve,
+			wantArchive: []domain.Finding{zlib, openssl, curl},
+		},
+		{
+			name: "no findings of the route severities",
+			wlid: "wlid://cluster-minikube/namespace-prod-eu/deployment-nginx",
+			cve:  manifestWithFindings(curl),
+		},
+		{
+			name: "registry scan",
+			cve:  cve,
+		},
+		{
+			name:    "opted out",
+			wlid:    "wlid://cluster-minikube/namespace-prod-eu/deployment-nginx",
+			cve:     cve,
+			scanErr: domain.ErrSkippedByAnnotation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			siem, archive := sentResults{}, sentResults{}
+			s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockCVEAdapter(),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockPlatform(),
+				false,
+				WithResultRoutes(routes, map[string]ports.ResultSink{"siem": siem, "archive": archive, "down": failingSink{}}),
+				WithSeverityThresholds([]domain.SeverityThresholds{{Name: "strict", Namespaces: []string{"*"}, Fail: map[string]int{"Critical": 0}}}, nil))
+			ctx := context.WithValue(context.TODO(), domain.ScanIDKey{}, "scanID")
+			s.routeResults(ctx, domain.ScanCommand{Wlid: tt.wlid}, tt.cve, tt.scanErr)
+			assert.Equal(t, tt.wantSIEM, siem["scanID"])
+			assert.Equal(t, tt.wantArchive, archive["scanID"])
+		})
+	}
+}
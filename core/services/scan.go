@@ -6,7 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/akyoto/cache"
@@ -18,6 +19,7 @@ import (
 	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
 	"github.com/kubescape/kubevuln/core/domain"
 	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/internal/tools"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -31,28 +33,204 @@ const (
 // ScanService implements ScanService from ports, this is the business component
 // business logic should be independent of implementations
 type ScanService struct {
-	sbomCreator     ports.SBOMCreator
-	sbomRepository  ports.SBOMRepository
-	cveScanner      ports.CVEScanner
-	cveRepository   ports.CVERepository
-	platform        ports.Platform
-	storage         bool
-	tooManyRequests *cache.Cache
+	allowedRegistries  []string
+	bundles            *bundles
+	caches             *cacheStats
+	sbomCreator        ports.SBOMCreator
+	sbomRepository     ports.SBOMRepository
+	canary             ports.CanaryProvider
+	cveScanner         ports.CVEScanner
+	coverageWindow     time.Duration
+	cveRepository      ports.CVERepository
+	enricher           ports.VulnerabilityEnricher
+	exposureChecker    ports.ExposureChecker
+	falsePositives     ports.FalsePositiveRepository
+	exploitProvider    ports.ExploitProvider
+	failureRepository  ports.ScanFailureRepository
+	failures           map[string]domain.ScanFailure
+	failuresLoad       sync.Once
+	failuresMu         sync.RWMutex
+	gc                 *garbageCollector
+	historyMu          sync.RWMutex
+	historyPrunedAt    time.Time
+	historyRetention   map[string]time.Duration
+	lastScans          map[string]time.Time
+	layerDiffCreator   ports.LayerDiffSBOMCreator
+	lastScansMu        sync.RWMutex
+	lastScansLoad      sync.Once
+	platform           ports.Platform
+	progressBroker     ports.ProgressBroker
+	pushAttestations   bool
+	relay              bool
+	relevancyProvider  ports.RelevancyProvider
+	release            string
+	repositoryCreator  ports.RepositorySBOMCreator
+	resultSinks        map[string]ports.ResultSink
+	riskWeights        domain.RiskWeights
+	routes             []domain.ResultRoute
+	sbomCheck          *sbomCheck
+	sbomMigrator       *sbomMigrator
+	sbomSigner         ports.SBOMSigner
+	scanHistory        map[string][]string
+	scanEvents         *scanEventLog
+	scanProfile        string
+	scanTimes          ports.ScanTimeRepository
+	scans              map[string]domain.ScanRecord
+	sendTombstones     bool
+	notifier           ports.Notifier
+	orphanRepository   ports.ScanResultRepository
+	ignoreUnfixed      bool
+	imageResolver      ports.ImageResolver
+	inventory          ports.InventoryRepository
+	matchExplanations  bool
+	matchTimeout       time.Duration
+	namespaceLabeler   ports.NamespaceLabeler
+	maxImageAge        time.Duration
+	goldenBases        map[string]string
+	goldenBasesVersion string
+	nodeName           string
+	storage            bool
+	submitTimeout      time.Duration
+	suppressions       []domain.Suppression
+	tagDigests         *cache.Cache
+	thresholds         []domain.SeverityThresholds
+	tooManyRequests    *cache.Cache
+	triage             ports.TriageRepository
+	trustedDigests     map[string]bool
+	trustedVersion     string
+	workloadAnnotator  ports.WorkloadAnnotator
+	workloadLister     ports.WorkloadLister
+	workloadPatcher    ports.WorkloadPatcher
 }
 
 var _ ports.ScanService = (*ScanService)(nil)
 
+// ScanServiceOption configures optional dependencies of the ScanService
+type ScanServiceOption func(*ScanService)
+
+// WithNotifier injects the Notifier used to report scan completion to the caller
+func WithNotifier(notifier ports.Notifier) ScanServiceOption {
+	return func(s *ScanService) {
+		s.notifier = notifier
+	}
+}
+
+// WithImageResolver injects the ImageResolver used to pin registry scan tags to digests
+func WithImageResolver(imageResolver ports.ImageResolver) ScanServiceOption {
+	return func(s *ScanService) {
+		s.imageResolver = imageResolver
+	}
+}
+
+// WithAllowedRegistries only accepts the commands referencing images of the given registries,
+// an entry is a registry host optionally followed by a repository prefix, empty allows any registry
+func WithAllowedRegistries(registries []string) ScanServiceOption {
+	return func(s *ScanService) {
+		s.allowedRegistries = nil
+		for _, registry := range registries {
+			s.allowedRegistries = append(s.allowedRegistries, tools.NormalizeRegistry(registry))
+		}
+	}
+}
+
+// WithMaxImageAge flags images created longer ago than maxImageAge, zero disables the check
+func WithMaxImageAge(maxImageAge time.Duration) ScanServiceOption {
+	return func(s *ScanService) {
+		s.maxImageAge = maxImageAge
+	}
+}
+
+// WithMatchTimeout sets the timeout budget of the vulnerability matching, zero disables it
+func WithMatchTimeout(matchTimeout time.Duration) ScanServiceOption {
+	return func(s *ScanService) {
+		s.matchTimeout = matchTimeout
+	}
+}
+
+// WithSubmitTimeout sets the timeout budget of the submission to the platform, zero disables it
+func WithSubmitTimeout(submitTimeout time.Duration) ScanServiceOption {
+	return func(s *ScanService) {
+		s.submitTimeout = submitTimeout
+	}
+}
+
+// WithCoverageTracking injects the WorkloadLister used to report which running images were scanned within window
+func WithCoverageTracking(workloadLister ports.WorkloadLister, window time.Duration) ScanServiceOption {
+	return func(s *ScanService) {
+		s.workloadLister = workloadLister
+		if window > 0 {
+			s.coverageWindow = window
+		}
+	}
+}
+
+// WithGarbageCollection deletes the stored scan results of the images and the workloads no workload listed by
+// workloadLister, nor the inventory of WithInventory, references for longer than gracePeriod
+func WithGarbageCollection(workloadLister ports.WorkloadLister, repository ports.ScanResultRepository, gracePeriod time.Duration) ScanServiceOption {
+	return func(s *ScanService) {
+		s.gc = &garbageCollector{
+			gracePeriod:       gracePeriod,
+			repository:        repository,
+			unreferencedSince: map[domain.StoredResult]time.Time{},
+			workloadLister:    workloadLister,
+		}
+	}
+}
+
+// WithWorkloadDeletion marks the stored summaries of the deleted workloads as orphaned in repository,
+// and sends a tombstone to the platform for them if sendTombstones is set
+func WithWorkloadDeletion(repository ports.ScanResultRepository, sendTombstones bool) ScanServiceOption {
+	return func(s *ScanService) {
+		s.orphanRepository = repository
+		s.sendTombstones = sendTombstones
+	}
+}
+
+// WithProgressBroker publishes the progress of each scan stage to broker
+func WithProgressBroker(broker ports.ProgressBroker) ScanServiceOption {
+	return func(s *ScanService) {
+		s.progressBroker = broker
+	}
+}
+
+// WithRelevancyProvider injects the RelevancyProvider used to scan the packages used at runtime,
+// by default the relevant SBOMs are read from the storage
+func WithRelevancyProvider(provider ports.RelevancyProvider) ScanServiceOption {
+	return func(s *ScanService) {
+		s.relevancyProvider = provider
+	}
+}
+
+// WithRelease sets the kubevuln release reported in traces
+func WithRelease(release string) ScanServiceOption {
+	return func(s *ScanService) {
+		s.release = release
+	}
+}
+
 // NewScanService initializes the ScanService with all injected dependencies
-func NewScanService(sbomCreator ports.SBOMCreator, sbomRepository ports.SBOMRepository, cveScanner ports.CVEScanner, cveRepository ports.CVERepository, platform ports.Platform, storage bool) *ScanService {
-	return &ScanService{
+func NewScanService(sbomCreator ports.SBOMCreator, sbomRepository ports.SBOMRepository, cveScanner ports.CVEScanner, cveRepository ports.CVERepository, platform ports.Platform, storage bool, opts ...ScanServiceOption) *ScanService {
+	s := &ScanService{
+		caches:          newCacheStats(),
 		sbomCreator:     sbomCreator,
 		sbomRepository:  sbomRepository,
 		cveScanner:      cveScanner,
+		coverageWindow:  defaultCoverageWindow,
 		cveRepository:   cveRepository,
+		failures:        map[string]domain.ScanFailure{},
+		lastScans:       map[string]time.Time{},
 		platform:        platform,
+		riskWeights:     domain.DefaultRiskWeights(),
+		scanHistory:     map[string][]string{},
+		scans:           map[string]domain.ScanRecord{},
 		storage:         storage,
+		tagDigests:      cache.New(cleaningInterval),
 		tooManyRequests: cache.New(cleaningInterval),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *ScanService) checkCreateSBOM(err error, key string) {
@@ -65,7 +243,7 @@ func (s *ScanService) checkCreateSBOM(err error, key string) {
 }
 
 // GenerateSBOM implements the "Generate SBOM flow"
-func (s *ScanService) GenerateSBOM(ctx context.Context) error {
+func (s *ScanService) GenerateSBOM(ctx context.Context) (err error) {
 	ctx, span := otel.Tracer("").Start(ctx, "ScanService.GenerateSBOM")
 	defer span.End()
 
@@ -77,15 +255,23 @@ func (s *ScanService) GenerateSBOM(ctx context.Context) error {
 		return domain.ErrCastingWorkload
 	}
 
+	// report the progress until the scan is over
+	ctx = s.trackProgress(ctx, workload)
+	defer func() {
+		finishProgress(ctx, err)
+	}()
+	// a panic fails the scan instead of crashing the pod
+	defer tools.RecoverPanic(ctx, &err)
+
 	// check if SBOM is already available
 	sbom := domain.SBOM{}
-	var err error
 	if s.storage {
 		sbom, err = s.sbomRepository.GetSBOM(ctx, workload.ImageSlug, s.sbomCreator.Version())
 		if err != nil {
 			logger.L().Ctx(ctx).Warning("error getting SBOM", helpers.Error(err),
 				helpers.String("imageSlug", workload.ImageSlug))
 		}
+		s.recordSBOMLookup(sbom)
 	}
 
 	// if SBOM is not available, create it
@@ -96,10 +282,11 @@ func (s *ScanService) GenerateSBOM(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
+		sbom = s.signSBOM(ctx, workload, sbom)
 	}
 
 	// store SBOM
-	if s.storage {
+	if s.storage && !partialSBOM(sbom) {
 		err = s.sbomRepository.StoreSBOM(ctx, sbom)
 		if err != nil {
 			return err
@@ -115,11 +302,12 @@ func (s *ScanService) Ready(ctx context.Context) bool {
 }
 
 // ScanCVE implements the "Scanning for CVEs flow"
-func (s *ScanService) ScanCVE(ctx context.Context) error {
+func (s *ScanService) ScanCVE(ctx context.Context) (err error) {
 	ctx, span := otel.Tracer("").Start(ctx, "ScanService.ScanCVE")
 	defer span.End()
 
 	ctx = addTimestamp(ctx)
+	ctx = trackStages(ctx)
 
 	// retrieve workload from context
 	workload, ok := ctx.Value(domain.WorkloadKey{}).(domain.ScanCommand)
@@ -130,45 +318,73 @@ func (s *ScanService) ScanCVE(ctx context.Context) error {
 		helpers.String("imageSlug", workload.ImageSlug),
 		helpers.String("jobID", workload.JobID))
 
+	// record the outcome, notify the caller and report the progress until the scan is over
+	ctx = s.trackProgress(ctx, workload)
+	cve := domain.CVEManifest{}
+	stage := domain.StageSBOM
+	defer func() {
+		err = s.reportFailure(ctx, workload, stage, err)
+		s.recordOutcome(ctx, workload, stage, err)
+		s.notify(ctx, workload, cve, err)
+		s.annotateWorkload(ctx, workload, cve, err)
+		s.routeResults(ctx, workload, cve, err)
+		finishProgress(ctx, err)
+	}()
+	// a panic fails the scan instead of crashing the pod
+	defer tools.RecoverPanic(ctx, &err)
+
+	// detect if the tag now points to a different digest
+	previousDigest := s.checkTagMutation(workload.ImageTagNormalized, workload.ImageHash)
+
 	// report to platform
-	err := s.platform.SendStatus(ctx, domain.Started)
+	err = s.platform.SendStatus(ctx, domain.Started)
 	if err != nil {
 		logger.L().Ctx(ctx).Warning("telemetry error", helpers.Error(err),
 			helpers.String("imageSlug", workload.ImageSlug))
 	}
 
 	// check if CVE manifest is already available
-	cve := domain.CVEManifest{}
 	if s.storage {
 		cve, err = s.cveRepository.GetCVE(ctx, workload.ImageSlug, s.sbomCreator.Version(), s.cveScanner.Version(ctx), s.cveScanner.DBVersion(ctx))
 		if err != nil {
 			logger.L().Ctx(ctx).Warning("error getting CVE", helpers.Error(err),
 				helpers.String("imageSlug", workload.ImageSlug))
 		}
+		s.recordCVELookup(cve)
+		if cve.Content != nil {
+			tools.RecordScanEvent(ctx, "vulnerability manifest read from storage", nil)
+		}
 	}
 
 	// if CVE manifest is not available, create it
+	sbom := domain.SBOM{}
 	if cve.Content == nil {
 		// check if SBOM is already available
-		sbom := domain.SBOM{}
 		if s.storage {
 			sbom, err = s.sbomRepository.GetSBOM(ctx, workload.ImageSlug, s.sbomCreator.Version())
 			if err != nil {
 				logger.L().Ctx(ctx).Warning("error getting SBOM", helpers.Error(err),
 					helpers.String("imageSlug", workload.ImageSlug))
 			}
+			s.recordSBOMLookup(sbom)
+			if sbom.Content != nil {
+				tools.RecordScanEvent(ctx, "SBOM read from storage", nil)
+			}
 		}
 
 		// if SBOM is not available, create it
 		if sbom.Content == nil {
 			// create SBOM
+			start := time.Now()
 			sbom, err = s.sbomCreator.CreateSBOM(ctx, workload.ImageSlug, workload.ImageHash, optionsFromWorkload(workload))
+			recordStage(ctx, domain.StageSBOM, start)
 			s.checkCreateSBOM(err, workload.ImageHash)
 			if err != nil {
 				return err
 			}
+			sbom = s.signSBOM(ctx, workload, sbom)
 			// store SBOM
-			if s.storage {
+			if s.storage && !partialSBOM(sbom) {
 				err = s.sbomRepository.StoreSBOM(ctx, sbom)
 				if err != nil {
 					logger.L().Ctx(ctx).Warning("error storing SBOM", helpers.Error(err),
@@ -179,17 +395,21 @@ func (s *ScanService) ScanCVE(ctx context.Context) error {
 
 		// do not process timed out SBOM
 		if sbom.Status == instanceidhandler.Incomplete {
-			return domain.ErrIncompleteSBOM
+			return incompleteSBOMError(sbom)
 		}
 
 		// scan for CVE
-		cve, err = s.cveScanner.ScanSBOM(ctx, sbom)
+		stage = domain.StageMatch
+		cve, err = s.scanSBOM(ctx, sbom)
 		if err != nil {
 			return err
 		}
+		if cve.Content != nil {
+			tools.RecordScanEvent(ctx, "vulnerabilities matched", map[string]string{"matches": strconv.Itoa(len(cve.Content.Matches))})
+		}
 
 		// store CVE
-		if s.storage {
+		if s.storage && !partialSBOM(sbom) {
 			err = s.cveRepository.StoreCVE(ctx, cve, false)
 			if err != nil {
 				logger.L().Ctx(ctx).Warning("error storing CVE", helpers.Error(err),
@@ -203,10 +423,10 @@ func (s *ScanService) ScanCVE(ctx context.Context) error {
 		}
 	}
 
-	// check if SBOM' is already available
+	// check if SBOM' is available
 	sbomp := domain.SBOM{}
-	if s.storage && workload.InstanceID != "" {
-		sbomp, err = s.sbomRepository.GetSBOMp(ctx, workload.InstanceID, s.sbomCreator.Version())
+	if workload.InstanceID != "" {
+		sbomp, err = s.relevantSBOM(ctx, workload, sbom)
 		if err != nil {
 			logger.L().Ctx(ctx).Warning("error getting relevant SBOM", helpers.Error(err),
 				helpers.String("instanceID", workload.InstanceID))
@@ -217,7 +437,8 @@ func (s *ScanService) ScanCVE(ctx context.Context) error {
 	cvep := domain.CVEManifest{}
 	if sbomp.Content != nil {
 		// scan for CVE'
-		cvep, err = s.cveScanner.ScanSBOM(ctx, sbomp)
+		stage = domain.StageMatch
+		cvep, err = s.scanSBOM(ctx, sbomp)
 		if err != nil {
 			return err
 		}
@@ -237,14 +458,26 @@ func (s *ScanService) ScanCVE(ctx context.Context) error {
 		}
 	}
 
+	// apply the false positive feedback given since the stored manifests were scanned
+	cve, cvep = s.markFalsePositives(ctx, cve), s.markFalsePositives(ctx, cvep)
+	// apply the triage of the findings of the workload
+	cve = s.withTriage(ctx, workload, cve)
+	// flag the results of the scan-time checks
+	s.annotateChecks(&cve, previousDigest)
+	// score the risk of the image in the context of its workload
+	cve = s.withExploits(ctx, cve)
+	s.annotateRisk(ctx, workload, &cve, cvep)
+
 	// report scan success to platform
 	err = s.platform.SendStatus(ctx, domain.Success)
 	if err != nil {
 		logger.L().Ctx(ctx).Warning("telemetry error", helpers.Error(err),
 			helpers.String("imageSlug", workload.ImageSlug))
 	}
-	// submit CVE manifest to platform
-	err = s.platform.SubmitCVE(ctx, cve, cvep)
+	// submit CVE manifest to platform, with the SBOM for the v1 reports, even past the deadline of the scan
+	warnDeadlineExceeded(ctx, workload)
+	stage = domain.StageSubmit
+	err = s.submitCVE(context.WithValue(ctx, domain.SBOMKey{}, sbom), cve, cvep)
 	if err != nil {
 		return err
 	}
@@ -255,17 +488,20 @@ func (s *ScanService) ScanCVE(ctx context.Context) error {
 			helpers.String("imageSlug", workload.ImageSlug))
 	}
 
+	s.recordScan(workload.ImageHash)
+	s.recordResults(ctx, workload, cve)
 	logger.L().Info("scan complete",
 		helpers.String("imageSlug", workload.ImageSlug),
 		helpers.String("jobID", workload.JobID))
 	return nil
 }
 
-func (s *ScanService) ScanRegistry(ctx context.Context) error {
+func (s *ScanService) ScanRegistry(ctx context.Context) (err error) {
 	ctx, span := otel.Tracer("").Start(ctx, "ScanService.ScanRegistry")
 	defer span.End()
 
 	ctx = addTimestamp(ctx)
+	ctx = trackStages(ctx)
 
 	// retrieve workload from context
 	workload, ok := ctx.Value(domain.WorkloadKey{}).(domain.ScanCommand)
@@ -276,15 +512,42 @@ func (s *ScanService) ScanRegistry(ctx context.Context) error {
 		helpers.String("imageSlug", workload.ImageSlug),
 		helpers.String("jobID", workload.JobID))
 
+	// record the outcome, notify the caller and report the progress until the scan is over
+	ctx = s.trackProgress(ctx, workload)
+	cve := domain.CVEManifest{}
+	stage := domain.StageSBOM
+	defer func() {
+		err = s.reportFailure(ctx, workload, stage, err)
+		s.recordOutcome(ctx, workload, stage, err)
+		s.notify(ctx, workload, cve, err)
+		s.annotateWorkload(ctx, workload, cve, err)
+		s.routeResults(ctx, workload, cve, err)
+		finishProgress(ctx, err)
+	}()
+	// a panic fails the scan instead of crashing the pod
+	defer tools.RecoverPanic(ctx, &err)
+
 	// report to platform
-	err := s.platform.SendStatus(ctx, domain.Started)
+	err = s.platform.SendStatus(ctx, domain.Started)
 	if err != nil {
 		logger.L().Ctx(ctx).Warning("telemetry error", helpers.Error(err),
 			helpers.String("imageSlug", workload.ImageSlug))
 	}
 
+	// pin the tag to a digest so that the scanned image cannot change under our feet
+	imageID := workload.ImageTag
+	if digest := s.resolveDigest(ctx, workload); digest != "" {
+		imageID = digest
+		workload.ImageHash = digest
+		ctx = context.WithValue(ctx, domain.WorkloadKey{}, workload)
+	}
+	// detect if the tag now points to a different digest
+	previousDigest := s.checkTagMutation(workload.ImageTagNormalized, workload.ImageHash)
+
 	// create SBOM
-	sbom, err := s.sbomCreator.CreateSBOM(ctx, workload.ImageSlug, workload.ImageTag, optionsFromWorkload(workload))
+	start := time.Now()
+	sbom, err := s.sbomCreator.CreateSBOM(ctx, workload.ImageSlug, imageID, optionsFromWorkload(workload))
+	recordStage(ctx, domain.StageSBOM, start)
 	s.checkCreateSBOM(err, workload.ImageTag)
 	if err != nil {
 		return err
@@ -292,23 +555,32 @@ func (s *ScanService) ScanRegistry(ctx context.Context) error {
 
 	// do not process timed out SBOM
 	if sbom.Status == instanceidhandler.Incomplete {
-		return domain.ErrIncompleteSBOM
+		return incompleteSBOMError(sbom)
 	}
 
 	// scan for CVE
-	cve, err := s.cveScanner.ScanSBOM(ctx, sbom)
+	stage = domain.StageMatch
+	cve, err = s.scanSBOM(ctx, sbom)
 	if err != nil {
 		return err
 	}
 
+	// flag the results of the scan-time checks
+	s.annotateChecks(&cve, previousDigest)
+	// score the risk of the image, registry scans have neither relevancy data nor workload
+	cve = s.withExploits(ctx, cve)
+	s.annotateRisk(ctx, workload, &cve, domain.CVEManifest{})
+
 	// report scan success to platform
 	err = s.platform.SendStatus(ctx, domain.Success)
 	if err != nil {
 		logger.L().Ctx(ctx).Warning("telemetry error", helpers.Error(err),
 			helpers.String("imageSlug", workload.ImageSlug))
 	}
-	// submit CVE manifest to platform
-	err = s.platform.SubmitCVE(ctx, cve, domain.CVEManifest{})
+	// submit CVE manifest to platform, with the SBOM for the v1 reports, even past the deadline of the scan
+	warnDeadlineExceeded(ctx, workload)
+	stage = domain.StageSubmit
+	err = s.submitCVE(context.WithValue(ctx, domain.SBOMKey{}, sbom), cve, domain.CVEManifest{})
 	if err != nil {
 		return err
 	}
@@ -319,12 +591,119 @@ func (s *ScanService) ScanRegistry(ctx context.Context) error {
 			helpers.String("imageID", workload.ImageSlug))
 	}
 
+	s.recordScan(workload.ImageHash)
+	s.recordResults(ctx, workload, cve)
 	logger.L().Info("registry scan complete",
 		helpers.String("imageSlug", workload.ImageSlug),
 		helpers.String("jobID", workload.JobID))
 	return nil
 }
 
+// scanSBOM scans the SBOM for CVEs within the matching timeout budget, applies the suppression rules and records
+// the provenance of the CVE manifest
+func (s *ScanService) scanSBOM(ctx context.Context, sbom domain.SBOM) (domain.CVEManifest, error) {
+	start := time.Now()
+	cve, err := tools.RunWithTimeout(ctx, domain.StageMatch, s.matchTimeout, func(ctx context.Context) (domain.CVEManifest, error) {
+		return s.cveScanner.ScanSBOM(ctx, sbom)
+	})
+	if err != nil {
+		return cve, err
+	}
+	return s.explainMatches(s.withScanProvenance(ctx, s.markFalsePositives(ctx, s.suppress(ctx, cve)), time.Since(start))), nil
+}
+
+// submitCVE submits the CVE manifests to the platform within the submission timeout budget
+func (s *ScanService) submitCVE(ctx context.Context, cve, cvep domain.CVEManifest) error {
+	_, err := tools.RunWithTimeout(ctx, domain.StageSubmit, s.submitTimeout, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.platform.SubmitCVE(ctx, s.withEnrichment(ctx, s.withDBBuilt(ctx, cve)), cvep)
+	})
+	return err
+}
+
+// notify sends a compact scan report to the caller, errors are only logged
+func (s *ScanService) notify(ctx context.Context, workload domain.ScanCommand, cve domain.CVEManifest, scanErr error) {
+	if s.notifier == nil || workload.CallbackURL == "" {
+		return
+	}
+	report := s.scanReport(ctx, workload, cve, scanErr)
+	if err := s.notifier.Notify(ctx, report); err != nil {
+		logger.L().Ctx(ctx).Warning("callback error", helpers.Error(err),
+			helpers.String("imageSlug", workload.ImageSlug),
+			helpers.String("callbackURL", workload.CallbackURL))
+	}
+}
+
+// scanReport returns the compact report of a scan with its verdict
+func (s *ScanService) scanReport(ctx context.Context, workload domain.ScanCommand, cve domain.CVEManifest, scanErr error) domain.ScanReport {
+	scanID, _ := ctx.Value(domain.ScanIDKey{}).(string)
+	report := domain.ScanReport{
+		ScanID:    scanID,
+		Wlid:      workload.Wlid,
+		ImageSlug: workload.ImageSlug,
+		ImageTag:  workload.ImageTagNormalized,
+		ImageHash: workload.ImageHash,
+		Verdict:   domain.VerdictSuccess,
+		Summary:   summarizeSeverities(cve),
+		Snoozed:   countSnoozed(cve),
+	}
+	if previousDigest, ok := cve.Annotations[domain.AnnotationPreviousDigest]; ok {
+		report.TagMutated = true
+		report.PreviousDigest = previousDigest
+	}
+	report.ImageCreated = cve.Annotations[domain.AnnotationImageCreated]
+	report.ImageSource = cve.Annotations[domain.AnnotationImageSource]
+	report.ImageLabels = imageLabels(cve)
+	report.GoldenBase = cve.Annotations[domain.AnnotationGoldenBase]
+	report.Partial = cve.Annotations[domain.AnnotationPartial] == "true"
+	report.Violations = violations(cve)
+	report.RiskScore, _ = strconv.ParseFloat(cve.Annotations[domain.AnnotationRiskScore], 64)
+	if scanErr != nil {
+		report.Verdict = domain.VerdictError
+		report.Error = scanErr.Error()
+		var authErr *domain.RegistryAuthError
+		if errors.As(scanErr, &authErr) {
+			report.AuthFailure = authErr.Reason
+		}
+		var pullErr *domain.RegistryPullError
+		if errors.As(scanErr, &pullErr) {
+			report.PullEndpoints = pullErr.Endpoints()
+		}
+	} else {
+		// the verdict of a completed scan depends on the severity thresholds of its namespace
+		s.checkThresholds(ctx, workload, &report)
+	}
+	return report
+}
+
+// summarizeSeverities counts the vulnerabilities of a CVE manifest by severity, but the snoozed ones
+func summarizeSeverities(cve domain.CVEManifest) map[string]int {
+	if cve.Content == nil {
+		return nil
+	}
+	summary := map[string]int{}
+	for _, match := range cve.Content.Matches {
+		if cve.Snoozed(match) {
+			continue
+		}
+		summary[match.Vulnerability.Severity]++
+	}
+	return summary
+}
+
+// countSnoozed counts the vulnerabilities of a CVE manifest snoozed by their triage
+func countSnoozed(cve domain.CVEManifest) int {
+	if cve.Content == nil || len(cve.Triage) == 0 {
+		return 0
+	}
+	snoozed := 0
+	for _, match := range cve.Content.Matches {
+		if cve.Snoozed(match) {
+			snoozed++
+		}
+	}
+	return snoozed
+}
+
 func addTimestamp(ctx context.Context) context.Context {
 	return context.WithValue(ctx, domain.TimestampKey{}, time.Now().Unix())
 }
@@ -382,10 +761,14 @@ func (s *ScanService) ValidateGenerateSBOM(ctx context.Context, workload domain.
 	if workload.ImageHash == "" || workload.ImageSlug == "" {
 		return ctx, domain.ErrMissingImageInfo
 	}
+	// refuse to pull images of registries outside of the allowlist
+	if err := s.checkRegistry(workload); err != nil {
+		return ctx, err
+	}
 	// add imageSlug to parent span
 	if parentSpan := trace.SpanFromContext(ctx); parentSpan != nil {
 		parentSpan.SetAttributes(attribute.String("imageSlug", workload.ImageSlug))
-		parentSpan.SetAttributes(attribute.String("version", os.Getenv("RELEASE")))
+		parentSpan.SetAttributes(attribute.String("version", s.release))
 		ctx = trace.ContextWithSpan(ctx, parentSpan)
 	}
 	// check if previous image pull resulted in TOOMANYREQUESTS error
@@ -404,22 +787,35 @@ func (s *ScanService) ValidateScanCVE(ctx context.Context, workload domain.ScanC
 	if workload.ImageHash == "" || workload.ImageSlug == "" {
 		return ctx, domain.ErrMissingImageInfo
 	}
+	// refuse to pull images of registries outside of the allowlist
+	if err := s.checkRegistry(workload); err != nil {
+		return ctx, err
+	}
 	// add instanceID and imageSlug to parent span
 	if parentSpan := trace.SpanFromContext(ctx); parentSpan != nil {
 		if workload.InstanceID != "" {
 			parentSpan.SetAttributes(attribute.String("instanceID", workload.InstanceID))
 		}
 		parentSpan.SetAttributes(attribute.String("imageSlug", workload.ImageSlug))
-		parentSpan.SetAttributes(attribute.String("version", os.Getenv("RELEASE")))
+		parentSpan.SetAttributes(attribute.String("version", s.release))
 		parentSpan.SetAttributes(attribute.String("wlid", workload.Wlid))
 		ctx = trace.ContextWithSpan(ctx, parentSpan)
 	}
+	// honor the opt-out of the workload
+	if err := s.checkScanAnnotation(ctx, workload); err != nil {
+		return ctx, err
+	}
+	// skip the vetted images
+	var err error
+	if ctx, err = s.checkTrustedDigest(ctx, workload, workload.ImageHash); err != nil {
+		return ctx, err
+	}
 	// check if previous image pull resulted in TOOMANYREQUESTS error
 	if _, ok := s.tooManyRequests.Get(workload.ImageHash); ok {
 		return ctx, domain.ErrTooManyRequests
 	}
 	// report to platform
-	err := s.platform.SendStatus(ctx, domain.Accepted)
+	err = s.platform.SendStatus(ctx, domain.Accepted)
 	if err != nil {
 		logger.L().Ctx(ctx).Error("telemetry error", helpers.Error(err))
 	}
@@ -435,12 +831,21 @@ func (s *ScanService) ValidateScanRegistry(ctx context.Context, workload domain.
 	if workload.ImageTag == "" || workload.ImageSlug == "" {
 		return ctx, domain.ErrMissingImageInfo
 	}
+	// refuse to pull images of registries outside of the allowlist
+	if err := s.checkRegistry(workload); err != nil {
+		return ctx, err
+	}
 	// add imageSlug to parent span
 	if parentSpan := trace.SpanFromContext(ctx); parentSpan != nil {
 		parentSpan.SetAttributes(attribute.String("imageSlug", workload.ImageSlug))
-		parentSpan.SetAttributes(attribute.String("version", os.Getenv("RELEASE")))
+		parentSpan.SetAttributes(attribute.String("version", s.release))
 		ctx = trace.ContextWithSpan(ctx, parentSpan)
 	}
+	// skip the vetted images, registry images being referenced by digest
+	var err error
+	if ctx, err = s.checkTrustedDigest(ctx, workload, workload.ImageTag); err != nil {
+		return ctx, err
+	}
 	// check if previous image pull resulted in TOOMANYREQUESTS error
 	if _, ok := s.tooManyRequests.Get(workload.ImageTag); ok {
 		return ctx, domain.ErrTooManyRequests
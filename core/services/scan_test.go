@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
 	"testing"
@@ -15,6 +16,7 @@ import (
 	"github.com/kubescape/kubevuln/repositories"
 	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestScanService_GenerateSBOM(t *testing.T) {
@@ -381,9 +383,10 @@ func TestScanService_ValidateGenerateSBOM(t *testing.T) {
 
 func TestScanService_ValidateScanCVE(t *testing.T) {
 	tests := []struct {
-		name     string
-		workload domain.ScanCommand
-		wantErr  bool
+		name              string
+		allowedRegistries []string
+		workload          domain.ScanCommand
+		wantErr           bool
 	}{
 		{
 			name:     "missing Wlid",
@@ -406,6 +409,16 @@ func TestScanService_ValidateScanCVE(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:              "registry not allowed",
+			allowedRegistries: []string{"ghcr.io"},
+			workload: domain.ScanCommand{
+				ImageSlug: "imageSlug",
+				ImageHash: "k8s.gcr.io/kube-proxy@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+				Wlid:      "wlid://cluster-minikube/namespace-kube-system/daemonset-kube-proxy",
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -414,7 +427,8 @@ func TestScanService_ValidateScanCVE(t *testing.T) {
 				adapters.NewMockCVEAdapter(),
 				repositories.NewMemoryStorage(false, false),
 				adapters.NewMockPlatform(),
-				false)
+				false,
+				WithAllowedRegistries(tt.allowedRegistries))
 			_, err := s.ValidateScanCVE(context.TODO(), tt.workload)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ValidateScanCVE() error = %v, wantErr %v", err, tt.wantErr)
@@ -579,3 +593,160 @@ func Test_generateScanID(t *testing.T) {
 		})
 	}
 }
+
+func TestScanService_Notify(t *testing.T) {
+	tests := []struct {
+		name            string
+		callbackURL     string
+		createSBOMError bool
+		wantReports     int
+		wantVerdict     string
+	}{
+		{
+			name: "no callback URL",
+		},
+		{
+			name:        "scan success",
+			callbackURL: "http://operator:4002/v1/callback",
+			wantReports: 1,
+			wantVerdict: domain.VerdictSuccess,
+		},
+		{
+			name:            "scan error",
+			callbackURL:     "http://operator:4002/v1/callback",
+			createSBOMError: true,
+			wantReports:     1,
+			wantVerdict:     domain.VerdictError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := adapters.NewMockNotifier()
+			s := NewScanService(adapters.NewMockSBOMAdapter(tt.createSBOMError, false, false),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockCVEAdapter(),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockPlatform(),
+				false,
+				WithNotifier(notifier))
+			workload := domain.ScanCommand{
+				CallbackURL: tt.callbackURL,
+				ImageSlug:   "imageSlug",
+				ImageHash:   "k8s.gcr.io/kube-proxy@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+				Wlid:        "wlid://cluster-minikube/namespace-kube-system/daemonset-kube-proxy",
+			}
+			ctx, err := s.ValidateScanCVE(context.TODO(), workload)
+			tools.EnsureSetup(t, err == nil)
+			_ = s.ScanCVE(ctx)
+			reports := notifier.Reports()
+			assert.Len(t, reports, tt.wantReports)
+			if tt.wantReports > 0 {
+				assert.Equal(t, tt.wantVerdict, reports[0].Verdict)
+				assert.NotEmpty(t, reports[0].ScanID)
+			}
+		})
+	}
+}
+
+type panickingSBOMAdapter struct {
+	*adapters.MockSBOMAdapter
+}
+
+func (p panickingSBOMAdapter) CreateSBOM(context.Context, string, string, domain.RegistryOptions) (domain.SBOM, error) {
+	panic("malformed archive")
+}
+
+func TestScanService_RecoverPanic(t *testing.T) {
+	notifier := adapters.NewMockNotifier()
+	s := NewScanService(panickingSBOMAdapter{adapters.NewMockSBOMAdapter(false, false, false)},
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false,
+		WithNotifier(notifier))
+	workload := domain.ScanCommand{
+		CallbackURL: "http://operator:4002/v1/callback",
+		ImageSlug:   "imageSlug",
+		ImageHash:   "k8s.gcr.io/kube-proxy@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+		Wlid:        "wlid://cluster-minikube/namespace-kube-system/daemonset-kube-proxy",
+	}
+	ctx, err := s.ValidateGenerateSBOM(context.TODO(), workload)
+	tools.EnsureSetup(t, err == nil)
+	assert.ErrorIs(t, s.GenerateSBOM(ctx), domain.ErrPanic)
+	ctx, err = s.ValidateScanCVE(context.TODO(), workload)
+	tools.EnsureSetup(t, err == nil)
+	assert.ErrorIs(t, s.ScanCVE(ctx), domain.ErrPanic)
+	reports := notifier.Reports()
+	assert.Len(t, reports, 1)
+	assert.Equal(t, domain.VerdictError, reports[0].Verdict)
+}
+
+type unauthorizedSBOMAdapter struct {
+	*adapters.MockSBOMAdapter
+}
+
+func (u unauthorizedSBOMAdapter) CreateSBOM(context.Context, string, string, domain.RegistryOptions) (domain.SBOM, error) {
+	return domain.SBOM{}, &domain.RegistryAuthError{Registry: "harbor.example.com", Provider: domain.AuthProviderHarbor, Reason: domain.AuthReasonDenied, Err: errors.New("403 Forbidden")}
+}
+
+func TestScanService_Notify_authFailure(t *testing.T) {
+	notifier := adapters.NewMockNotifier()
+	s := NewScanService(unauthorizedSBOMAdapter{adapters.NewMockSBOMAdapter(false, false, false)},
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false,
+		WithNotifier(notifier))
+	workload := domain.ScanCommand{
+		CallbackURL: "http://operator:4002/v1/callback",
+		ImageSlug:   "imageSlug",
+		ImageHash:   "harbor.example.com/team-a/app@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+		Wlid:        "wlid://cluster-minikube/namespace-team-a/deployment-app",
+	}
+	ctx, err := s.ValidateScanCVE(context.TODO(), workload)
+	tools.EnsureSetup(t, err == nil)
+	_ = s.ScanCVE(ctx)
+	reports := notifier.Reports()
+	require.Len(t, reports, 1)
+	assert.Equal(t, domain.VerdictError, reports[0].Verdict)
+	assert.Equal(t, domain.AuthReasonDenied, reports[0].AuthFailure)
+	assert.Contains(t, reports[0].Error, "harbor.example.com using harbor credentials (denied)")
+}
+
+type unreachableSBOMAdapter struct {
+	*adapters.MockSBOMAdapter
+}
+
+func (u unreachableSBOMAdapter) CreateSBOM(context.Context, string, string, domain.RegistryOptions) (domain.SBOM, error) {
+	return domain.SBOM{}, &domain.RegistryPullError{Attempts: []domain.PullAttempt{
+		{Endpoint: "index.docker.io", Class: domain.PullErrorServer, Tries: 3, Err: errors.New("503 Service Unavailable")},
+		{Endpoint: "mirror.gcr.io", Class: domain.PullErrorTimeout, Tries: 3, Err: errors.New("i/o timeout")},
+	}}
+}
+
+func TestScanService_Notify_pullFailure(t *testing.T) {
+	notifier := adapters.NewMockNotifier()
+	s := NewScanService(unreachableSBOMAdapter{adapters.NewMockSBOMAdapter(false, false, false)},
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false,
+		WithNotifier(notifier))
+	workload := domain.ScanCommand{
+		CallbackURL: "http://operator:4002/v1/callback",
+		ImageSlug:   "imageSlug",
+		ImageHash:   "nginx@sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137",
+		Wlid:        "wlid://cluster-minikube/namespace-default/deployment-nginx",
+	}
+	ctx, err := s.ValidateScanCVE(context.TODO(), workload)
+	tools.EnsureSetup(t, err == nil)
+	_ = s.ScanCVE(ctx)
+	reports := notifier.Reports()
+	require.Len(t, reports, 1)
+	assert.Equal(t, domain.VerdictError, reports[0].Verdict)
+	assert.Equal(t, []string{"index.docker.io", "mirror.gcr.io"}, reports[0].PullEndpoints)
+	assert.Contains(t, reports[0].Error, "attempted index.docker.io (server, 3 tries), mirror.gcr.io (timeout, 3 tries): i/o timeout")
+}
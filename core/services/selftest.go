@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// selfTestName is the name of the SBOM of the reference image, it is never stored
+const selfTestName = "kubevuln-self-test"
+
+// WithSelfTest enables the self-test, scanning end-to-end the reference image provided by canary
+func WithSelfTest(canary ports.CanaryProvider) ScanServiceOption {
+	return func(s *ScanService) {
+		s.canary = canary
+	}
+}
+
+// SelfTest scans the reference image through the same scanners as the workloads, without storing nor submitting
+// anything, and reports the outcome of each stage; it stops at the first failed stage
+func (s *ScanService) SelfTest(ctx context.Context) (domain.SelfTestReport, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.SelfTest")
+	defer span.End()
+
+	if s.canary == nil {
+		return domain.SelfTestReport{}, domain.ErrNoSelfTest
+	}
+	var report domain.SelfTestReport
+	// run records the outcome of a stage and tells whether the self-test goes on
+	run := func(name string, stage func() error) bool {
+		start := time.Now()
+		err := stage()
+		result := domain.SelfTestStage{
+			Name:     name,
+			Success:  err == nil,
+			Duration: time.Since(start).Round(time.Millisecond).String(),
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		report.Stages = append(report.Stages, result)
+		return err == nil
+	}
+
+	var options domain.RegistryOptions
+	var sbom domain.SBOM
+	var cve domain.CVEManifest
+	report.Success = run(domain.SelfTestDatabase, func() error {
+		if !s.cveScanner.Ready(ctx) {
+			return domain.ErrInitVulnDB
+		}
+		return nil
+	}) && run(domain.SelfTestImage, func() (err error) {
+		report.ImageTag, options, err = s.canary.CanaryImage(ctx)
+		return err
+	}) && run(domain.SelfTestSBOM, func() (err error) {
+		sbom, err = s.sbomCreator.CreateSBOM(ctx, selfTestName, report.ImageTag, options)
+		switch {
+		case err != nil:
+			return err
+		case sbom.Status == instanceidhandler.Incomplete || sbom.Content == nil:
+			return domain.ErrIncompleteSBOM
+		}
+		report.Packages = len(sbom.Content.Packages)
+		return nil
+	}) && run(domain.SelfTestMatch, func() (err error) {
+		cve, err = s.scanSBOM(ctx, sbom)
+		if err != nil {
+			return err
+		}
+		if cve.Content == nil {
+			return errors.New("empty vulnerability manifest")
+		}
+		report.Vulnerabilities = len(cve.Content.Matches)
+		return nil
+	})
+
+	logger.L().Info("self-test done",
+		helpers.String("imageTag", report.ImageTag),
+		helpers.Interface("success", report.Success),
+		helpers.Int("packages", report.Packages),
+		helpers.Int("vulnerabilities", report.Vulnerabilities))
+	return report, nil
+}
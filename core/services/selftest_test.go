@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticCanary struct {
+	imageTag string
+	err      error
+}
+
+func (s staticCanary) CanaryImage(context.Context) (string, domain.RegistryOptions, error) {
+	return s.imageTag, domain.RegistryOptions{InsecureUseHTTP: true}, s.err
+}
+
+func TestScanService_SelfTest(t *testing.T) {
+	tests := []struct {
+		name        string
+		canary      *staticCanary
+		sbomError   bool
+		sbomTimeout bool
+		wantErr     error
+		wantSuccess bool
+		wantStages  []domain.SelfTestStage
+	}{
+		{
+			name:    "not enabled",
+			wantErr: domain.ErrNoSelfTest,
+		},
+		{
+			name:        "success",
+			canary:      &staticCanary{imageTag: "127.0.0.1:5000/kubevuln/canary:latest"},
+			wantSuccess: true,
+			wantStages: []domain.SelfTestStage{
+				{Name: domain.SelfTestDatabase, Success: true},
+				{Name: domain.SelfTestImage, Success: true},
+				{Name: domain.SelfTestSBOM, Success: true},
+				{Name: domain.SelfTestMatch, Success: true},
+			},
+		},
+		{
+			name:   "canary unavailable",
+			canary: &staticCanary{err: domain.ErrMockError},
+			wantStages: []domain.SelfTestStage{
+				{Name: domain.SelfTestDatabase, Success: true},
+				{Name: domain.SelfTestImage, Error: domain.ErrMockError.Error()},
+			},
+		},
+		{
+			name:      "SBOM creation error",
+			canary:    &staticCanary{imageTag: "127.0.0.1:5000/kubevuln/canary:latest"},
+			sbomError: true,
+			wantStages: []domain.SelfTestStage{
+				{Name: domain.SelfTestDatabase, Success: true},
+				{Name: domain.SelfTestImage, Success: true},
+				{Name: domain.SelfTestSBOM, Error: domain.ErrMockError.Error()},
+			},
+		},
+		{
+			name:        "incomplete SBOM",
+			canary:      &staticCanary{imageTag: "127.0.0.1:5000/kubevuln/canary:latest"},
+			sbomTimeout: true,
+			wantStages: []domain.SelfTestStage{
+				{Name: domain.SelfTestDatabase, Success: true},
+				{Name: domain.SelfTestImage, Success: true},
+				{Name: domain.SelfTestSBOM, Error: domain.ErrIncompleteSBOM.Error()},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []ScanServiceOption
+			if tt.canary != nil {
+				opts = append(opts, WithSelfTest(tt.canary))
+			}
+			s := NewScanService(adapters.NewMockSBOMAdapter(tt.sbomError, tt.sbomTimeout, false),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockCVEAdapter(),
+				repositories.NewMemoryStorage(false, false),
+				adapters.NewMockPlatform(),
+				false,
+				opts...)
+			report, err := s.SelfTest(context.TODO())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSuccess, report.Success)
+			for i := range report.Stages {
+				assert.NotEmpty(t, report.Stages[i].Duration)
+				report.Stages[i].Duration = ""
+			}
+			assert.Equal(t, tt.wantStages, report.Stages)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+)
+
+// WithSuppressions moves the matches of the given suppression rules out of the CVE manifests of the scans, into their
+// ignored matches along with the rules applied to them
+func WithSuppressions(suppressions []domain.Suppression) ScanServiceOption {
+	return func(s *ScanService) {
+		s.suppressions = suppressions
+	}
+}
+
+// WithIgnoreUnfixed moves the matches of the vulnerabilities without a fix available out of the CVE manifests of the
+// scans, like the suppression rules; since the manifests are matched again against each new vulnerabilities database,
+// the vulnerabilities resurface with the first scan matching them once a fix is published
+func WithIgnoreUnfixed() ScanServiceOption {
+	return func(s *ScanService) {
+		s.ignoreUnfixed = true
+	}
+}
+
+// suppress moves the suppressed matches of a CVE manifest into its ignored matches, the manifest is copied when
+// some are suppressed
+func (s *ScanService) suppress(ctx context.Context, cve domain.CVEManifest) domain.CVEManifest {
+	if (len(s.suppressions) == 0 && !s.ignoreUnfixed) || cve.Content == nil {
+		return cve
+	}
+	content := *cve.Content
+	content.Matches = make([]v1beta1.Match, 0, len(cve.Content.Matches))
+	content.IgnoredMatches = append([]v1beta1.IgnoredMatch{}, cve.Content.IgnoredMatches...)
+	for _, match := range cve.Content.Matches {
+		var rules []v1beta1.IgnoreRule
+		for _, suppression := range s.suppressions {
+			if suppresses(suppression, match) {
+				rules = append(rules, v1beta1.IgnoreRule{
+					Vulnerability: suppression.Vulnerability,
+					Package: &v1beta1.IgnoreRulePackage{
+						Name:     suppression.Package,
+						Location: suppression.Location,
+					},
+				})
+			}
+		}
+		if s.ignoreUnfixed && match.Vulnerability.Fix.State != domain.FixStateFixed {
+			fixState := match.Vulnerability.Fix.State
+			if fixState == "" {
+				fixState = domain.FixStateUnknown
+			}
+			rules = append(rules, v1beta1.IgnoreRule{FixState: fixState})
+		}
+		if len(rules) == 0 {
+			content.Matches = append(content.Matches, match)
+			continue
+		}
+		content.IgnoredMatches = append(content.IgnoredMatches, v1beta1.IgnoredMatch{Match: match, AppliedIgnoreRules: rules})
+	}
+	suppressed := len(cve.Content.Matches) - len(content.Matches)
+	if suppressed == 0 {
+		return cve
+	}
+	logger.L().Ctx(ctx).Debug("suppressed vulnerabilities",
+		helpers.String("name", cve.Name),
+		helpers.Int("suppressed", suppressed))
+	cve.Content = &content
+	return cve
+}
+
+// suppresses tells whether a suppression rule applies to a match, the location pattern applies to any location of
+// the package
+func suppresses(suppression domain.Suppression, match v1beta1.Match) bool {
+	if !globMatch(suppression.Vulnerability, match.Vulnerability.ID) || !globMatch(suppression.Package, match.Artifact.Name) {
+		return false
+	}
+	if suppression.Location == "" {
+		return true
+	}
+	for _, location := range match.Artifact.Locations {
+		if globMatch(suppression.Location, location.RealPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch matches a value against a glob pattern, an empty pattern matches anything and an invalid one nothing
+func globMatch(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, err := doublestar.Match(pattern, value)
+	return err == nil && matched
+}
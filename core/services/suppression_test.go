@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMatch returns a match of a vulnerability of a package found at the given paths
+func newMatch(id, name string, paths ...string) v1beta1.Match {
+	match := v1beta1.Match{}
+	match.Vulnerability.ID = id
+	match.Artifact.Name = name
+	for _, path := range paths {
+		match.Artifact.Locations = append(match.Artifact.Locations, v1beta1.SyftCoordinates{RealPath: path})
+	}
+	return match
+}
+
+func TestScanService_suppress(t *testing.T) {
+	textFixture := newMatch("CVE-2022-32149", "golang.org/x/text", "/usr/lib/test/fixtures/go.mod")
+	textBinary := newMatch("CVE-2022-32149", "golang.org/x/text", "/usr/bin/app")
+	netFixture := newMatch("GHSA-vvpx-j8f3-3w6h", "golang.org/x/net", "/usr/lib/test/go.mod")
+	openssl := newMatch("CVE-2023-0286", "openssl", "/lib/apk/db/installed")
+	cve := domain.CVEManifest{
+		Name:    "imageSlug",
+		Content: &v1beta1.GrypeDocument{Matches: []v1beta1.Match{textFixture, textBinary, netFixture, openssl}},
+	}
+	tests := []struct {
+		name         string
+		suppressions []domain.Suppression
+		wantMatches  []v1beta1.Match
+		wantIgnored  []v1beta1.IgnoredMatch
+	}{
+		{
+			name:        "no suppression",
+			wantMatches: []v1beta1.Match{textFixture, textBinary, netFixture, openssl},
+		},
+		{
+			name:         "CVE of a package under a path",
+			suppressions: []domain.Suppression{{Vulnerability: "CVE-*", Package: "golang.org/x/text", Location: "/usr/lib/test/**"}},
+			wantMatches:  []v1beta1.Match{textBinary, netFixture, openssl},
+			wantIgnored: []v1beta1.IgnoredMatch{
+				{Match: textFixture, AppliedIgnoreRules: []v1beta1.IgnoreRule{{Vulnerability: "CVE-*", Package: &v1beta1.IgnoreRulePackage{Name: "golang.org/x/text", Location: "/usr/lib/test/**"}}}},
+			},
+		},
+		{
+			name: "every rule applied is recorded",
+			suppressions: []domain.Suppression{
+				{Location: "/usr/lib/test/**"},
+				{Package: "golang.org/x/*"},
+				{Vulnerability: "CVE-2023-0286", Package: "libssl*"},
+			},
+			wantMatches: []v1beta1.Match{openssl},
+			wantIgnored: []v1beta1.IgnoredMatch{
+				{Match: textFixture, AppliedIgnoreRules: []v1beta1.IgnoreRule{{Package: &v1beta1.IgnoreRulePackage{Location: "/usr/lib/test/**"}}, {Package: &v1beta1.IgnoreRulePackage{Name: "golang.org/x/*"}}}},
+				{Match: textBinary, AppliedIgnoreRules: []v1beta1.IgnoreRule{{Package: &v1beta1.IgnoreRulePackage{Name: "golang.org/x/*"}}}},
+				{Match: netFixture, AppliedIgnoreRules: []v1beta1.IgnoreRule{{Package: &v1beta1.IgnoreRulePackage{Location: "/usr/lib/test/**"}}, {Package: &v1beta1.IgnoreRulePackage{Name: "golang.org/x/*"}}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &ScanService{}
+			WithSuppressions(tt.suppressions)(s)
+			got := s.suppress(context.TODO(), cve)
+			require.NotNil(t, got.Content)
+			assert.Equal(t, tt.wantMatches, got.Content.Matches)
+			assert.Equal(t, tt.wantIgnored, got.Content.IgnoredMatches)
+			// the manifest of the scanner is left untouched
+			assert.Len(t, cve.Content.Matches, 4)
+		})
+	}
+}
+
+func TestScanService_suppress_ignoreUnfixed(t *testing.T) {
+	unfixed := newSeverityMatch("CVE-2022-32149", "golang.org/x/text", domain.HighSeverity, "not-fixed")
+	wontFix := newSeverityMatch("CVE-2022-28391", "busybox", domain.MediumSeverity, "wont-fix")
+	unknown := newSeverityMatch("CVE-2023-0286", "openssl", domain.HighSeverity, "")
+	fixed := newSeverityMatch("CVE-2021-44228", "log4j-core", domain.CriticalSeverity, domain.FixStateFixed)
+	s := &ScanService{}
+	WithSuppressions([]domain.Suppression{{Package: "busybox"}})(s)
+	WithIgnoreUnfixed()(s)
+	got := s.suppress(context.TODO(), domain.CVEManifest{
+		Content: &v1beta1.GrypeDocument{Matches: []v1beta1.Match{unfixed, wontFix, unknown, fixed}},
+	})
+	assert.Equal(t, []v1beta1.Match{fixed}, got.Content.Matches)
+	assert.Equal(t, []v1beta1.IgnoredMatch{
+		{Match: unfixed, AppliedIgnoreRules: []v1beta1.IgnoreRule{{FixState: "not-fixed"}}},
+		{Match: wontFix, AppliedIgnoreRules: []v1beta1.IgnoreRule{{Package: &v1beta1.IgnoreRulePackage{Name: "busybox"}}, {FixState: "wont-fix"}}},
+		{Match: unknown, AppliedIgnoreRules: []v1beta1.IgnoreRule{{FixState: domain.FixStateUnknown}}},
+	}, got.Content.IgnoredMatches)
+
+	// the vulnerability resurfaces once a new database publishes its fix
+	unfixed.Vulnerability.Fix = v1beta1.Fix{State: domain.FixStateFixed, Versions: []string{"0.3.8"}}
+	got = s.suppress(context.TODO(), domain.CVEManifest{Content: &v1beta1.GrypeDocument{Matches: []v1beta1.Match{unfixed}}})
+	assert.Equal(t, []v1beta1.Match{unfixed}, got.Content.Matches)
+	assert.Empty(t, got.Content.IgnoredMatches)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	wlidpkg "github.com/armosec/utils-k8s-go/wlid"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+)
+
+// WithSeverityThresholds checks the completed scans against the first of the given policies matching the namespace of
+// their workload before notifying their verdict, labeler reads the labels of the namespaces for the policies with a
+// namespace selector
+func WithSeverityThresholds(thresholds []domain.SeverityThresholds, labeler ports.NamespaceLabeler) ScanServiceOption {
+	return func(s *ScanService) {
+		s.thresholds = thresholds
+		s.namespaceLabeler = labeler
+	}
+}
+
+// checkThresholds records the policy applied to a report, fails its verdict when its summary is above the fail
+// thresholds and adds the violations of the thresholds exceeded
+func (s *ScanService) checkThresholds(ctx context.Context, workload domain.ScanCommand, report *domain.ScanReport) {
+	policy, ok := s.thresholdsFor(ctx, workload.Wlid)
+	if !ok {
+		return
+	}
+	report.Policy = policy.Name
+	if exceedsThresholds(report.Summary, policy.Fail) {
+		report.Verdict = domain.VerdictFail
+		report.Violations = append(report.Violations, domain.PolicySeverityFail)
+	}
+	if exceedsThresholds(report.Summary, policy.Alert) {
+		report.Violations = append(report.Violations, domain.PolicySeverityAlert)
+	}
+}
+
+// thresholdsFor returns the first policy matching the namespace of a workload, the scans without a workload such as
+// the registry scans only match the policies selecting any namespace
+func (s *ScanService) thresholdsFor(ctx context.Context, wlid string) (domain.SeverityThresholds, bool) {
+	var namespace string
+	if wlid != "" {
+		namespace = wlidpkg.GetNamespaceFromWlid(wlid)
+	}
+	var labels map[string]string
+	labelsRead := false
+	for _, policy := range s.thresholds {
+		if len(policy.Namespaces) == 0 && len(policy.NamespaceSelector) == 0 {
+			return policy, true
+		}
+		if namespace == "" || (len(policy.Namespaces) > 0 && !matchesNamespace(policy.Namespaces, namespace)) {
+			continue
+		}
+		if len(policy.NamespaceSelector) > 0 {
+			if !labelsRead {
+				labels = s.namespaceLabels(ctx, namespace)
+				labelsRead = true
+			}
+			if !selectsLabels(policy.NamespaceSelector, labels) {
+				continue
+			}
+		}
+		return policy, true
+	}
+	return domain.SeverityThresholds{}, false
+}
+
+// namespaceLabels returns the labels of a namespace, none when they cannot be read so that the policies selecting
+// labels do not apply
+func (s *ScanService) namespaceLabels(ctx context.Context, namespace string) map[string]string {
+	if s.namespaceLabeler == nil {
+		return nil
+	}
+	labels, err := s.namespaceLabeler.NamespaceLabels(ctx, namespace)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to read the namespace labels of the severity thresholds", helpers.Error(err),
+			helpers.String("namespace", namespace))
+		return nil
+	}
+	return labels
+}
+
+// matchesNamespace tells whether a namespace matches one of the glob patterns
+func matchesNamespace(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := doublestar.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// selectsLabels tells whether the labels contain all the labels of the selector
+func selectsLabels(selector, labels map[string]string) bool {
+	for key, value := range selector {
+		if actual, ok := labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// exceedsThresholds tells whether a count of the severity summary is above its maximum, severities are compared
+// ignoring case since configuration keys are often lower-cased
+func exceedsThresholds(summary, thresholds map[string]int) bool {
+	for severity, count := range summary {
+		for thresholdSeverity, maximum := range thresholds {
+			if strings.EqualFold(severity, thresholdSeverity) && count > maximum {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// namespaceLabels is a NamespaceLabeler of fixed labels keyed by namespace
+type namespaceLabels map[string]map[string]string
+
+func (n namespaceLabels) NamespaceLabels(_ context.Context, namespace string) (map[string]string, error) {
+	labels, ok := n[namespace]
+	if !ok {
+		return nil, errors.New("namespace not found")
+	}
+	return labels, nil
+}
+
+func TestScanService_checkThresholds(t *testing.T) {
+	thresholds := []domain.SeverityThresholds{
+		{
+			Name:              "production",
+			NamespaceSelector: map[string]string{"env": "prod"},
+			Fail:              map[string]int{"critical": 0, "high": 5},
+			Alert:             map[string]int{"medium": 10},
+		},
+		{
+			Name:       "development",
+			Namespaces: []string{"dev-*"},
+			Fail:       map[string]int{"Critical": 10},
+		},
+		{
+			Name:  "default",
+			Alert: map[string]int{"Critical": 0},
+		},
+	}
+	labeler := namespaceLabels{
+		"payments": {"env": "prod"},
+		"dev-team": {"env": "dev"},
+	}
+	tests := []struct {
+		name           string
+		wlid           string
+		summary        map[string]int
+		wantPolicy     string
+		wantVerdict    string
+		wantViolations []string
+	}{
+		{
+			name:        "production below its thresholds",
+			wlid:        "wlid://cluster-minikube/namespace-payments/deployment-api",
+			summary:     map[string]int{domain.HighSeverity: 5, domain.MediumSeverity: 10},
+			wantPolicy:  "production",
+			wantVerdict: domain.VerdictSuccess,
+		},
+		{
+			name:           "production fails and alerts",
+			wlid:           "wlid://cluster-minikube/namespace-payments/deployment-api",
+			summary:        map[string]int{domain.CriticalSeverity: 1, domain.MediumSeverity: 11},
+			wantPolicy:     "production",
+			wantVerdict:    domain.VerdictFail,
+			wantViolations: []string{domain.PolicySeverityFail, domain.PolicySeverityAlert},
+		},
+		{
+			name:        "development is more lenient",
+			wlid:        "wlid://cluster-minikube/namespace-dev-team/deployment-api",
+			summary:     map[string]int{domain.CriticalSeverity: 3},
+			wantPolicy:  "development",
+			wantVerdict: domain.VerdictSuccess,
+		},
+		{
+			name:           "other namespaces fall back to the default",
+			wlid:           "wlid://cluster-minikube/namespace-unknown/deployment-api",
+			summary:        map[string]int{domain.CriticalSeverity: 1},
+			wantPolicy:     "default",
+			wantVerdict:    domain.VerdictSuccess,
+			wantViolations: []string{domain.PolicySeverityAlert},
+		},
+		{
+			name:        "registry scans only match the default",
+			summary:     map[string]int{domain.HighSeverity: 6},
+			wantPolicy:  "default",
+			wantVerdict: domain.VerdictSuccess,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &ScanService{}
+			WithSeverityThresholds(thresholds, labeler)(s)
+			report := domain.ScanReport{Verdict: domain.VerdictSuccess, Summary: tt.summary}
+			s.checkThresholds(context.TODO(), domain.ScanCommand{Wlid: tt.wlid}, &report)
+			assert.Equal(t, tt.wantPolicy, report.Policy)
+			assert.Equal(t, tt.wantVerdict, report.Verdict)
+			assert.Equal(t, tt.wantViolations, report.Violations)
+		})
+	}
+}
+
+func TestScanService_checkThresholds_noPolicy(t *testing.T) {
+	s := &ScanService{}
+	WithSeverityThresholds([]domain.SeverityThresholds{{Name: "production", Namespaces: []string{"prod"}, Fail: map[string]int{"critical": 0}}}, nil)(s)
+	report := domain.ScanReport{Verdict: domain.VerdictSuccess, Summary: map[string]int{domain.CriticalSeverity: 1}}
+	s.checkThresholds(context.TODO(), domain.ScanCommand{Wlid: "wlid://cluster-minikube/namespace-dev/deployment-api"}, &report)
+	assert.Equal(t, domain.ScanReport{Verdict: domain.VerdictSuccess, Summary: map[string]int{domain.CriticalSeverity: 1}}, report)
+}
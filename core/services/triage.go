@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// WithTriage enables the triage of the findings kept by repository: the acknowledged findings are flagged in the
+// reports, the snoozed ones are flagged and left out of the verdicts and notifications until their date
+func WithTriage(repository ports.TriageRepository) ScanServiceOption {
+	return func(s *ScanService) {
+		s.triage = repository
+	}
+}
+
+// AddTriage records the acknowledgement or the snooze of a finding of a workload, it applies to the following scans
+func (s *ScanService) AddTriage(ctx context.Context, triage domain.Triage) (domain.Triage, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.AddTriage")
+	defer span.End()
+
+	if s.triage == nil {
+		return domain.Triage{}, domain.ErrNoTriage
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	if triage.Wlid == "" || triage.Vulnerability == "" {
+		return domain.Triage{}, domain.ErrInvalidTriage
+	}
+	switch triage.State {
+	case domain.TriageAcknowledged:
+		triage.Until = nil
+	case domain.TriageSnoozed:
+		if triage.Until == nil || !triage.Until.After(now) {
+			return domain.Triage{}, domain.ErrInvalidTriage
+		}
+	default:
+		return domain.Triage{}, domain.ErrInvalidTriage
+	}
+	triage.ID = uuid.NewString()
+	triage.CreatedAt = now
+	if err := s.triage.StoreTriage(ctx, triage); err != nil {
+		return domain.Triage{}, err
+	}
+	logger.L().Info("triaged finding",
+		helpers.String("wlid", triage.Wlid),
+		helpers.String("vulnerability", triage.Vulnerability),
+		helpers.String("package", triage.Package),
+		helpers.String("state", triage.State))
+	return triage, nil
+}
+
+// DeleteTriage forgets the triage of a finding, the finding counts again with the following scans
+func (s *ScanService) DeleteTriage(ctx context.Context, id string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.DeleteTriage")
+	defer span.End()
+
+	if s.triage == nil {
+		return domain.ErrNoTriage
+	}
+	return s.triage.DeleteTriage(ctx, id)
+}
+
+// ListTriage returns the triage of the findings of the given workload, of all the workloads when empty, ordered by
+// creation time; the expired snoozes are listed until deleted
+func (s *ScanService) ListTriage(ctx context.Context, wlid string) ([]domain.Triage, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.ListTriage")
+	defer span.End()
+
+	if s.triage == nil {
+		return nil, domain.ErrNoTriage
+	}
+	triage, err := s.triage.ListTriage(ctx)
+	if err != nil || wlid == "" {
+		return triage, err
+	}
+	selected := make([]domain.Triage, 0, len(triage))
+	for _, t := range triage {
+		if t.Wlid == wlid {
+			selected = append(selected, t)
+		}
+	}
+	return selected, nil
+}
+
+// withTriage records the active triage of the workload applying to the matches of its CVE manifest, the manifest goes
+// without it when the triage cannot be read
+func (s *ScanService) withTriage(ctx context.Context, workload domain.ScanCommand, cve domain.CVEManifest) domain.CVEManifest {
+	if s.triage == nil || workload.Wlid == "" || cve.Content == nil {
+		return cve
+	}
+	triage, err := s.triage.ListTriage(ctx)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to list triage", helpers.Error(err),
+			helpers.String("wlid", workload.Wlid))
+		return cve
+	}
+	now := time.Now()
+	var applied []domain.Triage
+	for _, t := range triage {
+		if t.Wlid != workload.Wlid || !t.Active(now) {
+			continue
+		}
+		for _, match := range cve.Content.Matches {
+			if t.Matches(match) {
+				applied = append(applied, t)
+				break
+			}
+		}
+	}
+	cve.Triage = applied
+	return cve
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// triageList keeps the triage in memory, in insertion order
+type triageList struct {
+	triage []domain.Triage
+}
+
+func (l *triageList) DeleteTriage(_ context.Context, id string) error {
+	for i, triage := range l.triage {
+		if triage.ID == id {
+			l.triage = append(l.triage[:i], l.triage[i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrTriageNotFound
+}
+
+func (l *triageList) ListTriage(context.Context) ([]domain.Triage, error) {
+	return l.triage, nil
+}
+
+func (l *triageList) StoreTriage(_ context.Context, triage domain.Triage) error {
+	l.triage = append(l.triage, triage)
+	return nil
+}
+
+func TestScanService_triage(t *testing.T) {
+	const nginx = "wlid://cluster-minikube/namespace-default/deployment-nginx"
+	const redis = "wlid://cluster-minikube/namespace-default/deployment-redis"
+	ctx := context.TODO()
+	s := &ScanService{}
+	_, err := s.AddTriage(ctx, domain.Triage{Wlid: nginx, Vulnerability: "CVE-2021-44228", State: domain.TriageAcknowledged})
+	assert.ErrorIs(t, err, domain.ErrNoTriage)
+	_, err = s.ListTriage(ctx, "")
+	assert.ErrorIs(t, err, domain.ErrNoTriage)
+
+	WithTriage(&triageList{})(s)
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(24*time.Hour)
+	for _, invalid := range []domain.Triage{
+		{Vulnerability: "CVE-2021-44228", State: domain.TriageAcknowledged},
+		{Wlid: nginx, State: domain.TriageAcknowledged},
+		{Wlid: nginx, Vulnerability: "CVE-2021-44228", State: "ignored"},
+		{Wlid: nginx, Vulnerability: "CVE-2021-44228", State: domain.TriageSnoozed},
+		{Wlid: nginx, Vulnerability: "CVE-2021-44228", State: domain.TriageSnoozed, Until: &past},
+	} {
+		_, err = s.AddTriage(ctx, invalid)
+		assert.ErrorIs(t, err, domain.ErrInvalidTriage)
+	}
+	acknowledged, err := s.AddTriage(ctx, domain.Triage{Wlid: nginx, Vulnerability: "CVE-2021-44228", State: domain.TriageAcknowledged, Until: &future})
+	require.NoError(t, err)
+	assert.NotEmpty(t, acknowledged.ID)
+	assert.False(t, acknowledged.CreatedAt.IsZero())
+	assert.Nil(t, acknowledged.Until)
+	snoozed, err := s.AddTriage(ctx, domain.Triage{Wlid: nginx, Vulnerability: "CVE-2023-0286", Package: "openssl", State: domain.TriageSnoozed, Until: &future})
+	require.NoError(t, err)
+	other, err := s.AddTriage(ctx, domain.Triage{Wlid: redis, Vulnerability: "CVE-2022-37434", State: domain.TriageSnoozed, Until: &future})
+	require.NoError(t, err)
+	triage, err := s.ListTriage(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Triage{acknowledged, snoozed, other}, triage)
+	triage, err = s.ListTriage(ctx, nginx)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Triage{acknowledged, snoozed}, triage)
+
+	assert.NoError(t, s.DeleteTriage(ctx, other.ID))
+	assert.ErrorIs(t, s.DeleteTriage(ctx, other.ID), domain.ErrTriageNotFound)
+}
+
+func TestScanService_withTriage(t *testing.T) {
+	const wlid = "wlid://cluster-minikube/namespace-default/deployment-nginx"
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(24*time.Hour)
+	acknowledged := domain.Triage{ID: "1", Wlid: wlid, Vulnerability: "CVE-2023-0286", State: domain.TriageAcknowledged}
+	snoozed := domain.Triage{ID: "2", Wlid: wlid, Vulnerability: "CVE-2022-37434", Package: "zlib", State: domain.TriageSnoozed, Until: &future}
+	expired := domain.Triage{ID: "3", Wlid: wlid, Vulnerability: "CVE-2023-23914", State: domain.TriageSnoozed, Until: &past}
+	otherWorkload := domain.Triage{ID: "4", Wlid: "wlid://cluster-minikube/namespace-default/deployment-redis", Vulnerability: "CVE-2023-23914", State: domain.TriageSnoozed, Until: &future}
+	otherPackage := domain.Triage{ID: "5", Wlid: wlid, Vulnerability: "CVE-2022-37434", Package: "rsync", State: domain.TriageSnoozed, Until: &future}
+	s := &ScanService{}
+	WithTriage(&triageList{triage: []domain.Triage{acknowledged, snoozed, expired, otherWorkload, otherPackage}})(s)
+	cve := manifestWithFindings(
+		domain.Finding{ID: "CVE-2023-0286", Package: "openssl", Version: "3.0.7", Severity: "High"},
+		domain.Finding{ID: "CVE-2022-37434", Package: "zlib", Version: "1.2.12", Severity: "Critical"},
+		domain.Finding{ID: "CVE-2023-23914", Package: "curl", Version: "7.87.0", Severity: "Medium"},
+	)
+
+	cve = s.withTriage(context.TODO(), domain.ScanCommand{Wlid: wlid}, cve)
+	assert.Equal(t, []domain.Triage{acknowledged, snoozed}, cve.Triage)
+	// the snoozed findings are left out of the summary deciding the verdicts, and of the routed findings
+	assert.Equal(t, map[string]int{"High": 1, "Medium": 1}, summarizeSeverities(cve))
+	assert.Equal(t, 1, countSnoozed(cve))
+	assert.Len(t, sortedFindings(cve), 2)
+	// the scans without workload are not triaged
+	registry := manifestWithFindings(domain.Finding{ID: "CVE-2022-37434", Package: "zlib", Version: "1.2.12", Severity: "Critical"})
+	assert.Empty(t, s.withTriage(context.TODO(), domain.ScanCommand{}, registry).Triage)
+}
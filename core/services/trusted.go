@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// WithTrustedDigests skips the scans of the image digests of the allowlist, which are reported as trusted with the
+// version of the allowlist
+func WithTrustedDigests(allowlist domain.TrustedDigests) ScanServiceOption {
+	return func(s *ScanService) {
+		s.trustedDigests = make(map[string]bool, len(allowlist.Digests))
+		for _, trusted := range allowlist.Digests {
+			s.trustedDigests[trusted.Digest] = true
+		}
+		s.trustedVersion = allowlist.Version
+	}
+}
+
+// checkTrustedDigest returns domain.ErrTrustedDigest when the digest of the image is in the allowlist, after
+// reporting the scan as skipped with the version of the allowlist
+func (s *ScanService) checkTrustedDigest(ctx context.Context, workload domain.ScanCommand, imageHash string) (context.Context, error) {
+	digest := digestFromImageHash(imageHash)
+	if digest == "" || !s.trustedDigests[digest] {
+		return ctx, nil
+	}
+	ctx = context.WithValue(ctx, domain.TrustedDigestsVersionKey{}, s.trustedVersion)
+	logger.L().Info("scan skipped for trusted image digest",
+		helpers.String("wlid", workload.Wlid),
+		helpers.String("imageTag", workload.ImageTag),
+		helpers.String("digest", digest),
+		helpers.String("allowlistVersion", s.trustedVersion))
+	if err := s.platform.SendStatus(ctx, domain.Trusted); err != nil {
+		logger.L().Ctx(ctx).Error("telemetry error", helpers.Error(err))
+	}
+	return ctx, domain.ErrTrustedDigest
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanService_checkTrustedDigest(t *testing.T) {
+	trusted := "sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137"
+	other := "sha256:3cdf3b7b3c1a1e5b5f0e4f5ad1a0f7b6e4bd2e5ad4a5e3f3d7f1a0b5c2d4e6f8"
+	allowlist := domain.TrustedDigests{Version: "2023.04", Digests: []domain.TrustedDigest{{Digest: trusted}}}
+	newService := func(opts ...ScanServiceOption) *ScanService {
+		return NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+			repositories.NewMemoryStorage(false, false),
+			adapters.NewMockCVEAdapter(),
+			repositories.NewMemoryStorage(false, false),
+			adapters.NewMockPlatform(),
+			false, opts...)
+	}
+
+	t.Run("workload scans", func(t *testing.T) {
+		s := newService(WithTrustedDigests(allowlist))
+		ctx, err := s.ValidateScanCVE(context.TODO(), domain.ScanCommand{ImageSlug: "imageSlug", ImageHash: "golden/base@" + trusted})
+		assert.ErrorIs(t, err, domain.ErrTrustedDigest)
+		assert.Equal(t, "2023.04", ctx.Value(domain.TrustedDigestsVersionKey{}))
+		_, err = s.ValidateScanCVE(context.TODO(), domain.ScanCommand{ImageSlug: "imageSlug", ImageHash: other})
+		assert.NoError(t, err)
+		assert.Equal(t, "2023.04", s.Version(context.TODO()).TrustedDigestsVersion)
+	})
+
+	t.Run("registry scans by digest", func(t *testing.T) {
+		s := newService(WithTrustedDigests(allowlist))
+		_, err := s.ValidateScanRegistry(context.TODO(), domain.ScanCommand{ImageSlug: "imageSlug", ImageTag: "golden/base@" + trusted})
+		assert.ErrorIs(t, err, domain.ErrTrustedDigest)
+		_, err = s.ValidateScanRegistry(context.TODO(), domain.ScanCommand{ImageSlug: "imageSlug", ImageTag: "golden/base:1.0"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("without allowlist", func(t *testing.T) {
+		s := newService()
+		_, err := s.ValidateScanCVE(context.TODO(), domain.ScanCommand{ImageSlug: "imageSlug", ImageHash: trusted})
+		assert.NoError(t, err)
+		assert.Empty(t, s.Version(context.TODO()).TrustedDigestsVersion)
+	})
+}
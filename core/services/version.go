@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/k8s-interface/names"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"go.opentelemetry.io/otel"
+)
+
+// sbomCheck holds the dependencies of the SBOM compatibility check
+type sbomCheck struct {
+	repository     ports.ScanResultRepository
+	workloadLister ports.WorkloadLister
+}
+
+// WithSBOMCompatibilityCheck enables the check of the stored SBOMs against the current SBOM creator,
+// the SBOMs of the running images listed by workloadLister are generated again, workloadLister may be nil
+func WithSBOMCompatibilityCheck(repository ports.ScanResultRepository, workloadLister ports.WorkloadLister) ScanServiceOption {
+	return func(s *ScanService) {
+		s.sbomCheck = &sbomCheck{repository: repository, workloadLister: workloadLister}
+	}
+}
+
+// Version reports the versions of the scanners, of the libraries they depend on and of the vulnerabilities database
+func (s *ScanService) Version(ctx context.Context) domain.VersionInfo {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.Version")
+	defer span.End()
+
+	info := domain.VersionInfo{
+		Release:               s.release,
+		SBOMCreatorVersion:    s.sbomCreator.Version(),
+		CVEScannerVersion:     s.cveScanner.Version(ctx),
+		CVEDBVersion:          s.cveScanner.DBVersion(ctx),
+		Libraries:             tools.LibraryVersions(),
+		TrustedDigestsVersion: s.trustedVersion,
+	}
+	if built := s.cveScanner.DBBuilt(ctx); !built.IsZero() {
+		info.CVEDBBuilt = &built
+	}
+	return info
+}
+
+// withDBBuilt records the build date of the vulnerabilities database in the annotations of a CVE manifest
+func (s *ScanService) withDBBuilt(ctx context.Context, cve domain.CVEManifest) domain.CVEManifest {
+	built := s.cveScanner.DBBuilt(ctx)
+	if built.IsZero() {
+		return cve
+	}
+	annotations := make(map[string]string, len(cve.Annotations)+1)
+	for key, value := range cve.Annotations {
+		annotations[key] = value
+	}
+	annotations[domain.AnnotationCVEDBBuilt] = built.UTC().Format(time.RFC3339)
+	cve.Annotations = annotations
+	return cve
+}
+
+// CheckSBOMCompatibility deletes the stored SBOMs created by another version of the SBOM creator, which are never
+// reused, and returns the commands generating again the SBOMs of the running images among them
+func (s *ScanService) CheckSBOMCompatibility(ctx context.Context) (domain.SBOMCompatibilityReport, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ScanService.CheckSBOMCompatibility")
+	defer span.End()
+
+	if s.sbomCheck == nil {
+		return domain.SBOMCompatibilityReport{}, domain.ErrNoSBOMCheck
+	}
+	results, err := s.sbomCheck.repository.ListScanResults(ctx)
+	if err != nil {
+		return domain.SBOMCompatibilityReport{}, err
+	}
+	version := s.sbomCreator.Version()
+	var report domain.SBOMCompatibilityReport
+	var errs *multierror.Error
+	incompatible := map[string]bool{}
+	for _, result := range results {
+		if result.Kind != domain.StoredSBOM {
+			continue
+		}
+		report.Checked++
+		if s.isCompatibleSBOM(ctx, result, version) {
+			continue
+		}
+		report.Incompatible++
+		// the summary goes along with its SBOM
+		for _, kind := range []string{domain.StoredSBOM, domain.StoredSBOMSummary} {
+			stored := result
+			stored.Kind = kind
+			if err := s.sbomCheck.repository.DeleteScanResult(ctx, stored); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("failed to delete %s %s: %w", kind, result.Name, err))
+			}
+		}
+		if result.ImageSlug != "" {
+			incompatible[result.ImageSlug] = true
+		}
+	}
+	if len(incompatible) > 0 && s.sbomCheck.workloadLister != nil {
+		images, err := s.sbomCheck.workloadLister.ListWorkloadImages(ctx)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("failed to list running images: %w", err))
+		}
+		for _, image := range images {
+			slug, err := names.ImageInfoToSlug(image.ImageTag, image.ImageHash)
+			if err != nil || !incompatible[slug] {
+				continue
+			}
+			// each image is generated once
+			delete(incompatible, slug)
+			report.Regenerate = append(report.Regenerate, domain.ScanCommand{
+				ImageSlug:          slug,
+				ImageHash:          image.ImageHash,
+				ImageTag:           image.ImageTag,
+				ImageTagNormalized: image.ImageTag,
+			})
+		}
+	}
+	logger.L().Info("checked stored SBOMs compatibility",
+		helpers.String("sbomCreatorVersion", version),
+		helpers.Int("checked", report.Checked),
+		helpers.Int("incompatible", report.Incompatible),
+		helpers.Int("regenerate", len(report.Regenerate)))
+	return report, errs.ErrorOrNil()
+}
+
+// isCompatibleSBOM tells whether a stored SBOM was created by the given SBOM creator version, the SBOMs stored
+// without their version are read, incompatible ones are then discarded by the repository
+func (s *ScanService) isCompatibleSBOM(ctx context.Context, result domain.StoredResult, version string) bool {
+	if result.SBOMCreatorVersion != "" {
+		return result.SBOMCreatorVersion == version
+	}
+	sbom, err := s.sbomRepository.GetSBOM(ctx, result.Name, version)
+	if err != nil {
+		// keep what cannot be checked
+		return true
+	}
+	return sbom.Content != nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubescape/k8s-interface/names"
+	"github.com/kubescape/kubevuln/adapters"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/repositories"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanService_Version(t *testing.T) {
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false,
+		WithRelease("v0.2.0"))
+	info := s.Version(context.TODO())
+	assert.Equal(t, "v0.2.0", info.Release)
+	assert.Equal(t, "Mock SBOM 1.0", info.SBOMCreatorVersion)
+	assert.Equal(t, "Mock CVE 1.0", info.CVEScannerVersion)
+	assert.Equal(t, "v1.0.0", info.CVEDBVersion)
+	require.NotNil(t, info.CVEDBBuilt)
+	assert.Equal(t, time.Date(2023, 3, 24, 6, 54, 57, 0, time.UTC), info.CVEDBBuilt.UTC())
+	assert.NotEmpty(t, info.Libraries)
+}
+
+func TestScanService_withDBBuilt(t *testing.T) {
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		false)
+	annotations := map[string]string{"key": "value"}
+	cve := s.withDBBuilt(context.TODO(), domain.CVEManifest{Annotations: annotations})
+	assert.Equal(t, map[string]string{
+		"key":                       "value",
+		domain.AnnotationCVEDBBuilt: "2023-03-24T06:54:57Z",
+	}, cve.Annotations)
+	// the original annotations are left untouched
+	assert.Len(t, annotations, 1)
+}
+
+func TestScanService_CheckSBOMCompatibility(t *testing.T) {
+	running := "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"
+	runningSlug, err := names.ImageInfoToSlug("nginx:1.14.1", running)
+	require.NoError(t, err)
+	lister := staticWorkloadLister{
+		{Namespace: "default", Kind: "Deployment", Name: "nginx", ContainerName: "nginx", ImageTag: "nginx:1.14.1", ImageHash: running},
+	}
+	current := domain.StoredResult{Kind: domain.StoredSBOM, Name: "redis-slug", ImageSlug: "redis-slug", SBOMCreatorVersion: "Mock SBOM 1.0"}
+	outdated := domain.StoredResult{Kind: domain.StoredSBOM, Name: runningSlug, ImageSlug: runningSlug, SBOMCreatorVersion: "Mock SBOM 0.9"}
+	outdatedSummary := outdated
+	outdatedSummary.Kind = domain.StoredSBOMSummary
+	unversioned := domain.StoredResult{Kind: domain.StoredSBOM, Name: "alpine-slug", ImageSlug: "alpine-slug"}
+	unreadable := domain.StoredResult{Kind: domain.StoredSBOM, Name: "busybox-slug", ImageSlug: "busybox-slug"}
+	manifest := domain.StoredResult{Kind: domain.StoredVulnerabilityManifest, Name: "instance-id", ImageSlug: runningSlug}
+	repository := &fakeResultRepository{
+		results: []domain.StoredResult{current, outdated, outdatedSummary, unversioned, unreadable, manifest},
+	}
+	// only the SBOM of alpine can be read with the current SBOM creator version
+	sbomRepository := repositories.NewMemoryStorage(false, false)
+	require.NoError(t, sbomRepository.StoreSBOM(context.TODO(), domain.SBOM{
+		Name:               "alpine-slug",
+		SBOMCreatorVersion: "Mock SBOM 1.0",
+		Content:            &v1beta1.Document{},
+	}))
+	s := NewScanService(adapters.NewMockSBOMAdapter(false, false, false),
+		sbomRepository,
+		adapters.NewMockCVEAdapter(),
+		repositories.NewMemoryStorage(false, false),
+		adapters.NewMockPlatform(),
+		true)
+	_, err = s.CheckSBOMCompatibility(context.TODO())
+	assert.ErrorIs(t, err, domain.ErrNoSBOMCheck)
+
+	WithSBOMCompatibilityCheck(repository, lister)(s)
+	report, err := s.CheckSBOMCompatibility(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Checked)
+	assert.Equal(t, 2, report.Incompatible)
+	// only the running images are generated again
+	assert.Equal(t, []domain.ScanCommand{{
+		ImageSlug:          runningSlug,
+		ImageHash:          running,
+		ImageTag:           "nginx:1.14.1",
+		ImageTagNormalized: "nginx:1.14.1",
+	}}, report.Regenerate)
+	assert.ElementsMatch(t, []domain.StoredResult{current, unversioned, manifest}, repository.results)
+}
@@ -0,0 +1,32 @@
+package tools
+
+import (
+	"context"
+	"time"
+)
+
+// WithoutCancel returns a context carrying the values of ctx, such as the workload and the trace span, but neither
+// its deadline nor its cancellation, for the scans outliving the request submitting them
+func WithoutCancel(ctx context.Context) context.Context {
+	return withoutCancel{parent: ctx}
+}
+
+type withoutCancel struct {
+	parent context.Context
+}
+
+func (withoutCancel) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (withoutCancel) Done() <-chan struct{} {
+	return nil
+}
+
+func (withoutCancel) Err() error {
+	return nil
+}
+
+func (c withoutCancel) Value(key any) any {
+	return c.parent.Value(key)
+}
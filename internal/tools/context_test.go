@@ -0,0 +1,21 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithoutCancel(t *testing.T) {
+	parent, cancel := context.WithCancel(context.WithValue(context.TODO(), domain.ScanIDKey{}, "scanID"))
+	ctx := WithoutCancel(parent)
+	cancel()
+	assert.Error(t, parent.Err())
+	assert.NoError(t, ctx.Err())
+	assert.Nil(t, ctx.Done())
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+	assert.Equal(t, "scanID", ctx.Value(domain.ScanIDKey{}))
+}
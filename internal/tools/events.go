@@ -0,0 +1,14 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// RecordScanEvent records a detailed event of a scan to the function carried by ctx, if any
+func RecordScanEvent(ctx context.Context, message string, attributes map[string]string) {
+	if record, ok := ctx.Value(domain.ScanEventKey{}).(func(domain.ScanEvent)); ok {
+		record(domain.ScanEvent{Message: message, Attributes: attributes})
+	}
+}
@@ -0,0 +1,20 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordScanEvent(t *testing.T) {
+	// no recorder
+	RecordScanEvent(context.TODO(), "pull started", nil)
+	var recorded []domain.ScanEvent
+	ctx := context.WithValue(context.TODO(), domain.ScanEventKey{}, func(e domain.ScanEvent) {
+		recorded = append(recorded, e)
+	})
+	RecordScanEvent(ctx, "layer read", map[string]string{"layer": "1"})
+	assert.Equal(t, []domain.ScanEvent{{Message: "layer read", Attributes: map[string]string{"layer": "1"}}}, recorded)
+}
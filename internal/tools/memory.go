@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// cgroup v2 and v1 memory accounting files, relative to the cgroup mount point
+var (
+	cgroupV2Limit = "memory.max"
+	cgroupV2Usage = "memory.current"
+	cgroupV1Limit = filepath.Join("memory", "memory.limit_in_bytes")
+	cgroupV1Usage = filepath.Join("memory", "memory.usage_in_bytes")
+	cgroupV2Stat  = "memory.stat"
+	cgroupV1Stat  = filepath.Join("memory", "memory.stat")
+)
+
+// inactive file cache counters of memory.stat, reclaimed by the kernel before the container is OOM killed
+const (
+	cgroupV2InactiveFile = "inactive_file"
+	cgroupV1InactiveFile = "total_inactive_file"
+)
+
+// cgroupRoot is where the cgroup filesystem is mounted
+var cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupV1Unlimited is the value reported by cgroup v1 when no limit is set (rounded to the page size)
+const cgroupV1Unlimited = 1 << 62
+
+var ErrNoMemoryLimit = errors.New("no container memory limit")
+
+// MemoryLimit returns the container memory limit read from cgroups
+func MemoryLimit() (uint64, error) {
+	for _, file := range []string{cgroupV2Limit, cgroupV1Limit} {
+		value, err := readCgroupValue(file)
+		if err != nil {
+			continue
+		}
+		if value == 0 || value >= cgroupV1Unlimited {
+			return 0, ErrNoMemoryLimit
+		}
+		return value, nil
+	}
+	return 0, ErrNoMemoryLimit
+}
+
+// MemoryUsage returns the container working set read from cgroups, i.e. its usage without the inactive file cache
+// like the kubelet evicts on, falling back to the memory obtained by the Go runtime
+func MemoryUsage() (uint64, error) {
+	for _, cgroup := range []struct{ usage, stat, inactiveFile string }{
+		{cgroupV2Usage, cgroupV2Stat, cgroupV2InactiveFile},
+		{cgroupV1Usage, cgroupV1Stat, cgroupV1InactiveFile},
+	} {
+		value, err := readCgroupValue(cgroup.usage)
+		if err != nil {
+			continue
+		}
+		if inactive, err := readCgroupStat(cgroup.stat, cgroup.inactiveFile); err == nil {
+			if inactive >= value {
+				return 0, nil
+			}
+			value -= inactive
+		}
+		return value, nil
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.Sys - stats.HeapReleased, nil
+}
+
+// readCgroupValue parses a single value cgroup file, "max" is reported as unlimited
+func readCgroupValue(file string) (uint64, error) {
+	content, err := os.ReadFile(filepath.Join(cgroupRoot, file))
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(content))
+	if value == "max" {
+		return cgroupV1Unlimited, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// readCgroupStat parses the given counter of a memory.stat cgroup file
+func readCgroupStat(file, key string) (uint64, error) {
+	content, err := os.ReadFile(filepath.Join(cgroupRoot, file))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == key {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("no %s in %s", key, file)
+}
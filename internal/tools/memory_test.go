@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCgroupFile(t *testing.T, root, file, content string) {
+	path := filepath.Join(root, file)
+	EnsureSetup(t, os.MkdirAll(filepath.Dir(path), 0o755) == nil)
+	EnsureSetup(t, os.WriteFile(path, []byte(content), 0o644) == nil)
+}
+
+func TestMemoryLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		wantLimit uint64
+		wantUsage uint64
+		wantErr   bool
+	}{
+		{
+			name:      "cgroup v2",
+			files:     map[string]string{cgroupV2Limit: "536870912\n", cgroupV2Usage: "104857600\n"},
+			wantLimit: 536870912,
+			wantUsage: 104857600,
+		},
+		{
+			name: "cgroup v2 working set",
+			files: map[string]string{cgroupV2Limit: "536870912\n", cgroupV2Usage: "104857600\n",
+				cgroupV2Stat: "anon 52428800\nfile 52428800\ninactive_file 41943040\nactive_file 10485760\n"},
+			wantLimit: 536870912,
+			wantUsage: 62914560,
+		},
+		{
+			name:    "cgroup v2 unlimited",
+			files:   map[string]string{cgroupV2Limit: "max\n", cgroupV2Usage: "104857600\n"},
+			wantErr: true,
+		},
+		{
+			name:      "cgroup v1",
+			files:     map[string]string{cgroupV1Limit: "1073741824\n", cgroupV1Usage: "209715200\n"},
+			wantLimit: 1073741824,
+			wantUsage: 209715200,
+		},
+		{
+			name: "cgroup v1 working set",
+			files: map[string]string{cgroupV1Limit: "1073741824\n", cgroupV1Usage: "209715200\n",
+				cgroupV1Stat: "cache 104857600\ninactive_file 1048576\ntotal_inactive_file 104857600\n"},
+			wantLimit: 1073741824,
+			wantUsage: 104857600,
+		},
+		{
+			name:    "cgroup v1 unlimited",
+			files:   map[string]string{cgroupV1Limit: "9223372036854771712\n", cgroupV1Usage: "209715200\n"},
+			wantErr: true,
+		},
+		{
+			name:    "no cgroups",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cgroupRoot = t.TempDir()
+			defer func() { cgroupRoot = "/sys/fs/cgroup" }()
+			for file, content := range tt.files {
+				writeCgroupFile(t, cgroupRoot, file, content)
+			}
+			limit, err := MemoryLimit()
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantLimit, limit)
+			usage, err := MemoryUsage()
+			assert.NoError(t, err)
+			if tt.wantUsage != 0 {
+				assert.Equal(t, tt.wantUsage, usage)
+			} else {
+				assert.Positive(t, usage)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var panicCounter, _ = otel.Meter("").Int64Counter("kubevuln_scan_panics",
+	metric.WithDescription("Number of panics recovered during scans, by stack fingerprint"))
+
+// RecoverPanic converts a panic into an error wrapping domain.ErrPanic and records its stack fingerprint,
+// it must be deferred directly by the function to protect
+func RecoverPanic(ctx context.Context, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	fingerprint := StackFingerprint(stack)
+	if panicCounter != nil {
+		panicCounter.Add(ctx, 1, metric.WithAttributes(attribute.String("fingerprint", fingerprint)))
+	}
+	logger.L().Ctx(ctx).Error("recovered from panic",
+		helpers.String("panic", fmt.Sprint(r)),
+		helpers.String("fingerprint", fingerprint),
+		helpers.String("stack", string(stack)))
+	*err = fmt.Errorf("%w: %v (fingerprint %s)", domain.ErrPanic, r, fingerprint)
+}
+
+// StackFingerprint hashes the function names of the frames below the panic in a goroutine stack trace,
+// ignoring arguments, addresses and line numbers so that the same crash always gets the same fingerprint
+func StackFingerprint(stack []byte) string {
+	h := sha256.New()
+	belowPanic := false
+	for _, line := range strings.Split(string(stack), "\n") {
+		if line == "" || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "goroutine ") {
+			continue
+		}
+		if i := strings.LastIndex(line, "("); i > 0 {
+			line = line[:i]
+		}
+		if line == "panic" {
+			belowPanic = true
+			continue
+		}
+		if belowPanic {
+			h.Write([]byte(line + "\n"))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
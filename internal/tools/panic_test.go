@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func panicking(ctx context.Context, value any) (err error) {
+	defer RecoverPanic(ctx, &err)
+	if value != nil {
+		panic(value)
+	}
+	return nil
+}
+
+func TestRecoverPanic(t *testing.T) {
+	assert.NoError(t, panicking(context.TODO(), nil))
+	err := panicking(context.TODO(), "malformed archive")
+	assert.True(t, errors.Is(err, domain.ErrPanic))
+	assert.Contains(t, err.Error(), "malformed archive")
+}
+
+func TestStackFingerprint(t *testing.T) {
+	stack1 := []byte(`goroutine 7 [running]:
+runtime/debug.Stack()
+	/usr/local/go/src/runtime/debug/stack.go:24 +0x65
+github.com/kubescape/kubevuln/internal/tools.RecoverPanic({0x1, 0x2}, 0xc000)
+	/app/internal/tools/panic.go:29 +0x90
+panic({0x3, 0x4})
+	/usr/local/go/src/runtime/panic.go:884 +0x213
+github.com/anchore/syft/syft/pkg/cataloger.Catalog(0xc001)
+	/go/pkg/mod/syft/catalog.go:42 +0x11
+`)
+	stack2 := []byte(`goroutine 12 [running]:
+runtime/debug.Stack()
+	/usr/local/go/src/runtime/debug/stack.go:24 +0x65
+github.com/kubescape/kubevuln/internal/tools.RecoverPanic({0x9, 0x8}, 0xc999)
+	/app/internal/tools/panic.go:31 +0x92
+panic({0x7, 0x6})
+	/usr/local/go/src/runtime/panic.go:884 +0x213
+github.com/anchore/syft/syft/pkg/cataloger.Catalog(0xc123)
+	/go/pkg/mod/syft/catalog.go:43 +0x15
+`)
+	stack3 := []byte(`goroutine 12 [running]:
+panic({0x7, 0x6})
+	/usr/local/go/src/runtime/panic.go:884 +0x213
+github.com/anchore/grype/grype.FindVulnerabilities(0xc123)
+	/go/pkg/mod/grype/grype.go:43 +0x15
+`)
+	assert.Len(t, StackFingerprint(stack1), 12)
+	assert.Equal(t, StackFingerprint(stack1), StackFingerprint(stack2))
+	assert.NotEqual(t, StackFingerprint(stack1), StackFingerprint(stack3))
+}
@@ -0,0 +1,14 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// ReportProgress reports the progress of a scan stage to the function carried by ctx, if any
+func ReportProgress(ctx context.Context, progress domain.Progress) {
+	if report, ok := ctx.Value(domain.ProgressKey{}).(func(domain.Progress)); ok {
+		report(progress)
+	}
+}
@@ -0,0 +1,20 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportProgress(t *testing.T) {
+	// no reporter
+	ReportProgress(context.TODO(), domain.Progress{Stage: domain.StagePull})
+	var reported []domain.Progress
+	ctx := context.WithValue(context.TODO(), domain.ProgressKey{}, func(p domain.Progress) {
+		reported = append(reported, p)
+	})
+	ReportProgress(ctx, domain.Progress{Stage: domain.StagePull, Unit: domain.ProgressBytes, Current: 1, Total: 2})
+	assert.Equal(t, []domain.Progress{{Stage: domain.StagePull, Unit: domain.ProgressBytes, Current: 1, Total: 2}}, reported)
+}
@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ScratchDirs are the writable directories used at runtime, so that kubevuln can run with a read-only root filesystem
+type ScratchDirs struct {
+	// DB holds the vulnerabilities database
+	DB string
+	// Profiles holds the profiles captured from the scans
+	Profiles string
+	// Temp is the temporary directory of the process, used by Grype to download the database
+	Temp string
+	// Workspaces holds the per-scan workspaces, where images are pulled
+	Workspaces string
+}
+
+// NewScratchDirs lays out the scratch directories on the writable volume mounted at root
+func NewScratchDirs(root string) ScratchDirs {
+	return ScratchDirs{
+		DB:         filepath.Join(root, "grype", "db"),
+		Profiles:   filepath.Join(root, "profiles"),
+		Temp:       filepath.Join(root, "tmp"),
+		Workspaces: filepath.Join(root, "workspaces"),
+	}
+}
+
+// Prepare creates the scratch directories, verifies that the current user can write to them,
+// and points the temporary directory of the process to Temp
+func (s ScratchDirs) Prepare() error {
+	var errs []error
+	for _, dir := range []string{s.DB, s.Profiles, s.Temp, s.Workspaces} {
+		errs = append(errs, CheckWritable(dir))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	return os.Setenv("TMPDIR", s.Temp)
+}
+
+// CheckWritable creates the directory if needed and verifies that the current user can create files in it
+func CheckWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("directory %s cannot be created by uid %d: %w", dir, os.Getuid(), err)
+	}
+	probe, err := os.CreateTemp(dir, ".probe-")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable by uid %d: %w", dir, os.Getuid(), err)
+	}
+	_ = probe.Close()
+	return os.Remove(probe.Name())
+}
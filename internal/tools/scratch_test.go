@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScratchDirs_Prepare(t *testing.T) {
+	t.Setenv("TMPDIR", os.TempDir())
+	root := t.TempDir()
+	s := NewScratchDirs(root)
+	assert.NoError(t, s.Prepare())
+	assert.Equal(t, filepath.Join(root, "tmp"), os.TempDir())
+	for _, dir := range []string{s.DB, s.Profiles, s.Temp, s.Workspaces} {
+		info, err := os.Stat(dir)
+		assert.NoError(t, err)
+		assert.True(t, info.IsDir())
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Empty(t, entries, "probe files must be removed")
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	assert.NoError(t, CheckWritable(t.TempDir()))
+	assert.NoError(t, CheckWritable(filepath.Join(t.TempDir(), "scratch")))
+	// a file mounted where a directory is expected
+	file := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, os.WriteFile(file, nil, 0o600))
+	assert.Error(t, CheckWritable(file))
+	assert.Error(t, CheckWritable(filepath.Join(file, "scratch")))
+}
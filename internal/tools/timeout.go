@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// RunWithTimeout runs fn within the timeout budget of the given pipeline stage, a zero timeout runs fn inline.
+// When the budget is exceeded, the returned error wraps domain.ErrStageTimeout and names the stage; when ctx is done
+// first, its own error is returned right away. In both cases fn keeps running in the background until it honors the
+// cancelled context.
+func RunWithTimeout[T any](ctx context.Context, stage string, timeout time.Duration, fn func(context.Context) (T, error)) (value T, err error) {
+	if timeout == 0 {
+		defer RecoverPanic(ctx, &err)
+		return fn(ctx)
+	}
+	stageCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var r result
+		defer func() { done <- r }()
+		defer RecoverPanic(stageCtx, &r.err)
+		r.value, r.err = fn(stageCtx)
+	}()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-stageCtx.Done():
+		var zero T
+		// the deadline or the cancellation of ctx is not a timeout of the stage
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		return zero, fmt.Errorf("%w: %s stage exceeded %s", domain.ErrStageTimeout, stage, timeout)
+	}
+}
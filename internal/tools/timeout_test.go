@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunWithTimeout(t *testing.T) {
+	slow := func(ctx context.Context) (string, error) {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(time.Second):
+			return "done", nil
+		}
+	}
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		fn      func(context.Context) (string, error)
+		want    string
+		wantErr error
+	}{
+		{
+			name: "no budget",
+			fn:   slow,
+			want: "done",
+		},
+		{
+			name:    "within budget",
+			timeout: 5 * time.Second,
+			fn:      slow,
+			want:    "done",
+		},
+		{
+			name:    "budget exceeded",
+			timeout: 10 * time.Millisecond,
+			fn:      slow,
+			wantErr: domain.ErrStageTimeout,
+		},
+		{
+			name:    "error is returned",
+			timeout: 5 * time.Second,
+			fn: func(context.Context) (string, error) {
+				return "", domain.ErrMockError
+			},
+			wantErr: domain.ErrMockError,
+		},
+		{
+			name: "panic is recovered without budget",
+			fn: func(context.Context) (string, error) {
+				panic("malformed archive")
+			},
+			wantErr: domain.ErrPanic,
+		},
+		{
+			name:    "panic is recovered",
+			timeout: 5 * time.Second,
+			fn: func(context.Context) (string, error) {
+				panic("malformed archive")
+			},
+			wantErr: domain.ErrPanic,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RunWithTimeout(context.TODO(), domain.StageMatch, tt.timeout, tt.fn)
+			assert.True(t, errors.Is(err, tt.wantErr), err)
+			assert.Equal(t, tt.want, got)
+			if tt.wantErr == domain.ErrStageTimeout {
+				assert.Contains(t, err.Error(), domain.StageMatch)
+			}
+		})
+	}
+}
+
+func TestRunWithTimeout_cancelled(t *testing.T) {
+	// without budget the stage runs inline and is only aborted by honoring ctx
+	ctx, cancel := context.WithCancel(context.TODO())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err := RunWithTimeout(ctx, domain.StageMatch, 0, func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	// the cancellation of ctx aborts the stage within its budget, without being reported as a timeout of the stage
+	ctx, cancel = context.WithCancel(context.TODO())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = RunWithTimeout(ctx, domain.StageMatch, time.Minute, func(context.Context) (string, error) {
+		time.Sleep(time.Minute)
+		return "done", nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, domain.ErrStageTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+	// neither is the deadline of ctx
+	ctx, cancel = context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	_, err = RunWithTimeout(ctx, domain.StageMatch, time.Minute, func(context.Context) (string, error) {
+		time.Sleep(time.Minute)
+		return "done", nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, domain.ErrStageTimeout)
+}
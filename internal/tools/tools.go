@@ -6,10 +6,12 @@ import (
 	"path"
 	"regexp"
 	"runtime/debug"
+	"strings"
 	"testing"
 
 	"github.com/aquilax/truncate"
 	"github.com/distribution/distribution/reference"
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
 	"github.com/kubescape/kubevuln/core/domain"
 	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
@@ -33,6 +35,22 @@ func PackageVersion(name string) string {
 	return "unknown"
 }
 
+// libraries are the modules the scans depend on, besides the scanners themselves
+var libraries = []string{
+	"github.com/anchore/stereoscope",
+	"github.com/google/go-containerregistry",
+	"github.com/kubescape/storage",
+}
+
+// LibraryVersions returns the versions of the libraries the scans depend on, keyed by module
+func LibraryVersions() map[string]string {
+	versions := make(map[string]string, len(libraries))
+	for _, library := range libraries {
+		versions[library] = PackageVersion(library)
+	}
+	return versions
+}
+
 var offendingChars = regexp.MustCompile("[@:/ ._]")
 
 func sanitize(s string) string {
@@ -113,3 +131,17 @@ func NormalizeReference(ref string) string {
 	}
 	return n.String()
 }
+
+// NormalizeRegistry qualifies a registry host, optionally followed by a repository path, the same way image
+// references are, so that "docker.io" matches the images of Docker Hub
+func NormalizeRegistry(registry string) string {
+	registry = strings.TrimSuffix(strings.TrimSpace(registry), "/")
+	host, repository, _ := strings.Cut(registry, "/")
+	if r, err := name.NewRegistry(host); err == nil {
+		host = r.RegistryStr()
+	}
+	if repository == "" {
+		return host
+	}
+	return host + "/" + repository
+}
@@ -15,6 +15,12 @@ func TestPackageVersion(t *testing.T) {
 	assert.True(t, PackageVersion("github.com/anchore/syft") == "unknown") // only works on compiled binaries
 }
 
+func TestLibraryVersions(t *testing.T) {
+	versions := LibraryVersions()
+	assert.Len(t, versions, len(libraries))
+	assert.Contains(t, versions, "github.com/kubescape/storage")
+}
+
 func TestLabelsFromImageID(t *testing.T) {
 	tests := []struct {
 		imageID string
@@ -147,3 +153,20 @@ func TestNormalizeReference(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeRegistry(t *testing.T) {
+	tests := []struct {
+		registry string
+		want     string
+	}{
+		{registry: "docker.io", want: "index.docker.io"},
+		{registry: "docker.io/library/", want: "index.docker.io/library"},
+		{registry: " quay.io/kubescape ", want: "quay.io/kubescape"},
+		{registry: "harbor.example.com:8443/team-a", want: "harbor.example.com:8443/team-a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.registry, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeRegistry(tt.registry))
+		})
+	}
+}
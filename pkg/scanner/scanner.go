@@ -0,0 +1,167 @@
+// Package scanner runs the scan pipeline of kubevuln in-process: it creates the SBOM of a container image and matches
+// it against the vulnerability database, without the HTTP API, the storage nor the backend.
+//
+// The package follows semantic versioning: the functions, the types and the fields of Config and Options are only
+// removed or changed incompatibly in a new major version of the module. SBOM and CVEManifest are the documents of
+// kubevuln, their contents are the SPDX and Grype documents of the storage API.
+package scanner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v1 "github.com/kubescape/kubevuln/adapters/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/services"
+)
+
+// default values of Config
+const (
+	DefaultListingURL   = "https://toolbox-data.anchore.io/grype/databases/listing.json"
+	DefaultMaxImageSize = 512 * 1024 * 1024
+	DefaultScanTimeout  = 5 * time.Minute
+)
+
+// SBOM is the software bill of materials of an image
+type SBOM = domain.SBOM
+
+// CVEManifest is the vulnerability manifest of an image
+type CVEManifest = domain.CVEManifest
+
+// PackageOverride corrects the identity of the packages of the SBOMs before matching them
+type PackageOverride = v1.PackageOverride
+
+// Config configures a Scanner, the zero values select the defaults
+type Config struct {
+	// DBCACertFile is a PEM file of the CA certificates trusted to download the listing and the vulnerability
+	// database, such as the CA of an internal artifact server
+	DBCACertFile string
+	// DBChecksumPolicy is "download" (default) to verify the checksum of the vulnerability database when it is
+	// downloaded, or "always" to also verify it each time it is loaded
+	DBChecksumPolicy string
+	// DBMaxAge refuses the vulnerability databases built longer ago, any age is accepted by default
+	DBMaxAge time.Duration
+	// DBRootDir is the directory holding the vulnerability database, the user cache directory by default
+	DBRootDir string
+	// DBURLRewrites rewrites the download URLs of the listing starting with one of its keys by replacing it with its
+	// value, such as a proxy cache of the upstream databases
+	DBURLRewrites map[string]string
+	// ListingURL is the listing of the vulnerability databases, DefaultListingURL by default
+	ListingURL string
+	// MatchExplanations records why each package matched its vulnerabilities in CVEManifest.Explanations
+	MatchExplanations bool
+	// MaxImageSize is the size in bytes above which the SBOMs are incomplete and not scanned, DefaultMaxImageSize by
+	// default
+	MaxImageSize int64
+	// PackageOverrides corrects the identity of the internal, forked or renamed packages, the first override
+	// selecting a package applies
+	PackageOverrides []PackageOverride
+	// ScanProfile is "full" (default) or "fast" for OS-only scans
+	ScanProfile string
+	// ScanTimeout bounds the creation of an SBOM, DefaultScanTimeout by default
+	ScanTimeout time.Duration
+}
+
+// Credentials authenticate to a registry with a username and a password, a token or a base64 encoded authority
+type Credentials struct {
+	Authority string
+	Password  string
+	Token     string
+	Username  string
+}
+
+// Options configures the pull of an image
+type Options struct {
+	Credentials           []Credentials
+	InsecureSkipTLSVerify bool
+	InsecureUseHTTP       bool
+	// Platform selects the image of a multi-platform index, such as "linux/arm64", the architecture of the program by
+	// default
+	Platform string
+}
+
+// Scanner scans container images, it is safe for concurrent use
+type Scanner struct {
+	cveScanner *v1.GrypeAdapter
+	service    *services.ScanService
+}
+
+// New initializes a Scanner, the vulnerability database is downloaded on the first scan
+func New(config Config) *Scanner {
+	if config.ListingURL == "" {
+		config.ListingURL = DefaultListingURL
+	}
+	if config.MaxImageSize == 0 {
+		config.MaxImageSize = DefaultMaxImageSize
+	}
+	if config.ScanTimeout == 0 {
+		config.ScanTimeout = DefaultScanTimeout
+	}
+	var syftOptions []v1.SyftAdapterOption
+	if config.ScanProfile != "" {
+		syftOptions = append(syftOptions, v1.WithScanProfile(config.ScanProfile))
+	}
+	grypeOptions := []v1.GrypeAdapterOption{
+		v1.WithDBCACert(config.DBCACertFile),
+		v1.WithDBChecksumPolicy(config.DBChecksumPolicy),
+		v1.WithDBMaxAge(config.DBMaxAge),
+		v1.WithDBURLRewrites(config.DBURLRewrites),
+	}
+	if config.DBRootDir != "" {
+		grypeOptions = append(grypeOptions, v1.WithDBRootDir(config.DBRootDir))
+	}
+	if len(config.PackageOverrides) > 0 {
+		grypeOptions = append(grypeOptions, v1.WithPackageOverrides(config.PackageOverrides))
+	}
+	sbomAdapter := v1.NewSyftAdapter(config.ScanTimeout, config.MaxImageSize, syftOptions...)
+	cveAdapter := v1.NewGrypeAdapter(config.ListingURL, grypeOptions...)
+	serviceOptions := []services.ScanServiceOption{services.WithImageResolver(sbomAdapter)}
+	if config.MatchExplanations {
+		serviceOptions = append(serviceOptions, services.WithMatchExplanations())
+	}
+	return &Scanner{
+		cveScanner: cveAdapter,
+		service:    services.NewScanService(sbomAdapter, nil, cveAdapter, nil, nil, false, serviceOptions...),
+	}
+}
+
+// Scan pulls an image, creates its SBOM and scans it for vulnerabilities, the vulnerability database is updated
+// when older than a day
+func (s *Scanner) Scan(ctx context.Context, image string, opts Options) (SBOM, CVEManifest, error) {
+	if err := s.cveScanner.LoadDB(ctx); err != nil {
+		return SBOM{}, CVEManifest{}, err
+	}
+	return s.service.ScanImage(ctx, image, opts.registryOptions())
+}
+
+// registryOptions converts the options to the domain ones
+func (o Options) registryOptions() domain.RegistryOptions {
+	options := domain.RegistryOptions{
+		InsecureSkipTLSVerify: o.InsecureSkipTLSVerify,
+		InsecureUseHTTP:       o.InsecureUseHTTP,
+		Platform:              o.Platform,
+	}
+	for _, credentials := range o.Credentials {
+		options.Credentials = append(options.Credentials, domain.RegistryCredentials{
+			Authority: credentials.Authority,
+			Password:  credentials.Password,
+			Token:     credentials.Token,
+			Username:  credentials.Username,
+		})
+	}
+	return options
+}
+
+var (
+	defaultScanner     *Scanner
+	defaultScannerOnce sync.Once
+)
+
+// Scan scans an image with a Scanner of the default Config, shared by all the calls
+func Scan(ctx context.Context, image string, opts Options) (SBOM, CVEManifest, error) {
+	defaultScannerOnce.Do(func() {
+		defaultScanner = New(Config{})
+	})
+	return defaultScanner.Scan(ctx, image, opts)
+}
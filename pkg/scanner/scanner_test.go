@@ -0,0 +1,35 @@
+package scanner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptions_registryOptions(t *testing.T) {
+	opts := Options{
+		Credentials: []Credentials{
+			{Username: "robot", Password: "secret"},
+			{Token: "token"},
+		},
+		InsecureSkipTLSVerify: true,
+		Platform:              "linux/arm64",
+	}
+	assert.Equal(t, domain.RegistryOptions{
+		Credentials: []domain.RegistryCredentials{
+			{Username: "robot", Password: "secret"},
+			{Token: "token"},
+		},
+		InsecureSkipTLSVerify: true,
+		Platform:              "linux/arm64",
+	}, opts.registryOptions())
+}
+
+func TestScanner_Scan(t *testing.T) {
+	// the errors of the vulnerability database are returned to the caller
+	s := New(Config{DBRootDir: t.TempDir(), ListingURL: "http://127.0.0.1:1/listing.json"})
+	_, _, err := s.Scan(context.TODO(), "nginx:1.14.1", Options{})
+	assert.Error(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/armosec/utils-k8s-go/wlid"
@@ -34,14 +35,18 @@ const (
 type APIServerStore struct {
 	StorageClient spdxv1beta1.SpdxV1beta1Interface
 	Namespace     string
+	compression   Compression
+	encoding      Encoding
 }
 
 var _ ports.CVERepository = (*APIServerStore)(nil)
 
 var _ ports.SBOMRepository = (*APIServerStore)(nil)
 
+var _ ports.SBOMMigrationRepository = (*APIServerStore)(nil)
+
 // NewAPIServerStorage initializes the APIServerStore struct
-func NewAPIServerStorage(namespace string) (*APIServerStore, error) {
+func NewAPIServerStorage(namespace string, opts ...APIServerStoreOption) (*APIServerStore, error) {
 	config, err := rest.InClusterConfig()
 	if err != nil {
 		return nil, err
@@ -50,17 +55,25 @@ func NewAPIServerStorage(namespace string) (*APIServerStore, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &APIServerStore{
+	a := &APIServerStore{
 		StorageClient: clientset.SpdxV1beta1(),
 		Namespace:     namespace,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a, nil
 }
 
-func NewFakeAPIServerStorage(namespace string) *APIServerStore {
-	return &APIServerStore{
+func NewFakeAPIServerStorage(namespace string, opts ...APIServerStoreOption) *APIServerStore {
+	a := &APIServerStore{
 		StorageClient: fake.NewSimpleClientset().SpdxV1beta1(),
 		Namespace:     namespace,
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
 func (a *APIServerStore) GetCVE(ctx context.Context, name, SBOMCreatorVersion, CVEScannerVersion, CVEDBVersion string) (domain.CVEManifest, error) {
@@ -92,11 +105,17 @@ func (a *APIServerStore) GetCVE(ctx context.Context, name, SBOMCreatorVersion, C
 			helpers.String("wanted DB version", CVEDBVersion))
 		return domain.CVEManifest{}, nil
 	}
+	annotations, err := unpackContent(manifest.Annotations, &manifest.Spec.Payload)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to decode CVE manifest from apiserver", helpers.Error(err),
+			helpers.String("name", name))
+		return domain.CVEManifest{}, nil
+	}
 	logger.L().Debug("got CVE manifest from storage",
 		helpers.String("name", name))
 	return domain.CVEManifest{
 		Name:               name,
-		Annotations:        manifest.Annotations,
+		Annotations:        annotations,
 		Labels:             manifest.Labels,
 		SBOMCreatorVersion: SBOMCreatorVersion,
 		CVEScannerVersion:  CVEScannerVersion,
@@ -127,7 +146,7 @@ func (a *APIServerStore) StoreCVE(ctx context.Context, cve domain.CVEManifest, w
 	manifest := v1beta1.VulnerabilityManifest{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        cve.Name,
-			Annotations: cve.Annotations,
+			Annotations: withImageSlug(ctx, cve.Annotations),
 			Labels:      cve.Labels,
 		},
 		Spec: v1beta1.VulnerabilityManifestSpec{
@@ -142,7 +161,10 @@ func (a *APIServerStore) StoreCVE(ctx context.Context, cve domain.CVEManifest, w
 		},
 	}
 	if cve.Content != nil {
-		manifest.Spec.Payload = *cve.Content
+		var packed bool
+		if manifest.Annotations, packed = a.packContent(cve.Name, manifest.Annotations, cve.Content); !packed {
+			manifest.Spec.Payload = *cve.Content
+		}
 	}
 	_, err := a.StorageClient.VulnerabilityManifests(a.Namespace).Create(context.Background(), &manifest, metav1.CreateOptions{})
 	switch {
@@ -257,6 +279,40 @@ func parseSeverities(cve domain.CVEManifest, cvep domain.CVEManifest, withReleva
 	}
 }
 
+// withImageSlug returns a copy of the annotations recording the image of the scanned workload,
+// so that the garbage collection can attribute results named after an instance ID, the workload and the digest of
+// the image are only recorded for the scans of a workload, the other results are never collected
+func withImageSlug(ctx context.Context, annotations map[string]string) map[string]string {
+	workload, ok := ctx.Value(domain.WorkloadKey{}).(domain.ScanCommand)
+	if !ok || workload.ImageSlug == "" {
+		return annotations
+	}
+	enriched := make(map[string]string, len(annotations)+4)
+	for key, value := range annotations {
+		enriched[key] = value
+	}
+	enriched[domain.AnnotationImageSlug] = workload.ImageSlug
+	if workload.Wlid != "" {
+		enriched[v1.WlidMetadataKey] = workload.Wlid
+		enriched[v1.ContainerNameMetadataKey] = workload.ContainerName
+		if digest := imageDigestOf(workload.ImageHash); digest != "" {
+			enriched[domain.AnnotationImageDigest] = digest
+		}
+	}
+	return enriched
+}
+
+// imageDigestOf returns the digest of an image hash, which is either a digest or a reference pinned by digest
+func imageDigestOf(imageHash string) string {
+	if i := strings.LastIndex(imageHash, "@"); i != -1 {
+		return imageHash[i+1:]
+	}
+	if strings.HasPrefix(imageHash, "sha256:") {
+		return imageHash
+	}
+	return ""
+}
+
 func enrichSummaryManifestObjectAnnotations(ctx context.Context, annotations map[string]string) (map[string]string, error) {
 	if annotations == nil {
 		annotations = make(map[string]string)
@@ -429,9 +485,15 @@ func (a *APIServerStore) GetSBOM(ctx context.Context, name, SBOMCreatorVersion s
 			helpers.String("wanted scanner version", SBOMCreatorVersion))
 		return domain.SBOM{}, nil
 	}
+	annotations, err := unpackContent(manifest.Annotations, &manifest.Spec.SPDX)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to decode SBOM from apiserver", helpers.Error(err),
+			helpers.String("name", name))
+		return domain.SBOM{}, nil
+	}
 	result := domain.SBOM{
 		Name:               name,
-		Annotations:        manifest.Annotations,
+		Annotations:        annotations,
 		Labels:             manifest.Labels,
 		SBOMCreatorVersion: SBOMCreatorVersion,
 		Content:            &manifest.Spec.SPDX,
@@ -475,9 +537,15 @@ func (a *APIServerStore) GetSBOMp(ctx context.Context, name, SBOMCreatorVersion
 			helpers.String("name", name))
 		return domain.SBOM{}, nil
 	}
+	annotations, err := unpackContent(manifest.Annotations, &manifest.Spec.SPDX)
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("failed to decode SBOM from apiserver", helpers.Error(err),
+			helpers.String("name", name))
+		return domain.SBOM{}, nil
+	}
 	result := domain.SBOM{
 		Name:               name,
-		Annotations:        manifest.Annotations,
+		Annotations:        annotations,
 		Labels:             manifest.Labels,
 		SBOMCreatorVersion: SBOMCreatorVersion,
 		Content:            &manifest.Spec.SPDX,
@@ -501,7 +569,7 @@ func (a *APIServerStore) storeSBOMWithContent(ctx context.Context, sbom domain.S
 	manifest := v1beta1.SBOMSPDXv2p3{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        sbom.Name,
-			Annotations: sbom.Annotations,
+			Annotations: withImageSlug(ctx, sbom.Annotations),
 			Labels:      sbom.Labels,
 		},
 		Spec: v1beta1.SBOMSPDXv2p3Spec{
@@ -515,7 +583,10 @@ func (a *APIServerStore) storeSBOMWithContent(ctx context.Context, sbom domain.S
 		Status: v1beta1.SBOMSPDXv2p3Status{}, // TODO move timeout information here
 	}
 	if sbom.Content != nil {
-		manifest.Spec.SPDX = *sbom.Content
+		var packed bool
+		if manifest.Annotations, packed = a.packContent(sbom.Name, manifest.Annotations, sbom.Content); !packed {
+			manifest.Spec.SPDX = *sbom.Content
+		}
 		created, err := time.Parse(time.RFC3339, sbom.Content.CreationInfo.Created)
 		if err != nil {
 			manifest.Spec.Metadata.Report.CreatedAt.Time = created
@@ -525,6 +596,10 @@ func (a *APIServerStore) storeSBOMWithContent(ctx context.Context, sbom domain.S
 		manifest.Annotations = map[string]string{}
 	}
 	manifest.Annotations[instanceidhandler.StatusMetadataKey] = sbom.Status // for the moment stored as an annotation
+	if sbom.SBOMCreatorVersion != "" {
+		manifest.Annotations[domain.AnnotationSBOMCreatorVersion] = sbom.SBOMCreatorVersion
+	}
+	manifest.Annotations[domain.AnnotationSBOMSchemaVersion] = strconv.Itoa(domain.SBOMSchemaVersion)
 	_, err := a.StorageClient.SBOMSPDXv2p3s(a.Namespace).Create(context.Background(), &manifest, metav1.CreateOptions{})
 	switch {
 	case errors.IsAlreadyExists(err):
@@ -551,7 +626,7 @@ func (a *APIServerStore) storeSBOMWithoutContent(ctx context.Context, sbom domai
 	manifest := v1beta1.SBOMSummary{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        sbom.Name,
-			Annotations: sbom.Annotations,
+			Annotations: withImageSlug(ctx, sbom.Annotations),
 			Labels:      sbom.Labels,
 		},
 		Spec:   v1beta1.SBOMSummarySpec{},
@@ -561,6 +636,10 @@ func (a *APIServerStore) storeSBOMWithoutContent(ctx context.Context, sbom domai
 		manifest.Annotations = map[string]string{}
 	}
 	manifest.Annotations[instanceidhandler.StatusMetadataKey] = sbom.Status // for the moment stored as an annotation
+	if sbom.SBOMCreatorVersion != "" {
+		manifest.Annotations[domain.AnnotationSBOMCreatorVersion] = sbom.SBOMCreatorVersion
+	}
+	manifest.Annotations[domain.AnnotationSBOMSchemaVersion] = strconv.Itoa(domain.SBOMSchemaVersion)
 	_, err := a.StorageClient.SBOMSummaries(a.Namespace).Create(context.Background(), &manifest, metav1.CreateOptions{})
 	switch {
 	case errors.IsAlreadyExists(err):
@@ -576,6 +655,62 @@ func (a *APIServerStore) storeSBOMWithoutContent(ctx context.Context, sbom domai
 	return nil
 }
 
+// UpdateSBOM replaces the annotations and the content of a stored SBOM, and the annotations of its summary
+func (a *APIServerStore) UpdateSBOM(ctx context.Context, sbom domain.SBOM) error {
+	_, span := otel.Tracer("").Start(ctx, "APIServerStore.UpdateSBOM")
+	defer span.End()
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// retrieve the latest version before attempting update
+		manifest, err := a.StorageClient.SBOMSPDXv2p3s(a.Namespace).Get(context.Background(), sbom.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		encoded, hasEncoded := manifest.Annotations[domain.AnnotationEncodedContent]
+		manifest.Annotations = sbom.Annotations
+		switch {
+		case sbom.Content == nil && hasEncoded:
+			// keep the stored content, which the annotations read back no longer carry
+			manifest.Annotations = make(map[string]string, len(sbom.Annotations)+1)
+			for key, value := range sbom.Annotations {
+				manifest.Annotations[key] = value
+			}
+			manifest.Annotations[domain.AnnotationEncodedContent] = encoded
+		case sbom.Content != nil:
+			var packed bool
+			if manifest.Annotations, packed = a.packContent(sbom.Name, manifest.Annotations, sbom.Content); packed {
+				manifest.Spec.SPDX = v1beta1.Document{}
+			} else {
+				manifest.Spec.SPDX = *sbom.Content
+			}
+		}
+		_, err = a.StorageClient.SBOMSPDXv2p3s(a.Namespace).Update(context.Background(), manifest, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update SBOM manifest: %w", err)
+	}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		summary, err := a.StorageClient.SBOMSummaries(a.Namespace).Get(context.Background(), sbom.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		summary.Annotations = sbom.Annotations
+		_, err = a.StorageClient.SBOMSummaries(a.Namespace).Update(context.Background(), summary, metav1.UpdateOptions{})
+		return err
+	})
+	switch {
+	case errors.IsNotFound(err):
+		logger.L().Debug("SBOM summary manifest not found in storage",
+			helpers.String("name", sbom.Name))
+	case err != nil:
+		return fmt.Errorf("failed to update SBOM summary manifest: %w", err)
+	}
+	logger.L().Debug("updated SBOM in storage",
+		helpers.String("name", sbom.Name))
+	return nil
+}
+
 func (a *APIServerStore) StoreSBOM(ctx context.Context, sbom domain.SBOM) error {
 	innerCtx, span := otel.Tracer("").Start(ctx, "APIServerStore.StoreSBOM")
 	defer span.End()
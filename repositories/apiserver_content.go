@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/kubevuln/core/domain"
+)
+
+// maxEncodedContentSize keeps the encoded contents within the annotations size limit of the API server,
+// larger contents are stored in the spec as usual
+const maxEncodedContentSize = 200 << 10
+
+// APIServerStoreOption configures optional behaviors of the APIServerStore
+type APIServerStoreOption func(*APIServerStore)
+
+// WithContentCompression stores the contents of the SBOMs and vulnerability manifests compressed with the given
+// algorithm in the AnnotationEncodedContent annotation, they are transparently decompressed on read
+func WithContentCompression(compression Compression) APIServerStoreOption {
+	return func(a *APIServerStore) {
+		a.compression = compression
+	}
+}
+
+// WithContentEncoding stores the contents of the SBOMs and vulnerability manifests serialized with the given encoding
+// in the AnnotationEncodedContent annotation, the encoding is detected on read
+func WithContentEncoding(encoding Encoding) APIServerStoreOption {
+	return func(a *APIServerStore) {
+		a.encoding = encoding
+	}
+}
+
+// encodesContents tells whether contents are stored in the AnnotationEncodedContent annotation
+func (a *APIServerStore) encodesContents() bool {
+	return a.compression != CompressionNone || (a.encoding != "" && a.encoding != EncodingJSON)
+}
+
+// packContent returns a copy of the annotations carrying the encoded content, and whether the content was packed,
+// the caller then leaves the spec empty
+func (a *APIServerStore) packContent(name string, annotations map[string]string, content interface{}) (map[string]string, bool) {
+	if !a.encodesContents() {
+		return annotations, false
+	}
+	data, err := encodeContent(a.encoding, a.compression, content)
+	if err != nil {
+		logger.L().Warning("failed to encode content, storing it as is", helpers.Error(err),
+			helpers.String("name", name))
+		return annotations, false
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	if len(encoded) > maxEncodedContentSize {
+		logger.L().Debug("encoded content too large for an annotation, storing it as is",
+			helpers.String("name", name),
+			helpers.Int("size", len(encoded)))
+		return annotations, false
+	}
+	packed := make(map[string]string, len(annotations)+1)
+	for key, value := range annotations {
+		packed[key] = value
+	}
+	packed[domain.AnnotationEncodedContent] = encoded
+	return packed, true
+}
+
+// unpackContent decodes the content carried by the annotations into content, and returns a copy of the annotations
+// without it, annotations without an encoded content are returned as is
+func unpackContent(annotations map[string]string, content interface{}) (map[string]string, error) {
+	encoded, ok := annotations[domain.AnnotationEncodedContent]
+	if !ok {
+		return annotations, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode content: %w", err)
+	}
+	if err := decodeContent(data, content); err != nil {
+		return nil, fmt.Errorf("failed to decode content: %w", err)
+	}
+	unpacked := make(map[string]string, len(annotations)-1)
+	for key, value := range annotations {
+		if key != domain.AnnotationEncodedContent {
+			unpacked[key] = value
+		}
+	}
+	return unpacked, nil
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAPIServerStore_contentCompression(t *testing.T) {
+	ctx := context.TODO()
+	a := NewFakeAPIServerStorage("kubescape", WithContentCompression(CompressionZstd))
+
+	sbom := domain.SBOM{
+		Name:               "nginx-slug",
+		SBOMCreatorVersion: "v0.76.0",
+		Annotations:        map[string]string{"key": "value"},
+		Content:            tools.FileToSBOM("testdata/alpine-sbom.json"),
+	}
+	require.NoError(t, a.StoreSBOM(ctx, sbom))
+	manifest, err := a.StorageClient.SBOMSPDXv2p3s("kubescape").Get(ctx, "nginx-slug", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, manifest.Annotations[domain.AnnotationEncodedContent])
+	assert.Equal(t, v1beta1.Document{}, manifest.Spec.SPDX)
+	got, err := a.GetSBOM(ctx, "nginx-slug", "v0.76.0")
+	require.NoError(t, err)
+	assert.Equal(t, sbom.Content, got.Content)
+	assert.NotContains(t, got.Annotations, domain.AnnotationEncodedContent)
+	assert.Equal(t, "value", got.Annotations["key"])
+
+	// updating the annotations only keeps the stored content
+	got.Content = nil
+	got.Annotations[domain.AnnotationSBOMSchemaVersion] = "3"
+	require.NoError(t, a.UpdateSBOM(ctx, got))
+	updated, err := a.GetSBOM(ctx, "nginx-slug", "v0.76.0")
+	require.NoError(t, err)
+	assert.Equal(t, sbom.Content, updated.Content)
+	assert.Equal(t, "3", updated.Annotations[domain.AnnotationSBOMSchemaVersion])
+
+	cve := domain.CVEManifest{
+		Name:    "nginx-slug",
+		Content: &v1beta1.GrypeDocument{Matches: []v1beta1.Match{{Vulnerability: v1beta1.Vulnerability{VulnerabilityMetadata: v1beta1.VulnerabilityMetadata{ID: "CVE-2023-1234"}}}}},
+	}
+	require.NoError(t, a.StoreCVE(ctx, cve, false))
+	gotCVE, err := a.GetCVE(ctx, "nginx-slug", "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, cve.Content, gotCVE.Content)
+	assert.NotContains(t, gotCVE.Annotations, domain.AnnotationEncodedContent)
+}
+
+func TestAPIServerStore_contentEncoding(t *testing.T) {
+	ctx := context.TODO()
+	a := NewFakeAPIServerStorage("kubescape", WithContentEncoding(EncodingCBOR))
+	sbom := domain.SBOM{
+		Name:               "nginx-slug",
+		SBOMCreatorVersion: "v0.76.0",
+		Content:            &v1beta1.Document{CreationInfo: &v1beta1.CreationInfo{}, Packages: []*v1beta1.Package{{PackageName: "openssl"}}},
+	}
+	require.NoError(t, a.StoreSBOM(ctx, sbom))
+	manifest, err := a.StorageClient.SBOMSPDXv2p3s("kubescape").Get(ctx, "nginx-slug", metav1.GetOptions{})
+	require.NoError(t, err)
+	data, err := base64.StdEncoding.DecodeString(manifest.Annotations[domain.AnnotationEncodedContent])
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(data, cborMagic))
+	got, err := a.GetSBOM(ctx, "nginx-slug", "v0.76.0")
+	require.NoError(t, err)
+	assert.Equal(t, sbom.Content, got.Content)
+
+	// JSON contents are stored in the spec as usual
+	a = NewFakeAPIServerStorage("kubescape", WithContentEncoding(EncodingJSON))
+	require.NoError(t, a.StoreSBOM(ctx, sbom))
+	manifest, err = a.StorageClient.SBOMSPDXv2p3s("kubescape").Get(ctx, "nginx-slug", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, manifest.Annotations, domain.AnnotationEncodedContent)
+}
+
+func TestAPIServerStore_contentCompression_tooLarge(t *testing.T) {
+	ctx := context.TODO()
+	a := NewFakeAPIServerStorage("kubescape", WithContentCompression(CompressionGzip))
+	// random data does not compress, the content is too large for an annotation
+	random := make([]byte, maxEncodedContentSize)
+	_, err := rand.Read(random)
+	require.NoError(t, err)
+	cve := domain.CVEManifest{Name: "nginx-slug", Content: &v1beta1.GrypeDocument{
+		Descriptor: v1beta1.Descriptor{Name: hex.EncodeToString(random)},
+	}}
+	require.NoError(t, a.StoreCVE(ctx, cve, false))
+	manifest, err := a.StorageClient.VulnerabilityManifests("kubescape").Get(ctx, "nginx-slug", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotContains(t, manifest.Annotations, domain.AnnotationEncodedContent)
+	assert.Equal(t, *cve.Content, manifest.Spec.Payload)
+}
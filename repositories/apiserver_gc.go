@@ -0,0 +1,228 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/armosec/utils-k8s-go/wlid"
+	v1 "github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// listPageSize bounds the number of manifests held in memory while listing them
+const listPageSize = 100
+
+var _ ports.ScanResultRepository = (*APIServerStore)(nil)
+var _ ports.ScanTimeRepository = (*APIServerStore)(nil)
+
+// listPages calls list with the continue token of the previous page until all pages are read
+func listPages(list func(opts metav1.ListOptions) (string, error)) error {
+	opts := metav1.ListOptions{Limit: listPageSize}
+	for {
+		next, err := list(opts)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		opts.Continue = next
+	}
+}
+
+// imageSlugOf attributes a stored result to its image, results named after an instance ID
+// are only attributed by the annotation recorded when storing them
+func imageSlugOf(meta metav1.ObjectMeta, namedBySlug bool) string {
+	if slug := meta.Annotations[domain.AnnotationImageSlug]; slug != "" {
+		return slug
+	}
+	if namedBySlug {
+		return meta.Name
+	}
+	return ""
+}
+
+// attributeToWorkload sets the image digest and the workload container recorded on a stored image result
+func attributeToWorkload(result domain.StoredResult, annotations map[string]string) domain.StoredResult {
+	result.ImageDigest = annotations[domain.AnnotationImageDigest]
+	if workloadID := annotations[v1.WlidMetadataKey]; workloadID != "" {
+		result.WorkloadNamespace = wlid.GetNamespaceFromWlid(workloadID)
+		result.WorkloadKind = wlid.GetKindFromWlid(workloadID)
+		result.WorkloadName = wlid.GetNameFromWlid(workloadID)
+		result.ContainerName = annotations[v1.ContainerNameMetadataKey]
+	}
+	return result
+}
+
+// ListScanTimes returns when the images of the workloads were last scanned, by digest, from the last update of their
+// stored vulnerability manifests
+func (a *APIServerStore) ListScanTimes(ctx context.Context) (map[string]time.Time, error) {
+	_, span := otel.Tracer("").Start(ctx, "APIServerStore.ListScanTimes")
+	defer span.End()
+
+	scanTimes := map[string]time.Time{}
+	err := listPages(func(opts metav1.ListOptions) (string, error) {
+		list, err := a.StorageClient.VulnerabilityManifests(a.Namespace).List(context.Background(), opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to list vulnerability manifests: %w", err)
+		}
+		for _, item := range list.Items {
+			digest := item.Annotations[domain.AnnotationImageDigest]
+			if digest == "" {
+				continue
+			}
+			if updated := lastUpdate(item.ObjectMeta); updated.After(scanTimes[digest]) {
+				scanTimes[digest] = updated
+			}
+		}
+		return list.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scanTimes, nil
+}
+
+// lastUpdate returns when an object was created or last updated by any of its managers
+func lastUpdate(meta metav1.ObjectMeta) time.Time {
+	updated := meta.CreationTimestamp.Time
+	for _, field := range meta.ManagedFields {
+		if field.Time != nil && field.Time.After(updated) {
+			updated = field.Time.Time
+		}
+	}
+	return updated
+}
+
+// ListScanResults lists the SBOMs, vulnerability manifests and their summaries stored by kubevuln,
+// SBOMs are listed through their summaries to avoid reading their contents, with the SBOM creator version
+// recorded on the summaries
+func (a *APIServerStore) ListScanResults(ctx context.Context) ([]domain.StoredResult, error) {
+	_, span := otel.Tracer("").Start(ctx, "APIServerStore.ListScanResults")
+	defer span.End()
+
+	var results []domain.StoredResult
+	err := listPages(func(opts metav1.ListOptions) (string, error) {
+		list, err := a.StorageClient.SBOMSummaries(a.Namespace).List(context.Background(), opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to list SBOM summaries: %w", err)
+		}
+		for _, item := range list.Items {
+			slug := imageSlugOf(item.ObjectMeta, true)
+			version := item.Annotations[domain.AnnotationSBOMCreatorVersion]
+			schema := item.Annotations[domain.AnnotationSBOMSchemaVersion]
+			results = append(results,
+				attributeToWorkload(domain.StoredResult{Kind: domain.StoredSBOM, Namespace: a.Namespace, Name: item.Name, ImageSlug: slug, SBOMCreatorVersion: version, SBOMSchemaVersion: schema}, item.Annotations),
+				attributeToWorkload(domain.StoredResult{Kind: domain.StoredSBOMSummary, Namespace: a.Namespace, Name: item.Name, ImageSlug: slug, SBOMCreatorVersion: version, SBOMSchemaVersion: schema}, item.Annotations))
+		}
+		return list.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = listPages(func(opts metav1.ListOptions) (string, error) {
+		list, err := a.StorageClient.VulnerabilityManifests(a.Namespace).List(context.Background(), opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to list vulnerability manifests: %w", err)
+		}
+		for _, item := range list.Items {
+			namedBySlug := item.Labels[v1.ContextMetadataKey] != v1.ContextMetadataKeyFiltered
+			results = append(results, attributeToWorkload(domain.StoredResult{
+				Kind:      domain.StoredVulnerabilityManifest,
+				Namespace: a.Namespace,
+				Name:      item.Name,
+				ImageSlug: imageSlugOf(item.ObjectMeta, namedBySlug),
+			}, item.Annotations))
+		}
+		return list.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// summaries are stored in the namespace of their workload
+	err = listPages(func(opts metav1.ListOptions) (string, error) {
+		list, err := a.StorageClient.VulnerabilityManifestSummaries("").List(context.Background(), opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to list vulnerability manifest summaries: %w", err)
+		}
+		for _, item := range list.Items {
+			results = append(results, domain.StoredResult{
+				Kind:              domain.StoredVulnerabilitySummary,
+				Namespace:         item.Namespace,
+				Name:              item.Name,
+				WorkloadNamespace: item.Labels[v1.NamespaceMetadataKey],
+				WorkloadKind:      item.Labels[v1.KindMetadataKey],
+				WorkloadName:      item.Labels[v1.NameMetadataKey],
+				ContainerName:     item.Labels[v1.ContainerNameMetadataKey],
+			})
+		}
+		return list.Continue, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// DeleteScanResult deletes a stored result, results already deleted are ignored
+func (a *APIServerStore) DeleteScanResult(ctx context.Context, result domain.StoredResult) error {
+	_, span := otel.Tracer("").Start(ctx, "APIServerStore.DeleteScanResult")
+	defer span.End()
+
+	var err error
+	switch result.Kind {
+	case domain.StoredSBOM:
+		err = a.StorageClient.SBOMSPDXv2p3s(result.Namespace).Delete(context.Background(), result.Name, metav1.DeleteOptions{})
+	case domain.StoredSBOMSummary:
+		err = a.StorageClient.SBOMSummaries(result.Namespace).Delete(context.Background(), result.Name, metav1.DeleteOptions{})
+	case domain.StoredVulnerabilityManifest:
+		err = a.StorageClient.VulnerabilityManifests(result.Namespace).Delete(context.Background(), result.Name, metav1.DeleteOptions{})
+	case domain.StoredVulnerabilitySummary:
+		err = a.StorageClient.VulnerabilityManifestSummaries(result.Namespace).Delete(context.Background(), result.Name, metav1.DeleteOptions{})
+	default:
+		return fmt.Errorf("unknown scan result kind %q", result.Kind)
+	}
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// MarkOrphaned annotates the vulnerability manifest summaries of a deleted workload with the deletion time,
+// they are deleted later by the garbage collection
+func (a *APIServerStore) MarkOrphaned(ctx context.Context, namespace, kind, name string) error {
+	_, span := otel.Tracer("").Start(ctx, "APIServerStore.MarkOrphaned")
+	defer span.End()
+
+	selector := labels.SelectorFromSet(labels.Set{
+		v1.NamespaceMetadataKey: namespace,
+		v1.KindMetadataKey:      kind,
+		v1.NameMetadataKey:      name,
+	}).String()
+	orphanedAt := time.Now().UTC().Format(time.RFC3339)
+	return listPages(func(opts metav1.ListOptions) (string, error) {
+		opts.LabelSelector = selector
+		list, err := a.StorageClient.VulnerabilityManifestSummaries(namespace).List(context.Background(), opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to list vulnerability manifest summaries: %w", err)
+		}
+		for i := range list.Items {
+			summary := &list.Items[i]
+			if summary.Annotations == nil {
+				summary.Annotations = map[string]string{}
+			}
+			summary.Annotations[domain.AnnotationOrphaned] = orphanedAt
+			_, err := a.StorageClient.VulnerabilityManifestSummaries(namespace).Update(context.Background(), summary, metav1.UpdateOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return "", fmt.Errorf("failed to mark vulnerability manifest summary %s as orphaned: %w", summary.Name, err)
+			}
+		}
+		return list.Continue, nil
+	})
+}
@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/internal/tools"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAPIServerStore_ListScanResults(t *testing.T) {
+	ctx := context.WithValue(context.TODO(), domain.WorkloadKey{}, domain.ScanCommand{
+		ImageSlug:     "nginx-slug",
+		ImageHash:     "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7",
+		Wlid:          "wlid://cluster-minikube/namespace-default/deployment-nginx",
+		ContainerName: "nginx",
+	})
+	a := NewFakeAPIServerStorage("kubescape")
+	require.NoError(t, a.StoreSBOM(ctx, domain.SBOM{Name: "nginx-slug", Content: tools.FileToSBOM("testdata/alpine-sbom.json")}))
+	cve := domain.CVEManifest{Name: "nginx-slug", Content: &v1beta1.GrypeDocument{}}
+	require.NoError(t, a.StoreCVE(ctx, cve, false))
+	cvep := domain.CVEManifest{Name: "instance-id", Content: &v1beta1.GrypeDocument{}}
+	require.NoError(t, a.StoreCVE(ctx, cvep, true))
+	require.NoError(t, a.StoreCVESummary(ctx, cve, domain.CVEManifest{}, false))
+	// relevancy manifests stored by a previous version cannot be attributed
+	require.NoError(t, a.StoreCVE(context.TODO(), domain.CVEManifest{Name: "legacy-instance-id", Content: &v1beta1.GrypeDocument{}}, true))
+
+	// the results of a registry scan are not attributed to a workload
+	registry := context.WithValue(context.TODO(), domain.WorkloadKey{}, domain.ScanCommand{ImageSlug: "redis-slug", ImageHash: "redis@sha256:1234"})
+	require.NoError(t, a.StoreCVE(registry, domain.CVEManifest{Name: "redis-slug", Content: &v1beta1.GrypeDocument{}}, false))
+
+	results, err := a.ListScanResults(context.TODO())
+	require.NoError(t, err)
+	// the image results of a workload scan record the digest of the image and the workload
+	attributed := func(result domain.StoredResult) domain.StoredResult {
+		result.ImageDigest = "sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7"
+		result.WorkloadNamespace, result.WorkloadKind, result.WorkloadName = "default", "Deployment", "nginx"
+		result.ContainerName = "nginx"
+		return result
+	}
+	assert.ElementsMatch(t, []domain.StoredResult{
+		attributed(domain.StoredResult{Kind: domain.StoredSBOM, Namespace: "kubescape", Name: "nginx-slug", ImageSlug: "nginx-slug", SBOMSchemaVersion: "2"}),
+		attributed(domain.StoredResult{Kind: domain.StoredSBOMSummary, Namespace: "kubescape", Name: "nginx-slug", ImageSlug: "nginx-slug", SBOMSchemaVersion: "2"}),
+		attributed(domain.StoredResult{Kind: domain.StoredVulnerabilityManifest, Namespace: "kubescape", Name: "nginx-slug", ImageSlug: "nginx-slug"}),
+		attributed(domain.StoredResult{Kind: domain.StoredVulnerabilityManifest, Namespace: "kubescape", Name: "instance-id", ImageSlug: "nginx-slug"}),
+		{Kind: domain.StoredVulnerabilityManifest, Namespace: "kubescape", Name: "legacy-instance-id"},
+		{Kind: domain.StoredVulnerabilityManifest, Namespace: "kubescape", Name: "redis-slug", ImageSlug: "redis-slug"},
+		{
+			Kind:              domain.StoredVulnerabilitySummary,
+			Namespace:         "default",
+			Name:              "Deployment-nginx-nginx",
+			WorkloadNamespace: "default",
+			WorkloadKind:      "Deployment",
+			WorkloadName:      "nginx",
+			ContainerName:     "nginx",
+		},
+	}, results)
+
+	for _, result := range results {
+		assert.NoError(t, a.DeleteScanResult(context.TODO(), result))
+	}
+	results, err = a.ListScanResults(context.TODO())
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	// deleting again is not an error
+	assert.NoError(t, a.DeleteScanResult(context.TODO(), domain.StoredResult{Kind: domain.StoredSBOM, Namespace: "kubescape", Name: "nginx-slug"}))
+	assert.Error(t, a.DeleteScanResult(context.TODO(), domain.StoredResult{Kind: "ConfigMap", Name: "nginx-slug"}))
+}
+
+func TestAPIServerStore_MarkOrphaned(t *testing.T) {
+	a := NewFakeAPIServerStorage("kubescape")
+	for _, wlid := range []string{
+		"wlid://cluster-minikube/namespace-default/deployment-nginx",
+		"wlid://cluster-minikube/namespace-default/deployment-redis",
+	} {
+		ctx := context.WithValue(context.TODO(), domain.WorkloadKey{}, domain.ScanCommand{Wlid: wlid, ContainerName: "app"})
+		cve := domain.CVEManifest{Name: "slug", Content: &v1beta1.GrypeDocument{}}
+		require.NoError(t, a.StoreCVESummary(ctx, cve, domain.CVEManifest{}, false))
+	}
+
+	require.NoError(t, a.MarkOrphaned(context.TODO(), "default", "Deployment", "nginx"))
+	summary, err := a.StorageClient.VulnerabilityManifestSummaries("default").Get(context.TODO(), "Deployment-nginx-app", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, summary.Annotations[domain.AnnotationOrphaned])
+	summary, err = a.StorageClient.VulnerabilityManifestSummaries("default").Get(context.TODO(), "Deployment-redis-app", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, summary.Annotations[domain.AnnotationOrphaned])
+	// workloads without stored summaries are ignored
+	assert.NoError(t, a.MarkOrphaned(context.TODO(), "default", "Deployment", "postgres"))
+}
+
+func TestAPIServerStore_ListScanTimes(t *testing.T) {
+	a := NewFakeAPIServerStorage("kubescape")
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := created.Add(48 * time.Hour)
+	for _, manifest := range []v1beta1.VulnerabilityManifest{
+		{ObjectMeta: metav1.ObjectMeta{Name: "nginx-slug", Namespace: "kubescape", CreationTimestamp: metav1.NewTime(created),
+			Annotations: map[string]string{domain.AnnotationImageDigest: "sha256:nginx"}}},
+		// rescans update the manifest in place
+		{ObjectMeta: metav1.ObjectMeta{Name: "instance-id", Namespace: "kubescape", CreationTimestamp: metav1.NewTime(created),
+			Annotations:   map[string]string{domain.AnnotationImageDigest: "sha256:nginx"},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubevuln", Time: &metav1.Time{Time: updated}}}}},
+		// the registry scans are not attributed to an image digest
+		{ObjectMeta: metav1.ObjectMeta{Name: "redis-slug", Namespace: "kubescape", CreationTimestamp: metav1.NewTime(created)}},
+	} {
+		manifest := manifest
+		_, err := a.StorageClient.VulnerabilityManifests("kubescape").Create(context.TODO(), &manifest, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	scanTimes, err := a.ListScanTimes(context.TODO())
+	require.NoError(t, err)
+	assert.Len(t, scanTimes, 1)
+	assert.True(t, updated.Equal(scanTimes["sha256:nginx"]))
+}
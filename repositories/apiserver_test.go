@@ -461,6 +461,29 @@ func TestAPIServerStore_storeSBOMWithoutContent(t *testing.T) {
 	assert.Equal(t, err, nil)
 }
 
+func TestAPIServerStore_UpdateSBOM(t *testing.T) {
+	ctx := context.TODO()
+	a := NewFakeAPIServerStorage("kubescape")
+	sbom := domain.SBOM{
+		Name:               "nginx-slug",
+		SBOMCreatorVersion: "v0.76.0",
+		Content:            tools.FileToSBOM("testdata/alpine-sbom.json"),
+	}
+	// missing SBOMs cannot be updated
+	assert.Error(t, a.UpdateSBOM(ctx, sbom))
+
+	assert.NoError(t, a.StoreSBOM(ctx, sbom))
+	sbom.Annotations = map[string]string{domain.AnnotationSBOMSchemaVersion: "3"}
+	assert.NoError(t, a.UpdateSBOM(ctx, sbom))
+	manifest, err := a.StorageClient.SBOMSPDXv2p3s("kubescape").Get(ctx, "nginx-slug", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, sbom.Annotations, manifest.Annotations)
+	assert.Equal(t, *sbom.Content, manifest.Spec.SPDX)
+	summary, err := a.StorageClient.SBOMSummaries("kubescape").Get(ctx, "nginx-slug", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, sbom.Annotations, summary.Annotations)
+}
+
 func TestAPIServerStore_enrichSummaryManifestObjectLabels(t *testing.T) {
 	ctx := context.Background()
 
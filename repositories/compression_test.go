@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kubescape/kubevuln/internal/tools"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_compress(t *testing.T) {
+	document := *tools.FileToCVEManifest("testdata/nginx-cve.json").Content
+	plain, err := json.Marshal(document)
+	assert.NoError(t, err)
+	tests := []struct {
+		name        string
+		compression Compression
+		wantErr     bool
+	}{
+		{
+			name:        "none",
+			compression: CompressionNone,
+		},
+		{
+			name:        "gzip",
+			compression: CompressionGzip,
+		},
+		{
+			name:        "zstd",
+			compression: CompressionZstd,
+		},
+		{
+			name:        "unknown",
+			compression: "lz4",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := encodeContent(EncodingJSON, tt.compression, document)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compress() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.compression != CompressionNone {
+				assert.Less(t, len(data), len(plain)/5)
+			}
+			var got v1beta1.GrypeDocument
+			assert.NoError(t, decodeContent(data, &got))
+			b, err := json.Marshal(got)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(plain), string(b))
+		})
+	}
+}
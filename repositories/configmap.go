@@ -0,0 +1,480 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// maxQueueLedgerSize caps the size of the scan queue ledger below the 1 MiB limit of the ConfigMaps
+const maxQueueLedgerSize = 900 << 10
+
+// ConfigMapQueueStore implements ScanQueueRepository with a ConfigMap ledger holding one key per pending scan,
+// capped to maxQueueLedgerSize, the queue can be inspected with kubectl get configmap
+type ConfigMapQueueStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+var _ ports.ScanQueueRepository = (*ConfigMapQueueStore)(nil)
+
+// NewConfigMapQueueStore initializes the ConfigMapQueueStore struct
+func NewConfigMapQueueStore(client kubernetes.Interface, namespace, name string) *ConfigMapQueueStore {
+	return &ConfigMapQueueStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// Enqueue records a pending scan in the ConfigMap, registry credentials and relayed SBOMs are never persisted,
+// ErrScanQueueFull is returned once the ledger reaches maxQueueLedgerSize
+func (c *ConfigMapQueueStore) Enqueue(ctx context.Context, scan domain.QueuedScan) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ConfigMapQueueStore.Enqueue")
+	defer span.End()
+
+	scan.HadCredentials = scan.HadCredentials || len(scan.Command.Credentialslist) > 0
+	scan.Command.Credentialslist = nil
+	scan.Command.RelayedSBOM = nil
+	value, err := json.Marshal(scan)
+	if err != nil {
+		return err
+	}
+	var full bool
+	err = updateConfigMap(ctx, c.client, c.namespace, c.name, func(data map[string]string) {
+		size := len(scan.ID) + len(value)
+		for key, value := range data {
+			if key != scan.ID {
+				size += len(key) + len(value)
+			}
+		}
+		if full = size > maxQueueLedgerSize; !full {
+			data[scan.ID] = string(value)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if full {
+		return domain.ErrScanQueueFull
+	}
+	return nil
+}
+
+// Dequeue removes a scan from the ConfigMap
+func (c *ConfigMapQueueStore) Dequeue(ctx context.Context, id string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ConfigMapQueueStore.Dequeue")
+	defer span.End()
+
+	return updateConfigMap(ctx, c.client, c.namespace, c.name, func(data map[string]string) {
+		delete(data, id)
+	})
+}
+
+// ListQueued returns the pending scans ordered by queuing time, unreadable entries are skipped
+func (c *ConfigMapQueueStore) ListQueued(ctx context.Context) ([]domain.QueuedScan, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ConfigMapQueueStore.ListQueued")
+	defer span.End()
+
+	configMap, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(ctx, c.name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	scans := make([]domain.QueuedScan, 0, len(configMap.Data))
+	for _, value := range configMap.Data {
+		var scan domain.QueuedScan
+		if err := json.Unmarshal([]byte(value), &scan); err != nil {
+			continue
+		}
+		scans = append(scans, scan)
+	}
+	sort.Slice(scans, func(i, j int) bool {
+		return scans[i].QueuedAt.Before(scans[j].QueuedAt)
+	})
+	return scans, nil
+}
+
+// updateConfigMap applies the given mutation to the data of a ConfigMap, creating the ConfigMap if needed and retrying
+// on conflicts
+func updateConfigMap(ctx context.Context, client kubernetes.Interface, namespace, name string, mutate func(map[string]string)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMaps := client.CoreV1().ConfigMaps(namespace)
+		configMap, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+				},
+				Data: map[string]string{},
+			}
+			mutate(configMap.Data)
+			_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+			if errors.IsAlreadyExists(err) {
+				// let RetryOnConflict try again with the existing ConfigMap
+				return errors.NewConflict(corev1.Resource("configmaps"), name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		mutate(configMap.Data)
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// maxPseudonymConfigMaps caps the number of ConfigMaps holding the pseudonyms, the one named after the store and its
+// overflows
+const maxPseudonymConfigMaps = 16
+
+// ConfigMapPseudonymStore implements PseudonymRepository with ConfigMaps holding the original value of each pseudonym,
+// each capped to maxQueueLedgerSize, the pseudonyms can be resolved with kubectl get configmap
+type ConfigMapPseudonymStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+var _ ports.PseudonymRepository = (*ConfigMapPseudonymStore)(nil)
+
+// NewConfigMapPseudonymStore initializes the ConfigMapPseudonymStore struct
+func NewConfigMapPseudonymStore(client kubernetes.Interface, namespace, name string) *ConfigMapPseudonymStore {
+	return &ConfigMapPseudonymStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// StorePseudonym records the original value of a pseudonym in the first ConfigMap with room for it: the one named after
+// the store, then its overflows suffixed with -1, -2... ErrPseudonymStoreFull is returned once the
+// maxPseudonymConfigMaps are full
+func (c *ConfigMapPseudonymStore) StorePseudonym(ctx context.Context, pseudonym, original string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ConfigMapPseudonymStore.StorePseudonym")
+	defer span.End()
+
+	for i := 0; i < maxPseudonymConfigMaps; i++ {
+		name := c.name
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d", c.name, i)
+		}
+		var stored bool
+		err := updateConfigMap(ctx, c.client, c.namespace, name, func(data map[string]string) {
+			if _, ok := data[pseudonym]; ok {
+				stored = true
+				return
+			}
+			size := len(pseudonym) + len(original)
+			for key, value := range data {
+				size += len(key) + len(value)
+			}
+			if stored = size <= maxQueueLedgerSize; stored {
+				data[pseudonym] = original
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if stored {
+			return nil
+		}
+	}
+	return domain.ErrPseudonymStoreFull
+}
+
+// ConfigMapFalsePositiveStore implements FalsePositiveRepository with a ConfigMap holding one key per false positive
+// feedback, the feedback can be reviewed with kubectl get configmap
+type ConfigMapFalsePositiveStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+var _ ports.FalsePositiveRepository = (*ConfigMapFalsePositiveStore)(nil)
+
+// NewConfigMapFalsePositiveStore initializes the ConfigMapFalsePositiveStore struct
+func NewConfigMapFalsePositiveStore(client kubernetes.Interface, namespace, name string) *ConfigMapFalsePositiveStore {
+	return &ConfigMapFalsePositiveStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// StoreFalsePositive records a false positive feedback in the ConfigMap
+func (c *ConfigMapFalsePositiveStore) StoreFalsePositive(ctx context.Context, falsePositive domain.FalsePositive) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ConfigMapFalsePositiveStore.StoreFalsePositive")
+	defer span.End()
+
+	value, err := json.Marshal(falsePositive)
+	if err != nil {
+		return err
+	}
+	return updateConfigMap(ctx, c.client, c.namespace, c.name, func(data map[string]string) {
+		data[falsePositive.ID] = string(value)
+	})
+}
+
+// DeleteFalsePositive removes a false positive feedback from the ConfigMap
+func (c *ConfigMapFalsePositiveStore) DeleteFalsePositive(ctx context.Context, id string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ConfigMapFalsePositiveStore.DeleteFalsePositive")
+	defer span.End()
+
+	var found bool
+	err := updateConfigMap(ctx, c.client, c.namespace, c.name, func(data map[string]string) {
+		_, found = data[id]
+		delete(data, id)
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return domain.ErrFalsePositiveNotFound
+	}
+	return nil
+}
+
+// ListFalsePositives returns the false positive feedback ordered by creation time, unreadable entries are skipped
+func (c *ConfigMapFalsePositiveStore) ListFalsePositives(ctx context.Context) ([]domain.FalsePositive, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ConfigMapFalsePositiveStore.ListFalsePositives")
+	defer span.End()
+
+	configMap, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(ctx, c.name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	falsePositives := make([]domain.FalsePositive, 0, len(configMap.Data))
+	for _, value := range configMap.Data {
+		var falsePositive domain.FalsePositive
+		if err := json.Unmarshal([]byte(value), &falsePositive); err != nil {
+			continue
+		}
+		falsePositives = append(falsePositives, falsePositive)
+	}
+	sort.Slice(falsePositives, func(i, j int) bool {
+		if falsePositives[i].CreatedAt.Equal(falsePositives[j].CreatedAt) {
+			return falsePositives[i].ID < falsePositives[j].ID
+		}
+		return falsePositives[i].CreatedAt.Before(falsePositives[j].CreatedAt)
+	})
+	return falsePositives, nil
+}
+
+// ConfigMapTriageStore implements TriageRepository with a ConfigMap holding one key per triaged finding, the triage
+// can be reviewed with kubectl get configmap
+type ConfigMapTriageStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+var _ ports.TriageRepository = (*ConfigMapTriageStore)(nil)
+
+// NewConfigMapTriageStore initializes the ConfigMapTriageStore struct
+func NewConfigMapTriageStore(client kubernetes.Interface, namespace, name string) *ConfigMapTriageStore {
+	return &ConfigMapTriageStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// StoreTriage records the triage of a finding in the ConfigMap
+func (c *ConfigMapTriageStore) StoreTriage(ctx context.Context, triage domain.Triage) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ConfigMapTriageStore.StoreTriage")
+	defer span.End()
+
+	value, err := json.Marshal(triage)
+	if err != nil {
+		return err
+	}
+	return updateConfigMap(ctx, c.client, c.namespace, c.name, func(data map[string]string) {
+		data[triage.ID] = string(value)
+	})
+}
+
+// DeleteTriage removes the triage of a finding from the ConfigMap
+func (c *ConfigMapTriageStore) DeleteTriage(ctx context.Context, id string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ConfigMapTriageStore.DeleteTriage")
+	defer span.End()
+
+	var found bool
+	err := updateConfigMap(ctx, c.client, c.namespace, c.name, func(data map[string]string) {
+		_, found = data[id]
+		delete(data, id)
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return domain.ErrTriageNotFound
+	}
+	return nil
+}
+
+// ListTriage returns the triage of the findings ordered by creation time, unreadable entries are skipped
+func (c *ConfigMapTriageStore) ListTriage(ctx context.Context) ([]domain.Triage, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ConfigMapTriageStore.ListTriage")
+	defer span.End()
+
+	configMap, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(ctx, c.name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	triage := make([]domain.Triage, 0, len(configMap.Data))
+	for _, value := range configMap.Data {
+		var t domain.Triage
+		if err := json.Unmarshal([]byte(value), &t); err != nil {
+			continue
+		}
+		triage = append(triage, t)
+	}
+	sort.Slice(triage, func(i, j int) bool {
+		if triage[i].CreatedAt.Equal(triage[j].CreatedAt) {
+			return triage[i].ID < triage[j].ID
+		}
+		return triage[i].CreatedAt.Before(triage[j].CreatedAt)
+	})
+	return triage, nil
+}
+
+// invalidConfigMapKeyChars matches the characters not allowed in the keys of a ConfigMap
+var invalidConfigMapKeyChars = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+
+// ConfigMapScanFailureStore implements ScanFailureRepository with a ConfigMap holding one key per image whose last
+// scan failed, capped to maxQueueLedgerSize by evicting the oldest failures, the failures can be reviewed with kubectl
+// get configmap
+type ConfigMapScanFailureStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+var _ ports.ScanFailureRepository = (*ConfigMapScanFailureStore)(nil)
+
+// NewConfigMapScanFailureStore initializes the ConfigMapScanFailureStore struct
+func NewConfigMapScanFailureStore(client kubernetes.Interface, namespace, name string) *ConfigMapScanFailureStore {
+	return &ConfigMapScanFailureStore{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// StoreScanFailure records the last scan failure of an image in the ConfigMap, keyed by the image with the
+// characters not allowed in ConfigMap keys replaced
+func (c *ConfigMapScanFailureStore) StoreScanFailure(ctx context.Context, failure domain.ScanFailure) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ConfigMapScanFailureStore.StoreScanFailure")
+	defer span.End()
+
+	value, err := json.Marshal(failure)
+	if err != nil {
+		return err
+	}
+	key := invalidConfigMapKeyChars.ReplaceAllString(failure.Image, "_")
+	return updateConfigMap(ctx, c.client, c.namespace, c.name, func(data map[string]string) {
+		data[key] = string(value)
+		evictOldestFailures(data, key)
+	})
+}
+
+// evictOldestFailures removes the oldest failures but the one of keep until the data fits in maxQueueLedgerSize, the
+// unreadable entries go first
+func evictOldestFailures(data map[string]string, keep string) {
+	size := 0
+	for key, value := range data {
+		size += len(key) + len(value)
+	}
+	if size <= maxQueueLedgerSize {
+		return
+	}
+	type entry struct {
+		key       string
+		timestamp time.Time
+	}
+	entries := make([]entry, 0, len(data))
+	for key, value := range data {
+		if key == keep {
+			continue
+		}
+		var failure domain.ScanFailure
+		_ = json.Unmarshal([]byte(value), &failure)
+		entries = append(entries, entry{key: key, timestamp: failure.Timestamp})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].timestamp.Equal(entries[j].timestamp) {
+			return entries[i].key < entries[j].key
+		}
+		return entries[i].timestamp.Before(entries[j].timestamp)
+	})
+	for _, e := range entries {
+		if size <= maxQueueLedgerSize {
+			return
+		}
+		size -= len(e.key) + len(data[e.key])
+		delete(data, e.key)
+	}
+}
+
+// DeleteScanFailure removes the last scan failure of an image from the ConfigMap
+func (c *ConfigMapScanFailureStore) DeleteScanFailure(ctx context.Context, image string) error {
+	ctx, span := otel.Tracer("").Start(ctx, "ConfigMapScanFailureStore.DeleteScanFailure")
+	defer span.End()
+
+	return updateConfigMap(ctx, c.client, c.namespace, c.name, func(data map[string]string) {
+		delete(data, invalidConfigMapKeyChars.ReplaceAllString(image, "_"))
+	})
+}
+
+// ListScanFailures returns the scan failures recorded in the ConfigMap, unreadable entries are skipped
+func (c *ConfigMapScanFailureStore) ListScanFailures(ctx context.Context) ([]domain.ScanFailure, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "ConfigMapScanFailureStore.ListScanFailures")
+	defer span.End()
+
+	configMap, err := c.client.CoreV1().ConfigMaps(c.namespace).Get(ctx, c.name, metav1.GetOptions{})
+	switch {
+	case errors.IsNotFound(err):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	failures := make([]domain.ScanFailure, 0, len(configMap.Data))
+	for _, value := range configMap.Data {
+		var failure domain.ScanFailure
+		if err := json.Unmarshal([]byte(value), &failure); err != nil || failure.Image == "" {
+			continue
+		}
+		failures = append(failures, failure)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].Image < failures[j].Image
+	})
+	return failures, nil
+}
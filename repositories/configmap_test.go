@@ -0,0 +1,208 @@
+package repositories
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigMapQueueStore(t *testing.T) {
+	ctx := context.TODO()
+	client := fake.NewSimpleClientset()
+	q := NewConfigMapQueueStore(client, "kubescape", "kubevuln-queue")
+	// empty queue without ConfigMap
+	scans, err := q.ListQueued(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, scans)
+	// enqueue scans
+	now := time.Now().UTC().Truncate(time.Second)
+	second := domain.QueuedScan{ID: "2", Kind: domain.ScanKindScanRegistry, QueuedAt: now.Add(time.Second), Command: domain.ScanCommand{
+		ImageTag:        "nginx:latest",
+		Credentialslist: []types.AuthConfig{{Username: "user", Password: "password"}},
+	}}
+	first := domain.QueuedScan{ID: "1", Kind: domain.ScanKindScanCVE, QueuedAt: now, Command: domain.ScanCommand{
+		ImageHash:   "nginx@sha256:73e957703f1266530db0aeac1fd6a3f87c1e59943f4c13eb340bb8521c6041d7",
+		Wlid:        "wlid://cluster-minikube/namespace-default/deployment-nginx",
+		RelayedSBOM: &domain.SBOM{Name: "relayed"},
+	}}
+	assert.NoError(t, q.Enqueue(ctx, second))
+	assert.NoError(t, q.Enqueue(ctx, first))
+	configMap, err := client.CoreV1().ConfigMaps("kubescape").Get(ctx, "kubevuln-queue", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, configMap.Data, 2)
+	assert.NotContains(t, configMap.Data["2"], "password")
+	// list in queuing order without credentials
+	scans, err = q.ListQueued(ctx)
+	assert.NoError(t, err)
+	second.Command.Credentialslist = nil
+	second.HadCredentials = true
+	first.Command.RelayedSBOM = nil
+	assert.Equal(t, []domain.QueuedScan{first, second}, scans)
+	// dequeue
+	assert.NoError(t, q.Dequeue(ctx, "1"))
+	assert.NoError(t, q.Dequeue(ctx, "unknown"))
+	scans, err = q.ListQueued(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.QueuedScan{second}, scans)
+}
+
+func TestConfigMapQueueStore_full(t *testing.T) {
+	ctx := context.TODO()
+	q := NewConfigMapQueueStore(fake.NewSimpleClientset(), "kubescape", "kubevuln-queue")
+	scan := domain.QueuedScan{Kind: domain.ScanKindScanCVE, Command: domain.ScanCommand{ImageTag: strings.Repeat("a", 100<<10)}}
+	var err error
+	var queued int
+	for err == nil {
+		scan.ID = strconv.Itoa(queued)
+		if err = q.Enqueue(ctx, scan); err == nil {
+			queued++
+		}
+	}
+	assert.ErrorIs(t, err, domain.ErrScanQueueFull)
+	assert.Equal(t, 8, queued)
+	// the scans already queued can still be replaced
+	scan.ID = "0"
+	assert.NoError(t, q.Enqueue(ctx, scan))
+	scans, err := q.ListQueued(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, scans, queued)
+}
+
+func TestConfigMapPseudonymStore(t *testing.T) {
+	ctx := context.TODO()
+	client := fake.NewSimpleClientset()
+	s := NewConfigMapPseudonymStore(client, "kubescape", "kubevuln-pseudonyms")
+	assert.NoError(t, s.StorePseudonym(ctx, "anon-0123456789abcdef", "default"))
+	assert.NoError(t, s.StorePseudonym(ctx, "anon-fedcba9876543210", "nginx"))
+	configMap, err := client.CoreV1().ConfigMaps("kubescape").Get(ctx, "kubevuln-pseudonyms", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"anon-0123456789abcdef": "default", "anon-fedcba9876543210": "nginx"}, configMap.Data)
+}
+
+func TestConfigMapScanFailureStore_full(t *testing.T) {
+	ctx := context.TODO()
+	s := NewConfigMapScanFailureStore(fake.NewSimpleClientset(), "kubescape", "kubevuln-scan-failures")
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := 0; i < 6; i++ {
+		require.NoError(t, s.StoreScanFailure(ctx, domain.ScanFailure{Image: "nginx:1." + strconv.Itoa(i),
+			Timestamp: now.Add(time.Duration(i) * time.Minute), Error: strings.Repeat("a", 200<<10)}))
+	}
+	// the oldest failures are evicted to fit in the ConfigMap
+	failures, err := s.ListScanFailures(ctx)
+	require.NoError(t, err)
+	images := make([]string, 0, len(failures))
+	for _, failure := range failures {
+		images = append(images, failure.Image)
+	}
+	assert.Equal(t, []string{"nginx:1.2", "nginx:1.3", "nginx:1.4", "nginx:1.5"}, images)
+}
+
+func TestConfigMapPseudonymStore_full(t *testing.T) {
+	ctx := context.TODO()
+	client := fake.NewSimpleClientset()
+	s := NewConfigMapPseudonymStore(client, "kubescape", "kubevuln-pseudonyms")
+	original := strings.Repeat("a", 200<<10)
+	var err error
+	var stored int
+	for err == nil {
+		if err = s.StorePseudonym(ctx, "anon-"+strconv.Itoa(stored), original); err == nil {
+			stored++
+		}
+	}
+	assert.ErrorIs(t, err, domain.ErrPseudonymStoreFull)
+	// four pseudonyms fit in each ConfigMap
+	assert.Equal(t, 4*maxPseudonymConfigMaps, stored)
+	overflow, err := client.CoreV1().ConfigMaps("kubescape").Get(ctx, "kubevuln-pseudonyms-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, overflow.Data, "anon-4")
+	// the pseudonyms already stored are found again
+	assert.NoError(t, s.StorePseudonym(ctx, "anon-4", original))
+}
+
+func TestConfigMapFalsePositiveStore(t *testing.T) {
+	ctx := context.TODO()
+	client := fake.NewSimpleClientset()
+	s := NewConfigMapFalsePositiveStore(client, "kubescape", "kubevuln-false-positives")
+	// no feedback without ConfigMap
+	falsePositives, err := s.ListFalsePositives(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, falsePositives)
+	now := time.Now().UTC().Truncate(time.Second)
+	second := domain.FalsePositive{ID: "2", Vulnerability: "CVE-2021-44228", Package: "log4j-core", CreatedAt: now.Add(time.Second)}
+	first := domain.FalsePositive{ID: "1", Vulnerability: "CVE-2023-0286", Package: "openssl", Version: "1.1.1n-0+deb11u3", Reason: "patched by the vendor", CreatedAt: now}
+	assert.NoError(t, s.StoreFalsePositive(ctx, second))
+	assert.NoError(t, s.StoreFalsePositive(ctx, first))
+	// list in creation order
+	falsePositives, err = s.ListFalsePositives(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.FalsePositive{first, second}, falsePositives)
+	// delete
+	assert.NoError(t, s.DeleteFalsePositive(ctx, "1"))
+	assert.ErrorIs(t, s.DeleteFalsePositive(ctx, "1"), domain.ErrFalsePositiveNotFound)
+	falsePositives, err = s.ListFalsePositives(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.FalsePositive{second}, falsePositives)
+}
+
+func TestConfigMapTriageStore(t *testing.T) {
+	ctx := context.TODO()
+	client := fake.NewSimpleClientset()
+	s := NewConfigMapTriageStore(client, "kubescape", "kubevuln-triage")
+	// no triage without ConfigMap
+	triage, err := s.ListTriage(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, triage)
+	now := time.Now().UTC().Truncate(time.Second)
+	until := now.Add(30 * 24 * time.Hour)
+	second := domain.Triage{ID: "2", Wlid: "wlid://cluster-minikube/namespace-default/deployment-nginx", Vulnerability: "CVE-2021-44228", State: domain.TriageAcknowledged, CreatedAt: now.Add(time.Second)}
+	first := domain.Triage{ID: "1", Wlid: "wlid://cluster-minikube/namespace-default/deployment-nginx", Vulnerability: "CVE-2023-0286", Package: "openssl", State: domain.TriageSnoozed, Until: &until, Reason: "fix scheduled", CreatedAt: now}
+	assert.NoError(t, s.StoreTriage(ctx, second))
+	assert.NoError(t, s.StoreTriage(ctx, first))
+	// list in creation order
+	triage, err = s.ListTriage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Triage{first, second}, triage)
+	// delete
+	assert.NoError(t, s.DeleteTriage(ctx, "1"))
+	assert.ErrorIs(t, s.DeleteTriage(ctx, "1"), domain.ErrTriageNotFound)
+	triage, err = s.ListTriage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Triage{second}, triage)
+}
+
+func TestConfigMapScanFailureStore(t *testing.T) {
+	ctx := context.TODO()
+	client := fake.NewSimpleClientset()
+	s := NewConfigMapScanFailureStore(client, "kubescape", "kubevuln-scan-failures")
+	// no failure without ConfigMap
+	failures, err := s.ListScanFailures(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+	now := time.Now().UTC().Truncate(time.Second)
+	auth := domain.ScanFailure{Image: "sha256:c1b135231b5b1a6799346cd701da4b59e5b7ef8e694ec7b04fb23b8dbe144137", Timestamp: now,
+		Stage: domain.StageSBOM, Reason: domain.FailureAuth, Error: "registry authentication failed", Attempts: 3}
+	size := domain.ScanFailure{Image: "quay.io/kubescape/kubevuln:latest", Timestamp: now,
+		Stage: domain.StageSBOM, Reason: domain.FailureImageTooLarge, Error: "incomplete SBOM", Attempts: 1}
+	assert.NoError(t, s.StoreScanFailure(ctx, auth))
+	assert.NoError(t, s.StoreScanFailure(ctx, size))
+	configMap, err := client.CoreV1().ConfigMaps("kubescape").Get(ctx, "kubevuln-scan-failures", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Contains(t, configMap.Data, "quay.io_kubescape_kubevuln_latest")
+	failures, err = s.ListScanFailures(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.ScanFailure{size, auth}, failures)
+	// the failure is forgotten once a scan succeeds
+	assert.NoError(t, s.DeleteScanFailure(ctx, size.Image))
+	failures, err = s.ListScanFailures(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.ScanFailure{auth}, failures)
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+)
+
+// Encoding is the serialization format of stored and exchanged contents
+type Encoding string
+
+const (
+	EncodingJSON Encoding = "json"
+	EncodingCBOR Encoding = "cbor"
+)
+
+// cborMagic is the self-described CBOR tag (RFC 8949 section 3.4.6) prefixing CBOR contents,
+// it tells them apart from JSON ones
+var cborMagic = []byte{0xd9, 0xd9, 0xf7}
+
+// encode serializes v with the given encoding, JSON being the default
+func encode(encoding Encoding, v interface{}) ([]byte, error) {
+	switch encoding {
+	case "", EncodingJSON:
+		return json.Marshal(v)
+	case EncodingCBOR:
+		data, err := cbor.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return append(append([]byte{}, cborMagic...), data...), nil
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
+
+// decode deserializes data into v, detecting its encoding
+func decode(data []byte, v interface{}) error {
+	if bytes.HasPrefix(data, cborMagic) {
+		return cbor.Unmarshal(data[len(cborMagic):], v)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// encodeContent serializes and compresses v
+func encodeContent(encoding Encoding, compression Compression, v interface{}) ([]byte, error) {
+	data, err := encode(encoding, v)
+	if err != nil {
+		return nil, err
+	}
+	return compress(compression, data)
+}
+
+// decodeContent decompresses and deserializes data into v, whatever their encoding and compression
+func decodeContent(data []byte, v interface{}) error {
+	data, err := decompress(data)
+	if err != nil {
+		return err
+	}
+	return decode(data, v)
+}
+
+// ConvertSBOM converts an SBOM document between JSON and CBOR, the source encoding and compression being detected,
+// so that components exchanging SBOMs can use the compact binary encoding
+func ConvertSBOM(data []byte, to Encoding) ([]byte, error) {
+	var doc v1beta1.Document
+	if err := decodeContent(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode SBOM: %w", err)
+	}
+	return encode(to, doc)
+}
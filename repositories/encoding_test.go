@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kubescape/kubevuln/internal/tools"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_encode(t *testing.T) {
+	sbom := tools.FileToSBOM("testdata/alpine-sbom.json")
+	cve := tools.FileToCVEManifest("testdata/nginx-cve.json").Content
+	tests := []struct {
+		name     string
+		encoding Encoding
+		wantErr  bool
+	}{
+		{
+			name: "default",
+		},
+		{
+			name:     "json",
+			encoding: EncodingJSON,
+		},
+		{
+			name:     "cbor",
+			encoding: EncodingCBOR,
+		},
+		{
+			name:     "unknown",
+			encoding: "protobuf",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := encode(tt.encoding, sbom)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("encode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			var gotSBOM v1beta1.Document
+			assert.NoError(t, decode(data, &gotSBOM))
+			assert.Equal(t, *sbom, gotSBOM)
+			data, err = encode(tt.encoding, cve)
+			assert.NoError(t, err)
+			var gotCVE v1beta1.GrypeDocument
+			assert.NoError(t, decode(data, &gotCVE))
+			want, _ := json.Marshal(cve)
+			got, _ := json.Marshal(gotCVE)
+			assert.JSONEq(t, string(want), string(got))
+		})
+	}
+}
+
+func TestConvertSBOM(t *testing.T) {
+	sbom := tools.FileToSBOM("testdata/alpine-sbom.json")
+	jsonData, err := json.Marshal(sbom)
+	assert.NoError(t, err)
+	cborData, err := ConvertSBOM(jsonData, EncodingCBOR)
+	assert.NoError(t, err)
+	assert.Less(t, len(cborData), len(jsonData))
+	// compressed contents are converted too
+	compressed, err := compress(CompressionZstd, cborData)
+	assert.NoError(t, err)
+	got, err := ConvertSBOM(compressed, EncodingJSON)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(jsonData), string(got))
+	_, err = ConvertSBOM([]byte("not an SBOM"), EncodingCBOR)
+	assert.Error(t, err)
+}
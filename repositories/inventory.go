@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/kubescape/kubevuln/core/ports"
+	"go.opentelemetry.io/otel"
+)
+
+// MemoryInventory implements InventoryRepository with the last inventory posted by the operator kept in memory, the
+// operator posts it again after a restart
+type MemoryInventory struct {
+	images []domain.WorkloadImage
+	mu     sync.RWMutex
+	synced bool
+}
+
+var _ ports.InventoryRepository = (*MemoryInventory)(nil)
+
+// NewMemoryInventory initializes the MemoryInventory struct
+func NewMemoryInventory() *MemoryInventory {
+	return &MemoryInventory{}
+}
+
+// ListWorkloadImages returns the workload images of the last inventory, ErrInventoryNotSynced until one is posted so
+// that nothing is collected or reported against an empty cluster
+func (m *MemoryInventory) ListWorkloadImages(ctx context.Context) ([]domain.WorkloadImage, error) {
+	_, span := otel.Tracer("").Start(ctx, "MemoryInventory.ListWorkloadImages")
+	defer span.End()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if !m.synced {
+		return nil, domain.ErrInventoryNotSynced
+	}
+	return append([]domain.WorkloadImage{}, m.images...), nil
+}
+
+// StoreInventory replaces the inventory
+func (m *MemoryInventory) StoreInventory(ctx context.Context, images []domain.WorkloadImage) error {
+	_, span := otel.Tracer("").Start(ctx, "MemoryInventory.StoreInventory")
+	defer span.End()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.images = append([]domain.WorkloadImage{}, images...)
+	m.synced = true
+	return nil
+}
@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubescape/kubevuln/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryInventory(t *testing.T) {
+	ctx := context.TODO()
+	m := NewMemoryInventory()
+	_, err := m.ListWorkloadImages(ctx)
+	assert.ErrorIs(t, err, domain.ErrInventoryNotSynced)
+
+	images := []domain.WorkloadImage{{Namespace: "default", Kind: "Deployment", Name: "nginx", ContainerName: "nginx", ImageTag: "nginx:1.25"}}
+	require.NoError(t, m.StoreInventory(ctx, images))
+	got, err := m.ListWorkloadImages(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, images, got)
+
+	// an empty inventory is an empty cluster
+	require.NoError(t, m.StoreInventory(ctx, nil))
+	got, err = m.ListWorkloadImages(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
@@ -2,9 +2,12 @@ package repositories
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/kubescape/kubevuln/core/domain"
 	"github.com/kubescape/kubevuln/core/ports"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
 	"go.opentelemetry.io/otel"
 )
 
@@ -22,24 +25,125 @@ type sbomID struct {
 
 // MemoryStore implements both CVERepository and SBOMRepository with in-memory storage (maps) to be used for tests
 type MemoryStore struct {
+	compression Compression
+	encoding    Encoding
+	// contents holds the compressed contents of the stored objects, keyed by their cveID or sbomID
+	contents     map[interface{}][]byte
 	cveManifests map[cveID]domain.CVEManifest
-	sboms        map[sbomID]domain.SBOM
-	getError     bool
-	storeError   bool
+	// orphaned holds the deletion time of the deleted workloads, keyed by namespace/kind/name
+	orphaned   map[string]time.Time
+	sboms      map[sbomID]domain.SBOM
+	getError   bool
+	storeError bool
+}
+
+// MemoryStoreOption configures optional behaviors of the MemoryStore
+type MemoryStoreOption func(*MemoryStore)
+
+// WithEncoding keeps the contents of the stored objects serialized with the given encoding
+func WithEncoding(encoding Encoding) MemoryStoreOption {
+	return func(m *MemoryStore) {
+		m.encoding = encoding
+	}
+}
+
+// WithCompression keeps the contents of the stored objects compressed with the given algorithm,
+// they are transparently decompressed on read
+func WithCompression(compression Compression) MemoryStoreOption {
+	return func(m *MemoryStore) {
+		m.compression = compression
+	}
 }
 
 var _ ports.CVERepository = (*MemoryStore)(nil)
 
 var _ ports.SBOMRepository = (*MemoryStore)(nil)
 
+var _ ports.ScanResultRepository = (*MemoryStore)(nil)
+
+var _ ports.SBOMMigrationRepository = (*MemoryStore)(nil)
+
 // NewMemoryStorage initializes the MemoryStore struct and its maps
-func NewMemoryStorage(getError, storeError bool) *MemoryStore {
-	return &MemoryStore{
+func NewMemoryStorage(getError, storeError bool, opts ...MemoryStoreOption) *MemoryStore {
+	m := &MemoryStore{
+		contents:     map[interface{}][]byte{},
 		cveManifests: map[cveID]domain.CVEManifest{},
+		orphaned:     map[string]time.Time{},
 		sboms:        map[sbomID]domain.SBOM{},
 		getError:     getError,
 		storeError:   storeError,
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// serializeContents tells whether contents are kept serialized rather than as objects
+func (m *MemoryStore) serializeContents() bool {
+	return m.compression != CompressionNone || m.encoding != ""
+}
+
+// storeCVE keeps the CVE manifest, compressing its content if needed
+func (m *MemoryStore) storeCVE(id cveID, cve domain.CVEManifest) error {
+	delete(m.contents, id)
+	if m.serializeContents() && cve.Content != nil {
+		data, err := encodeContent(m.encoding, m.compression, cve.Content)
+		if err != nil {
+			return err
+		}
+		m.contents[id] = data
+		cve.Content = nil
+	}
+	m.cveManifests[id] = cve
+	return nil
+}
+
+// getCVE returns the CVE manifest, decompressing its content if needed
+func (m *MemoryStore) getCVE(id cveID) (domain.CVEManifest, error) {
+	cve, ok := m.cveManifests[id]
+	if !ok {
+		return domain.CVEManifest{}, nil
+	}
+	if data, ok := m.contents[id]; ok {
+		var content v1beta1.GrypeDocument
+		if err := decodeContent(data, &content); err != nil {
+			return domain.CVEManifest{}, err
+		}
+		cve.Content = &content
+	}
+	return cve, nil
+}
+
+// storeSBOM keeps the SBOM, compressing its content if needed
+func (m *MemoryStore) storeSBOM(id sbomID, sbom domain.SBOM) error {
+	delete(m.contents, id)
+	if m.serializeContents() && sbom.Content != nil {
+		data, err := encodeContent(m.encoding, m.compression, sbom.Content)
+		if err != nil {
+			return err
+		}
+		m.contents[id] = data
+		sbom.Content = nil
+	}
+	m.sboms[id] = sbom
+	return nil
+}
+
+// getSBOM returns the SBOM, decompressing its content if needed
+func (m *MemoryStore) getSBOM(id sbomID) (domain.SBOM, error) {
+	sbom, ok := m.sboms[id]
+	if !ok {
+		return domain.SBOM{}, nil
+	}
+	if data, ok := m.contents[id]; ok {
+		var content v1beta1.Document
+		if err := decodeContent(data, &content); err != nil {
+			return domain.SBOM{}, err
+		}
+		sbom.Content = &content
+	}
+	return sbom, nil
 }
 
 // GetCVE returns a CVE manifest from an in-memory map
@@ -57,10 +161,7 @@ func (m *MemoryStore) GetCVE(ctx context.Context, name, SBOMCreatorVersion, CVES
 		CVEScannerVersion:  CVEScannerVersion,
 		CVEDBVersion:       CVEDBVersion,
 	}
-	if value, ok := m.cveManifests[id]; ok {
-		return value, nil
-	}
-	return domain.CVEManifest{}, nil
+	return m.getCVE(id)
 }
 
 // StoreCVE stores a CVE manifest to an in-memory map
@@ -78,8 +179,7 @@ func (m *MemoryStore) StoreCVE(ctx context.Context, cve domain.CVEManifest, _ bo
 		CVEScannerVersion:  cve.CVEScannerVersion,
 		CVEDBVersion:       cve.CVEDBVersion,
 	}
-	m.cveManifests[id] = cve
-	return nil
+	return m.storeCVE(id, cve)
 }
 
 // StoreCVE stores a CVE Summary to an in-memory map
@@ -105,11 +205,12 @@ func (m *MemoryStore) StoreCVESummary(ctx context.Context, cve domain.CVEManifes
 			CVEScannerVersion:  cvep.CVEScannerVersion,
 			CVEDBVersion:       cvep.CVEDBVersion,
 		}
-		m.cveManifests[idSumm] = cvep
+		if err := m.storeCVE(idSumm, cvep); err != nil {
+			return err
+		}
 	}
 
-	m.cveManifests[id] = cve
-	return nil
+	return m.storeCVE(id, cve)
 }
 
 // GetSBOM returns a SBOM from an in-memory map
@@ -125,10 +226,7 @@ func (m *MemoryStore) GetSBOM(ctx context.Context, name, SBOMCreatorVersion stri
 		Name:               name,
 		SBOMCreatorVersion: SBOMCreatorVersion,
 	}
-	if value, ok := m.sboms[id]; ok {
-		return value, nil
-	}
-	return domain.SBOM{}, nil
+	return m.getSBOM(id)
 }
 
 // GetSBOMp returns a SBOM' from an in-memory map
@@ -144,10 +242,7 @@ func (m *MemoryStore) GetSBOMp(ctx context.Context, instanceID, SBOMCreatorVersi
 		Name:               instanceID,
 		SBOMCreatorVersion: SBOMCreatorVersion,
 	}
-	if value, ok := m.sboms[id]; ok {
-		return value, nil
-	}
-	return domain.SBOM{}, nil
+	return m.getSBOM(id)
 }
 
 // StoreSBOM stores an SBOM to an in-memory map
@@ -163,6 +258,93 @@ func (m *MemoryStore) StoreSBOM(ctx context.Context, sbom domain.SBOM) error {
 		Name:               sbom.Name,
 		SBOMCreatorVersion: sbom.SBOMCreatorVersion,
 	}
-	m.sboms[id] = sbom
+	return m.storeSBOM(id, sbom)
+}
+
+// UpdateSBOM replaces a SBOM in an in-memory map
+func (m *MemoryStore) UpdateSBOM(ctx context.Context, sbom domain.SBOM) error {
+	_, span := otel.Tracer("").Start(ctx, "MemoryStore.UpdateSBOM")
+	defer span.End()
+
+	if m.storeError {
+		return domain.ErrMockError
+	}
+
+	id := sbomID{
+		Name:               sbom.Name,
+		SBOMCreatorVersion: sbom.SBOMCreatorVersion,
+	}
+	return m.storeSBOM(id, sbom)
+}
+
+// ListScanResults lists the stored SBOMs and CVE manifests, attributed to the image they are named after
+func (m *MemoryStore) ListScanResults(ctx context.Context) ([]domain.StoredResult, error) {
+	_, span := otel.Tracer("").Start(ctx, "MemoryStore.ListScanResults")
+	defer span.End()
+
+	if m.getError {
+		return nil, domain.ErrMockError
+	}
+
+	seen := map[domain.StoredResult]bool{}
+	var results []domain.StoredResult
+	add := func(kind, name string, annotations map[string]string) {
+		result := domain.StoredResult{Kind: kind, Name: name, ImageSlug: name}
+		if slug := annotations[domain.AnnotationImageSlug]; slug != "" {
+			result.ImageSlug = slug
+		}
+		result = attributeToWorkload(result, annotations)
+		if !seen[result] {
+			seen[result] = true
+			results = append(results, result)
+		}
+	}
+	for id, sbom := range m.sboms {
+		add(domain.StoredSBOM, id.Name, sbom.Annotations)
+	}
+	for id, cve := range m.cveManifests {
+		add(domain.StoredVulnerabilityManifest, id.Name, cve.Annotations)
+	}
+	return results, nil
+}
+
+// DeleteScanResult deletes every version of a stored SBOM or CVE manifest
+func (m *MemoryStore) DeleteScanResult(ctx context.Context, result domain.StoredResult) error {
+	_, span := otel.Tracer("").Start(ctx, "MemoryStore.DeleteScanResult")
+	defer span.End()
+
+	if m.storeError {
+		return domain.ErrMockError
+	}
+
+	switch result.Kind {
+	case domain.StoredSBOM:
+		for id := range m.sboms {
+			if id.Name == result.Name {
+				delete(m.sboms, id)
+				delete(m.contents, id)
+			}
+		}
+	case domain.StoredVulnerabilityManifest:
+		for id := range m.cveManifests {
+			if id.Name == result.Name {
+				delete(m.cveManifests, id)
+				delete(m.contents, id)
+			}
+		}
+	}
+	return nil
+}
+
+// MarkOrphaned records the deletion of a workload, the memory store keeps no summary to annotate
+func (m *MemoryStore) MarkOrphaned(ctx context.Context, namespace, kind, name string) error {
+	_, span := otel.Tracer("").Start(ctx, "MemoryStore.MarkOrphaned")
+	defer span.End()
+
+	if m.storeError {
+		return domain.ErrMockError
+	}
+
+	m.orphaned[strings.Join([]string{namespace, kind, name}, "/")] = time.Now()
 	return nil
 }
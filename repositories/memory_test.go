@@ -45,3 +45,100 @@ func TestMemoryStore_GetSBOM(t *testing.T) {
 	got, _ = m.GetSBOMp(ctx, "name", "")
 	assert.NotNil(t, got.Content)
 }
+
+func TestMemoryStore_UpdateSBOM(t *testing.T) {
+	m := NewMemoryStorage(false, false)
+	ctx := context.TODO()
+	sbom := domain.SBOM{
+		Name:    "name",
+		Content: &v1beta1.Document{},
+	}
+	_ = m.StoreSBOM(ctx, sbom)
+	sbom.Annotations = map[string]string{domain.AnnotationSBOMSchemaVersion: "2"}
+	assert.NoError(t, m.UpdateSBOM(ctx, sbom))
+	got, _ := m.GetSBOM(ctx, "name", "")
+	assert.Equal(t, sbom.Annotations, got.Annotations)
+	assert.ErrorIs(t, NewMemoryStorage(false, true).UpdateSBOM(ctx, sbom), domain.ErrMockError)
+}
+
+func TestMemoryStore_serializeContents(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression Compression
+		encoding    Encoding
+	}{
+		{
+			name:        "gzip",
+			compression: CompressionGzip,
+		},
+		{
+			name:        "zstd",
+			compression: CompressionZstd,
+		},
+		{
+			name:     "cbor",
+			encoding: EncodingCBOR,
+		},
+		{
+			name:        "cbor zstd",
+			compression: CompressionZstd,
+			encoding:    EncodingCBOR,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemoryStorage(false, false, WithCompression(tt.compression), WithEncoding(tt.encoding))
+			ctx := context.TODO()
+			cve := domain.CVEManifest{
+				Name:    "name",
+				Content: &v1beta1.GrypeDocument{Matches: []v1beta1.Match{{Vulnerability: v1beta1.Vulnerability{VulnerabilityMetadata: v1beta1.VulnerabilityMetadata{ID: "CVE-2023-1234"}}}}},
+			}
+			assert.NoError(t, m.StoreCVE(ctx, cve, false))
+			assert.Nil(t, m.cveManifests[cveID{Name: "name"}].Content)
+			got, err := m.GetCVE(ctx, "name", "", "", "")
+			assert.NoError(t, err)
+			assert.Equal(t, cve, got)
+			sbom := domain.SBOM{
+				Name:    "name",
+				Content: &v1beta1.Document{Packages: []*v1beta1.Package{{PackageName: "openssl"}}},
+			}
+			assert.NoError(t, m.StoreSBOM(ctx, sbom))
+			assert.Nil(t, m.sboms[sbomID{Name: "name"}].Content)
+			got2, err := m.GetSBOM(ctx, "name", "")
+			assert.NoError(t, err)
+			assert.Equal(t, sbom, got2)
+		})
+	}
+}
+
+func TestMemoryStore_ListScanResults(t *testing.T) {
+	m := NewMemoryStorage(false, false, WithCompression(CompressionGzip))
+	ctx := context.TODO()
+	_ = m.StoreSBOM(ctx, domain.SBOM{Name: "slug", SBOMCreatorVersion: "v1", Content: &v1beta1.Document{}})
+	_ = m.StoreSBOM(ctx, domain.SBOM{Name: "slug", SBOMCreatorVersion: "v2", Content: &v1beta1.Document{}})
+	_ = m.StoreCVE(ctx, domain.CVEManifest{
+		Name:        "instance-id",
+		Annotations: map[string]string{domain.AnnotationImageSlug: "slug"},
+		Content:     &v1beta1.GrypeDocument{},
+	}, true)
+	results, err := m.ListScanResults(ctx)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []domain.StoredResult{
+		{Kind: domain.StoredSBOM, Name: "slug", ImageSlug: "slug"},
+		{Kind: domain.StoredVulnerabilityManifest, Name: "instance-id", ImageSlug: "slug"},
+	}, results)
+	for _, result := range results {
+		assert.NoError(t, m.DeleteScanResult(ctx, result))
+	}
+	results, err = m.ListScanResults(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Empty(t, m.contents)
+}
+
+func TestMemoryStore_MarkOrphaned(t *testing.T) {
+	m := NewMemoryStorage(false, false)
+	assert.NoError(t, m.MarkOrphaned(context.TODO(), "default", "Deployment", "nginx"))
+	assert.Contains(t, m.orphaned, "default/Deployment/nginx")
+	assert.ErrorIs(t, NewMemoryStorage(false, true).MarkOrphaned(context.TODO(), "default", "Deployment", "nginx"), domain.ErrMockError)
+}